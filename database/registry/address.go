@@ -0,0 +1,311 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+// AdvertiseAddressEnv is the env var of the address to advertise, which
+// overrides the detected one, but not that of WithAdvertiseAddress.
+const AdvertiseAddressEnv = "GXREGISTRY_ADVERTISE_ADDRESS"
+
+var (
+	ErrorLoopbackAddress    = jerrors.Errorf("loopback node address")
+	ErrorUnspecifiedAddress = jerrors.Errorf("unspecified node address")
+	ErrorMulticastAddress   = jerrors.Errorf("multicast node address")
+	ErrorMalformedAddress   = jerrors.Errorf("malformed node address")
+	ErrorNoAdvertiseAddress = jerrors.Errorf("no non-loopback interface address to advertise")
+)
+
+// AddressError is the error of a node address rejected by the strict
+// address validation, Err is one of the Error*Address above.
+type AddressError struct {
+	ID      string
+	Address string
+	Port    int32
+	Err     error
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("node %s address %q port %d: %s", e.ID, e.Address, e.Port, e.Err)
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// Interface is a network interface of the host.
+type Interface struct {
+	Name     string
+	Up       bool
+	Loopback bool
+	Addrs    []net.IP
+}
+
+// AddressResolver resolves the address to advertise for the nodes of an
+// empty, loopback or unspecified address. The address is resolved once,
+// and kept for all the nodes.
+type AddressResolver struct {
+	// Override is the address to advertise, AdvertiseAddressEnv and the
+	// interfaces are used if it is empty.
+	Override string
+	// Interfaces returns the interfaces of the host, net.Interfaces if nil.
+	Interfaces func() ([]Interface, error)
+	// Route returns the local address routing to the registry server
+	// @addr, by a udp "connection" if nil.
+	Route func(addr string) (net.IP, error)
+
+	lock    sync.Mutex
+	address string
+}
+
+// NeedAdvertise returns whether @address should be resolved by an
+// AddressResolver: it is empty, a loopback or an unspecified address.
+func NeedAdvertise(address string) bool {
+	host := strings.Trim(address, "[]")
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// Resolve returns the address to advertise. Out of the non-loopback
+// addresses of the interfaces which are up, it prefers that routing to one
+// of the registry @servers, then a private IPv4 address, a public IPv4
+// address and a global IPv6 address, in the order of the interfaces.
+func (r *AddressResolver) Resolve(servers []string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.address != "" {
+		return r.address, nil
+	}
+	if r.Override != "" {
+		r.address = r.Override
+		return r.address, nil
+	}
+	if env := os.Getenv(AdvertiseAddressEnv); env != "" {
+		r.address = env
+		return r.address, nil
+	}
+
+	interfaces := r.Interfaces
+	if interfaces == nil {
+		interfaces = hostInterfaces
+	}
+	ifs, err := interfaces()
+	if err != nil {
+		return "", jerrors.Annotate(err, "list interfaces")
+	}
+	var candidates []net.IP
+	for _, i := range ifs {
+		if !i.Up || i.Loopback {
+			continue
+		}
+		for _, ip := range i.Addrs {
+			if ip.IsGlobalUnicast() {
+				candidates = append(candidates, ip)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrorNoAdvertiseAddress
+	}
+
+	route := r.Route
+	if route == nil {
+		route = routeTo
+	}
+	for _, server := range servers {
+		ip, err := route(serverHostPort(server))
+		if err != nil {
+			continue
+		}
+		for _, c := range candidates {
+			if c.Equal(ip) {
+				r.address = c.String()
+				return r.address, nil
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return addressRank(candidates[i]) < addressRank(candidates[j])
+	})
+	r.address = candidates[0].String()
+
+	return r.address, nil
+}
+
+// addressRank ranks the private IPv4 addresses first, then the public IPv4
+// addresses and the IPv6 ones.
+func addressRank(ip net.IP) int {
+	switch {
+	case ip.To4() != nil && ip.IsPrivate():
+		return 0
+	case ip.To4() != nil:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// serverHostPort returns the host:port of a registry server address, which
+// can be an url, e.g. http://127.0.0.1:2379 of etcd.
+func serverHostPort(server string) string {
+	if i := strings.Index(server, "://"); i >= 0 {
+		server = server[i+3:]
+	}
+	if i := strings.IndexByte(server, '/'); i >= 0 {
+		server = server[:i]
+	}
+
+	return server
+}
+
+func hostInterfaces() ([]Interface, error) {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return nil, jerrors.Trace(err)
+	}
+
+	var res []Interface
+	for _, i := range ifs {
+		addrs, err := i.Addrs()
+		if err != nil {
+			continue
+		}
+		it := Interface{
+			Name:     i.Name,
+			Up:       i.Flags&net.FlagUp != 0,
+			Loopback: i.Flags&net.FlagLoopback != 0,
+		}
+		for _, addr := range addrs {
+			switch v := addr.(type) {
+			case *net.IPNet:
+				it.Addrs = append(it.Addrs, v.IP)
+			case *net.IPAddr:
+				it.Addrs = append(it.Addrs, v.IP)
+			}
+		}
+		res = append(res, it)
+	}
+
+	return res, nil
+}
+
+// routeTo returns the local address of a udp socket connected to @addr,
+// which sends no packet.
+func routeTo(addr string) (net.IP, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, jerrors.Trace(err)
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// ValidateAddress returns an *AddressError if the address of @node is not
+// an address to advertise: a loopback, unspecified or multicast IP, an
+// invalid host name, or a port out of (0, 65535].
+func ValidateAddress(node *Node) error {
+	invalid := func(err error) error {
+		return &AddressError{ID: node.ID, Address: node.Address, Port: node.Port, Err: err}
+	}
+
+	if node.Port <= 0 || node.Port > 65535 {
+		return invalid(ErrorMalformedAddress)
+	}
+	host := node.Address
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	// the address must be a host without a port
+	if _, _, err := net.SplitHostPort(net.JoinHostPort(host, strconv.Itoa(int(node.Port)))); err != nil {
+		return invalid(ErrorMalformedAddress)
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		if strings.EqualFold(host, "localhost") {
+			return invalid(ErrorLoopbackAddress)
+		}
+		if !validHostname(host) {
+			return invalid(ErrorMalformedAddress)
+		}
+	case ip.IsUnspecified():
+		return invalid(ErrorUnspecifiedAddress)
+	case ip.IsLoopback():
+		return invalid(ErrorLoopbackAddress)
+	case ip.IsMulticast():
+		return invalid(ErrorMulticastAddress)
+	}
+
+	return nil
+}
+
+// validHostname checks @host by the rules of RFC 1123.
+func validHostname(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// AdvertiseService returns @s of the node addresses resolved by
+// o.Advertise, and validated if o.StrictAddress, which is @s if neither is
+// set. @s is not changed. The registries call it on Register and
+// Deregister, so that both have the same nodes.
+func (o *Options) AdvertiseService(s Service) (Service, error) {
+	if o.Advertise == nil && !o.StrictAddress {
+		return s, nil
+	}
+
+	s = *s.Copy()
+	for _, node := range s.Nodes {
+		if o.Advertise != nil && NeedAdvertise(node.Address) {
+			address, err := o.Advertise.Resolve(o.Addrs)
+			if err != nil {
+				return Service{}, jerrors.Annotatef(err, "resolve the address of node %s", node.ID)
+			}
+			node.Address = strings.Trim(address, "[]")
+		}
+		if o.StrictAddress {
+			if err := ValidateAddress(node); err != nil {
+				return Service{}, err
+			}
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,154 @@
+package gxregistry
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func testInterfaces() ([]Interface, error) {
+	return []Interface{
+		{Name: "lo", Up: true, Loopback: true, Addrs: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}},
+		{Name: "eth0", Up: true, Addrs: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("2001:db8::10")}},
+		{Name: "eth1", Up: true, Addrs: []net.IP{net.ParseIP("203.0.113.7")}},
+		{Name: "docker0", Up: false, Addrs: []net.IP{net.ParseIP("172.17.0.1")}},
+		{Name: "eth2", Up: true, Addrs: []net.IP{net.ParseIP("10.1.2.3")}},
+	}, nil
+}
+
+// testRoute routes 10.0.0.0/8 by eth2, and the others by eth1.
+func testRoute(addr string) (net.IP, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil && ip.To4()[0] == 10 {
+		return net.ParseIP("10.1.2.3"), nil
+	}
+	if host == "zk.v6" {
+		return net.ParseIP("2001:db8::10"), nil
+	}
+	return net.ParseIP("203.0.113.7"), nil
+}
+
+func TestAddressResolver(t *testing.T) {
+	t.Setenv(AdvertiseAddressEnv, "")
+	for _, c := range []struct {
+		name    string
+		servers []string
+		route   func(string) (net.IP, error)
+		want    string
+	}{
+		{"route to zk", []string{"10.9.9.9:2181"}, testRoute, "10.1.2.3"},
+		{"route to etcd url", []string{"http://198.51.100.1:2379"}, testRoute, "203.0.113.7"},
+		{"route by ipv6", []string{"zk.v6:2181"}, testRoute, "2001:db8::10"},
+		{"first routable server", []string{"bad", "10.9.9.9:2181"}, testRoute, "10.1.2.3"},
+		{"no route", []string{"10.9.9.9:2181"}, func(string) (net.IP, error) { return nil, errors.New("unreachable") }, "10.1.2.3"},
+		{"route by an interface down", []string{"172.17.0.2:2181"}, func(string) (net.IP, error) { return net.ParseIP("172.17.0.1"), nil }, "10.1.2.3"},
+		{"no server", nil, testRoute, "10.1.2.3"},
+	} {
+		r := &AddressResolver{Interfaces: testInterfaces, Route: c.route}
+		if addr, err := r.Resolve(c.servers); err != nil || addr != c.want {
+			t.Fatalf("%s: Resolve() = %q, error:%v, want %q", c.name, addr, err, c.want)
+		}
+	}
+
+	// a public IPv4 address is preferred to an IPv6 one
+	r := &AddressResolver{Interfaces: func() ([]Interface, error) {
+		ifs, _ := testInterfaces()
+		return ifs[:3], nil
+	}, Route: testRoute}
+	if addr, _ := r.Resolve(nil); addr != "203.0.113.7" {
+		t.Fatalf("Resolve() = %q, want the public IPv4 address", addr)
+	}
+
+	r = &AddressResolver{Interfaces: func() ([]Interface, error) {
+		ifs, _ := testInterfaces()
+		return ifs[:1], nil
+	}}
+	if _, err := r.Resolve(nil); err != ErrorNoAdvertiseAddress {
+		t.Fatalf("Resolve() of loopback interfaces = error:%v", err)
+	}
+
+	// the overrides
+	t.Setenv(AdvertiseAddressEnv, "192.0.2.1")
+	r = &AddressResolver{Interfaces: testInterfaces, Route: testRoute}
+	if addr, _ := r.Resolve(nil); addr != "192.0.2.1" {
+		t.Fatalf("Resolve() = %q, want the env address", addr)
+	}
+	r = &AddressResolver{Override: "192.0.2.2", Interfaces: testInterfaces}
+	if addr, _ := r.Resolve(nil); addr != "192.0.2.2" {
+		t.Fatalf("Resolve() = %q, want the override address", addr)
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	for _, c := range []struct {
+		address string
+		port    int32
+		err     error
+	}{
+		{"10.1.2.3", 8080, nil},
+		{"2001:db8::10", 8080, nil},
+		{"[2001:db8::10]", 8080, nil},
+		{"provider-1.svc.local", 8080, nil},
+		{"127.0.0.1", 8080, ErrorLoopbackAddress},
+		{"::1", 8080, ErrorLoopbackAddress},
+		{"localhost", 8080, ErrorLoopbackAddress},
+		{"0.0.0.0", 8080, ErrorUnspecifiedAddress},
+		{"::", 8080, ErrorUnspecifiedAddress},
+		{"224.0.0.1", 8080, ErrorMulticastAddress},
+		{"ff02::1", 8080, ErrorMulticastAddress},
+		{"10.1.2.3:8080", 8080, ErrorMalformedAddress},
+		{"", 8080, ErrorMalformedAddress},
+		{"bad_host", 8080, ErrorMalformedAddress},
+		{"10.1.2.3", 0, ErrorMalformedAddress},
+		{"10.1.2.3", 70000, ErrorMalformedAddress},
+	} {
+		err := ValidateAddress(&Node{ID: "node0", Address: c.address, Port: c.port})
+		var addrErr *AddressError
+		if c.err == nil && err != nil || c.err != nil && (!errors.As(err, &addrErr) || !errors.Is(err, c.err)) {
+			t.Fatalf("ValidateAddress(%s, %d) = error:%v, want:%v", c.address, c.port, err, c.err)
+		}
+	}
+}
+
+func TestAdvertiseService(t *testing.T) {
+	t.Setenv(AdvertiseAddressEnv, "")
+	attr := ServiceAttr{Service: "shopping", Role: SRT_Provider}
+	s := Service{Attr: &attr, Nodes: []*Node{
+		{ID: "empty", Port: 8080},
+		{ID: "loopback", Address: "127.0.0.1", Port: 8081},
+		{ID: "any", Address: "::", Port: 8082},
+		{ID: "set", Address: "10.9.9.9", Port: 8083},
+	}}
+
+	var o Options
+	WithAddrs("10.0.0.1:2181")(&o)
+	WithAddressResolver(&AddressResolver{Interfaces: testInterfaces, Route: testRoute})(&o)
+	WithStrictAddress()(&o)
+	advertised, err := o.AdvertiseService(s)
+	if err != nil {
+		t.Fatalf("AdvertiseService() = error:%s", err)
+	}
+	for i, want := range []string{"10.1.2.3", "10.1.2.3", "10.1.2.3", "10.9.9.9"} {
+		if advertised.Nodes[i].Address != want {
+			t.Fatalf("node %s address = %q, want %q", advertised.Nodes[i].ID, advertised.Nodes[i].Address, want)
+		}
+	}
+	if s.Nodes[0].Address != "" {
+		t.Fatalf("AdvertiseService() changes its service")
+	}
+
+	// strict without the resolver
+	o = Options{}
+	WithStrictAddress()(&o)
+	if _, err = o.AdvertiseService(s); !errors.Is(err, ErrorMalformedAddress) {
+		t.Fatalf("AdvertiseService() of an empty address = error:%v", err)
+	}
+	// neither
+	o = Options{}
+	if advertised, err = o.AdvertiseService(s); err != nil || advertised.Nodes[1].Address != "127.0.0.1" {
+		t.Fatalf("AdvertiseService() = %+v, error:%v", advertised, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"fmt"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+var ErrorBatchAborted = jerrors.Errorf("batch aborted by the failure of another service")
+
+// BatchRegistry is a Registry which registers a batch of services at once,
+// e.g. the zookeeper registry. Check it by a type assertion.
+type BatchRegistry interface {
+	Registry
+	// RegisterBatch registers all of @services or none of them, as Register
+	// of every service does. It returns a *BatchError if any fails.
+	RegisterBatch(services []Service) error
+	// UnregisterBatch deregisters @services, as Deregister of every service
+	// does. The services failing do not stop the others, and it returns a
+	// *BatchError if any fails.
+	UnregisterBatch(services []Service) error
+}
+
+// BatchFailure is a service of a batch failing by Err.
+type BatchFailure struct {
+	Service Service
+	Err     error
+}
+
+// BatchError is the result of a batch failing, which lists every service
+// of the batch by whether it is done. The services of a register batch
+// rolled back for the failure of another one fail by ErrorBatchAborted.
+type BatchError struct {
+	Op        string // OpRegister or OpDeregister
+	Succeeded []Service
+	Failed    []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	cause := e.First()
+	if cause == nil {
+		return fmt.Sprintf("%s batch: %d of %d services failed",
+			e.Op, len(e.Failed), len(e.Failed)+len(e.Succeeded))
+	}
+
+	return fmt.Sprintf("%s batch: %d of %d services failed, the first by: %v",
+		e.Op, len(e.Failed), len(e.Failed)+len(e.Succeeded), cause)
+}
+
+// First returns the first error of the failed services which is not
+// ErrorBatchAborted. It is not named Cause, so jerrors.Cause keeps the
+// *BatchError.
+func (e *BatchError) First() error {
+	for _, f := range e.Failed {
+		if f.Err != ErrorBatchAborted {
+			return f.Err
+		}
+	}
+
+	return nil
+}
+
+// Unwrap returns First, for errors.Is and errors.As.
+func (e *BatchError) Unwrap() error {
+	return e.First()
+}
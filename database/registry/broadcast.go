@@ -0,0 +1,258 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/time"
+)
+
+// DefaultSubscriptionSize is the buffer size of a Subscription by default.
+const DefaultSubscriptionSize = 256
+
+// BroadcastEvent is an event of a Broadcaster.
+type BroadcastEvent struct {
+	Seq    uint64 // from 1, in the order of the watcher
+	Time   time.Time
+	Result *EventResult // nil if Err is not
+	Err    error        // an error of the watcher
+	// Dropped is the count of the events dropped by the subscription
+	// before this one, as its buffer was full.
+	Dropped uint64
+}
+
+// Broadcaster fans out the events of a watcher to its subscriptions, so
+// that many consumers share a watcher.
+type Broadcaster struct {
+	w     Watcher
+	clock gxtime.Clock
+
+	sync.Mutex
+	seq  uint64
+	subs map[*Subscription]struct{}
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+type BroadcasterOption func(*Broadcaster)
+
+// WithBroadcasterClock stamps the events by @c, gxtime.RealClock by default.
+func WithBroadcasterClock(c gxtime.Clock) BroadcasterOption {
+	return func(b *Broadcaster) {
+		b.clock = c
+	}
+}
+
+// NewBroadcaster returns a broadcaster of @w, which reads @w until it is
+// closed. The events before a subscription are not sent to it.
+func NewBroadcaster(w Watcher, opts ...BroadcasterOption) *Broadcaster {
+	b := &Broadcaster{
+		w:     w,
+		clock: gxtime.RealClock{},
+		subs:  make(map[*Subscription]struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b
+}
+
+func (b *Broadcaster) run() {
+	defer b.wg.Done()
+	defer b.stop()
+
+	for {
+		res, err := b.w.Notify()
+		if b.w.IsClosed() || jerrors.Cause(err) == ErrWatcherClosed {
+			return
+		}
+		b.publish(BroadcastEvent{Time: b.clock.Now(), Result: res, Err: err})
+	}
+}
+
+func (b *Broadcaster) publish(e BroadcastEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	for s := range b.subs {
+		s.push(e)
+	}
+}
+
+// stop ends the subscriptions after their buffered events.
+func (b *Broadcaster) stop() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.once.Do(func() {
+		close(b.done)
+	})
+	for s := range b.subs {
+		s.end()
+	}
+	b.subs = make(map[*Subscription]struct{})
+}
+
+// Subscribe returns a subscription of the events from now on, which buffers
+// @size events at most, DefaultSubscriptionSize if @size <= 0, and drops
+// the oldest one for a new one when it is full. The subscription of a
+// closed broadcaster ends at once.
+func (b *Broadcaster) Subscribe(size int) *Subscription {
+	if size <= 0 {
+		size = DefaultSubscriptionSize
+	}
+	s := &Subscription{
+		b:     b,
+		size:  size,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	select {
+	case <-b.done:
+		s.end()
+	default:
+		b.subs[s] = struct{}{}
+	}
+
+	return s
+}
+
+func (b *Broadcaster) unsubscribe(s *Subscription) {
+	b.Lock()
+	delete(b.subs, s)
+	b.Unlock()
+}
+
+// Subscribers returns the count of the subscriptions not closed.
+func (b *Broadcaster) Subscribers() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return len(b.subs)
+}
+
+// Done is closed when the broadcaster stops, as its watcher is closed.
+func (b *Broadcaster) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close closes the watcher, and ends all the subscriptions.
+func (b *Broadcaster) Close() {
+	b.w.Close()
+	b.wg.Wait()
+}
+
+// Subscription is a subscription of a Broadcaster.
+type Subscription struct {
+	b     *Broadcaster
+	size  int
+	ready chan struct{} // there are events, or it ends
+
+	sync.Mutex
+	events []BroadcastEvent
+	ended  bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+// push buffers @e, dropping the oldest event if it is full. The drops are
+// counted by the oldest event left.
+func (s *Subscription) push(e BroadcastEvent) {
+	s.Lock()
+	if len(s.events) == s.size {
+		dropped := s.events[0].Dropped + 1
+		s.events[0] = BroadcastEvent{}
+		s.events = s.events[1:]
+		if len(s.events) > 0 {
+			s.events[0].Dropped += dropped
+		} else {
+			e.Dropped += dropped
+		}
+	}
+	s.events = append(s.events, e)
+	s.Unlock()
+
+	s.signal()
+}
+
+// end ends the subscription after its buffered events.
+func (s *Subscription) end() {
+	s.Lock()
+	s.ended = true
+	s.Unlock()
+
+	s.signal()
+}
+
+func (s *Subscription) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns the next event. It returns ErrWatcherClosed after the
+// subscription is closed, or after the buffered events of a stopped
+// broadcaster, and the error of @ctx if it is done first.
+func (s *Subscription) Next(ctx context.Context) (BroadcastEvent, error) {
+	for {
+		select {
+		case <-s.done:
+			return BroadcastEvent{}, ErrWatcherClosed
+		default:
+		}
+
+		s.Lock()
+		if len(s.events) > 0 {
+			e := s.events[0]
+			s.events[0] = BroadcastEvent{}
+			s.events = s.events[1:]
+			s.Unlock()
+			return e, nil
+		}
+		ended := s.ended
+		s.Unlock()
+		if ended {
+			return BroadcastEvent{}, ErrWatcherClosed
+		}
+
+		select {
+		case <-s.ready:
+		case <-s.done:
+			return BroadcastEvent{}, ErrWatcherClosed
+		case <-ctx.Done():
+			return BroadcastEvent{}, ctx.Err()
+		}
+	}
+}
+
+// Close removes the subscription from its broadcaster.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.b.unsubscribe(s)
+	})
+}
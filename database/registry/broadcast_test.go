@@ -0,0 +1,111 @@
+package gxregistry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chanWatcher notifies the events sent to its channel.
+type chanWatcher struct {
+	events chan *EventResult
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newChanWatcher() *chanWatcher {
+	return &chanWatcher{events: make(chan *EventResult), done: make(chan struct{})}
+}
+
+func (w *chanWatcher) Notify() (*EventResult, error) {
+	return w.NotifyCtx(context.Background())
+}
+
+func (w *chanWatcher) NotifyCtx(ctx context.Context) (*EventResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-w.done:
+		return nil, ErrWatcherClosed
+	case res := <-w.events:
+		return res, nil
+	}
+}
+
+func (w *chanWatcher) Valid() bool { return !w.IsClosed() }
+func (w *chanWatcher) Close()      { w.once.Do(func() { close(w.done) }) }
+
+func (w *chanWatcher) IsClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func addEvent(id string) *EventResult {
+	attr := ServiceAttr{Service: "shopping", Role: SRT_Provider}
+	return &EventResult{Action: ServiceAdd, Service: &Service{Attr: &attr, Nodes: []*Node{{ID: id}}}}
+}
+
+func nextEvent(t *testing.T, s *Subscription) BroadcastEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := s.Next(ctx)
+	if err != nil {
+		t.Fatalf("Subscription.Next() = error:%s", err)
+	}
+	return e
+}
+
+func TestBroadcaster(t *testing.T) {
+	w := newChanWatcher()
+	b := NewBroadcaster(w)
+	s0, s1 := b.Subscribe(0), b.Subscribe(2)
+	if n := b.Subscribers(); n != 2 {
+		t.Fatalf("Subscribers() = %d", n)
+	}
+
+	for _, id := range []string{"node0", "node1", "node2", "node3"} {
+		w.events <- addEvent(id)
+	}
+	for i, id := range []string{"node0", "node1", "node2", "node3"} {
+		if e := nextEvent(t, s0); e.Seq != uint64(i+1) || e.Result.Service.Nodes[0].ID != id || e.Dropped != 0 {
+			t.Fatalf("event %d = %+v", i, e)
+		}
+	}
+
+	// s1 drops the oldest events, counted by the oldest one left. They are
+	// pushed to s1 before s0 gets the last one.
+	if e := nextEvent(t, s1); e.Seq != 3 || e.Dropped != 2 {
+		t.Fatalf("event of a full subscription = %+v", e)
+	}
+	if e := nextEvent(t, s1); e.Seq != 4 || e.Dropped != 0 {
+		t.Fatalf("event of a full subscription = %+v", e)
+	}
+
+	s1.Close()
+	if _, err := s1.Next(context.Background()); err != ErrWatcherClosed {
+		t.Fatalf("Next() of a closed subscription = error:%v", err)
+	}
+	if n := b.Subscribers(); n != 1 {
+		t.Fatalf("Subscribers() after Close() = %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s0.Next(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Next() without events = error:%v", err)
+	}
+
+	// the subscriptions end as the watcher is closed
+	b.Close()
+	if _, err := s0.Next(context.Background()); err != ErrWatcherClosed {
+		t.Fatalf("Next() after Close() = error:%v", err)
+	}
+	if _, err := b.Subscribe(1).Next(context.Background()); err != ErrWatcherClosed {
+		t.Fatalf("Next() of a closed broadcaster = error:%v", err)
+	}
+}
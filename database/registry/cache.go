@@ -0,0 +1,259 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+// The format of a local cache file:
+//
+//	"GXCACHE" | version(1 byte) | length(4 bytes, big endian) |
+//	crc32(4 bytes, big endian) | the json of the services
+//
+// The length and the IEEE crc32 are of the json, so that a truncated or
+// corrupted file is detected.
+const LocalCacheVersion = 1
+
+var (
+	localCacheMagic = []byte("GXCACHE")
+
+	ErrLocalCacheVersion   = jerrors.Errorf("unsupported local cache version")
+	ErrLocalCacheCorrupted = jerrors.Errorf("corrupted local cache")
+)
+
+type cacheNodeKey struct {
+	id      string
+	address string
+	port    int32
+}
+
+type cachedService struct {
+	service Service // of the last event of the attr, without nodes
+	nodes   map[cacheNodeKey]*Node
+}
+
+// LocalCache keeps the services known by a watcher in a file, which is
+// rewritten atomically by every change, see WithLocalCache. The services
+// are loaded by LoadLocalCache when the registry is unreachable.
+type LocalCache struct {
+	path string
+
+	sync.Mutex
+	services map[ServiceAttr]*cachedService
+}
+
+// NewLocalCache returns an empty cache of the file @path, which is not
+// written until the first change.
+func NewLocalCache(path string) *LocalCache {
+	return &LocalCache{path: path, services: make(map[ServiceAttr]*cachedService)}
+}
+
+// Update applies the Add, Update or Del of @e, and writes the file. The
+// stale events, e.g. of the services loaded from the file, are ignored, as
+// they are not known by the registry.
+func (c *LocalCache) Update(e *EventResult) error {
+	if e == nil || e.Stale || e.Service == nil || e.Service.Attr == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	switch e.Action {
+	case ServiceAdd, ServiceUpdate:
+		c.add(e.Service)
+	case ServiceDel:
+		cs, ok := c.services[*e.Service.Attr]
+		if !ok {
+			return nil
+		}
+		for _, node := range e.Service.Nodes {
+			delete(cs.nodes, cacheNodeKey{id: node.ID, address: node.Address, port: node.Port})
+		}
+		if len(cs.nodes) == 0 {
+			delete(c.services, *e.Service.Attr)
+		}
+	default:
+		return nil
+	}
+
+	return c.write()
+}
+
+// Add adds @services, e.g. of a Snapshot, and writes the file.
+func (c *LocalCache) Add(services []*Service) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, s := range services {
+		if s != nil && s.Attr != nil {
+			c.add(s)
+		}
+	}
+
+	return c.write()
+}
+
+// add adds the nodes of @s, c must be locked.
+func (c *LocalCache) add(s *Service) {
+	cs, ok := c.services[*s.Attr]
+	if !ok {
+		cs = &cachedService{nodes: make(map[cacheNodeKey]*Node)}
+		c.services[*s.Attr] = cs
+	}
+	cs.service = Service{Attr: s.Attr, Metadata: s.Metadata}
+	for _, node := range s.Nodes {
+		cs.nodes[cacheNodeKey{id: node.ID, address: node.Address, port: node.Port}] = node
+	}
+}
+
+// Services returns the services of the cache, sorted by their paths, and
+// their nodes by their IDs.
+func (c *LocalCache) Services() []*Service {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.list()
+}
+
+// list returns the sorted services, c must be locked.
+func (c *LocalCache) list() []*Service {
+	services := make([]*Service, 0, len(c.services))
+	for _, cs := range c.services {
+		s := cs.service
+		s.Nodes = make([]*Node, 0, len(cs.nodes))
+		for _, node := range cs.nodes {
+			s.Nodes = append(s.Nodes, node)
+		}
+		sort.Slice(s.Nodes, func(i, j int) bool {
+			a, b := s.Nodes[i], s.Nodes[j]
+			switch {
+			case a.ID != b.ID:
+				return a.ID < b.ID
+			case a.Address != b.Address:
+				return a.Address < b.Address
+			default:
+				return a.Port < b.Port
+			}
+		})
+		services = append(services, s.Copy())
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Path("") < services[j].Path("")
+	})
+
+	return services
+}
+
+// write writes the services to a temporary file and renames it to the
+// path, so the file is never partly written, c must be locked.
+func (c *LocalCache) write() error {
+	data, err := json.Marshal(c.list())
+	if err != nil {
+		return jerrors.Annotatef(err, "json.Marshal(services)")
+	}
+	var frame bytes.Buffer
+	frame.Write(localCacheMagic)
+	frame.WriteByte(LocalCacheVersion)
+	binary.Write(&frame, binary.BigEndian, uint32(len(data)))
+	binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(data))
+	frame.Write(data)
+
+	f, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return jerrors.Annotatef(err, "ioutil.TempFile(dir:%s)", filepath.Dir(c.path))
+	}
+	_, err = f.Write(frame.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return jerrors.Annotatef(err, "write local cache %s", c.path)
+	}
+
+	return nil
+}
+
+// LoadLocalCache returns the services of the local cache file @path, e.g.
+// for a consumer starting when the registry is unreachable, see
+// WithLocalCache. It returns ErrLocalCacheCorrupted if the file is
+// truncated or corrupted, which must not be used then, and an error of
+// os.IsNotExist if there is no file.
+func LoadLocalCache(path string) ([]*Service, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "read local cache %s", path)
+	}
+
+	header := len(localCacheMagic) + 1
+	if len(data) < header+8 || !bytes.Equal(data[:len(localCacheMagic)], localCacheMagic) {
+		return nil, jerrors.Annotatef(ErrLocalCacheCorrupted, "header of %s", path)
+	}
+	if data[len(localCacheMagic)] != LocalCacheVersion {
+		return nil, jerrors.Annotatef(ErrLocalCacheVersion, "version %d of %s", data[len(localCacheMagic)], path)
+	}
+	length := binary.BigEndian.Uint32(data[header:])
+	checksum := binary.BigEndian.Uint32(data[header+4:])
+	data = data[header+8:]
+	if uint32(len(data)) != length {
+		return nil, jerrors.Annotatef(ErrLocalCacheCorrupted, "length %d of %s, want %d", len(data), path, length)
+	}
+	if crc32.ChecksumIEEE(data) != checksum {
+		return nil, jerrors.Annotatef(ErrLocalCacheCorrupted, "checksum of %s", path)
+	}
+
+	var services []*Service
+	if err = json.Unmarshal(data, &services); err != nil {
+		return nil, jerrors.Annotatef(ErrLocalCacheCorrupted, "json.Unmarshal(%s) = error:%s", path, err)
+	}
+	for _, s := range services {
+		if s == nil || s.Attr == nil {
+			return nil, jerrors.Annotatef(ErrLocalCacheCorrupted, "service without attr in %s", path)
+		}
+	}
+
+	return services, nil
+}
+
+// StaleEvents returns the ServiceAdd events of @services of @root, which are
+// Stale, one of every node, e.g. of the services of LoadLocalCache. The
+// services not of @filter are skipped as the watchers do, see
+// ServiceAttr.MeshFilter.
+func StaleEvents(services []*Service, root string, filter ServiceAttr) []*EventResult {
+	var events []*EventResult
+	for _, s := range services {
+		if !filter.MeshFilter(*s.Attr) {
+			continue
+		}
+		for _, node := range s.Nodes {
+			service := *s.Copy()
+			service.Nodes = []*Node{node}
+			events = append(events, &EventResult{Action: ServiceAdd, Service: &service, Root: root, Stale: true})
+		}
+	}
+
+	return events
+}
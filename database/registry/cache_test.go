@@ -0,0 +1,131 @@
+package gxregistry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+func cacheEvent(action ServiceEventType, service, id string, port int32) *EventResult {
+	attr := ServiceAttr{Group: "bjtelecom", Service: service, Protocol: "pb", Version: "1.0.1", Role: SRT_Provider}
+	return &EventResult{
+		Action:  action,
+		Service: &Service{Attr: &attr, Nodes: []*Node{{ID: id, Address: "127.0.0.1", Port: port}}},
+	}
+}
+
+// cacheSet returns the nodes of @services by their service names.
+func cacheSet(services []*Service) map[string][]string {
+	set := make(map[string][]string)
+	for _, s := range services {
+		for _, node := range s.Nodes {
+			set[s.Attr.Service] = append(set[s.Attr.Service], node.ID)
+		}
+	}
+	return set
+}
+
+func tempCache(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gxregistry-cache")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = error:%s", err)
+	}
+	return filepath.Join(dir, "services.cache"), func() { os.RemoveAll(dir) }
+}
+
+func TestLocalCache(t *testing.T) {
+	path, clean := tempCache(t)
+	defer clean()
+
+	c := NewLocalCache(path)
+	for _, e := range []*EventResult{
+		cacheEvent(ServiceAdd, "shopping", "node0", 10000),
+		cacheEvent(ServiceAdd, "shopping", "node1", 10001),
+		cacheEvent(ServiceAdd, "cart", "node2", 10002),
+		cacheEvent(ServiceAdd, "order", "node3", 10003),
+		cacheEvent(ServiceDel, "shopping", "node0", 10000),
+		cacheEvent(ServiceUpdate, "cart", "node2", 10002),
+		cacheEvent(ServiceDel, "order", "node3", 10003),
+		cacheEvent(ServiceDel, "order", "node3", 10003),
+		// a stale event is not known by the registry
+		{Action: ServiceAdd, Service: cacheEvent(ServiceAdd, "stale", "node4", 10004).Service, Stale: true},
+	} {
+		if err := c.Update(e); err != nil {
+			t.Fatalf("Update(%s) = error:%s", e.GoString(), err)
+		}
+	}
+	want := map[string][]string{"shopping": {"node1"}, "cart": {"node2"}}
+	if got := cacheSet(c.Services()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Services() = %v, want %v", got, want)
+	}
+
+	// the file of the cache of a killed watcher
+	services, err := LoadLocalCache(path)
+	if err != nil {
+		t.Fatalf("LoadLocalCache() = error:%s", err)
+	}
+	if got := cacheSet(services); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoadLocalCache() = %v, want %v", got, want)
+	}
+	if services[0].Attr.Service != "cart" || services[1].Nodes[0].Port != 10001 {
+		t.Fatalf("LoadLocalCache() = %+v", services)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Fatalf("%d files in the cache directory", len(files))
+	}
+
+	events := StaleEvents(services, "/test", ServiceAttr{Service: "shopping"})
+	if len(events) != 1 || !events[0].Stale || events[0].Action != ServiceAdd ||
+		events[0].Root != "/test" || events[0].Service.Nodes[0].ID != "node1" {
+		t.Fatalf("StaleEvents() = %+v", events)
+	}
+
+	if _, err := LoadLocalCache(path + ".none"); !os.IsNotExist(jerrors.Cause(err)) {
+		t.Fatalf("LoadLocalCache() of no file = error:%v", err)
+	}
+}
+
+func TestLocalCacheCorrupted(t *testing.T) {
+	path, clean := tempCache(t)
+	defer clean()
+
+	if err := NewLocalCache(path).Update(cacheEvent(ServiceAdd, "shopping", "node0", 10000)); err != nil {
+		t.Fatalf("Update() = error:%s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() = error:%s", err)
+	}
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-3] ^= 0x20
+	version := append([]byte(nil), data...)
+	version[len(localCacheMagic)] = LocalCacheVersion + 1
+	for _, c := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrLocalCacheCorrupted},
+		{"no magic", []byte("{\"services\":[]}"), ErrLocalCacheCorrupted},
+		{"truncated header", data[:len(localCacheMagic)+3], ErrLocalCacheCorrupted},
+		{"truncated", data[:len(data)-1], ErrLocalCacheCorrupted},
+		{"appended", append(append([]byte(nil), data...), ' '), ErrLocalCacheCorrupted},
+		{"flipped", flipped, ErrLocalCacheCorrupted},
+		{"version", version, ErrLocalCacheVersion},
+	} {
+		if err := ioutil.WriteFile(path, c.data, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile() = error:%s", err)
+		}
+		if services, err := LoadLocalCache(path); jerrors.Cause(err) != c.err {
+			t.Fatalf("LoadLocalCache() of %s = %v, error:%v", c.name, services, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry/pb"
+	"github.com/AlexStocks/goext/strings"
+)
+
+const (
+	CodecJSON     = "json"
+	CodecProtobuf = "protobuf"
+
+	// the metadata key of the node weight, the same as gxselector.WeightKey
+	weightKey = "weight"
+)
+
+// Codec encodes the service payloads stored in a registry.
+type Codec interface {
+	Name() string
+	Encode(s *Service) ([]byte, error)
+	Decode(data []byte) (*Service, error)
+}
+
+var (
+	codecLock sync.RWMutex
+	codecs    = make(map[string]Codec)
+)
+
+func init() {
+	RegisterCodec(jsonCodec{})
+	RegisterCodec(protobufCodec{})
+}
+
+// RegisterCodec makes @c available by its name. It panics if the name
+// has been registered.
+func RegisterCodec(c Codec) {
+	codecLock.Lock()
+	defer codecLock.Unlock()
+	if _, ok := codecs[c.Name()]; ok {
+		panic("gxregistry: RegisterCodec called twice for codec " + c.Name())
+	}
+	codecs[c.Name()] = c
+}
+
+// GetCodec returns the codec named @name, nil if it is not registered.
+func GetCodec(name string) Codec {
+	codecLock.RLock()
+	defer codecLock.RUnlock()
+	return codecs[name]
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return CodecJSON
+}
+
+func (jsonCodec) Encode(s *Service) ([]byte, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "json.Marshal(Service:%+v)", s)
+	}
+
+	return b, nil
+}
+
+func (jsonCodec) Decode(data []byte) (*Service, error) {
+	var s Service
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "json.Unmarshal(data:%s)", gxstrings.String(data))
+	}
+
+	return &s, nil
+}
+
+// protobufCodec encodes a service as a gxregistrypb.Service, which can be
+// read by the clients of other languages. The node weight is kept in the
+// node metadata.
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return CodecProtobuf
+}
+
+func (protobufCodec) Encode(s *Service) ([]byte, error) {
+	ps := gxregistrypb.Service{Metadata: s.Metadata}
+	if s.Attr != nil {
+		ps.Attr = &gxregistrypb.ServiceAttr{
+			Group:    s.Attr.Group,
+			Service:  s.Attr.Service,
+			Protocol: s.Attr.Protocol,
+			Version:  s.Attr.Version,
+			Role:     gxregistrypb.ServiceRole(s.Attr.Role),
+		}
+	}
+	for _, n := range s.Nodes {
+		if n == nil {
+			continue
+		}
+		pn := &gxregistrypb.Node{
+			Id:       n.ID,
+			Address:  n.Address,
+			Port:     n.Port,
+			Metadata: n.Metadata,
+		}
+		if w, err := strconv.ParseInt(n.Metadata[weightKey], 10, 32); err == nil && w > 0 {
+			pn.Weight = int32(w)
+		}
+		ps.Nodes = append(ps.Nodes, pn)
+	}
+
+	b, err := ps.Marshal()
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "gxregistrypb.Service{%+v}.Marshal()", ps)
+	}
+
+	return b, nil
+}
+
+func (protobufCodec) Decode(data []byte) (*Service, error) {
+	var ps gxregistrypb.Service
+	if err := ps.Unmarshal(data); err != nil {
+		return nil, jerrors.Annotatef(err, "gxregistrypb.Service.Unmarshal(data:%q)", data)
+	}
+
+	s := Service{Metadata: ps.Metadata}
+	if ps.Attr != nil {
+		s.Attr = &ServiceAttr{
+			Group:    ps.Attr.Group,
+			Service:  ps.Attr.Service,
+			Protocol: ps.Attr.Protocol,
+			Version:  ps.Attr.Version,
+			Role:     ServiceRoleType(ps.Attr.Role),
+		}
+	}
+	for _, pn := range ps.Nodes {
+		n := &Node{
+			ID:       pn.Id,
+			Address:  pn.Address,
+			Port:     pn.Port,
+			Metadata: pn.Metadata,
+		}
+		if _, ok := n.Metadata[weightKey]; !ok && pn.Weight > 0 {
+			WithNodeMeta(weightKey, strconv.Itoa(int(pn.Weight)))(n)
+		}
+		s.Nodes = append(s.Nodes, n)
+	}
+
+	return &s, nil
+}
+
+// isJSON tells whether @data looks like a json object. A protobuf service
+// never begins with '{', which is the start group of the field 15.
+func isJSON(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
@@ -0,0 +1,144 @@
+package gxregistry
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry/pb"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+func codecService() *Service {
+	return &Service{
+		Attr: &ServiceAttr{
+			Group:    "bjtelecom",
+			Service:  "shopping",
+			Protocol: "pb",
+			Version:  "1.0.1",
+			Role:     SRT_Provider,
+		},
+		Nodes: []*Node{{
+			ID:      "node0",
+			Address: "127.0.0.1",
+			Port:    12000,
+			Metadata: map[string]string{
+				"weight": "200",
+				"zone":   "bj",
+				"idc":    "yizhuang",
+			},
+		}},
+		Metadata: map[string]string{"owner": "alex", "env": "test"},
+	}
+}
+
+// TestCodecGolden checks that the payloads of the codecs never change, run
+// it with -update after an intended change of the wire format.
+func TestCodecGolden(t *testing.T) {
+	for _, name := range []string{CodecJSON, CodecProtobuf} {
+		c := GetCodec(name)
+		if c == nil || c.Name() != name {
+			t.Fatalf("GetCodec(%s) = %v", name, c)
+		}
+		data, err := c.Encode(codecService())
+		if err != nil {
+			t.Fatalf("%s Encode() = error:%s", name, err)
+		}
+		golden := filepath.Join("testdata", "service."+name)
+		if *updateGolden {
+			if err = ioutil.WriteFile(golden, data, 0644); err != nil {
+				t.Fatalf("WriteFile(%s) = error:%s", golden, err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) = error:%s", golden, err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Fatalf("%s Encode() = %q, golden:%q", name, data, want)
+		}
+
+		// the golden payloads of both codecs are decoded by DecodeService
+		s, err := DecodeService(want)
+		if err != nil || !reflect.DeepEqual(s, codecService()) {
+			t.Fatalf("DecodeService(%s) = service:%+v, error:%v", golden, s, err)
+		}
+	}
+}
+
+func TestProtobufCodecWeight(t *testing.T) {
+	c := GetCodec(CodecProtobuf)
+	data, err := c.Encode(codecService())
+	if err != nil {
+		t.Fatalf("Encode() = error:%s", err)
+	}
+	var ps gxregistrypb.Service
+	if err = ps.Unmarshal(data); err != nil || ps.Nodes[0].Weight != 200 {
+		t.Fatalf("the encoded service:%+v, error:%v", ps, err)
+	}
+
+	// a client of other languages may set the weight field only
+	ps.Nodes[0].Metadata = nil
+	data, _ = ps.Marshal()
+	s, err := c.Decode(data)
+	if err != nil || s.Nodes[0].Metadata["weight"] != "200" {
+		t.Fatalf("Decode() = service:%+v, error:%v", s, err)
+	}
+}
+
+func TestDecodeServiceFallback(t *testing.T) {
+	if _, err := DecodeService(nil); err == nil {
+		t.Fatalf("DecodeService(nil) = nil error")
+	}
+	// json with leading spaces
+	data, _ := GetCodec(CodecJSON).Encode(codecService())
+	s, err := DecodeService(append([]byte(" \n"), data...))
+	if err != nil || !reflect.DeepEqual(s, codecService()) {
+		t.Fatalf("DecodeService() = service:%+v, error:%v", s, err)
+	}
+	if _, err = DecodeService([]byte("{\"Attr\":")); err == nil {
+		t.Fatalf("DecodeService(broken json) = nil error")
+	}
+}
+
+func TestDecodeServiceBy(t *testing.T) {
+	jsonCodec, pbCodec := GetCodec(CodecJSON), GetCodec(CodecProtobuf)
+	jsonData, _ := jsonCodec.Encode(codecService())
+	pbData, _ := pbCodec.Encode(codecService())
+
+	for _, c := range []struct {
+		codec Codec
+		data  []byte
+	}{
+		{jsonCodec, jsonData},
+		{pbCodec, pbData},
+		{nil, jsonData},
+		{nil, pbData},
+	} {
+		s, err := DecodeServiceBy(c.codec, c.data)
+		if err != nil || !reflect.DeepEqual(s, codecService()) {
+			t.Fatalf("DecodeServiceBy(%v) = service:%+v, error:%v", c.codec, s, err)
+		}
+	}
+
+	// a codec decodes its own payloads only
+	if s, err := DecodeServiceBy(jsonCodec, pbData); err == nil {
+		t.Fatalf("json DecodeServiceBy() of protobuf = service:%+v", s)
+	}
+	if s, err := DecodeServiceBy(pbCodec, jsonData); err == nil {
+		t.Fatalf("protobuf DecodeServiceBy() of json = service:%+v", s)
+	}
+	if _, err := DecodeServiceBy(jsonCodec, make([]byte, MaxServiceSize+1)); jerrors.Cause(err) != ErrorServiceTooLarge {
+		t.Fatalf("DecodeServiceBy() of a large payload = error:%v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxdebug provides a http debug handler of the registry components
+package gxdebug
+
+import (
+	"net/http"
+	"strconv"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+)
+
+const (
+	DefaultLimit = 100  // the items of a page by default
+	MaxLimit     = 1000 // the max items of a page
+)
+
+// component is a page of the items of an Inspection.
+type component struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Valid  bool          `json:"valid"`
+	Stale  bool          `json:"stale"`
+	State  interface{}   `json:"state"`
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Items  []interface{} `json:"items"`
+}
+
+type page struct {
+	Components []component `json:"components"`
+}
+
+type componentHealth struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Valid bool   `json:"valid"`
+	Stale bool   `json:"stale"`
+}
+
+type health struct {
+	Healthy    bool              `json:"healthy"`
+	Components []componentHealth `json:"components"`
+}
+
+type handler struct {
+	components []gxregistry.Inspectable
+}
+
+// NewHandler returns a handler of the state of @components in json:
+//
+//	/registry  the Inspections of gxregistry.InspectRegistry
+//	/watcher   the Inspections of gxregistry.InspectWatcher
+//	/services  the Inspections of gxregistry.InspectServices
+//	/health    whether all components are valid and not stale, 503 if not
+//
+// The items of an Inspection are paginated by the query parameters offset
+// and limit, e.g. "/services?offset=100&limit=100". The json is printed by
+// gxlog.JSONString, so its keys are sorted and the credentials are redacted.
+// Mount it under a prefix by http.StripPrefix.
+func NewHandler(components ...gxregistry.Inspectable) http.Handler {
+	h := &handler{components: components}
+
+	mux := http.NewServeMux()
+	for _, kind := range []string{
+		gxregistry.InspectRegistry,
+		gxregistry.InspectWatcher,
+		gxregistry.InspectServices,
+	} {
+		kind := kind
+		mux.HandleFunc("/"+kind, func(w http.ResponseWriter, r *http.Request) {
+			h.serveKind(w, r, kind)
+		})
+	}
+	mux.HandleFunc("/health", h.serveHealth)
+
+	return mux
+}
+
+// queryInt returns the int query parameter @name, @def if it is missing.
+func queryInt(r *http.Request, name string, def int) (int, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, true
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return 0, false
+	}
+
+	return i, true
+}
+
+func (h *handler) serveKind(w http.ResponseWriter, r *http.Request, kind string) {
+	offset, ok := queryInt(r, "offset", 0)
+	if !ok {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+	limit, ok := queryInt(r, "limit", DefaultLimit)
+	if !ok || limit == 0 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	p := page{Components: []component{}}
+	for _, c := range h.components {
+		ins := c.Inspect()
+		if ins.Kind != kind {
+			continue
+		}
+		total, start := len(ins.Items), offset
+		if start > total {
+			start = total
+		}
+		end := start + limit
+		if end > total {
+			end = total
+		}
+		p.Components = append(p.Components, component{
+			Kind:   ins.Kind,
+			Name:   ins.Name,
+			Valid:  ins.Valid,
+			Stale:  ins.Stale,
+			State:  ins.State,
+			Total:  total,
+			Offset: start,
+			Items:  append([]interface{}{}, ins.Items[start:end]...),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	hl := health{Healthy: true, Components: make([]componentHealth, 0, len(h.components))}
+	for _, c := range h.components {
+		ins := c.Inspect()
+		hl.Healthy = hl.Healthy && ins.Valid && !ins.Stale
+		hl.Components = append(hl.Components, componentHealth{
+			Kind:  ins.Kind,
+			Name:  ins.Name,
+			Valid: ins.Valid,
+			Stale: ins.Stale,
+		})
+	}
+
+	code := http.StatusOK
+	if !hl.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, hl)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	// the nested slices and maps are bounded like the pages
+	w.Write([]byte(gxlog.JSONString(v, gxlog.WithMaxSliceLen(MaxLimit))))
+}
@@ -0,0 +1,162 @@
+package gxdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+type fakeComponent struct {
+	ins gxregistry.Inspection
+}
+
+func (c *fakeComponent) Inspect() gxregistry.Inspection {
+	return c.ins
+}
+
+func get(t *testing.T, h http.Handler, url string) (int, string) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code == http.StatusOK && !json.Valid(body) {
+		t.Fatalf("GET %s = invalid json %s", url, body)
+	}
+	return w.Code, string(body)
+}
+
+func testComponents() (*fakeComponent, *fakeComponent, *fakeComponent) {
+	reg := &fakeComponent{gxregistry.Inspection{
+		Kind:  gxregistry.InspectRegistry,
+		Name:  "registry",
+		Valid: true,
+		State: map[string]interface{}{"root": "/test", "addrs": []string{"127.0.0.1:2181"}},
+	}}
+	watcher := &fakeComponent{gxregistry.Inspection{
+		Kind:  gxregistry.InspectWatcher,
+		Name:  "watcher",
+		Valid: true,
+		State: map[string]int{"pending": 0},
+	}}
+	var items []interface{}
+	for i := 0; i < 250; i++ {
+		items = append(items, gxregistry.Node{
+			ID:       fmt.Sprintf("node%03d", i),
+			Address:  "127.0.0.1",
+			Port:     int32(10000 + i),
+			Metadata: map[string]string{"token": "s3cret", "zone": "bj"},
+		})
+	}
+	services := &fakeComponent{gxregistry.Inspection{
+		Kind:  gxregistry.InspectServices,
+		Name:  "selector",
+		Valid: true,
+		Items: items,
+	}}
+
+	return reg, watcher, services
+}
+
+func TestHandlerKinds(t *testing.T) {
+	reg, watcher, services := testComponents()
+	h := NewHandler(reg, watcher, services)
+
+	code, body := get(t, h, "/registry")
+	if code != http.StatusOK {
+		t.Fatalf("GET /registry = %d", code)
+	}
+	want := `{"components":[{"kind":"registry","name":"registry","valid":true,"stale":false,` +
+		`"state":{"addrs":["127.0.0.1:2181"],"root":"/test"},"total":0,"offset":0,"items":[]}]}`
+	if body != want {
+		t.Fatalf("GET /registry = %s, want %s", body, want)
+	}
+	if _, body = get(t, h, "/watcher"); !strings.Contains(body, `"name":"watcher"`) || strings.Contains(body, "selector") {
+		t.Fatalf("GET /watcher = %s", body)
+	}
+	if code, _ = get(t, h, "/unknown"); code != http.StatusNotFound {
+		t.Fatalf("GET /unknown = %d", code)
+	}
+}
+
+func TestHandlerPagination(t *testing.T) {
+	_, _, services := testComponents()
+	h := NewHandler(services)
+
+	var p struct {
+		Components []struct {
+			Total  int
+			Offset int
+			Items  []gxregistry.Node
+		}
+	}
+	for _, c := range []struct {
+		url    string
+		offset int
+		ids    []string
+	}{
+		{"/services", 0, []string{"node000", "node099"}},
+		{"/services?offset=200&limit=30", 200, []string{"node200", "node229"}},
+		{"/services?offset=240&limit=30", 240, []string{"node240", "node249"}},
+		{"/services?offset=1000", 250, nil},
+	} {
+		_, body := get(t, h, c.url)
+		if err := json.Unmarshal([]byte(body), &p); err != nil {
+			t.Fatalf("GET %s = %s, error:%s", c.url, body, err)
+		}
+		comp := p.Components[0]
+		if comp.Total != 250 || comp.Offset != c.offset {
+			t.Fatalf("GET %s total:%d, offset:%d", c.url, comp.Total, comp.Offset)
+		}
+		if len(c.ids) == 0 {
+			if len(comp.Items) != 0 {
+				t.Fatalf("GET %s = %d items", c.url, len(comp.Items))
+			}
+			continue
+		}
+		if comp.Items[0].ID != c.ids[0] || comp.Items[len(comp.Items)-1].ID != c.ids[1] {
+			t.Fatalf("GET %s = items from %s to %s", c.url, comp.Items[0].ID, comp.Items[len(comp.Items)-1].ID)
+		}
+		if comp.Items[0].Metadata["token"] != "******" || comp.Items[0].Metadata["zone"] != "bj" {
+			t.Fatalf("GET %s metadata:%v", c.url, comp.Items[0].Metadata)
+		}
+	}
+
+	_, body := get(t, h, "/services?limit=5000")
+	if err := json.Unmarshal([]byte(body), &p); err != nil || len(p.Components[0].Items) != 250 {
+		t.Fatalf("GET ?limit=5000 = error:%v", err)
+	}
+	for _, url := range []string{"/services?offset=-1", "/services?limit=0", "/services?limit=x"} {
+		if code, _ := get(t, h, url); code != http.StatusBadRequest {
+			t.Fatalf("GET %s = %d", url, code)
+		}
+	}
+}
+
+func TestHandlerHealth(t *testing.T) {
+	reg, watcher, services := testComponents()
+	h := NewHandler(reg, watcher, services)
+
+	code, body := get(t, h, "/health")
+	if code != http.StatusOK || !strings.HasPrefix(body, `{"healthy":true,`) {
+		t.Fatalf("GET /health = %d %s", code, body)
+	}
+
+	services.ins.Stale = true
+	if code, body = get(t, h, "/health"); code != http.StatusServiceUnavailable ||
+		!strings.Contains(body, `{"kind":"services","name":"selector","valid":true,"stale":true}`) {
+		t.Fatalf("GET /health of stale services = %d %s", code, body)
+	}
+
+	services.ins.Stale = false
+	watcher.ins.Valid = false
+	if code, _ = get(t, h, "/health"); code != http.StatusServiceUnavailable {
+		t.Fatalf("GET /health of an invalid watcher = %d", code)
+	}
+}
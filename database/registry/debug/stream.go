@@ -0,0 +1,169 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxdebug provides a http debug handler of the registry components
+package gxdebug
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+)
+
+const (
+	// ContentTypeSSE is the content type of the server-sent events stream.
+	ContentTypeSSE = "text/event-stream"
+	// ContentTypeNDJSON is the content type of the newline delimited json
+	// stream.
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// streamEvent is the json of an event of the stream.
+type streamEvent struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Action  string          `json:"action"`
+	Service *serviceSummary `json:"service"`
+	Error   string          `json:"error"`
+	Dropped uint64          `json:"dropped"` // before this event
+}
+
+type serviceSummary struct {
+	Attr  gxregistry.ServiceAttr `json:"attr"`
+	Nodes []string               `json:"nodes"` // id@address:port
+}
+
+func summarize(e gxregistry.BroadcastEvent) streamEvent {
+	se := streamEvent{Seq: e.Seq, Time: e.Time, Dropped: e.Dropped}
+	if e.Err != nil {
+		se.Error = e.Err.Error()
+		return se
+	}
+	if e.Result == nil {
+		return se
+	}
+	se.Action = e.Result.Action.String()
+	if s := e.Result.Service; s != nil {
+		sum := &serviceSummary{Nodes: []string{}}
+		if s.Attr != nil {
+			sum.Attr = *s.Attr
+		}
+		for _, node := range s.Nodes {
+			if node != nil {
+				sum.Nodes = append(sum.Nodes, node.ID+"@"+net.JoinHostPort(node.Address, strconv.Itoa(int(node.Port))))
+			}
+		}
+		se.Service = sum
+	}
+
+	return se
+}
+
+type streamHandler struct {
+	b    *gxregistry.Broadcaster
+	size int
+}
+
+// NewEventStreamHandler returns a handler streaming the events of @b to
+// each client, which is a subscription of @size events, see
+// gxregistry.Broadcaster.Subscribe. Share a watcher among the clients by
+// wrapping it in a broadcaster, e.g.
+//
+//	b := gxregistry.NewBroadcaster(watcher)
+//	http.Handle("/debug/registry/events", gxdebug.NewEventStreamHandler(b, 0))
+//
+// The stream is of server-sent events, or of newline delimited json if the
+// Accept header of the request has ContentTypeNDJSON. Every event is a
+// json of gxlog.JSONString with its seq, time, action name and a summary
+// of its service, and the count of the events dropped before it when the
+// client is slow. The query parameters group, service, protocol, version
+// and role filter the events by their service attr, e.g.
+// "?service=shopping&role=SRT_Provider".
+func NewEventStreamHandler(b *gxregistry.Broadcaster, size int) http.Handler {
+	return &streamHandler{b: b, size: size}
+}
+
+// queryAttr returns the filter attr of the query parameters.
+func queryAttr(r *http.Request) (gxregistry.ServiceAttr, bool) {
+	q := r.URL.Query()
+	attr := gxregistry.ServiceAttr{
+		Group:    q.Get("group"),
+		Service:  q.Get("service"),
+		Protocol: q.Get("protocol"),
+		Version:  q.Get("version"),
+	}
+	if role := q.Get("role"); role != "" {
+		if attr.Role = gxregistry.String2ServiceRoleType(role); attr.Role == gxregistry.SRT_UNKOWN {
+			return attr, false
+		}
+	}
+
+	return attr, true
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	filter, ok := queryAttr(r)
+	if !ok {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+	ndjson := strings.Contains(r.Header.Get("Accept"), ContentTypeNDJSON)
+
+	sub := h.b.Subscribe(h.size)
+	defer sub.Close()
+
+	if ndjson {
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+	} else {
+		w.Header().Set("Content-Type", ContentTypeSSE)
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var dropped uint64 // the drops of the filtered events
+	for {
+		e, err := sub.Next(r.Context())
+		if err != nil {
+			// the client is gone, or the watcher is closed
+			return
+		}
+		if res := e.Result; res != nil && res.Service != nil && res.Service.Attr != nil &&
+			!filter.Filter(*res.Service.Attr) {
+			dropped += e.Dropped
+			continue
+		}
+		e.Dropped += dropped
+		dropped = 0
+
+		se := summarize(e)
+		data := gxlog.JSONString(se)
+		if ndjson {
+			_, err = fmt.Fprintf(w, "%s\n", data)
+		} else {
+			event := se.Action
+			if event == "" {
+				event = "error"
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", strconv.FormatUint(e.Seq, 10), event, data)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
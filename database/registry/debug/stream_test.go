@@ -0,0 +1,155 @@
+package gxdebug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+type chanWatcher struct {
+	events chan *gxregistry.EventResult
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (w *chanWatcher) Notify() (*gxregistry.EventResult, error) {
+	return w.NotifyCtx(context.Background())
+}
+
+func (w *chanWatcher) NotifyCtx(ctx context.Context) (*gxregistry.EventResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-w.done:
+		return nil, gxregistry.ErrWatcherClosed
+	case res := <-w.events:
+		return res, nil
+	}
+}
+
+func (w *chanWatcher) Valid() bool { return !w.IsClosed() }
+func (w *chanWatcher) Close()      { w.once.Do(func() { close(w.done) }) }
+
+func (w *chanWatcher) IsClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func event(action gxregistry.ServiceEventType, service, id string) *gxregistry.EventResult {
+	attr := gxregistry.ServiceAttr{Service: service, Role: gxregistry.SRT_Provider}
+	return &gxregistry.EventResult{
+		Action: action,
+		Service: &gxregistry.Service{
+			Attr:  &attr,
+			Nodes: []*gxregistry.Node{{ID: id, Address: "10.0.0.1", Port: 8080}},
+		},
+	}
+}
+
+// testEvent is the json of streamEvent, of the role name.
+type testEvent struct {
+	Seq     uint64 `json:"seq"`
+	Action  string `json:"action"`
+	Service struct {
+		Attr  map[string]string `json:"attr"`
+		Nodes []string          `json:"nodes"`
+	} `json:"service"`
+}
+
+// stream opens a stream of @url, and returns its lines.
+func stream(t *testing.T, url, accept string) (*http.Response, *bufio.Scanner) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s = error:%s", url, err)
+	}
+	return resp, bufio.NewScanner(resp.Body)
+}
+
+func waitSubscribers(t *testing.T, b *gxregistry.Broadcaster, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Subscribers() = %d, want %d", b.Subscribers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	w := &chanWatcher{events: make(chan *gxregistry.EventResult), done: make(chan struct{})}
+	b := gxregistry.NewBroadcaster(w)
+	srv := httptest.NewServer(NewEventStreamHandler(b, 0))
+	defer srv.Close()
+	defer b.Close() // ends the streams before the server closes
+
+	sseResp, sse := stream(t, srv.URL, "")
+	ndResp, nd := stream(t, srv.URL+"?service=shopping", ContentTypeNDJSON)
+	if ct := sseResp.Header.Get("Content-Type"); ct != ContentTypeSSE {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	if ct := ndResp.Header.Get("Content-Type"); ct != ContentTypeNDJSON {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	waitSubscribers(t, b, 2)
+
+	w.events <- event(gxregistry.ServiceAdd, "shopping", "node0")
+	w.events <- event(gxregistry.ServiceAdd, "payment", "node1")
+	w.events <- event(gxregistry.ServiceDel, "shopping", "node0")
+
+	// sse: id, event, data and a blank line of each event
+	var lines []string
+	for i := 0; i < 12 && sse.Scan(); i++ {
+		lines = append(lines, sse.Text())
+	}
+	if len(lines) != 12 || lines[0] != "id: 1" || lines[1] != "event: ServiceAdd" ||
+		lines[4] != "id: 2" || lines[9] != "event: ServiceDel" || lines[11] != "" {
+		t.Fatalf("sse lines:%q", lines)
+	}
+	var e testEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &e); err != nil ||
+		e.Seq != 1 || e.Service.Nodes[0] != "node0@10.0.0.1:8080" || e.Service.Attr["Role"] != "SRT_Provider" {
+		t.Fatalf("sse data %s = %+v, error:%v", lines[2], e, err)
+	}
+
+	// ndjson of the filtered events
+	for _, want := range []struct {
+		seq    uint64
+		action string
+	}{{1, "ServiceAdd"}, {3, "ServiceDel"}} {
+		if !nd.Scan() {
+			t.Fatalf("ndjson stream ends: %v", nd.Err())
+		}
+		var e testEvent
+		if err := json.Unmarshal(nd.Bytes(), &e); err != nil || e.Seq != want.seq || e.Action != want.action {
+			t.Fatalf("ndjson line %s = %+v, error:%v", nd.Text(), e, err)
+		}
+	}
+
+	// a disconnected client is unsubscribed
+	sseResp.Body.Close()
+	waitSubscribers(t, b, 1)
+	ndResp.Body.Close()
+	waitSubscribers(t, b, 0)
+
+	if resp, _ := stream(t, srv.URL+"?role=bad", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("GET ?role=bad = %d", resp.StatusCode)
+	}
+}
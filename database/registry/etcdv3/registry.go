@@ -50,6 +50,9 @@ func NewRegistry(opts ...gxregistry.Option) (gxregistry.Registry, error) {
 	if options.Timeout == 0 {
 		options.Timeout = gxregistry.DefaultTimeout
 	}
+	if options.Codec == nil {
+		options.Codec = gxregistry.GetCodec(gxregistry.CodecJSON)
+	}
 
 	var addrs []string
 	for _, addr := range options.Addrs {
@@ -267,16 +270,16 @@ func (r *Registry) register(s gxregistry.Service) error {
 	// serviceRegistry every node
 	for i, node := range s.Nodes {
 		service.Nodes = []*gxregistry.Node{node}
-		data, err := gxregistry.EncodeService(&service)
+		data, err := r.options.Codec.Encode(&service)
 		if err != nil {
 			service.Nodes = s.Nodes[:i]
 			r.unregister(service)
-			return jerrors.Annotatef(err, "gxregistry.EncodeService(service:%+v) = error:%s", service, err)
+			return jerrors.Annotatef(err, "%s codec Encode(service:%+v)", r.options.Codec.Name(), service)
 		}
 		_, err = r.client.EtcdClient().Put(
 			ctx,
 			service.NodePath(r.options.Root, *node),
-			data,
+			string(data),
 			ecv3.WithLease(r.client.Lease()),
 		)
 		if err != nil {
@@ -294,12 +297,16 @@ func (r *Registry) Register(s gxregistry.Service) error {
 	if len(s.Nodes) == 0 {
 		return jerrors.Errorf("Require at least one node")
 	}
+	s, err := r.options.AdvertiseService(s)
+	if err != nil {
+		return err
+	}
 
 	if _, exist := r.exist(s); exist {
 		return gxregistry.ErrorAlreadyRegister
 	}
 
-	err := r.register(s)
+	err = r.register(s)
 	if err != nil {
 		return jerrors.Annotate(err, "Registry.register")
 	}
@@ -329,6 +336,10 @@ func (r *Registry) unregister(s gxregistry.Service) error {
 }
 
 func (r *Registry) Deregister(s gxregistry.Service) error {
+	s, err := r.options.AdvertiseService(s)
+	if err != nil {
+		return err
+	}
 	r.deleteService(s)
 	return jerrors.Trace(r.unregister(s))
 }
@@ -106,6 +106,40 @@ func (suite *RegisterTestSuite) TestRegistry_Register() {
 	suite.Equalf(gxregistry.ErrorRegistryNotFound, err, "GetService(ServiceAttr:%#v)", suite.sa)
 }
 
+func (suite *RegisterTestSuite) TestRegistry_AdvertiseAddress() {
+	reg, err := NewRegistry(
+		gxregistry.WithAddrs([]string{"127.0.0.1:2379"}...),
+		gxregistry.WithTimeout(3e9),
+		gxregistry.WithRoot("/etcd_test"),
+		gxregistry.WithAdvertiseAddress("192.0.2.1"),
+	)
+	suite.Equal(nil, err, "NewRegistry()")
+	defer reg.Close()
+
+	// the loopback address of suite.node is advertised as 192.0.2.1
+	service := gxregistry.Service{Attr: &suite.sa, Nodes: []*gxregistry.Node{&suite.node}}
+	err = reg.Register(service)
+	suite.Equalf(nil, err, "Register(service:%+v)", service)
+	suite.Equalf("127.0.0.1", suite.node.Address, "Register() changes its service")
+	err = reg.Register(service)
+	suite.Equalf(gxregistry.ErrorAlreadyRegister, err, "Register(service:%+v)", service)
+
+	service1, err := reg.GetServices(suite.sa)
+	suite.Equalf(nil, err, "GetService(ServiceAttr:%#v)", suite.sa)
+	suite.Equalf(1, len(service1), "GetService(ServiceAttr:%+v)", suite.sa)
+	suite.Equalf("192.0.2.1", service1[0].Nodes[0].Address, "GetService(ServiceAttr:%+v)", suite.sa)
+
+	// the service is deregistered by its original node as well
+	err = reg.Deregister(service)
+	suite.Equalf(nil, err, "Deregister(service:%+v)", service)
+	_, err = reg.GetServices(suite.sa)
+	suite.Equalf(gxregistry.ErrorRegistryNotFound, err, "GetService(ServiceAttr:%#v)", suite.sa)
+	err = reg.Register(service)
+	suite.Equalf(nil, err, "Register(service:%+v) after Deregister", service)
+	err = reg.Deregister(service)
+	suite.Equalf(nil, err, "Deregister(service:%+v)", service)
+}
+
 func (suite *RegisterTestSuite) TestRegistry_EtcdRestart() {
 	fmt.Println("start to test etcd restart ... ")
 	node1 := gxregistry.Node{ID: "node1", Address: "127.0.0.1", Port: 12346}
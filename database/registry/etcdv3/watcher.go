@@ -8,6 +8,7 @@ package gxetcd
 
 import (
 	"context"
+	"os"
 	"strings"
 	"sync"
 )
@@ -19,6 +20,7 @@ import (
 	// ecv3 "github.com/coreos/etcd/clientv3"
 	jerrors "github.com/juju/errors"
 	ecv3 "go.etcd.io/etcd/clientv3"
+	"google.golang.org/grpc/connectivity"
 )
 
 // watcher的watch系列函数暴露给registry，而Next函数则暴露给selector
@@ -28,7 +30,11 @@ type Watcher struct {
 	w         ecv3.WatchChan
 	opts      gxregistry.WatchOptions
 	client    *gxetcd.Client
-	sync.Once // for Close
+	cache     *gxregistry.LocalCache    // nil without WithLocalCache
+	lock      sync.Mutex                // lock pending and stale
+	pending   []*ecv3.Event             // the events of a response not notified yet
+	stale     []*gxregistry.EventResult // of the local cache, notified first
+	sync.Once                           // for Close
 }
 
 func NewWatcher(client *gxetcd.Client, opts ...gxregistry.WatchOption) (gxregistry.Watcher, error) {
@@ -37,9 +43,14 @@ func NewWatcher(client *gxetcd.Client, opts ...gxregistry.WatchOption) (gxregist
 		o(&options)
 	}
 
-	if options.Root == "" {
-		options.Root = gxregistry.DefaultServiceRoot
+	roots, err := options.WatchRoots()
+	if err != nil {
+		return nil, jerrors.Trace(err)
 	}
+	if len(roots) != 1 {
+		return nil, jerrors.Errorf("etcdv3 watcher watches one root, not %v", roots)
+	}
+	options.Root = roots[0]
 
 	if client.TTL() < 0 {
 		// there is no lease
@@ -65,18 +76,54 @@ func NewWatcher(client *gxetcd.Client, opts ...gxregistry.WatchOption) (gxregist
 		opts:   options,
 		client: client,
 	}
+	if options.LocalCache != "" {
+		wc.cache = gxregistry.NewLocalCache(options.LocalCache)
+		if !wc.Valid() {
+			wc.loadStale()
+		}
+	}
 
 	return wc, nil
 }
 
 func (w *Watcher) Notify() (*gxregistry.EventResult, error) {
+	return w.NotifyCtx(context.Background())
+}
+
+// NotifyCtx returns the next event of the filter of the watcher, ctx.Err()
+// when @ctx is done before it, or gxregistry.ErrWatcherClosed after Close.
+func (w *Watcher) NotifyCtx(ctx context.Context) (*gxregistry.EventResult, error) {
 	var (
-		err     error
-		service *gxregistry.Service
-		action  gxregistry.ServiceEventType
+		ok  bool
+		msg ecv3.WatchResponse
 	)
 
-	for msg := range w.w {
+	for {
+		if res := w.nextStale(); res != nil {
+			return res, nil
+		}
+		for ev := w.next(); ev != nil; ev = w.next() {
+			res, err := w.result(ev)
+			if res != nil && w.cache != nil {
+				if e := w.cache.Update(res); e != nil {
+					log.Warn("failed to write the local cache %s, error:%s", w.opts.LocalCache, jerrors.ErrorStack(e))
+				}
+			}
+			if res != nil || err != nil {
+				return res, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-w.done:
+			return nil, gxregistry.ErrWatcherClosed
+		case msg, ok = <-w.w:
+		}
+		if !ok {
+			return nil, jerrors.Errorf("could not get next")
+		}
 		if w.IsClosed() {
 			return nil, gxregistry.ErrWatcherClosed
 		}
@@ -85,49 +132,133 @@ func (w *Watcher) Notify() (*gxregistry.EventResult, error) {
 			return nil, msg.Err()
 		}
 
-		for _, ev := range msg.Events {
-			switch ev.Type {
-			case ecv3.EventTypePut:
-				if ev.IsCreate() {
-					action = gxregistry.ServiceAdd
-				} else if ev.IsModify() {
-					action = gxregistry.ServiceUpdate
-				}
+		w.lock.Lock()
+		w.pending = append(w.pending, msg.Events...)
+		w.lock.Unlock()
+	}
+}
 
-				service, err = gxregistry.DecodeService(ev.Kv.Value)
-				if err != nil || service == nil {
-					log.Warn("gxregistry.DecodeService() = {service:%p, error:%+v}",
-						service, jerrors.ErrorStack(err))
-					continue
-				}
+// loadStale loads the services of the local cache as the stale events. A
+// corrupted cache is ignored.
+func (w *Watcher) loadStale() {
+	services, err := gxregistry.LoadLocalCache(w.opts.LocalCache)
+	if err != nil {
+		if !os.IsNotExist(jerrors.Cause(err)) {
+			log.Warn("ignore the local cache %s, error:%s", w.opts.LocalCache, jerrors.ErrorStack(err))
+		}
+		return
+	}
+	w.stale = gxregistry.StaleEvents(services, w.opts.Root, w.opts.Filter)
+}
 
-			case ecv3.EventTypeDelete:
-				action = gxregistry.ServiceDel
+// nextStale pops the first stale event, nil if there is none.
+func (w *Watcher) nextStale() *gxregistry.EventResult {
+	w.lock.Lock()
+	defer w.lock.Unlock()
 
-				// get service from prevKv
-				service, err = gxregistry.DecodeService(ev.PrevKv.Value)
-				if err != nil || service == nil {
-					log.Warn("gxregistry.DecodeService() = {service:%p, error:%+v}",
-						service, jerrors.ErrorStack(err))
-					continue
-				}
-			}
+	if len(w.stale) == 0 {
+		return nil
+	}
+	res := w.stale[0]
+	w.stale = w.stale[1:]
+
+	return res
+}
+
+// next pops the first pending event, nil if there is none.
+func (w *Watcher) next() *ecv3.Event {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+	ev := w.pending[0]
+	w.pending[0] = nil
+	w.pending = w.pending[1:]
+
+	return ev
+}
+
+// result translates @ev to the event of its service, and returns nil if the
+// service is not of the filter or the actions of the watcher, the same as
+// the zookeeper watcher, or failed to decode.
+func (w *Watcher) result(ev *ecv3.Event) (*gxregistry.EventResult, error) {
+	var (
+		err      error
+		data     []byte
+		service  *gxregistry.Service
+		action   gxregistry.ServiceEventType
+		revision int64
+	)
 
-			return &gxregistry.EventResult{
-				Action:  action,
-				Service: service,
-			}, nil
+	switch ev.Type {
+	case ecv3.EventTypePut:
+		action = gxregistry.ServiceAdd
+		if ev.IsModify() {
+			action = gxregistry.ServiceUpdate
 		}
+		data = ev.Kv.Value
+		revision = ev.Kv.ModRevision
+
+	case ecv3.EventTypeDelete:
+		action = gxregistry.ServiceDel
+		// get service from prevKv
+		if ev.PrevKv != nil {
+			data = ev.PrevKv.Value
+			revision = ev.PrevKv.ModRevision
+		}
+	}
+	if !w.opts.WatchAction(action) {
+		return nil, nil
+	}
+	if ev.Type == ecv3.EventTypeDelete && ev.PrevKv == nil {
+		// the previous value has been compacted, so the service is unknown,
+		// which is not a decode error
+		log.Warn("no previous value of the deleted key %s", ev.Kv.Key)
+		return nil, nil
+	}
+
+	service, err = gxregistry.DecodeServiceBy(w.opts.Codec, data)
+	if err != nil || service == nil {
+		log.Warn("gxregistry.DecodeService() = {service:%p, error:%+v}",
+			service, jerrors.ErrorStack(err))
+		if err != nil && w.opts.DecodeErrors {
+			return nil, jerrors.Annotatef(gxregistry.ErrorServiceDecode, "key:%s, error:%s", ev.Kv.Key, err)
+		}
+		return nil, nil
+	}
+
+	conf := w.opts.Filter
+	if !conf.MeshFilter(*service.Attr) {
+		// Fix: just filter service & role. database/filter/pool/filter.go:Filter::copy
+		// will use Filter to get valid service. 2018/10/18
+		log.Debug("service{%#v} is not compatible with Config{%#v}", service, conf)
+		return nil, nil
 	}
 
-	return nil, jerrors.Errorf("could not get next")
+	return &gxregistry.EventResult{
+		Action:   action,
+		Service:  service,
+		Root:     w.opts.Root,
+		Revision: revision,
+	}, nil
 }
 
+// Valid returns whether the lease of the client is alive, and the client
+// is connected to etcd.
 func (w *Watcher) Valid() bool {
 	if w.IsClosed() {
 		return false
 	}
 
+	if conn := w.client.EtcdClient().ActiveConnection(); conn != nil {
+		switch conn.GetState() {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return false
+		}
+	}
+
 	return w.client.TTL() > 0
 }
 
@@ -1,6 +1,7 @@
 package gxetcd
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -9,7 +10,10 @@ import (
 
 import (
 	"github.com/AlexStocks/goext/database/registry"
+	jerrors "github.com/juju/errors"
 	"github.com/stretchr/testify/suite"
+	ecv3 "go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
 )
 
 type WatcherTestSuite struct {
@@ -185,3 +189,93 @@ func (suite *WatcherTestSuite) TestNotify() {
 func TestWatcherTestSuite(t *testing.T) {
 	suite.Run(t, new(WatcherTestSuite))
 }
+
+// newChanWatcher returns a watcher of the responses sent to its channel.
+func newChanWatcher(opts gxregistry.WatchOptions) (*Watcher, chan ecv3.WatchResponse) {
+	ch := make(chan ecv3.WatchResponse, 1)
+	return &Watcher{
+		done:   make(chan struct{}),
+		cancel: func() {},
+		w:      ch,
+		opts:   opts,
+	}, ch
+}
+
+func putEvent(t *testing.T, attr gxregistry.ServiceAttr, id string, version int64) *ecv3.Event {
+	service := gxregistry.Service{Attr: &attr, Nodes: []*gxregistry.Node{{ID: id}}}
+	data, err := gxregistry.GetCodec(gxregistry.CodecJSON).Encode(&service)
+	if err != nil {
+		t.Fatalf("Encode() = error:%s", err)
+	}
+	return &ecv3.Event{
+		Type: ecv3.EventTypePut,
+		Kv: &mvccpb.KeyValue{
+			Key:            []byte(service.NodePath("/test", *service.Nodes[0])),
+			Value:          data,
+			CreateRevision: 1,
+			ModRevision:    version,
+			Version:        version,
+		},
+	}
+}
+
+func TestChanWatcherEvents(t *testing.T) {
+	provider := gxregistry.ServiceAttr{Group: "bjtelecom", Service: "shopping", Version: "1.0.1", Role: gxregistry.SRT_Provider}
+	other := provider
+	other.Service = "payment"
+	w, ch := newChanWatcher(gxregistry.WatchOptions{
+		Root:   "/test",
+		Filter: gxregistry.ServiceAttr{Service: "shopping", Role: gxregistry.SRT_Provider},
+	})
+
+	add := putEvent(t, provider, "node0", 1)
+	del := &ecv3.Event{Type: ecv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: add.Kv.Key}, PrevKv: add.Kv}
+	// all the events of a response are notified, but those of the other
+	// services
+	ch <- ecv3.WatchResponse{Events: []*ecv3.Event{
+		add,
+		putEvent(t, other, "node1", 1),
+		putEvent(t, provider, "node0", 2),
+		del,
+	}}
+	for _, want := range []gxregistry.ServiceEventType{gxregistry.ServiceAdd, gxregistry.ServiceUpdate, gxregistry.ServiceDel} {
+		res, err := w.Notify()
+		if err != nil || res.Action != want || res.Service.Nodes[0].ID != "node0" || res.Root != "/test" {
+			t.Fatalf("Notify() = %s, error:%v, want %s", res.GoString(), err, want)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := w.NotifyCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("NotifyCtx() without events = error:%v", err)
+	}
+
+	w.Close()
+	if _, err := w.Notify(); err != gxregistry.ErrWatcherClosed {
+		t.Fatalf("Notify() after Close() = error:%v", err)
+	}
+}
+
+func TestChanWatcherDecodeErrors(t *testing.T) {
+	broken := &ecv3.Event{Type: ecv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("/test/a/b"), Value: []byte("{")}}
+	provider := gxregistry.ServiceAttr{Service: "shopping", Role: gxregistry.SRT_Provider}
+
+	// the broken services are skipped by default
+	w, ch := newChanWatcher(gxregistry.WatchOptions{Root: "/test"})
+	ch <- ecv3.WatchResponse{Events: []*ecv3.Event{broken, putEvent(t, provider, "node0", 1)}}
+	if res, err := w.Notify(); err != nil || res.Service.Nodes[0].ID != "node0" {
+		t.Fatalf("Notify() = %s, error:%v", res.GoString(), err)
+	}
+
+	// a deletion without the previous value is skipped, not a decode error
+	compacted := &ecv3.Event{Type: ecv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("/test/a/b")}}
+	w, ch = newChanWatcher(gxregistry.WatchOptions{Root: "/test", DecodeErrors: true})
+	ch <- ecv3.WatchResponse{Events: []*ecv3.Event{compacted, broken, putEvent(t, provider, "node0", 1)}}
+	if _, err := w.Notify(); jerrors.Cause(err) != gxregistry.ErrorServiceDecode {
+		t.Fatalf("Notify() of a broken service = error:%v", err)
+	}
+	if res, err := w.Notify(); err != nil || res.Service.Nodes[0].ID != "node0" {
+		t.Fatalf("Notify() after a broken service = %s, error:%v", res.GoString(), err)
+	}
+}
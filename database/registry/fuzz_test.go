@@ -0,0 +1,119 @@
+package gxregistry
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry/pb"
+)
+
+// The seeds of the fuzzers cover what their runs have found, and
+// testdata/fuzz keeps the inputs of the bugs fixed. Run them by
+//
+//	go test -run XXX -fuzz FuzzUnmarshalPath -fuzztime 10m
+//	go test -run XXX -fuzz FuzzDecodeService -fuzztime 10m
+
+func FuzzUnmarshalPath(f *testing.F) {
+	path, _ := codecService().Attr.MarshalPath()
+	f.Add(path)
+	f.Add([]byte("group%3D%26%26%26"))
+	f.Add([]byte("service%3D%FF%FE"))
+	f.Add([]byte("%zz"))
+	f.Add([]byte("v2:bj%2Ftelecom;%E4%B8%96;;%;SRT_Provider;"))
+	f.Add([]byte("v9:;;;;"))
+	f.Add(bytes.Repeat([]byte("%26"), MaxAttrPathSegments))
+	f.Add([]byte("group%3D%20%20"))
+	f.Add([]byte("group%3Da%26group%3Db"))
+	f.Add([]byte("protocol%3D0%26role%3DSRT_Provider"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var attr ServiceAttr
+		err := attr.UnmarshalPath(data)
+		var invalid *InvalidAttrError
+		if errors.As(err, &invalid) && utf8.ValidString(invalid.Value) {
+			t.Fatalf("UnmarshalPath(%q) = error:%v of a valid value", data, err)
+		}
+		if err != nil {
+			if attr != (ServiceAttr{}) {
+				t.Fatalf("UnmarshalPath(%q) = error:%v, attr:%+v", data, err, attr)
+			}
+			return
+		}
+		if attr.Validate() != nil {
+			t.Fatalf("UnmarshalPath(%q) = invalid attr:%+v", data, attr)
+		}
+
+		// the path of an unmarshaled attr is stable
+		path, err := attr.MarshalPath()
+		if err != nil {
+			t.Fatalf("MarshalPath(%+v) = error:%s", attr, err)
+		}
+		var attr2 ServiceAttr
+		if err = attr2.UnmarshalPath(path); err != nil || attr2 != attr {
+			t.Fatalf("UnmarshalPath(%q) = attr:%+v, error:%v, want:%+v", path, attr2, err, attr)
+		}
+	})
+}
+
+func FuzzDecodeService(f *testing.F) {
+	for _, name := range []string{CodecJSON, CodecProtobuf} {
+		data, _ := GetCodec(name).Encode(codecService())
+		f.Add(data)
+	}
+	f.Add([]byte(`{"Attr":null,"Nodes":[null]}`))
+	f.Add([]byte(`{"Attr":{"Service":"\xff"}}`))
+	f.Add([]byte{0x12, 0x00})
+	f.Add([]byte{})
+	// a protobuf node of a weight but no metadata
+	ps := gxregistrypb.Service{
+		Attr:  &gxregistrypb.ServiceAttr{Service: "shopping"},
+		Nodes: []*gxregistrypb.Node{{Id: "node0", Weight: 200}},
+	}
+	data, _ := ps.Marshal()
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := DecodeService(data)
+		if err != nil {
+			if s != nil {
+				t.Fatalf("DecodeService(%q) = service:%+v, error:%s", data, s, err)
+			}
+			return
+		}
+		if validService(s) != nil {
+			t.Fatalf("DecodeService(%q) = invalid service:%+v", data, s)
+		}
+
+		// a decoded service can be registered again
+		for _, name := range []string{CodecJSON, CodecProtobuf} {
+			data, err := GetCodec(name).Encode(s)
+			if err != nil {
+				t.Fatalf("%s Encode(%+v) = error:%s", name, s, err)
+			}
+			if _, err = DecodeService(data); err != nil {
+				t.Fatalf("DecodeService(%s %q) = error:%s", name, data, err)
+			}
+		}
+	})
+}
+
+func TestDecodeServiceLimits(t *testing.T) {
+	data := append([]byte(`{"Metadata":{"key":"`), bytes.Repeat([]byte("x"), MaxServiceSize)...)
+	if _, err := DecodeService(data); err == nil {
+		t.Fatalf("DecodeService() of %d bytes = nil error", len(data))
+	}
+
+	var attr ServiceAttr
+	err := attr.UnmarshalPath([]byte("group%3Dbj%26service%3D%FF"))
+	var invalid *InvalidAttrError
+	if !errors.As(err, &invalid) || invalid.Field != "service" {
+		t.Fatalf("UnmarshalPath() of invalid utf-8 = error:%v", err)
+	}
+	if err = attr.UnmarshalPath(bytes.Repeat([]byte("%26"), MaxAttrPathSegments)); err == nil {
+		t.Fatalf("UnmarshalPath() of %d segments = nil error", MaxAttrPathSegments+1)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/os/process"
+	"github.com/AlexStocks/goext/time"
+)
+
+// The keys of the load of a provider in the Metadata of its nodes.
+const (
+	LoadCPUKey        = "load.cpu"
+	LoadRSSKey        = "load.rss"
+	LoadGoroutinesKey = "load.goroutines"
+)
+
+// Load is the resource usage of a provider process.
+type Load struct {
+	CPUPercent float64 // of all the CPUs, in [0, 100]
+	RSS        uint64
+	Goroutines int
+}
+
+// LoadProbe returns the current load of the provider.
+type LoadProbe func() (Load, error)
+
+// LoadThreshold is the least change of a load to rewrite the nodes.
+type LoadThreshold struct {
+	CPUPercent float64
+	RSSRatio   float64 // of the RSS last written
+	Goroutines int
+}
+
+// DefaultLoadThreshold is the threshold of a Heartbeat by default.
+var DefaultLoadThreshold = LoadThreshold{CPUPercent: 5, RSSRatio: 0.1, Goroutines: 100}
+
+// exceeds returns whether @l changes from @last beyond @t.
+func (t LoadThreshold) exceeds(last, l Load) bool {
+	if math.Abs(l.CPUPercent-last.CPUPercent) >= t.CPUPercent {
+		return true
+	}
+	if math.Abs(float64(l.RSS)-float64(last.RSS)) >= t.RSSRatio*float64(last.RSS) {
+		return true
+	}
+	n := l.Goroutines - last.Goroutines
+	return n >= t.Goroutines || -n >= t.Goroutines
+}
+
+// setMetadata writes @l into @node.
+func (l Load) setMetadata(node *Node) {
+	if node.Metadata == nil {
+		node.Metadata = make(map[string]string)
+	}
+	node.Metadata[LoadCPUKey] = strconv.FormatFloat(l.CPUPercent, 'f', 1, 64)
+	node.Metadata[LoadRSSKey] = strconv.FormatUint(l.RSS, 10)
+	node.Metadata[LoadGoroutinesKey] = strconv.Itoa(l.Goroutines)
+}
+
+// NodeLoad returns the load in the Metadata of @node, and whether it has
+// a valid one.
+func NodeLoad(node *Node) (Load, bool) {
+	var (
+		l   Load
+		err error
+	)
+	if l.CPUPercent, err = strconv.ParseFloat(node.Metadata[LoadCPUKey], 64); err != nil ||
+		l.CPUPercent < 0 || l.CPUPercent > 100 {
+		return Load{}, false
+	}
+	if l.RSS, err = strconv.ParseUint(node.Metadata[LoadRSSKey], 10, 64); err != nil {
+		return Load{}, false
+	}
+	if l.Goroutines, err = strconv.Atoi(node.Metadata[LoadGoroutinesKey]); err != nil {
+		return Load{}, false
+	}
+
+	return l, true
+}
+
+// ProcessLoadProbe returns a probe of the current process by gxprocess. The
+// CPU usage is that since the previous probe, 0 for the first one.
+func ProcessLoadProbe() (LoadProbe, error) {
+	p, err := gxprocess.FindProcess(os.Getpid())
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "gxprocess.FindProcess(pid:%d)", os.Getpid())
+	}
+
+	var (
+		lock     sync.Mutex
+		lastCPU  time.Duration
+		lastTime time.Time
+	)
+	return func() (Load, error) {
+		user, system, err := p.CPUTimes()
+		if err != nil {
+			return Load{}, jerrors.Trace(err)
+		}
+		mem, err := p.MemoryInfo()
+		if err != nil {
+			return Load{}, jerrors.Trace(err)
+		}
+
+		l := Load{RSS: mem.RSS, Goroutines: runtime.NumGoroutine()}
+		lock.Lock()
+		now := time.Now()
+		if !lastTime.IsZero() {
+			busy := user + system - lastCPU
+			l.CPUPercent = 100 * float64(busy) / float64(now.Sub(lastTime)) / float64(runtime.NumCPU())
+		}
+		lastCPU, lastTime = user+system, now
+		lock.Unlock()
+
+		return l, nil
+	}, nil
+}
+
+//////////////////////////////////////////
+// Heartbeat
+//////////////////////////////////////////
+
+// Heartbeat reports the load of a registered service periodically: it
+// writes the load of the probe into the Metadata of every node of the
+// service, and registers the service again when the load changes beyond
+// its threshold. The load goes to the nodes rather than the service, as
+// the selectors pick nodes, see gxselector.LoadWeighted.
+//
+// The registries have no update of a node, so the service is deregistered
+// before it is registered again, and its watchers see a ServiceDel and a
+// ServiceAdd of every node.
+type Heartbeat struct {
+	r         Registry
+	probe     LoadProbe
+	interval  time.Duration
+	clock     gxtime.Clock
+	threshold LoadThreshold
+	logger    gxlog.Logger
+
+	sync.Mutex         // for Beat
+	service    Service // the service registered
+	last       *Load   // nil before the first write
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type HeartbeatOption func(*Heartbeat)
+
+// WithHeartbeatClock ticks the heartbeat by @c, gxtime.RealClock by default.
+func WithHeartbeatClock(c gxtime.Clock) HeartbeatOption {
+	return func(h *Heartbeat) {
+		h.clock = c
+	}
+}
+
+// WithLoadThreshold rewrites the nodes when the load changes beyond @t,
+// DefaultLoadThreshold by default.
+func WithLoadThreshold(t LoadThreshold) HeartbeatOption {
+	return func(h *Heartbeat) {
+		h.threshold = t
+	}
+}
+
+// NewHeartbeat returns a heartbeat of the service @s registered to @r,
+// which beats every @interval after Start.
+func NewHeartbeat(r Registry, s Service, interval time.Duration, probe LoadProbe,
+	opts ...HeartbeatOption) (*Heartbeat, error) {
+
+	if interval <= 0 {
+		return nil, jerrors.Errorf("illegal heartbeat interval %v", interval)
+	}
+	if len(s.Nodes) == 0 {
+		return nil, jerrors.Errorf("Require at least one node")
+	}
+
+	h := &Heartbeat{
+		r:         r,
+		probe:     probe,
+		interval:  interval,
+		clock:     gxtime.RealClock{},
+		threshold: DefaultLoadThreshold,
+		logger:    r.Options().Logger,
+		service:   *s.Copy(),
+		done:      make(chan struct{}),
+	}
+	if h.logger == nil {
+		h.logger = gxlog.Default()
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
+}
+
+// Start beats in a goroutine until Stop.
+func (h *Heartbeat) Start() {
+	h.wg.Add(1)
+	go h.run()
+}
+
+func (h *Heartbeat) run() {
+	defer h.wg.Done()
+
+	// one beat after a stall of the process, not a burst of them
+	ticker := gxtime.NewIntervalTicker(h.interval, gxtime.WithIntervalTickerClock(h.clock))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C():
+		}
+
+		if err := h.Beat(); err != nil {
+			h.logger.Warnw("registry heartbeat failed", "service", h.Service().Attr, "error", gxlog.ErrorField(err))
+		}
+	}
+}
+
+// Beat probes the load, and registers the service with it if it changes
+// beyond the threshold. The first beat always registers it.
+func (h *Heartbeat) Beat() error {
+	l, err := h.probe()
+	if err != nil {
+		return jerrors.Annotate(err, "probe load")
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.last != nil && !h.threshold.exceeds(*h.last, l) {
+		return nil
+	}
+
+	service := *h.service.Copy()
+	for _, node := range service.Nodes {
+		l.setMetadata(node)
+	}
+	if err = h.r.Deregister(h.service); err != nil {
+		return jerrors.Annotate(err, "Deregister")
+	}
+	if err = h.r.Register(service); err != nil {
+		// registers the old one again, which keeps the provider available
+		// at least
+		if e := h.r.Register(h.service); e != nil {
+			gxlog.LogError(h.logger, "register service again failed", e, "service", h.service.Attr)
+		}
+		return jerrors.Annotate(err, "Register")
+	}
+	h.service, h.last = service, &l
+
+	return nil
+}
+
+// Service returns the service registered at last.
+func (h *Heartbeat) Service() Service {
+	h.Lock()
+	defer h.Unlock()
+
+	return *h.service.Copy()
+}
+
+// Stop stops the beats, the service is kept registered.
+func (h *Heartbeat) Stop() {
+	h.once.Do(func() {
+		close(h.done)
+	})
+	h.wg.Wait()
+}
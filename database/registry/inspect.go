@@ -0,0 +1,31 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+// the kinds of Inspection
+const (
+	InspectRegistry = "registry"
+	InspectWatcher  = "watcher"
+	InspectServices = "services" // the node table of a selector or cache
+)
+
+// Inspection is the json serializable snapshot of a registry component.
+type Inspection struct {
+	Kind  string      `json:"kind"`
+	Name  string      `json:"name"`
+	Valid bool        `json:"valid"`
+	Stale bool        `json:"stale"` // serving the last known services
+	State interface{} `json:"state"`
+	// Items is the list of the component which may be huge, e.g. the
+	// nodes of the services, in a deterministic order to be paginated.
+	Items []interface{} `json:"items"`
+}
+
+// Inspectable is a component of a registry showing its state, e.g. by the
+// debug handler.
+type Inspectable interface {
+	Inspect() Inspection
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"expvar"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+// MetricsHook observes the events of the watchers of a registry. It is
+// called concurrently, out of the locks of the watchers, and must not block,
+// as the watch goroutines wait for it. Embed NopMetricsHook to observe some
+// of the events only.
+type MetricsHook interface {
+	// WatchEvent is called when a watcher sends an event of @action.
+	WatchEvent(action ServiceEventType)
+	// WatchDropped is called when a watcher drops an event of the registry,
+	// e.g. a service node it failed to get or decode.
+	WatchDropped()
+	// WatchDecodeError is called when a watcher fails to decode a service
+	// node, which is dropped as well, or a service path.
+	WatchDecodeError()
+	// WatchReconnect is called when a watcher rewatches the registry.
+	WatchReconnect()
+	// WatchLag is called when an event is notified, @lag after it was sent,
+	// so it is called once of every event notified.
+	WatchLag(lag time.Duration)
+}
+
+// NopMetricsHook is the MetricsHook observing nothing, the default of the
+// watchers.
+type NopMetricsHook struct{}
+
+func (NopMetricsHook) WatchEvent(ServiceEventType) {}
+func (NopMetricsHook) WatchDropped()               {}
+func (NopMetricsHook) WatchDecodeError()           {}
+func (NopMetricsHook) WatchReconnect()             {}
+func (NopMetricsHook) WatchLag(time.Duration)      {}
+
+// ExpvarMetrics is the MetricsHook publishing the counters of the watchers
+// as an expvar map:
+//
+//	events.ServiceAdd    the events sent, by action
+//	notified             the events notified
+//	lag_ns               the total lag of the events notified
+//	dropped              the events dropped
+//	decode_errors        the service nodes and paths failed to decode
+//	reconnects           the rewatches
+//
+// e.g.
+//
+//	r, err := gxzookeeper.NewRegistry(
+//		gxregistry.WithAddrs("127.0.0.1:2181"),
+//		gxregistry.WithMetricsHook(gxregistry.NewExpvarMetrics("gxregistry")),
+//	)
+type ExpvarMetrics struct {
+	events       [ServiceUpdate + 1]expvar.Int // by action
+	notified     expvar.Int
+	lag          expvar.Int
+	dropped      expvar.Int
+	decodeErrors expvar.Int
+	reconnects   expvar.Int
+}
+
+// NewExpvarMetrics returns the metrics published as the expvar map @name.
+// It panics if @name is published already, as expvar.Publish.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{}
+	vars := expvar.NewMap(name)
+	for action := range m.events {
+		vars.Set("events."+ServiceEventType(action).String(), &m.events[action])
+	}
+	vars.Set("notified", &m.notified)
+	vars.Set("lag_ns", &m.lag)
+	vars.Set("dropped", &m.dropped)
+	vars.Set("decode_errors", &m.decodeErrors)
+	vars.Set("reconnects", &m.reconnects)
+
+	return m
+}
+
+func (m *ExpvarMetrics) WatchEvent(action ServiceEventType) {
+	if 0 <= action && int(action) < len(m.events) {
+		m.events[action].Add(1)
+	}
+}
+
+func (m *ExpvarMetrics) WatchDropped() {
+	m.dropped.Add(1)
+}
+
+func (m *ExpvarMetrics) WatchDecodeError() {
+	m.decodeErrors.Add(1)
+}
+
+func (m *ExpvarMetrics) WatchReconnect() {
+	m.reconnects.Add(1)
+}
+
+func (m *ExpvarMetrics) WatchLag(lag time.Duration) {
+	m.notified.Add(1)
+	m.lag.Add(int64(lag))
+}
+
+// the ops of OpObserver
+const (
+	OpRegister    = "register"
+	OpDeregister  = "deregister"
+	OpGetServices = "get_services"
+	OpWatch       = "watch"
+	// OpReregister is the registration of a service again after the
+	// session expires
+	OpReregister = "reregister"
+	// the ops of BatchRegistry
+	OpRegisterBatch   = "register_batch"
+	OpUnregisterBatch = "unregister_batch"
+)
+
+// OpObserver observes the ops of a registry. It is called concurrently, and
+// should not block.
+type OpObserver interface {
+	// ObserveOp is called when @op returns @err after @d.
+	ObserveOp(op string, d time.Duration, err error)
+}
+
+// ErrorClass returns the class of an error of a registry op, to be a
+// metrics label of few values.
+func ErrorClass(err error) string {
+	if err == nil {
+		return "none"
+	}
+
+	switch jerrors.Cause(err) {
+	case ErrorRegistryNotFound:
+		return "not_found"
+	case ErrorAlreadyRegister:
+		return "already_registered"
+	case ErrWatcherClosed:
+		return "closed"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,73 @@
+package gxregistry
+
+import (
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+func TestErrorClass(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		class string
+	}{
+		{nil, "none"},
+		{ErrorRegistryNotFound, "not_found"},
+		{jerrors.Annotate(ErrorAlreadyRegister, "Register"), "already_registered"},
+		{jerrors.Trace(ErrWatcherClosed), "closed"},
+		{fmt.Errorf("zk: connection closed"), "other"},
+	} {
+		if class := ErrorClass(c.err); class != c.class {
+			t.Fatalf("ErrorClass(%v) = %s, want %s", c.err, class, c.class)
+		}
+	}
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics("gxregistry_test")
+	m.WatchEvent(ServiceAdd)
+	m.WatchEvent(ServiceAdd)
+	m.WatchEvent(ServiceDel)
+	m.WatchEvent(ServiceEventType(100))
+	m.WatchLag(time.Millisecond)
+	m.WatchLag(2 * time.Millisecond)
+	m.WatchDropped()
+	m.WatchDecodeError()
+	m.WatchReconnect()
+
+	vars := expvar.Get("gxregistry_test").(*expvar.Map)
+	for key, n := range map[string]string{
+		"events.ServiceAdd":    "2",
+		"events.ServiceDel":    "1",
+		"events.ServiceUpdate": "0",
+		"notified":             "2",
+		"lag_ns":               "3000000",
+		"dropped":              "1",
+		"decode_errors":        "1",
+		"reconnects":           "1",
+	} {
+		if v := vars.Get(key); v == nil || v.String() != n {
+			t.Fatalf("%s = %v, want %s", key, v, n)
+		}
+	}
+}
+
+func TestMetricsHookAllocs(t *testing.T) {
+	for _, h := range []MetricsHook{NopMetricsHook{}, NewExpvarMetrics("gxregistry_test_allocs")} {
+		allocs := testing.AllocsPerRun(100, func() {
+			h.WatchEvent(ServiceAdd)
+			h.WatchDropped()
+			h.WatchDecodeError()
+			h.WatchReconnect()
+			h.WatchLag(time.Millisecond)
+		})
+		if allocs != 0 {
+			t.Fatalf("%T allocs %v times", h, allocs)
+		}
+	}
+}
@@ -6,23 +6,115 @@
 package gxregistry
 
 import (
+	"path"
+	"strings"
 	"time"
 )
 
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/time"
+)
+
 type Options struct {
 	Addrs   []string
 	Timeout time.Duration
 	Root    string
+	// Retry retries a failed register, it is tried once if nil
+	Retry *gxtime.RetryPolicy
+	// Logger logs the registry and its watchers, gxlog.Default() if nil
+	Logger gxlog.Logger
+	// Metrics observes the events of the watchers if not nil
+	Metrics MetricsHook
+	// Observer observes the ops of the registry if not nil
+	Observer OpObserver
+	// Codec encodes the registered services, the json codec if nil. The
+	// watchers decode both codecs.
+	Codec Codec
+	// Advertise resolves the empty, loopback or unspecified addresses of
+	// the registered nodes if not nil
+	Advertise *AddressResolver
+	// StrictAddress rejects the nodes whose address is not to advertise,
+	// see ValidateAddress
+	StrictAddress bool
+	// StateListener is called with the state transitions of the connection
+	// of the registry if not nil, see WithStateListener
+	StateListener func(old, new ConnState)
+	// Reregister retries the registration of the services after the session
+	// expires, DefaultReregisterPolicy if nil
+	Reregister *gxtime.RetryPolicy
+	// ReregisterFailure is called with a service failed to register again
+	// after the retries of Reregister if not nil
+	ReregisterFailure func(s Service, err error)
+	// ACL protects the nodes registered by the credential of the digest
+	// scheme if not nil, see WithDigestACL
+	ACL *DigestACL
+}
+
+// DigestACL is the user and password of the digest scheme of zookeeper.
+type DigestACL struct {
+	User     string
+	Password string
+}
+
+// DefaultReregisterPolicy retries the registration of a service after the
+// session expires 10 times, in at most about 30 seconds.
+var DefaultReregisterPolicy = gxtime.RetryPolicy{
+	MaxAttempts: 10,
+	Backoff: gxtime.Backoff{
+		Base:   100 * time.Millisecond,
+		Max:    10 * time.Second,
+		Jitter: gxtime.EqualJitter,
+	},
 }
 
 type WatchOptions struct {
 	// the root registry path, such as "/dubbo/"
 	Root string
+	// Roots is the root registry paths of a watcher of multiple roots. It
+	// overrides Root if not empty, see WatchRoots.
+	Roots []string
 	// filter the second path, such as
-	// "/test/group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1"
+	// "/test/v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider"
 	Filter ServiceAttr
+	// Sampler samples the error logs of the watcher, not sampled if nil
+	Sampler *gxlog.SamplerConfig
+	// Codec decodes the watched services, DecodeService of the json and the
+	// protobuf codecs if nil
+	Codec Codec
+	// DecodeErrors notifies the nodes failed to decode as the errors of
+	// ErrorServiceDecode. A selector stops watching on an error, so they
+	// are only counted by default.
+	DecodeErrors bool
+	// Backoff delays the rewatches of a failed path, copied for every
+	// path. Its Rand must be nil, as the copies are used concurrently. The
+	// watcher has its default backoff if nil.
+	Backoff *gxtime.Backoff
+	// Wheel times the rewatch delays instead of the runtime timers, which
+	// is cheaper for many watchers. It is not stopped by the watcher.
+	Wheel *gxtime.Wheel
+	// Metrics observes the events of the watcher instead of the Metrics of
+	// the registry if not nil
+	Metrics MetricsHook
+	// ReplaySize is the count of the last notified events kept for Replay of
+	// a ReplayWatcher, DefaultReplaySize if not positive
+	ReplaySize int
+	// Actions is the actions of the notified events, all if empty. The
+	// nodes of the other events are watched still, e.g. the Del of a
+	// node is notified even if its Add is not.
+	Actions []ServiceEventType
+	// LocalCache is the file keeping the services known by the watcher,
+	// see WithLocalCache
+	LocalCache string
 }
 
+// DefaultReplaySize is the count of the events kept for Replay by default.
+const DefaultReplaySize = 256
+
 type Option func(*Options)
 
 // Addrs is the registry addresses to use
@@ -44,6 +136,110 @@ func WithRoot(root string) Option {
 	}
 }
 
+// WithRetryPolicy retries a failed register by @p
+func WithRetryPolicy(p *gxtime.RetryPolicy) Option {
+	return func(o *Options) {
+		o.Retry = p
+	}
+}
+
+// WithLogger logs the registry and its watchers by @l
+func WithLogger(l gxlog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+// WithMetricsHook observes the events of the watchers by @h
+func WithMetricsHook(h MetricsHook) Option {
+	return func(o *Options) {
+		o.Metrics = h
+	}
+}
+
+// WithOpObserver observes the ops of the registry by @ob
+func WithOpObserver(ob OpObserver) Option {
+	return func(o *Options) {
+		o.Observer = ob
+	}
+}
+
+// WithCodec encodes the registered services by @c, e.g.
+// WithCodec(GetCodec(CodecProtobuf)).
+func WithCodec(c Codec) Option {
+	return func(o *Options) {
+		o.Codec = c
+	}
+}
+
+// WithAutoAddress advertises an interface address for the nodes of an
+// empty, loopback or unspecified address, see AddressResolver.
+func WithAutoAddress() Option {
+	return func(o *Options) {
+		o.Advertise = &AddressResolver{}
+	}
+}
+
+// WithAdvertiseAddress advertises @address for the nodes of an empty,
+// loopback or unspecified address.
+func WithAdvertiseAddress(address string) Option {
+	return func(o *Options) {
+		o.Advertise = &AddressResolver{Override: address}
+	}
+}
+
+// WithAddressResolver resolves the addresses of the nodes by @r.
+func WithAddressResolver(r *AddressResolver) Option {
+	return func(o *Options) {
+		o.Advertise = r
+	}
+}
+
+// WithStrictAddress rejects the registration of the nodes of a loopback,
+// unspecified, multicast or malformed address by an *AddressError.
+func WithStrictAddress() Option {
+	return func(o *Options) {
+		o.StrictAddress = true
+	}
+}
+
+// WithStateListener calls @l with every state transition of the connection
+// of the registry, in order, e.g. to flush the services as the session
+// expires. @l is called by the event goroutine of the registry, so it must
+// not block.
+func WithStateListener(l func(old, new ConnState)) Option {
+	return func(o *Options) {
+		o.StateListener = l
+	}
+}
+
+// WithReregisterPolicy retries the registration of the services after the
+// session expires by @p.
+func WithReregisterPolicy(p *gxtime.RetryPolicy) Option {
+	return func(o *Options) {
+		o.Reregister = p
+	}
+}
+
+// WithReregisterFailure calls @fn with a service failed to register again
+// after the session expires, and the error of its last attempt.
+func WithReregisterFailure(fn func(s Service, err error)) Option {
+	return func(o *Options) {
+		o.ReregisterFailure = fn
+	}
+}
+
+// WithDigestACL protects the nodes registered, and the missing parents
+// created for them, by the digest scheme of @user and @password: the
+// session of the registry is authenticated by them and has all the
+// permissions of the nodes, and the others can only read them, as the
+// watchers do. It is supported by the zookeeper registry.
+func WithDigestACL(user, password string) Option {
+	return func(o *Options) {
+		o.ACL = &DigestACL{User: user, Password: password}
+	}
+}
+
 type WatchOption func(*WatchOptions)
 
 // Watch root
@@ -53,9 +249,148 @@ func WithWatchRoot(root string) WatchOption {
 	}
 }
 
+// WithWatchRoots watches the services under all the @roots, which must
+// not overlap.
+func WithWatchRoots(roots ...string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Roots = roots
+	}
+}
+
 // Watch ServiceAttr Filter
 func WithWatchFilter(filter ServiceAttr) WatchOption {
 	return func(o *WatchOptions) {
 		o.Filter = filter
 	}
 }
+
+// WithWatchSampler samples the error logs of the watcher by @conf, to not
+// flood the logs when the registry flaps
+func WithWatchSampler(conf gxlog.SamplerConfig) WatchOption {
+	return func(o *WatchOptions) {
+		o.Sampler = &conf
+	}
+}
+
+// WithWatchCodec decodes the watched services by @c only, e.g. to watch the
+// services registered by WithCodec(@c).
+func WithWatchCodec(c Codec) WatchOption {
+	return func(o *WatchOptions) {
+		o.Codec = c
+	}
+}
+
+// WithWatchDecodeErrors notifies the nodes failed to decode as the errors
+// of ErrorServiceDecode, so that a mismatched codec can be detected.
+func WithWatchDecodeErrors() WatchOption {
+	return func(o *WatchOptions) {
+		o.DecodeErrors = true
+	}
+}
+
+// WithRetryBackoff delays the rewatches of a failed path exponentially from
+// @base to @max, by full jitter so that the watchers of a registry do not
+// rewatch at once. The delays start over after a successful watch.
+func WithRetryBackoff(base, max time.Duration) WatchOption {
+	return func(o *WatchOptions) {
+		o.Backoff = &gxtime.Backoff{Base: base, Max: max, Jitter: gxtime.FullJitter}
+	}
+}
+
+// WithWatchWheel times the rewatch delays by @w, with the precision of its
+// tick. @w may be shared by many watchers.
+func WithWatchWheel(w *gxtime.Wheel) WatchOption {
+	return func(o *WatchOptions) {
+		o.Wheel = w
+	}
+}
+
+// WithWatchMetricsHook observes the events of the watcher by @h instead of
+// the hook of the registry
+func WithWatchMetricsHook(h MetricsHook) WatchOption {
+	return func(o *WatchOptions) {
+		o.Metrics = h
+	}
+}
+
+// WithWatchReplaySize keeps the last @n notified events for Replay of a
+// ReplayWatcher
+func WithWatchReplaySize(n int) WatchOption {
+	return func(o *WatchOptions) {
+		o.ReplaySize = n
+	}
+}
+
+// WithWatchActions notifies the events of @actions only, e.g.
+// WithWatchActions(ServiceDel) of a consumer of the deletions.
+func WithWatchActions(actions ...ServiceEventType) WatchOption {
+	return func(o *WatchOptions) {
+		o.Actions = actions
+	}
+}
+
+// WithLocalCache keeps the services notified by the watcher in the file
+// @path, which is rewritten atomically after every Add, Update or Del, see
+// LocalCache. A watcher created when the registry is unreachable notifies
+// the services of the file first, as the ServiceAdd events of Stale, so a
+// consumer can route to the providers it knew before. A corrupted file is
+// ignored with a warning. The watchers of different roots or filters must
+// not share a file.
+func WithLocalCache(path string) WatchOption {
+	return func(o *WatchOptions) {
+		o.LocalCache = path
+	}
+}
+
+// ErrOverlappingRoots is the error of the watch roots of which one is under
+// another one.
+var ErrOverlappingRoots = jerrors.Errorf("overlapping watch roots")
+
+// WatchAction returns whether the events of @action are notified, see
+// Actions.
+func (o WatchOptions) WatchAction(action ServiceEventType) bool {
+	if len(o.Actions) == 0 {
+		return true
+	}
+	for _, a := range o.Actions {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatchRoots returns the cleaned Roots, or Root as Roots of one root if
+// they are empty, or DefaultServiceRoot if both are empty. A root under
+// another one, e.g. "/dubbo/a" and "/dubbo", fails by ErrOverlappingRoots.
+func (o WatchOptions) WatchRoots() ([]string, error) {
+	roots := o.Roots
+	if len(roots) == 0 {
+		roots = []string{o.Root}
+		if o.Root == "" {
+			roots = []string{DefaultServiceRoot}
+		}
+	}
+
+	cleaned := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if !strings.HasPrefix(root, "/") {
+			return nil, jerrors.Errorf("watch root %q is not an absolute path", root)
+		}
+		root = path.Clean(root)
+		for _, r := range cleaned {
+			if underRoot(root, r) || underRoot(r, root) {
+				return nil, jerrors.Annotatef(ErrOverlappingRoots, "%s and %s", r, root)
+			}
+		}
+		cleaned = append(cleaned, root)
+	}
+
+	return cleaned, nil
+}
+
+// underRoot returns whether the cleaned path @p is @root or under it.
+func underRoot(p, root string) bool {
+	return p == root || root == "/" || strings.HasPrefix(p, root+"/")
+}
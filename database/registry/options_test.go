@@ -0,0 +1,40 @@
+package gxregistry
+
+import (
+	"reflect"
+	"testing"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+func TestWatchRoots(t *testing.T) {
+	for _, c := range []struct {
+		opts  WatchOptions
+		roots []string
+	}{
+		{WatchOptions{}, []string{DefaultServiceRoot}},
+		{WatchOptions{Root: "/dubbo/"}, []string{"/dubbo"}},
+		{WatchOptions{Root: "/dubbo", Roots: []string{"/a", "/b/"}}, []string{"/a", "/b"}},
+		{WatchOptions{Roots: []string{"/dubbo", "/dubbo2", "/a/dubbo"}}, []string{"/dubbo", "/dubbo2", "/a/dubbo"}},
+	} {
+		if roots, err := c.opts.WatchRoots(); err != nil || !reflect.DeepEqual(roots, c.roots) {
+			t.Fatalf("%+v.WatchRoots() = %v, error:%v", c.opts, roots, err)
+		}
+	}
+
+	for _, roots := range [][]string{
+		{"/dubbo", "/dubbo/providers"},
+		{"/dubbo/providers/", "/dubbo"},
+		{"/dubbo", "/dubbo/"},
+		{"/", "/dubbo"},
+	} {
+		if _, err := (WatchOptions{Roots: roots}).WatchRoots(); jerrors.Cause(err) != ErrOverlappingRoots {
+			t.Fatalf("WatchRoots() of %v = error:%v", roots, err)
+		}
+	}
+	if _, err := (WatchOptions{Roots: []string{"dubbo"}}).WatchRoots(); err == nil {
+		t.Fatalf("WatchRoots() of a relative root = nil error")
+	}
+}
@@ -0,0 +1,17 @@
+#!/usr/bin/env bash
+# ******************************************************
+# DESC    : generate registry.pb.go of registry.proto
+# AUTHOR  : Alex Stocks
+# VERSION : 1.0
+# LICENCE : Apache License 2.0
+# EMAIL   : alexstocks@foxmail.com
+# MOD     : 2018-06-12 16:20
+# FILE    : pb.sh
+# ******************************************************
+
+# descriptor.proto
+gopath=~/test/golang/lib/src/github.com/gogo/protobuf/protobuf
+# gogo.proto is located in github.com/gogo/protobuf/gogoproto
+gogopath=~/test/golang/lib/src/
+
+protoc -I=$gopath:$gogopath:./ --gogofaster_out=./ registry.proto
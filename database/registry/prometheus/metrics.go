@@ -0,0 +1,124 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxprometheus provides the prometheus metrics of a registry. It is
+// a package of its own to not add the prometheus dependency to gxregistry.
+// It requires github.com/prometheus/client_golang v1.0.0 or later.
+package gxprometheus
+
+import (
+	"time"
+)
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+const namespace = "gxregistry"
+
+// Metrics is the gxregistry.MetricsHook and gxregistry.OpObserver of the
+// prometheus metrics:
+//
+//	gxregistry_watcher_events_total{action}           the events sent
+//	gxregistry_watcher_dropped_events_total           the events dropped
+//	gxregistry_watcher_decode_errors_total            the nodes and paths failed to decode
+//	gxregistry_watcher_reconnects_total               the rewatches
+//	gxregistry_watcher_lag_seconds                    from sent to notified
+//	gxregistry_op_duration_seconds{op, error_class}   the registry ops
+//
+// e.g.
+//
+//	m, err := gxprometheus.NewMetrics(prometheus.DefaultRegisterer)
+//	r, err := gxzookeeper.NewRegistry(
+//		gxregistry.WithAddrs("127.0.0.1:2181"),
+//		gxregistry.WithMetricsHook(m),
+//		gxregistry.WithOpObserver(m),
+//	)
+type Metrics struct {
+	events       *prometheus.CounterVec
+	dropped      prometheus.Counter
+	decodeErrors prometheus.Counter
+	reconnects   prometheus.Counter
+	lag          prometheus.Histogram
+	ops          *prometheus.HistogramVec
+}
+
+// NewMetrics returns the metrics registered to @reg.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "events_total",
+			Help:      "The events sent by the registry watchers, by action.",
+		}, []string{"action"}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "dropped_events_total",
+			Help:      "The registry events the watchers failed to send.",
+		}),
+		decodeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "decode_errors_total",
+			Help:      "The service nodes and paths the watchers failed to decode.",
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "reconnects_total",
+			Help:      "The rewatches of the registry after failures.",
+		}),
+		lag: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "lag_seconds",
+			Help:      "The delay from an event sent to notified.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		ops: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "op_duration_seconds",
+			Help:      "The latency of the registry ops, by op and error class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op", "error_class"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.events, m.dropped, m.decodeErrors, m.reconnects, m.lag, m.ops} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) WatchEvent(action gxregistry.ServiceEventType) {
+	m.events.WithLabelValues(action.String()).Inc()
+}
+
+func (m *Metrics) WatchDropped() {
+	m.dropped.Inc()
+}
+
+func (m *Metrics) WatchDecodeError() {
+	m.decodeErrors.Inc()
+}
+
+func (m *Metrics) WatchReconnect() {
+	m.reconnects.Inc()
+}
+
+func (m *Metrics) WatchLag(lag time.Duration) {
+	m.lag.Observe(lag.Seconds())
+}
+
+func (m *Metrics) ObserveOp(op string, d time.Duration, err error) {
+	m.ops.WithLabelValues(op, gxregistry.ErrorClass(err)).Observe(d.Seconds())
+}
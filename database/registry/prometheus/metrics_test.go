@@ -0,0 +1,89 @@
+package gxprometheus
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+// fakeWatcher emits its events to the hook as a registry watcher does.
+type fakeWatcher struct {
+	hook gxregistry.MetricsHook
+}
+
+func (w *fakeWatcher) emit(action gxregistry.ServiceEventType, lag time.Duration) {
+	w.hook.WatchEvent(action)
+	w.hook.WatchLag(lag)
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewMetrics(reg)
+	if err != nil {
+		t.Fatalf("NewMetrics() = error:%s", err)
+	}
+	if _, err = NewMetrics(reg); err == nil {
+		t.Fatalf("NewMetrics() registered twice")
+	}
+
+	w := &fakeWatcher{hook: m}
+	w.emit(gxregistry.ServiceAdd, time.Millisecond)
+	w.emit(gxregistry.ServiceAdd, time.Millisecond)
+	w.emit(gxregistry.ServiceDel, time.Second)
+	m.WatchDropped()
+	m.WatchDecodeError()
+	m.WatchReconnect()
+
+	if n := testutil.ToFloat64(m.events.WithLabelValues("ServiceAdd")); n != 2 {
+		t.Fatalf("ServiceAdd events %v", n)
+	}
+	if n := testutil.ToFloat64(m.events.WithLabelValues("ServiceDel")); n != 1 {
+		t.Fatalf("ServiceDel events %v", n)
+	}
+	if n := testutil.ToFloat64(m.dropped); n != 1 {
+		t.Fatalf("dropped events %v", n)
+	}
+	if n := testutil.ToFloat64(m.decodeErrors); n != 1 {
+		t.Fatalf("decode errors %v", n)
+	}
+	if n := testutil.ToFloat64(m.reconnects); n != 1 {
+		t.Fatalf("reconnects %v", n)
+	}
+
+	m.ObserveOp(gxregistry.OpRegister, 10*time.Millisecond, nil)
+	m.ObserveOp(gxregistry.OpGetServices, time.Millisecond, gxregistry.ErrorRegistryNotFound)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() = error:%s", err)
+	}
+	counts := make(map[string]uint64)
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			if h := metric.GetHistogram(); h != nil {
+				key := f.GetName()
+				for _, l := range metric.GetLabel() {
+					key += "," + l.GetValue()
+				}
+				counts[key] = h.GetSampleCount()
+			}
+		}
+	}
+	for key, n := range map[string]uint64{
+		"gxregistry_watcher_lag_seconds":                        3,
+		"gxregistry_op_duration_seconds,none,register":          1,
+		"gxregistry_op_duration_seconds,not_found,get_services": 1,
+	} {
+		if counts[key] != n {
+			t.Fatalf("histogram %s count %d, want %d, all:%v", key, counts[key], n, counts)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/time"
+)
+
+// The format of a recorded event stream:
+//
+//	header: "GXREC" | version(1 byte)
+//	record: length(4 bytes, big endian) | the json of a record
+//
+// A record carries the seq and the time of an event, and its EventResult or
+// its error. The stream of RecordVersion 1 has no other fields, a new
+// field must come with a new version.
+const (
+	RecordVersion = 1
+	// MaxRecordSize is the max size of a record, a larger length is taken
+	// as a corrupted stream.
+	MaxRecordSize = MaxServiceSize + 4096
+)
+
+var (
+	recordMagic = []byte("GXREC")
+
+	ErrRecordVersion   = jerrors.Errorf("unsupported record stream version")
+	ErrRecordCorrupted = jerrors.Errorf("corrupted record stream")
+	// ErrRecordTruncated is returned by the Notify of a Replayer when the
+	// stream ends in a record, the replayer then ends as at the end of
+	// the stream.
+	ErrRecordTruncated = jerrors.Errorf("truncated record stream")
+)
+
+type record struct {
+	Seq     uint64           `json:"seq"`
+	Time    time.Time        `json:"time"`
+	Action  ServiceEventType `json:"action,omitempty"`
+	Service *Service         `json:"service,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+//////////////////////////////////////////
+// Recorder
+//////////////////////////////////////////
+
+// Recorder is a Watcher writing the events of another watcher to a sink
+// while passing them through.
+type Recorder struct {
+	Watcher
+	clock gxtime.Clock
+
+	sync.Mutex
+	sink io.Writer
+	seq  uint64
+	err  error
+}
+
+type RecorderOption func(*Recorder)
+
+// WithRecorderClock stamps the records by @c, gxtime.RealClock by default.
+func WithRecorderClock(c gxtime.Clock) RecorderOption {
+	return func(r *Recorder) {
+		r.clock = c
+	}
+}
+
+// NewRecorder returns a recorder of @w, which writes the stream header to
+// @sink at once.
+func NewRecorder(w Watcher, sink io.Writer, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{Watcher: w, sink: sink, clock: gxtime.RealClock{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	header := append(append([]byte(nil), recordMagic...), RecordVersion)
+	if _, err := sink.Write(header); err != nil {
+		return nil, jerrors.Annotatef(err, "write record header")
+	}
+
+	return r, nil
+}
+
+// Notify returns the next event of the watcher, and records it. A failure
+// of the sink does not fail Notify, see Err.
+func (r *Recorder) Notify() (*EventResult, error) {
+	res, err := r.Watcher.Notify()
+	r.record(res, err)
+
+	return res, err
+}
+
+// NotifyCtx is Notify of the NotifyCtx of the watcher. The error of a done
+// @ctx is not recorded, as it is not of the watcher.
+func (r *Recorder) NotifyCtx(ctx context.Context) (*EventResult, error) {
+	res, err := r.Watcher.NotifyCtx(ctx)
+	if err == nil || err != ctx.Err() {
+		r.record(res, err)
+	}
+
+	return res, err
+}
+
+func (r *Recorder) record(res *EventResult, err error) {
+	rec := record{Time: r.clock.Now()}
+	if err != nil {
+		rec.Error = err.Error()
+	} else if res != nil {
+		rec.Action, rec.Service = res.Action, res.Service
+	}
+	r.write(&rec)
+}
+
+func (r *Recorder) write(rec *record) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.seq++
+	rec.Seq = r.seq
+	data, err := json.Marshal(rec)
+	if err != nil {
+		r.err = jerrors.Annotatef(err, "json.Marshal(record:%+v)", rec)
+		return
+	}
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, uint32(len(data)))
+	frame.Write(data)
+	if _, err = r.sink.Write(frame.Bytes()); err != nil {
+		r.err = jerrors.Annotatef(err, "write record %d", rec.Seq)
+	}
+}
+
+// Err returns the first error writing the sink, after which the events are
+// not recorded any more.
+func (r *Recorder) Err() error {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.err
+}
+
+//////////////////////////////////////////
+// Replayer
+//////////////////////////////////////////
+
+// EndOfStream is the behavior of a Replayer at the end of its stream.
+type EndOfStream int
+
+const (
+	// StopAtEnd closes the replayer at the end, its Notify returns
+	// ErrWatcherClosed.
+	StopAtEnd EndOfStream = iota
+	// HoldAtEnd keeps the replayer valid at the end, its Notify blocks until
+	// it is closed, as a watcher of a quiet registry does.
+	HoldAtEnd
+)
+
+// Replayer is a Watcher replaying a stream of a Recorder.
+type Replayer struct {
+	src   io.Reader
+	speed float64
+	clock gxtime.Clock
+	end   EndOfStream
+
+	sync.Mutex // for Notify
+	last       time.Time
+	pending    *record // read before a NotifyCtx gave up
+	ended      bool
+	done       chan struct{}
+	once       sync.Once
+}
+
+type ReplayerOption func(*Replayer)
+
+// WithReplayerClock waits the intervals of the events by @c,
+// gxtime.RealClock by default.
+func WithReplayerClock(c gxtime.Clock) ReplayerOption {
+	return func(r *Replayer) {
+		r.clock = c
+	}
+}
+
+// WithEndOfStream sets the behavior at the end of the stream, StopAtEnd by
+// default.
+func WithEndOfStream(end EndOfStream) ReplayerOption {
+	return func(r *Replayer) {
+		r.end = end
+	}
+}
+
+// NewReplayer returns a replayer of the stream @src, which reads and checks
+// the stream header at once. The events are notified at their recorded
+// intervals divided by @speed, e.g. 2 replays twice as fast, and 0 replays
+// without waits.
+func NewReplayer(src io.Reader, speed float64, opts ...ReplayerOption) (*Replayer, error) {
+	r := &Replayer{
+		src:   src,
+		speed: speed,
+		clock: gxtime.RealClock{},
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	header := make([]byte, len(recordMagic)+1)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, jerrors.Annotatef(ErrRecordCorrupted, "read header: %v", err)
+	}
+	if !bytes.Equal(header[:len(recordMagic)], recordMagic) {
+		return nil, jerrors.Annotatef(ErrRecordCorrupted, "header %q", header)
+	}
+	if header[len(recordMagic)] != RecordVersion {
+		return nil, jerrors.Annotatef(ErrRecordVersion, "version %d", header[len(recordMagic)])
+	}
+
+	return r, nil
+}
+
+// read returns the next record, io.EOF at the end of the stream.
+func (r *Replayer) read() (*record, error) {
+	var size [4]byte
+	n, err := io.ReadFull(r.src, size[:])
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, jerrors.Annotatef(ErrRecordTruncated, "read %d bytes of a record length", n)
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length > MaxRecordSize {
+		return nil, jerrors.Annotatef(ErrRecordCorrupted, "record length %d", length)
+	}
+	data := make([]byte, length)
+	if n, err = io.ReadFull(r.src, data); err != nil {
+		return nil, jerrors.Annotatef(ErrRecordTruncated, "read %d bytes of a %d bytes record", n, length)
+	}
+
+	var rec record
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return nil, jerrors.Annotatef(ErrRecordCorrupted, "json.Unmarshal(record): %v", err)
+	}
+
+	return &rec, nil
+}
+
+// Notify returns the next recorded event after its recorded interval, or
+// the recorded error of the watcher.
+func (r *Replayer) Notify() (*EventResult, error) {
+	return r.NotifyCtx(context.Background())
+}
+
+// NotifyCtx is Notify which gives up when @ctx is done. The event it gives
+// up is returned by the next call, after its interval again.
+func (r *Replayer) NotifyCtx(ctx context.Context) (*EventResult, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.IsClosed() {
+		return nil, ErrWatcherClosed
+	}
+	if r.ended {
+		return r.atEnd(ctx)
+	}
+
+	rec := r.pending
+	r.pending = nil
+	if rec == nil {
+		var err error
+		if rec, err = r.read(); err != nil {
+			r.ended = true
+			if err == io.EOF {
+				return r.atEnd(ctx)
+			}
+			return nil, err
+		}
+	}
+
+	if !r.last.IsZero() && r.speed > 0 {
+		if d := time.Duration(float64(rec.Time.Sub(r.last)) / r.speed); d > 0 {
+			select {
+			case <-r.clock.After(d):
+			case <-r.done:
+				return nil, ErrWatcherClosed
+			case <-ctx.Done():
+				r.pending = rec
+				return nil, ctx.Err()
+			}
+		}
+	}
+	r.last = rec.Time
+
+	if rec.Error != "" {
+		return nil, jerrors.New(rec.Error)
+	}
+	return &EventResult{Action: rec.Action, Service: rec.Service}, nil
+}
+
+func (r *Replayer) atEnd(ctx context.Context) (*EventResult, error) {
+	if r.end == StopAtEnd {
+		r.Close()
+		return nil, ErrWatcherClosed
+	}
+
+	select {
+	case <-r.done:
+		return nil, ErrWatcherClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Valid is false after the replayer is closed, or stops at the end.
+func (r *Replayer) Valid() bool {
+	return !r.IsClosed()
+}
+
+func (r *Replayer) Close() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *Replayer) IsClosed() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
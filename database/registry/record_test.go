@@ -0,0 +1,255 @@
+package gxregistry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/time"
+)
+
+type notified struct {
+	res *EventResult
+	err error
+}
+
+// listWatcher notifies its events one by one.
+type listWatcher struct {
+	Watcher // not implemented
+	clock   *gxtime.FakeClock
+	events  []notified
+	gaps    []time.Duration // advance the clock before each event
+}
+
+func (w *listWatcher) Notify() (*EventResult, error) {
+	w.clock.Advance(w.gaps[0])
+	e := w.events[0]
+	w.events, w.gaps = w.events[1:], w.gaps[1:]
+	return e.res, e.err
+}
+
+type failWriter struct {
+	n int // the writes to succeed
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, fmt.Errorf("disk full")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func recordEvents(t *testing.T) ([]notified, []byte) {
+	attr := ServiceAttr{Service: "shopping", Role: SRT_Provider}
+	events := []notified{
+		{res: &EventResult{Action: ServiceAdd, Service: &Service{Attr: &attr, Nodes: []*Node{{ID: "node0"}}}}},
+		{res: &EventResult{Action: ServiceAdd, Service: &Service{Attr: &attr, Nodes: []*Node{{ID: "node1"}}}}},
+		{err: fmt.Errorf("zk: connection loss")},
+		{res: &EventResult{Action: ServiceDel, Service: &Service{Attr: &attr, Nodes: []*Node{{ID: "node0"}}}}},
+	}
+	clock := gxtime.NewFakeClock(time.Unix(1500000000, 0))
+	w := &listWatcher{
+		clock:  clock,
+		events: events,
+		gaps:   []time.Duration{0, time.Second, 0, 2 * time.Second},
+	}
+
+	var sink bytes.Buffer
+	r, err := NewRecorder(w, &sink, WithRecorderClock(clock))
+	if err != nil {
+		t.Fatalf("NewRecorder() = error:%s", err)
+	}
+	for i, e := range events {
+		res, err := r.Notify()
+		if res != e.res || err != e.err {
+			t.Fatalf("Recorder.Notify() %d = event:%v, error:%v", i, res, err)
+		}
+	}
+	if r.Err() != nil {
+		t.Fatalf("Recorder.Err() = %s", r.Err())
+	}
+
+	return events, sink.Bytes()
+}
+
+func checkReplay(r *Replayer, want notified) error {
+	res, err := r.Notify()
+	if want.err != nil {
+		if err == nil || err.Error() != want.err.Error() {
+			return fmt.Errorf("Replayer.Notify() = error:%v, want:%v", err, want.err)
+		}
+		return nil
+	}
+	if err != nil || res.Action != want.res.Action || res.Service.Nodes[0].ID != want.res.Service.Nodes[0].ID ||
+		*res.Service.Attr != *want.res.Service.Attr {
+		return fmt.Errorf("Replayer.Notify() = event:%v, error:%v, want:%v", res, err, want.res)
+	}
+	return nil
+}
+
+func expectReplay(t *testing.T, r *Replayer, want notified) {
+	if err := checkReplay(r, want); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	events, stream := recordEvents(t)
+
+	// replayed twice as fast
+	clock := gxtime.NewFakeClock(time.Now())
+	r, err := NewReplayer(bytes.NewReader(stream), 2, WithReplayerClock(clock))
+	if err != nil {
+		t.Fatalf("NewReplayer() = error:%s", err)
+	}
+	expectReplay(t, r, events[0])
+	for i, gap := range []time.Duration{500 * time.Millisecond, 0, time.Second} {
+		done := make(chan error, 1)
+		go func() {
+			done <- checkReplay(r, events[i+1])
+		}()
+		if gap > 0 {
+			clock.BlockUntil(1)
+			clock.Advance(gap - time.Millisecond)
+			select {
+			case <-done:
+				t.Fatalf("event %d is replayed before its interval %s", i+1, gap)
+			case <-time.After(10 * time.Millisecond):
+			}
+			clock.Advance(time.Millisecond)
+		}
+		if err = <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// stops at the end
+	if _, err = r.Notify(); err != ErrWatcherClosed || !r.IsClosed() || r.Valid() {
+		t.Fatalf("Replayer.Notify() at the end = error:%v, closed:%v", err, r.IsClosed())
+	}
+}
+
+func TestReplayerHoldAtEnd(t *testing.T) {
+	events, stream := recordEvents(t)
+	r, err := NewReplayer(bytes.NewReader(stream), 0, WithEndOfStream(HoldAtEnd))
+	if err != nil {
+		t.Fatalf("NewReplayer() = error:%s", err)
+	}
+	for _, e := range events {
+		expectReplay(t, r, e)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := r.Notify()
+		errc <- err
+	}()
+	select {
+	case err = <-errc:
+		t.Fatalf("Replayer.Notify() at the end = error:%v, want blocked", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !r.Valid() {
+		t.Fatalf("Replayer.Valid() at the end = false")
+	}
+	r.Close()
+	if err = <-errc; err != ErrWatcherClosed {
+		t.Fatalf("Replayer.Notify() after Close() = error:%v", err)
+	}
+}
+
+func TestReplayerNotifyCtx(t *testing.T) {
+	events, stream := recordEvents(t)
+	clock := gxtime.NewFakeClock(time.Now())
+	r, err := NewReplayer(bytes.NewReader(stream), 1, WithReplayerClock(clock), WithEndOfStream(HoldAtEnd))
+	if err != nil {
+		t.Fatalf("NewReplayer() = error:%s", err)
+	}
+	expectReplay(t, r, events[0])
+
+	// the event given up in its interval is replayed by the next call
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = r.NotifyCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Replayer.NotifyCtx() in an interval = error:%v", err)
+	}
+	r.speed = 0
+	for _, e := range events[1:] {
+		expectReplay(t, r, e)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = r.NotifyCtx(ctx); err != context.Canceled {
+		t.Fatalf("Replayer.NotifyCtx() at the end = error:%v", err)
+	}
+	r.Close()
+	if _, err = r.NotifyCtx(context.Background()); err != ErrWatcherClosed {
+		t.Fatalf("Replayer.NotifyCtx() after Close() = error:%v", err)
+	}
+}
+
+func TestReplayerBrokenStream(t *testing.T) {
+	events, stream := recordEvents(t)
+
+	// the last record is truncated
+	r, err := NewReplayer(bytes.NewReader(stream[:len(stream)-3]), 0)
+	if err != nil {
+		t.Fatalf("NewReplayer() = error:%s", err)
+	}
+	for _, e := range events[:3] {
+		expectReplay(t, r, e)
+	}
+	if _, err = r.Notify(); jerrors.Cause(err) != ErrRecordTruncated {
+		t.Fatalf("Replayer.Notify() of a truncated record = error:%v", err)
+	}
+	if _, err = r.Notify(); err != ErrWatcherClosed {
+		t.Fatalf("Replayer.Notify() after the truncated record = error:%v", err)
+	}
+
+	for _, c := range []struct {
+		header []byte
+		err    error
+	}{
+		{[]byte("GXREC\x02"), ErrRecordVersion},
+		{[]byte("GXRE"), ErrRecordCorrupted},
+		{[]byte("PK\x03\x04\x00\x00"), ErrRecordCorrupted},
+	} {
+		if _, err = NewReplayer(bytes.NewReader(c.header), 1); jerrors.Cause(err) != c.err {
+			t.Fatalf("NewReplayer(%q) = error:%v, want:%v", c.header, err, c.err)
+		}
+	}
+	// a record longer than MaxRecordSize
+	r, _ = NewReplayer(bytes.NewReader([]byte("GXREC\x01\xff\xff\xff\xff")), 1)
+	if _, err = r.Notify(); jerrors.Cause(err) != ErrRecordCorrupted {
+		t.Fatalf("Replayer.Notify() of a huge record = error:%v", err)
+	}
+}
+
+func TestRecorderSinkFailure(t *testing.T) {
+	clock := gxtime.NewFakeClock(time.Now())
+	e := notified{res: &EventResult{Action: ServiceAdd}}
+	w := &listWatcher{clock: clock, events: []notified{e, e}, gaps: []time.Duration{0, 0}}
+	if _, err := NewRecorder(w, &failWriter{}); err == nil {
+		t.Fatalf("NewRecorder() of a failed sink = nil error")
+	}
+
+	r, _ := NewRecorder(w, &failWriter{n: 1})
+	for i := 0; i < 2; i++ {
+		if res, err := r.Notify(); res != e.res || err != nil {
+			t.Fatalf("Recorder.Notify() = event:%v, error:%v", res, err)
+		}
+	}
+	if r.Err() == nil {
+		t.Fatalf("Recorder.Err() of a failed sink = nil")
+	}
+}
@@ -24,10 +24,47 @@ const (
 	REGISTRY_CONN_DELAY = 3 // watchDir中使用，防止不断地对zk重连
 	DefaultTimeout      = 3e9
 	MaxFailTime         = 15e9 // fail retry wait time delay
+	// MaxServiceSize is the max size of a service payload, the default
+	// jute.maxbuffer of zookeeper
+	MaxServiceSize = 1 << 20
+	// MaxAttrPathSegments is the max count of the key/value pairs of a
+	// service attr path
+	MaxAttrPathSegments = 32
 )
 
 var (
 	ErrorRegistryNotFound = jerrors.Errorf("registry not found")
 	ErrorAlreadyRegister  = jerrors.Errorf("service has already been registered")
+	ErrorServiceTooLarge  = jerrors.Errorf("service payload is larger than MaxServiceSize")
+	ErrorPathTooLong      = jerrors.Errorf("service attr path has too many segments")
+	ErrorNoServiceAttr    = jerrors.Errorf("service has no attr")
+	ErrorServiceDecode    = jerrors.Errorf("failed to decode service")
+	ErrMalformedAttrPath  = jerrors.Errorf("malformed service attr path")
 	DefaultServiceRoot    = "/gxregistry"
 )
+
+// ConnState is the state of the connection of a registry to its servers,
+// see WithStateListener.
+type ConnState int
+
+const (
+	ConnConnected    ConnState = iota // connected since the start
+	ConnDisconnected                  // disconnected, the session may be back
+	ConnExpired                       // the session expired, and its nodes are lost
+	ConnReconnected                   // connected again after a disconnection or an expiry
+)
+
+var connStateNames = [...]string{
+	ConnConnected:    "connected",
+	ConnDisconnected: "disconnected",
+	ConnExpired:      "expired",
+	ConnReconnected:  "reconnected",
+}
+
+func (s ConnState) String() string {
+	if s < 0 || int(s) >= len(connStateNames) {
+		return "unknown"
+	}
+
+	return connStateNames[s]
+}
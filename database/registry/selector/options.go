@@ -0,0 +1,68 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxselector provides a service node selector of a registry
+package gxselector
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/time"
+)
+
+type Options struct {
+	// Strategy picks the node of a Select, RoundRobin if nil
+	Strategy Strategy
+	// Logger logs the selector, the logger of the registry if nil
+	Logger gxlog.Logger
+	// Backoff delays the rewatch of the registry after its watcher failed
+	Backoff gxtime.Backoff
+	// LocalCache is the directory of the local caches of the watchers, see
+	// WithLocalCache
+	LocalCache string
+}
+
+type Option func(*Options)
+
+// WithStrategy picks the nodes by @s
+func WithStrategy(s Strategy) Option {
+	return func(o *Options) {
+		o.Strategy = s
+	}
+}
+
+// WithLogger logs the selector by @l
+func WithLogger(l gxlog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+// WithBackoff delays the rewatch of the registry by @b
+func WithBackoff(b gxtime.Backoff) Option {
+	return func(o *Options) {
+		o.Backoff = b
+	}
+}
+
+// WithLocalCache keeps the nodes of every ServiceAttr selected in a file
+// under the directory @dir by gxregistry.WithLocalCache, and selects the
+// nodes of the file, which are stale, if the registry is unreachable as the
+// ServiceAttr is selected the first time.
+func WithLocalCache(dir string) Option {
+	return func(o *Options) {
+		o.LocalCache = dir
+	}
+}
+
+func defaultOptions() Options {
+	return Options{
+		Strategy: RoundRobin,
+		Backoff: gxtime.Backoff{
+			Base:   gxtime.TimeSecondDuration(float64(gxregistry.REGISTRY_CONN_DELAY)),
+			Max:    gxtime.TimeSecondDuration(float64(15 * gxregistry.REGISTRY_CONN_DELAY)),
+			Jitter: gxtime.EqualJitter,
+		},
+	}
+}
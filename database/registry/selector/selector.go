@@ -0,0 +1,478 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxselector provides a service node selector of a registry
+package gxselector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+)
+
+var (
+	ErrNoAvailableNode = jerrors.Errorf("no available node")
+	ErrSelectorClosed  = jerrors.Errorf("selector closed")
+)
+
+type nodeKey struct {
+	id      string
+	address string
+	port    int32
+}
+
+func keyOf(node *gxregistry.Node) nodeKey {
+	return nodeKey{id: node.ID, address: node.Address, port: node.Port}
+}
+
+// table is the live nodes of a ServiceAttr.
+type table struct {
+	attr  gxregistry.ServiceAttr
+	ready chan struct{} // closed after the first load
+	once  sync.Once     // for ready
+	next  uint64        // the sequence number of the next Select
+
+	sync.RWMutex
+	nodes []*gxregistry.Node // copy on write
+	w     gxregistry.Watcher // nil if the registry is not watched
+}
+
+func (t *table) markReady() {
+	t.once.Do(func() { close(t.ready) })
+}
+
+func (t *table) get() ([]*gxregistry.Node, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.nodes, t.w == nil || !t.w.Valid()
+}
+
+// reset replaces the nodes by the snapshot @nodes watched by @w.
+func (t *table) reset(nodes []*gxregistry.Node, w gxregistry.Watcher) {
+	t.Lock()
+	t.nodes = nodes
+	t.w = w
+	t.Unlock()
+}
+
+// unwatch keeps the last known nodes, which are stale now.
+func (t *table) unwatch() int {
+	t.Lock()
+	defer t.Unlock()
+
+	t.w = nil
+	return len(t.nodes)
+}
+
+func (t *table) add(nodes []*gxregistry.Node) {
+	t.Lock()
+	defer t.Unlock()
+
+	arr := make([]*gxregistry.Node, len(t.nodes), len(t.nodes)+len(nodes))
+	copy(arr, t.nodes)
+NEXT:
+	for _, node := range nodes {
+		key := keyOf(node)
+		for i := range arr {
+			if keyOf(arr[i]) == key {
+				arr[i] = node
+				continue NEXT
+			}
+		}
+		arr = append(arr, node)
+	}
+	t.nodes = arr
+}
+
+func (t *table) del(nodes []*gxregistry.Node) {
+	t.Lock()
+	defer t.Unlock()
+
+	keys := make(map[nodeKey]struct{}, len(nodes))
+	for _, node := range nodes {
+		keys[keyOf(node)] = struct{}{}
+	}
+	arr := make([]*gxregistry.Node, 0, len(t.nodes))
+	for _, node := range t.nodes {
+		if _, ok := keys[keyOf(node)]; !ok {
+			arr = append(arr, node)
+		}
+	}
+	t.nodes = arr
+}
+
+// filter returns the distinct nodes of the services of the attr of t.
+func (t *table) filter(services []gxregistry.Service) []*gxregistry.Node {
+	var nodes []*gxregistry.Node
+	keys := make(map[nodeKey]struct{})
+	for _, service := range services {
+		if service.Attr == nil || !t.attr.Filter(*service.Attr) {
+			continue
+		}
+		for _, node := range service.Nodes {
+			if _, ok := keys[keyOf(node)]; ok {
+				continue
+			}
+			keys[keyOf(node)] = struct{}{}
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
+// Selector selects the nodes of the services of a registry. It keeps the
+// live nodes of a ServiceAttr by a watcher of the registry since its first
+// Select. If the watcher fails, the last known nodes are selected, which are
+// stale until the registry is watched again.
+type Selector struct {
+	reg  gxregistry.Registry
+	opts Options
+
+	sync.Mutex
+	tables map[gxregistry.ServiceAttr]*table
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewSelector returns a selector of the services of @r.
+func NewSelector(r gxregistry.Registry, opts ...Option) (*Selector, error) {
+	if r == nil {
+		return nil, jerrors.Errorf("@r is nil")
+	}
+
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Strategy == nil {
+		options.Strategy = RoundRobin
+	}
+	if options.Logger == nil {
+		options.Logger = r.Options().Logger
+	}
+	if options.Logger == nil {
+		options.Logger = gxlog.Default()
+	}
+
+	return &Selector{
+		reg:    r,
+		opts:   options,
+		tables: make(map[gxregistry.ServiceAttr]*table),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+func (s *Selector) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// table returns the table of @attr, which is watched since its first call.
+func (s *Selector) table(attr gxregistry.ServiceAttr) (*table, error) {
+	s.Lock()
+	if s.isClosed() {
+		s.Unlock()
+		return nil, ErrSelectorClosed
+	}
+	t, ok := s.tables[attr]
+	if !ok {
+		t = &table{attr: attr, ready: make(chan struct{})}
+		s.tables[attr] = t
+		s.wg.Add(1)
+		go s.run(t)
+	}
+	s.Unlock()
+
+	select {
+	case <-t.ready:
+		return t, nil
+	case <-s.done:
+		return nil, ErrSelectorClosed
+	}
+}
+
+// load loads the snapshot of the nodes of @t watched by @w.
+func (s *Selector) load(t *table, w gxregistry.Watcher) error {
+	services, err := s.reg.GetServices(t.attr)
+	if err != nil && jerrors.Cause(err) != gxregistry.ErrorRegistryNotFound {
+		return jerrors.Annotatef(err, "GetServices(ServiceAttr:%+v)", t.attr)
+	}
+	t.reset(t.filter(services), w)
+
+	return nil
+}
+
+// localCache returns the local cache file of @t, "" without one.
+func (s *Selector) localCache(t *table) string {
+	if s.opts.LocalCache == "" {
+		return ""
+	}
+	name, err := t.attr.MarshalPath()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(s.opts.LocalCache, string(name)+".cache")
+}
+
+// loadLocalCache loads the stale nodes of @t from its local cache. A
+// corrupted cache is ignored.
+func (s *Selector) loadLocalCache(t *table) {
+	file := s.localCache(t)
+	if file == "" {
+		return
+	}
+	services, err := gxregistry.LoadLocalCache(file)
+	if err != nil {
+		if !os.IsNotExist(jerrors.Cause(err)) {
+			gxlog.LogError(s.opts.Logger, "selector ignores the local cache", err, "attr", t.attr, "path", file)
+		}
+		return
+	}
+	list := make([]gxregistry.Service, 0, len(services))
+	for _, service := range services {
+		list = append(list, *service)
+	}
+	nodes := t.filter(list)
+	t.reset(nodes, nil)
+	s.opts.Logger.Warnw("selector loads the stale nodes of the local cache", "attr", t.attr,
+		"path", file, "stale nodes", len(nodes))
+}
+
+// run watches the registry for @t until the selector is closed. The
+// watcher is created before the snapshot is loaded, so no event is lost
+// between them, and the events of the nodes in the snapshot are idempotent.
+func (s *Selector) run(t *table) {
+	defer s.wg.Done()
+
+	backoff := s.opts.Backoff
+	opts := []gxregistry.WatchOption{
+		gxregistry.WithWatchRoot(s.reg.Options().Root),
+		gxregistry.WithWatchFilter(t.attr),
+	}
+	if file := s.localCache(t); file != "" {
+		opts = append(opts, gxregistry.WithLocalCache(file))
+	}
+	for first := true; ; first = false {
+		w, err := s.reg.Watch(opts...)
+		if err == nil {
+			if err = s.load(t, w); err != nil {
+				w.Close()
+			}
+		}
+		if err != nil && first {
+			s.loadLocalCache(t)
+		}
+		t.markReady()
+		if err == nil {
+			backoff.Reset()
+			// this function will block until the watcher fails or the selector is closed
+			err = s.watch(t, w)
+		}
+		if s.isClosed() {
+			return
+		}
+
+		n := t.unwatch()
+		gxlog.LogError(s.opts.Logger, "selector failed to watch the registry", err,
+			"attr", t.attr, "stale nodes", n)
+		select {
+		case <-time.After(backoff.Next()):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Selector) watch(t *table, w gxregistry.Watcher) error {
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		w.Close()
+	}()
+	s.wg.Add(1)
+	go func() {
+		select {
+		case <-s.done:
+			w.Close()
+		case <-stop:
+		}
+		s.wg.Done()
+	}()
+
+	for {
+		res, err := w.Notify()
+		if err != nil {
+			return jerrors.Trace(err)
+		}
+		if res == nil || res.Service == nil || res.Service.Attr == nil {
+			continue
+		}
+		if !t.attr.Filter(*res.Service.Attr) {
+			continue
+		}
+
+		switch res.Action {
+		case gxregistry.ServiceAdd, gxregistry.ServiceUpdate:
+			t.add(res.Service.Nodes)
+		case gxregistry.ServiceDel:
+			if !w.Valid() {
+				// do not delete any node when the watcher failed to connect the registry.
+				s.opts.Logger.Warnf("ignore the deleted service{%s} of the invalid watcher", gxlog.Lazy(res.Service))
+				continue
+			}
+			t.del(res.Service.Nodes)
+		}
+	}
+}
+
+// Select returns a node of the services of @attr picked by the Strategy.
+// It returns ErrNoAvailableNode if there is none.
+func (s *Selector) Select(attr gxregistry.ServiceAttr) (gxregistry.Node, error) {
+	t, err := s.table(attr)
+	if err != nil {
+		return gxregistry.Node{}, err
+	}
+	nodes, _ := t.get()
+	if len(nodes) == 0 {
+		return gxregistry.Node{}, ErrNoAvailableNode
+	}
+	node := s.opts.Strategy(nodes, atomic.AddUint64(&t.next, 1)-1)
+	if node == nil {
+		return gxregistry.Node{}, ErrNoAvailableNode
+	}
+
+	return *node.Copy(), nil
+}
+
+// Stale returns whether the nodes of @attr are the last known ones of a
+// failed watcher. It is false if @attr has not been selected.
+func (s *Selector) Stale(attr gxregistry.ServiceAttr) bool {
+	s.Lock()
+	t, ok := s.tables[attr]
+	s.Unlock()
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-t.ready:
+	default:
+		return false
+	}
+	_, stale := t.get()
+
+	return stale
+}
+
+type inspectedNode struct {
+	Attr  gxregistry.ServiceAttr `json:"attr"`
+	Stale bool                   `json:"stale"`
+	Node  *gxregistry.Node       `json:"node"`
+}
+
+func attrLess(a, b gxregistry.ServiceAttr) bool {
+	switch {
+	case a.Service != b.Service:
+		return a.Service < b.Service
+	case a.Group != b.Group:
+		return a.Group < b.Group
+	case a.Protocol != b.Protocol:
+		return a.Protocol < b.Protocol
+	case a.Version != b.Version:
+		return a.Version < b.Version
+	default:
+		return a.Role < b.Role
+	}
+}
+
+func nodeLess(a, b *gxregistry.Node) bool {
+	switch {
+	case a.ID != b.ID:
+		return a.ID < b.ID
+	case a.Address != b.Address:
+		return a.Address < b.Address
+	default:
+		return a.Port < b.Port
+	}
+}
+
+// Inspect returns the node tables, the items are the nodes sorted by their
+// ServiceAttr and ID.
+func (s *Selector) Inspect() gxregistry.Inspection {
+	s.Lock()
+	tables := make([]*table, 0, len(s.tables))
+	for _, t := range s.tables {
+		tables = append(tables, t)
+	}
+	s.Unlock()
+	sort.Slice(tables, func(i, j int) bool {
+		return attrLess(tables[i].attr, tables[j].attr)
+	})
+
+	var (
+		stale bool
+		items []interface{}
+	)
+	for _, t := range tables {
+		select {
+		case <-t.ready:
+		default:
+			continue
+		}
+		nodes, tstale := t.get()
+		stale = stale || tstale
+		nodes = append([]*gxregistry.Node(nil), nodes...)
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodeLess(nodes[i], nodes[j])
+		})
+		for _, node := range nodes {
+			items = append(items, inspectedNode{Attr: t.attr, Stale: tstale, Node: node})
+		}
+	}
+
+	return gxregistry.Inspection{
+		Kind:  gxregistry.InspectServices,
+		Name:  "selector",
+		Valid: !s.isClosed(),
+		Stale: stale,
+		Items: items,
+	}
+}
+
+// Close stops watching the registry, the registry is not closed.
+func (s *Selector) Close() error {
+	s.Lock()
+	select {
+	case <-s.done:
+		s.Unlock()
+		return nil
+	default:
+		close(s.done)
+	}
+	s.tables = make(map[gxregistry.ServiceAttr]*table)
+	s.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
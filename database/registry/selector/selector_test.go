@@ -0,0 +1,538 @@
+package gxselector
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/time"
+)
+
+type fakeWatcher struct {
+	events  chan *gxregistry.EventResult
+	invalid int32
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{
+		events: make(chan *gxregistry.EventResult, 1024),
+		done:   make(chan struct{}),
+	}
+}
+
+func (w *fakeWatcher) Notify() (*gxregistry.EventResult, error) {
+	return w.NotifyCtx(context.Background())
+}
+
+func (w *fakeWatcher) NotifyCtx(ctx context.Context) (*gxregistry.EventResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-w.done:
+		return nil, gxregistry.ErrWatcherClosed
+	case res := <-w.events:
+		return res, nil
+	}
+}
+
+func (w *fakeWatcher) Valid() bool {
+	return atomic.LoadInt32(&w.invalid) == 0
+}
+
+func (w *fakeWatcher) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *fakeWatcher) IsClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// fakeRegistry returns the services set by its tests, and records the
+// watchers created.
+type fakeRegistry struct {
+	sync.Mutex
+	services []gxregistry.Service
+	watchErr error
+	watchers []*fakeWatcher
+	caches   []string // the local caches of the watches
+}
+
+func (r *fakeRegistry) Register(service gxregistry.Service) error   { return nil }
+func (r *fakeRegistry) Deregister(service gxregistry.Service) error { return nil }
+func (r *fakeRegistry) Close() error                                { return nil }
+func (r *fakeRegistry) String() string                              { return "fake registry" }
+func (r *fakeRegistry) Options() gxregistry.Options {
+	return gxregistry.Options{Logger: gxlog.NewNop()}
+}
+
+func (r *fakeRegistry) GetServices(attr gxregistry.ServiceAttr) ([]gxregistry.Service, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.services) == 0 {
+		return nil, gxregistry.ErrorRegistryNotFound
+	}
+	return append([]gxregistry.Service(nil), r.services...), nil
+}
+
+func (r *fakeRegistry) Watch(opts ...gxregistry.WatchOption) (gxregistry.Watcher, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var options gxregistry.WatchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	r.caches = append(r.caches, options.LocalCache)
+	if r.watchErr != nil {
+		return nil, r.watchErr
+	}
+	w := newFakeWatcher()
+	r.watchers = append(r.watchers, w)
+	return w, nil
+}
+
+func (r *fakeRegistry) watcher(t *testing.T, i int) *fakeWatcher {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.Lock()
+		if len(r.watchers) > i {
+			w := r.watchers[i]
+			r.Unlock()
+			return w
+		}
+		r.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("watcher %d is not created", i)
+	return nil
+}
+
+var testAttr = gxregistry.ServiceAttr{
+	Group:    "bjtelecom",
+	Service:  "shopping",
+	Protocol: "pb",
+	Version:  "1.0.1",
+	Role:     gxregistry.SRT_Provider,
+}
+
+func testService(nodes ...*gxregistry.Node) gxregistry.Service {
+	attr := testAttr
+	return gxregistry.Service{Attr: &attr, Nodes: nodes}
+}
+
+func testNode(id string, metas ...gxregistry.NodeMeta) *gxregistry.Node {
+	node := &gxregistry.Node{ID: id, Address: "127.0.0.1", Port: 12345}
+	for _, meta := range metas {
+		meta(node)
+	}
+	return node
+}
+
+func newTestSelector(t *testing.T, r gxregistry.Registry, opts ...Option) *Selector {
+	opts = append([]Option{WithBackoff(gxtime.Backoff{Base: time.Millisecond, Max: 10 * time.Millisecond})}, opts...)
+	s, err := NewSelector(r, opts...)
+	if err != nil {
+		t.Fatalf("NewSelector() = error:%s", err)
+	}
+	return s
+}
+
+// waitFor polls @cond until it is true.
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func selectIDs(t *testing.T, s *Selector, n int) map[string]int {
+	ids := make(map[string]int)
+	for i := 0; i < n; i++ {
+		node, err := s.Select(testAttr)
+		if err != nil {
+			t.Fatalf("Select() = error:%s", err)
+		}
+		ids[node.ID]++
+	}
+	return ids
+}
+
+func TestSelectorRoundRobin(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{
+		testService(testNode("node0")),
+		testService(testNode("node1")),
+		testService(testNode("node2"), testNode("node0")),
+	}}
+	s := newTestSelector(t, r)
+	defer s.Close()
+
+	var ids []string
+	for i := 0; i < 6; i++ {
+		node, err := s.Select(testAttr)
+		if err != nil {
+			t.Fatalf("Select() = error:%s", err)
+		}
+		ids = append(ids, node.ID)
+	}
+	for i, id := range []string{"node0", "node1", "node2", "node0", "node1", "node2"} {
+		if ids[i] != id {
+			t.Fatalf("round robin ids:%v", ids)
+		}
+	}
+	if s.Stale(testAttr) {
+		t.Fatalf("the nodes of a valid watcher are stale")
+	}
+}
+
+func TestSelectorRandom(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{
+		testService(testNode("node0"), testNode("node1"), testNode("node2")),
+	}}
+	s := newTestSelector(t, r, WithStrategy(Random))
+	defer s.Close()
+
+	ids := selectIDs(t, s, 300)
+	if len(ids) != 3 {
+		t.Fatalf("random ids:%v", ids)
+	}
+}
+
+func TestSelectorWeighted(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{
+		testService(
+			testNode("light", WithNodeWeight(100)),
+			testNode("heavy", WithNodeWeight(300)),
+			testNode("off", WithNodeWeight(0)),
+		),
+	}}
+	s := newTestSelector(t, r, WithStrategy(Weighted))
+	defer s.Close()
+
+	ids := selectIDs(t, s, 4000)
+	if ids["off"] != 0 {
+		t.Fatalf("the node of weight 0 is selected %d times", ids["off"])
+	}
+	if ids["heavy"] < 2*ids["light"] || ids["heavy"] > 4*ids["light"] {
+		t.Fatalf("weighted ids:%v", ids)
+	}
+
+	if w := Weight(testNode("bad", gxregistry.WithNodeMeta(WeightKey, "x"))); w != DefaultWeight {
+		t.Fatalf("Weight(bad) = %d", w)
+	}
+	if w := Weight(testNode("none")); w != DefaultWeight {
+		t.Fatalf("Weight(none) = %d", w)
+	}
+}
+
+func TestLoadWeight(t *testing.T) {
+	loaded := func(id, cpu string, metas ...gxregistry.NodeMeta) *gxregistry.Node {
+		node := testNode(id, metas...)
+		gxregistry.WithNodeMeta(gxregistry.LoadCPUKey, cpu)(node)
+		gxregistry.WithNodeMeta(gxregistry.LoadRSSKey, "1024")(node)
+		gxregistry.WithNodeMeta(gxregistry.LoadGoroutinesKey, "10")(node)
+		return node
+	}
+	for _, c := range []struct {
+		node *gxregistry.Node
+		w    int
+	}{
+		{testNode("none"), DefaultWeight},
+		{loaded("idle", "0.0"), DefaultWeight},
+		{loaded("half", "50.0", WithNodeWeight(300)), 150},
+		{loaded("full", "100.0"), 1},
+		{loaded("off", "10.0", WithNodeWeight(0)), 0},
+		{loaded("bad", "120.0"), DefaultWeight},
+	} {
+		if w := LoadWeight(c.node); w != c.w {
+			t.Fatalf("LoadWeight(%s) = %d, want %d", c.node.ID, w, c.w)
+		}
+	}
+}
+
+func TestSelectorNoAvailableNode(t *testing.T) {
+	r := &fakeRegistry{}
+	s := newTestSelector(t, r)
+	defer s.Close()
+
+	if _, err := s.Select(testAttr); err != ErrNoAvailableNode {
+		t.Fatalf("Select() = error:%v", err)
+	}
+
+	r2 := &fakeRegistry{services: []gxregistry.Service{testService(testNode("off", WithNodeWeight(0)))}}
+	s2 := newTestSelector(t, r2, WithStrategy(Weighted))
+	defer s2.Close()
+	if _, err := s2.Select(testAttr); err != ErrNoAvailableNode {
+		t.Fatalf("Select() = error:%v", err)
+	}
+}
+
+func TestSelectorEvents(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{testService(testNode("node0"))}}
+	s := newTestSelector(t, r)
+	defer s.Close()
+
+	if _, err := s.Select(testAttr); err != nil {
+		t.Fatalf("Select() = error:%s", err)
+	}
+	w := r.watcher(t, 0)
+
+	other := testAttr
+	other.Version = "2.0.0"
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &gxregistry.Service{
+		Attr: &other, Nodes: []*gxregistry.Node{testNode("other")},
+	}}
+	svc := testService(testNode("node1"))
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &svc}
+	waitFor(t, func() bool { return len(selectIDs(t, s, 4)) == 2 }, "node1 added")
+
+	svc = testService(testNode("node0"))
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceDel, Service: &svc}
+	waitFor(t, func() bool {
+		ids := selectIDs(t, s, 4)
+		return len(ids) == 1 && ids["node1"] == 4
+	}, "node0 deleted")
+
+	svc = testService(testNode("node1"))
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceDel, Service: &svc}
+	waitFor(t, func() bool {
+		_, err := s.Select(testAttr)
+		return err == ErrNoAvailableNode
+	}, "node1 deleted")
+}
+
+func TestSelectorStale(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{testService(testNode("node0"), testNode("node1"))}}
+	s := newTestSelector(t, r)
+	defer s.Close()
+
+	if _, err := s.Select(testAttr); err != nil {
+		t.Fatalf("Select() = error:%s", err)
+	}
+	w := r.watcher(t, 0)
+
+	// the deletions of an invalid watcher are ignored
+	atomic.StoreInt32(&w.invalid, 1)
+	if !s.Stale(testAttr) {
+		t.Fatalf("the nodes of an invalid watcher are not stale")
+	}
+	svc := testService(testNode("node0"))
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceDel, Service: &svc}
+	svc1 := testService(testNode("node2"))
+	w.events <- &gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &svc1}
+	waitFor(t, func() bool { return len(selectIDs(t, s, 6)) == 3 }, "node2 added")
+
+	// the last known nodes are served until the registry is watched again
+	r.Lock()
+	r.watchErr = jerrors.New("registry is down")
+	r.Unlock()
+	w.Close()
+	waitFor(t, func() bool {
+		s.Lock()
+		tbl := s.tables[testAttr]
+		s.Unlock()
+		tbl.RLock()
+		defer tbl.RUnlock()
+		return tbl.w == nil
+	}, "watcher failed")
+	if !s.Stale(testAttr) {
+		t.Fatalf("the nodes of a failed watcher are not stale")
+	}
+	if ids := selectIDs(t, s, 6); len(ids) != 3 {
+		t.Fatalf("stale ids:%v", ids)
+	}
+
+	// the snapshot of the new watcher replaces the stale nodes
+	r.Lock()
+	r.watchErr = nil
+	r.services = []gxregistry.Service{testService(testNode("node3"))}
+	r.Unlock()
+	r.watcher(t, 1)
+	waitFor(t, func() bool { return !s.Stale(testAttr) }, "rewatched")
+	if ids := selectIDs(t, s, 3); ids["node3"] != 3 {
+		t.Fatalf("rewatched ids:%v", ids)
+	}
+}
+
+// localCache writes the services of @services to the local cache file of
+// testAttr under a temporary directory, which is returned with the file.
+func localCache(t *testing.T, services ...gxregistry.Service) (string, string) {
+	dir, err := ioutil.TempDir("", "gxselector-cache")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = error:%s", err)
+	}
+	name, _ := testAttr.MarshalPath()
+	file := filepath.Join(dir, string(name)+".cache")
+	c := gxregistry.NewLocalCache(file)
+	for i := range services {
+		if err = c.Update(&gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &services[i]}); err != nil {
+			t.Fatalf("Update() = error:%s", err)
+		}
+	}
+	return dir, file
+}
+
+func TestSelectorLocalCache(t *testing.T) {
+	cart := testService(testNode("node9"))
+	cart.Attr.Service = "cart"
+	dir, file := localCache(t, testService(testNode("node0"), testNode("node1")), cart)
+	defer os.RemoveAll(dir)
+
+	// the registry is unreachable at startup
+	r := &fakeRegistry{watchErr: jerrors.New("registry is down")}
+	s := newTestSelector(t, r, WithLocalCache(dir))
+	defer s.Close()
+
+	if ids := selectIDs(t, s, 4); ids["node0"] != 2 || ids["node1"] != 2 {
+		t.Fatalf("cached ids:%v", ids)
+	}
+	if !s.Stale(testAttr) {
+		t.Fatalf("the nodes of the local cache are not stale")
+	}
+	r.Lock()
+	if len(r.caches) == 0 || r.caches[0] != file {
+		t.Fatalf("local caches of the watches:%v, want %s", r.caches, file)
+	}
+	r.Unlock()
+
+	// the snapshot of the registry replaces the cached nodes
+	r.Lock()
+	r.watchErr = nil
+	r.services = []gxregistry.Service{testService(testNode("node2"))}
+	r.Unlock()
+	r.watcher(t, 0)
+	waitFor(t, func() bool { return !s.Stale(testAttr) }, "watched")
+	if ids := selectIDs(t, s, 3); ids["node2"] != 3 {
+		t.Fatalf("watched ids:%v", ids)
+	}
+}
+
+func TestSelectorLocalCacheCorrupted(t *testing.T) {
+	dir, file := localCache(t, testService(testNode("node0")))
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() = error:%s", err)
+	}
+	data[len(data)-2] ^= 0x01
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() = error:%s", err)
+	}
+
+	r := &fakeRegistry{watchErr: jerrors.New("registry is down")}
+	s := newTestSelector(t, r, WithLocalCache(dir))
+	defer s.Close()
+
+	if node, err := s.Select(testAttr); err != ErrNoAvailableNode {
+		t.Fatalf("Select() = %+v, error:%v", node, err)
+	}
+}
+
+func TestSelectorConcurrent(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{testService(testNode("node0"))}}
+	s := newTestSelector(t, r, WithStrategy(Weighted))
+
+	if _, err := s.Select(testAttr); err != nil {
+		t.Fatalf("Select() = error:%s", err)
+	}
+	w := r.watcher(t, 0)
+
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				node, err := s.Select(testAttr)
+				if err != nil && err != ErrNoAvailableNode {
+					t.Errorf("Select() = error:%s", err)
+					return
+				}
+				if err == nil && node.ID == "" {
+					t.Errorf("Select() = empty node")
+					return
+				}
+			}
+		}()
+	}
+
+	ids := []string{"node0", "node1", "node2", "node3"}
+	for i := 0; i < 1000; i++ {
+		svc := testService(testNode(ids[i%len(ids)], WithNodeWeight(i%3)))
+		action := gxregistry.ServiceAdd
+		if i%3 == 0 {
+			action = gxregistry.ServiceDel
+		}
+		w.events <- &gxregistry.EventResult{Action: action, Service: &svc}
+	}
+	waitFor(t, func() bool { return len(w.events) == 0 }, "events consumed")
+
+	close(stop)
+	wg.Wait()
+	s.Close()
+	if _, err := s.Select(testAttr); err != ErrSelectorClosed {
+		t.Fatalf("Select() after Close = error:%v", err)
+	}
+	if !w.IsClosed() {
+		t.Fatalf("the watcher is not closed by Close")
+	}
+}
+
+func TestSelectorInspect(t *testing.T) {
+	r := &fakeRegistry{services: []gxregistry.Service{
+		testService(testNode("node1"), testNode("node0")),
+	}}
+	s := newTestSelector(t, r)
+
+	if _, err := s.Select(testAttr); err != nil {
+		t.Fatalf("Select() = error:%s", err)
+	}
+	ins := s.Inspect()
+	if ins.Kind != gxregistry.InspectServices || !ins.Valid || ins.Stale || len(ins.Items) != 2 {
+		t.Fatalf("Inspect() = %+v", ins)
+	}
+	if node := ins.Items[0].(inspectedNode); node.Node.ID != "node0" || node.Attr != testAttr {
+		t.Fatalf("Inspect() items:%+v", ins.Items)
+	}
+
+	atomic.StoreInt32(&r.watcher(t, 0).invalid, 1)
+	if ins = s.Inspect(); !ins.Stale || !ins.Items[1].(inspectedNode).Stale {
+		t.Fatalf("Inspect() of an invalid watcher = %+v", ins)
+	}
+
+	s.Close()
+	if ins = s.Inspect(); ins.Valid {
+		t.Fatalf("Inspect() after Close = %+v", ins)
+	}
+}
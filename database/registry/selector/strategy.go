@@ -0,0 +1,120 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxselector provides a service node selector of a registry
+package gxselector
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+const (
+	// WeightKey is the key of the weight of a node in its Metadata, as
+	// gxregistry.Node has no weight field.
+	WeightKey = "weight"
+	// DefaultWeight is the weight of a node without a valid one.
+	DefaultWeight = 100
+)
+
+// Strategy picks a node of @nodes, which are not empty. @n is the sequence
+// number of the Select of the nodes. It returns nil if none is available.
+// A Strategy is called concurrently.
+type Strategy func(nodes []*gxregistry.Node, n uint64) *gxregistry.Node
+
+// RoundRobin picks the nodes in turn.
+func RoundRobin(nodes []*gxregistry.Node, n uint64) *gxregistry.Node {
+	return nodes[n%uint64(len(nodes))]
+}
+
+// Random picks a node randomly.
+func Random(nodes []*gxregistry.Node, n uint64) *gxregistry.Node {
+	return nodes[rand.Intn(len(nodes))]
+}
+
+// Weighted picks a node randomly in proportion to its Weight. The nodes of
+// weight 0 are never picked.
+func Weighted(nodes []*gxregistry.Node, n uint64) *gxregistry.Node {
+	var total int
+	for _, node := range nodes {
+		total += Weight(node)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	i := rand.Intn(total)
+	for _, node := range nodes {
+		if i -= Weight(node); i < 0 {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// Weight returns the weight of @node in its Metadata, DefaultWeight if it
+// is not a non negative integer.
+func Weight(node *gxregistry.Node) int {
+	s, ok := node.Metadata[WeightKey]
+	if !ok {
+		return DefaultWeight
+	}
+	w, err := strconv.Atoi(s)
+	if err != nil || w < 0 {
+		return DefaultWeight
+	}
+
+	return w
+}
+
+// WithNodeWeight sets the weight of a node to @w, e.g.
+//
+//	node := gxregistry.Node{ID: "node0", Address: "127.0.0.1", Port: 12345}
+//	gxselector.WithNodeWeight(200)(&node)
+func WithNodeWeight(w int) gxregistry.NodeMeta {
+	return gxregistry.WithNodeMeta(WeightKey, strconv.Itoa(w))
+}
+
+// LoadWeighted picks a node as Weighted does, by its LoadWeight, so that
+// the nodes of less loaded providers are picked more, see
+// gxregistry.Heartbeat.
+func LoadWeighted(nodes []*gxregistry.Node, n uint64) *gxregistry.Node {
+	var total int
+	for _, node := range nodes {
+		total += LoadWeight(node)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	i := rand.Intn(total)
+	for _, node := range nodes {
+		if i -= LoadWeight(node); i < 0 {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// LoadWeight returns the Weight of @node scaled by the idle CPU of its
+// load, and at least 1 for a node of a positive weight. It is the Weight
+// for a node without a load.
+func LoadWeight(node *gxregistry.Node) int {
+	w := Weight(node)
+	l, ok := gxregistry.NodeLoad(node)
+	if !ok || w == 0 {
+		return w
+	}
+	if w = int(float64(w) * (100 - l.CPUPercent) / 100); w < 1 {
+		w = 1
+	}
+
+	return w
+}
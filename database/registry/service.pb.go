@@ -16,7 +16,6 @@
 package gxregistry
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"math"
@@ -24,6 +23,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 import (
@@ -122,19 +122,31 @@ func (m *ServiceAttr) Reset()                    { *m = ServiceAttr{} }
 func (*ServiceAttr) ProtoMessage()               {}
 func (*ServiceAttr) Descriptor() ([]byte, []int) { return fileDescriptorService, []int{0} }
 
-// fine filtering
+// Filter returns whether @service is of the filter @m, by fine filtering. A
+// string field of @m matches the field of @service by its patterns split
+// by commas:
+//
+//	"" or "*"   any value
+//	"a,b"       "a" or "b"
+//	"!a"        any value but "a"
+//	"a,b,!b"    "a", as a value of a negation never matches
+//	"*,!a"      the same as "!a"
+//
+// The values are compared unescaped, as those of UnmarshalPath, so a value
+// with a comma is only matched by a wildcard. The role matches if it is
+// SRT_UNKOWN in @m or equal.
 func (m *ServiceAttr) Filter(service ServiceAttr) bool {
 	switch {
-	case "" != m.Protocol && service.Protocol != m.Protocol:
+	case !matchAttr(m.Protocol, service.Protocol):
 		return false
 
-	case "" != m.Service && service.Service != m.Service:
+	case !matchAttr(m.Service, service.Service):
 		return false
 
-	case "" != m.Group && service.Group != m.Group:
+	case !matchAttr(m.Group, service.Group):
 		return false
 
-	case "" != m.Version && service.Version != m.Version:
+	case !matchAttr(m.Version, service.Version):
 		return false
 
 	case SRT_UNKOWN != m.Role && service.Role != m.Role:
@@ -145,9 +157,10 @@ func (m *ServiceAttr) Filter(service ServiceAttr) bool {
 	}
 }
 
+// MeshFilter is Filter of the service and the role only.
 func (m *ServiceAttr) MeshFilter(service ServiceAttr) bool {
 	switch {
-	case "" != m.Service && service.Service != m.Service:
+	case !matchAttr(m.Service, service.Service):
 		return false
 
 	case SRT_UNKOWN != m.Role && service.Role != m.Role:
@@ -158,6 +171,42 @@ func (m *ServiceAttr) MeshFilter(service ServiceAttr) bool {
 	}
 }
 
+// matchAttr returns whether @value matches the patterns of @pattern, see
+// ServiceAttr.Filter.
+func matchAttr(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if pattern[0] != '!' && strings.IndexByte(pattern, ',') < 0 {
+		return pattern == value
+	}
+
+	var matched, positive bool
+	for pattern != "" {
+		p := pattern
+		if i := strings.IndexByte(pattern, ','); i >= 0 {
+			p, pattern = pattern[:i], pattern[i+1:]
+		} else {
+			pattern = ""
+		}
+
+		switch {
+		case p == "":
+		case p[0] == '!':
+			if p[1:] == value {
+				return false
+			}
+		default:
+			positive = true
+			if p == "*" || p == value {
+				matched = true
+			}
+		}
+	}
+
+	return matched || !positive
+}
+
 func (m *ServiceAttr) Copy() *ServiceAttr {
 	return &ServiceAttr{
 		Group:    m.Group,
@@ -251,8 +300,12 @@ func WithServiceMeta(key, value string) ServiceMeta {
 // Result is returned by a call to Next on
 // the watcher. Actions can be create, update, delete
 type EventResult struct {
-	Action  ServiceEventType `protobuf:"varint,1,opt,name=Action,proto3,enum=gxregistry.ServiceEventType" json:"Action,omitempty"`
-	Service *Service         `protobuf:"bytes,2,opt,name=Service" json:"Service,omitempty"`
+	Action   ServiceEventType `protobuf:"varint,1,opt,name=Action,proto3,enum=gxregistry.ServiceEventType" json:"Action,omitempty"`
+	Service  *Service         `protobuf:"bytes,2,opt,name=Service" json:"Service,omitempty"`
+	Root     string           `protobuf:"bytes,3,opt,name=Root,proto3" json:"Root,omitempty"`
+	Seq      uint64           `protobuf:"varint,4,opt,name=Seq,proto3" json:"Seq,omitempty"`
+	Revision int64            `protobuf:"varint,5,opt,name=Revision,proto3" json:"Revision,omitempty"`
+	Stale    bool             `protobuf:"varint,6,opt,name=Stale,proto3" json:"Stale,omitempty"`
 }
 
 func (m *EventResult) Reset()                    { *m = EventResult{} }
@@ -575,6 +628,18 @@ func (this *EventResult) VerboseEqual(that interface{}) error {
 	if !this.Service.Equal(that1.Service) {
 		return fmt.Errorf("Service this(%v) Not Equal that(%v)", this.Service, that1.Service)
 	}
+	if this.Root != that1.Root {
+		return fmt.Errorf("Root this(%v) Not Equal that(%v)", this.Root, that1.Root)
+	}
+	if this.Seq != that1.Seq {
+		return fmt.Errorf("Seq this(%v) Not Equal that(%v)", this.Seq, that1.Seq)
+	}
+	if this.Revision != that1.Revision {
+		return fmt.Errorf("Revision this(%v) Not Equal that(%v)", this.Revision, that1.Revision)
+	}
+	if this.Stale != that1.Stale {
+		return fmt.Errorf("Stale this(%v) Not Equal that(%v)", this.Stale, that1.Stale)
+	}
 	return nil
 }
 func (this *EventResult) Equal(that interface{}) bool {
@@ -608,6 +673,18 @@ func (this *EventResult) Equal(that interface{}) bool {
 	if !this.Service.Equal(that1.Service) {
 		return false
 	}
+	if this.Root != that1.Root {
+		return false
+	}
+	if this.Seq != that1.Seq {
+		return false
+	}
+	if this.Revision != that1.Revision {
+		return false
+	}
+	if this.Stale != that1.Stale {
+		return false
+	}
 	return true
 }
 func (this *ServiceAttr) GoString() string {
@@ -681,12 +758,16 @@ func (this *EventResult) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 6)
+	s := make([]string, 0, 8)
 	s = append(s, "&gxregistry.EventResult{")
 	s = append(s, "Action: "+fmt.Sprintf("%#v", this.Action)+",\n")
 	if this.Service != nil {
 		s = append(s, "Service: "+fmt.Sprintf("%#v", this.Service)+",\n")
 	}
+	s = append(s, "Root: "+fmt.Sprintf("%#v", this.Root)+",\n")
+	s = append(s, "Seq: "+fmt.Sprintf("%#v", this.Seq)+",\n")
+	s = append(s, "Revision: "+fmt.Sprintf("%#v", this.Revision)+",\n")
+	s = append(s, "Stale: "+fmt.Sprintf("%#v", this.Stale)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -884,6 +965,32 @@ func (m *EventResult) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += n2
 	}
+	if len(m.Root) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintService(dAtA, i, uint64(len(m.Root)))
+		i += copy(dAtA[i:], m.Root)
+	}
+	if m.Seq != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintService(dAtA, i, uint64(m.Seq))
+	}
+	if m.Revision != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintService(dAtA, i, uint64(m.Revision))
+	}
+	if m.Stale {
+		dAtA[i] = 0x30
+		i++
+		if m.Stale {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -998,6 +1105,19 @@ func (m *EventResult) Size() (n int) {
 		l = m.Service.Size()
 		n += 1 + l + sovService(uint64(l))
 	}
+	l = len(m.Root)
+	if l > 0 {
+		n += 1 + l + sovService(uint64(l))
+	}
+	if m.Seq != 0 {
+		n += 1 + sovService(uint64(m.Seq))
+	}
+	if m.Revision != 0 {
+		n += 1 + sovService(uint64(m.Revision))
+	}
+	if m.Stale {
+		n += 2
+	}
 	return n
 }
 
@@ -1080,6 +1200,10 @@ func (this *EventResult) String() string {
 	s := strings.Join([]string{`&EventResult{`,
 		`Action:` + fmt.Sprintf("%v", this.Action) + `,`,
 		`Service:` + strings.Replace(fmt.Sprintf("%v", this.Service), "Service", "Service", 1) + `,`,
+		`Root:` + fmt.Sprintf("%v", this.Root) + `,`,
+		`Seq:` + fmt.Sprintf("%v", this.Seq) + `,`,
+		`Revision:` + fmt.Sprintf("%v", this.Revision) + `,`,
+		`Stale:` + fmt.Sprintf("%v", this.Stale) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1835,6 +1959,93 @@ func (m *EventResult) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Root", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthService
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Root = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			m.Seq = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Seq |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Revision", wireType)
+			}
+			m.Revision = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Revision |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stale", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Stale = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipService(dAtA[iNdEx:])
@@ -1995,69 +2206,336 @@ var fileDescriptorService = []byte{
 
 //////////////////////////////////////////
 
+// The service attr path is versioned by a leading marker "v<n>:". A path
+// without a marker is of v1, the url.QueryEscape of the url query of the
+// attr, which escapes its values twice:
+//
+//	group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1
+//
+// A path of v2 is the fields group, service, protocol, version and role in
+// order, separated by ';', in each of which '%', '/', ';', the spaces, the
+// control characters and the non-ascii bytes are percent-escaped once:
+//
+//	v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider
+//
+// A later version may only append fields to v2, so the readers of v2 read
+// the paths of a later version by their first five fields.
+const (
+	attrPathV2     = "v2:"
+	attrPathSep    = ';'
+	attrPathFields = 5
+)
+
+// MarshalPath returns the v2 path of @a, or an empty path if @a is empty.
 func (a *ServiceAttr) MarshalPath() ([]byte, error) {
-	params := url.Values{}
-	if len(a.Group) != 0 {
-		params.Add("group", a.Group)
+	if *a == (ServiceAttr{}) {
+		return gxstrings.Slice(""), nil
 	}
-	if len(a.Service) != 0 {
-		params.Add("service", a.Service)
+
+	var role string
+	if a.Role != SRT_UNKOWN {
+		role = a.Role.String()
 	}
-	if len(a.Protocol) != 0 {
-		params.Add("protocol", a.Protocol)
+	fields := [attrPathFields]string{a.Group, a.Service, a.Protocol, a.Version, role}
+
+	path := make([]byte, 0, 64)
+	path = append(path, attrPathV2...)
+	for i, field := range fields {
+		if i > 0 {
+			path = append(path, attrPathSep)
+		}
+		path = escapeAttrField(path, field)
 	}
-	if len(a.Version) != 0 {
-		params.Add("version", a.Version)
+
+	return path, nil
+}
+
+func escapeAttrField(path []byte, field string) []byte {
+	const upperhex = "0123456789ABCDEF"
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c <= ' ' || c >= 0x7f || c == '%' || c == '/' || c == attrPathSep {
+			path = append(path, '%', upperhex[c>>4], upperhex[c&15])
+		} else {
+			path = append(path, c)
+		}
 	}
-	if a.Role != SRT_UNKOWN {
-		params.Add("role", a.Role.String())
+
+	return path
+}
+
+func unescapeAttrField(field string) (string, error) {
+	if strings.IndexByte(field, '%') < 0 {
+		return field, nil
 	}
 
-	if len(params) == 0 {
-		return gxstrings.Slice(""), nil
+	value := make([]byte, 0, len(field))
+	for i := 0; i < len(field); i++ {
+		if field[i] != '%' {
+			value = append(value, field[i])
+			continue
+		}
+		if i+2 >= len(field) {
+			return "", jerrors.Errorf("truncated escape %q", field[i:])
+		}
+		c, err := strconv.ParseUint(field[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", jerrors.Errorf("invalid escape %q", field[i:i+3])
+		}
+		value = append(value, byte(c))
+		i += 2
 	}
 
-	// encode result: group=bjtelecom&protocol=pb&service=shopping&type=add+service&version=1.0.1
-	// query escape: group%3Dbjtelecom%26protocol%3Dpb%26service%3Dshopping%26type%3Dadd%2Bservice%26version%3D1.0.1
-	return []byte(url.QueryEscape(params.Encode())), nil
+	return string(value), nil
 }
 
+// InvalidAttrError is the error of a service attr field which is not
+// valid utf-8.
+type InvalidAttrError struct {
+	Field string
+	Value string
+}
+
+func (e *InvalidAttrError) Error() string {
+	return fmt.Sprintf("service attr %s:%q is not valid utf-8", e.Field, e.Value)
+}
+
+// MalformedAttrPathError is the error of UnmarshalPath of a path which is
+// not of MarshalPath. It is ErrMalformedAttrPath by errors.Is.
+type MalformedAttrPathError struct {
+	Segment string // the offending segment of the path
+	Err     error
+}
+
+func (e *MalformedAttrPathError) Error() string {
+	return fmt.Sprintf("malformed service attr path segment %.64q: %v", e.Segment, e.Err)
+}
+
+func (e *MalformedAttrPathError) Unwrap() error {
+	return e.Err
+}
+
+func (e *MalformedAttrPathError) Is(target error) bool {
+	return target == ErrMalformedAttrPath
+}
+
+// Validate returns an *InvalidAttrError if a field of @a is not valid utf-8.
+func (a *ServiceAttr) Validate() error {
+	for _, f := range []struct{ name, value string }{
+		{"group", a.Group},
+		{"service", a.Service},
+		{"protocol", a.Protocol},
+		{"version", a.Version},
+	} {
+		if !utf8.ValidString(f.value) {
+			return &InvalidAttrError{Field: f.name, Value: f.value}
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalPath parses @data of MarshalPath of any version. It returns a
+// *MalformedAttrPathError of the offending segment if @data is malformed.
+// @a is not changed if it fails.
 func (a *ServiceAttr) UnmarshalPath(data []byte) error {
-	rawString, err := url.QueryUnescape(gxstrings.String(data))
+	// a copy, the fields may be substrings of it
+	path := string(data)
+
+	var attr ServiceAttr
+	version, rest, err := attrPathVersion(path)
+	switch {
+	case err != nil:
+	case version == 1:
+		attr, err = unmarshalPathV1(rest)
+	default:
+		attr, err = unmarshalPathV2(rest)
+	}
+	if err == nil {
+		err = attr.Validate()
+	}
 	if err != nil {
-		return jerrors.Annotatef(err, "url.QueryUnescape(data:%s)", gxstrings.String(data))
+		return err
+	}
+	*a = attr
+
+	return nil
+}
+
+// attrPathVersion returns the version of @path and the rest of it after
+// the version marker.
+func attrPathVersion(path string) (int, string, error) {
+	i := strings.IndexByte(path, ':')
+	if len(path) == 0 || path[0] != 'v' || i < 2 {
+		return 1, path, nil
+	}
+	for _, c := range path[1:i] {
+		if c < '0' || '9' < c {
+			return 1, path, nil
+		}
 	}
 
-	query, err := url.ParseQuery(rawString)
+	version, err := strconv.Atoi(path[1:i])
+	if err != nil || version == 0 {
+		return 0, "", &MalformedAttrPathError{Segment: path[:i+1], Err: jerrors.Errorf("invalid version")}
+	}
+
+	return version, path[i+1:], nil
+}
+
+func unmarshalPathV1(path string) (ServiceAttr, error) {
+	rawString, err := url.QueryUnescape(path)
 	if err != nil {
-		return jerrors.Annotatef(err, "url.ParseQuery(string:%s)", rawString)
+		return ServiceAttr{}, &MalformedAttrPathError{Segment: path, Err: err}
+	}
+	if strings.Count(rawString, "&") >= MaxAttrPathSegments {
+		return ServiceAttr{}, &MalformedAttrPathError{Segment: rawString, Err: ErrorPathTooLong}
 	}
 
-	a.Group = query.Get("group")
-	a.Service = query.Get("service")
-	a.Protocol = query.Get("protocol")
-	a.Version = query.Get("version")
-	a.Role = String2ServiceRoleType(query.Get("role"))
+	var (
+		attr ServiceAttr
+		seen = make(map[string]bool, attrPathFields)
+	)
+	for _, segment := range strings.Split(rawString, "&") {
+		if segment == "" {
+			continue
+		}
+		key, value := segment, ""
+		if i := strings.IndexByte(segment, '='); i >= 0 {
+			key, value = segment[:i], segment[i+1:]
+		}
+		if key, err = url.QueryUnescape(key); err == nil {
+			value, err = url.QueryUnescape(value)
+		}
+		if err != nil {
+			return ServiceAttr{}, &MalformedAttrPathError{Segment: segment, Err: err}
+		}
+		// the first value of a key, as url.Values.Get
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch key {
+		case "group":
+			attr.Group = value
+		case "service":
+			attr.Service = value
+		case "protocol":
+			attr.Protocol = value
+		case "version":
+			attr.Version = value
+		case "role":
+			attr.Role = String2ServiceRoleType(value)
+		}
+	}
 
-	return nil
+	return attr, nil
+}
+
+func unmarshalPathV2(path string) (ServiceAttr, error) {
+	if strings.Count(path, string(attrPathSep)) >= MaxAttrPathSegments {
+		return ServiceAttr{}, &MalformedAttrPathError{Segment: path, Err: ErrorPathTooLong}
+	}
+	segments := strings.Split(path, string(attrPathSep))
+	if len(segments) < attrPathFields {
+		return ServiceAttr{}, &MalformedAttrPathError{
+			Segment: path,
+			Err:     jerrors.Errorf("%d fields, want %d", len(segments), attrPathFields),
+		}
+	}
+
+	var fields [attrPathFields]string
+	for i := range fields {
+		field, err := unescapeAttrField(segments[i])
+		if err != nil {
+			return ServiceAttr{}, &MalformedAttrPathError{Segment: segments[i], Err: err}
+		}
+		fields[i] = field
+	}
+
+	return ServiceAttr{
+		Group:    fields[0],
+		Service:  fields[1],
+		Protocol: fields[2],
+		Version:  fields[3],
+		Role:     String2ServiceRoleType(fields[4]),
+	}, nil
 }
 
+// EncodeService encodes @s by the json codec.
 func EncodeService(s *Service) (string, error) {
-	b, err := json.Marshal(s)
+	b, err := GetCodec(CodecJSON).Encode(s)
 	if err != nil {
-		return "", jerrors.Annotatef(err, "json.Marshal(Service:%+v)", s)
+		return "", jerrors.Trace(err)
 	}
 
 	return string(b), nil
 }
 
+// DecodeService decodes @ds encoded by the json or the protobuf codec. It
+// tries the codec that @ds looks like first, then the other one.
 func DecodeService(ds []byte) (*Service, error) {
-	var s Service
-	err := json.Unmarshal(ds, &s)
+	if len(ds) == 0 {
+		return nil, jerrors.Errorf("empty service data")
+	}
+	if len(ds) > MaxServiceSize {
+		return nil, jerrors.Annotatef(ErrorServiceTooLarge, "size:%d", len(ds))
+	}
+	first, second := GetCodec(CodecProtobuf), GetCodec(CodecJSON)
+	if isJSON(ds) {
+		first, second = second, first
+	}
+	s, err := first.Decode(ds)
+	if err == nil {
+		err = validService(s)
+	}
+	if err == nil {
+		return s, nil
+	}
+	if s, err2 := second.Decode(ds); err2 == nil && validService(s) == nil {
+		return s, nil
+	}
+
+	return nil, err
+}
+
+// DecodeServiceBy decodes @ds by the codec @c only, or by DecodeService if
+// @c is nil.
+func DecodeServiceBy(c Codec, ds []byte) (*Service, error) {
+	if c == nil {
+		return DecodeService(ds)
+	}
+	if len(ds) == 0 {
+		return nil, jerrors.Errorf("empty service data")
+	}
+	if len(ds) > MaxServiceSize {
+		return nil, jerrors.Annotatef(ErrorServiceTooLarge, "size:%d", len(ds))
+	}
+	s, err := c.Decode(ds)
 	if err != nil {
-		return nil, jerrors.Annotatef(err, "json.Unmarshal(data:%s)", gxstrings.String(ds))
+		return nil, jerrors.Annotatef(err, "%s codec Decode()", c.Name())
+	}
+	if err = validService(s); err != nil {
+		return nil, jerrors.Trace(err)
 	}
-	return &s, nil
+
+	return s, nil
+}
+
+// validService checks the fields of a decoded service which the watchers
+// and the selectors depend on.
+func validService(s *Service) error {
+	if s.Attr == nil {
+		return ErrorNoServiceAttr
+	}
+	for _, n := range s.Nodes {
+		if n == nil {
+			return jerrors.Errorf("service %+v has a nil node", *s.Attr)
+		}
+	}
+
+	return s.Attr.Validate()
 }
 
 func registryPath(paths ...string) string {
@@ -2074,7 +2552,7 @@ func registryPath(paths ...string) string {
 	return service.String()
 }
 
-// /dubbo/group%3Dbjtelecom%26protocol%3Dpb%26service%3Dshopping%26type%3Dadd%2Bservice%26version%3D1.0.1/node1
+// /dubbo/v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider/node1
 func (s *Service) Path(root string) string {
 	saPath, _ := s.Attr.MarshalPath()
 	return registryPath([]string{root, gxstrings.String(saPath)}...)
@@ -1,6 +1,9 @@
 package gxregistry
 
 import (
+	"errors"
+	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -33,28 +36,33 @@ func (suite *ServiceAddrTestSuite) TearDownSuite() {
 func (suite *ServiceAddrTestSuite) TestServiceAttr_MarshalPath() {
 	saBytes, err := suite.sa.MarshalPath()
 	suite.T().Logf("sa string:%#v, err:%#v", string(saBytes), err)
-	saStr := "group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1"
+	saStr := "v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider"
 	suite.Equalf([]byte(saStr), saBytes, "Marshal(sa:%+v)", suite.sa)
 	suite.Equalf(nil, err, "Marshal(sa:%+v)", suite.sa)
 }
 
 func (suite *ServiceAddrTestSuite) TestServiceAttr_UnmarshalPath() {
-	var sa ServiceAttr
-	saStr := "group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1"
-	err := (&sa).UnmarshalPath([]byte(saStr))
-	suite.T().Logf("suite.sa:%+v, sa:%+v", suite.sa, sa)
-	suite.Equalf(sa, suite.sa, "Unmarshal(sa:%+v)", suite.sa)
-	suite.Equalf(nil, err, "Unmarshal(sa:%+v)", suite.sa)
+	for _, saStr := range []string{
+		"v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider",
+		// v1
+		"group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1",
+	} {
+		var sa ServiceAttr
+		err := (&sa).UnmarshalPath([]byte(saStr))
+		suite.T().Logf("suite.sa:%+v, sa:%+v", suite.sa, sa)
+		suite.Equalf(sa, suite.sa, "Unmarshal(sa:%+v)", suite.sa)
+		suite.Equalf(nil, err, "Unmarshal(sa:%+v)", suite.sa)
+	}
 }
 
-// Path example: /dubbo/shopping-bjtelecom-pb-1.0.1/node1
+// Path example: /dubbo/v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider/node1
 func (suite *ServiceAddrTestSuite) TestService_NodePath() {
 	service := Service{
 		Attr:  &suite.sa,
 		Nodes: []*Node{&suite.node},
 	}
 	path := service.Path("/dubbo")
-	saStr := "group%3Dbjtelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1"
+	saStr := "v2:bjtelecom;shopping;pb;1.0.1;SRT_Provider"
 	suite.Equalf("/dubbo/"+saStr+"/", path, "service:%+v, path:%s", service, path)
 	suite.T().Logf("service path:%s", path)
 
@@ -66,3 +74,130 @@ func (suite *ServiceAddrTestSuite) TestService_NodePath() {
 func TestServiceAddrTestSuite(t *testing.T) {
 	suite.Run(t, new(ServiceAddrTestSuite))
 }
+
+func TestServiceAttrFilter(t *testing.T) {
+	attr := ServiceAttr{Group: "bj/telecom", Service: "shopping", Protocol: "pb", Version: "1.0.1", Role: SRT_Provider}
+	for _, c := range []struct {
+		filter ServiceAttr
+		match  bool
+	}{
+		{ServiceAttr{}, true},
+		{ServiceAttr{Group: "*", Service: "*", Protocol: "*", Version: "*"}, true},
+		{ServiceAttr{Service: "shopping", Role: SRT_Provider}, true},
+		{ServiceAttr{Service: "shopping", Role: SRT_Consumer}, false},
+		{ServiceAttr{Service: "shop"}, false},
+		{ServiceAttr{Version: "1.0.0,1.0.1"}, true},
+		{ServiceAttr{Version: "1.0.0,1.1.0"}, false},
+		{ServiceAttr{Version: "1.0.0,,*"}, true},
+		{ServiceAttr{Group: "!canary"}, true},
+		{ServiceAttr{Group: "!canary,!bj/telecom"}, false},
+		{ServiceAttr{Group: "*,!bj/telecom"}, false},
+		{ServiceAttr{Group: "sh,bj/telecom,!canary"}, true},
+		{ServiceAttr{Group: "bj/telecom,!bj/telecom"}, false},
+		{ServiceAttr{Protocol: "json,pb", Service: "payment,shopping", Version: "!1.0.0"}, true},
+		{ServiceAttr{Protocol: "json,pb", Service: "payment"}, false},
+	} {
+		if match := c.filter.Filter(attr); match != c.match {
+			t.Fatalf("%+v.Filter() = %v", c.filter, match)
+		}
+	}
+
+	// the attrs of UnmarshalPath are unescaped
+	var parsed ServiceAttr
+	if err := parsed.UnmarshalPath([]byte("group%3Dbj%252Ftelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1")); err != nil {
+		t.Fatalf("UnmarshalPath() = error:%s", err)
+	}
+	for _, c := range []struct {
+		group string
+		match bool
+	}{
+		{"bj/telecom", true},
+		{"bj%2Ftelecom", false},
+		{"sh,bj/telecom", true},
+		{"!bj/telecom", false},
+	} {
+		filter := ServiceAttr{Group: c.group}
+		if match := filter.Filter(parsed); match != c.match {
+			t.Fatalf("Filter(%+v) by group %q = %v", parsed, c.group, match)
+		}
+	}
+
+	// MeshFilter is of the service and the role only
+	mesh := ServiceAttr{Group: "canary", Service: "*,!payment", Role: SRT_Provider}
+	if !mesh.MeshFilter(attr) || mesh.Filter(attr) {
+		t.Fatalf("%+v.MeshFilter() = false or Filter() = true", mesh)
+	}
+}
+
+func TestServiceAttrPathRoundTrip(t *testing.T) {
+	// the separators, the escapes of v1 and v2, unicode and empty fields
+	pieces := []string{"", "a", "Z9", "/", "%", "%2F", "%%", ";", ":", "v2:", "&", "=", "+", " ", ",", "\x00", "\x7f", "é", "世界", "\U0001F600"}
+	randField := func(r *rand.Rand) string {
+		var b strings.Builder
+		for n := r.Intn(5); n > 0; n-- {
+			b.WriteString(pieces[r.Intn(len(pieces))])
+		}
+		return b.String()
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		attr := ServiceAttr{
+			Group:    randField(r),
+			Service:  randField(r),
+			Protocol: randField(r),
+			Version:  randField(r),
+			Role:     ServiceRoleType(r.Intn(len(ServiceRoleType_name))),
+		}
+		path, err := attr.MarshalPath()
+		if err != nil {
+			t.Fatalf("MarshalPath(%+v) = error:%s", attr, err)
+		}
+		// a node name of zookeeper
+		if strings.ContainsAny(string(path), "/\x00") {
+			t.Fatalf("MarshalPath(%+v) = %q", attr, path)
+		}
+		var parsed ServiceAttr
+		if err = parsed.UnmarshalPath(path); err != nil || parsed != attr {
+			t.Fatalf("UnmarshalPath(%q) = attr:%+v, error:%v, want:%+v", path, parsed, err, attr)
+		}
+	}
+}
+
+func TestServiceAttrPathVersions(t *testing.T) {
+	attr := ServiceAttr{Group: "bj/telecom", Service: "shopping", Protocol: "pb", Version: "1.0.1", Role: SRT_Provider}
+	for _, path := range []string{
+		"v2:bj%2Ftelecom;shopping;pb;1.0.1;SRT_Provider",
+		// the fields appended by a later version are ignored
+		"v3:bj%2Ftelecom;shopping;pb;1.0.1;SRT_Provider;canary",
+		// v1 with or without its marker
+		"group%3Dbj%252Ftelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1",
+		"v1:group%3Dbj%252Ftelecom%26protocol%3Dpb%26role%3DSRT_Provider%26service%3Dshopping%26version%3D1.0.1",
+		// v1 of any order, unknown keys are ignored
+		"version%3D1.0.1%26weight%3D3%26service%3Dshopping%26protocol%3Dpb%26role%3DSRT_Provider%26group%3Dbj%252Ftelecom",
+	} {
+		var parsed ServiceAttr
+		if err := parsed.UnmarshalPath([]byte(path)); err != nil || parsed != attr {
+			t.Fatalf("UnmarshalPath(%q) = attr:%+v, error:%v, want:%+v", path, parsed, err, attr)
+		}
+	}
+
+	for _, c := range []struct {
+		path, segment string
+	}{
+		{"v2:bj;shopping;pb;1.0.1", "bj;shopping;pb;1.0.1"},
+		{"v2:bj;shop%zzping;pb;1.0.1;", "shop%zzping"},
+		{"v2:bj;shopping;pb;1.0.1;SRT_Provider%2", "SRT_Provider%2"},
+		{"v0:bj;shopping;pb;1.0.1;", "v0:"},
+		{"group%3Dbj%26service%3D%25zz", "service=%zz"},
+		{"group%3Dbj%zz", "group%3Dbj%zz"},
+		{"v2:" + strings.Repeat(";", MaxAttrPathSegments), strings.Repeat(";", MaxAttrPathSegments)},
+	} {
+		var parsed ServiceAttr
+		err := parsed.UnmarshalPath([]byte(c.path))
+		var malformed *MalformedAttrPathError
+		if !errors.Is(err, ErrMalformedAttrPath) || !errors.As(err, &malformed) || malformed.Segment != c.segment {
+			t.Fatalf("UnmarshalPath(%q) = error:%v, want the segment %q", c.path, err, c.segment)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxregistry provides a interface for service register/discovery
+package gxregistry
+
+import (
+	"context"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+// withContext runs @fn, and returns ctx.Err() if @ctx is done before it
+// returns.
+func withContext(ctx context.Context, fn func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fn()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeregisterHook returns a stop hook deregistering @services from @r, e.g.
+// of gxruntime.Coordinator. All the services are deregistered even if some
+// fail, and the first error is returned.
+func DeregisterHook(r Registry, services ...Service) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return withContext(ctx, func() error {
+			var first error
+			for _, s := range services {
+				if err := r.Deregister(s); err != nil && first == nil {
+					first = jerrors.Annotatef(err, "Deregister(service:%+v)", s)
+				}
+			}
+			return first
+		})
+	}
+}
+
+// CloseWatcherHook returns a stop hook closing @w.
+func CloseWatcherHook(w Watcher) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return withContext(ctx, func() error {
+			w.Close()
+			return nil
+		})
+	}
+}
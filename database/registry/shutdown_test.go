@@ -0,0 +1,65 @@
+package gxregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type deregistry struct {
+	Registry // not implemented
+	sync.Mutex
+	fail  string
+	block chan struct{}
+	done  []string
+}
+
+func (r *deregistry) Deregister(s Service) error {
+	if r.block != nil {
+		<-r.block
+	}
+	if s.Attr.Service == r.fail {
+		return fmt.Errorf("failed to deregister %s", s.Attr.Service)
+	}
+	r.Lock()
+	r.done = append(r.done, s.Attr.Service)
+	r.Unlock()
+	return nil
+}
+
+type closeWatcher struct {
+	Watcher // not implemented
+	closed  bool
+}
+
+func (w *closeWatcher) Close() {
+	w.closed = true
+}
+
+func TestStopHooks(t *testing.T) {
+	services := []Service{
+		{Attr: &ServiceAttr{Service: "shopping"}},
+		{Attr: &ServiceAttr{Service: "payment"}},
+		{Attr: &ServiceAttr{Service: "refund"}},
+	}
+	r := &deregistry{fail: "payment"}
+	err := DeregisterHook(r, services...)(context.Background())
+	if err == nil || len(r.done) != 2 || r.done[1] != "refund" {
+		t.Fatalf("DeregisterHook() = error:%v, deregistered:%v", err, r.done)
+	}
+
+	r = &deregistry{block: make(chan struct{})}
+	defer close(r.block)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = DeregisterHook(r, services...)(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("DeregisterHook() of a blocked registry = error:%v", err)
+	}
+
+	w := &closeWatcher{}
+	if err = CloseWatcherHook(w)(context.Background()); err != nil || !w.closed {
+		t.Fatalf("CloseWatcherHook() = error:%v, closed:%v", err, w.closed)
+	}
+}
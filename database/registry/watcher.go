@@ -5,18 +5,60 @@
 // Package gxregistry provides a interface for service register/discovery
 package gxregistry
 
+import (
+	"context"
+)
+
 import (
 	jerrors "github.com/juju/errors"
 )
 
-// Watcher provides an interface for service discovery
+// Watcher provides an interface for service discovery.
+//
+// The Revision of an event is the revision of its service node in the
+// registry, e.g. the Mzxid of the zookeeper node or the ModRevision of the
+// etcd key, 0 if unknown. It increases with every version of a node, and a
+// Del is of the revision of the deleted version, so a consumer drops an
+// event of a node older than the last one it has handled when the node
+// flaps.
 type Watcher interface {
 	Notify() (*EventResult, error)
+	// NotifyCtx is Notify which gives up when @ctx is done, and returns
+	// ctx.Err() then. Both return ErrWatcherClosed after Close.
+	NotifyCtx(ctx context.Context) (*EventResult, error)
 	Valid() bool // 检查watcher与registry连接是否正常
 	Close()
 	IsClosed() bool
 }
 
+// Snapshotter is a Watcher which returns the services under its root at
+// once, e.g. the zookeeper watcher. Check it by a type assertion. The events
+// notified after Snapshot are the changes since it, so that a service is in
+// the snapshot or notified as added.
+type Snapshotter interface {
+	Watcher
+	Snapshot() ([]*Service, error)
+}
+
+// ReplayWatcher is a Watcher which keeps the last events it notified, e.g. the
+// zookeeper watcher. Check it by a type assertion. Every notified event has
+// a Seq increasing by one from 1, so a consumer restarting its Notify loop
+// gets the events it may have lost by Replay of the last Seq it handled.
+type ReplayWatcher interface {
+	Watcher
+	// Replay returns the kept events notified after the one of @seq, in
+	// order. It returns ErrReplayGap if some of them are not kept any more,
+	// so the consumer must resync, e.g. by Snapshot, and ErrWatcherClosed
+	// after Close.
+	Replay(seq uint64) ([]*EventResult, error)
+}
+
 var (
 	ErrWatcherClosed = jerrors.Errorf("Watcher closed")
+	// ErrSessionExpired is notified by a watcher when the session of its
+	// registry expires, so that the consumers flush the services of it.
+	ErrSessionExpired = jerrors.Errorf("registry session expired")
+	// ErrReplayGap is returned by ReplayWatcher.Replay of an event older than the
+	// kept ones.
+	ErrReplayGap = jerrors.Errorf("replay gap")
 )
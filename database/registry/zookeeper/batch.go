@@ -0,0 +1,260 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxzookeeper provides a zookeeper registry
+package gxzookeeper
+
+import (
+	"context"
+	"time"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
+)
+
+// maxMultiSize is the max size of the paths and the data of the nodes of a
+// transaction, under the default jute.maxbuffer of a request with room for
+// its headers. A larger batch is done node by node.
+var maxMultiSize = gxregistry.MaxServiceSize - 64<<10
+
+var _ gxregistry.BatchRegistry = (*Registry)(nil)
+
+// batchNode is a zookeeper node of the service @service of a batch.
+type batchNode struct {
+	service int
+	path    string
+	data    []byte
+}
+
+// batchResult is the errors of the services of a batch, the services
+// without an error are done.
+type batchResult struct {
+	op       string
+	services []gxregistry.Service
+	errs     map[int]error
+}
+
+func newBatchResult(op string, services []gxregistry.Service) *batchResult {
+	return &batchResult{op: op, services: services, errs: make(map[int]error)}
+}
+
+// abort fails the service @i by @err, and the others by ErrorBatchAborted
+// unless they have failed. A negative @i fails all by @err.
+func (b *batchResult) abort(i int, err error) error {
+	for j := range b.services {
+		if _, ok := b.errs[j]; ok {
+			continue
+		}
+		if i < 0 || j == i {
+			b.errs[j] = err
+		} else {
+			b.errs[j] = gxregistry.ErrorBatchAborted
+		}
+	}
+
+	return b.err()
+}
+
+// err returns nil if all services are done, or a *gxregistry.BatchError.
+func (b *batchResult) err() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+
+	e := &gxregistry.BatchError{Op: b.op}
+	for i, s := range b.services {
+		if err, ok := b.errs[i]; ok {
+			e.Failed = append(e.Failed, gxregistry.BatchFailure{Service: s, Err: err})
+		} else {
+			e.Succeeded = append(e.Succeeded, s)
+		}
+	}
+
+	return e
+}
+
+// RegisterBatch registers @services in a zookeeper transaction, so that all
+// or none of their nodes are created, of the same paths and data as those
+// of Register. A batch too large for a transaction is registered node by
+// node, and the nodes created are deleted if one fails. The persistent
+// paths of the services are created before either way, as by Register.
+func (r *Registry) RegisterBatch(services []gxregistry.Service) (err error) {
+	defer r.observe(gxregistry.OpRegisterBatch, time.Now(), &err)
+
+	result := newBatchResult(gxregistry.OpRegister, services)
+	advertised := make([]gxregistry.Service, len(services))
+
+	r.regLock.Lock()
+	defer r.regLock.Unlock()
+
+	var (
+		nodes []batchNode
+		size  int
+	)
+	for i, s := range services {
+		if len(s.Nodes) == 0 {
+			return result.abort(i, jerrors.Errorf("Require at least one node"))
+		}
+		s, e := r.options.AdvertiseService(s)
+		if e != nil {
+			return result.abort(i, e)
+		}
+		if _, exist := r.exist(s); exist {
+			return result.abort(i, gxregistry.ErrorAlreadyRegister)
+		}
+		advertised[i] = s
+
+		for _, node := range s.Nodes {
+			data, e := r.nodeData(s, node)
+			if e != nil {
+				return result.abort(i, e)
+			}
+			n := batchNode{service: i, path: s.NodePath(r.options.Root, *node), data: data}
+			nodes = append(nodes, n)
+			size += len(n.path) + len(n.data)
+		}
+	}
+
+	for i, s := range advertised {
+		zkPath := s.Path(r.options.Root)
+		if e := r.client.EnsurePath(zkPath, r.acl); e != nil {
+			return result.abort(i, jerrors.Annotatef(e, "zkClient.EnsurePath(%s)", zkPath))
+		}
+	}
+
+	if size <= maxMultiSize {
+		r.createMulti(nodes, result)
+	} else {
+		r.createEach(nodes, result)
+	}
+	if err = result.err(); err != nil {
+		return err
+	}
+	for _, s := range advertised {
+		r.addService(s)
+	}
+
+	return nil
+}
+
+// createMulti creates @nodes in a transaction.
+func (r *Registry) createMulti(nodes []batchNode, result *batchResult) {
+	ops := make([]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		ops = append(ops, &zk.CreateRequest{
+			Path:  n.path,
+			Data:  n.data,
+			Acl:   r.acl,
+			Flags: zk.FlagEphemeral,
+		})
+	}
+
+	var rsp []zk.MultiResponse
+	err := r.retry(func(context.Context) error {
+		var err error
+		rsp, err = r.client.Multi(ops...)
+		return err
+	})
+	if err == nil {
+		return
+	}
+	for i, res := range rsp {
+		if res.Error != nil && i < len(nodes) {
+			result.abort(nodes[i].service, jerrors.Annotatef(res.Error, "zk.Create(%s, ephemeral)", nodes[i].path))
+			return
+		}
+	}
+	result.abort(-1, err)
+}
+
+// createEach creates @nodes one by one, and deletes the nodes created if
+// one fails. The services of the nodes failing to be deleted fail by the
+// errors of the deletions.
+func (r *Registry) createEach(nodes []batchNode, result *batchResult) {
+	for k, n := range nodes {
+		err := r.retry(func(context.Context) error {
+			_, err := r.client.CreateTemp(n.path, n.data, r.acl)
+			return err
+		})
+		if err == nil {
+			continue
+		}
+
+		err = jerrors.Annotatef(err, "gxregister.CreateTemp(path:%s)", n.path)
+		result.errs[n.service] = err
+		for i := k - 1; i >= 0; i-- {
+			if e := r.client.DeleteZkPath(nodes[i].path); e != nil {
+				gxlog.LogError(r.logger, "rollback of the register batch failed", e, "path", nodes[i].path)
+				if _, ok := result.errs[nodes[i].service]; !ok {
+					result.errs[nodes[i].service] = jerrors.Annotatef(e, "rollback of %s", nodes[i].path)
+				}
+			}
+		}
+		result.abort(n.service, err)
+		return
+	}
+}
+
+// UnregisterBatch deletes the nodes of @services in a zookeeper transaction.
+// If it fails, e.g. a node has been deleted, or the batch is too large for
+// a transaction, the services are deregistered one by one as Deregister
+// does, and the failures are returned by a *gxregistry.BatchError.
+func (r *Registry) UnregisterBatch(services []gxregistry.Service) (err error) {
+	defer r.observe(gxregistry.OpUnregisterBatch, time.Now(), &err)
+
+	result := newBatchResult(gxregistry.OpDeregister, services)
+	advertised := make([]gxregistry.Service, 0, len(services))
+	index := make([]int, 0, len(services))
+
+	r.regLock.Lock()
+	defer r.regLock.Unlock()
+
+	var (
+		ops  []interface{}
+		size int
+	)
+	for i, s := range services {
+		if len(s.Nodes) == 0 {
+			result.errs[i] = jerrors.Errorf("Require at least one node")
+			continue
+		}
+		s, e := r.options.AdvertiseService(s)
+		if e != nil {
+			result.errs[i] = e
+			continue
+		}
+		advertised = append(advertised, s)
+		index = append(index, i)
+		for _, node := range s.Nodes {
+			zkPath := s.NodePath(r.options.Root, *node)
+			ops = append(ops, &zk.DeleteRequest{Path: zkPath, Version: -1})
+			size += len(zkPath)
+		}
+	}
+
+	if len(ops) > 0 && size <= maxMultiSize {
+		if _, e := r.client.Multi(ops...); e == nil {
+			for _, s := range advertised {
+				r.deleteService(s)
+			}
+			return result.err()
+		}
+	}
+
+	for k, s := range advertised {
+		r.deleteService(s)
+		if e := r.unregister(s); e != nil {
+			result.errs[index[k]] = jerrors.Trace(e)
+		}
+	}
+
+	return result.err()
+}
@@ -0,0 +1,170 @@
+package gxzookeeper
+
+import (
+	"bytes"
+	"testing"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/database/registry/zookeeper/zktest"
+)
+
+func batchServices(z *fakeZk) []gxregistry.Service {
+	cart := fakeAttr
+	cart.Service = "cart"
+
+	return []gxregistry.Service{
+		z.service(fakeAttr, "node0"),
+		z.service(cart, "node1"),
+		z.service(fakeAttr, "node2"),
+	}
+}
+
+func batchError(t *testing.T, err error, succeeded, failed int) *gxregistry.BatchError {
+	e, ok := err.(*gxregistry.BatchError)
+	if !ok {
+		t.Fatalf("error %v, want a *BatchError", err)
+	}
+	if len(e.Succeeded) != succeeded || len(e.Failed) != failed {
+		t.Fatalf("error %v, succeeded %d, failed %d", e, len(e.Succeeded), len(e.Failed))
+	}
+	return e
+}
+
+func TestFakeRegisterBatch(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	w := z.watch(t)
+	defer w.Close()
+
+	services := batchServices(z)
+	if err := z.reg.RegisterBatch(services); err != nil {
+		t.Fatalf("RegisterBatch() = error:%s", err)
+	}
+	// the cart is filtered
+	for i := 0; i < 2; i++ {
+		e := notify(t, w)
+		if e.Action != gxregistry.ServiceAdd || e.Service.Attr.Service != "shopping" {
+			t.Fatalf("Notify() = %s", e.GoString())
+		}
+	}
+
+	// the same nodes as Register creates
+	single := newFakeZk()
+	defer single.reg.Close()
+	for _, s := range services {
+		single.register(t, *s.Attr, s.Nodes[0].ID)
+		path := s.NodePath("/test", *s.Nodes[0])
+		data, ok := z.client.Data(path)
+		if want, _ := single.client.Data(path); !ok || !bytes.Equal(data, want) {
+			t.Fatalf("data of %s = %q, want %q", path, data, want)
+		}
+	}
+	if err := z.reg.RegisterBatch(services[:1]); jerrors.Cause(batchError(t, err, 0, 1).First()) != gxregistry.ErrorAlreadyRegister {
+		t.Fatalf("RegisterBatch() again = error:%v", err)
+	}
+
+	if err := z.reg.UnregisterBatch(services); err != nil {
+		t.Fatalf("UnregisterBatch() = error:%s", err)
+	}
+	for _, s := range services {
+		if _, ok := z.client.Data(s.NodePath("/test", *s.Nodes[0])); ok {
+			t.Fatalf("node %s is left", s.NodePath("/test", *s.Nodes[0]))
+		}
+	}
+	// registered again, so they have been forgotten
+	if err := z.reg.RegisterBatch(services); err != nil {
+		t.Fatalf("RegisterBatch() after UnregisterBatch() = error:%s", err)
+	}
+}
+
+func TestFakeRegisterBatchConflict(t *testing.T) {
+	for _, multi := range []bool{true, false} {
+		z := newFakeZk()
+		if !multi {
+			// node by node with rollback
+			defer func(size int) { maxMultiSize = size }(maxMultiSize)
+			maxMultiSize = 0
+		}
+
+		services := batchServices(z)
+		conflict := services[1].NodePath("/test", *services[1].Nodes[0])
+		z.client.Create(conflict, []byte("other"))
+
+		e := batchError(t, z.reg.RegisterBatch(services), 0, 3)
+		for i, f := range e.Failed {
+			if i == 1 {
+				if jerrors.Cause(f.Err) != zk.ErrNodeExists {
+					t.Fatalf("multi %v, error of the conflicting service = %v", multi, f.Err)
+				}
+			} else if f.Err != gxregistry.ErrorBatchAborted {
+				t.Fatalf("multi %v, error of service %d = %v", multi, i, f.Err)
+			}
+		}
+		if jerrors.Cause(e.First()) != zk.ErrNodeExists {
+			t.Fatalf("multi %v, First() = %v", multi, e.First())
+		}
+		for _, s := range services {
+			path := s.NodePath("/test", *s.Nodes[0])
+			if data, ok := z.client.Data(path); path != conflict && ok {
+				t.Fatalf("multi %v, node %s is left", multi, path)
+			} else if path == conflict && string(data) != "other" {
+				t.Fatalf("multi %v, the conflicting node is %q", multi, data)
+			}
+		}
+
+		// nothing is kept by the registry
+		z.client.Delete(conflict)
+		if err := z.reg.RegisterBatch(services); err != nil {
+			t.Fatalf("multi %v, RegisterBatch() = error:%s", multi, err)
+		}
+		z.reg.Close()
+	}
+}
+
+func TestFakeRegisterBatchRollbackFailure(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	defer func(size int) { maxMultiSize = size }(maxMultiSize)
+	maxMultiSize = 0
+
+	services := batchServices(z)
+	z.client.Create(services[2].NodePath("/test", *services[2].Nodes[0]), []byte("other"))
+	z.client.SetError(gxzktest.OpDeleteZkPath, zk.ErrConnectionClosed)
+
+	// the nodes which can not be deleted are of the failed services
+	e := batchError(t, z.reg.RegisterBatch(services), 0, 3)
+	for i, f := range e.Failed {
+		if f.Err == gxregistry.ErrorBatchAborted || (i < 2 && jerrors.Cause(f.Err) != zk.ErrConnectionClosed) {
+			t.Fatalf("error of service %d = %v", i, f.Err)
+		}
+	}
+}
+
+func TestFakeUnregisterBatch(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+
+	services := batchServices(z)
+	if err := z.reg.RegisterBatch(services); err != nil {
+		t.Fatalf("RegisterBatch() = error:%s", err)
+	}
+	// a node deleted fails the transaction, and the others are deleted
+	// one by one
+	z.client.Delete(services[0].NodePath("/test", *services[0].Nodes[0]))
+	e := batchError(t, z.reg.UnregisterBatch(services), 2, 1)
+	if jerrors.Cause(e.Failed[0].Err) != zk.ErrNoNode || e.Failed[0].Service.Nodes[0].ID != "node0" {
+		t.Fatalf("failed %+v", e.Failed)
+	}
+	for _, s := range services {
+		if _, ok := z.client.Data(s.NodePath("/test", *s.Nodes[0])); ok {
+			t.Fatalf("node %s is left", s.NodePath("/test", *s.Nodes[0]))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxzookeeper provides a zookeeper registry
+package gxzookeeper
+
+import (
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/zookeeper"
+)
+
+// ZkClient is the zookeeper client of the registry and its watchers. It is
+// implemented by *gxzookeeper.Client of database/zookeeper, and by
+// gxzktest.Client in the tests.
+type ZkClient interface {
+	State() zk.State
+	StateToString(state zk.State) string
+	EnsurePath(path string, acl []zk.ACL) error
+	DeleteZkPath(path string) error
+	CreateTemp(path string, data []byte, acl []zk.ACL) (string, error)
+	Multi(ops ...interface{}) ([]zk.MultiResponse, error)
+	Get(path string) ([]byte, error)
+	GetWithStat(path string) ([]byte, *zk.Stat, error)
+	GetChildren(path string) ([]string, error)
+	GetChildrenW(path string) ([]string, <-chan zk.Event, error)
+	ExistW(path string) (<-chan zk.Event, error)
+	Close()
+}
+
+var _ ZkClient = (*gxzookeeper.Client)(nil)
@@ -0,0 +1,112 @@
+package gxzookeeper
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/database/registry/selector"
+	"github.com/AlexStocks/goext/log"
+)
+
+// loads is a LoadProbe returning the loads set by its test.
+type loads struct {
+	sync.Mutex
+	load gxregistry.Load
+}
+
+func (l *loads) set(load gxregistry.Load) {
+	l.Lock()
+	l.load = load
+	l.Unlock()
+}
+
+func (l *loads) probe() (gxregistry.Load, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.load, nil
+}
+
+// expectLoad expects the Del and the Add of the node @id, or the Add only
+// if they are coalesced.
+func expectLoad(t *testing.T, w *Watcher, id string, cpu float64) {
+	e := notify(t, w)
+	if e.Action == gxregistry.ServiceDel && e.Service.Nodes[0].ID == id {
+		e = notify(t, w)
+	}
+	if e.Action != gxregistry.ServiceAdd || e.Service.Nodes[0].ID != id {
+		t.Fatalf("Notify() = %s, want ServiceAdd of %s", e.GoString(), id)
+	}
+	if l, ok := gxregistry.NodeLoad(e.Service.Nodes[0]); !ok || l.CPUPercent != cpu {
+		t.Fatalf("load of node %s = %+v, %v, want cpu %v", id, l, ok, cpu)
+	}
+}
+
+func TestHeartbeatLoad(t *testing.T) {
+	z := newFakeZk()
+	busy := z.register(t, fakeAttr, "busy")
+	idle := z.register(t, fakeAttr, "idle")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "busy")
+	expectEvent(t, w, gxregistry.ServiceAdd, "idle")
+
+	var busyLoad, idleLoad loads
+	busyLoad.set(gxregistry.Load{CPUPercent: 90, RSS: 1 << 30, Goroutines: 1000})
+	idleLoad.set(gxregistry.Load{CPUPercent: 10, RSS: 1 << 30, Goroutines: 1000})
+	hb, err := gxregistry.NewHeartbeat(z.reg, busy, time.Second, busyLoad.probe, gxregistry.WithHeartbeatClock(z.clock))
+	if err != nil {
+		t.Fatalf("NewHeartbeat() = error:%s", err)
+	}
+	idleHb, _ := gxregistry.NewHeartbeat(z.reg, idle, time.Second, idleLoad.probe)
+
+	// the first beat writes the load
+	z.client.WaitWatch(busy.NodePath("/test", *busy.Nodes[0]))
+	hb.Start()
+	defer hb.Stop()
+	z.clock.BlockUntil(1)
+	z.clock.Advance(time.Second)
+	expectLoad(t, w, "busy", 90)
+
+	// a change under the threshold does not rewrite the nodes
+	busyLoad.set(gxregistry.Load{CPUPercent: 92, RSS: 1 << 30, Goroutines: 1010})
+	z.clock.Advance(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if e, err := w.NotifyCtx(ctx); err == nil {
+		t.Fatalf("Notify() = %s under the load threshold", e.GoString())
+	}
+	busyLoad.set(gxregistry.Load{CPUPercent: 80, RSS: 1 << 30, Goroutines: 1000})
+	z.client.WaitWatch(busy.NodePath("/test", *busy.Nodes[0]))
+	z.clock.Advance(time.Second)
+	expectLoad(t, w, "busy", 80)
+
+	z.client.WaitWatch(idle.NodePath("/test", *idle.Nodes[0]))
+	if err = idleHb.Beat(); err != nil {
+		t.Fatalf("Beat() = error:%s", err)
+	}
+	expectLoad(t, w, "idle", 10)
+
+	// the selector picks the idle node more
+	s, err := gxselector.NewSelector(z.reg, gxselector.WithStrategy(gxselector.LoadWeighted),
+		gxselector.WithLogger(gxlog.NewNop()))
+	if err != nil {
+		t.Fatalf("NewSelector() = error:%s", err)
+	}
+	defer s.Close()
+	ids := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		node, err := s.Select(fakeAttr)
+		if err != nil {
+			t.Fatalf("Select() = error:%s", err)
+		}
+		ids[node.ID]++
+	}
+	if ids["idle"] < 2*ids["busy"] {
+		t.Fatalf("load weighted ids:%v", ids)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxzookeeper
+
+import (
+	"container/list"
+	"sync"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+// eventQueue is the unbounded FIFO queue of the events of a watcher, so
+// that its watch goroutines never block on a slow selector. The events of
+// a service node are coalesced into its last action:
+//
+//	queued Add + Add -> Add of the new service
+//	queued Del + Add -> Add, which replaces the node of the selector
+//	queued Add + Del -> nothing, if the node is not notified before
+//	queued Add + Del -> Del, if the queued Add is of a queued Del + Add
+//
+// and the node is moved to the end of the queue. The events without a
+// node, e.g. the errors, are not coalesced.
+type eventQueue struct {
+	sync.Mutex
+	events    *list.List               // of *queuedEvent
+	nodes     map[string]*list.Element // the queued event of a node
+	ready     chan struct{}            // signaled after a push
+	maxLen    int
+	coalesced int64
+}
+
+type queuedEvent struct {
+	event
+	known bool // whether the node is notified before the queued events
+}
+
+// newEventQueue returns a queue of the capacity hint @size.
+func newEventQueue(size int) *eventQueue {
+	return &eventQueue{
+		events: list.New(),
+		nodes:  make(map[string]*list.Element, size),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+func (q *eventQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *eventQueue) push(e event) {
+	q.Lock()
+	defer q.Unlock()
+	defer q.signal()
+
+	if e.node == "" || e.res == nil {
+		q.events.PushBack(&queuedEvent{event: e})
+		q.grow()
+		return
+	}
+
+	elem, ok := q.nodes[e.node]
+	if !ok {
+		// a Del or an Update is of a notified node
+		qe := &queuedEvent{event: e, known: e.res.Action != gxregistry.ServiceAdd}
+		q.nodes[e.node] = q.events.PushBack(qe)
+		q.grow()
+		return
+	}
+
+	qe := elem.Value.(*queuedEvent)
+	q.coalesced++
+	if e.res.Action == gxregistry.ServiceDel && !qe.known {
+		q.coalesced++ // the queued Add is not notified either
+		q.events.Remove(elem)
+		delete(q.nodes, e.node)
+		return
+	}
+	qe.event = e
+	q.events.MoveToBack(elem)
+}
+
+// grow records the max length of the queue, q must be locked.
+func (q *eventQueue) grow() {
+	if n := q.events.Len(); n > q.maxLen {
+		q.maxLen = n
+	}
+}
+
+// pop returns the first event, and false if the queue is empty.
+func (q *eventQueue) pop() (event, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	elem := q.events.Front()
+	if elem == nil {
+		return event{}, false
+	}
+	qe := q.events.Remove(elem).(*queuedEvent)
+	if qe.node != "" {
+		delete(q.nodes, qe.node)
+	}
+	if q.events.Len() != 0 {
+		// for the other callers of Notify
+		q.signal()
+	}
+
+	return qe.event, true
+}
+
+// dropAdd drops the queued Add of @node, and returns whether it is queued.
+func (q *eventQueue) dropAdd(node string) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	elem, ok := q.nodes[node]
+	if !ok || elem.Value.(*queuedEvent).res.Action == gxregistry.ServiceDel {
+		return false
+	}
+	q.events.Remove(elem)
+	delete(q.nodes, node)
+
+	return true
+}
+
+func (q *eventQueue) clear() {
+	q.Lock()
+	q.events.Init()
+	q.nodes = make(map[string]*list.Element)
+	q.Unlock()
+}
+
+// stats returns the length, the max length and the coalesced events of
+// the queue.
+func (q *eventQueue) stats() (int, int, int64) {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.events.Len(), q.maxLen, q.coalesced
+}
@@ -6,13 +6,16 @@
 package gxzookeeper
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 	//"io/ioutil"
 )
 
 import (
-	log "github.com/AlexStocks/log4go"
 	jerrors "github.com/juju/errors"
 	"github.com/samuel/go-zookeeper/zk"
 )
@@ -20,6 +23,8 @@ import (
 import (
 	"github.com/AlexStocks/goext/database/registry"
 	"github.com/AlexStocks/goext/database/zookeeper"
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/time"
 )
 
 //////////////////////////////////////////////
@@ -27,53 +32,101 @@ import (
 //////////////////////////////////////////////
 
 type Registry struct {
-	client          *gxzookeeper.Client
+	client          ZkClient
 	options         gxregistry.Options
+	acl             []zk.ACL     // of the registered nodes
+	clock           gxtime.Clock // of the watcher backoff
+	logger          gxlog.Logger
 	sync.Mutex      // lock for client + register
 	done            chan struct{}
 	wg              sync.WaitGroup
 	eventRegistry   map[string][]*chan struct{}
 	serviceRegistry map[gxregistry.ServiceAttr]gxregistry.Service
+	regLock         sync.Mutex            // serializes the registrations and handleZkRestart
+	stateLock       sync.Mutex            // lock connState + watchers
+	connState       gxregistry.ConnState  // of the session events
+	watchers        map[*Watcher]struct{} // notified of the conn state
 }
 
-func NewRegistry(opts ...gxregistry.Option) (gxregistry.Registry, error) {
-	var (
-		err     error
-		r       *Registry
-		conn    *zk.Conn
-		event   <-chan zk.Event
-		options gxregistry.Options
-	)
-
+func registryOptions(opts ...gxregistry.Option) gxregistry.Options {
+	var options gxregistry.Options
 	for _, o := range opts {
 		o(&options)
 	}
-	if options.Addrs == nil {
-		return nil, jerrors.Errorf("@options.Addrs is nil")
-	}
 	if options.Timeout == 0 {
 		options.Timeout = gxregistry.DefaultTimeout
 	}
+	if options.Codec == nil {
+		options.Codec = gxregistry.GetCodec(gxregistry.CodecJSON)
+	}
 	if options.Root == "" {
 		options.Root = gxregistry.DefaultServiceRoot
 	}
+	if options.Logger == nil {
+		options.Logger = gxlog.Default()
+	}
+
+	return options
+}
+
+func NewRegistry(opts ...gxregistry.Option) (gxregistry.Registry, error) {
+	options := registryOptions(opts...)
+	if options.Addrs == nil {
+		return nil, jerrors.Errorf("@options.Addrs is nil")
+	}
 	// connect to zookeeper
 	//zk.DefaultLogger = golog.New(ioutil.Discard, "[goext] ", golog.LstdFlags)
-	conn, event, err = zk.Connect(options.Addrs, options.Timeout)
+	conn, event, err := zk.Connect(options.Addrs, options.Timeout)
 	if err != nil {
 		return nil, jerrors.Annotatef(err, "zk.Connect(zk addr:%#v, timeout:%d)",
 			options.Addrs, options.Timeout)
 	}
-	r = &Registry{
+
+	client := gxzookeeper.NewClient(conn)
+	if options.ACL != nil {
+		auth := options.ACL.User + ":" + options.ACL.Password
+		if err = client.AddAuth("digest", []byte(auth)); err != nil {
+			conn.Close()
+			return nil, jerrors.Annotatef(err, "zk.AddAuth(user:%s)", options.ACL.User)
+		}
+	}
+
+	return newRegistry(options, client, event), nil
+}
+
+// NewRegistryWithClient returns a registry on @client whose session events
+// are @session, e.g. of a gxzktest.Client. @opts.Addrs is not used, and
+// @client must be authenticated by the credential of @opts.ACL if any.
+func NewRegistryWithClient(client ZkClient, session <-chan zk.Event, opts ...gxregistry.Option) gxregistry.Registry {
+	return newRegistry(registryOptions(opts...), client, session)
+}
+
+func newRegistry(options gxregistry.Options, client ZkClient, session <-chan zk.Event) *Registry {
+	r := &Registry{
 		options:         options,
-		client:          gxzookeeper.NewClient(conn),
+		acl:             nodeACL(options.ACL),
+		logger:          options.Logger,
+		client:          client,
+		clock:           gxtime.RealClock{},
 		done:            make(chan struct{}),
 		eventRegistry:   make(map[string][]*chan struct{}),
 		serviceRegistry: make(map[gxregistry.ServiceAttr]gxregistry.Service),
+		watchers:        make(map[*Watcher]struct{}),
+	}
+	r.wg.Add(1)
+	go r.handleZkEvent(session)
+
+	return r
+}
+
+// nodeACL returns the ACL of the registered nodes of @acl, which are open
+// to all if it is nil.
+func nodeACL(acl *gxregistry.DigestACL) []zk.ACL {
+	if acl == nil {
+		return zk.WorldACL(zk.PermAll)
 	}
-	go r.handleZkEvent(event)
 
-	return r, nil
+	return append(zk.DigestACL(zk.PermAll, acl.User, acl.Password), zk.WorldACL(zk.PermRead)...)
 }
 
 func (r *Registry) registerEvent(path string, event *chan struct{}) {
@@ -86,7 +139,7 @@ func (r *Registry) registerEvent(path string, event *chan struct{}) {
 	a = append(a, event)
 	r.eventRegistry[path] = a
 	r.Unlock()
-	log.Debug("zkClient register event{path:%s, ptr:%p}", path, event)
+	r.logger.Debugw("zkClient register event", "path", path, "event", fmt.Sprintf("%p", event))
 }
 
 func (r *Registry) unregisterEvent(path string, event *chan struct{}) {
@@ -104,10 +157,10 @@ func (r *Registry) unregisterEvent(path string, event *chan struct{}) {
 			if e == event {
 				arr := a
 				a = append(arr[:i], arr[i+1:]...)
-				log.Debug("zkClient unregister event{path:%s, event:%p}", path, event)
+				r.logger.Debugw("zkClient unregister event", "path", path, "event", fmt.Sprintf("%p", event))
 			}
 		}
-		log.Debug("after zkClient unregister event{path:%s, event:%p}, array length %d", path, event, len(a))
+		r.logger.Debugw("after zkClient unregister event", "path", path, "events", len(a))
 		if len(a) == 0 {
 			delete(r.eventRegistry, path)
 		} else {
@@ -118,25 +171,147 @@ func (r *Registry) unregisterEvent(path string, event *chan struct{}) {
 	r.Unlock()
 }
 
+// handleZkRestart registers the registered services again after the session
+// expired, as their ephemeral nodes are deleted with it. A service is retried
+// by the Reregister policy, and one deregistered meanwhile is given up.
 func (r *Registry) handleZkRestart() {
-	var (
-		err      error
-		services []gxregistry.Service
-	)
+	defer r.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	policy := gxregistry.DefaultReregisterPolicy
+	if r.options.Reregister != nil {
+		policy = *r.options.Reregister
+	}
 
 	// copy c.services
-	services = make([]gxregistry.Service, 0, len(r.serviceRegistry))
 	r.Lock()
-	for _, s := range r.serviceRegistry {
-		services = append(services, s)
+	attrs := make([]gxregistry.ServiceAttr, 0, len(r.serviceRegistry))
+	for attr := range r.serviceRegistry {
+		attrs = append(attrs, attr)
 	}
 	r.Unlock()
 
-	for _, s := range services {
-		err = r.register(s)
-		if err != nil {
-			log.Error("(ZookeeperRegistry)register(service:%s) = error:%s", s, jerrors.ErrorStack(err))
+	for _, attr := range attrs {
+		var service gxregistry.Service
+		start := time.Now()
+		err := policy.Do(ctx, func(context.Context) error {
+			r.regLock.Lock()
+			defer r.regLock.Unlock()
+
+			var ok bool
+			if service, ok = r.exist(gxregistry.Service{Attr: &attr}); !ok || len(service.Nodes) == 0 {
+				// deregistered
+				return nil
+			}
+			s := gxregistry.Service{Attr: service.Attr, Metadata: service.Metadata}
+			for _, node := range service.Nodes {
+				s.Nodes = []*gxregistry.Node{node}
+				data, err := r.options.Codec.Encode(&s)
+				if err == nil {
+					err = r.createNode(s, *node, data)
+				}
+				if err != nil && jerrors.Cause(err) != zk.ErrNodeExists {
+					return jerrors.Trace(err)
+				}
+			}
+			return nil
+		})
+		if r.options.Observer != nil {
+			r.options.Observer.ObserveOp(gxregistry.OpReregister, time.Since(start), err)
+		}
+		if err == nil {
+			continue
+		}
+		gxlog.LogError(r.logger, "register service again failed", err, "service", service)
+		if ctx.Err() != nil {
+			return
+		}
+		if r.options.ReregisterFailure != nil {
+			r.options.ReregisterFailure(service, err)
+		}
+	}
+
+	// the watches of the paths emptied by the expiry fail until now
+	for _, w := range r.stateWatchers() {
+		w.retryPaths()
+	}
+}
+
+// addWatcher notifies @w of the transitions of the conn state.
+func (r *Registry) addWatcher(w *Watcher) {
+	r.stateLock.Lock()
+	r.watchers[w] = struct{}{}
+	r.stateLock.Unlock()
+}
+
+func (r *Registry) removeWatcher(w *Watcher) {
+	r.stateLock.Lock()
+	delete(r.watchers, w)
+	r.stateLock.Unlock()
+}
+
+// stateWatchers returns the watchers of the registry.
+func (r *Registry) stateWatchers() []*Watcher {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	watchers := make([]*Watcher, 0, len(r.watchers))
+	for w := range r.watchers {
+		watchers = append(watchers, w)
+	}
+
+	return watchers
+}
+
+// updateConnState updates the conn state by the zookeeper session state
+// @state, and notifies the listener and the watchers of its transition. An
+// expired session stays expired until the session is back.
+func (r *Registry) updateConnState(state zk.State) {
+	r.stateLock.Lock()
+	old := r.connState
+	next := old
+	switch state {
+	case zk.StateDisconnected:
+		if old != gxregistry.ConnExpired {
+			next = gxregistry.ConnDisconnected
 		}
+	case zk.StateExpired:
+		next = gxregistry.ConnExpired
+	case zk.StateHasSession:
+		if old == gxregistry.ConnDisconnected || old == gxregistry.ConnExpired {
+			next = gxregistry.ConnReconnected
+		}
+	}
+	if next == old {
+		r.stateLock.Unlock()
+		return
+	}
+	r.connState = next
+	r.stateLock.Unlock()
+	watchers := r.stateWatchers()
+
+	r.logger.Warnw("zk conn state changed", "addrs", r.options.Addrs, "root", r.options.Root,
+		"from", old.String(), "to", next.String())
+	if r.options.StateListener != nil {
+		r.options.StateListener(old, next)
+	}
+	for _, w := range watchers {
+		w.connStateChanged(next)
+	}
+	if old == gxregistry.ConnExpired && next == gxregistry.ConnReconnected {
+		r.logger.Infow("start to handle zookeeper restart event")
+		// in the event goroutine, so it is not added after Close waits
+		r.wg.Add(1)
+		go r.handleZkRestart()
 	}
 }
 
@@ -146,10 +321,9 @@ func (r *Registry) handleZkEvent(session <-chan zk.Event) {
 		event zk.Event
 	)
 
-	r.wg.Add(1)
 	defer func() {
 		r.wg.Done()
-		log.Info("zk{addr:%#v, path:%v} connection goroutine game over.", r.options.Addrs, r.options.Root)
+		r.logger.Infow("zk connection goroutine game over", "addrs", r.options.Addrs, "root", r.options.Root)
 	}()
 
 LOOP:
@@ -159,18 +333,21 @@ LOOP:
 			break LOOP
 
 		case event = <-session:
-			log.Warn("client get a zookeeper event{type:%s, server:%s, path:%s, state:%d-%s, err:%#v}",
-				event.Type, event.Server, event.Path, event.State, r.client.StateToString(event.State), event.Err)
+			r.logger.Warnw("client get a zookeeper event", "type", event.Type.String(), "server", event.Server,
+				"path", event.Path, "state", r.client.StateToString(event.State), "error", event.Err)
+			if event.Type == zk.EventSession {
+				r.updateConnState(event.State)
+			}
 			switch (int)(event.State) {
 			case (int)(zk.StateDisconnected):
-				log.Warn("zk{addr:%#v, path:%v} state is StateDisconnected.", r.options.Addrs, r.options.Root)
+				r.logger.Warnw("zk state is StateDisconnected", "addrs", r.options.Addrs, "root", r.options.Root)
 
 			case (int)(zk.EventNodeDataChanged), (int)(zk.EventNodeChildrenChanged):
-				log.Info("zkClient get zk node changed event{path:%s}", event.Path)
+				r.logger.Infow("zkClient get zk node changed event", "path", event.Path)
 				r.Lock()
 				for p, a := range r.eventRegistry {
 					if strings.HasPrefix(p, event.Path) {
-						log.Info("send event{zk.EventNodeDataChange, zk.Path:%s} to path{%s} related watcher", event.Path, p)
+						r.logger.Infow("send zk.EventNodeDataChange to the related watchers", "path", event.Path, "watched", p)
 						for _, e := range a {
 							*e <- struct{}{}
 						}
@@ -187,10 +364,6 @@ LOOP:
 						*e <- struct{}{}
 					}
 				}
-				if state == (int)(zk.StateDisconnected) && (int)(event.State) == (int)(zk.StateConnected) {
-					log.Info("start to handle zookeeper restart event.")
-					r.handleZkRestart()
-				}
 			}
 			state = (int)(event.State)
 		}
@@ -201,12 +374,15 @@ func (r *Registry) Options() gxregistry.Options {
 	return r.options
 }
 
+// Client returns the zookeeper client, nil if the registry is closed or
+// is not on a *gxzookeeper.Client.
 func (r *Registry) Client() *gxzookeeper.Client {
 	select {
 	case <-r.done:
 		return nil
 	default:
-		return r.client
+		c, _ := r.client.(*gxzookeeper.Client)
+		return c
 	}
 }
 
@@ -293,48 +469,105 @@ func (r *Registry) deleteService(s gxregistry.Service) {
 	return
 }
 
+// nodeData returns the data of the zookeeper node of @node of @s, which is
+// @s of @node only.
+func (r *Registry) nodeData(s gxregistry.Service, node *gxregistry.Node) ([]byte, error) {
+	service := gxregistry.Service{Metadata: s.Metadata, Attr: s.Attr, Nodes: []*gxregistry.Node{node}}
+	data, err := r.options.Codec.Encode(&service)
+	if err != nil {
+		return nil, jerrors.Annotatef(err, "%s codec Encode(service:%+v)", r.options.Codec.Name(), service)
+	}
+
+	return data, nil
+}
+
 func (r *Registry) register(s gxregistry.Service) error {
 	service := gxregistry.Service{Metadata: s.Metadata}
 	service.Attr = s.Attr
 
 	// serviceRegistry every node
-	var zkPath string
 	for i, node := range s.Nodes {
 		service.Nodes = []*gxregistry.Node{node}
-		data, err := gxregistry.EncodeService(&service)
+		data, err := r.nodeData(s, node)
 		if err != nil {
 			service.Nodes = s.Nodes[:i]
 			r.unregister(service)
-			return jerrors.Annotatef(err, "gxregistry.EncodeService(service:%+v) = error:%s", service, err)
+			return err
 		}
 
-		zkPath = service.Path(r.options.Root)
-		err = r.client.CreateZkPath(zkPath)
+		err = r.retry(func(context.Context) error {
+			return r.createNode(service, *node, data)
+		})
 		if err != nil {
-			log.Error("zkClient.CreateZkPath(root{%s})", zkPath, err)
-			return jerrors.Trace(err)
+			return err
 		}
+	}
 
-		zkPath = service.NodePath(r.options.Root, *node)
-		_, err = r.client.RegisterTemp(zkPath, []byte(data))
-		if err != nil {
-			return jerrors.Annotatef(err, "gxregister.RegisterTemp(path:%s)", zkPath)
-		}
+	return nil
+}
+
+// createNode creates the ephemeral zookeeper node of @node of @service, whose
+// data is @data.
+func (r *Registry) createNode(service gxregistry.Service, node gxregistry.Node, data []byte) error {
+	zkPath := service.Path(r.options.Root)
+	err := r.client.EnsurePath(zkPath, r.acl)
+	if err != nil {
+		gxlog.LogError(r.logger, "zkClient.EnsurePath() failed", err, "path", zkPath)
+		return jerrors.Trace(err)
+	}
+
+	zkPath = service.NodePath(r.options.Root, node)
+	_, err = r.client.CreateTemp(zkPath, data, r.acl)
+	if err != nil {
+		return jerrors.Annotatef(err, "gxregister.CreateTemp(path:%s)", zkPath)
 	}
 
 	return nil
 }
 
-func (r *Registry) Register(s gxregistry.Service) error {
+// retry calls @fn by the retry policy of the options, or once if there is
+// none. A node existing already is not retried.
+func (r *Registry) retry(fn func(context.Context) error) error {
+	if r.options.Retry == nil {
+		return fn(context.Background())
+	}
+
+	p := *r.options.Retry
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = gxtime.DefaultRetryable
+	}
+	p.Retryable = func(err error) bool {
+		return jerrors.Cause(err) != zk.ErrNodeExists && retryable(err)
+	}
+
+	return p.Do(context.Background(), fn)
+}
+
+// observe observes the @op started at @start, which returns *@err.
+func (r *Registry) observe(op string, start time.Time, err *error) {
+	if r.options.Observer != nil {
+		r.options.Observer.ObserveOp(op, time.Since(start), *err)
+	}
+}
+
+func (r *Registry) Register(s gxregistry.Service) (err error) {
+	defer r.observe(gxregistry.OpRegister, time.Now(), &err)
+
 	if len(s.Nodes) == 0 {
 		return jerrors.Errorf("Require at least one node")
 	}
+	if s, err = r.options.AdvertiseService(s); err != nil {
+		return err
+	}
 
+	r.regLock.Lock()
+	defer r.regLock.Unlock()
 	if _, exist := r.exist(s); exist {
 		return gxregistry.ErrorAlreadyRegister
 	}
 
-	err := r.register(s)
+	err = r.register(s)
 	if err != nil {
 		return jerrors.Annotate(err, "Registry.register")
 	}
@@ -361,12 +594,21 @@ func (r *Registry) unregister(s gxregistry.Service) error {
 	return nil
 }
 
-func (r *Registry) Deregister(s gxregistry.Service) error {
+func (r *Registry) Deregister(s gxregistry.Service) (err error) {
+	defer r.observe(gxregistry.OpDeregister, time.Now(), &err)
+
+	if s, err = r.options.AdvertiseService(s); err != nil {
+		return err
+	}
+	r.regLock.Lock()
+	defer r.regLock.Unlock()
 	r.deleteService(s)
 	return jerrors.Trace(r.unregister(s))
 }
 
-func (r *Registry) GetServices(attr gxregistry.ServiceAttr) ([]gxregistry.Service, error) {
+func (r *Registry) GetServices(attr gxregistry.ServiceAttr) (services []gxregistry.Service, err error) {
+	defer r.observe(gxregistry.OpGetServices, time.Now(), &err)
+
 	svc := gxregistry.Service{Attr: &attr}
 	path := svc.Path(r.options.Root)
 	children, err := r.client.GetChildren(path)
@@ -385,13 +627,14 @@ func (r *Registry) GetServices(attr gxregistry.ServiceAttr) ([]gxregistry.Servic
 
 		childData, err := r.client.Get(zkPath)
 		if err != nil {
-			log.Warn("gxzookeeper.Get(name:%s) = error:%s", zkPath, jerrors.ErrorStack(err))
+			r.logger.Warnw("gxzookeeper.Get() failed", "path", zkPath, "error", gxlog.ErrorField(err))
 			continue
 		}
 
-		sn, err := gxregistry.DecodeService(childData)
+		sn, err := decodeService(nil, childData)
 		if err != nil {
-			log.Warn("gxregistry.DecodeService(data:%#v) = error:%s", childData, jerrors.ErrorStack(err))
+			r.logger.Warnw("gxregistry.DecodeService() failed", "path", zkPath, "size", len(childData),
+				"error", gxlog.ErrorField(err))
 			continue
 		}
 		if attr.MeshFilter(*sn.Attr) {
@@ -409,9 +652,10 @@ func (r *Registry) GetServices(attr gxregistry.ServiceAttr) ([]gxregistry.Servic
 	return serviceArray, nil
 }
 
-func (r *Registry) Watch(opts ...gxregistry.WatchOption) (gxregistry.Watcher, error) {
-	w, err := NewWatcher(r, opts...)
+func (r *Registry) Watch(opts ...gxregistry.WatchOption) (w gxregistry.Watcher, err error) {
+	defer r.observe(gxregistry.OpWatch, time.Now(), &err)
 
+	w, err = NewWatcher(r, opts...)
 	return w, jerrors.Trace(err)
 }
 
@@ -419,20 +663,86 @@ func (r *Registry) String() string {
 	return "zookeeper registry"
 }
 
+type registryState struct {
+	Addrs   []string `json:"addrs"`
+	Root    string   `json:"root"`
+	Timeout string   `json:"timeout"`
+	ZkState string   `json:"zk_state"`
+	// ConnState is the state of the session events, see ConnState
+	ConnState string `json:"conn_state"`
+}
+
+// Inspect returns the zookeeper connection and the registered services,
+// sorted by their paths.
+func (r *Registry) Inspect() gxregistry.Inspection {
+	state := registryState{
+		Addrs:   r.options.Addrs,
+		Root:    r.options.Root,
+		Timeout: r.options.Timeout.String(),
+		ZkState: "closed",
+	}
+
+	r.Lock()
+	valid := false
+	if r.client != nil {
+		zkState := r.client.State()
+		state.ZkState = r.client.StateToString(zkState)
+		valid = zkState == zk.StateConnected || zkState == zk.StateHasSession
+	}
+	services := make([]gxregistry.Service, 0, len(r.serviceRegistry))
+	for _, s := range r.serviceRegistry {
+		services = append(services, *s.Copy())
+	}
+	r.Unlock()
+	r.stateLock.Lock()
+	state.ConnState = r.connState.String()
+	r.stateLock.Unlock()
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Path(r.options.Root) < services[j].Path(r.options.Root)
+	})
+	items := make([]interface{}, 0, len(services))
+	for i := range services {
+		items = append(items, services[i])
+	}
+
+	return gxregistry.Inspection{
+		Kind:  gxregistry.InspectRegistry,
+		Name:  r.String(),
+		Valid: valid,
+		State: state,
+		Items: items,
+	}
+}
+
 // check whether the session has been closed.
 func (r *Registry) Done() <-chan struct{} {
 	return r.done
 }
 
+// Close closes the client, and waits for the goroutines of the registry,
+// which may lock it, so it is not locked meanwhile.
 func (r *Registry) Close() error {
 	r.Lock()
-	defer r.Unlock()
-	if r.client != nil {
-		close(r.done)
-		r.client.ZkConn().Close()
-		r.wg.Wait()
-		r.client = nil
+	client := r.client
+	select {
+	case <-r.done:
+		client = nil
+	default:
+		if client != nil {
+			close(r.done)
+		}
+	}
+	r.Unlock()
+	if client == nil {
+		return nil
 	}
 
+	client.Close()
+	r.wg.Wait()
+	r.Lock()
+	r.client = nil
+	r.Unlock()
+
 	return nil
 }
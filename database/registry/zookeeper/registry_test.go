@@ -1,13 +1,19 @@
 package gxzookeeper
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
 import (
 	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/database/registry/zookeeper/zktest"
 	"github.com/AlexStocks/goext/log"
+	jerrors "github.com/juju/errors"
+	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -122,3 +128,127 @@ func (suite *RegisterTestSuite) TestRegistry_ZookeeperRestart() {
 func TestRegisterTestSuite(t *testing.T) {
 	suite.Run(t, new(RegisterTestSuite))
 }
+
+func TestFakeRegistryDigestACL(t *testing.T) {
+	client := gxzktest.NewClient()
+	if err := client.AddAuth("digest", []byte("user:password")); err != nil {
+		t.Fatalf("AddAuth() = error:%s", err)
+	}
+	client.Create("/test", nil)
+	reg := NewRegistryWithClient(client, client.Session(),
+		gxregistry.WithRoot("/test/acl"),
+		gxregistry.WithLogger(gxlog.NewNop()),
+		gxregistry.WithDigestACL("user", "password"),
+	)
+	defer reg.Close()
+
+	s := gxregistry.Service{
+		Attr:  &fakeAttr,
+		Nodes: []*gxregistry.Node{{ID: "node0", Address: "127.0.0.1", Port: 12345}},
+	}
+	if err := reg.Register(s); err != nil {
+		t.Fatalf("Register() = error:%s", err)
+	}
+	cart := fakeAttr
+	cart.Service = "cart"
+	batch := gxregistry.Service{
+		Attr:  &cart,
+		Nodes: []*gxregistry.Node{{ID: "node1", Address: "127.0.0.1", Port: 12346}},
+	}
+	if err := reg.(gxregistry.BatchRegistry).RegisterBatch([]gxregistry.Service{batch}); err != nil {
+		t.Fatalf("RegisterBatch() = error:%s", err)
+	}
+
+	// the nodes and the parents created for them, not the existing "/test"
+	want := append(zk.DigestACL(zk.PermAll, "user", "password"), zk.WorldACL(zk.PermRead)...)
+	for _, p := range []string{"/test/acl", s.Path("/test/acl"), s.NodePath("/test/acl", *s.Nodes[0]),
+		batch.Path("/test/acl"), batch.NodePath("/test/acl", *batch.Nodes[0])} {
+		if acl, _ := client.ACL(p); !reflect.DeepEqual(acl, want) {
+			t.Fatalf("ACL of %s = %+v, want %+v", p, acl, want)
+		}
+	}
+	if acl, _ := client.ACL("/test"); acl != nil {
+		t.Fatalf("ACL of /test = %+v", acl)
+	}
+
+	// an unauthenticated client reads the nodes, and can not write them
+	other := client.NewSession()
+	node := s.NodePath("/test/acl", *s.Nodes[0])
+	if _, err := other.Get(node); err != nil {
+		t.Fatalf("Get(%s) = error:%s", node, err)
+	}
+	if err := other.DeleteZkPath(node); jerrors.Cause(err) != zk.ErrNoAuth {
+		t.Fatalf("DeleteZkPath(%s) = error:%v", node, err)
+	}
+	if _, err := other.RegisterTemp(s.Path("/test/acl")+"/node2", []byte("v")); jerrors.Cause(err) != zk.ErrNoAuth {
+		t.Fatalf("RegisterTemp() = error:%v", err)
+	}
+	otherReg := NewRegistryWithClient(other, other.Session(),
+		gxregistry.WithRoot("/test/acl"),
+		gxregistry.WithLogger(gxlog.NewNop()),
+	)
+	defer otherReg.Close()
+	node2 := gxregistry.Service{
+		Attr:  &fakeAttr,
+		Nodes: []*gxregistry.Node{{ID: "node2", Address: "127.0.0.1", Port: 12347}},
+	}
+	if err := otherReg.Register(node2); jerrors.Cause(err) != zk.ErrNoAuth {
+		t.Fatalf("Register() of an unauthenticated registry = error:%v", err)
+	}
+	if err := otherReg.(gxregistry.BatchRegistry).RegisterBatch([]gxregistry.Service{node2}); err == nil {
+		t.Fatalf("RegisterBatch() of an unauthenticated registry = nil")
+	}
+
+	if err := reg.Deregister(s); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+}
+
+func TestFakeRegistryWorldACL(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+
+	s := z.register(t, fakeAttr, "node0")
+	node := s.NodePath("/test", *s.Nodes[0])
+	if acl, _ := z.client.ACL(node); !reflect.DeepEqual(acl, zk.WorldACL(zk.PermAll)) {
+		t.Fatalf("ACL of %s = %+v", node, acl)
+	}
+	if err := z.client.NewSession().DeleteZkPath(node); err != nil {
+		t.Fatalf("DeleteZkPath(%s) = error:%s", node, err)
+	}
+}
+
+func TestFakeRegisterConcurrentParents(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+
+	// the registries of the sessions race on creating the same parents
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client := z.client.NewSession()
+			reg := NewRegistryWithClient(client, client.Session(),
+				gxregistry.WithRoot("/test/deep/root"),
+				gxregistry.WithLogger(gxlog.NewNop()),
+			)
+			defer reg.Close()
+			errs <- reg.Register(z.service(fakeAttr, fmt.Sprintf("node%d", i)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Register() = error:%s", err)
+		}
+	}
+
+	s := z.service(fakeAttr, "node0")
+	children, err := z.client.GetChildren(s.Path("/test/deep/root"))
+	if err != nil || len(children) != cap(errs) {
+		t.Fatalf("GetChildren() = %v, error:%v", children, err)
+	}
+}
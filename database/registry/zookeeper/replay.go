@@ -0,0 +1,73 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxzookeeper
+
+import (
+	"sync"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/registry"
+)
+
+// replayBuffer stamps the notified events of a watcher with their
+// sequences, and keeps the last ones of them in a ring for Replay.
+type replayBuffer struct {
+	sync.Mutex
+	events []*gxregistry.EventResult // the ring, events[seq%len] of seq
+	last   uint64                    // the seq of the last event
+}
+
+// newReplayBuffer returns a buffer of the last @size events.
+func newReplayBuffer(size int) *replayBuffer {
+	if size <= 0 {
+		size = gxregistry.DefaultReplaySize
+	}
+
+	return &replayBuffer{events: make([]*gxregistry.EventResult, size)}
+}
+
+// add stamps @res with the next seq, and keeps it.
+func (b *replayBuffer) add(res *gxregistry.EventResult) {
+	b.Lock()
+	b.last++
+	res.Seq = b.last
+	b.events[b.last%uint64(len(b.events))] = res
+	b.Unlock()
+}
+
+// since returns the kept events after the one of @seq.
+func (b *replayBuffer) since(seq uint64) ([]*gxregistry.EventResult, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if seq >= b.last {
+		return nil, nil
+	}
+	// the first kept event is of b.last-len+1
+	if size := uint64(len(b.events)); b.last-seq > size {
+		return nil, jerrors.Annotatef(gxregistry.ErrReplayGap, "seq:%d, kept:[%d, %d]", seq, b.last-size+1, b.last)
+	}
+
+	events := make([]*gxregistry.EventResult, 0, b.last-seq)
+	for s := seq + 1; s <= b.last; s++ {
+		events = append(events, b.events[s%uint64(len(b.events))])
+	}
+
+	return events, nil
+}
+
+// clear drops the kept events.
+func (b *replayBuffer) clear() {
+	b.Lock()
+	for i := range b.events {
+		b.events[i] = nil
+	}
+	b.Unlock()
+}
@@ -6,46 +6,92 @@
 package gxzookeeper
 
 import (
+	"context"
+	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 import (
-	log "github.com/AlexStocks/log4go"
 	jerrors "github.com/juju/errors"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
 import (
-	"github.com/AlexStocks/goext/container/array"
 	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/log"
 	"github.com/AlexStocks/goext/strings"
+	"github.com/AlexStocks/goext/sync"
 	"github.com/AlexStocks/goext/time"
 )
 
 const (
 	MAX_TIMES                   = 15 // 设置(wathcer)watchDir()等待时长
-	Wactch_Event_Channel_Size   = 32 // 用于设置通知selector的event channel的size
+	Wactch_Event_Channel_Size   = 32 // 用于设置通知selector的event queue的初始容量
 	ZKCLIENT_EVENT_CHANNEL_SIZE = 4  // 设置用于zk client与watcher&consumer&provider之间沟通的channel的size
+
+	// the window of WatcherStats.EventsPerSecond
+	eventRateWindow  = time.Minute
+	eventRateBuckets = 12
 )
 
 // watcher的watch系列函数暴露给zk registry，而Next函数则暴露给selector
 type Watcher struct {
 	opts       gxregistry.WatchOptions
+	roots      []string // the cleaned watch roots
 	reg        *Registry
-	events     chan event // 通过这个channel把registry与selector连接了起来
+	errLog     gxlog.Logger           // of the warnings and errors, sampled if configured
+	queue      *eventQueue            // 通过这个queue把registry与selector连接了起来
+	replay     *replayBuffer          // the last notified events
+	cache      *gxregistry.LocalCache // nil without WithLocalCache
+	metrics    gxregistry.MetricsHook
+	added      *gxsync.Counter
+	updated    *gxsync.Counter
+	deleted    *gxsync.Counter
+	dropped    *gxsync.Counter
+	undecoded  *gxsync.Counter
+	reconnects *gxsync.Counter
+	events     *gxtime.SlidingWindowCounter // the events sent in the last window
 	done       chan struct{}
-	sync.Mutex // lock path set
-	pathSet    []string
+	clock      gxtime.Clock // of the reconnection backoff and the event rate
+	sync.Mutex              // lock paths and nodes
+	paths      map[string]*pathState
+	nodes      map[string]*nodeState // the watched service nodes
 	wg         sync.WaitGroup
 	sync.Once  // for Close
 }
 
+// pathState is the state of a watched path.
+type pathState struct {
+	retry     chan struct{} // retries a failed watch of the path now
+	children  []string      // the known children
+	lastEvent time.Time     // of the last zookeeper event of the path
+	retries   int           // the consecutive watch failures
+}
+
+// nodeState is the last observed data of a watched service node, which
+// its Update and Del events carry.
+type nodeState struct {
+	service  *gxregistry.Service
+	revision int64 // the Mzxid of service
+}
+
+// PathStats is the state of a watched path in WatcherStats.
+type PathStats struct {
+	Path      string    `json:"path"`
+	Children  int       `json:"children"`
+	LastEvent time.Time `json:"last_event"`
+	Retries   int       `json:"retries"`
+}
+
 type event struct {
-	res *gxregistry.EventResult
-	err error
+	res  *gxregistry.EventResult
+	err  error
+	node string // the zookeeper path of the service node of res
+	sent time.Time
 }
 
 func NewWatcher(r gxregistry.Registry, opts ...gxregistry.WatchOption) (gxregistry.Watcher, error) {
@@ -59,51 +105,268 @@ func NewWatcher(r gxregistry.Registry, opts ...gxregistry.WatchOption) (gxregist
 		o(&options)
 	}
 
+	roots, err := options.WatchRoots()
+	if err != nil {
+		return nil, jerrors.Trace(err)
+	}
 	if options.Root == "" {
-		options.Root = gxregistry.DefaultServiceRoot
+		options.Root = roots[0]
 	}
 
 	w := &Watcher{
-		opts:   options,
-		reg:    reg,
-		events: make(chan event, Wactch_Event_Channel_Size),
-		done:   make(chan struct{}),
+		opts:       options,
+		roots:      roots,
+		reg:        reg,
+		queue:      newEventQueue(Wactch_Event_Channel_Size),
+		replay:     newReplayBuffer(options.ReplaySize),
+		done:       make(chan struct{}),
+		clock:      reg.clock,
+		paths:      make(map[string]*pathState),
+		nodes:      make(map[string]*nodeState),
+		errLog:     reg.logger,
+		metrics:    gxregistry.NopMetricsHook{},
+		added:      gxsync.NewCounter(),
+		updated:    gxsync.NewCounter(),
+		deleted:    gxsync.NewCounter(),
+		dropped:    gxsync.NewCounter(),
+		undecoded:  gxsync.NewCounter(),
+		reconnects: gxsync.NewCounter(),
+	}
+	if options.Sampler != nil {
+		w.errLog = gxlog.NewSampler(reg.logger, *options.Sampler)
+	}
+	if options.Wheel != nil {
+		w.clock = gxtime.NewWheelClock(options.Wheel)
+	}
+	w.events = gxtime.NewSlidingWindowCounter(eventRateWindow, eventRateBuckets, w.clock)
+	switch {
+	case options.Metrics != nil:
+		w.metrics = options.Metrics
+	case reg.options.Metrics != nil:
+		w.metrics = reg.options.Metrics
+	}
+	if options.LocalCache != "" {
+		w.cache = gxregistry.NewLocalCache(options.LocalCache)
+		if !w.Valid() {
+			w.sendStale()
+		}
 	}
 
 	//go w.watchService()
-	go w.watchDir(w.opts.Root)
+	for _, root := range roots {
+		w.wg.Add(1)
+		go w.watchDir(root)
+	}
+	reg.addWatcher(w)
 
 	return w, nil
 }
 
-// 这个函数退出，意味着要么收到了stop信号，要么watch的node不存在了
-func (w *Watcher) watchServiceNode(zkPath string) bool {
+// isRoot returns whether @zkPath is a watch root.
+func (w *Watcher) isRoot(zkPath string) bool {
+	for _, root := range w.roots {
+		if zkPath == root {
+			return true
+		}
+	}
+
+	return false
+}
+
+// send queues the event of @service of the zookeeper path @node of the
+// Mzxid @revision for the selector, and returns whether it is queued. It gives
+// up if the watcher is closed, as no one may notify the events then, or the
+// action is not watched. It is called under the lock of the watcher, so the
+// WatchEvent hook is called by unlock.
+func (w *Watcher) send(action gxregistry.ServiceEventType, node string, service *gxregistry.Service, revision int64) bool {
+	if w.IsClosed() || !w.opts.WatchAction(action) {
+		return false
+	}
+	// the node is of a service path under a root
+	res := &gxregistry.EventResult{
+		Action:   action,
+		Service:  service,
+		Root:     path.Dir(path.Dir(node)),
+		Revision: revision,
+	}
+	w.queue.push(event{res: res, node: node, sent: time.Now()})
+	switch action {
+	case gxregistry.ServiceDel:
+		w.deleted.Inc()
+	case gxregistry.ServiceUpdate:
+		w.updated.Inc()
+	default:
+		w.added.Inc()
+	}
+	w.events.Incr(1)
+
+	return true
+}
+
+// unlock releases the lock of the watcher, and then calls the WatchEvent hook
+// of @action if @sent, so a hook does not run under the lock.
+func (w *Watcher) unlock(action gxregistry.ServiceEventType, sent *bool) {
+	w.Unlock()
+	if *sent {
+		w.metrics.WatchEvent(action)
+	}
+}
+
+// sendStale queues the services of the local cache as the stale events,
+// before the events of the registry. A corrupted cache is ignored.
+func (w *Watcher) sendStale() {
+	services, err := gxregistry.LoadLocalCache(w.opts.LocalCache)
+	if err != nil {
+		if !os.IsNotExist(jerrors.Cause(err)) {
+			gxlog.LogError(w.errLog, "ignore the local cache", err, "path", w.opts.LocalCache)
+		}
+		return
+	}
+	for _, res := range gxregistry.StaleEvents(services, w.opts.Root, w.opts.Filter) {
+		w.queue.push(event{res: res, sent: time.Now()})
+	}
+}
+
+// updateCache writes the notified @res to the local cache if any.
+func (w *Watcher) updateCache(res *gxregistry.EventResult) {
+	if w.cache == nil {
+		return
+	}
+	if err := w.cache.Update(res); err != nil {
+		gxlog.LogError(w.errLog, "failed to write the local cache", err, "path", w.opts.LocalCache)
+	}
+}
+
+// drop counts an event of the registry failed to send.
+func (w *Watcher) drop() {
+	w.dropped.Inc()
+	w.metrics.WatchDropped()
+}
+
+// decode decodes the data of the service node @node. A failure is counted
+// as dropped, and sent as an error if the watcher notifies decode errors.
+func (w *Watcher) decode(node string, data []byte) (*gxregistry.Service, error) {
+	service, err := decodeService(w.opts.Codec, data)
+	if err == nil {
+		return service, nil
+	}
+
+	gxlog.LogError(w.errLog, "gxregistry.DecodeService() failed", err, "path", node, "size", len(data))
+	w.drop()
+	w.undecoded.Inc()
+	w.metrics.WatchDecodeError()
+	if w.opts.DecodeErrors && !w.IsClosed() {
+		err = jerrors.Annotatef(gxregistry.ErrorServiceDecode, "path:%s, error:%s", node, err)
+		w.queue.push(event{err: err, sent: time.Now()})
+	}
+
+	return nil, err
+}
+
+// connStateChanged handles the transition of the conn state of the registry
+// to @state. An expired session is notified as gxregistry.ErrSessionExpired.
+// After a reconnection, the failed watches are retried at once, and the
+// services are listed and sent again, as the nodes may be changed without
+// the watches.
+func (w *Watcher) connStateChanged(state gxregistry.ConnState) {
+	if w.IsClosed() {
+		return
+	}
+
+	switch state {
+	case gxregistry.ConnExpired:
+		w.queue.push(event{err: gxregistry.ErrSessionExpired, sent: time.Now()})
+	case gxregistry.ConnReconnected:
+		w.retryPaths()
+		w.goWatch(w.relist)
+	}
+}
+
+// goWatch runs @f in a goroutine counted by the WaitGroup of the watcher,
+// unless it is closed. It is for the callers out of the watch goroutines,
+// whose Add may be concurrent with the Wait of Close, so the Add is under
+// the lock that Close closes done under.
+func (w *Watcher) goWatch(f func()) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.IsClosed() {
+		return
+	}
 	w.wg.Add(1)
+	go f()
+}
+
+// retryPaths retries the failed watches of the paths now.
+func (w *Watcher) retryPaths() {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, state := range w.paths {
+		select {
+		case state.retry <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// relist sends the Add events of the services under the roots again, and
+// watches the nodes not watched yet.
+func (w *Watcher) relist() {
 	defer w.wg.Done()
 
-	var zkEvent zk.Event
+	for _, root := range w.roots {
+		err := w.walk(root, func(node string, service *gxregistry.Service, revision int64) {
+			if !w.addNode(node, service, revision) {
+				w.resendNode(node, service, revision)
+			} else if !w.IsClosed() {
+				w.wg.Add(1)
+				go w.watchNode(node)
+			}
+		})
+		if err != nil {
+			gxlog.LogError(w.errLog, "relist the services failed", err, "root", root)
+		}
+	}
+}
+
+// reconnect counts a rewatch of a path.
+func (w *Watcher) reconnect() {
+	w.reconnects.Inc()
+	w.metrics.WatchReconnect()
+}
+
+// 这个函数退出，意味着要么收到了stop信号，要么watch的node不存在了
+// The changes of the data of the node are sent as the Update events.
+func (w *Watcher) watchServiceNode(zkPath string) bool {
+	var (
+		zkEvent zk.Event
+		changed bool
+	)
 	for {
 		keyEventCh, err := w.reg.client.ExistW(zkPath)
 		if err != nil {
-			log.Error("existW{key:%s} = error{%#v}", zkPath, err)
+			gxlog.LogError(w.errLog, "existW failed", err, "key", zkPath)
 			return false
 		}
+		// read the data after the watch is set, so that a later change is
+		// not missed
+		if changed {
+			w.changeNode(zkPath)
+			changed = false
+		}
 
 		select {
 		case zkEvent = <-keyEventCh:
-			log.Warn("get a zookeeper zkEvent{type:%s, server:%s, path:%s, state:%d-%s, err:%s}",
-				zkEvent.Type, zkEvent.Server, zkEvent.Path, zkEvent.State, w.reg.client.StateToString(zkEvent.State), zkEvent.Err)
+			w.errLog.Warnw("existW got a zookeeper event", "key", zkPath, "type", zkEvent.Type.String(),
+				"server", zkEvent.Server, "path", zkEvent.Path,
+				"state", w.reg.client.StateToString(zkEvent.State), "error", zkEvent.Err)
 			switch zkEvent.Type {
-			case zk.EventNodeDataChanged:
-				log.Warn("zk.ExistW(key{%s}) = event{EventNodeDataChanged}", zkPath)
-			case zk.EventNodeCreated:
-				log.Warn("zk.ExistW(key{%s}) = event{EventNodeCreated}", zkPath)
-			case zk.EventNotWatching:
-				log.Warn("zk.ExistW(key{%s}) = event{EventNotWatching}", zkPath)
 			case zk.EventNodeDeleted:
-				log.Warn("zk.ExistW(key{%s}) = event{EventNodeDeleted}", zkPath)
 				//The Node was deleted - stop watching
 				return true
+			case zk.EventNodeDataChanged:
+				changed = true
 			}
 		case <-w.done:
 			// There is no way to stop existW so just quit
@@ -114,60 +377,239 @@ func (w *Watcher) watchServiceNode(zkPath string) bool {
 	return false
 }
 
-func contains(s []string, e string) bool {
-	for _, a := range s {
-		if a == e {
-			return true
+// watchNode watches the added service node @node until it is deleted, or
+// the watcher fails.
+func (w *Watcher) watchNode(node string) {
+	defer w.wg.Done()
+	defer w.errLog.Warnw("stop watching node", "path", node)
+
+	// watch goroutine退出，原因可能是service node不存在或者是与registry连接断开了
+	// 为了selector服务的稳定，仅在收到delete event的情况下向selector发送delete service event
+	for w.watchServiceNode(node) {
+		w.deleteNode(node)
+
+		// the node can be created again before the children of its parent
+		// are got again, e.g. by gxregistry.Heartbeat, and then it is not
+		// an added child of the parent.
+		data, stat, err := w.reg.client.GetWithStat(node)
+		if err != nil {
+			return
+		}
+		service, err := w.decode(node, data)
+		if err != nil {
+			return
 		}
+		if !w.addNode(node, service, stat.Mzxid) {
+			// watched by the watcher of its parent
+			return
+		}
+		w.reg.logger.Debugw("add service again", "path", node, "service", service)
 	}
+	w.removeNode(node)
+}
 
-	return false
+// addNode adds the service node @node and sends its Add event of @service
+// and @revision, and returns false if it is watched. The node is added and
+// sent at once for syncNode.
+func (w *Watcher) addNode(node string, service *gxregistry.Service, revision int64) bool {
+	var sent bool
+	w.Lock()
+	defer w.unlock(gxregistry.ServiceAdd, &sent)
+
+	if _, ok := w.nodes[node]; ok {
+		return false
+	}
+	w.nodes[node] = &nodeState{service: service, revision: revision}
+	sent = w.send(gxregistry.ServiceAdd, node, service, revision)
+
+	return true
+}
+
+// resendNode sends the Add event of the watched service node @node listed
+// again, of @service of @revision or its last observed data if newer.
+func (w *Watcher) resendNode(node string, service *gxregistry.Service, revision int64) {
+	var sent bool
+	w.Lock()
+	defer w.unlock(gxregistry.ServiceAdd, &sent)
+
+	state, ok := w.nodes[node]
+	if !ok {
+		return
+	}
+	state.update(service, revision)
+	sent = w.send(gxregistry.ServiceAdd, node, state.service, state.revision)
+}
+
+// changeNode reads the changed data of the watched service node @node, and
+// sends its Update event if it is newer than the last observed one.
+func (w *Watcher) changeNode(node string) {
+	data, stat, err := w.reg.client.GetWithStat(node)
+	if err != nil {
+		w.errLog.Warnw("can not get value of zk node", "path", node, "error", err)
+		w.drop()
+		return
+	}
+	service, err := w.decode(node, data)
+	if err != nil {
+		return
+	}
+	if !w.opts.Filter.MeshFilter(*service.Attr) {
+		w.errLog.Warnw("service is not compatible with the filter", "service", service, "filter", w.opts.Filter)
+		return
+	}
+
+	var sent bool
+	w.Lock()
+	defer w.unlock(gxregistry.ServiceUpdate, &sent)
+
+	state, ok := w.nodes[node]
+	if !ok || !state.update(service, stat.Mzxid) {
+		return
+	}
+	w.reg.logger.Debugw("update service", "path", node, "service", service)
+	sent = w.send(gxregistry.ServiceUpdate, node, service, stat.Mzxid)
+}
+
+// deleteNode sends the Del event of the watched service node @node of its
+// last observed data, and removes it.
+func (w *Watcher) deleteNode(node string) {
+	var sent bool
+	w.Lock()
+	defer w.unlock(gxregistry.ServiceDel, &sent)
+
+	state, ok := w.nodes[node]
+	if !ok {
+		return
+	}
+	w.reg.logger.Infow("delete service", "path", node, "service", state.service)
+	sent = w.send(gxregistry.ServiceDel, node, state.service, state.revision)
+	delete(w.nodes, node)
+}
+
+// update sets the data of @s to @service of @revision if it is newer, and
+// returns whether it is set.
+func (s *nodeState) update(service *gxregistry.Service, revision int64) bool {
+	if revision <= s.revision {
+		return false
+	}
+	s.service, s.revision = service, revision
+
+	return true
+}
+
+// syncNode adds the service node @node of @service of @revision read by
+// Snapshot, and returns false if it is watched. The queued Add event of a
+// watched node is dropped, as the node is in the snapshot.
+func (w *Watcher) syncNode(node string, service *gxregistry.Service, revision int64) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	if state, ok := w.nodes[node]; ok {
+		state.update(service, revision)
+		w.queue.dropAdd(node)
+		return false
+	}
+	w.nodes[node] = &nodeState{service: service, revision: revision}
+
+	return true
+}
+
+func (w *Watcher) removeNode(node string) {
+	w.Lock()
+	delete(w.nodes, node)
+	w.Unlock()
+}
+
+// addPath adds the state of @zkPath, and returns false if it is watched.
+// A failed watch of a watched path is retried now.
+func (w *Watcher) addPath(zkPath string) (*pathState, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	if state, ok := w.paths[zkPath]; ok {
+		// the path may be created again, so retry now if it failed
+		select {
+		case state.retry <- struct{}{}:
+		default:
+		}
+		return nil, false
+	}
+	state := &pathState{retry: make(chan struct{}, 1)}
+	w.paths[zkPath] = state
+
+	return state, true
+}
+
+func (w *Watcher) removePath(zkPath string) {
+	w.Lock()
+	delete(w.paths, zkPath)
+	w.Unlock()
+}
+
+// updatePath updates @state of a watched path by @f.
+func (w *Watcher) updatePath(state *pathState, f func(*pathState)) {
+	w.Lock()
+	f(state)
+	w.Unlock()
+}
+
+// decodeService decodes the data of a node written by any client by the
+// codec @c, see gxregistry.DecodeServiceBy. A panic of the decoder is
+// returned as the error of the node.
+func decodeService(c gxregistry.Codec, data []byte) (service *gxregistry.Service, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			service, err = nil, jerrors.Errorf("gxregistry.DecodeService() panic: %v", r)
+		}
+	}()
+
+	return gxregistry.DecodeServiceBy(c, data)
+}
+
+// filterPath returns whether the service path @n under the root is of the
+// filter of the watcher.
+func (w *Watcher) filterPath(n string) bool {
+	var attr gxregistry.ServiceAttr
+	if err := attr.UnmarshalPath(gxstrings.Slice(n)); err != nil {
+		gxlog.LogError(w.errLog, "ServiceAttr.UnmarshalPath() failed", err, "path", n)
+		w.metrics.WatchDecodeError()
+		return false
+	}
+
+	conf := w.opts.Filter
+	if !conf.MeshFilter(attr) {
+		// Fix: just filter service & role. database/filter/pool/filter.go:Filter::copy
+		// will use Filter to get valid service. 2018/10/18
+		w.errLog.Warnw("path attr is not compatible with the filter", "attr", attr, "filter", conf)
+		return false
+	}
+
+	return true
 }
 
 func (w *Watcher) handleZkPathEvent(zkRoot string, children []string) error {
 	newChildren, err := w.reg.client.GetChildren(zkRoot)
-	log.Debug("@zkRoot:%s, @children:%#v, newChildren:%#v, err:%#v", zkRoot, children, newChildren, err)
+	w.reg.logger.Debugw("path children changed", "root", zkRoot, "children", children, "newChildren", newChildren)
 	if err != nil {
 		// 不要发送不必要的error给selector，以防止selector/cache/cache.go:(cacheSelector)watch
 		// 调用(Watcher)Next获取error后，不断退出
-		log.Error("path{%s} child nodes changed, zk.Children() = error{%v}", zkRoot, err)
+		gxlog.LogError(w.errLog, "path child nodes changed, zk.Children() failed", err, "path", zkRoot)
 		return jerrors.Trace(err)
 	}
 
 	// a node was added -- watch the new node
-	var (
-		newPath    string
-		zkData     []byte
-		conf, attr gxregistry.ServiceAttr
-	)
-
-	conf = w.opts.Filter
-	for _, n := range newChildren {
-		if contains(children, n) {
-			continue
-		}
-
-		err = attr.UnmarshalPath(gxstrings.Slice(n))
-		if err != nil {
-			log.Error("ServiceAttr.UnmarshalPath(zkData:%s) = error{%v}", string(zkData), err)
-			continue
-		}
-
-		if !conf.MeshFilter(attr) {
-			// Fix: just filter service & role. database/filter/pool/filter.go:Filter::copy
-			// will use Filter to get valid service. 2018/10/18
-			log.Warn("path attr:{%#v} is not compatible with Config{%#v}", attr, conf)
-			continue
-		}
-		if len(conf.Service) != 0 && conf.Service != attr.Service {
-			log.Warn("path attr:{%#v} is not compatible with Config{%#v}", attr, conf)
+	var newPath string
+	added, _ := gxstrings.Diff(newChildren, children)
+	for _, n := range added {
+		if !w.filterPath(n) {
 			continue
 		}
 		newPath = path.Join(zkRoot, n)
+		w.wg.Add(1)
 		go func(path string) {
-			log.Info("start to watch path %s", path)
+			w.reg.logger.Infow("start to watch path", "path", path)
 			w.watchDir(path)
-			log.Info("watch path %s goroutine exit now.", path)
+			w.reg.logger.Infow("watch path goroutine exit now", "path", path)
 		}(newPath)
 	}
 
@@ -176,9 +618,9 @@ func (w *Watcher) handleZkPathEvent(zkRoot string, children []string) error {
 
 func (w *Watcher) handleZkNodeEvent(zkPath string, children []string) error {
 	newChildren, err := w.reg.client.GetChildren(zkPath)
-	log.Debug("zkPath:%s, newChildren:%#v, children:%#v", zkPath, newChildren, children)
+	w.reg.logger.Debugw("node children changed", "path", zkPath, "children", children, "newChildren", newChildren)
 	if err != nil {
-		log.Error("path{%s} child nodes changed, zk.Children() = error{%v}", zkPath, err)
+		gxlog.LogError(w.errLog, "path child nodes changed, zk.Children() failed", err, "path", zkPath)
 		return jerrors.Trace(err)
 	}
 
@@ -186,46 +628,39 @@ func (w *Watcher) handleZkNodeEvent(zkPath string, children []string) error {
 	var (
 		newNode string
 		zkData  []byte
+		zkStat  *zk.Stat
 		conf    gxregistry.ServiceAttr
 		service *gxregistry.Service
 	)
 	conf = w.opts.Filter
-	for _, n := range newChildren {
-		if contains(children, n) {
-			continue
-		}
-
+	added, _ := gxstrings.Diff(newChildren, children)
+	for _, n := range added {
 		newNode = path.Join(zkPath, n)
-		log.Debug("add zkNode{%s}", newNode)
-		zkData, err = w.reg.client.Get(newNode)
+		w.reg.logger.Debugw("add zk node", "path", newNode)
+		zkData, zkStat, err = w.reg.client.GetWithStat(newNode)
 		if err != nil {
-			log.Warn("can not get value of zk node %s", newNode)
+			w.errLog.Warnw("can not get value of zk node", "path", newNode, "error", err)
+			w.drop()
 			continue
 		}
-		service, err = gxregistry.DecodeService(zkData)
+		service, err = w.decode(newNode, zkData)
 		if err != nil {
-			log.Error("gxregistry.DecodeService(zkData:%s) = error{%v}", string(zkData), err)
 			continue
 		}
 
 		if !conf.MeshFilter(*service.Attr) {
 			// Fix: just filter service & role. database/filter/pool/filter.go:Filter::copy
 			// will use Filter to get valid service. 2018/10/18
-			log.Warn("service{%#v} is not compatible with Config{%#v}", service, conf)
+			w.errLog.Warnw("service is not compatible with the filter", "service", service, "filter", conf)
 			continue
 		}
-		log.Debug("add service{%#v}", service)
-		w.events <- event{&gxregistry.EventResult{gxregistry.ServiceAdd, service}, nil}
-		// watch w service node
-		go func(node string, service *gxregistry.Service) {
-			// watch goroutine退出，原因可能是service node不存在或者是与registry连接断开了
-			// 为了selector服务的稳定，仅在收到delete event的情况下向selector发送delete service event
-			if w.watchServiceNode(node) {
-				log.Info("delete service{%#v}", service)
-				w.events <- event{&gxregistry.EventResult{gxregistry.ServiceDel, service}, nil}
-			}
-			log.Warn("watchSelf(zk path{%s}) goroutine exit now", zkPath)
-		}(newNode, service)
+		if !w.addNode(newNode, service, zkStat.Mzxid) {
+			// watched already, it is deleted and created again
+			continue
+		}
+		w.reg.logger.Debugw("add service", "path", newNode, "service", service)
+		w.wg.Add(1)
+		go w.watchNode(newNode)
 	}
 
 	return nil
@@ -233,55 +668,60 @@ func (w *Watcher) handleZkNodeEvent(zkPath string, children []string) error {
 
 // zkPath 是/dubbo/com.xxx.service
 // 关注zk path下面node的添加或者删除
+// The caller adds it to w.wg, so that Close waits for it.
 func (w *Watcher) watchDir(zkPath string) {
 	var (
 		flag         bool
 		err          error
-		failTimes    int
+		state        *pathState
+		backoff      gxtime.Backoff
 		event        chan struct{}
 		zkEvent      zk.Event
 		children     []string
 		childEventCh <-chan zk.Event
 	)
+	defer w.wg.Done()
 
 	if strings.HasSuffix(zkPath, "/") {
 		zkPath = strings.TrimSuffix(zkPath, "/")
 	}
 
-	w.Lock()
-	flag = contains(w.pathSet, zkPath)
+	state, flag = w.addPath(zkPath)
 	if !flag {
-		w.pathSet = append(w.pathSet, zkPath)
-	}
-	w.Unlock()
-	if flag {
-		log.Warn("zookeeper path has been watched.", zkPath)
+		w.errLog.Warnw("zookeeper path has been watched", "path", zkPath)
 		return
 	}
 
 	event = make(chan struct{}, ZKCLIENT_EVENT_CHANNEL_SIZE)
 
-	w.wg.Add(1)
 	defer func() {
-		w.wg.Done()
 		close(event)
-		w.Lock()
-		w.pathSet, _ = gxarray.RemoveElem(w.pathSet, zkPath)
-		w.Unlock()
-		log.Warn("stop watching dir %s", zkPath)
+		w.removePath(zkPath)
+		w.errLog.Warnw("stop watching dir", "path", zkPath)
 	}()
 
+	// 防止疯狂重试连接zookeeper
+	backoff = gxtime.Backoff{
+		Base:   gxtime.TimeSecondDuration(float64(gxregistry.REGISTRY_CONN_DELAY)),
+		Max:    gxtime.TimeSecondDuration(float64(MAX_TIMES * gxregistry.REGISTRY_CONN_DELAY)),
+		Jitter: gxtime.FullJitter,
+	}
+	if w.opts.Backoff != nil {
+		backoff = *w.opts.Backoff
+		backoff.Reset()
+	}
 	flag = true
 	for {
 		// get current children for a zkPath
 		children, childEventCh, err = w.reg.client.GetChildrenW(zkPath)
-		log.Debug("path:%s, children:%#v", zkPath, children)
+		w.reg.logger.Debugw("watch dir children", "path", zkPath, "children", children)
 		if err != nil {
-			failTimes++
-			if MAX_TIMES <= failTimes {
-				failTimes = MAX_TIMES
-			}
-			log.Error("watchDir(path{%s}) = error{%v}", zkPath, err)
+			gxlog.LogError(w.errLog, "watchDir failed", err, "path", zkPath)
+			w.updatePath(state, func(s *pathState) { s.retries++ })
+			// the path may be deleted and created again, or its nodes
+			// may be changed without a watch, so handle all its children
+			// after the watch is back.
+			flag = true
 			// clear the event channel
 		CLEAR:
 			for {
@@ -293,27 +733,40 @@ func (w *Watcher) watchDir(zkPath string) {
 			}
 
 			w.reg.registerEvent(zkPath, &event)
+			// stopped on the other cases, so that the timers of a wheel
+			// are not left in its slots
+			timer := w.clock.NewTimer(backoff.Next())
 			select {
-			// 防止疯狂重试连接zookeeper
-			case <-time.After(gxtime.TimeSecondDuration(float64(failTimes * gxregistry.REGISTRY_CONN_DELAY))):
+			case <-timer.C():
+				w.reg.unregisterEvent(zkPath, &event)
+				w.reconnect()
+				continue
+			case <-state.retry:
+				timer.Stop()
 				w.reg.unregisterEvent(zkPath, &event)
+				w.reconnect()
 				continue
 			case <-w.done:
+				timer.Stop()
 				w.reg.unregisterEvent(zkPath, &event)
-				log.Warn("client.done(), watch(path{%s}, ServiceConfig{%#v}) goroutine exit now...",
-					zkPath, w.opts.Filter)
+				w.errLog.Warnw("watcher closed, watchDir goroutine exit now", "path", zkPath)
 				return
 			case <-event:
-				log.Info("get zk.EventNodeDataChange notify event")
+				timer.Stop()
+				w.reg.logger.Infow("get zk.EventNodeDataChange notify event", "path", zkPath)
 				w.reg.unregisterEvent(zkPath, &event)
+				w.reconnect()
 				w.handleZkNodeEvent(zkPath, nil)
 				continue
 			}
 		}
-		failTimes = 0
+		backoff.Reset()
+		w.updatePath(state, func(s *pathState) {
+			s.children, s.retries = children, 0
+		})
 
 		if flag {
-			if zkPath == w.opts.Root {
+			if w.isRoot(zkPath) {
 				err = w.handleZkPathEvent(zkPath, nil)
 			} else {
 				err = w.handleZkNodeEvent(zkPath, nil)
@@ -325,14 +778,15 @@ func (w *Watcher) watchDir(zkPath string) {
 
 		select {
 		case zkEvent = <-childEventCh:
-			log.Warn("get a zookeeper zkEvent {type:%s, server:%s, path:%s, state:%d-%s, err:%#v}",
-				zkEvent.Type, zkEvent.Server, zkEvent.Path, zkEvent.State,
-				w.reg.client.StateToString(zkEvent.State), zkEvent.Err)
+			w.errLog.Warnw("watchDir got a zookeeper event", "type", zkEvent.Type.String(),
+				"server", zkEvent.Server, "path", zkEvent.Path,
+				"state", w.reg.client.StateToString(zkEvent.State), "error", zkEvent.Err)
+			w.updatePath(state, func(s *pathState) { s.lastEvent = w.clock.Now() })
 			if zkEvent.Type != zk.EventNodeChildrenChanged {
 				continue
 			}
 
-			if zkEvent.Path == w.opts.Root {
+			if w.isRoot(zkEvent.Path) {
 				w.handleZkPathEvent(zkEvent.Path, children)
 			} else {
 				w.handleZkNodeEvent(zkEvent.Path, children)
@@ -340,21 +794,126 @@ func (w *Watcher) watchDir(zkPath string) {
 
 		case <-w.done:
 			// There is no way to stop GetW/ChildrenW so just quit
-			log.Warn("client.done(), watch(path{%s}, ServiceConfig{%#v}) goroutine exit now...",
-				zkPath, w.opts.Filter)
+			w.errLog.Warnw("watcher closed, watchDir goroutine exit now", "path", zkPath)
 			return
 		}
 	}
 }
 
 func (w *Watcher) Notify() (*gxregistry.EventResult, error) {
-	select {
-	case <-w.done:
-		return nil, jerrors.New("watcher stopped")
+	return w.NotifyCtx(context.Background())
+}
+
+// NotifyCtx returns the next event, ctx.Err() when @ctx is done before it,
+// or gxregistry.ErrWatcherClosed after Close. An event is not lost when
+// @ctx is done, it is returned by the next call.
+// The events are stamped with their Seq and kept for Replay.
+func (w *Watcher) NotifyCtx(ctx context.Context) (*gxregistry.EventResult, error) {
+	for {
+		if r, ok := w.queue.pop(); ok {
+			if r.err == nil {
+				w.replay.add(r.res)
+				w.updateCache(r.res)
+				w.metrics.WatchLag(time.Since(r.sent))
+			}
+			return r.res, r.err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-w.done:
+			return nil, gxregistry.ErrWatcherClosed
+
+		case <-w.queue.ready:
+		}
+	}
+}
+
+// Snapshot returns the services of the filter of the watcher under its
+// roots at once, one of each service node. The nodes are watched since
+// then, so the events notified after it are the changes since it: a node
+// of the snapshot is not notified as added, even if its Add event is queued
+// before, and a node missed by it, e.g. of a failed read, is notified as
+// added.
+func (w *Watcher) Snapshot() ([]*gxregistry.Service, error) {
+	if w.IsClosed() {
+		return nil, gxregistry.ErrWatcherClosed
+	}
+
+	var services []*gxregistry.Service
+	for _, root := range w.roots {
+		rootServices, err := w.snapshot(root)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, rootServices...)
+	}
+	if w.cache != nil {
+		if err := w.cache.Add(services); err != nil {
+			gxlog.LogError(w.errLog, "failed to write the local cache", err, "path", w.opts.LocalCache)
+		}
+	}
+
+	return services, nil
+}
+
+// snapshot returns the services under @root, see Snapshot.
+func (w *Watcher) snapshot(root string) ([]*gxregistry.Service, error) {
+	var services []*gxregistry.Service
+	err := w.walk(root, func(node string, service *gxregistry.Service, revision int64) {
+		services = append(services, service)
+
+		if w.syncNode(node, service, revision) {
+			w.goWatch(func() { w.watchNode(node) })
+		}
+	})
+
+	return services, err
+}
+
+// walk calls @fn with the service nodes of the filter of the watcher under
+// @root, their services and Mzxids, sorted by their paths. The nodes failed
+// to read are skipped, as they are notified by the watches of their paths.
+func (w *Watcher) walk(root string, fn func(node string, service *gxregistry.Service, revision int64)) error {
+	paths, err := w.reg.client.GetChildren(root)
+	if err != nil {
+		// the zookeeper client fails to get the children of an empty path
+		if !w.Valid() {
+			return jerrors.Trace(err)
+		}
+		return nil
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if !w.filterPath(p) {
+			continue
+		}
+		zkPath := path.Join(root, p)
+		nodes, err := w.reg.client.GetChildren(zkPath)
+		if err != nil {
+			// the nodes are notified by the watch of the path
+			continue
+		}
+		sort.Strings(nodes)
 
-	case r := <-w.events:
-		return r.res, r.err
+		for _, n := range nodes {
+			node := path.Join(zkPath, n)
+			data, stat, err := w.reg.client.GetWithStat(node)
+			if err != nil {
+				continue
+			}
+			service, err := decodeService(w.opts.Codec, data)
+			if err != nil || !w.opts.Filter.MeshFilter(*service.Attr) {
+				continue
+			}
+			fn(node, service, stat.Mzxid)
+		}
 	}
+
+	return nil
 }
 
 func (w *Watcher) Valid() bool {
@@ -367,7 +926,7 @@ func (w *Watcher) Valid() bool {
 		return false
 
 	default:
-		zkState := w.reg.client.ZkConn().State()
+		zkState := w.reg.client.State()
 		if zkState == zk.StateConnected || zkState == zk.StateHasSession {
 			return true
 		}
@@ -376,16 +935,34 @@ func (w *Watcher) Valid() bool {
 	}
 }
 
+// Close stops the watch goroutines and waits for them. The events not
+// notified yet are dropped, and Notify returns an error after it.
 func (w *Watcher) Close() {
 	w.Once.Do(func() {
-		if !w.IsClosed() {
-			close(w.done)
-		}
-
+		w.reg.removeWatcher(w)
+		// no goroutine is added after it, see goWatch
+		w.Lock()
+		close(w.done)
+		w.Unlock()
 		w.wg.Wait()
+
+		// no one sends after the goroutines exit
+		w.queue.clear()
+		w.replay.clear()
 	})
 }
 
+// Replay returns the notified events after the one of @seq, see
+// gxregistry.ReplayWatcher. The events are the ones returned by Notify, do not
+// change them.
+func (w *Watcher) Replay(seq uint64) ([]*gxregistry.EventResult, error) {
+	if w.IsClosed() {
+		return nil, gxregistry.ErrWatcherClosed
+	}
+
+	return w.replay.since(seq)
+}
+
 // check whether the session has been closed.
 func (w *Watcher) IsClosed() bool {
 	select {
@@ -396,3 +973,81 @@ func (w *Watcher) IsClosed() bool {
 		return false
 	}
 }
+
+// WatcherStats is the statistics of a Watcher.
+type WatcherStats struct {
+	Root   string                 `json:"root"` // the first of Roots
+	Roots  []string               `json:"roots"`
+	Filter gxregistry.ServiceAttr `json:"filter"`
+	Paths  []string               `json:"paths"` // the watched zookeeper paths
+	// PathStates is the state of the Paths, in the same order
+	PathStates []PathStats `json:"path_states"`
+	Pending    int         `json:"pending"`     // the events not notified yet
+	MaxPending int         `json:"max_pending"` // the max length of the event queue
+	Coalesced  int64       `json:"coalesced"`   // the events coalesced in the queue
+	Added      int64       `json:"added"`       // the ServiceAdd events sent
+	Updated    int64       `json:"updated"`     // the ServiceUpdate events sent
+	Deleted    int64       `json:"deleted"`     // the ServiceDel events sent
+	Dropped    int64       `json:"dropped"`     // the nodes failed to get or decode
+	Undecoded  int64       `json:"undecoded"`   // the nodes failed to decode
+	// Reconnects is the rewatches of the paths after failures
+	Reconnects int64 `json:"reconnects"`
+	// EventsPerSecond is the rate of the events sent in the last minute
+	EventsPerSecond float64 `json:"events_per_second"`
+	Valid           bool    `json:"valid"`
+	Closed          bool    `json:"closed"`
+}
+
+func (w *Watcher) Stats() WatcherStats {
+	w.Lock()
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	states := make([]PathStats, 0, len(paths))
+	for _, path := range paths {
+		s := w.paths[path]
+		states = append(states, PathStats{
+			Path:      path,
+			Children:  len(s.children),
+			LastEvent: s.lastEvent,
+			Retries:   s.retries,
+		})
+	}
+	w.Unlock()
+	pending, maxPending, coalesced := w.queue.stats()
+
+	return WatcherStats{
+		Root:       w.roots[0],
+		Roots:      w.roots,
+		Filter:     w.opts.Filter,
+		Paths:      paths,
+		PathStates: states,
+		Pending:    pending,
+		MaxPending: maxPending,
+		Coalesced:  coalesced,
+		Added:      w.added.Load(),
+		Updated:    w.updated.Load(),
+		Deleted:    w.deleted.Load(),
+		Dropped:    w.dropped.Load(),
+		Undecoded:  w.undecoded.Load(),
+		Reconnects: w.reconnects.Load(),
+		// of the last eventRateWindow
+		EventsPerSecond: w.events.Rate(),
+		Valid:           w.Valid(),
+		Closed:          w.IsClosed(),
+	}
+}
+
+// Inspect returns the Stats of the watcher.
+func (w *Watcher) Inspect() gxregistry.Inspection {
+	stats := w.Stats()
+
+	return gxregistry.Inspection{
+		Kind:  gxregistry.InspectWatcher,
+		Name:  "zookeeper watcher " + strings.Join(stats.Roots, ","),
+		Valid: stats.Valid,
+		State: stats,
+	}
+}
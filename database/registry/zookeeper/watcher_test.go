@@ -1,16 +1,33 @@
 package gxzookeeper
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
+import (
+	jerrors "github.com/juju/errors"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/suite"
+)
+
 import (
 	"github.com/AlexStocks/goext/database/registry"
+	"github.com/AlexStocks/goext/database/registry/zookeeper/zktest"
 	"github.com/AlexStocks/goext/log"
-	"github.com/stretchr/testify/suite"
+	"github.com/AlexStocks/goext/strings"
+	"github.com/AlexStocks/goext/time"
 )
 
 type WatcherTestSuite struct {
@@ -19,6 +36,7 @@ type WatcherTestSuite struct {
 	node gxregistry.Node
 	reg  gxregistry.Registry
 	wt   gxregistry.Watcher
+	ring *gxlog.RingLogger
 }
 
 func (suite *WatcherTestSuite) SetupSuite() {
@@ -39,10 +57,12 @@ func (suite *WatcherTestSuite) TearDownSuite() {
 func (suite *WatcherTestSuite) SetupTest() {
 	var err error
 
+	suite.ring = gxlog.NewRingLogger(1024)
 	suite.reg, err = NewRegistry(
 		gxregistry.WithAddrs([]string{"127.0.0.1:2181"}...),
 		gxregistry.WithTimeout(10e9),
 		gxregistry.WithRoot("/test"),
+		gxregistry.WithLogger(gxlog.Tee(gxlog.Default(), suite.ring)),
 	)
 	suite.Equal(nil, err, "NewRegistry")
 	suite.wt, err = suite.reg.Watch(
@@ -162,6 +182,16 @@ func (suite *WatcherTestSuite) TestZookeeperRestart() {
 	suite.Equalf(nil, err, "registry.GetService(ServiceAttr:%+v)", suite.sa)
 	suite.Equalf(1, len(service1[0].Nodes), "registry.GetService(ServiceAttr:%+v)", suite.sa)
 	suite.Equalf(2, len(service1), "registry.GetService(ServiceAttr:%+v)", suite.sa)
+
+	// the zookeeper session events of the (re)connections are warned
+	var warned bool
+	for _, r := range suite.ring.Filter(gxlog.LevelWarn) {
+		if strings.Contains(r.Message, "client get a zookeeper event") {
+			warned = true
+			break
+		}
+	}
+	suite.Truef(warned, "no zookeeper event warnings in %s", suite.ring.Records())
 }
 
 func (suite *WatcherTestSuite) TestNotify() {
@@ -219,3 +249,1383 @@ func (suite *WatcherTestSuite) TestNotify() {
 func TestWatcherTestSuite(t *testing.T) {
 	suite.Run(t, new(WatcherTestSuite))
 }
+
+//////////////////////////////////////////
+// watcher tests on gxzktest.Client
+//////////////////////////////////////////
+
+var fakeAttr = gxregistry.ServiceAttr{
+	Group:    "bjtelecom",
+	Service:  "shopping",
+	Protocol: "pb",
+	Version:  "1.0.1",
+	Role:     gxregistry.SRT_Provider,
+}
+
+type fakeZk struct {
+	client *gxzktest.Client
+	clock  *gxtime.FakeClock
+	reg    *Registry
+}
+
+func newFakeZk() *fakeZk {
+	client := gxzktest.NewClient()
+	reg := NewRegistryWithClient(client, client.Session(),
+		gxregistry.WithRoot("/test"),
+		gxregistry.WithLogger(gxlog.NewNop()),
+	).(*Registry)
+	clock := gxtime.NewFakeClock(time.Now())
+	reg.clock = clock
+
+	return &fakeZk{client: client, clock: clock, reg: reg}
+}
+
+func (z *fakeZk) service(attr gxregistry.ServiceAttr, id string) gxregistry.Service {
+	return gxregistry.Service{
+		Attr:  &attr,
+		Nodes: []*gxregistry.Node{{ID: id, Address: "127.0.0.1", Port: 12345}},
+	}
+}
+
+func (z *fakeZk) register(t *testing.T, attr gxregistry.ServiceAttr, id string) gxregistry.Service {
+	s := z.service(attr, id)
+	if err := z.reg.Register(s); err != nil {
+		t.Fatalf("Register(service:%+v) = error:%s", s, err)
+	}
+	return s
+}
+
+func (z *fakeZk) watch(t *testing.T) *Watcher {
+	w, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchFilter(gxregistry.ServiceAttr{
+			Service: "shopping",
+			Role:    gxregistry.SRT_Provider,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	return w.(*Watcher)
+}
+
+func (z *fakeZk) close(w *Watcher) {
+	w.Close()
+	z.reg.Close()
+}
+
+// notify returns the next event of @w in 1s.
+func notify(t *testing.T, w *Watcher) *gxregistry.EventResult {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := w.NotifyCtx(ctx)
+	if err != nil {
+		t.Fatalf("Notify() = error:%s, stats:%+v", err, w.Stats())
+	}
+	return e
+}
+
+func expectEvent(t *testing.T, w *Watcher, action gxregistry.ServiceEventType, id string) {
+	e := notify(t, w)
+	if e.Action != action || len(e.Service.Nodes) != 1 || e.Service.Nodes[0].ID != id {
+		t.Fatalf("Notify() = %s, want %s of %s", e.GoString(), action, id)
+	}
+}
+
+// eventually waits 1s for @cond.
+func eventually(t *testing.T, w *Watcher, cond func(WatcherStats) bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond(w.Stats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("stats:%+v", w.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeWatcherAddDel(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	z.client.WaitWatch(s0.Path("/test"))
+	z.register(t, fakeAttr, "node1")
+	expectEvent(t, w, gxregistry.ServiceAdd, "node1")
+	z.client.WaitWatch(s0.Path("/test"))
+	if stats := w.Stats(); len(stats.PathStates) != 2 || stats.PathStates[1].Children != 2 ||
+		stats.PathStates[1].LastEvent.IsZero() || stats.PathStates[1].Path != stats.Paths[1] {
+		t.Fatalf("stats:%+v", stats)
+	}
+
+	z.client.WaitWatch(s0.NodePath("/test", *s0.Nodes[0]))
+	if err := z.reg.Deregister(s0); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+	expectEvent(t, w, gxregistry.ServiceDel, "node0")
+
+	// an undecodable node is dropped
+	z.client.WaitWatch(s0.Path("/test"))
+	z.client.Create(s0.Path("/test")+"bad", []byte("{"))
+	eventually(t, w, func(s WatcherStats) bool { return s.Dropped == 1 })
+	if stats := w.Stats(); stats.Added != 2 || stats.Deleted != 1 || stats.Pending != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+func TestFakeWatcherEventRate(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+	z.client.WaitWatch(s0.Path("/test"))
+	z.register(t, fakeAttr, "node1")
+	expectEvent(t, w, gxregistry.ServiceAdd, "node1")
+
+	// the rate of the first bucket is of the whole bucket
+	span := eventRateWindow / eventRateBuckets
+	if rate := w.Stats().EventsPerSecond; rate != 2/span.Seconds() {
+		t.Fatalf("EventsPerSecond = %v, want %v", rate, 2/span.Seconds())
+	}
+	// the last bucket of the window
+	covered := eventRateWindow - span
+	z.clock.Advance(covered)
+	if rate := w.Stats().EventsPerSecond; rate != 2/covered.Seconds() {
+		t.Fatalf("EventsPerSecond = %v, want %v", rate, 2/covered.Seconds())
+	}
+	z.clock.Advance(span)
+	if rate := w.Stats().EventsPerSecond; rate != 0 {
+		t.Fatalf("EventsPerSecond a window later = %v", rate)
+	}
+}
+
+// recordingMetrics records the events observed by a gxregistry.MetricsHook.
+type recordingMetrics struct {
+	sync.Mutex
+	events       map[gxregistry.ServiceEventType]int
+	notified     int
+	dropped      int
+	decodeErrors int
+	reconnects   int
+}
+
+func (m *recordingMetrics) WatchEvent(action gxregistry.ServiceEventType) {
+	m.Lock()
+	m.events[action]++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) WatchDropped() {
+	m.Lock()
+	m.dropped++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) WatchDecodeError() {
+	m.Lock()
+	m.decodeErrors++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) WatchReconnect() {
+	m.Lock()
+	m.reconnects++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) WatchLag(lag time.Duration) {
+	m.Lock()
+	m.notified++
+	m.Unlock()
+}
+
+func TestFakeWatcherMetrics(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	s0 := z.register(t, fakeAttr, "node0")
+	z.client.SetError(gxzktest.OpGetChildrenW, errors.New("zk: connection loss"))
+	// the hook of the watcher overrides the one of the registry
+	z.reg.options.Metrics = gxregistry.NopMetricsHook{}
+	m := &recordingMetrics{events: make(map[gxregistry.ServiceEventType]int)}
+	wt, err := z.reg.Watch(gxregistry.WithWatchRoot("/test"), gxregistry.WithWatchMetricsHook(m))
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w := wt.(*Watcher)
+	defer w.Close()
+
+	z.clock.BlockUntil(1)
+	z.client.SetError(gxzktest.OpGetChildrenW, nil)
+	z.clock.Advance(time.Minute)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	z.client.WaitWatch(s0.Path("/test"))
+	z.register(t, fakeAttr, "node1")
+	expectEvent(t, w, gxregistry.ServiceAdd, "node1")
+	z.client.WaitWatch(s0.NodePath("/test", *s0.Nodes[0]))
+	if err = z.reg.Deregister(s0); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+	expectEvent(t, w, gxregistry.ServiceDel, "node0")
+
+	// an undecodable node, and a malformed service path
+	z.client.WaitWatch(s0.Path("/test"))
+	z.client.Create(s0.Path("/test")+"bad", []byte("{"))
+	eventually(t, w, func(s WatcherStats) bool { return s.Dropped == 1 })
+	z.client.WaitWatch("/test")
+	z.client.Create("/test/v2:bad", nil)
+	z.client.WaitWatch("/test")
+
+	m.Lock()
+	defer m.Unlock()
+	if m.events[gxregistry.ServiceAdd] != 2 || m.events[gxregistry.ServiceDel] != 1 || m.notified != 3 ||
+		m.dropped != 1 || m.decodeErrors != 2 || m.reconnects != 1 {
+		t.Fatalf("metrics:%+v, stats:%+v", m, w.Stats())
+	}
+}
+
+// statsMetrics reads the stats of its watcher when an event is sent.
+type statsMetrics struct {
+	gxregistry.NopMetricsHook
+	watcher chan *Watcher
+	stats   chan WatcherStats
+}
+
+func (m *statsMetrics) WatchEvent(gxregistry.ServiceEventType) {
+	w := <-m.watcher
+	m.watcher <- w
+	m.stats <- w.Stats()
+}
+
+func TestFakeWatcherMetricsUnlocked(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	m := &statsMetrics{watcher: make(chan *Watcher, 1), stats: make(chan WatcherStats, 1)}
+	wt, err := z.reg.Watch(gxregistry.WithWatchRoot("/test"), gxregistry.WithWatchMetricsHook(m))
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w := wt.(*Watcher)
+	defer w.Close()
+	m.watcher <- w
+
+	// the hook takes the lock of the watcher, so it is called out of it
+	z.register(t, fakeAttr, "node0")
+	select {
+	case stats := <-m.stats:
+		if stats.Added != 1 {
+			t.Fatalf("stats in WatchEvent:%+v", stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("WatchEvent is not called out of the lock of the watcher")
+	}
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+}
+
+func TestFakeWatcherFilter(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	consumer, other := fakeAttr, fakeAttr
+	consumer.Role = gxregistry.SRT_Consumer
+	other.Service = "payment"
+	for _, attr := range []gxregistry.ServiceAttr{consumer, other} {
+		z.client.WaitWatch("/test")
+		z.register(t, attr, "node1")
+	}
+	// the root is watched again after the filtered paths are handled
+	z.client.WaitWatch("/test")
+	if stats := w.Stats(); len(stats.Paths) != 2 || stats.Paths[1] != strings.TrimSuffix(s0.Path("/test"), "/") ||
+		stats.Added != 1 || stats.Pending != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+
+	// the services of a multi-value filter
+	wt, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchFilter(gxregistry.ServiceAttr{Service: "*,!shopping", Role: gxregistry.SRT_Provider}),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	defer wt.Close()
+	expectEvent(t, wt.(*Watcher), gxregistry.ServiceAdd, "node1")
+}
+
+func TestFakeWatcherReconnect(t *testing.T) {
+	z := newFakeZk()
+	z.register(t, fakeAttr, "node0")
+	z.client.SetError(gxzktest.OpGetChildrenW, errors.New("zk: connection loss"))
+	w := z.watch(t)
+	defer z.close(w)
+
+	// the watcher backs off after the failure
+	z.clock.BlockUntil(1)
+	if stats := w.Stats(); stats.Added != 0 || stats.Reconnects != 0 ||
+		len(stats.PathStates) != 1 || stats.PathStates[0].Retries != 1 {
+		t.Fatalf("stats:%+v", stats)
+	}
+	z.client.SetError(gxzktest.OpGetChildrenW, nil)
+	z.clock.Advance(time.Minute)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+	if stats := w.Stats(); stats.Reconnects != 1 || stats.PathStates[0].Retries != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+// delayClock records the delays of the timers of a FakeClock.
+type delayClock struct {
+	*gxtime.FakeClock
+
+	sync.Mutex
+	delays []time.Duration
+}
+
+func (c *delayClock) NewTimer(d time.Duration) gxtime.ClockTimer {
+	t := c.FakeClock.NewTimer(d)
+	c.Lock()
+	c.delays = append(c.delays, d)
+	c.Unlock()
+	return t
+}
+
+// next waits 1s for the @n-th delay.
+func (c *delayClock) next(t *testing.T, n int) time.Duration {
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.Lock()
+		delays := c.delays
+		c.Unlock()
+		if len(delays) >= n {
+			return delays[n-1]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delays:%v, want %d delays", delays, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeWatcherBackoff(t *testing.T) {
+	z := newFakeZk()
+	clock := &delayClock{FakeClock: z.clock}
+	z.reg.clock = clock
+	w, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchFilter(gxregistry.ServiceAttr{Service: "shopping"}),
+		func(o *gxregistry.WatchOptions) {
+			o.Backoff = &gxtime.Backoff{Base: time.Second, Max: 4 * time.Second, Jitter: gxtime.NoJitter}
+		},
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	defer z.close(w.(*Watcher))
+
+	// the root has none children, so its watch fails until one is added
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if d := clock.next(t, i+1); d != want {
+			t.Fatalf("delay %d = %s, want %s", i, d, want)
+		}
+		if i < 3 {
+			clock.Advance(want)
+		}
+	}
+
+	// a filtered out service makes the watch succeed
+	attr := fakeAttr
+	attr.Service = "cart"
+	z.register(t, attr, "node0")
+	clock.Advance(4 * time.Second)
+	z.client.WaitWatch("/test")
+	eventually(t, w.(*Watcher), func(s WatcherStats) bool {
+		return len(s.PathStates) == 1 && s.PathStates[0].Retries == 0
+	})
+
+	// the delays start over after the success
+	z.client.SetError(gxzktest.OpGetChildrenW, errors.New("zk: connection loss"))
+	z.client.Fire("/test", zk.EventNodeChildrenChanged)
+	if d := clock.next(t, 5); d != time.Second {
+		t.Fatalf("delay after a success = %s, want 1s", d)
+	}
+}
+
+func TestFakeWatcherWheel(t *testing.T) {
+	z := newFakeZk()
+	z.register(t, fakeAttr, "node0")
+	z.client.SetError(gxzktest.OpGetChildrenW, errors.New("zk: connection loss"))
+	wheel := gxtime.NewWheel(time.Millisecond, 16)
+	defer wheel.Stop()
+	w, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchWheel(wheel),
+		gxregistry.WithRetryBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	defer z.close(w.(*Watcher))
+
+	// the rewatches are timed by the wheel rather than the fake clock
+	eventually(t, w.(*Watcher), func(s WatcherStats) bool {
+		return len(s.PathStates) == 1 && s.PathStates[0].Retries > 3
+	})
+	z.client.SetError(gxzktest.OpGetChildrenW, nil)
+	expectEvent(t, w.(*Watcher), gxregistry.ServiceAdd, "node0")
+}
+
+// TestFakeWatcherRecreate deletes the path of a service, which is then
+// created again by a restarted provider.
+func TestFakeWatcherRecreate(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	node := s0.NodePath("/test", *s0.Nodes[0])
+	data, _ := z.client.Data(node)
+	z.client.WaitWatch(node)
+	z.client.Delete(s0.Path("/test"))
+	expectEvent(t, w, gxregistry.ServiceDel, "node0")
+	// the watch of the path fails, and backs off
+	z.clock.BlockUntil(1)
+	eventually(t, w, func(s WatcherStats) bool {
+		return len(s.PathStates) == 2 && s.PathStates[1].Retries > 0
+	})
+
+	// the path is created again, and watched again by the watch of the root
+	// or after the backoff
+	z.client.Create(node, data)
+	z.clock.Advance(time.Minute)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+	if stats := w.Stats(); stats.Added != 2 || stats.Deleted != 1 || len(stats.Paths) != 2 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+func TestFakeWatcherActions(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	// keeps the service path, which is rewatched after a backoff if empty
+	z.register(t, fakeAttr, "node2")
+	wt, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchFilter(fakeAttr),
+		gxregistry.WithWatchActions(gxregistry.ServiceDel),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w := wt.(*Watcher)
+	defer z.close(w)
+
+	// the nodes of the masked Add events are watched still
+	for _, id := range []string{"node0", "node1"} {
+		s := s0
+		if id != "node0" {
+			s = z.register(t, fakeAttr, id)
+		}
+		node := s.NodePath("/test", *s.Nodes[0])
+		z.client.WaitWatch(node)
+		stat, _ := z.client.Stat(node)
+		if err := z.reg.Deregister(s); err != nil {
+			t.Fatalf("Deregister() = error:%s", err)
+		}
+		e := notify(t, w)
+		if e.Action != gxregistry.ServiceDel || e.Service.Nodes[0].ID != id || e.Revision != stat.Mzxid {
+			t.Fatalf("Notify() = %s, want the Del of %s of revision %d", e.GoString(), id, stat.Mzxid)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if e, err := w.NotifyCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("NotifyCtx() = %v, error:%v", e, err)
+	}
+	if stats := w.Stats(); stats.Added != 0 || stats.Deleted != 2 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+func TestFakeWatcherRevision(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	// keeps the service path, which is rewatched after a backoff if empty
+	z.register(t, fakeAttr, "node1")
+	w := z.watch(t)
+	defer z.close(w)
+
+	// the events of node0
+	next := func() *gxregistry.EventResult {
+		for {
+			if e := notify(t, w); e.Service.Nodes[0].ID == "node0" {
+				return e
+			}
+		}
+	}
+	node := s0.NodePath("/test", *s0.Nodes[0])
+	var last int64
+	for i := 0; i < 5; i++ {
+		e := next()
+		stat, _ := z.client.Stat(node)
+		if e.Action != gxregistry.ServiceAdd || e.Revision != stat.Mzxid || e.Revision <= last {
+			t.Fatalf("Notify() = %s, last revision %d, Mzxid %d", e.GoString(), last, stat.Mzxid)
+		}
+		last = e.Revision
+
+		// the node flaps
+		z.client.WaitWatch(node)
+		if err := z.reg.Deregister(s0); err != nil {
+			t.Fatalf("Deregister() = error:%s", err)
+		}
+		if e = next(); e.Action != gxregistry.ServiceDel || e.Revision != last {
+			t.Fatalf("Notify() = %s, want the Del of revision %d", e.GoString(), last)
+		}
+		// created again after the path is watched again
+		z.client.WaitWatch(s0.Path("/test"))
+		z.register(t, fakeAttr, "node0")
+	}
+}
+
+// TestFakeWatcherUpdate changes the data of a node twice and deletes it,
+// and its Update and Del events carry its last data.
+func TestFakeWatcherUpdate(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	// keeps the service path, which is rewatched after a backoff if empty
+	z.register(t, fakeAttr, "node1")
+	w := z.watch(t)
+	defer z.close(w)
+
+	node := s0.NodePath("/test", *s0.Nodes[0])
+	next := func(action gxregistry.ServiceEventType, weight string) {
+		for {
+			e := notify(t, w)
+			if e.Service.Nodes[0].ID != "node0" {
+				continue
+			}
+			stat, _ := z.client.Stat(node)
+			if e.Action != action || e.Service.Nodes[0].Metadata["weight"] != weight || e.Revision != stat.Mzxid {
+				t.Fatalf("Notify() = %s, want %s of weight %q of revision %d", e.GoString(), action, weight, stat.Mzxid)
+			}
+			return
+		}
+	}
+	next(gxregistry.ServiceAdd, "")
+
+	for _, weight := range []string{"10", "20"} {
+		s := z.service(fakeAttr, "node0")
+		s.Nodes[0].Metadata = map[string]string{"weight": weight}
+		data, err := z.reg.options.Codec.Encode(&s)
+		if err != nil {
+			t.Fatalf("Encode() = error:%s", err)
+		}
+		z.client.WaitWatch(node)
+		z.client.Set(node, data)
+		next(gxregistry.ServiceUpdate, weight)
+	}
+
+	// the deleted node has no data, the Del is of the last one
+	z.client.WaitWatch(node)
+	revision, _ := z.client.Stat(node)
+	z.client.Delete(node)
+	e := notify(t, w)
+	if e.Action != gxregistry.ServiceDel || e.Service.Nodes[0].Metadata["weight"] != "20" ||
+		e.Revision != revision.Mzxid {
+		t.Fatalf("Notify() = %s, want the Del of weight 20 of revision %d", e.GoString(), revision.Mzxid)
+	}
+	if stats := w.Stats(); stats.Added != 2 || stats.Updated != 2 || stats.Deleted != 1 {
+		t.Fatalf("stats:%+v", stats)
+	}
+	w.Lock()
+	n := len(w.nodes)
+	w.Unlock()
+	if n != 1 {
+		t.Fatalf("%d watched nodes, want 1", n)
+	}
+}
+
+func TestFakeWatcherClose(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.reg.Close()
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+	z.client.WaitWatch(s0.NodePath("/test", *s0.Nodes[0]))
+
+	if !w.Valid() {
+		t.Fatalf("Valid() = false")
+	}
+	z.client.SetState(zk.StateDisconnected)
+	if w.Valid() {
+		t.Fatalf("Valid() of a disconnected client = true")
+	}
+	z.client.SetState(zk.StateHasSession)
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Close() blocks")
+	}
+	if _, err := w.Notify(); err == nil {
+		t.Fatalf("Notify() of a closed watcher = nil error")
+	}
+	if stats := w.Stats(); stats.Valid || !stats.Closed || len(stats.Paths) != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+// go test -race -run FakeWatcherCloseReconnect
+func TestFakeWatcherCloseReconnect(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		z := newFakeZk()
+		z.register(t, fakeAttr, "node0")
+		w := z.watch(t)
+		expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+		// the reconnections of the state fan-out race with Close
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 10; k++ {
+					w.connStateChanged(gxregistry.ConnReconnected)
+				}
+			}()
+		}
+		w.Close()
+		wg.Wait()
+
+		// no relist sends after Close
+		if stats := w.Stats(); stats.Pending != 0 {
+			t.Fatalf("stats after Close:%+v", stats)
+		}
+		z.reg.Close()
+	}
+}
+
+func TestFakeWatcherNotifyCtx(t *testing.T) {
+	z := newFakeZk()
+	w := z.watch(t)
+	defer z.reg.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := w.NotifyCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("NotifyCtx() without events = error:%v", err)
+	}
+
+	// an event racing with the cancellation is notified by the next call
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 100; i++ {
+		s := z.service(fakeAttr, fmt.Sprintf("node%d", i))
+		w.queue.push(event{res: &gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &s}})
+		res, err := w.NotifyCtx(canceled)
+		if errors.Is(err, context.Canceled) {
+			res, err = w.Notify()
+		}
+		if err != nil || res.Service.Nodes[0].ID != s.Nodes[0].ID {
+			t.Fatalf("NotifyCtx() %d = event:%v, error:%v", i, res, err)
+		}
+	}
+
+	// Close wakes up the waiting callers
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := w.NotifyCtx(context.Background())
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, gxregistry.ErrWatcherClosed) {
+				t.Fatalf("NotifyCtx() after Close() = error:%v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("NotifyCtx() blocks after Close()")
+		}
+	}
+	if _, err := w.NotifyCtx(context.Background()); err != gxregistry.ErrWatcherClosed {
+		t.Fatalf("NotifyCtx() of a closed watcher = error:%v", err)
+	}
+}
+
+// TestFakeWatcherSnapshot checks that the services registered before a
+// watcher is created are in its snapshot or notified after it, once.
+func TestFakeWatcherReplay(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	wt, err := z.reg.Watch(gxregistry.WithWatchRoot("/test"), gxregistry.WithWatchReplaySize(100))
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w := wt.(*Watcher)
+
+	// Replay races with Notify
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			events, err := w.Replay(uint64(i % 500))
+			if err != nil && !errors.Is(err, gxregistry.ErrReplayGap) {
+				t.Errorf("Replay() = error:%s", err)
+				return
+			}
+			for j := 1; j < len(events); j++ {
+				if events[j].Seq != events[j-1].Seq+1 {
+					t.Errorf("Replay() = seqs %d, %d", events[j-1].Seq, events[j].Seq)
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		s := z.service(fakeAttr, fmt.Sprintf("node%d", i))
+		w.queue.push(event{res: &gxregistry.EventResult{Action: gxregistry.ServiceAdd, Service: &s}})
+		if e := notify(t, w); e.Seq != uint64(i+1) || e.Service.Nodes[0].ID != s.Nodes[0].ID {
+			t.Fatalf("Notify() %d = %s", i, e.GoString())
+		}
+	}
+	<-done
+
+	for _, c := range []struct {
+		seq   uint64
+		first uint64 // the seq of the first replayed event
+		n     int
+	}{
+		{500, 0, 0},
+		{600, 0, 0},
+		{499, 500, 1},
+		{450, 451, 50},
+		// the first kept event
+		{400, 401, 100},
+	} {
+		events, err := w.Replay(c.seq)
+		if err != nil || len(events) != c.n {
+			t.Fatalf("Replay(%d) = %d events, error:%v", c.seq, len(events), err)
+		}
+		for i, e := range events {
+			if e.Seq != c.first+uint64(i) || e.Service.Nodes[0].ID != fmt.Sprintf("node%d", e.Seq-1) {
+				t.Fatalf("Replay(%d)[%d] = %s", c.seq, i, e.GoString())
+			}
+		}
+	}
+	for _, seq := range []uint64{399, 100, 0} {
+		if _, err := w.Replay(seq); !errors.Is(err, gxregistry.ErrReplayGap) {
+			t.Fatalf("Replay(%d) = error:%v, want ErrReplayGap", seq, err)
+		}
+	}
+
+	w.Close()
+	if _, err := w.Replay(450); !errors.Is(err, gxregistry.ErrWatcherClosed) {
+		t.Fatalf("Replay() after Close() = error:%v", err)
+	}
+}
+
+func TestFakeWatcherSnapshot(t *testing.T) {
+	t.Run("at once", func(t *testing.T) { testSnapshot(t, false) })
+	t.Run("after the events", func(t *testing.T) { testSnapshot(t, true) })
+}
+
+// testSnapshot takes the snapshot at once or, if @sent, after the watcher
+// sends the Add events of the services.
+func testSnapshot(t *testing.T, sent bool) {
+	z := newFakeZk()
+	other := fakeAttr
+	other.Version = "1.0.2"
+	consumer := fakeAttr
+	consumer.Role = gxregistry.SRT_Consumer
+	var want []string
+	for i := 0; i < 4; i++ {
+		z.register(t, fakeAttr, fmt.Sprintf("node%d", i))
+		z.register(t, other, fmt.Sprintf("node%d", i+4))
+		want = append(want, fmt.Sprintf("node%d", i), fmt.Sprintf("node%d", i+4))
+	}
+	z.register(t, consumer, "node8")
+	w := z.watch(t)
+	defer z.close(w)
+	if sent {
+		eventually(t, w, func(s WatcherStats) bool { return s.Pending == len(want) })
+	}
+
+	var sw gxregistry.Watcher = w
+	services, err := sw.(gxregistry.Snapshotter).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() = error:%s", err)
+	}
+	seen := make(map[string]int)
+	for _, s := range services {
+		seen[s.Nodes[0].ID]++
+	}
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		res, err := w.NotifyCtx(ctx)
+		cancel()
+		if err != nil {
+			break
+		}
+		if res.Action != gxregistry.ServiceAdd {
+			t.Fatalf("Notify() = %s", res.GoString())
+		}
+		seen[res.Service.Nodes[0].ID]++
+	}
+	for _, id := range want {
+		if seen[id] != 1 {
+			t.Fatalf("node %s is seen %d times, snapshot:%d, seen:%v", id, seen[id], len(services), seen)
+		}
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("seen:%v", seen)
+	}
+
+	// the nodes of the snapshot are watched
+	s0 := z.service(fakeAttr, "node0")
+	z.client.WaitWatch(s0.NodePath("/test", *s0.Nodes[0]))
+	if err = z.reg.Deregister(s0); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+	expectEvent(t, w, gxregistry.ServiceDel, "node0")
+	z.client.WaitWatch(s0.Path("/test"))
+	z.register(t, fakeAttr, "node9")
+	expectEvent(t, w, gxregistry.ServiceAdd, "node9")
+}
+
+// watchCache returns a watcher of the shopping providers of @z, which keeps
+// them in the local cache @cache.
+func (z *fakeZk) watchCache(t *testing.T, cache string) *Watcher {
+	w, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchFilter(gxregistry.ServiceAttr{
+			Service: "shopping",
+			Role:    gxregistry.SRT_Provider,
+		}),
+		gxregistry.WithLocalCache(cache),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	return w.(*Watcher)
+}
+
+// noEvent checks that @w notifies nothing in 50ms.
+func noEvent(t *testing.T, w *Watcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if res, err := w.NotifyCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Notify() = %v, error:%v", res, err)
+	}
+}
+
+func TestFakeWatcherLocalCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxzookeeper-cache")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = error:%s", err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "shopping.cache")
+
+	z := newFakeZk()
+	for i := 0; i < 3; i++ {
+		z.register(t, fakeAttr, fmt.Sprintf("node%d", i))
+	}
+	w := z.watchCache(t, cache)
+	for i := 0; i < 3; i++ {
+		if e := notify(t, w); e.Action != gxregistry.ServiceAdd || e.Stale {
+			t.Fatalf("Notify() = %s", e.GoString())
+		}
+	}
+	s0 := z.service(fakeAttr, "node0")
+	z.client.WaitWatch(s0.NodePath("/test", *s0.Nodes[0]))
+	if err = z.reg.Deregister(s0); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+	expectEvent(t, w, gxregistry.ServiceDel, "node0")
+	z.client.WaitWatch(s0.Path("/test"))
+	z.register(t, fakeAttr, "node3")
+	expectEvent(t, w, gxregistry.ServiceAdd, "node3")
+	z.close(w)
+
+	want := []string{"node1", "node2", "node3"}
+	services, err := gxregistry.LoadLocalCache(cache)
+	if err != nil || len(services) != 1 {
+		t.Fatalf("LoadLocalCache() = %v, error:%v", services, err)
+	}
+	var ids []string
+	for _, node := range services[0].Nodes {
+		ids = append(ids, node.ID)
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("nodes of the local cache %v, want %v", ids, want)
+	}
+
+	// a watcher of an unreachable registry notifies the nodes of the cache
+	z = newFakeZk()
+	z.client.SetState(zk.StateDisconnected)
+	w = z.watchCache(t, cache)
+	ids = ids[:0]
+	for range want {
+		e := notify(t, w)
+		if e.Action != gxregistry.ServiceAdd || !e.Stale || e.Root != "/test" {
+			t.Fatalf("Notify() = %s", e.GoString())
+		}
+		ids = append(ids, e.Service.Nodes[0].ID)
+	}
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("stale nodes %v, want %v", ids, want)
+	}
+	noEvent(t, w)
+	z.close(w)
+
+	// the stale events are not written back
+	if services, err = gxregistry.LoadLocalCache(cache); err != nil || len(services[0].Nodes) != len(want) {
+		t.Fatalf("LoadLocalCache() = %v, error:%v", services, err)
+	}
+}
+
+func TestFakeWatcherLocalCacheCorrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxzookeeper-cache")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = error:%s", err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "shopping.cache")
+
+	z := newFakeZk()
+	z.register(t, fakeAttr, "node0")
+	w := z.watchCache(t, cache)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+	z.close(w)
+
+	data, err := ioutil.ReadFile(cache)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() = error:%s", err)
+	}
+	if err = ioutil.WriteFile(cache, data[:len(data)/2], 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() = error:%s", err)
+	}
+	z = newFakeZk()
+	z.client.SetState(zk.StateDisconnected)
+	w = z.watchCache(t, cache)
+	defer z.close(w)
+	noEvent(t, w)
+}
+
+func TestFakeWatcherRoots(t *testing.T) {
+	z := newFakeZk()
+	s0 := z.register(t, fakeAttr, "node0")
+	data, _ := z.client.Data(s0.NodePath("/test", *s0.Nodes[0]))
+	otherNode := s0.NodePath("/other", *s0.Nodes[0])
+	z.client.Create(otherNode, data)
+
+	if _, err := z.reg.Watch(gxregistry.WithWatchRoots("/test", "/test/a")); jerrors.Cause(err) != gxregistry.ErrOverlappingRoots {
+		t.Fatalf("Watch() of overlapping roots = error:%v", err)
+	}
+	wt, err := z.reg.Watch(
+		gxregistry.WithWatchRoots("/test", "/other/"),
+		gxregistry.WithWatchFilter(gxregistry.ServiceAttr{Service: "shopping", Role: gxregistry.SRT_Provider}),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w := wt.(*Watcher)
+	defer z.reg.Close()
+
+	roots := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		e := notify(t, w)
+		if e.Action != gxregistry.ServiceAdd || e.Service.Nodes[0].ID != "node0" {
+			t.Fatalf("Notify() = %s", e.GoString())
+		}
+		roots[e.Root]++
+	}
+	if roots["/test"] != 1 || roots["/other"] != 1 {
+		t.Fatalf("roots of the events:%v", roots)
+	}
+
+	z.client.WaitWatch(otherNode)
+	z.client.Delete(otherNode)
+	if e := notify(t, w); e.Action != gxregistry.ServiceDel || e.Root != "/other" {
+		t.Fatalf("Notify() = %s", e.GoString())
+	}
+	if stats := w.Stats(); len(stats.Paths) != 4 || !reflect.DeepEqual(stats.Roots, []string{"/test", "/other"}) {
+		t.Fatalf("stats:%+v", stats)
+	}
+
+	// Close stops the watches of all the roots
+	w.Close()
+	if stats := w.Stats(); len(stats.Paths) != 0 {
+		t.Fatalf("stats after Close():%+v", stats)
+	}
+}
+
+func TestFakeWatcherCodec(t *testing.T) {
+	z := newFakeZk()
+	defer z.reg.Close()
+	z.register(t, fakeAttr, "node0")
+	s1 := z.service(fakeAttr, "node1")
+	data, _ := gxregistry.GetCodec(gxregistry.CodecProtobuf).Encode(&s1)
+	z.client.Create(s1.NodePath("/test", *s1.Nodes[0]), data)
+	s2 := z.service(fakeAttr, "node2")
+	z.client.Create(s2.NodePath("/test", *s2.Nodes[0]), []byte("\x0a\xff"))
+
+	// both codecs by default, and the broken node is counted only
+	w := z.watch(t)
+	ids := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		e := notify(t, w)
+		ids[e.Service.Nodes[0].ID] = e.Action == gxregistry.ServiceAdd
+	}
+	if !ids["node0"] || !ids["node1"] {
+		t.Fatalf("the added nodes:%v", ids)
+	}
+	eventually(t, w, func(s WatcherStats) bool { return s.Undecoded == 1 })
+	w.Close()
+
+	// the json codec only, and the nodes failed to decode are notified
+	wt, err := z.reg.Watch(
+		gxregistry.WithWatchRoot("/test"),
+		gxregistry.WithWatchCodec(gxregistry.GetCodec(gxregistry.CodecJSON)),
+		gxregistry.WithWatchDecodeErrors(),
+	)
+	if err != nil {
+		t.Fatalf("Watch() = error:%s", err)
+	}
+	w = wt.(*Watcher)
+	defer w.Close()
+	var errs int
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		e, err := w.NotifyCtx(ctx)
+		cancel()
+		switch {
+		case err == nil:
+			if e.Action != gxregistry.ServiceAdd || e.Service.Nodes[0].ID != "node0" {
+				t.Fatalf("Notify() = %s", e.GoString())
+			}
+		case jerrors.Cause(err) == gxregistry.ErrorServiceDecode:
+			errs++
+		default:
+			t.Fatalf("Notify() = error:%s", err)
+		}
+	}
+	if stats := w.Stats(); errs != 2 || stats.Undecoded != 2 || stats.Added != 1 {
+		t.Fatalf("decode errors:%d, stats:%+v", errs, stats)
+	}
+}
+
+func TestFakeWatcherSession(t *testing.T) {
+	client := gxzktest.NewClient()
+	transitions := make(chan [2]gxregistry.ConnState, 8)
+	reg := NewRegistryWithClient(client, client.Session(),
+		gxregistry.WithRoot("/test"),
+		gxregistry.WithLogger(gxlog.NewNop()),
+		gxregistry.WithStateListener(func(old, new gxregistry.ConnState) {
+			transitions <- [2]gxregistry.ConnState{old, new}
+		}),
+	).(*Registry)
+	z := &fakeZk{client: client, clock: gxtime.NewFakeClock(time.Now()), reg: reg}
+	z.register(t, fakeAttr, "node0")
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	// the connection is lost, and then its session expires
+	session := func(state zk.State) {
+		client.SetState(state)
+		client.SendSession(zk.Event{Type: zk.EventSession, State: state})
+	}
+	session(zk.StateDisconnected)
+	session(zk.StateConnecting)
+	session(zk.StateExpired)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := w.NotifyCtx(ctx); err != gxregistry.ErrSessionExpired {
+		t.Fatalf("Notify() after the expiry = error:%v", err)
+	}
+
+	// the services are sent again after the session is back
+	session(zk.StateConnected)
+	session(zk.StateHasSession)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	for i, want := range [][2]gxregistry.ConnState{
+		{gxregistry.ConnConnected, gxregistry.ConnDisconnected},
+		{gxregistry.ConnDisconnected, gxregistry.ConnExpired},
+		{gxregistry.ConnExpired, gxregistry.ConnReconnected},
+	} {
+		select {
+		case got := <-transitions:
+			if got != want {
+				t.Fatalf("transition %d = %s -> %s, want %s -> %s", i, got[0], got[1], want[0], want[1])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no transition %d", i)
+		}
+	}
+	if state := reg.Inspect().State.(registryState); state.ConnState != "reconnected" {
+		t.Fatalf("Inspect() = %+v", state)
+	}
+	if stats := w.Stats(); stats.Added != 2 || stats.Pending != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+// TestFakeRegistryReregister expires the session of a provider, and checks
+// that its nodes are created again, but the deregistered ones.
+func TestFakeRegistryReregister(t *testing.T) {
+	client := gxzktest.NewClient()
+	failures := make(chan gxregistry.Service, 1)
+	reg := NewRegistryWithClient(client, client.Session(),
+		gxregistry.WithRoot("/test"),
+		gxregistry.WithLogger(gxlog.NewNop()),
+		gxregistry.WithReregisterPolicy(&gxtime.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     gxtime.Backoff{Base: time.Millisecond, Max: time.Millisecond},
+		}),
+		gxregistry.WithReregisterFailure(func(s gxregistry.Service, err error) {
+			failures <- s
+		}),
+	).(*Registry)
+	z := &fakeZk{client: client, clock: gxtime.NewFakeClock(time.Now()), reg: reg}
+	// the failed watches are retried by the reconnection only
+	reg.clock = z.clock
+	s0 := z.register(t, fakeAttr, "node0")
+	other := fakeAttr
+	other.Service = "other"
+	s1 := z.register(t, other, "node1")
+	node0, node1 := s0.NodePath("/test", *s0.Nodes[0]), s1.NodePath("/test", *s1.Nodes[0])
+	w := z.watch(t)
+	defer z.close(w)
+	expectEvent(t, w, gxregistry.ServiceAdd, "node0")
+
+	session := func(state zk.State) {
+		client.SetState(state)
+		client.SendSession(zk.Event{Type: zk.EventSession, State: state})
+	}
+	// expire expires the session, which deletes the ephemeral node0, and
+	// waits for the Add of node0 after the session is back.
+	expire := func() {
+		if _, ok := client.Data(node0); ok {
+			client.WaitWatch(node0)
+			client.Delete(node0)
+		}
+		session(zk.StateDisconnected)
+		session(zk.StateExpired)
+		session(zk.StateHasSession)
+
+		expired := false
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			e, err := w.NotifyCtx(ctx)
+			cancel()
+			switch {
+			case err == gxregistry.ErrSessionExpired:
+				expired = true
+			case err != nil:
+				t.Fatalf("Notify() = error:%s, stats:%+v", err, w.Stats())
+			case expired && e.Action == gxregistry.ServiceAdd && e.Service.Nodes[0].ID == "node0":
+				return
+			}
+		}
+	}
+
+	// node1 is deregistered during the expiry, so it is not created again
+	if err := reg.Deregister(s1); err != nil {
+		t.Fatalf("Deregister() = error:%s", err)
+	}
+	expire()
+	if _, ok := client.Data(node0); !ok {
+		t.Fatalf("%s is not created again", node0)
+	}
+	if _, ok := client.Data(node1); ok {
+		t.Fatalf("the deregistered %s is created again", node1)
+	}
+
+	// a service failed to register again is reported after the retries
+	client.SetError(gxzktest.OpRegisterTemp, zk.ErrConnectionClosed)
+	client.WaitWatch(node0)
+	client.Delete(node0)
+	session(zk.StateDisconnected)
+	session(zk.StateExpired)
+	session(zk.StateHasSession)
+	select {
+	case s := <-failures:
+		if s.Nodes[0].ID != "node0" {
+			t.Fatalf("the failed service:%+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no reregister failure")
+	}
+	client.SetError(gxzktest.OpRegisterTemp, nil)
+	expire()
+}
+
+// TestFakeWatcherCloseFull closes a watcher whose events are not notified,
+// more than the initial capacity of its event queue.
+func TestFakeWatcherCloseFull(t *testing.T) {
+	const n = Wactch_Event_Channel_Size + 8
+	z := newFakeZk()
+	for i := 0; i < n; i++ {
+		z.register(t, fakeAttr, fmt.Sprintf("node%d", i))
+	}
+	w := z.watch(t)
+	defer z.reg.Close()
+	eventually(t, w, func(s WatcherStats) bool { return s.Pending == n })
+
+	var wg sync.WaitGroup
+	closed := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Close()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Close() of a full watcher blocks")
+	}
+
+	if _, err := w.Notify(); err == nil {
+		t.Fatalf("Notify() of a closed watcher = nil error")
+	}
+	if stats := w.Stats(); stats.Pending != 0 || stats.MaxPending != n || stats.Added != n {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+// TestFakeWatcherCoalesce sends 10k events of flapping nodes without a
+// consumer, and checks the final state of the nodes notified.
+func TestFakeWatcherCoalesce(t *testing.T) {
+	const (
+		nodes  = 500
+		events = 10000
+	)
+	z := newFakeZk()
+	w := z.watch(t)
+	defer z.close(w)
+
+	rnd := rand.New(rand.NewSource(1))
+	send := func(action gxregistry.ServiceEventType, id string, port int) {
+		s := z.service(fakeAttr, id)
+		s.Nodes[0].Port = int32(port)
+		w.send(action, "/test/"+id, &s, int64(port))
+	}
+	want := make(map[string]int32) // the port of the last Add of a node
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < events; i++ {
+			id := fmt.Sprintf("node%d", rnd.Intn(nodes))
+			if _, ok := want[id]; ok && rnd.Intn(2) == 0 {
+				delete(want, id)
+				send(gxregistry.ServiceDel, id, i)
+				continue
+			}
+			want[id] = int32(i)
+			send(gxregistry.ServiceAdd, id, i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("send() blocks, stats:%+v", w.Stats())
+	}
+
+	// a node is queued once, and the Del of a node not notified before
+	// removes its Add
+	stats := w.Stats()
+	if stats.Pending != len(want) || stats.MaxPending > nodes || int(stats.Coalesced)+stats.Pending != events {
+		t.Fatalf("stats:%+v, want %d pending", stats, len(want))
+	}
+	got := make(map[string]int32)
+	for i := 0; i < stats.Pending; i++ {
+		e := notify(t, w)
+		id := e.Service.Nodes[0].ID
+		if _, ok := got[id]; ok || e.Action != gxregistry.ServiceAdd {
+			t.Fatalf("Notify() = %s, notified:%v", e.GoString(), got)
+		}
+		got[id] = e.Service.Nodes[0].Port
+	}
+	if len(got) != len(want) {
+		t.Fatalf("notified %d nodes, want %d", len(got), len(want))
+	}
+	for id, port := range want {
+		if got[id] != port {
+			t.Fatalf("port of %s = %d, want %d", id, got[id], port)
+		}
+	}
+
+	// the last actions of the notified nodes, in their order
+	var ids []string
+	for id := range want {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	a, b := ids[0], ids[1]
+	send(gxregistry.ServiceDel, a, 1)
+	send(gxregistry.ServiceDel, b, 2)
+	send(gxregistry.ServiceAdd, a, 3)
+	send(gxregistry.ServiceAdd, b, 4)
+	send(gxregistry.ServiceDel, b, 5)
+	expectEvent(t, w, gxregistry.ServiceAdd, a)
+	expectEvent(t, w, gxregistry.ServiceDel, b)
+	if stats = w.Stats(); stats.Pending != 0 {
+		t.Fatalf("stats:%+v", stats)
+	}
+}
+
+// BenchmarkWatcherPaths checks whether a path is watched among 10k paths,
+// by the map of the watcher and by a slice.
+func BenchmarkWatcherPaths(b *testing.B) {
+	const n = 10000
+	paths := make([]string, n)
+	w := &Watcher{paths: make(map[string]*pathState, n)}
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/test/group%%3Dbjtelecom%%26service%%3Dshopping%d", i)
+		w.addPath(paths[i])
+	}
+
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, ok := w.addPath(paths[i%n]); ok {
+				b.Fatalf("path %s is not watched", paths[i%n])
+			}
+		}
+	})
+	b.Run("slice", func(b *testing.B) {
+		var mu sync.Mutex
+		for i := 0; i < b.N; i++ {
+			mu.Lock()
+			found := gxstrings.ContainsAny(paths, paths[i%n])
+			mu.Unlock()
+			if !found {
+				b.Fatalf("path %s is not watched", paths[i%n])
+			}
+		}
+	})
+}
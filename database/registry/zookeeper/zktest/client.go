@@ -0,0 +1,641 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxzktest provides a fake zookeeper client of the registry
+package gxzktest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+import (
+	"github.com/AlexStocks/goext/database/zookeeper"
+)
+
+// Op is a method of the client which can fail by SetError.
+type Op string
+
+const (
+	OpGet          Op = "Get"
+	OpGetChildren  Op = "GetChildren"
+	OpGetChildrenW Op = "GetChildrenW"
+	OpExistW       Op = "ExistW"
+	OpCreateZkPath Op = "CreateZkPath"
+	OpDeleteZkPath Op = "DeleteZkPath"
+	OpRegisterTemp Op = "RegisterTemp"
+	OpMulti        Op = "Multi"
+)
+
+const sessionChannelSize = 16
+
+// Client is an in-memory zookeeper tree implementing
+// gxzookeeper.ZkClient. Its methods return the errors of
+// gxzookeeper.Client for missing nodes and empty children, and the
+// watches fire once as those of zookeeper do. The ACLs of the nodes are
+// checked by the writes of the methods, not by the scripts, e.g.
+//
+//	c := gxzktest.NewClient()
+//	c.Create(service.NodePath("/test", node), data)
+//	r := gxzookeeper.NewRegistryWithClient(c, c.Session(), gxregistry.WithRoot("/test"))
+//	w, _ := r.Watch(gxregistry.WithWatchRoot("/test"))
+//	c.WaitWatch(service.NodePath("/test", node))
+//	c.Delete(service.NodePath("/test", node))
+type Client struct {
+	*tree
+	state   zk.State
+	errs    map[Op]error
+	session chan zk.Event
+	ids     []zk.ACL // authenticated by AddAuth, besides world:anyone
+}
+
+// tree is the nodes shared by the clients of the sessions of NewSession.
+type tree struct {
+	sync.Mutex
+	cond       *sync.Cond
+	nodes      map[string][]byte // "/" is implicit
+	stats      map[string]*zk.Stat
+	acls       map[string][]zk.ACL // nil of the nodes open to all
+	zxid       int64               // of the last change of the tree
+	childWatch map[string][]chan zk.Event
+	existWatch map[string][]chan zk.Event
+}
+
+// NewClient returns a client of an empty tree in zk.StateHasSession.
+func NewClient() *Client {
+	t := &tree{
+		nodes:      make(map[string][]byte),
+		stats:      make(map[string]*zk.Stat),
+		acls:       make(map[string][]zk.ACL),
+		childWatch: make(map[string][]chan zk.Event),
+		existWatch: make(map[string][]chan zk.Event),
+	}
+	t.cond = sync.NewCond(&t.Mutex)
+
+	return newSession(t)
+}
+
+func newSession(t *tree) *Client {
+	return &Client{
+		tree:    t,
+		state:   zk.StateHasSession,
+		errs:    make(map[Op]error),
+		session: make(chan zk.Event, sessionChannelSize),
+	}
+}
+
+// NewSession returns a client of another session on the tree of c, which
+// is not authenticated, e.g. to write the nodes of an ACL as another
+// zookeeper client. The watches are shared by the sessions.
+func (c *Client) NewSession() *Client {
+	return newSession(c.tree)
+}
+
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean(p)
+}
+
+//////////////////////////////////////////
+// script
+//////////////////////////////////////////
+
+// Session returns the session events of the client, to be passed to
+// gxzookeeper.NewRegistryWithClient.
+func (c *Client) Session() <-chan zk.Event {
+	return c.session
+}
+
+// SendSession sends @event to the session events.
+func (c *Client) SendSession(event zk.Event) {
+	c.session <- event
+}
+
+// SetState sets the connection state of the client.
+func (c *Client) SetState(state zk.State) {
+	c.Lock()
+	c.state = state
+	c.Unlock()
+}
+
+// SetError makes all the calls of @op fail by @err, until it is set to nil.
+func (c *Client) SetError(op Op, err error) {
+	c.Lock()
+	if err == nil {
+		delete(c.errs, op)
+	} else {
+		c.errs[op] = err
+	}
+	c.Unlock()
+}
+
+// Create creates the node @p of @data and its missing parents, and fires
+// the watches of the created nodes. The data of an existing node is set
+// without a watch, as a new version of the node.
+func (c *Client) Create(p string, data []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	p = cleanPath(p)
+	if c.exist(p) {
+		c.setData(p, data)
+		return
+	}
+	c.createAll(path.Dir(p))
+	c.create(p, data, nil)
+}
+
+// Set sets the data of the existing node @p as zk.Conn.Set does, and fires
+// its exist watches of zk.EventNodeDataChanged.
+func (c *Client) Set(p string, data []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	p = cleanPath(p)
+	if !c.exist(p) {
+		return
+	}
+	c.setData(p, data)
+	c.fire(c.existWatch, p, zk.EventNodeDataChanged)
+}
+
+// Delete deletes the node @p and its children, and fires their watches.
+func (c *Client) Delete(p string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.deleteTree(cleanPath(p))
+}
+
+// Fire sends an event of @eventType to all the watches of @p.
+func (c *Client) Fire(p string, eventType zk.EventType) {
+	c.Lock()
+	defer c.Unlock()
+
+	p = cleanPath(p)
+	c.fire(c.childWatch, p, eventType)
+	c.fire(c.existWatch, p, eventType)
+}
+
+// WaitWatch blocks until the node @p is watched.
+func (c *Client) WaitWatch(p string) {
+	c.Lock()
+	defer c.Unlock()
+
+	p = cleanPath(p)
+	for len(c.childWatch[p]) == 0 && len(c.existWatch[p]) == 0 {
+		c.cond.Wait()
+	}
+}
+
+// Data returns the data of the node @p, and whether it exists.
+func (c *Client) Data(p string) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	data, ok := c.nodes[cleanPath(p)]
+	return data, ok
+}
+
+// Stat returns the stat of the node @p, and whether it exists. The zxids
+// are those of the changes of the client.
+func (c *Client) Stat(p string) (zk.Stat, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	stat, ok := c.stats[cleanPath(p)]
+	if !ok {
+		return zk.Stat{}, false
+	}
+	return *stat, true
+}
+
+// ACL returns the ACL of the node @p, nil if it is open to all, and
+// whether it exists.
+func (c *Client) ACL(p string) ([]zk.ACL, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	p = cleanPath(p)
+	if !c.exist(p) {
+		return nil, false
+	}
+	return append([]zk.ACL(nil), c.acls[p]...), true
+}
+
+// setData sets the data of the existing node @p and bumps its version, c
+// must be locked.
+func (c *Client) setData(p string, data []byte) {
+	c.zxid++
+	stat := c.stats[p]
+	stat.Mzxid = c.zxid
+	stat.Version++
+	stat.DataLength = int32(len(data))
+	c.nodes[p] = data
+}
+
+// create adds @p of @acl and fires the child watches of its parent, c must
+// be locked.
+func (c *Client) create(p string, data []byte, acl []zk.ACL) {
+	c.zxid++
+	c.stats[p] = &zk.Stat{Czxid: c.zxid, Mzxid: c.zxid, DataLength: int32(len(data))}
+	c.nodes[p] = data
+	if acl != nil {
+		c.acls[p] = acl
+	}
+	c.fire(c.existWatch, p, zk.EventNodeCreated)
+	c.fire(c.childWatch, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+// createAll creates the missing nodes of @p level by level, c must be
+// locked.
+func (c *Client) createAll(p string) {
+	var tmpPath string
+	for _, str := range strings.Split(p, "/")[1:] {
+		tmpPath = path.Join(tmpPath, "/", str)
+		if !c.exist(tmpPath) {
+			c.create(tmpPath, nil, nil)
+		}
+	}
+}
+
+// deleteTree removes @p and its children, c must be locked.
+func (c *Client) deleteTree(p string) {
+	for _, child := range c.children(p) {
+		c.deleteTree(path.Join(p, child))
+	}
+	if _, ok := c.nodes[p]; ok {
+		c.delete(p)
+	}
+}
+
+// delete removes @p and fires its watches, c must be locked.
+func (c *Client) delete(p string) {
+	c.zxid++
+	delete(c.nodes, p)
+	delete(c.stats, p)
+	delete(c.acls, p)
+	c.fire(c.existWatch, p, zk.EventNodeDeleted)
+	c.fire(c.childWatch, p, zk.EventNodeDeleted)
+	c.fire(c.childWatch, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+// fire sends the event to the watches of @p once, c must be locked.
+func (c *Client) fire(watches map[string][]chan zk.Event, p string, eventType zk.EventType) {
+	for _, ch := range watches[p] {
+		ch <- zk.Event{Type: eventType, State: c.state, Path: p}
+		close(ch)
+	}
+	delete(watches, p)
+}
+
+// watch adds a watch of @p, c must be locked.
+func (c *Client) watch(watches map[string][]chan zk.Event, p string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	watches[p] = append(watches[p], ch)
+	c.cond.Broadcast()
+
+	return ch
+}
+
+// children returns the sorted child names of @p, c must be locked.
+func (c *Client) children(p string) []string {
+	var children []string
+	for node := range c.nodes {
+		if node != p && path.Dir(node) == p {
+			children = append(children, path.Base(node))
+		}
+	}
+	sort.Strings(children)
+
+	return children
+}
+
+// check returns the error of @op, c must be locked.
+func (c *Client) check(op Op) error {
+	if err := c.errs[op]; err != nil {
+		return err
+	}
+	if c.state == zk.StateDisconnected || c.state == zk.StateExpired {
+		return zk.ErrClosing
+	}
+
+	return nil
+}
+
+// allowed returns whether the session has the permission @perm of @acl, c
+// must be locked.
+func (c *Client) allowed(acl []zk.ACL, perm int32) bool {
+	if acl == nil {
+		return true
+	}
+	for _, a := range acl {
+		if a.Perms&perm == 0 {
+			continue
+		}
+		if a.Scheme == "world" && a.ID == "anyone" {
+			return true
+		}
+		for _, id := range c.ids {
+			if a.Scheme == id.Scheme && a.ID == id.ID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *Client) exist(p string) bool {
+	_, ok := c.nodes[p]
+	return ok || p == "/"
+}
+
+//////////////////////////////////////////
+// gxzookeeper.ZkClient
+//////////////////////////////////////////
+
+func (c *Client) State() zk.State {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.state
+}
+
+func (c *Client) StateToString(state zk.State) string {
+	return gxzookeeper.StateToString(state)
+}
+
+// AddAuth authenticates the session by "user:password" of the digest
+// scheme, which is the only scheme supported.
+func (c *Client) AddAuth(scheme string, auth []byte) error {
+	c.Lock()
+	defer c.Unlock()
+
+	credential := strings.SplitN(string(auth), ":", 2)
+	if scheme != "digest" || len(credential) != 2 {
+		return jerrors.Annotatef(zk.ErrAuthFailed, "zk.AddAuth(scheme:%s)", scheme)
+	}
+	id := zk.DigestACL(zk.PermAll, credential[0], credential[1])[0]
+	c.ids = append(c.ids, zk.ACL{Scheme: id.Scheme, ID: id.ID})
+
+	return nil
+}
+
+func (c *Client) CreateZkPath(p string) error {
+	return c.EnsurePath(p, nil)
+}
+
+// EnsurePath fails by the error of OpCreateZkPath as CreateZkPath.
+func (c *Client) EnsurePath(p string, acl []zk.ACL) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpCreateZkPath); err != nil {
+		return err
+	}
+	var tmpPath string
+	for _, str := range strings.Split(cleanPath(p), "/")[1:] {
+		tmpPath = path.Join(tmpPath, "/", str)
+		if c.exist(tmpPath) {
+			continue
+		}
+		if !c.allowed(c.acls[path.Dir(tmpPath)], zk.PermCreate) {
+			return jerrors.Annotatef(zk.ErrNoAuth, "zk.Create(path:%s)", tmpPath)
+		}
+		c.create(tmpPath, nil, aclOrWorld(acl))
+	}
+
+	return nil
+}
+
+func aclOrWorld(acl []zk.ACL) []zk.ACL {
+	if len(acl) == 0 {
+		return zk.WorldACL(zk.PermAll)
+	}
+	return acl
+}
+
+func (c *Client) DeleteZkPath(p string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpDeleteZkPath); err != nil {
+		return err
+	}
+	p = cleanPath(p)
+	if !c.exist(p) {
+		return jerrors.Annotatef(zk.ErrNoNode, "zk.Delete(path:%s)", p)
+	}
+	if len(c.children(p)) != 0 {
+		return jerrors.Annotatef(zk.ErrNotEmpty, "zk.Delete(path:%s)", p)
+	}
+	if !c.allowed(c.acls[path.Dir(p)], zk.PermDelete) {
+		return jerrors.Annotatef(zk.ErrNoAuth, "zk.Delete(path:%s)", p)
+	}
+	c.delete(p)
+
+	return nil
+}
+
+func (c *Client) RegisterTemp(p string, data []byte) (string, error) {
+	return c.CreateTemp(p, data, nil)
+}
+
+// CreateTemp fails by the error of OpRegisterTemp as RegisterTemp.
+func (c *Client) CreateTemp(p string, data []byte, acl []zk.ACL) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpRegisterTemp); err != nil {
+		return "", err
+	}
+	p = cleanPath(p)
+	if c.exist(p) {
+		return "", jerrors.Annotatef(zk.ErrNodeExists, "zk.Create(%s, ephemeral)", p)
+	}
+	if !c.exist(path.Dir(p)) {
+		return "", jerrors.Annotatef(zk.ErrNoNode, "zk.Create(%s, ephemeral)", p)
+	}
+	if !c.allowed(c.acls[path.Dir(p)], zk.PermCreate) {
+		return "", jerrors.Annotatef(zk.ErrNoAuth, "zk.Create(%s, ephemeral)", p)
+	}
+	c.create(p, data, aclOrWorld(acl))
+
+	return p, nil
+}
+
+// Multi runs the creations, deletions and version checks of @ops, all or
+// none of them. The versions are not checked, so a check passes if the
+// node exists.
+func (c *Client) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpMulti); err != nil {
+		return nil, err
+	}
+
+	// a dry run on a copy of the tree, to do none of the ops if one fails
+	nodes := make(map[string][]byte, len(c.nodes))
+	for p, data := range c.nodes {
+		nodes[p] = data
+	}
+	acls := make(map[string][]zk.ACL, len(c.acls))
+	for p, acl := range c.acls {
+		acls[p] = acl
+	}
+	rsp := make([]zk.MultiResponse, len(ops))
+	for i, op := range ops {
+		if err := c.multiOp(nodes, acls, op); err != nil {
+			rsp[i].Error = err
+			return rsp, jerrors.Annotatef(err, "zk.Multi(ops:%d)", len(ops))
+		}
+	}
+
+	for i, op := range ops {
+		switch req := op.(type) {
+		case *zk.CreateRequest:
+			p := cleanPath(req.Path)
+			c.create(p, req.Data, req.Acl)
+			rsp[i].String = p
+		case *zk.DeleteRequest:
+			c.delete(cleanPath(req.Path))
+		}
+	}
+
+	return rsp, nil
+}
+
+// multiOp does @op on @nodes of @acls, c must be locked.
+func (c *Client) multiOp(nodes map[string][]byte, acls map[string][]zk.ACL, op interface{}) error {
+	exist := func(p string) bool {
+		_, ok := nodes[p]
+		return ok || p == "/"
+	}
+
+	switch req := op.(type) {
+	case *zk.CreateRequest:
+		p := cleanPath(req.Path)
+		if exist(p) {
+			return zk.ErrNodeExists
+		}
+		if !exist(path.Dir(p)) {
+			return zk.ErrNoNode
+		}
+		if !c.allowed(acls[path.Dir(p)], zk.PermCreate) {
+			return zk.ErrNoAuth
+		}
+		nodes[p] = req.Data
+		acls[p] = req.Acl
+	case *zk.DeleteRequest:
+		p := cleanPath(req.Path)
+		if !exist(p) {
+			return zk.ErrNoNode
+		}
+		for node := range nodes {
+			if node != p && path.Dir(node) == p {
+				return zk.ErrNotEmpty
+			}
+		}
+		if !c.allowed(acls[path.Dir(p)], zk.PermDelete) {
+			return zk.ErrNoAuth
+		}
+		delete(nodes, p)
+		delete(acls, p)
+	case *zk.CheckVersionRequest:
+		if !exist(cleanPath(req.Path)) {
+			return zk.ErrNoNode
+		}
+	default:
+		return fmt.Errorf("unknown operation type %T", op)
+	}
+
+	return nil
+}
+
+func (c *Client) Get(p string) ([]byte, error) {
+	data, _, err := c.GetWithStat(p)
+	return data, err
+}
+
+// GetWithStat fails by the error of OpGet as Get.
+func (c *Client) GetWithStat(p string) ([]byte, *zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpGet); err != nil {
+		return nil, nil, err
+	}
+	p = cleanPath(p)
+	data := c.nodes[p]
+	if len(data) == 0 {
+		return nil, nil, jerrors.Errorf("path{%s} has none children", p)
+	}
+	stat := *c.stats[p]
+
+	return append([]byte(nil), data...), &stat, nil
+}
+
+func (c *Client) GetChildren(p string) ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpGetChildren); err != nil {
+		return nil, err
+	}
+	p = cleanPath(p)
+	children := c.children(p)
+	if len(children) == 0 {
+		return nil, jerrors.Errorf("path{%s} has none children", p)
+	}
+
+	return children, nil
+}
+
+func (c *Client) GetChildrenW(p string) ([]string, <-chan zk.Event, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpGetChildrenW); err != nil {
+		return nil, nil, err
+	}
+	p = cleanPath(p)
+	children := c.children(p)
+	if len(children) == 0 {
+		return nil, nil, jerrors.Errorf("path{%s} has none children", p)
+	}
+
+	return children, c.watch(c.childWatch, p), nil
+}
+
+func (c *Client) ExistW(p string) (<-chan zk.Event, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.check(OpExistW); err != nil {
+		return nil, err
+	}
+	p = cleanPath(p)
+	if !c.exist(p) {
+		return nil, jerrors.Errorf("zkClient App zk path{%s} does not exist.", p)
+	}
+
+	return c.watch(c.existWatch, p), nil
+}
+
+// Close expires the client, and its calls fail by zk.ErrClosing.
+func (c *Client) Close() {
+	c.SetState(zk.StateExpired)
+}
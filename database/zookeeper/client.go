@@ -51,7 +51,22 @@ func (c *Client) ZkConn() *zk.Conn {
 	return c.conn
 }
 
+// State returns the state of the zookeeper connection.
+func (c *Client) State() zk.State {
+	return c.conn.State()
+}
+
+// Close closes the zookeeper connection.
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
 func (c *Client) StateToString(state zk.State) string {
+	return StateToString(state)
+}
+
+// StateToString returns the description of a zookeeper state.
+func StateToString(state zk.State) string {
 	switch state {
 	case zk.StateDisconnected:
 		return "zookeeper disconnected"
@@ -84,28 +99,63 @@ func (c *Client) StateToString(state zk.State) string {
 
 // 节点须逐级创建
 func (c *Client) CreateZkPath(basePath string) error {
+	return c.EnsurePath(basePath, nil)
+}
+
+// EnsurePath creates the missing nodes of @zkPath level by level, whose ACL
+// is @acl, or zk.WorldACL(zk.PermAll) if it is empty. The existing nodes are
+// kept with their ACLs, and a node created concurrently by another client
+// is taken as existing.
+func (c *Client) EnsurePath(zkPath string, acl []zk.ACL) error {
 	var (
 		err     error
+		exist   bool
 		tmpPath string
 	)
 
-	if strings.HasSuffix(basePath, "/") {
-		basePath = strings.TrimSuffix(basePath, "/")
+	if strings.HasSuffix(zkPath, "/") {
+		zkPath = strings.TrimSuffix(zkPath, "/")
 	}
+	acl = aclOrWorld(acl)
 
-	for _, str := range strings.Split(basePath, "/")[1:] {
+	for _, str := range strings.Split(zkPath, "/")[1:] {
 		tmpPath = path.Join(tmpPath, "/", str)
-		_, err = c.conn.Create(tmpPath, []byte(""), 0, zk.WorldACL(zk.PermAll))
+		// the creation of an existing node needs the permission of its
+		// parent, which may be protected by an ACL
+		exist, _, err = c.conn.Exists(tmpPath)
 		if err != nil {
-			if err != zk.ErrNodeExists {
-				return jerrors.Annotatef(err, "zk.Create(path:%s)", tmpPath)
-			}
+			return jerrors.Annotatef(err, "zk.Exists(path:%s)", tmpPath)
+		}
+		if exist {
+			continue
 		}
+		_, err = c.conn.Create(tmpPath, []byte(""), 0, acl)
+		if err != nil && err != zk.ErrNodeExists {
+			return jerrors.Annotatef(err, "zk.Create(path:%s)", tmpPath)
+		}
+	}
+
+	return nil
+}
+
+// AddAuth authenticates the session by @auth of @scheme, e.g. "user:password"
+// of "digest", to write the nodes of a zk.DigestACL. It is kept by the
+// reconnections of the session.
+func (c *Client) AddAuth(scheme string, auth []byte) error {
+	if err := c.conn.AddAuth(scheme, auth); err != nil {
+		return jerrors.Annotatef(err, "zk.AddAuth(scheme:%s)", scheme)
 	}
 
 	return nil
 }
 
+func aclOrWorld(acl []zk.ACL) []zk.ACL {
+	if len(acl) == 0 {
+		return zk.WorldACL(zk.PermAll)
+	}
+	return acl
+}
+
 // 像创建一样，删除节点的时候也只能从叶子节点逐级回退删除
 // 当节点还有子节点的时候，删除是不会成功的
 func (c *Client) DeleteZkPath(path string) error {
@@ -121,7 +171,26 @@ func (c *Client) DeleteZkPath(path string) error {
 	return nil
 }
 
+// Multi runs @ops of *zk.CreateRequest, *zk.DeleteRequest,
+// *zk.SetDataRequest and *zk.CheckVersionRequest in a transaction, all or
+// none of them. The responses are of @ops in order, and the first one with
+// an error is of the op failing the transaction.
+func (c *Client) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	rsp, err := c.conn.Multi(ops...)
+	if err != nil {
+		return rsp, jerrors.Annotatef(err, "zk.Multi(ops:%d)", len(ops))
+	}
+
+	return rsp, nil
+}
+
 func (c *Client) RegisterTemp(path string, data []byte) (string, error) {
+	return c.CreateTemp(path, data, nil)
+}
+
+// CreateTemp creates the ephemeral node @path of @data, whose ACL is @acl,
+// or zk.WorldACL(zk.PermAll) if it is empty.
+func (c *Client) CreateTemp(path string, data []byte, acl []zk.ACL) (string, error) {
 	var (
 		err     error
 		tmpPath string
@@ -131,7 +200,7 @@ func (c *Client) RegisterTemp(path string, data []byte) (string, error) {
 		path = strings.TrimSuffix(path, "/")
 	}
 
-	tmpPath, err = c.conn.Create(path, data, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+	tmpPath, err = c.conn.Create(path, data, zk.FlagEphemeral, aclOrWorld(acl))
 	if err != nil {
 		return "", jerrors.Annotatef(err, "zk.Create(%s, ephemeral)", path)
 	}
@@ -175,6 +244,25 @@ func (c *Client) RegisterTempSeq(path string, data []byte) (string, error) {
 	return tmpPath, nil
 }
 
+// CreateTempSequential creates an ephemeral sequential node of @data under
+// the parent of @basePath, and the missing parents, whose ACL is @acl, or
+// zk.WorldACL(zk.PermAll) if it is empty. It returns the path of the node,
+// which is @basePath with the sequence number of zookeeper appended, e.g.
+// "/lock/lock-0000000007" of "/lock/lock-".
+func (c *Client) CreateTempSequential(basePath string, data []byte, acl []zk.ACL) (string, error) {
+	err := c.EnsurePath(path.Dir(basePath), acl)
+	if err != nil {
+		return "", jerrors.Trace(err)
+	}
+
+	tmpPath, err := c.conn.Create(basePath, data, zk.FlagEphemeral|zk.FlagSequence, aclOrWorld(acl))
+	if err != nil {
+		return "", jerrors.Annotatef(err, "zk.Create(%s, sequence | ephemeral)", basePath)
+	}
+
+	return tmpPath, nil
+}
+
 func (c *Client) GetChildrenW(path string) ([]string, <-chan zk.Event, error) {
 	var (
 		err      error
@@ -205,6 +293,13 @@ func (c *Client) GetChildrenW(path string) ([]string, <-chan zk.Event, error) {
 }
 
 func (c *Client) Get(path string) ([]byte, error) {
+	data, _, err := c.GetWithStat(path)
+	return data, err
+}
+
+// GetWithStat returns the data of the node @path and its stat, e.g. its
+// Mzxid and Version to order the updates of the node.
+func (c *Client) GetWithStat(path string) ([]byte, *zk.Stat, error) {
 	var (
 		err  error
 		data []byte
@@ -218,18 +313,18 @@ func (c *Client) Get(path string) ([]byte, error) {
 	data, stat, err = c.conn.Get(path)
 	if err != nil {
 		if err == zk.ErrNoNode {
-			return nil, jerrors.Errorf("path{%s} has none children", path)
+			return nil, nil, jerrors.Errorf("path{%s} has none children", path)
 		}
-		return nil, jerrors.Annotatef(err, "zk.Children(path:%s)", path)
+		return nil, nil, jerrors.Annotatef(err, "zk.Children(path:%s)", path)
 	}
 	if stat == nil {
-		return nil, jerrors.Errorf("path{%s} has none children", path)
+		return nil, nil, jerrors.Errorf("path{%s} has none children", path)
 	}
 	if len(data) == 0 {
-		return nil, jerrors.Errorf("path{%s} has none children", path)
+		return nil, nil, jerrors.Errorf("path{%s} has none children", path)
 	}
 
-	return data, nil
+	return data, stat, nil
 }
 
 func (c *Client) GetChildren(path string) ([]string, error) {
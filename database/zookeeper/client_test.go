@@ -44,7 +44,7 @@ func (suite *ClientTestSuite) TestClient_RegisterTempSeq() {
 	// time.Sleep(90e9)
 }
 
-func (suite *ClientTestSuite) TestClient_RegisterTempSeq() {
+func (suite *ClientTestSuite) TestClient_RegisterTempSeqPath() {
 	path := "/test"
 	err := suite.client.CreateZkPath(path)
 	suite.Equal(nil, err, "CreateZkPath")
@@ -145,6 +145,113 @@ func (suite *ClientTestSuite) TestClient_Lock() {
 	suite.Equal(nil, err)
 }
 
+func (suite *ClientTestSuite) TestClient_EnsurePath() {
+	path := "/test-ensure/a/b/c/d/"
+	err := suite.client.EnsurePath(path, nil)
+	suite.Equal(nil, err, "EnsurePath")
+	exist, err := suite.client.Exist(path)
+	suite.Equal(nil, err, "Exist")
+	suite.Equal(true, exist, "path:%s", path)
+
+	// idempotent
+	err = suite.client.EnsurePath(path, nil)
+	suite.Equal(nil, err, "EnsurePath again")
+
+	for _, p := range []string{"/test-ensure/a/b/c/d", "/test-ensure/a/b/c", "/test-ensure/a/b",
+		"/test-ensure/a", "/test-ensure"} {
+		suite.Equal(nil, suite.client.DeleteZkPath(p), "DeleteZkPath(%s)", p)
+	}
+}
+
+func (suite *ClientTestSuite) TestClient_EnsurePathConcurrent() {
+	var wg sync.WaitGroup
+
+	// the clients race on creating the same parents
+	path := "/test-ensure-race/a/b/c"
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, _, err := zk.Connect([]string{"127.0.0.1:2181"}, 3e9)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+			errs <- NewClient(conn).EnsurePath(path, nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Equal(nil, err, "EnsurePath")
+	}
+
+	for _, p := range []string{path, "/test-ensure-race/a/b", "/test-ensure-race/a", "/test-ensure-race"} {
+		suite.Equal(nil, suite.client.DeleteZkPath(p), "DeleteZkPath(%s)", p)
+	}
+}
+
+func (suite *ClientTestSuite) TestClient_CreateTempSequential() {
+	base := "/test-temp-seq/x/node-"
+	path0, err := suite.client.CreateTempSequential(base, []byte("v0"), nil)
+	suite.Equal(nil, err, "CreateTempSequential")
+	path1, err := suite.client.CreateTempSequential(base, []byte("v1"), nil)
+	suite.Equal(nil, err, "CreateTempSequential")
+	suite.Equal(true, strings.HasPrefix(path0, base), "path:%s", path0)
+	suite.NotEqual(path0, path1)
+
+	seq0, err := getSequenceNumber(path0, base)
+	suite.Equal(nil, err, "path:%s", path0)
+	seq1, err := getSequenceNumber(path1, base)
+	suite.Equal(nil, err, "path:%s", path1)
+	suite.Equal(true, seq0 < seq1, "seq0:%d, seq1:%d", seq0, seq1)
+
+	data, err := suite.client.Get(path1)
+	suite.Equal(nil, err, "Get")
+	suite.Equal("v1", string(data))
+
+	suite.client.DeleteZkPath(path0)
+	suite.client.DeleteZkPath(path1)
+	suite.client.DeleteZkPath("/test-temp-seq/x")
+	suite.client.DeleteZkPath("/test-temp-seq")
+}
+
+func (suite *ClientTestSuite) TestClient_DigestACL() {
+	err := suite.client.AddAuth("digest", []byte("user:password"))
+	suite.Equal(nil, err, "AddAuth")
+
+	// written by the user, read by all
+	acl := append(zk.DigestACL(zk.PermAll, "user", "password"), zk.WorldACL(zk.PermRead)...)
+	path := "/test-acl/service"
+	err = suite.client.EnsurePath(path, acl)
+	suite.Equal(nil, err, "EnsurePath")
+	node, err := suite.client.CreateTemp(path+"/node1", []byte("v0"), acl)
+	suite.Equal(nil, err, "CreateTemp")
+
+	conn, _, err := zk.Connect([]string{"127.0.0.1:2181"}, 3e9)
+	suite.Equal(nil, err, "zk.Connect")
+	defer conn.Close()
+	other := NewClient(conn)
+
+	data, err := other.Get(node)
+	suite.Equal(nil, err, "Get")
+	suite.Equal("v0", string(data))
+	err = other.EnsurePath(path, nil)
+	suite.Equal(nil, err, "EnsurePath of the existing path")
+	_, err = other.CreateTemp(path+"/node2", []byte("v1"), nil)
+	suite.Equal(zk.ErrNoAuth, jerrors.Cause(err), "CreateTemp")
+	err = other.DeleteZkPath(node)
+	suite.Equal(zk.ErrNoAuth, jerrors.Cause(err), "DeleteZkPath")
+	_, err = other.CreateTempSequential(path+"/seq-", nil, nil)
+	suite.Equal(zk.ErrNoAuth, jerrors.Cause(err), "CreateTempSequential")
+
+	suite.Equal(nil, suite.client.DeleteZkPath(node))
+	suite.Equal(nil, suite.client.DeleteZkPath(path))
+	suite.Equal(nil, suite.client.DeleteZkPath("/test-acl"))
+}
+
 func TestClientTestSuite(t *testing.T) {
 	suite.Run(t, new(ClientTestSuite))
 }
@@ -0,0 +1,118 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// caller.go provides the callers of the logs
+package gxlog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// callerCache maps a pc to its "pkg/file.go:line"
+var callerCache sync.Map
+
+// Caller returns the caller of the function calling Caller like
+// "pkg/file.go:123", the file with its dir only. @skip is the stack frames
+// to skip, 0 for the caller itself. The frames are cached by their pcs.
+func Caller(skip int) string {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return "???:0"
+	}
+
+	return callerOf(pcs[0])
+}
+
+func callerOf(pc uintptr) string {
+	if s, ok := callerCache.Load(pc); ok {
+		return s.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := frame.File
+	if file == "" {
+		file = "???"
+	}
+	// the dir of the file is its package in most cases
+	if i := strings.LastIndexByte(file, '/'); i > 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	s := file + ":" + strconv.Itoa(frame.Line)
+	callerCache.Store(pc, s)
+
+	return s
+}
+
+type callerLogger struct {
+	l    Logger
+	skip int
+}
+
+// WithCaller returns a logger prefixing the logs with their callers like
+// "[pkg/file.go:123] ". @skip is the frames to skip above the caller of the
+// logger, as many as the wrappers of the logger between them.
+func WithCaller(l Logger, skip int) Logger {
+	return &callerLogger{l: l, skip: skip}
+}
+
+// prefix returns the caller of the method of c calling prefix, as the
+// first arg of the format returned.
+func (c *callerLogger) prefix(format string, args []interface{}) (string, []interface{}) {
+	a := make([]interface{}, 0, len(args)+1)
+	a = append(a, Caller(c.skip+2))
+
+	return "[%s] " + format, append(a, args...)
+}
+
+func (c *callerLogger) Debugf(format string, args ...interface{}) {
+	format, args = c.prefix(format, args)
+	c.l.Debugf(format, args...)
+}
+
+func (c *callerLogger) Infof(format string, args ...interface{}) {
+	format, args = c.prefix(format, args)
+	c.l.Infof(format, args...)
+}
+
+func (c *callerLogger) Warnf(format string, args ...interface{}) {
+	format, args = c.prefix(format, args)
+	c.l.Warnf(format, args...)
+}
+
+func (c *callerLogger) Errorf(format string, args ...interface{}) {
+	format, args = c.prefix(format, args)
+	c.l.Errorf(format, args...)
+}
+
+// prefixw returns @msg prefixed by the caller of the method of c calling
+// prefixw.
+func (c *callerLogger) prefixw(msg string) string {
+	return "[" + Caller(c.skip+2) + "] " + msg
+}
+
+func (c *callerLogger) Debugw(msg string, kv ...interface{}) {
+	c.l.Debugw(c.prefixw(msg), kv...)
+}
+
+func (c *callerLogger) Infow(msg string, kv ...interface{}) {
+	c.l.Infow(c.prefixw(msg), kv...)
+}
+
+func (c *callerLogger) Warnw(msg string, kv ...interface{}) {
+	c.l.Warnw(c.prefixw(msg), kv...)
+}
+
+func (c *callerLogger) Errorw(msg string, kv ...interface{}) {
+	c.l.Errorw(c.prefixw(msg), kv...)
+}
+
+func (c *callerLogger) With(kv ...interface{}) Logger {
+	return &callerLogger{l: c.l.With(kv...), skip: c.skip}
+}
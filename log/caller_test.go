@@ -0,0 +1,67 @@
+package gxlog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaller(t *testing.T) {
+	if s := Caller(0); s != "log/caller_test.go:9" {
+		t.Fatalf("Caller(0) = %s", s)
+	}
+	wrapper := func() string {
+		return Caller(1)
+	}
+	if s := wrapper(); s != "log/caller_test.go:15" {
+		t.Fatalf("Caller(1) in a wrapper = %s", s)
+	}
+	// cached
+	for i := 0; i < 2; i++ {
+		if s := Caller(0); s != "log/caller_test.go:20" {
+			t.Fatalf("Caller(0) = %s", s)
+		}
+	}
+	if s := Caller(1 << 20); s != "???:0" {
+		t.Fatalf("Caller(too many) = %s", s)
+	}
+}
+
+// warn is a wrapper of a logger, the skip of WithCaller is 1 for it.
+func warn(l Logger, msg string) {
+	l.Warnf("%s", msg)
+}
+
+func TestWithCaller(t *testing.T) {
+	var r logRecorder
+	l := WithCaller(r.logger(), 0)
+
+	l.Infof("a %d", 1)
+	l.With("k", "v").Errorf("b")
+	l.Infof("c %s", Lazy(info{name: "lazy"}))
+	warn(WithCaller(r.logger(), 1), "d")
+
+	logs := r.get()
+	want := []string{
+		`INFO [log/caller_test.go:38] a 1`,
+		`ERROR [log/caller_test.go:39] b {"k":"v"}`,
+		`INFO [log/caller_test.go:40] c gxlog.info{`,
+		`WARN [log/caller_test.go:41] d`,
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("logs %q", logs)
+	}
+	for i := range want {
+		if !strings.HasPrefix(logs[i], want[i]) {
+			t.Fatalf("log %q, want %q", logs[i], want[i])
+		}
+	}
+}
+
+// go test -run '^$' -bench 'Caller' -benchmem ./log/
+// BenchmarkCaller 	10273734	       130.9 ns/op	       0 B/op	       0 allocs/op
+func BenchmarkCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Caller(0)
+	}
+}
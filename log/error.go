@@ -0,0 +1,131 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// error.go provides the structured logs of errors
+package gxlog
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+const (
+	// the errors of a chain and the lines of a stack in ErrorInfo
+	maxErrorChain = 16
+	maxErrorStack = 32
+)
+
+// ErrorInfo is the structured rendering of an error, see ErrorField.
+type ErrorInfo struct {
+	Error string   `json:"error"`
+	Chain []string `json:"chain"` // of the wrapped errors, the outermost first
+	Cause string   `json:"cause"` // the innermost error
+	Stack []string `json:"stack"` // where the error is created or wrapped
+	// the errors of the chain and the lines of the stack not rendered
+	Truncated int `json:"truncated"`
+}
+
+// unwrap returns the error wrapped by @err, by Unwrap of the errors package
+// or Cause of juju/errors and pkg/errors. The first one is returned of the
+// errors joined.
+func unwrap(err error) error {
+	var next error
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		next = e.Unwrap()
+	case interface{ Unwrap() []error }:
+		if errs := e.Unwrap(); len(errs) > 0 {
+			next = errs[0]
+		}
+	case interface{ Cause() error }:
+		next = e.Cause()
+	}
+	if next == nil {
+		return nil
+	}
+	// a Cause returning itself
+	if t := reflect.TypeOf(next); t == reflect.TypeOf(err) && t.Comparable() && next == err {
+		return nil
+	}
+
+	return next
+}
+
+// stackOf returns the stack of @err if it has a StackTrace method, like
+// *juju/errors.Err of []string or pkg/errors of errors.StackTrace.
+func stackOf(err error) []string {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 ||
+		m.Type().Out(0).Kind() != reflect.Slice {
+		return nil
+	}
+
+	var (
+		stack  []string
+		frames = m.Call(nil)[0]
+	)
+	for i := 0; i < frames.Len(); i++ {
+		var line string
+		switch f := frames.Index(i).Interface().(type) {
+		case string:
+			line = f
+		case encoding.TextMarshaler:
+			b, _ := f.MarshalText()
+			line = string(b)
+		default:
+			line = fmt.Sprint(f)
+		}
+		stack = append(stack, line)
+	}
+
+	return stack
+}
+
+// ErrorField returns the structured rendering of @err, to be a field of
+// Logger.With: its message chain, its innermost cause, and the first stack
+// found in the chain. The chain and the stack are bounded.
+func ErrorField(err error) ErrorInfo {
+	if err == nil {
+		return ErrorInfo{}
+	}
+
+	info := ErrorInfo{Error: err.Error()}
+	for e := err; e != nil; e = unwrap(e) {
+		if len(info.Chain) == maxErrorChain {
+			// the cause is not known, as a cycle is not
+			for ; e != nil && info.Truncated < maxErrorChain; e = unwrap(e) {
+				info.Truncated++
+			}
+			break
+		}
+		info.Chain = append(info.Chain, e.Error())
+		info.Cause = e.Error()
+		if info.Stack == nil {
+			info.Stack = stackOf(e)
+		}
+	}
+	if len(info.Stack) > maxErrorStack {
+		info.Truncated += len(info.Stack) - maxErrorStack
+		info.Stack = info.Stack[:maxErrorStack]
+	}
+
+	return info
+}
+
+// LogError logs @msg of @err at the error level with the fields @kv, and
+// the field "error" of ErrorField(@err). Nothing is logged if @err is nil.
+// @msg should be constant, the variables should be the fields, to sample
+// the logs by their messages.
+func LogError(l Logger, msg string, err error, kv ...interface{}) {
+	if err == nil {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(kv)+2)
+	fields = append(fields, kv...)
+	fields = append(fields, "error", ErrorField(err))
+	l.Errorw(msg, fields...)
+}
@@ -0,0 +1,117 @@
+package gxlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+import (
+	jerrors "github.com/juju/errors"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestErrorField(t *testing.T) {
+	if info := ErrorField(nil); info.Error != "" || info.Chain != nil {
+		t.Fatalf("ErrorField(nil) = %+v", info)
+	}
+
+	root := errors.New("no node")
+	err := fmt.Errorf("get /a: %w", root)
+	info := ErrorField(err)
+	if info.Error != "get /a: no node" || info.Cause != "no node" || len(info.Chain) != 2 || info.Stack != nil {
+		t.Fatalf("ErrorField() = %+v", info)
+	}
+
+	// juju/errors
+	err = jerrors.Annotate(jerrors.Trace(root), "register")
+	info = ErrorField(err)
+	if info.Cause != "no node" || len(info.Stack) != 3 || !strings.Contains(info.Stack[2], "TestErrorField") {
+		t.Fatalf("ErrorField() of juju = %+v", info)
+	}
+
+	// pkg/errors
+	err = pkgerrors.Wrap(root, "watch")
+	info = ErrorField(err)
+	if info.Cause != "no node" || len(info.Stack) == 0 || !strings.Contains(info.Stack[0], "TestErrorField") {
+		t.Fatalf("ErrorField() of pkg/errors = %+v", info)
+	}
+
+	// joined
+	info = ErrorField(joinError{root, errors.New("other")})
+	if info.Cause != "no node" {
+		t.Fatalf("ErrorField() of errors joined = %+v", info)
+	}
+}
+
+// joinError is the errors joined by errors.Join of go1.20.
+type joinError []error
+
+func (e joinError) Error() string {
+	return fmt.Sprint([]error(e))
+}
+
+func (e joinError) Unwrap() []error {
+	return e
+}
+
+// loopError wraps itself forever.
+type loopError struct {
+	n int
+}
+
+func (e *loopError) Error() string {
+	return fmt.Sprint("loop ", e.n)
+}
+
+func (e *loopError) Unwrap() error {
+	return &loopError{n: e.n + 1}
+}
+
+func TestErrorFieldBounded(t *testing.T) {
+	info := ErrorField(&loopError{})
+	if len(info.Chain) != maxErrorChain || info.Truncated != maxErrorChain {
+		t.Fatalf("ErrorField() of an endless chain = %+v", info)
+	}
+
+	err := errors.New("deep")
+	for i := 0; i < 100; i++ {
+		err = jerrors.Trace(err)
+	}
+	info = ErrorField(err)
+	if len(info.Stack) != maxErrorStack || len(info.Chain) != maxErrorChain {
+		t.Fatalf("ErrorField() of a deep chain: %d stack lines, %d errors", len(info.Stack), len(info.Chain))
+	}
+}
+
+func TestLogError(t *testing.T) {
+	ring := NewRingLogger(10)
+	LogError(ring, "never", nil)
+	if n := len(ring.Records()); n != 0 {
+		t.Fatalf("LogError(nil) logged %d records", n)
+	}
+
+	LogError(ring, "zk 100% down", jerrors.Trace(errors.New("lost")), "path", "/a")
+	records := ring.Records()
+	if len(records) != 1 {
+		t.Fatalf("%d records", len(records))
+	}
+	r := records[0]
+	if r.Level != LevelError || r.Message != "zk 100% down" || len(r.Fields) != 4 || r.Fields[2] != "error" {
+		t.Fatalf("record %+v", r)
+	}
+	if s := KV(r.Fields...); !strings.HasPrefix(s, `{"path":"/a","error":{"error":"lost","chain":["lost","lost"],"cause":"lost","stack":["`) {
+		t.Fatalf("fields %s", s)
+	}
+
+	// sampled by the message, not the fields
+	var rec logRecorder
+	l := NewSampler(rec.logger(), SamplerConfig{First: 1})
+	for i := 0; i < 3; i++ {
+		LogError(l, "failed", errors.New("x"), "i", i)
+	}
+	if logs := rec.get(); len(logs) != 1 {
+		t.Fatalf("logs %q", logs)
+	}
+}
@@ -0,0 +1,455 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// json.go provides compact json format string
+package gxlog
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+import (
+	"github.com/AlexStocks/goext/strings"
+)
+
+// badKey is the key of the last value of the odd args of KV
+const badKey = "!BADKEY"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type jsonPrinter struct {
+	prettyOptions
+	buf      strings.Builder
+	visiting visitSet
+}
+
+func newJSONPrinter(opts []Option) *jsonPrinter {
+	return &jsonPrinter{
+		prettyOptions: newPrettyOptions(opts),
+		visiting:      make(visitSet),
+	}
+}
+
+// JSONString returns @i in compact json, with the options and the redaction
+// of PrettyStringWithOptions. It never fails, and it is deterministic as the
+// map keys are sorted:
+//
+//   - NaN and Inf are the strings "NaN", "+Inf" and "-Inf";
+//   - time.Time is a RFC3339 string with nanoseconds;
+//   - []byte is a string if it is printable utf8, or base64 like encoding/json;
+//   - the exported struct fields are named by their json tags if any;
+//   - the values of the string map keys like the redacted field names are
+//     masked too, e.g. the "token" of a map[string]string;
+//   - json.Marshaler, encoding.TextMarshaler, error and fmt.Stringer are
+//     printed by their methods, and by fmt if the methods fail or panic;
+//   - the cycles, the values deeper than the max depth and the others
+//     like chan and func are strings too.
+func JSONString(i interface{}, opts ...Option) string {
+	p := newJSONPrinter(opts)
+	p.print(reflect.ValueOf(i), 0)
+
+	return p.buf.String()
+}
+
+// KV returns a json object of the alternating keys and values of @pairs,
+// in their order. The keys not of string are printed by fmt, and the last
+// value of the odd @pairs is keyed by "!BADKEY".
+func KV(pairs ...interface{}) string {
+	p := newJSONPrinter(nil)
+	p.buf.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			p.buf.WriteByte(',')
+		}
+		if i+1 == len(pairs) {
+			p.printString(badKey)
+			p.buf.WriteByte(':')
+			p.print(reflect.ValueOf(pairs[i]), 1)
+			break
+		}
+		key, ok := pairs[i].(string)
+		if !ok {
+			key = fmt.Sprint(pairs[i])
+		}
+		p.printString(key)
+		p.buf.WriteByte(':')
+		p.print(reflect.ValueOf(pairs[i+1]), 1)
+	}
+	p.buf.WriteByte('}')
+
+	return p.buf.String()
+}
+
+// printString prints @s as a json string, the invalid utf8 is replaced by
+// U+FFFD.
+func (p *jsonPrinter) printString(s string) {
+	more := 0
+	if p.maxStringLen > 0 && len(s) > p.maxStringLen {
+		t := gxstrings.TruncateBytes(s, p.maxStringLen, "")
+		more, s = len(s)-len(t), t
+	}
+
+	const hex = "0123456789abcdef"
+	p.buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				p.buf.WriteByte('\\')
+				p.buf.WriteByte(c)
+			case c == '\n':
+				p.buf.WriteString(`\n`)
+			case c == '\r':
+				p.buf.WriteString(`\r`)
+			case c == '\t':
+				p.buf.WriteString(`\t`)
+			case c < 0x20:
+				p.buf.WriteString(`\u00`)
+				p.buf.WriteByte(hex[c>>4])
+				p.buf.WriteByte(hex[c&0xf])
+			default:
+				p.buf.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		p.buf.WriteRune(r)
+		i += size
+	}
+	if more > 0 {
+		fmt.Fprintf(&p.buf, "...(%d more bytes)", more)
+	}
+	p.buf.WriteByte('"')
+}
+
+// isText returns whether @b is printable utf8, with spaces.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *jsonPrinter) printBytes(b []byte) {
+	if isText(b) {
+		p.printString(string(b))
+		return
+	}
+
+	// truncated before the encoding, to keep it decodable
+	more := 0
+	if p.maxStringLen > 0 && len(b) > p.maxStringLen {
+		more, b = len(b)-p.maxStringLen, b[:p.maxStringLen]
+	}
+	p.buf.WriteByte('"')
+	p.buf.WriteString(base64.StdEncoding.EncodeToString(b))
+	if more > 0 {
+		fmt.Fprintf(&p.buf, "...(%d more bytes)", more)
+	}
+	p.buf.WriteByte('"')
+}
+
+func (p *jsonPrinter) printFloat(f float64, bits int) {
+	switch {
+	case math.IsNaN(f):
+		p.buf.WriteString(`"NaN"`)
+	case math.IsInf(f, 1):
+		p.buf.WriteString(`"+Inf"`)
+	case math.IsInf(f, -1):
+		p.buf.WriteString(`"-Inf"`)
+	default:
+		p.buf.WriteString(strconv.FormatFloat(f, 'g', -1, bits))
+	}
+}
+
+// printMethod prints @v by its MarshalJSON, MarshalText, Error or String
+// method, and returns whether it has one.
+func (p *jsonPrinter) printMethod(v reflect.Value) bool {
+	if !v.CanInterface() || v.Kind() == reflect.Interface {
+		return false
+	}
+	t := v.Type()
+	if !t.Implements(jsonMarshalerType) && !t.Implements(textMarshalerType) &&
+		!t.Implements(errorType) && !t.Implements(stringerType) {
+		return false
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false
+	}
+	if hasRedaction(t) {
+		return false
+	}
+
+	var (
+		raw []byte
+		s   string
+	)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				raw, s = nil, fmt.Sprintf("<%s panic: %v>", t, r)
+			}
+		}()
+		switch x := v.Interface().(type) {
+		case json.Marshaler:
+			b, err := x.MarshalJSON()
+			if err == nil && json.Valid(b) {
+				raw = b
+			} else {
+				s = fmt.Sprintf("%+v", x)
+			}
+		case encoding.TextMarshaler:
+			b, err := x.MarshalText()
+			if err == nil {
+				s = string(b)
+			} else {
+				s = fmt.Sprintf("%+v", x)
+			}
+		case error:
+			s = x.Error()
+		case fmt.Stringer:
+			s = x.String()
+		}
+	}()
+	if raw != nil {
+		var c bytes.Buffer
+		json.Compact(&c, raw) // json.Valid has checked it
+		p.buf.Write(c.Bytes())
+		return true
+	}
+	p.printString(s)
+
+	return true
+}
+
+// printMasked prints @v of a redacted field, see prettyPrinter.printMasked.
+func (p *jsonPrinter) printMasked(v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			p.buf.WriteString("null")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		p.printString(gxstrings.Mask(v.String(), 0, 0, '*'))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		p.printString(gxstrings.Mask(string(v.Bytes()), 0, 0, '*'))
+	default:
+		p.printString("<masked>")
+	}
+}
+
+// fieldName returns the json name of @f, and whether it is printed.
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		tag = f.Name
+	}
+
+	return tag, true
+}
+
+func (p *jsonPrinter) print(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		p.buf.WriteString("null")
+		return
+	}
+	t := v.Type()
+	if t == timeType && v.CanInterface() {
+		p.printString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+		return
+	}
+	if p.printMethod(v) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		p.buf.WriteString(strconv.FormatBool(v.Bool()))
+		return
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+		return
+	case reflect.Float32, reflect.Float64:
+		p.printFloat(v.Float(), t.Bits())
+		return
+	case reflect.Complex64, reflect.Complex128:
+		p.printString(fmt.Sprint(v.Complex()))
+		return
+	case reflect.String:
+		p.printString(v.String())
+		return
+	case reflect.Interface:
+		p.print(v.Elem(), depth)
+		return
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			p.buf.WriteString("null")
+		} else {
+			p.printString(fmt.Sprintf("(%s)(%#x)", t, v.Pointer()))
+		}
+		return
+	}
+
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		p.buf.WriteString("null")
+		return
+	}
+	if p.maxDepth > 0 && depth >= p.maxDepth {
+		p.printString("<max depth>")
+		return
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+		key, ok := p.visiting.enter(v)
+		if !ok {
+			p.printString("<cycle>")
+			return
+		}
+		defer delete(p.visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		p.print(v.Elem(), depth)
+
+	case reflect.Struct:
+		p.buf.WriteByte('{')
+		fields := 0
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := fieldName(f)
+			redact := redaction(f)
+			if !ok || redact == redactOmit {
+				continue
+			}
+			if fields > 0 {
+				p.buf.WriteByte(',')
+			}
+			fields++
+			p.printString(name)
+			p.buf.WriteByte(':')
+			if redact == redactMask {
+				p.printMasked(v.Field(i))
+			} else {
+				p.print(v.Field(i), depth+1)
+			}
+		}
+		p.buf.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			p.printBytes(v.Bytes())
+			return
+		}
+
+		n := v.Len()
+		if p.maxSliceLen > 0 && n > p.maxSliceLen {
+			n = p.maxSliceLen
+		}
+		p.buf.WriteByte('[')
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.print(v.Index(i), depth+1)
+		}
+		if more := v.Len() - n; more > 0 {
+			p.buf.WriteByte(',')
+			p.printString(fmt.Sprintf("...(%d more)", more))
+		}
+		p.buf.WriteByte(']')
+
+	case reflect.Map:
+		type entry struct {
+			key    string
+			val    reflect.Value
+			masked bool
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+			var key string
+			if k.Kind() == reflect.String {
+				key = k.String()
+			} else {
+				key = fmt.Sprint(k)
+			}
+			entries = append(entries, entry{
+				key:    key,
+				val:    iter.Value(),
+				masked: k.Kind() == reflect.String && redactedName(key),
+			})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+
+		n := len(entries)
+		if p.maxSliceLen > 0 && n > p.maxSliceLen {
+			n = p.maxSliceLen
+		}
+		p.buf.WriteByte('{')
+		for i, e := range entries[:n] {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.printString(e.key)
+			p.buf.WriteByte(':')
+			if e.masked {
+				p.printMasked(e.val)
+			} else {
+				p.print(e.val, depth+1)
+			}
+		}
+		if more := len(entries) - n; more > 0 {
+			if n > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.printString("...")
+			p.buf.WriteByte(':')
+			p.printString(fmt.Sprintf("%d more", more))
+		}
+		p.buf.WriteByte('}')
+	}
+}
@@ -0,0 +1,110 @@
+package gxlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jsonNode struct {
+	Name     string            `json:"name"`
+	Weight   float64           `json:"weight,omitempty"`
+	Attrs    map[string]string `json:"attrs"`
+	Password string
+	Ignored  int `json:"-"`
+	Next     *jsonNode
+	hidden   int
+}
+
+func checkJSON(t *testing.T, s, want string) {
+	if !json.Valid([]byte(s)) {
+		t.Fatalf("invalid json %s", s)
+	}
+	if s != want {
+		t.Fatalf("json %s, want %s", s, want)
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	n := &jsonNode{
+		Name:     "a\"b\n",
+		Weight:   1.5,
+		Attrs:    map[string]string{"z": "1", "a": "2", "Token": "t0ken"},
+		Password: "pa55",
+		Ignored:  1,
+		hidden:   1,
+	}
+	n.Next = n
+	checkJSON(t, JSONString(n),
+		`{"name":"a\"b\n","weight":1.5,"attrs":{"Token":"*****","a":"2","z":"1"},"Password":"****","Next":"<cycle>"}`)
+
+	checkJSON(t, JSONString(nil), `null`)
+	checkJSON(t, JSONString([]interface{}{1, "x", nil, true, fmt.Errorf("failed"), 2 * time.Second}),
+		`[1,"x",null,true,"failed","2s"]`)
+	checkJSON(t, JSONString(map[int]int{10: 1, 2: 2}), `{"10":1,"2":2}`)
+	checkJSON(t, JSONString(json.RawMessage("{ \"a\" : [1, 2] }")), `{"a":[1,2]}`)
+}
+
+func TestJSONStringFloat(t *testing.T) {
+	checkJSON(t, JSONString([]float64{math.NaN(), math.Inf(1), math.Inf(-1), 0.1, 1e21}),
+		`["NaN","+Inf","-Inf",0.1,1e+21]`)
+	checkJSON(t, JSONString(float32(0.1)), `0.1`)
+}
+
+func TestJSONStringBytes(t *testing.T) {
+	checkJSON(t, JSONString([]byte("hello\tworld")), `"hello\tworld"`)
+	// binary, base64 as encoding/json does
+	checkJSON(t, JSONString([]byte{0, 1, 0xff}), `"AAH/"`)
+	checkJSON(t, JSONString([]byte("\x00\x01")), `"AAE="`)
+	checkJSON(t, JSONString(string([]byte{'a', 0xff, 1})), `"a`+"�"+`\u0001"`)
+}
+
+func TestJSONStringTime(t *testing.T) {
+	tm := time.Date(2018, 10, 18, 8, 30, 0, 123456789, time.FixedZone("CST", 8*3600))
+	checkJSON(t, JSONString(tm), `"2018-10-18T08:30:00.123456789+08:00"`)
+	checkJSON(t, JSONString(struct{ At *time.Time }{&tm}), `{"At":"2018-10-18T08:30:00.123456789+08:00"}`)
+	checkJSON(t, JSONString(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)), `"2018-01-02T03:04:05Z"`)
+}
+
+func TestJSONStringLimits(t *testing.T) {
+	checkJSON(t, JSONString([]int{1, 2, 3}, WithMaxSliceLen(2)), `[1,2,"...(1 more)"]`)
+	checkJSON(t, JSONString(map[string]int{"a": 1, "b": 2, "c": 3}, WithMaxSliceLen(1)), `{"a":1,"...":"2 more"}`)
+	checkJSON(t, JSONString("abcdef", WithMaxStringLen(3)), `"abc...(3 more bytes)"`)
+	checkJSON(t, JSONString([][]int{{1}}, WithMaxDepth(1)), `["<max depth>"]`)
+	if s := JSONString(make(chan int)); !strings.HasPrefix(s, `"(chan int)(0x`) {
+		t.Fatalf("JSONString(chan) = %s", s)
+	}
+}
+
+type badMarshaler struct{}
+
+func (badMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte("{bad"), nil
+}
+
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	panic("boom")
+}
+
+func TestJSONStringNeverFails(t *testing.T) {
+	s := JSONString([]interface{}{badMarshaler{}, panicStringer{}, complex(1, 2), func() {}})
+	if !json.Valid([]byte(s)) {
+		t.Fatalf("invalid json %s", s)
+	}
+	if !strings.Contains(s, `"{}"`) || !strings.Contains(s, "panic: boom") || !strings.Contains(s, `"(1+2i)"`) {
+		t.Fatalf("JSONString() = %s", s)
+	}
+}
+
+func TestKV(t *testing.T) {
+	checkJSON(t, KV(), `{}`)
+	checkJSON(t, KV("path", "/a", "n", 3, 7, []string{"x"}), `{"path":"/a","n":3,"7":["x"]}`)
+	checkJSON(t, KV("path", "/a", "dangling"), `{"path":"/a","!BADKEY":"dangling"}`)
+	checkJSON(t, KV("cred", credential{User: "u", Password: "p"}),
+		`{"cred":{"User":"u","Password":"*","Key":"","Extra":null}}`)
+}
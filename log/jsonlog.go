@@ -0,0 +1,98 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// jsonlog.go provides a logger writing json lines
+package gxlog
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// jsonOutput is the writer of a json logger and its loggers of With.
+type jsonOutput struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min Level
+}
+
+type jsonLogger struct {
+	out *jsonOutput
+	kv  []interface{}
+}
+
+// NewJSONLogger returns a logger writing the logs of @min and the higher
+// levels to @w, a json object a line like
+//
+//	{"time":"2018-10-18T10:00:00.123+08:00","level":"WARN","msg":"zk lost","path":"/goext"}
+//
+// The fields of With and of the methods like Warnw follow "msg" in their
+// order, see KV. The lines are written by single Write calls.
+func NewJSONLogger(w io.Writer, min Level) Logger {
+	return &jsonLogger{out: &jsonOutput{w: w, min: min}}
+}
+
+func (l *jsonLogger) log(level Level, msg string, kv []interface{}) {
+	if level < l.out.min {
+		return
+	}
+
+	pairs := make([]interface{}, 0, 6+len(l.kv)+len(kv))
+	pairs = append(pairs, "time", time.Now(), "level", level.String(), "msg", msg)
+	pairs = append(append(pairs, l.kv...), kv...)
+	line := KV(pairs...) + "\n"
+
+	l.out.mu.Lock()
+	io.WriteString(l.out.w, line)
+	l.out.mu.Unlock()
+}
+
+func (l *jsonLogger) logf(level Level, format string, args []interface{}) {
+	if level >= l.out.min {
+		l.log(level, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (l *jsonLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args)
+}
+
+func (l *jsonLogger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args)
+}
+
+func (l *jsonLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args)
+}
+
+func (l *jsonLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args)
+}
+
+func (l *jsonLogger) Debugw(msg string, kv ...interface{}) {
+	l.log(LevelDebug, msg, kv)
+}
+
+func (l *jsonLogger) Infow(msg string, kv ...interface{}) {
+	l.log(LevelInfo, msg, kv)
+}
+
+func (l *jsonLogger) Warnw(msg string, kv ...interface{}) {
+	l.log(LevelWarn, msg, kv)
+}
+
+func (l *jsonLogger) Errorw(msg string, kv ...interface{}) {
+	l.log(LevelError, msg, kv)
+}
+
+func (l *jsonLogger) With(kv ...interface{}) Logger {
+	if len(kv) == 0 {
+		return l
+	}
+
+	return &jsonLogger{out: l.out, kv: append(l.kv[:len(l.kv):len(l.kv)], kv...)}
+}
@@ -0,0 +1,67 @@
+package gxlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSONLogger(&buf, LevelInfo)
+
+	l.Debugw("dropped", "k", 1)
+	l.With("root", "/goext").Warnw("zk lost", "retry", 3, "addr", "127.0.0.1:2181")
+	l.Infof("%d%% done", 50)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines %q", lines)
+	}
+	// the fields are in order
+	if !strings.HasSuffix(lines[0], `"level":"WARN","msg":"zk lost","root":"/goext","retry":3,"addr":"127.0.0.1:2181"}`) {
+		t.Fatalf("line %s", lines[0])
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = error %v", lines[0], err)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, m["time"].(string))
+	if err != nil || time.Since(ts) > time.Minute {
+		t.Fatalf("time %v, error %v", m["time"], err)
+	}
+	if m["retry"] != float64(3) || m["root"] != "/goext" {
+		t.Fatalf("fields %v", m)
+	}
+
+	m = nil
+	if err = json.Unmarshal([]byte(lines[1]), &m); err != nil || m["msg"] != "50% done" || m["level"] != "INFO" {
+		t.Fatalf("line %s, error %v", lines[1], err)
+	}
+}
+
+func TestLoggerW(t *testing.T) {
+	var r logRecorder
+	l := r.logger().With("a", 1)
+
+	l.Warnw("100% lost", "b", "x")
+	l.Infow("no fields")
+	l.Errorw("odd", "c")
+	want := []string{
+		`WARN 100% lost {"a":1,"b":"x"}`,
+		`INFO no fields {"a":1}`,
+		`ERROR odd {"a":1,"!BADKEY":"c"}`,
+	}
+	if logs := r.get(); strings.Join(logs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("logs %q, want %q", logs, want)
+	}
+
+	ring := NewRingLogger(4)
+	ring.With("a", 1).Debugw("m", "b", 2)
+	if rs := ring.Records(); len(rs) != 1 || rs[0].Message != "m" || KV(rs[0].Fields...) != `{"a":1,"b":2}` {
+		t.Fatalf("records %v", rs)
+	}
+}
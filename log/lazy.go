@@ -0,0 +1,48 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// lazy.go defers formatting until a log is written
+package gxlog
+
+import (
+	"fmt"
+	"sync"
+)
+
+type lazyString struct {
+	once sync.Once
+	v    interface{}
+	f    func() string
+	s    string
+}
+
+// String renders the value at the first call, and returns the result cached
+// at the following ones.
+func (l *lazyString) String() string {
+	l.once.Do(func() {
+		if l.f != nil {
+			l.s = l.f()
+		} else {
+			l.s = PrettyString(l.v)
+		}
+		l.v, l.f = nil, nil
+	})
+
+	return l.s
+}
+
+// Lazy returns a fmt.Stringer of PrettyString(@v), which is rendered only
+// if its String method is called, like when a logger formats it with "%s"
+// or "%v", and not if the log level is disabled. @v is rendered when it is
+// formatted, so it should not be changed before.
+func Lazy(v interface{}) fmt.Stringer {
+	return &lazyString{v: v}
+}
+
+// LazyFunc returns a fmt.Stringer of the result of @f, which is called only
+// once, and only if its String method is called.
+func LazyFunc(f func() string) fmt.Stringer {
+	return &lazyString{f: f}
+}
@@ -0,0 +1,95 @@
+package gxlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	i := info{name: "hello", age: 23.5}
+	l := Lazy(i)
+	if s := fmt.Sprintf("%s", l); s != PrettyString(i) {
+		t.Fatalf("Lazy() = %s, want %s", s, PrettyString(i))
+	}
+	if s := fmt.Sprint(l); s != PrettyString(i) {
+		t.Fatalf("Lazy() = %s, want %s", s, PrettyString(i))
+	}
+}
+
+func TestLazyFunc(t *testing.T) {
+	calls := 0
+	l := LazyFunc(func() string {
+		calls++
+		return "rendered"
+	})
+	if calls != 0 {
+		t.Fatalf("LazyFunc() calls @f before String()")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s := l.String(); s != "rendered" {
+				t.Errorf("String() = %q", s)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("@f called %d times", calls)
+	}
+}
+
+// debugf formats its arguments only if the debug level is enabled, as a
+// logger does.
+func debugf(enabled bool, format string, args ...interface{}) string {
+	if !enabled {
+		return ""
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func benchService() interface{} {
+	nodes := make([]map[string]string, 16)
+	for i := range nodes {
+		nodes[i] = map[string]string{
+			"id":      fmt.Sprintf("node-%d", i),
+			"address": strings.Repeat("10.0.0.1:", 2),
+		}
+	}
+
+	return struct {
+		Name    string
+		Version string
+		Nodes   []map[string]string
+	}{"gxregistry.test", "v1.0.0", nodes}
+}
+
+// go test -run '^$' -bench 'Debug' -benchmem ./log/
+func BenchmarkDebugDisabledPrettyString(b *testing.B) {
+	svc := benchService()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		debugf(false, "add service{%s}", PrettyString(svc))
+	}
+}
+
+func BenchmarkDebugDisabledLazy(b *testing.B) {
+	svc := benchService()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		debugf(false, "add service{%s}", Lazy(svc))
+	}
+}
+
+func BenchmarkDebugEnabledLazy(b *testing.B) {
+	svc := benchService()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		debugf(true, "add service{%s}", Lazy(svc))
+	}
+}
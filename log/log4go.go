@@ -35,12 +35,14 @@ type Conf struct {
 	Json      bool   // whether output json log
 }
 
-type Logger struct {
+// Log4goLogger is a log4go logger writing to the console and the files of
+// Conf, its Logger is NewFromLog4go(l.Logger).
+type Log4goLogger struct {
 	log4go.Logger
 }
 
 // init a logger
-func NewLogger(conf Conf) (Logger, error) {
+func NewLogger(conf Conf) (Log4goLogger, error) {
 	var (
 		err        error
 		fileName   string
@@ -50,7 +52,7 @@ func NewLogger(conf Conf) (Logger, error) {
 
 	if err = gxos.CreateDir(conf.Dir); err != nil {
 		log4go.Error("goext.os.CreateDir(%s) = error{%#v}", conf.Dir, err)
-		return Logger{logger}, err
+		return Log4goLogger{logger}, err
 	}
 
 	logger = log4go.NewLogger()
@@ -62,7 +64,7 @@ func NewLogger(conf Conf) (Logger, error) {
 	fileName = comLogFileName(conf.Name, conf.Dir, false)
 	fileLogger = log4go.NewFileLogWriter(fileName, true, conf.BufSize)
 	if fileLogger == nil {
-		return Logger{logger}, fmt.Errorf("log4go.NewFileLogWriter(%s) = nil", fileName)
+		return Log4goLogger{logger}, fmt.Errorf("log4go.NewFileLogWriter(%s) = nil", fileName)
 	}
 	fileLogger.SetJson(conf.Json)
 	fileLogger.SetFormat(log4go.FORMAT_DEFAULT)
@@ -78,7 +80,7 @@ func NewLogger(conf Conf) (Logger, error) {
 	fileName = comLogFileName(conf.Name, conf.Dir, true)
 	fileLogger = log4go.NewFileLogWriter(fileName, true, conf.BufSize)
 	if fileLogger == nil {
-		return Logger{logger}, fmt.Errorf("log4go.NewFileLogWriter(%s) = nil", fileName)
+		return Log4goLogger{logger}, fmt.Errorf("log4go.NewFileLogWriter(%s) = nil", fileName)
 	}
 	fileLogger.SetJson(conf.Json)
 	fileLogger.SetFormat(log4go.FORMAT_DEFAULT)
@@ -93,12 +95,12 @@ func NewLogger(conf Conf) (Logger, error) {
 	}
 	logger.AddFilter("wflog", log4go.WARNING, fileLogger)
 
-	return Logger{logger}, nil
+	return Log4goLogger{logger}, nil
 }
 
-func NewLoggerWithConfFile(conf string) Logger {
+func NewLoggerWithConfFile(conf string) Log4goLogger {
 	logger := log4go.NewLogger()
-	return Logger{(&logger).LoadConfiguration(conf)}
+	return Log4goLogger{(&logger).LoadConfiguration(conf)}
 }
 
 func comLogFileName(appName string, dir string, err bool) string {
@@ -22,7 +22,7 @@ func TestNewLogger(t *testing.T) {
 
 	var (
 		err    error
-		logger Logger
+		logger Log4goLogger
 	)
 	if logger, err = NewLogger(conf); err != nil {
 		t.Errorf("NewLogger(conf{%#v}) = error{%#v}", conf, err)
@@ -53,7 +53,7 @@ func TestJsonLogger(t *testing.T) {
 		err      error
 		logBytes []byte
 		logStr   string
-		logger   Logger
+		logger   Log4goLogger
 	)
 
 	logBytes, err = json.Marshal(
@@ -97,7 +97,7 @@ func TestAsyncLogger(t *testing.T) {
 
 	var (
 		err    error
-		logger Logger
+		logger Log4goLogger
 	)
 	if logger, err = NewLogger(conf); err != nil {
 		t.Errorf("NewLogger(conf{%#v}) = error{%#v}", conf, err)
@@ -115,7 +115,7 @@ func TestAsyncLogger(t *testing.T) {
 func TestNewLoggerWithConfFile(t *testing.T) {
 	var (
 		conf   string
-		logger Logger
+		logger Log4goLogger
 	)
 	conf = "log_test.xml"
 	logger = NewLoggerWithConfFile(conf)
@@ -146,8 +146,8 @@ func TestMultiLoggers(t *testing.T) {
 
 	var (
 		err     error
-		logger1 Logger
-		logger2 Logger
+		logger1 Log4goLogger
+		logger2 Log4goLogger
 	)
 	if logger1, err = NewLogger(conf); err != nil {
 		t.Errorf("NewLogger(conf{%#v}) = error{%#v}", conf, err)
@@ -208,7 +208,7 @@ func BenchmarkSyncLogger(b *testing.B) {
 
 	var (
 		err    error
-		logger Logger
+		logger Log4goLogger
 	)
 
 	b.StopTimer()
@@ -275,7 +275,7 @@ func BenchmarkAsyncLogger(b *testing.B) {
 
 	var (
 		err    error
-		logger Logger
+		logger Log4goLogger
 	)
 
 	b.StopTimer()
@@ -0,0 +1,227 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// logger.go provides a logger interface and its adapters
+package gxlog
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+import (
+	"github.com/AlexStocks/log4go"
+)
+
+// Level is the level of a log of Logger.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+
+	return levelNames[l]
+}
+
+// Logger is the logger of the goext packages, to not depend on a log
+// library. The args are formatted by fmt.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// Debugw, Infow, Warnw and Errorw log @msg with the alternating keys and
+	// values of @kv, like l.With(kv...).Debugf(msg) but @msg is not a
+	// format. @msg should be constant and the variables be the fields, to
+	// sample the logs by their messages.
+	Debugw(msg string, kv ...interface{})
+	Infow(msg string, kv ...interface{})
+	Warnw(msg string, kv ...interface{})
+	Errorw(msg string, kv ...interface{})
+	// With returns a logger appending the alternating keys and values of
+	// @kv to its logs, as a json object of KV.
+	With(kv ...interface{}) Logger
+}
+
+// logf logs a message of @level.
+type logf func(level Level, format string, args ...interface{})
+
+type logger struct {
+	logf   logf
+	kv     []interface{}
+	fields string // KV(kv...)
+}
+
+func newLogger(f logf) Logger {
+	return &logger{logf: f}
+}
+
+func (l *logger) log(level Level, format string, args []interface{}) {
+	if l.fields != "" {
+		format += " %s"
+		args = append(args[:len(args):len(args)], l.fields)
+	}
+	l.logf(level, format, args...)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, format, args)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, format, args)
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, format, args)
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, format, args)
+}
+
+// logw logs @msg with the fields of l and @kv, which are formatted only if
+// the level is enabled.
+func (l *logger) logw(level Level, msg string, kv []interface{}) {
+	if len(kv) == 0 {
+		l.log(level, "%s", []interface{}{msg})
+		return
+	}
+	all := append(l.kv[:len(l.kv):len(l.kv)], kv...)
+	l.logf(level, "%s %s", msg, LazyFunc(func() string {
+		return KV(all...)
+	}))
+}
+
+func (l *logger) Debugw(msg string, kv ...interface{}) {
+	l.logw(LevelDebug, msg, kv)
+}
+
+func (l *logger) Infow(msg string, kv ...interface{}) {
+	l.logw(LevelInfo, msg, kv)
+}
+
+func (l *logger) Warnw(msg string, kv ...interface{}) {
+	l.logw(LevelWarn, msg, kv)
+}
+
+func (l *logger) Errorw(msg string, kv ...interface{}) {
+	l.logw(LevelError, msg, kv)
+}
+
+func (l *logger) With(kv ...interface{}) Logger {
+	if len(kv) == 0 {
+		return l
+	}
+	all := append(l.kv[:len(l.kv):len(l.kv)], kv...)
+
+	return &logger{logf: l.logf, kv: all, fields: KV(all...)}
+}
+
+// NewFromLog4go returns a Logger of @l, which formats the args only if the
+// level is enabled.
+func NewFromLog4go(l log4go.Logger) Logger {
+	return newLogger(func(level Level, format string, args ...interface{}) {
+		switch level {
+		case LevelDebug:
+			l.Debug(format, args...)
+		case LevelInfo:
+			l.Info(format, args...)
+		case LevelWarn:
+			l.Warn(format, args...)
+		default:
+			l.Error(format, args...)
+		}
+	})
+}
+
+// NewFromStd returns a Logger of @l, the logs are prefixed by their levels
+// like "[WARN] ".
+func NewFromStd(l *log.Logger) Logger {
+	return newLogger(func(level Level, format string, args ...interface{}) {
+		l.Output(4, "["+level.String()+"] "+fmt.Sprintf(format, args...))
+	})
+}
+
+// NewFromFunc returns a Logger passing the formatted logs to @f, e.g. of a
+// zap.SugaredLogger:
+//
+//	gxlog.NewFromFunc(func(level gxlog.Level, msg string) {
+//		switch level {
+//		case gxlog.LevelDebug:
+//			sugar.Debug(msg)
+//		...
+//		}
+//	})
+func NewFromFunc(f func(level Level, msg string)) Logger {
+	return newLogger(func(level Level, format string, args ...interface{}) {
+		f(level, fmt.Sprintf(format, args...))
+	})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Warnf(format string, args ...interface{})  {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+func (nopLogger) Debugw(msg string, kv ...interface{})      {}
+func (nopLogger) Infow(msg string, kv ...interface{})       {}
+func (nopLogger) Warnw(msg string, kv ...interface{})       {}
+func (nopLogger) Errorw(msg string, kv ...interface{})      {}
+func (n nopLogger) With(kv ...interface{}) Logger           { return n }
+
+// NewNop returns a Logger discarding all logs.
+func NewNop() Logger {
+	return nopLogger{}
+}
+
+var (
+	defaultLock sync.RWMutex
+	// the global logger of log4go
+	defaultLogger = newLogger(func(level Level, format string, args ...interface{}) {
+		switch level {
+		case LevelDebug:
+			log4go.Debug(format, args...)
+		case LevelInfo:
+			log4go.Info(format, args...)
+		case LevelWarn:
+			log4go.Warn(format, args...)
+		default:
+			log4go.Error(format, args...)
+		}
+	})
+)
+
+// SetDefault sets the logger of the packages not given one, the global
+// logger of log4go by default.
+func SetDefault(l Logger) {
+	if l == nil {
+		panic("@l is nil")
+	}
+
+	defaultLock.Lock()
+	defaultLogger = l
+	defaultLock.Unlock()
+}
+
+// Default returns the logger set by SetDefault.
+func Default() Logger {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+
+	return defaultLogger
+}
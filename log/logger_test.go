@@ -0,0 +1,57 @@
+package gxlog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFromStd(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewFromStd(log.New(&buf, "", log.Lshortfile))
+
+	l.Warnf("zk %s lost", "127.0.0.1:2181")
+	if s := buf.String(); s != "logger_test.go:14: [WARN] zk 127.0.0.1:2181 lost\n" {
+		t.Fatalf("log %q", s)
+	}
+
+	buf.Reset()
+	l = l.With("path", "/a").With("n", 1)
+	l.Debugf("%d%% done", 100)
+	if s := buf.String(); !strings.HasSuffix(s, `[DEBUG] 100% done {"path":"/a","n":1}`+"\n") {
+		t.Fatalf("log %q", s)
+	}
+}
+
+func TestLoggerFromFunc(t *testing.T) {
+	var logs []string
+	l := NewFromFunc(func(level Level, msg string) {
+		logs = append(logs, level.String()+" "+msg)
+	})
+
+	base := l.With("a", 1)
+	a, b := base.With("b", 2), base.With("c", 3)
+	a.Infof("x")
+	b.Errorf("y %v", nil)
+	l.Infof("z")
+	want := []string{`INFO x {"a":1,"b":2}`, `ERROR y <nil> {"a":1,"c":3}`, `INFO z`}
+	if strings.Join(logs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("logs %q, want %q", logs, want)
+	}
+	if s := Level(7).String(); s != "Level(7)" {
+		t.Fatalf("Level(7).String() = %s", s)
+	}
+}
+
+func TestLoggerDefault(t *testing.T) {
+	old := Default()
+	defer SetDefault(old)
+
+	nop := NewNop()
+	nop.With("a", 1).Errorf("discarded")
+	SetDefault(nop)
+	if Default() != nop {
+		t.Fatalf("Default() is not the logger set")
+	}
+}
@@ -7,12 +7,578 @@
 package gxlog
 
 import (
-	"github.com/davecgh/go-spew/spew"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+import (
 	"github.com/k0kubun/pp"
 )
 
+import (
+	"github.com/AlexStocks/goext/strings"
+	"github.com/AlexStocks/goext/sync"
+)
+
+const (
+	defaultMaxDepth     = 8
+	defaultMaxStringLen = 1024
+	defaultMaxSliceLen  = 100
+
+	prettyIndent = "  "
+	// the buffers grown larger are not put back to the pool
+	maxPooledBuffer = 64 << 10
+)
+
+type prettyOptions struct {
+	maxDepth     int
+	maxStringLen int
+	maxSliceLen  int
+}
+
+// Option is an option of PrettyStringWithOptions and JSONString.
+type Option func(*prettyOptions)
+
+// WithMaxDepth limits the nested structs, maps, slices and arrays to @n
+// levels, 8 by default, no limit if @n <= 0.
+func WithMaxDepth(n int) Option {
+	return func(o *prettyOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxStringLen truncates the strings and []byte to @n bytes, 1024 by
+// default, no limit if @n <= 0.
+func WithMaxStringLen(n int) Option {
+	return func(o *prettyOptions) {
+		o.maxStringLen = n
+	}
+}
+
+// WithMaxSliceLen truncates the slices, arrays and maps to @n elements, 100
+// by default, no limit if @n <= 0.
+func WithMaxSliceLen(n int) Option {
+	return func(o *prettyOptions) {
+		o.maxSliceLen = n
+	}
+}
+
+var (
+	redactLock sync.RWMutex
+	// lower case, see SetRedactedFields
+	redactedFields = map[string]bool{
+		"password": true,
+		"secret":   true,
+		"token":    true,
+	}
+	// increased by SetRedactedFields, to rebuild the plans
+	redactGen uint64
+)
+
+// SetRedactedFields replaces the names of the struct fields masked by
+// PrettyString, "Password", "Secret" and "Token" by default. The names are
+// case insensitive, and match the whole field names only.
+func SetRedactedFields(names ...string) {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[strings.ToLower(name)] = true
+	}
+
+	redactLock.Lock()
+	redactedFields = m
+	redactLock.Unlock()
+	atomic.AddUint64(&redactGen, 1)
+}
+
+// the redaction of a struct field
+const (
+	redactNone = iota
+	redactOmit
+	redactMask
+)
+
+// redaction returns how to print @f, by its tag `log:"-"` or `log:"mask"`,
+// or the redacted field names.
+func redaction(f reflect.StructField) int {
+	switch f.Tag.Get("log") {
+	case "-":
+		return redactOmit
+	case "mask":
+		return redactMask
+	}
+
+	if redactedName(f.Name) {
+		return redactMask
+	}
+
+	return redactNone
+}
+
+// redactedName returns whether @name is one of the redacted field names.
+func redactedName(name string) bool {
+	redactLock.RLock()
+	defer redactLock.RUnlock()
+
+	return redactedFields[strings.ToLower(name)]
+}
+
+// typePlan is how to print the values of a type, built once a type.
+type typePlan struct {
+	gen      uint64 // the redactGen it is built by
+	name     string // of the type
+	redacted bool   // a struct, or a pointer to one, with redacted fields
+	method   bool   // printed by its Error or String method
+	fields   []fieldPlan
+}
+
+// fieldPlan is how to print a field of a struct.
+type fieldPlan struct {
+	index  int
+	name   string
+	redact int
+}
+
+// planCache maps a reflect.Type to its *typePlan
+var planCache sync.Map
+
+// planOf returns the plan of @t, built if it is not cached or out of date.
+func planOf(t reflect.Type) *typePlan {
+	gen := atomic.LoadUint64(&redactGen)
+	if p, ok := planCache.Load(t); ok && p.(*typePlan).gen == gen {
+		return p.(*typePlan)
+	}
+
+	p := &typePlan{gen: gen, name: t.String()}
+	if t.Kind() == reflect.Struct {
+		p.fields = make([]fieldPlan, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			redact := redaction(f)
+			p.redacted = p.redacted || redact != redactNone
+			if redact != redactOmit {
+				p.fields = append(p.fields, fieldPlan{index: i, name: f.Name, redact: redact})
+			}
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		p.redacted = planOf(t.Elem()).redacted
+	}
+	p.method = (t.Implements(errorType) || t.Implements(stringerType)) && !p.redacted
+	planCache.Store(t, p)
+
+	return p
+}
+
+// hasRedaction returns whether @t is a struct, or a pointer to one, with
+// redacted fields.
+func hasRedaction(t reflect.Type) bool {
+	return planOf(t).redacted
+}
+
+func newPrettyOptions(opts []Option) prettyOptions {
+	o := prettyOptions{
+		maxDepth:     defaultMaxDepth,
+		maxStringLen: defaultMaxStringLen,
+		maxSliceLen:  defaultMaxSliceLen,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// visit is a pointer, map or slice being printed, to detect cycles.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// visitSet is the pointers, maps and slices being printed.
+type visitSet map[visit]bool
+
+// enter marks @v visited, and returns false if it is being printed.
+func (s visitSet) enter(v reflect.Value) (visit, bool) {
+	key := visit{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if s[key] {
+		return key, false
+	}
+	s[key] = true
+
+	return key, true
+}
+
+// mapEntry is an entry of a map being printed, its key printed is
+// keys[start:end] of the printer.
+type mapEntry struct {
+	start, end int
+	val        reflect.Value
+}
+
+// mapEntries sorts the entries of a map by their keys printed.
+type mapEntries struct {
+	keys    []byte
+	entries []mapEntry
+}
+
+func (m *mapEntries) Len() int {
+	return len(m.entries)
+}
+
+func (m *mapEntries) Less(i, j int) bool {
+	a, b := m.entries[i], m.entries[j]
+	return string(m.keys[a.start:a.end]) < string(m.keys[b.start:b.end])
+}
+
+func (m *mapEntries) Swap(i, j int) {
+	m.entries[i], m.entries[j] = m.entries[j], m.entries[i]
+}
+
+type prettyPrinter struct {
+	prettyOptions
+	buf      []byte
+	visiting visitSet
+	// the keys and the entries of the maps being printed, as stacks
+	keys    []byte
+	entries []mapEntry
+	sorter  mapEntries
+}
+
+var prettyPrinterPool = gxsync.NewPool(func() interface{} {
+	return &prettyPrinter{visiting: make(visitSet)}
+})
+
+// PrettyString returns @i in Go syntax, one field or element a line, by the
+// default options of PrettyStringWithOptions.
 func PrettyString(i interface{}) string {
-	return spew.Sdump(i)
+	return PrettyStringWithOptions(i)
+}
+
+// PrettyStringWithOptions returns @i in Go syntax, one field or element a
+// line. The values implementing error or fmt.Stringer are printed by their
+// methods. A pointer, map or slice inside itself is printed as "<cycle>",
+// and a value deeper than the max depth as "<max depth>".
+//
+// The struct fields tagged `log:"-"` are omitted, and those tagged
+// `log:"mask"` or named by SetRedactedFields are masked, at any depth. A
+// struct with such fields is printed field by field even if it has an
+// Error or String method, which may not redact them.
+func PrettyStringWithOptions(i interface{}, opts ...Option) string {
+	p := prettyPrinterPool.Get().(*prettyPrinter)
+	p.prettyOptions = newPrettyOptions(opts)
+	p.print(reflect.ValueOf(i), 0)
+	s := string(p.buf)
+
+	// a panic of printing leaves p dirty, and it is not put back
+	if cap(p.buf) <= maxPooledBuffer && cap(p.keys) <= maxPooledBuffer {
+		p.buf, p.keys = p.buf[:0], p.keys[:0]
+		for i := range p.entries {
+			p.entries[i] = mapEntry{}
+		}
+		p.entries = p.entries[:0]
+		prettyPrinterPool.Put(p)
+	}
+
+	return s
+}
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// printMethod prints @v by its Error or String method, and returns whether
+// it has one.
+func (p *prettyPrinter) printMethod(v reflect.Value, plan *typePlan) (ok bool) {
+	// a nil interface has no method, and a non-nil one is printed by its
+	// dynamic value
+	if !plan.method || !v.CanInterface() || v.Kind() == reflect.Interface {
+		return false
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false
+	}
+
+	var s string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s = fmt.Sprintf("<%s panic: %v>", plan.name, r)
+			}
+		}()
+		switch x := v.Interface().(type) {
+		case error:
+			s = x.Error()
+		case fmt.Stringer:
+			s = x.String()
+		}
+	}()
+	p.buf = append(p.buf, plan.name...)
+	p.buf = append(p.buf, '(')
+	p.printString(s)
+	p.buf = append(p.buf, ')')
+
+	return true
+}
+
+func (p *prettyPrinter) printString(s string) {
+	more := 0
+	if p.maxStringLen > 0 && len(s) > p.maxStringLen {
+		t := gxstrings.TruncateBytes(s, p.maxStringLen, "")
+		more, s = len(s)-len(t), t
+	}
+	p.buf = strconv.AppendQuote(p.buf, s)
+	if more > 0 {
+		p.buf = append(p.buf, "...("...)
+		p.buf = strconv.AppendInt(p.buf, int64(more), 10)
+		p.buf = append(p.buf, " more bytes)"...)
+	}
+}
+
+// printMasked prints @v of a redacted field, the strings and []byte are
+// masked rune by rune, and the others are not printed.
+func (p *prettyPrinter) printMasked(v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			p.print(v, 0)
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		p.printString(gxstrings.Mask(v.String(), 0, 0, '*'))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		p.printString(gxstrings.Mask(string(v.Bytes()), 0, 0, '*'))
+	default:
+		p.buf = append(p.buf, "<masked>"...)
+	}
+}
+
+func (p *prettyPrinter) newline(depth int) {
+	p.buf = append(p.buf, '\n')
+	for i := 0; i < depth; i++ {
+		p.buf = append(p.buf, prettyIndent...)
+	}
+}
+
+// elided prints the number of the elements not printed.
+func (p *prettyPrinter) elided(n, depth int) {
+	if n > 0 {
+		p.newline(depth + 1)
+		p.buf = append(p.buf, "...("...)
+		p.buf = strconv.AppendInt(p.buf, int64(n), 10)
+		p.buf = append(p.buf, " more)"...)
+	}
+}
+
+// printNil prints a nil of @plan like "(*T)(nil)".
+func (p *prettyPrinter) printNil(plan *typePlan) {
+	p.buf = append(p.buf, '(')
+	p.buf = append(p.buf, plan.name...)
+	p.buf = append(p.buf, ")(nil)"...)
+}
+
+func (p *prettyPrinter) print(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		p.buf = append(p.buf, "nil"...)
+		return
+	}
+	t := v.Type()
+	plan := planOf(t)
+	if p.printMethod(v, plan) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		p.buf = strconv.AppendBool(p.buf, v.Bool())
+		return
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.buf = strconv.AppendInt(p.buf, v.Int(), 10)
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.buf = strconv.AppendUint(p.buf, v.Uint(), 10)
+		return
+	case reflect.Float32, reflect.Float64:
+		p.buf = strconv.AppendFloat(p.buf, v.Float(), 'g', -1, t.Bits())
+		return
+	case reflect.Complex64, reflect.Complex128:
+		p.buf = append(p.buf, strconv.FormatComplex(v.Complex(), 'g', -1, t.Bits())...)
+		return
+	case reflect.String:
+		p.printString(v.String())
+		return
+	case reflect.Interface:
+		p.print(v.Elem(), depth)
+		return
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			p.printNil(plan)
+		} else {
+			p.buf = append(p.buf, '(')
+			p.buf = append(p.buf, plan.name...)
+			p.buf = append(p.buf, ")(0x"...)
+			p.buf = strconv.AppendUint(p.buf, uint64(v.Pointer()), 16)
+			p.buf = append(p.buf, ')')
+		}
+		return
+	}
+
+	// the composite ones
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		p.printNil(plan)
+		return
+	}
+	if p.maxDepth > 0 && depth >= p.maxDepth {
+		p.buf = append(p.buf, "<max depth>"...)
+		return
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+		key, ok := p.visiting.enter(v)
+		if !ok {
+			p.buf = append(p.buf, "<cycle>"...)
+			return
+		}
+		defer delete(p.visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		// not a level of the depth
+		p.buf = append(p.buf, '&')
+		p.print(v.Elem(), depth)
+
+	case reflect.Struct:
+		p.buf = append(p.buf, plan.name...)
+		p.buf = append(p.buf, '{')
+		for _, f := range plan.fields {
+			p.newline(depth + 1)
+			p.buf = append(p.buf, f.name...)
+			p.buf = append(p.buf, ": "...)
+			if f.redact == redactMask {
+				p.printMasked(v.Field(f.index))
+			} else {
+				p.print(v.Field(f.index), depth+1)
+			}
+			p.buf = append(p.buf, ',')
+		}
+		if len(plan.fields) > 0 {
+			p.newline(depth)
+		}
+		p.buf = append(p.buf, '}')
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			p.buf = append(p.buf, plan.name...)
+			p.buf = append(p.buf, '(')
+			p.printString(string(v.Bytes()))
+			p.buf = append(p.buf, ')')
+			return
+		}
+
+		n := v.Len()
+		if p.maxSliceLen > 0 && n > p.maxSliceLen {
+			n = p.maxSliceLen
+		}
+		p.buf = append(p.buf, plan.name...)
+		p.buf = append(p.buf, '{')
+		for i := 0; i < n; i++ {
+			p.newline(depth + 1)
+			p.print(v.Index(i), depth+1)
+			p.buf = append(p.buf, ',')
+		}
+		p.elided(v.Len()-n, depth)
+		if v.Len() > 0 {
+			p.newline(depth)
+		}
+		p.buf = append(p.buf, '}')
+
+	case reflect.Map:
+		p.printMap(v, plan, depth)
+	}
+}
+
+// printMap prints the map @v sorted by the keys printed, to be
+// deterministic.
+func (p *prettyPrinter) printMap(v reflect.Value, plan *typePlan, depth int) {
+	keysBase, entriesBase := len(p.keys), len(p.entries)
+	defer func() {
+		p.keys = p.keys[:keysBase]
+		for i := entriesBase; i < len(p.entries); i++ {
+			p.entries[i] = mapEntry{}
+		}
+		p.entries = p.entries[:entriesBase]
+	}()
+
+	var (
+		iter reflect.MapIter
+		key  reflect.Value
+		vals reflect.Value
+	)
+	// the values of a map of an unexported field can not be copied to
+	// the slots, and are copied by the iterator
+	copied := v.CanInterface()
+	if copied {
+		t := v.Type()
+		key = reflect.New(t.Key()).Elem()
+		vals = reflect.MakeSlice(reflect.SliceOf(t.Elem()), v.Len(), v.Len())
+	}
+	iter.Reset(v)
+	for i := 0; iter.Next(); i++ {
+		k, val := key, reflect.Value{}
+		if copied && i < vals.Len() {
+			k.SetIterKey(&iter)
+			val = vals.Index(i)
+			val.SetIterValue(&iter)
+		} else {
+			k, val = iter.Key(), iter.Value()
+		}
+
+		// printed at the end of the buffer, and moved to the keys
+		start := len(p.buf)
+		p.print(k, depth+1)
+		e := mapEntry{start: len(p.keys), val: val}
+		p.keys = append(p.keys, p.buf[start:]...)
+		p.buf = p.buf[:start]
+		e.end = len(p.keys)
+		p.entries = append(p.entries, e)
+	}
+	iter.Reset(reflect.Value{})
+
+	p.sorter = mapEntries{keys: p.keys, entries: p.entries[entriesBase:]}
+	sort.Sort(&p.sorter)
+	entries := p.sorter.entries
+	p.sorter = mapEntries{}
+
+	n := len(entries)
+	if p.maxSliceLen > 0 && n > p.maxSliceLen {
+		n = p.maxSliceLen
+	}
+	p.buf = append(p.buf, plan.name...)
+	p.buf = append(p.buf, '{')
+	for _, e := range entries[:n] {
+		p.newline(depth + 1)
+		p.buf = append(p.buf, p.keys[e.start:e.end]...)
+		p.buf = append(p.buf, ": "...)
+		p.print(e.val, depth+1)
+		p.buf = append(p.buf, ',')
+	}
+	p.elided(len(entries)-n, depth)
+	if len(entries) > 0 {
+		p.newline(depth)
+	}
+	p.buf = append(p.buf, '}')
 }
 
 func ColorSprint(i interface{}) string {
@@ -3,7 +3,10 @@ package gxlog
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type info struct {
@@ -26,3 +29,430 @@ func TestColorPrintf(t *testing.T) {
 	var i = info{name: "hello", age: 23.5, m: map[string]string{"h": "w", "hello": "world"}}
 	ColorPrintf("exapmle format:%s\n", i)
 }
+
+type cacheNode struct {
+	Name   string
+	Parent *cacheNode
+	Kids   []*cacheNode
+	Any    interface{}
+	Err    error
+}
+
+func TestPrettyStringCycle(t *testing.T) {
+	root := &cacheNode{Name: "root"}
+	kid := &cacheNode{Name: "kid", Parent: root}
+	root.Kids = []*cacheNode{kid, kid} // shared, not a cycle
+	root.Any = root
+
+	s := PrettyString(root)
+	if strings.Count(s, "<cycle>") != 3 || strings.Count(s, `"kid"`) != 2 {
+		t.Fatalf("PrettyString() of a cycle = %s", s)
+	}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	if s := PrettyString(m); !strings.Contains(s, `"self": <cycle>`) {
+		t.Fatalf("PrettyString() of a map in itself = %s", s)
+	}
+}
+
+func TestPrettyStringDepth(t *testing.T) {
+	var node *cacheNode
+	for i := 0; i < 20; i++ {
+		node = &cacheNode{Name: fmt.Sprint(i), Any: node}
+	}
+	s := PrettyStringWithOptions(node, WithMaxDepth(3))
+	if !strings.Contains(s, `Any: <max depth>`) || !strings.Contains(s, `"17"`) || strings.Contains(s, `"16"`) {
+		t.Fatalf("PrettyString() of depth 3 = %s", s)
+	}
+	if s := PrettyStringWithOptions(node, WithMaxDepth(0)); !strings.Contains(s, `"0"`) {
+		t.Fatalf("PrettyString() of no max depth = %s", s)
+	}
+}
+
+func TestPrettyStringTruncate(t *testing.T) {
+	big := make([]int, 10000)
+	s := PrettyString(big)
+	if !strings.HasSuffix(s, "...(9900 more)\n}") || strings.Count(s, "\n") != 102 {
+		t.Fatalf("PrettyString() of 10k ints = %s", s)
+	}
+
+	s = PrettyStringWithOptions(strings.Repeat("你", 10), WithMaxStringLen(10))
+	if s != `"你你你"...(21 more bytes)` {
+		t.Fatalf("PrettyString() of a long string = %s", s)
+	}
+	s = PrettyStringWithOptions([]byte("hello"), WithMaxStringLen(2))
+	if s != `[]uint8("he"...(3 more bytes))` {
+		t.Fatalf("PrettyString() of a long []byte = %s", s)
+	}
+
+	m := map[int]bool{}
+	for i := 0; i < 10; i++ {
+		m[i] = true
+	}
+	if s := PrettyStringWithOptions(m, WithMaxSliceLen(3)); !strings.Contains(s, "2: true,\n  ...(7 more)\n}") {
+		t.Fatalf("PrettyString() of a long map = %s", s)
+	}
+}
+
+func TestPrettyStringNil(t *testing.T) {
+	s := PrettyString(cacheNode{})
+	want := `gxlog.cacheNode{
+  Name: "",
+  Parent: (*gxlog.cacheNode)(nil),
+  Kids: ([]*gxlog.cacheNode)(nil),
+  Any: nil,
+  Err: nil,
+}`
+	if s != want {
+		t.Fatalf("PrettyString() of nils = %s", s)
+	}
+	if s := PrettyString(nil); s != "nil" {
+		t.Fatalf("PrettyString(nil) = %s", s)
+	}
+
+	s = PrettyString(cacheNode{Err: fmt.Errorf("failed"), Any: time.Duration(1500)})
+	if !strings.Contains(s, `Err: *errors.errorString("failed")`) {
+		t.Fatalf("PrettyString() of an error = %s", s)
+	}
+	if !strings.Contains(s, `Any: time.Duration("1.5µs")`) {
+		t.Fatalf("PrettyString() of a Stringer = %s", s)
+	}
+}
+
+type credential struct {
+	User     string
+	Password string
+	Key      []byte `log:"mask"`
+	Cookie   string `log:"-"`
+	Extra    interface{}
+}
+
+func (c credential) String() string {
+	return c.User + ":" + c.Password
+}
+
+func TestPrettyStringRedaction(t *testing.T) {
+	inner := &credential{User: "bob", Password: "hunter2", Key: []byte("k3y"), Cookie: "c00kie"}
+	outer := credential{
+		User:     "alice",
+		Password: "pa55",
+		Cookie:   "c00kie",
+		Extra: map[string]interface{}{
+			"list": []interface{}{inner, struct{ Token string }{"t0ken"}},
+		},
+	}
+
+	s := PrettyString(outer)
+	for _, secret := range []string{"pa55", "hunter2", "k3y", "c00kie", "Cookie", "t0ken"} {
+		if strings.Contains(s, secret) {
+			t.Fatalf("%q in %s", secret, s)
+		}
+	}
+	for _, want := range []string{`User: "alice"`, `User: "bob"`, `Password: "****"`, `Key: "***"`, `Token: "*****"`} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("%s not in %s", want, s)
+		}
+	}
+	if outer.Password != "pa55" || inner.Password != "hunter2" || string(inner.Key) != "k3y" || inner.Cookie != "c00kie" {
+		t.Fatalf("the value is modified: %+v", outer)
+	}
+
+	defer SetRedactedFields("Password", "Secret", "Token")
+	SetRedactedFields("user")
+	s = PrettyString(inner)
+	if strings.Contains(s, "bob") || !strings.Contains(s, `Password: "hunter2"`) {
+		t.Fatalf("PrettyString() with the field User redacted = %s", s)
+	}
+}
+
+type mediumNode struct {
+	ID      string
+	Address string
+	Port    int
+	Weight  float64
+	Meta    map[string]string
+}
+
+type mediumService struct {
+	Name     string
+	Version  string
+	Enabled  bool
+	Timeout  time.Duration
+	Tags     []string
+	Nodes    []*mediumNode
+	Attrs    map[string]int
+	Primary  mediumNode
+	Token    string
+	internal [2]uint16
+}
+
+func newMediumService() *mediumService {
+	s := &mediumService{
+		Name:     "gxregistry.shopping",
+		Version:  "v1.0.1",
+		Enabled:  true,
+		Timeout:  3 * time.Second,
+		Tags:     []string{"bjtelecom", "pb", "provider"},
+		Attrs:    map[string]int{"weight": 10, "zone": 3, "cpu": 8},
+		Primary:  mediumNode{ID: "node0", Address: "127.0.0.1", Port: 12345},
+		Token:    "s3cr3t",
+		internal: [2]uint16{1, 2},
+	}
+	for i := 0; i < 5; i++ {
+		s.Nodes = append(s.Nodes, &mediumNode{
+			ID:      fmt.Sprintf("node%d", i),
+			Address: "10.0.0.1",
+			Port:    20000 + i,
+			Weight:  0.5 * float64(i),
+			Meta:    map[string]string{"dc": "bj", "rack": fmt.Sprint(i)},
+		})
+	}
+
+	return s
+}
+
+// prettyCorpus is the values of the golden tests of PrettyString.
+func prettyCorpus() []interface{} {
+	root := &cacheNode{Name: "root"}
+	root.Kids = []*cacheNode{{Name: "kid", Parent: root}}
+	self := map[string]interface{}{"n": 1}
+	self["self"] = self
+	var nilMap map[int]int
+	var nilErr error
+
+	return []interface{}{
+		nil,
+		0,
+		-12,
+		uint8(7),
+		3.25,
+		float32(0.1),
+		complex(1, -2),
+		true,
+		"hello\n\"world\"",
+		[]byte("bytes\x00"),
+		[3]int{1, 2, 3},
+		[]interface{}{1, "a", nil, 2.5},
+		map[int]string{3: "c", 1: "a", 2: "b"},
+		nilMap,
+		&nilErr,
+		(chan int)(nil),
+		fmt.Errorf("failed"),
+		time.Duration(1500),
+		info{name: "hello", age: 23.5, m: map[string]string{"h": "w", "hello": "world"}},
+		root,
+		self,
+		credential{User: "u", Password: "pa55", Key: []byte("k"), Cookie: "c", Extra: []int{1}},
+		newMediumService(),
+		struct{}{},
+		[]struct{ A, B int }{{1, 2}, {3, 4}},
+	}
+}
+
+// prettyGolden is PrettyString of prettyCorpus.
+var prettyGolden = []string{
+	`nil`,
+	`0`,
+	`-12`,
+	`7`,
+	`3.25`,
+	`0.1`,
+	`(1-2i)`,
+	`true`,
+	`"hello\n\"world\""`,
+	`[]uint8("bytes\x00")`,
+	`[3]int{
+  1,
+  2,
+  3,
+}`,
+	`[]interface {}{
+  1,
+  "a",
+  nil,
+  2.5,
+}`,
+	`map[int]string{
+  1: "a",
+  2: "b",
+  3: "c",
+}`,
+	`(map[int]int)(nil)`,
+	`&nil`,
+	`(chan int)(nil)`,
+	`*errors.errorString("failed")`,
+	`time.Duration("1.5µs")`,
+	`gxlog.info{
+  name: "hello",
+  age: 23.5,
+  m: map[string]string{
+    "h": "w",
+    "hello": "world",
+  },
+}`,
+	`&gxlog.cacheNode{
+  Name: "root",
+  Parent: (*gxlog.cacheNode)(nil),
+  Kids: []*gxlog.cacheNode{
+    &gxlog.cacheNode{
+      Name: "kid",
+      Parent: <cycle>,
+      Kids: ([]*gxlog.cacheNode)(nil),
+      Any: nil,
+      Err: nil,
+    },
+  },
+  Any: nil,
+  Err: nil,
+}`,
+	`map[string]interface {}{
+  "n": 1,
+  "self": <cycle>,
+}`,
+	`gxlog.credential{
+  User: "u",
+  Password: "****",
+  Key: "*",
+  Extra: []int{
+    1,
+  },
+}`,
+	`&gxlog.mediumService{
+  Name: "gxregistry.shopping",
+  Version: "v1.0.1",
+  Enabled: true,
+  Timeout: time.Duration("3s"),
+  Tags: []string{
+    "bjtelecom",
+    "pb",
+    "provider",
+  },
+  Nodes: []*gxlog.mediumNode{
+    &gxlog.mediumNode{
+      ID: "node0",
+      Address: "10.0.0.1",
+      Port: 20000,
+      Weight: 0,
+      Meta: map[string]string{
+        "dc": "bj",
+        "rack": "0",
+      },
+    },
+    &gxlog.mediumNode{
+      ID: "node1",
+      Address: "10.0.0.1",
+      Port: 20001,
+      Weight: 0.5,
+      Meta: map[string]string{
+        "dc": "bj",
+        "rack": "1",
+      },
+    },
+    &gxlog.mediumNode{
+      ID: "node2",
+      Address: "10.0.0.1",
+      Port: 20002,
+      Weight: 1,
+      Meta: map[string]string{
+        "dc": "bj",
+        "rack": "2",
+      },
+    },
+    &gxlog.mediumNode{
+      ID: "node3",
+      Address: "10.0.0.1",
+      Port: 20003,
+      Weight: 1.5,
+      Meta: map[string]string{
+        "dc": "bj",
+        "rack": "3",
+      },
+    },
+    &gxlog.mediumNode{
+      ID: "node4",
+      Address: "10.0.0.1",
+      Port: 20004,
+      Weight: 2,
+      Meta: map[string]string{
+        "dc": "bj",
+        "rack": "4",
+      },
+    },
+  },
+  Attrs: map[string]int{
+    "cpu": 8,
+    "weight": 10,
+    "zone": 3,
+  },
+  Primary: gxlog.mediumNode{
+    ID: "node0",
+    Address: "127.0.0.1",
+    Port: 12345,
+    Weight: 0,
+    Meta: (map[string]string)(nil),
+  },
+  Token: "******",
+  internal: [2]uint16{
+    1,
+    2,
+  },
+}`,
+	`struct {}{}`,
+	`[]struct { A int; B int }{
+  struct { A int; B int }{
+    A: 1,
+    B: 2,
+  },
+  struct { A int; B int }{
+    A: 3,
+    B: 4,
+  },
+}`,
+}
+
+func TestPrettyStringGolden(t *testing.T) {
+	corpus := prettyCorpus()
+	if len(corpus) != len(prettyGolden) {
+		t.Fatalf("%d values, %d golden outputs", len(corpus), len(prettyGolden))
+	}
+	for i, v := range corpus {
+		// twice, the second by the cached plans
+		for j := 0; j < 2; j++ {
+			if s := PrettyString(v); s != prettyGolden[i] {
+				t.Fatalf("PrettyString(#%d) = %s, want %s", i, s, prettyGolden[i])
+			}
+		}
+	}
+}
+
+func TestPrettyStringConcurrent(t *testing.T) {
+	corpus := prettyCorpus()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k := j % len(corpus)
+				if s := PrettyString(corpus[k]); s != prettyGolden[k] {
+					t.Errorf("PrettyString(#%d) = %s, want %s", k, s, prettyGolden[k])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// go test -run '^$' -bench 'PrettyString' -benchmem ./log/
+// BenchmarkPrettyStringMedium 	  119922	     10404 ns/op	    2008 B/op	      23 allocs/op
+// and 20712 ns/op, 8008 B/op, 157 allocs/op without the plans and the pool
+func BenchmarkPrettyStringMedium(b *testing.B) {
+	s := newMediumService()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PrettyString(s)
+	}
+}
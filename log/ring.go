@@ -0,0 +1,224 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// ring.go provides a logger keeping the last logs in memory
+package gxlog
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Record is a log of RingLogger.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []interface{} // the alternating keys and values of With
+}
+
+func (r Record) String() string {
+	s := r.Time.Format("2006-01-02 15:04:05.000000") + " [" + r.Level.String() + "] " + r.Message
+	if len(r.Fields) > 0 {
+		s += " " + KV(r.Fields...)
+	}
+
+	return s
+}
+
+type logRing struct {
+	mu      sync.Mutex
+	records []Record
+	next    int // the index of the next record
+	full    bool
+}
+
+// RingLogger is a Logger keeping the last logs in memory, to assert the
+// logs in tests or to dump them after a crash. Its loggers of With share
+// its records.
+type RingLogger struct {
+	ring *logRing
+	kv   []interface{}
+}
+
+// NewRingLogger returns a logger keeping the last @capacity logs.
+func NewRingLogger(capacity int) *RingLogger {
+	if capacity <= 0 {
+		panic("@capacity <= 0")
+	}
+
+	return &RingLogger{ring: &logRing{records: make([]Record, capacity)}}
+}
+
+func (l *RingLogger) log(level Level, format string, args []interface{}) {
+	l.record(level, fmt.Sprintf(format, args...), l.kv)
+}
+
+func (l *RingLogger) record(level Level, msg string, fields []interface{}) {
+	r := Record{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+	}
+
+	ring := l.ring
+	ring.mu.Lock()
+	ring.records[ring.next] = r
+	ring.next++
+	if ring.next == len(ring.records) {
+		ring.next, ring.full = 0, true
+	}
+	ring.mu.Unlock()
+}
+
+func (l *RingLogger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, format, args)
+}
+
+func (l *RingLogger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, format, args)
+}
+
+func (l *RingLogger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, format, args)
+}
+
+func (l *RingLogger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, format, args)
+}
+
+func (l *RingLogger) logw(level Level, msg string, kv []interface{}) {
+	l.record(level, msg, append(l.kv[:len(l.kv):len(l.kv)], kv...))
+}
+
+func (l *RingLogger) Debugw(msg string, kv ...interface{}) {
+	l.logw(LevelDebug, msg, kv)
+}
+
+func (l *RingLogger) Infow(msg string, kv ...interface{}) {
+	l.logw(LevelInfo, msg, kv)
+}
+
+func (l *RingLogger) Warnw(msg string, kv ...interface{}) {
+	l.logw(LevelWarn, msg, kv)
+}
+
+func (l *RingLogger) Errorw(msg string, kv ...interface{}) {
+	l.logw(LevelError, msg, kv)
+}
+
+func (l *RingLogger) With(kv ...interface{}) Logger {
+	if len(kv) == 0 {
+		return l
+	}
+
+	return &RingLogger{ring: l.ring, kv: append(l.kv[:len(l.kv):len(l.kv)], kv...)}
+}
+
+// Records returns the logs kept, the oldest first.
+func (l *RingLogger) Records() []Record {
+	ring := l.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	if !ring.full {
+		return append([]Record(nil), ring.records[:ring.next]...)
+	}
+	records := make([]Record, 0, len(ring.records))
+	records = append(records, ring.records[ring.next:]...)
+
+	return append(records, ring.records[:ring.next]...)
+}
+
+// Filter returns the logs kept of @level or above, the oldest first.
+func (l *RingLogger) Filter(level Level) []Record {
+	var records []Record
+	for _, r := range l.Records() {
+		if r.Level >= level {
+			records = append(records, r)
+		}
+	}
+
+	return records
+}
+
+// Clear drops the logs kept.
+func (l *RingLogger) Clear() {
+	ring := l.ring
+	ring.mu.Lock()
+	for i := range ring.records {
+		ring.records[i] = Record{}
+	}
+	ring.next, ring.full = 0, false
+	ring.mu.Unlock()
+}
+
+// DumpTo writes the logs kept to @w a line each, the oldest first.
+func (l *RingLogger) DumpTo(w io.Writer) error {
+	for _, r := range l.Records() {
+		if _, err := io.WriteString(w, r.String()+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type teeLogger struct {
+	primary, shadow Logger
+}
+
+// Tee returns a logger writing the logs to both @primary and @shadow, like
+// a production logger and a RingLogger keeping the debug logs it drops.
+func Tee(primary, shadow Logger) Logger {
+	return &teeLogger{primary: primary, shadow: shadow}
+}
+
+func (t *teeLogger) Debugf(format string, args ...interface{}) {
+	t.primary.Debugf(format, args...)
+	t.shadow.Debugf(format, args...)
+}
+
+func (t *teeLogger) Infof(format string, args ...interface{}) {
+	t.primary.Infof(format, args...)
+	t.shadow.Infof(format, args...)
+}
+
+func (t *teeLogger) Warnf(format string, args ...interface{}) {
+	t.primary.Warnf(format, args...)
+	t.shadow.Warnf(format, args...)
+}
+
+func (t *teeLogger) Errorf(format string, args ...interface{}) {
+	t.primary.Errorf(format, args...)
+	t.shadow.Errorf(format, args...)
+}
+
+func (t *teeLogger) Debugw(msg string, kv ...interface{}) {
+	t.primary.Debugw(msg, kv...)
+	t.shadow.Debugw(msg, kv...)
+}
+
+func (t *teeLogger) Infow(msg string, kv ...interface{}) {
+	t.primary.Infow(msg, kv...)
+	t.shadow.Infow(msg, kv...)
+}
+
+func (t *teeLogger) Warnw(msg string, kv ...interface{}) {
+	t.primary.Warnw(msg, kv...)
+	t.shadow.Warnw(msg, kv...)
+}
+
+func (t *teeLogger) Errorw(msg string, kv ...interface{}) {
+	t.primary.Errorw(msg, kv...)
+	t.shadow.Errorw(msg, kv...)
+}
+
+func (t *teeLogger) With(kv ...interface{}) Logger {
+	return &teeLogger{primary: t.primary.With(kv...), shadow: t.shadow.With(kv...)}
+}
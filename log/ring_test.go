@@ -0,0 +1,91 @@
+package gxlog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func messages(records []Record) string {
+	var msgs []string
+	for _, r := range records {
+		msgs = append(msgs, r.Message)
+	}
+
+	return strings.Join(msgs, ",")
+}
+
+func TestRingLogger(t *testing.T) {
+	l := NewRingLogger(3)
+	if n := len(l.Records()); n != 0 {
+		t.Fatalf("%d records of a new ring", n)
+	}
+
+	l.Debugf("a")
+	l.Warnf("b %d", 1)
+	if s := messages(l.Records()); s != "a,b 1" {
+		t.Fatalf("Records() = %s", s)
+	}
+	l.With("k", "v").Errorf("c")
+	l.Infof("d")
+	l.Debugf("e")
+	if s := messages(l.Records()); s != "c,d,e" {
+		t.Fatalf("Records() = %s", s)
+	}
+	if s := messages(l.Filter(LevelInfo)); s != "c,d" {
+		t.Fatalf("Filter(LevelInfo) = %s", s)
+	}
+
+	var buf bytes.Buffer
+	if err := l.DumpTo(&buf); err != nil {
+		t.Fatalf("DumpTo() = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], ` [ERROR] c {"k":"v"}`) || !strings.HasSuffix(lines[2], " [DEBUG] e") {
+		t.Fatalf("DumpTo() wrote %q", lines)
+	}
+
+	l.Clear()
+	l.Infof("f")
+	if s := messages(l.Records()); s != "f" {
+		t.Fatalf("Records() after Clear() = %s", s)
+	}
+}
+
+func TestRingLoggerConcurrent(t *testing.T) {
+	l := NewRingLogger(100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				l.Infof("%d-%d", i, j)
+				l.Records()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(l.Records()); n != 100 {
+		t.Fatalf("%d records", n)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var r logRecorder
+	ring := NewRingLogger(10)
+	l := Tee(r.logger(), ring).With("id", 1)
+
+	l.Debugf("x %d", 1)
+	l.Warnf("y")
+	if logs := r.get(); fmt.Sprint(logs) != `[DEBUG x 1 {"id":1} WARN y {"id":1}]` {
+		t.Fatalf("primary logs %q", logs)
+	}
+	if s := messages(ring.Records()); s != "x 1,y" {
+		t.Fatalf("shadow records %s", s)
+	}
+}
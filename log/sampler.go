@@ -0,0 +1,285 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// package gxlog is based on log4go.
+// sampler.go provides sampling and rate limited loggers
+package gxlog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/sync"
+)
+
+const (
+	defaultSampleFirst    = 10
+	defaultSampleInterval = time.Second
+)
+
+// SamplerConfig is the config of NewSampler.
+type SamplerConfig struct {
+	First    int           // the logs passed of a format per interval, 10 if <= 0
+	Interval time.Duration // 1s if <= 0
+	// Levels overrides First of the levels in it, e.g. {LevelWarn: 1} for
+	// the reconnecting warnings. The logs of a negative value are all passed.
+	Levels map[Level]int
+}
+
+// sampleKey is a format of a level, the logs of the format are similar
+// whatever their args are.
+type sampleKey struct {
+	level  Level
+	format string
+}
+
+type sampleCount struct {
+	start      time.Time // of the interval
+	n          int
+	suppressed int
+}
+
+// sampleCounts is the counts of a sampler and its loggers of With.
+type sampleCounts struct {
+	mu     sync.Mutex
+	counts map[sampleKey]*sampleCount
+}
+
+type sampler struct {
+	l    Logger
+	conf SamplerConfig
+	*sampleCounts
+}
+
+// NewSampler returns a logger passing the first logs of a level and format
+// to @l in an interval. The others are counted, and a summary like
+// "suppressed 42 similar messages" is logged at the end of the interval.
+func NewSampler(l Logger, conf SamplerConfig) Logger {
+	if conf.First <= 0 {
+		conf.First = defaultSampleFirst
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultSampleInterval
+	}
+	if conf.Levels != nil {
+		levels := make(map[Level]int, len(conf.Levels))
+		for level, first := range conf.Levels {
+			levels[level] = first
+		}
+		conf.Levels = levels
+	}
+
+	return &sampler{
+		l:            l,
+		conf:         conf,
+		sampleCounts: &sampleCounts{counts: make(map[sampleKey]*sampleCount)},
+	}
+}
+
+// first returns the logs passed of a format of @level per interval, or a
+// negative number if the level is not sampled.
+func (s *sampler) first(level Level) int {
+	if first, ok := s.conf.Levels[level]; ok {
+		return first
+	}
+
+	return s.conf.First
+}
+
+// sample returns whether to pass the log of @key.
+func (s *sampler) sample(key sampleKey) bool {
+	first := s.first(key.level)
+	if first < 0 {
+		return true
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[key]
+	if c == nil || (c.suppressed == 0 && now.Sub(c.start) >= s.conf.Interval) {
+		s.counts[key] = &sampleCount{start: now, n: 1}
+		return true
+	}
+	if c.n < first {
+		c.n++
+		return true
+	}
+
+	c.suppressed++
+	if c.suppressed == 1 {
+		time.AfterFunc(s.conf.Interval-now.Sub(c.start), func() {
+			s.flush(key)
+		})
+	}
+
+	return false
+}
+
+// flush logs the summary of @key at the end of its interval.
+func (s *sampler) flush(key sampleKey) {
+	s.mu.Lock()
+	c := s.counts[key]
+	delete(s.counts, key)
+	s.mu.Unlock()
+
+	if c == nil || c.suppressed == 0 {
+		return
+	}
+	logf := s.l.Errorf
+	switch key.level {
+	case LevelDebug:
+		logf = s.l.Debugf
+	case LevelInfo:
+		logf = s.l.Infof
+	case LevelWarn:
+		logf = s.l.Warnf
+	}
+	logf("suppressed %d similar messages of %q", c.suppressed, key.format)
+}
+
+func (s *sampler) Debugf(format string, args ...interface{}) {
+	if s.sample(sampleKey{LevelDebug, format}) {
+		s.l.Debugf(format, args...)
+	}
+}
+
+func (s *sampler) Infof(format string, args ...interface{}) {
+	if s.sample(sampleKey{LevelInfo, format}) {
+		s.l.Infof(format, args...)
+	}
+}
+
+func (s *sampler) Warnf(format string, args ...interface{}) {
+	if s.sample(sampleKey{LevelWarn, format}) {
+		s.l.Warnf(format, args...)
+	}
+}
+
+func (s *sampler) Errorf(format string, args ...interface{}) {
+	if s.sample(sampleKey{LevelError, format}) {
+		s.l.Errorf(format, args...)
+	}
+}
+
+func (s *sampler) Debugw(msg string, kv ...interface{}) {
+	if s.sample(sampleKey{LevelDebug, msg}) {
+		s.l.Debugw(msg, kv...)
+	}
+}
+
+func (s *sampler) Infow(msg string, kv ...interface{}) {
+	if s.sample(sampleKey{LevelInfo, msg}) {
+		s.l.Infow(msg, kv...)
+	}
+}
+
+func (s *sampler) Warnw(msg string, kv ...interface{}) {
+	if s.sample(sampleKey{LevelWarn, msg}) {
+		s.l.Warnw(msg, kv...)
+	}
+}
+
+func (s *sampler) Errorw(msg string, kv ...interface{}) {
+	if s.sample(sampleKey{LevelError, msg}) {
+		s.l.Errorw(msg, kv...)
+	}
+}
+
+// With returns a sampler of l.With(kv...), sampling its logs with s, as
+// the logs of a format are similar whatever their fields are.
+func (s *sampler) With(kv ...interface{}) Logger {
+	return &sampler{l: s.l.With(kv...), conf: s.conf, sampleCounts: s.sampleCounts}
+}
+
+type rateLimited struct {
+	l       Logger
+	limiter *gxsync.RateLimiter
+	dropped *int64
+}
+
+// NewRateLimited returns a logger passing at most @perSecond logs a second
+// to @l, with bursts of as many. The number of the logs dropped is logged
+// before the next one passed.
+func NewRateLimited(l Logger, perSecond float64) Logger {
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimited{
+		l:       l,
+		limiter: gxsync.NewRateLimiter(perSecond, burst),
+		dropped: new(int64),
+	}
+}
+
+func (r *rateLimited) allow() bool {
+	if !r.limiter.Allow() {
+		atomic.AddInt64(r.dropped, 1)
+		return false
+	}
+	if n := atomic.SwapInt64(r.dropped, 0); n > 0 {
+		r.l.Warnf("rate limited logger dropped %d messages", n)
+	}
+
+	return true
+}
+
+func (r *rateLimited) Debugf(format string, args ...interface{}) {
+	if r.allow() {
+		r.l.Debugf(format, args...)
+	}
+}
+
+func (r *rateLimited) Infof(format string, args ...interface{}) {
+	if r.allow() {
+		r.l.Infof(format, args...)
+	}
+}
+
+func (r *rateLimited) Warnf(format string, args ...interface{}) {
+	if r.allow() {
+		r.l.Warnf(format, args...)
+	}
+}
+
+func (r *rateLimited) Errorf(format string, args ...interface{}) {
+	if r.allow() {
+		r.l.Errorf(format, args...)
+	}
+}
+
+func (r *rateLimited) Debugw(msg string, kv ...interface{}) {
+	if r.allow() {
+		r.l.Debugw(msg, kv...)
+	}
+}
+
+func (r *rateLimited) Infow(msg string, kv ...interface{}) {
+	if r.allow() {
+		r.l.Infow(msg, kv...)
+	}
+}
+
+func (r *rateLimited) Warnw(msg string, kv ...interface{}) {
+	if r.allow() {
+		r.l.Warnw(msg, kv...)
+	}
+}
+
+func (r *rateLimited) Errorw(msg string, kv ...interface{}) {
+	if r.allow() {
+		r.l.Errorw(msg, kv...)
+	}
+}
+
+// With returns a logger of r.l.With(kv...), sharing the rate of r.
+func (r *rateLimited) With(kv ...interface{}) Logger {
+	return &rateLimited{l: r.l.With(kv...), limiter: r.limiter, dropped: r.dropped}
+}
@@ -0,0 +1,146 @@
+package gxlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logRecorder struct {
+	sync.Mutex
+	logs []string
+}
+
+func (r *logRecorder) logger() Logger {
+	return NewFromFunc(func(level Level, msg string) {
+		r.Lock()
+		r.logs = append(r.logs, level.String()+" "+msg)
+		r.Unlock()
+	})
+}
+
+func (r *logRecorder) get() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]string(nil), r.logs...)
+}
+
+func TestSampler(t *testing.T) {
+	var r logRecorder
+	l := NewSampler(r.logger(), SamplerConfig{First: 2, Interval: 50 * time.Millisecond})
+
+	// the args do not make the logs different
+	for i := 0; i < 10; i++ {
+		l.Warnf("zk %s lost, retry %d", "127.0.0.1:2181", i)
+	}
+	l.Errorf("zk %s lost, retry %d", "127.0.0.1:2181", 0)
+	l.Warnf("another %d", 1)
+	want := []string{
+		"WARN zk 127.0.0.1:2181 lost, retry 0",
+		"WARN zk 127.0.0.1:2181 lost, retry 1",
+		"ERROR zk 127.0.0.1:2181 lost, retry 0",
+		"WARN another 1",
+	}
+	if logs := r.get(); strings.Join(logs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("logs %q, want %q", logs, want)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	want = append(want, `WARN suppressed 8 similar messages of "zk %s lost, retry %d"`)
+	if logs := r.get(); strings.Join(logs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("logs %q, want %q", logs, want)
+	}
+
+	// a new interval
+	l.Warnf("zk %s lost, retry %d", "127.0.0.1:2181", 10)
+	if logs := r.get(); logs[len(logs)-1] != "WARN zk 127.0.0.1:2181 lost, retry 10" {
+		t.Fatalf("logs %q", logs)
+	}
+}
+
+func TestSamplerConcurrent(t *testing.T) {
+	var r logRecorder
+	l := NewSampler(r.logger(), SamplerConfig{First: 5, Interval: 50 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Errorf("failed %d", j)
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(100 * time.Millisecond)
+
+	logs := r.get()
+	if len(logs) != 6 || logs[5] != `ERROR suppressed 795 similar messages of "failed %d"` {
+		t.Fatalf("logs %q", logs)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	var r logRecorder
+	l := NewRateLimited(r.logger(), 10)
+
+	for i := 0; i < 15; i++ {
+		l.With("i", i).Infof("event")
+	}
+	if logs := r.get(); len(logs) != 10 {
+		t.Fatalf("%d logs passed", len(logs))
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	l.Infof("event %d", 15)
+	logs := r.get()
+	want := []string{"WARN rate limited logger dropped 5 messages", "INFO event 15"}
+	if got := logs[len(logs)-2:]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("logs %q, want %q", got, want)
+	}
+}
+
+func TestSamplerLevels(t *testing.T) {
+	var r logRecorder
+	l := NewSampler(r.logger(), SamplerConfig{
+		First:    3,
+		Interval: 50 * time.Millisecond,
+		Levels:   map[Level]int{LevelWarn: 1, LevelError: -1},
+	})
+
+	for i := 0; i < 10; i++ {
+		l.Warnw("zk reconnecting", "retry", i)
+		l.Infow("zk reconnecting", "retry", i)
+		l.Errorf("zk lost %d", i)
+	}
+	count := func(prefix string) int {
+		var n int
+		for _, log := range r.get() {
+			if strings.HasPrefix(log, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count("WARN zk reconnecting"); n != 1 {
+		t.Fatalf("%d warnings passed, want 1", n)
+	}
+	if n := count("INFO zk reconnecting"); n != 3 {
+		t.Fatalf("%d infos passed, want 3", n)
+	}
+	if n := count("ERROR zk lost"); n != 10 {
+		t.Fatalf("%d errors passed, want 10", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := count(`WARN suppressed 9 similar messages of "zk reconnecting"`); n != 1 {
+		t.Fatalf("logs %q", r.get())
+	}
+	if n := count(`INFO suppressed 7 similar messages of "zk reconnecting"`); n != 1 {
+		t.Fatalf("logs %q", r.get())
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+// cgroups are linux only.
+
+func (p *DarwinProcess) Cgroups() ([]CgroupInfo, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *DarwinProcess) CgroupLimits() (*CgroupLimits, error) {
+	return nil, ErrNotImplemented
+}
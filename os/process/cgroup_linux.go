@@ -0,0 +1,248 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// v1 memory.limit_in_bytes is PAGE_COUNTER_MAX rounded down to the page
+// size when unlimited.
+const cgroupV1MemoryUnlimited = 1 << 62
+
+func (p *LinuxProcess) Cgroups() ([]CgroupInfo, error) {
+	data, err := readProcFile(p.pid, "cgroup")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCgroups(data)
+}
+
+func (p *LinuxProcess) CgroupLimits() (*CgroupLimits, error) {
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCgroupMounts(data).limits(cgroups), nil
+}
+
+// parseCgroups parses /proc/<pid>/cgroup, each line is
+// "hierarchy-ID:controller-list:cgroup-path".
+func parseCgroups(data []byte) ([]CgroupInfo, error) {
+	var cgroups []CgroupInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid cgroup line %q", line)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cgroup line %q", line)
+		}
+
+		cg := CgroupInfo{ID: id, Path: fields[2]}
+		if fields[1] != "" {
+			cg.Controllers = strings.Split(fields[1], ",")
+		}
+		cgroups = append(cgroups, cg)
+	}
+
+	return cgroups, nil
+}
+
+type cgroupMount struct {
+	root  string // the cgroup path mounted
+	point string
+}
+
+// dir returns the directory of cgroup @path, or "" if it is not under the
+// mount, e.g. a cgroup of the parent cgroup namespace.
+func (m *cgroupMount) dir(path string) string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+
+	return filepath.Join(m.point, rel)
+}
+
+type cgroupMounts struct {
+	unified *cgroupMount
+	v1      map[string]*cgroupMount // keyed by controller
+}
+
+// parseCgroupMounts finds the cgroup mounts in /proc/<pid>/mountinfo, each
+// line is "id parent major:minor root mount-point options [optional...] -
+// fstype source super-options".
+func parseCgroupMounts(data []byte) *cgroupMounts {
+	mounts := &cgroupMounts{v1: make(map[string]*cgroupMount)}
+	for _, line := range strings.Split(string(data), "\n") {
+		i := strings.Index(line, " - ")
+		if i < 0 {
+			continue
+		}
+		fields, post := strings.Fields(line[:i]), strings.Fields(line[i+3:])
+		if len(fields) < 5 || len(post) < 3 {
+			continue
+		}
+
+		m := &cgroupMount{root: fields[3], point: fields[4]}
+		switch post[0] {
+		case "cgroup2":
+			mounts.unified = m
+		case "cgroup":
+			for _, opt := range strings.Split(post[2], ",") {
+				if opt != "rw" && opt != "ro" {
+					mounts.v1[opt] = m
+				}
+			}
+		}
+	}
+
+	return mounts
+}
+
+// limits resolves the limits of @cgroups. Cgroup v2 files are preferred,
+// and v1 ones are used for the controllers the unified hierarchy lacks in
+// the hybrid layout.
+func (m *cgroupMounts) limits(cgroups []CgroupInfo) *CgroupLimits {
+	l := &CgroupLimits{MemoryMax: CgroupUnlimited, CPUMax: CgroupUnlimited}
+	var (
+		memFound, cpuFound bool
+	)
+	for _, cg := range cgroups {
+		if cg.ID == 0 && m.unified != nil {
+			m.walk(m.unified, cg.Path, func(dir string) {
+				if v, ok := readCgroupMemoryMax(dir); ok {
+					memFound = true
+					l.MemoryMax = minLimit(l.MemoryMax, v)
+				}
+				if v, ok := readCgroupCPUMax(dir); ok {
+					cpuFound = true
+					l.CPUMax = minCPULimit(l.CPUMax, v)
+				}
+			})
+		}
+	}
+
+	for _, cg := range cgroups {
+		for _, c := range cg.Controllers {
+			mount := m.v1[c]
+			if mount == nil {
+				continue
+			}
+			switch {
+			case c == "memory" && !memFound:
+				m.walk(mount, cg.Path, func(dir string) {
+					if v, ok := readCgroupInt(filepath.Join(dir, "memory.limit_in_bytes")); ok && v < cgroupV1MemoryUnlimited {
+						l.MemoryMax = minLimit(l.MemoryMax, v)
+					}
+				})
+			case c == "cpu" && !cpuFound:
+				m.walk(mount, cg.Path, func(dir string) {
+					quota, ok1 := readCgroupInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+					period, ok2 := readCgroupInt(filepath.Join(dir, "cpu.cfs_period_us"))
+					if ok1 && ok2 && quota > 0 && period > 0 {
+						l.CPUMax = minCPULimit(l.CPUMax, float64(quota)/float64(period))
+					}
+				})
+			}
+		}
+	}
+
+	return l
+}
+
+// walk calls @fn with the directory of cgroup @path and its ancestors up to
+// the mount point.
+func (m *cgroupMounts) walk(mount *cgroupMount, path string, fn func(dir string)) {
+	dir := mount.dir(path)
+	if dir == "" {
+		return
+	}
+	for {
+		fn(dir)
+		if dir == mount.point || !strings.HasPrefix(dir, mount.point) {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readCgroupMemoryMax reads v2 memory.max, "max" or bytes.
+func readCgroupMemoryMax(dir string) (int64, bool) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "memory.max"))
+	if err != nil {
+		return 0, false
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return CgroupUnlimited, true
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+
+	return v, err == nil
+}
+
+// readCgroupCPUMax reads v2 cpu.max, "$MAX $PERIOD" where $MAX may be "max".
+func readCgroupCPUMax(dir string) (float64, bool) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, false
+	}
+	if fields[0] == "max" {
+		return CgroupUnlimited, true
+	}
+	quota, err1 := strconv.ParseInt(fields[0], 10, 64)
+	period, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil || period <= 0 {
+		return 0, false
+	}
+
+	return float64(quota) / float64(period), true
+}
+
+func readCgroupInt(path string) (int64, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+
+	return v, err == nil
+}
+
+func minLimit(a, b int64) int64 {
+	if a == CgroupUnlimited || (b != CgroupUnlimited && b < a) {
+		return b
+	}
+	return a
+}
+
+func minCPULimit(a, b float64) float64 {
+	if a == CgroupUnlimited || (b != CgroupUnlimited && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,154 @@
+package gxprocess
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMountInfo = `25 30 0:23 / /sys rw,nosuid - sysfs sysfs rw
+31 25 0:27 / /sys/fs/cgroup ro - tmpfs tmpfs ro,mode=755
+32 31 0:28 / /sys/fs/cgroup/unified rw,nosuid shared:10 - cgroup2 cgroup2 rw,nsdelegate
+35 31 0:31 / /sys/fs/cgroup/memory rw,nosuid shared:15 - cgroup cgroup rw,memory
+36 31 0:32 / /sys/fs/cgroup/cpu,cpuacct rw,nosuid shared:16 - cgroup cgroup rw,cpu,cpuacct
+`
+
+func TestParseCgroups(t *testing.T) {
+	cgroups, err := parseCgroups([]byte("5:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n1:name=systemd:/init.scope\n0::/user.slice\n"))
+	if err != nil {
+		t.Fatalf("parseCgroups() = error %v", err)
+	}
+	if len(cgroups) != 4 {
+		t.Fatalf("parseCgroups() = %+v", cgroups)
+	}
+	if c := cgroups[0]; c.ID != 5 || len(c.Controllers) != 2 || c.Controllers[1] != "cpuacct" || c.Path != "/docker/abc" {
+		t.Fatalf("cgroups[0] = %+v", c)
+	}
+	if c := cgroups[3]; c.ID != 0 || c.Controllers != nil || c.Path != "/user.slice" {
+		t.Fatalf("cgroups[3] = %+v", c)
+	}
+
+	if _, err = parseCgroups([]byte("x:memory:/")); err == nil {
+		t.Fatal("parseCgroups() on broken cgroup should fail")
+	}
+}
+
+func TestParseCgroupMounts(t *testing.T) {
+	m := parseCgroupMounts([]byte(testMountInfo))
+	if m.unified == nil || m.unified.point != "/sys/fs/cgroup/unified" {
+		t.Fatalf("unified = %+v", m.unified)
+	}
+	if c := m.v1["cpuacct"]; c == nil || c.point != "/sys/fs/cgroup/cpu,cpuacct" {
+		t.Fatalf("cpuacct = %+v", c)
+	}
+	if m.v1["memory"] == nil || m.v1["tmpfs"] != nil {
+		t.Fatalf("v1 = %+v", m.v1)
+	}
+}
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroupLimitsV2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxcgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the parent limits memory, the child limits cpu
+	writeCgroupFiles(t, dir, map[string]string{
+		"memory.max":              "max\n",
+		"cpu.max":                 "max 100000\n",
+		"kube/memory.max":         "1048576\n",
+		"kube/cpu.max":            "max 100000\n",
+		"kube/pod/memory.max":     "max\n",
+		"kube/pod/cpu.max":        "150000 100000\n",
+		"kube/pod/ctr/memory.max": "2097152\n",
+		"kube/pod/ctr/cpu.max":    "max 100000\n",
+		"other/memory.max":        "1\n",
+	})
+	m := &cgroupMounts{unified: &cgroupMount{root: "/", point: dir}}
+	l := m.limits([]CgroupInfo{{Path: "/kube/pod/ctr"}})
+	if l.MemoryMax != 1048576 || l.CPUMax != 1.5 {
+		t.Fatalf("limits() = %+v", l)
+	}
+
+	// the root cgroup, or a cgroup namespace rooted at the container whose
+	// ancestors are not visible
+	if l = m.limits([]CgroupInfo{{Path: "/"}}); l.MemoryMax != CgroupUnlimited || l.CPUMax != CgroupUnlimited {
+		t.Fatalf("limits() of the root cgroup = %+v", l)
+	}
+	m.unified.point = filepath.Join(dir, "kube/pod/ctr")
+	if l = m.limits([]CgroupInfo{{Path: "/"}}); l.MemoryMax != 2097152 || l.CPUMax != CgroupUnlimited {
+		t.Fatalf("limits() in cgroup namespace = %+v", l)
+	}
+}
+
+func TestCgroupLimitsHybrid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxcgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the unified hierarchy has no controllers enabled
+	writeCgroupFiles(t, dir, map[string]string{
+		"unified/docker/abc/cgroup.procs":         "",
+		"memory/memory.limit_in_bytes":            "9223372036854771712\n",
+		"memory/docker/abc/memory.limit_in_bytes": "536870912\n",
+		"cpu/cpu.cfs_quota_us":                    "-1\n",
+		"cpu/cpu.cfs_period_us":                   "100000\n",
+		"cpu/docker/abc/cpu.cfs_quota_us":         "50000\n",
+		"cpu/docker/abc/cpu.cfs_period_us":        "100000\n",
+	})
+	m := &cgroupMounts{
+		unified: &cgroupMount{root: "/", point: filepath.Join(dir, "unified")},
+		v1: map[string]*cgroupMount{
+			"memory":  {root: "/", point: filepath.Join(dir, "memory")},
+			"cpu":     {root: "/", point: filepath.Join(dir, "cpu")},
+			"cpuacct": {root: "/", point: filepath.Join(dir, "cpu")},
+		},
+	}
+	l := m.limits([]CgroupInfo{
+		{ID: 3, Controllers: []string{"cpu", "cpuacct"}, Path: "/docker/abc"},
+		{ID: 2, Controllers: []string{"memory"}, Path: "/docker/abc"},
+		{ID: 0, Path: "/docker/abc"},
+	})
+	if l.MemoryMax != 536870912 || l.CPUMax != 0.5 {
+		t.Fatalf("limits() = %+v", l)
+	}
+
+	if l = m.limits([]CgroupInfo{{ID: 2, Controllers: []string{"memory"}, Path: "/"}}); l.MemoryMax != CgroupUnlimited {
+		t.Fatalf("limits() of the root cgroup = %+v", l)
+	}
+}
+
+// SelfCgroupLimits should work both in and out of containers.
+func TestSelfCgroupLimits(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cgroup"); err != nil {
+		t.Skip("no cgroup support")
+	}
+
+	l, err := SelfCgroupLimits()
+	if err != nil {
+		t.Fatalf("SelfCgroupLimits() = error %v", err)
+	}
+	if l.MemoryMax != CgroupUnlimited && l.MemoryMax <= 0 {
+		t.Fatalf("MemoryMax = %d", l.MemoryMax)
+	}
+	if l.CPUMax != CgroupUnlimited && l.CPUMax <= 0 {
+		t.Fatalf("CPUMax = %v", l.CPUMax)
+	}
+	t.Logf("limits: %+v", l)
+}
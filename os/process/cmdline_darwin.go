@@ -0,0 +1,91 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_KERN_PROCARGS2          = 49
+	_PROC_PIDVNODEPATHINFO   = 9
+	_VNODE_INFO_SIZE         = 152
+	_MAXPATHLEN              = 1024
+	_VNODE_INFO_PATH_SIZE    = _VNODE_INFO_SIZE + _MAXPATHLEN
+	_PROC_VNODEPATHINFO_SIZE = 2 * _VNODE_INFO_PATH_SIZE
+)
+
+// procArgs gets the arguments and environment variables of the process.
+// The layout of KERN_PROCARGS2 is
+// argc, exec path, NULs, argv[0]...argv[argc-1], env[0]... all NUL-terminated.
+func (p *DarwinProcess) procArgs() ([]string, []string, error) {
+	data, err := darwinSysctl([]int32{_CTRL_KERN, _KERN_PROCARGS2, int32(p.pid)})
+	switch {
+	case err == syscall.EINVAL || err == syscall.ESRCH:
+		// EINVAL is returned for both gone processes and ones of other users
+		if p.alive() == ErrProcessDone {
+			return nil, nil, ErrProcessDone
+		}
+		return nil, nil, ErrPermission
+	case err == syscall.EPERM:
+		return nil, nil, ErrPermission
+	case err != nil:
+		return nil, nil, err
+	case len(data) < 4:
+		return nil, nil, fmt.Errorf("invalid procargs of %d", p.pid)
+	}
+
+	argc := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+	// skip the exec path and its padding
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("invalid procargs of %d", p.pid)
+	}
+	data = bytes.TrimLeft(data[i:], "\x00")
+
+	strs := splitNUL(data)
+	if len(strs) < argc {
+		argc = len(strs)
+	}
+
+	return strs[:argc], strs[argc:], nil
+}
+
+func (p *DarwinProcess) Cmdline() ([]string, error) {
+	args, _, err := p.procArgs()
+	return args, err
+}
+
+func (p *DarwinProcess) Environ() (map[string]string, error) {
+	_, env, err := p.procArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEnviron(env), nil
+}
+
+func (p *DarwinProcess) Cwd() (string, error) {
+	var info [_PROC_VNODEPATHINFO_SIZE]byte
+	err := darwinPidInfo(p.pid, _PROC_PIDVNODEPATHINFO, unsafe.Pointer(&info[0]), uintptr(len(info)))
+	if err != nil {
+		return "", err
+	}
+
+	// the path of struct vnode_info_path pvi_cdir
+	path := info[_VNODE_INFO_SIZE:_VNODE_INFO_PATH_SIZE]
+	if i := bytes.IndexByte(path, 0); i >= 0 {
+		path = path[:i]
+	}
+
+	return string(path), nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"os"
+)
+
+func (p *LinuxProcess) Cmdline() ([]string, error) {
+	data, err := readProcFile(p.pid, "cmdline")
+	if err != nil {
+		return nil, err
+	}
+
+	return splitNUL(data), nil
+}
+
+func (p *LinuxProcess) Environ() (map[string]string, error) {
+	data, err := readProcFile(p.pid, "environ")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEnviron(splitNUL(data)), nil
+}
+
+func (p *LinuxProcess) Cwd() (string, error) {
+	cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", p.pid))
+	if err != nil {
+		return "", procError(err)
+	}
+
+	return cwd, nil
+}
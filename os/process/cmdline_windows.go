@@ -0,0 +1,22 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+// Reading them of another process needs to parse its PEB, which is not
+// supported yet.
+
+func (p *WindowsProcess) Cmdline() ([]string, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *WindowsProcess) Environ() (map[string]string, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *WindowsProcess) Cwd() (string, error) {
+	return "", ErrNotImplemented
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+type containerPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var (
+	containerPatternsLock sync.RWMutex
+	// the first submatch is the container id
+	containerPatterns = []containerPattern{
+		// the systemd cgroup driver, e.g.
+		// /kubepods.slice/kubepods-pod<uid>.slice/cri-containerd-<id>.scope
+		{"systemd", regexp.MustCompile(`(?:docker|cri-containerd|crio|libpod)-([0-9a-f]{64})\.scope$`)},
+		// containerd in systemd slices with the cgroupfs driver, e.g.
+		// /system.slice/containerd.service/kubepods-pod<uid>.slice:cri-containerd:<id>
+		{"containerd", regexp.MustCompile(`:(?:cri-containerd|crio|docker):([0-9a-f]{64})$`)},
+		// the cgroupfs driver, e.g. /docker/<id> and
+		// /kubepods/besteffort/pod<uid>/<id>
+		{"cgroupfs", regexp.MustCompile(`^/(?:docker|kubepods|crio|libpod_parent|ecs|lxc)(?:/[^/]+)*/([0-9a-f]{64})$`)},
+	}
+)
+
+// RegisterContainerPattern adds a cgroup path pattern of the container
+// runtime @name for ContainerID, whose first submatch is the container id.
+// Patterns registered later take precedence.
+func RegisterContainerPattern(name string, re *regexp.Regexp) error {
+	if re.NumSubexp() < 1 {
+		return fmt.Errorf("container pattern %s has no submatch", re)
+	}
+
+	containerPatternsLock.Lock()
+	defer containerPatternsLock.Unlock()
+	containerPatterns = append([]containerPattern{{name, re}}, containerPatterns...)
+
+	return nil
+}
+
+// containerID returns the container id in the cgroup paths of @cgroups, or
+// "" if none is found.
+func containerID(cgroups []CgroupInfo) string {
+	containerPatternsLock.RLock()
+	defer containerPatternsLock.RUnlock()
+
+	for _, pattern := range containerPatterns {
+		for _, cg := range cgroups {
+			if m := pattern.re.FindStringSubmatch(cg.Path); m != nil {
+				return m[1]
+			}
+		}
+	}
+
+	return ""
+}
+
+// InContainer checks whether the current process runs in a container.
+func InContainer() bool {
+	return inContainer()
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"os"
+	"strings"
+)
+
+// ContainerID returns "" if the cgroup layout is unknown, e.g. the process
+// is in a cgroup namespace whose root is the container.
+func (p *LinuxProcess) ContainerID() (string, error) {
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return "", err
+	}
+
+	return containerID(cgroups), nil
+}
+
+// inContainer checks, in order, the files created by docker and podman,
+// the cgroups, and whether pid 1 is another pid outside the pid namespace.
+func inContainer() bool {
+	for _, path := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	self := &LinuxProcess{pid: os.Getpid()}
+	if id, _ := self.ContainerID(); id != "" {
+		return true
+	}
+
+	// the first line is "comm (pid, #threads: n)", pid is of the root pid
+	// namespace
+	data, err := readProcFile(1, "sched")
+	if err != nil {
+		return false
+	}
+	line := strings.SplitN(string(data), "\n", 2)[0]
+	if i := strings.LastIndex(line, " ("); i >= 0 {
+		return !strings.HasPrefix(line[i:], " (1,")
+	}
+
+	return false
+}
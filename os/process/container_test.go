@@ -0,0 +1,62 @@
+package gxprocess
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestContainerID(t *testing.T) {
+	id := strings.Repeat("0123456789abcdef", 4)
+	tests := []struct {
+		path string
+		id   string
+	}{
+		// docker
+		{"/docker/" + id, id},
+		{"/system.slice/docker-" + id + ".scope", id},
+		// kubernetes with docker or containerd and the cgroupfs driver
+		{"/kubepods/besteffort/pod5c1f4ab2-7c3d-11e8-a71c-42010a8e0002/" + id, id},
+		{"/kubepods/pod5c1f4ab2-7c3d-11e8-a71c-42010a8e0002/" + id, id},
+		// kubernetes with containerd and the systemd driver
+		{"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod5c1f4ab2_7c3d.slice/cri-containerd-" + id + ".scope", id},
+		{"/system.slice/containerd.service/kubepods-besteffort-pod5c1f4ab2_7c3d.slice:cri-containerd:" + id, id},
+		// cri-o
+		{"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod5c1f4ab2_7c3d.slice/crio-" + id + ".scope", id},
+		{"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod5c1f4ab2_7c3d.slice/crio-conmon-" + id + ".scope", ""},
+		// podman
+		{"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id + ".scope", id},
+		{"/machine.slice/libpod-" + id + ".scope/container", ""},
+		// ecs
+		{"/ecs/5a0d5ceddf6c44a1928d9c9b2e1c1ea7/" + id, id},
+		// unknown
+		{"/", ""},
+		{"/init.scope", ""},
+		{"/user.slice/user-1000.slice/session-2.scope", ""},
+		{"/docker/" + id[:12], ""},
+	}
+
+	for _, test := range tests {
+		got := containerID([]CgroupInfo{{ID: 4, Controllers: []string{"memory"}, Path: "/"}, {Path: test.path}})
+		if got != test.id {
+			t.Errorf("containerID(%q) = %q, want %q", test.path, got, test.id)
+		}
+	}
+}
+
+func TestRegisterContainerPattern(t *testing.T) {
+	saved := containerPatterns
+	defer func() { containerPatterns = saved }()
+
+	if err := RegisterContainerPattern("bad", regexp.MustCompile(`^/garden/`)); err == nil {
+		t.Fatal("RegisterContainerPattern() of a pattern without submatch should fail")
+	}
+	if err := RegisterContainerPattern("garden", regexp.MustCompile(`^/garden/([0-9a-f-]{36})$`)); err != nil {
+		t.Fatalf("RegisterContainerPattern() = error %v", err)
+	}
+
+	id := "5c1f4ab2-7c3d-11e8-a71c-42010a8e0002"
+	if got := containerID([]CgroupInfo{{Path: "/garden/" + id}}); got != id {
+		t.Fatalf("containerID() = %q, want %q", got, id)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+func (p *WindowsProcess) ContainerID() (string, error) {
+	return "", ErrNotImplemented
+}
+
+func inContainer() bool {
+	return false
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// machTime converts mach absolute time units into duration. They are
+// nanoseconds on intel, and mach_timebase_info is 125/3 on apple silicon.
+func machTime(t uint64) time.Duration {
+	if runtime.GOARCH == "arm64" {
+		return time.Duration(t * 125 / 3)
+	}
+
+	return time.Duration(t)
+}
+
+func (p *DarwinProcess) CPUTimes() (user, system time.Duration, err error) {
+	var info procTaskInfo
+	err = darwinPidInfo(p.pid, _PROC_PIDTASKINFO, unsafe.Pointer(&info), unsafe.Sizeof(info))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return machTime(info.TotalUser), machTime(info.TotalSystem), nil
+}
+
+func (p *DarwinProcess) CPUPercent(interval time.Duration) (float64, error) {
+	return cpuPercent(p, interval)
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"strconv"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of times in /proc/<pid>/stat. It is 100
+// on all architectures linux supports, and sysconf(_SC_CLK_TCK) needs cgo.
+const clockTicks = 100
+
+func (p *LinuxProcess) CPUTimes() (user, system time.Duration, err error) {
+	data, err := readProcFile(p.pid, "stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	_, fields, err := parseStat(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// utime & stime are the 14th & 15th fields
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return ticksToDuration(utime), ticksToDuration(stime), nil
+}
+
+func ticksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * time.Second / clockTicks
+}
+
+func (p *LinuxProcess) CPUPercent(interval time.Duration) (float64, error) {
+	return cpuPercent(p, interval)
+}
@@ -0,0 +1,35 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"syscall"
+	"time"
+)
+
+func filetimeToDuration(ft *syscall.Filetime) time.Duration {
+	// in 100-nanosecond intervals
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
+
+func (p *WindowsProcess) CPUTimes() (user, system time.Duration, err error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var creation, exit, kernel, usr syscall.Filetime
+	if err = syscall.GetProcessTimes(h, &creation, &exit, &kernel, &usr); err != nil {
+		return 0, 0, err
+	}
+
+	return filetimeToDuration(&usr), filetimeToDuration(&kernel), nil
+}
+
+func (p *WindowsProcess) CPUPercent(interval time.Duration) (float64, error) {
+	return cpuPercent(p, interval)
+}
@@ -0,0 +1,35 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"time"
+)
+
+// daemonEnv marks the re-executed daemon process, its value is the fd of
+// the readiness pipe.
+const daemonEnv = "GXPROCESS_DAEMON_READY_FD"
+
+const defaultDaemonReadyTimeout = 10 * time.Second
+
+// DaemonOptions configures Daemonize.
+type DaemonOptions struct {
+	// WorkDir is the working directory of the daemon, "/" by default.
+	WorkDir string
+	// Umask is the file mode creation mask of the daemon.
+	Umask int
+	// Stdin, Stdout and Stderr are the paths the stdio of the daemon are
+	// redirected to, /dev/null by default. Stdout and Stderr are appended.
+	// Relative paths here and of PidFile are relative to the working
+	// directory of the parent.
+	Stdin  string
+	Stdout string
+	Stderr string
+	// PidFile is written by the daemon with WritePidFile if not empty.
+	PidFile string
+	// ReadyTimeout is how long the parent waits for the daemon to start,
+	// 10s by default.
+	ReadyTimeout time.Duration
+}
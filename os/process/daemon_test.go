@@ -0,0 +1,86 @@
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const daemonTestDir = "GXPROCESS_DAEMON_TEST_DIR"
+
+// TestDaemonHelper is the daemon of TestDaemonize.
+func TestDaemonHelper(t *testing.T) {
+	dir := os.Getenv(daemonTestDir)
+	if dir == "" {
+		t.Skip("run by TestDaemonize")
+	}
+
+	err := Daemonize(DaemonOptions{
+		WorkDir: dir,
+		Stdout:  filepath.Join(dir, "daemon.log"),
+		PidFile: filepath.Join(dir, "daemon.pid"),
+	})
+	if err != nil {
+		os.Exit(2)
+	}
+	cwd, _ := os.Getwd()
+	os.Stdout.WriteString("daemon started in " + cwd + "\n")
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func TestDaemonize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxdaemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDaemonHelper$")
+	cmd.Env = append(os.Environ(), daemonTestDir+"="+dir)
+	// the parent exits once the daemon is ready
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("daemonize: %v, %s", err, out)
+	}
+
+	p, err := ReadPidFile(filepath.Join(dir, "daemon.pid"))
+	if err != nil {
+		t.Fatalf("ReadPidFile() = error %v", err)
+	}
+	defer func() {
+		p.Kill()
+		p.WaitExit(context.Background(), 10*time.Millisecond)
+	}()
+	if p.Pid() == cmd.Process.Pid {
+		t.Fatal("the daemon is the parent")
+	}
+
+	var log []byte
+	for i := 0; i < 100; i++ {
+		if log, _ = ioutil.ReadFile(filepath.Join(dir, "daemon.log")); len(log) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if want := "daemon started in " + dir + "\n"; string(log) != want {
+		t.Fatalf("daemon log = %q, want %q", log, want)
+	}
+
+	// a second daemon fails to lock the pid file
+	cmd = exec.Command(os.Args[0], "-test.run=^TestDaemonHelper$")
+	cmd.Env = append(os.Environ(), daemonTestDir+"="+dir)
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), ErrPidFileLocked.Error()) {
+		t.Fatalf("second daemonize = %v, %s", err, out)
+	}
+}
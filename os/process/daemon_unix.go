@@ -0,0 +1,187 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// daemonRelease keeps the pid file of the daemon locked until it exits.
+var daemonRelease func() error
+
+// Daemonize detaches the current process from its controlling terminal.
+// As Go can not fork safely, it re-executes os.Args in the background and
+// exits once the new process, the daemon, has started, with status 0, or
+// the status of the daemon if it fails to start.
+//
+// In the daemon Daemonize starts a new session, changes the working
+// directory and umask, writes the pid file, and returns nil. The code
+// before Daemonize runs in both processes and should have no side effects.
+func Daemonize(opts DaemonOptions) error {
+	if fd := os.Getenv(daemonEnv); fd != "" {
+		return daemonChild(opts, fd)
+	}
+
+	status, err := daemonParent(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemonize: %v\n", err)
+	}
+	os.Exit(status)
+	return nil
+}
+
+// daemonParent starts the daemon and returns the exit status of the parent.
+func daemonParent(opts DaemonOptions) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 1, err
+	}
+
+	stdio := make([]*os.File, 0, 3)
+	defer func() {
+		for _, f := range stdio {
+			f.Close()
+		}
+	}()
+	for i, path := range []string{opts.Stdin, opts.Stdout, opts.Stderr} {
+		flag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		if i == 0 {
+			flag = os.O_RDONLY
+		}
+		if path == "" {
+			path = os.DevNull
+		}
+		f, err := os.OpenFile(path, flag, 0644)
+		if err != nil {
+			return 1, err
+		}
+		stdio = append(stdio, f)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 1, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Args[0] = os.Args[0]
+	// the write end is the first extra file, fd 3
+	cmd.Env = append(os.Environ(), daemonEnv+"=3")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio[0], stdio[1], stdio[2]
+	cmd.ExtraFiles = []*os.File{w}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return 1, err
+	}
+
+	timeout := opts.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultDaemonReadyTimeout
+	}
+	r.SetReadDeadline(time.Now().Add(timeout))
+	msg, err := readDaemonStatus(r)
+	if err == nil && msg == "" {
+		// the daemon runs on, it is reaped by init after the parent exits
+		cmd.Process.Release()
+		return 0, nil
+	}
+
+	if err != nil {
+		cmd.Process.Kill()
+		msg = fmt.Sprintf("daemon does not start: %v", err)
+	}
+	status := 1
+	cmd.Wait()
+	if code := cmd.ProcessState.ExitCode(); code > 0 {
+		status = code
+	}
+
+	return status, fmt.Errorf("%s", msg)
+}
+
+// readDaemonStatus reads the readiness byte 0 from the daemon, or the error
+// message it writes before exiting.
+func readDaemonStatus(r io.Reader) (string, error) {
+	var (
+		buf []byte
+		b   [256]byte
+	)
+	for {
+		n, err := r.Read(b[:])
+		buf = append(buf, b[:n]...)
+		switch {
+		case len(buf) > 0 && buf[0] == 0:
+			return "", nil
+		case err == io.EOF && len(buf) > 0:
+			return string(buf), nil
+		case err == io.EOF:
+			return "daemon exits before it starts", nil
+		case err != nil:
+			return "", err
+		}
+	}
+}
+
+func daemonChild(opts DaemonOptions, fd string) error {
+	n, err := strconv.Atoi(fd)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", daemonEnv, fd)
+	}
+	// the children of the daemon are not daemons
+	os.Unsetenv(daemonEnv)
+	ready := os.NewFile(uintptr(n), "daemon-ready")
+	defer ready.Close()
+
+	if err = daemonSetup(opts); err != nil {
+		ready.Write([]byte(err.Error()))
+		return err
+	}
+	_, err = ready.Write([]byte{0})
+
+	return err
+}
+
+func daemonSetup(opts DaemonOptions) error {
+	if _, err := syscall.Setsid(); err != nil {
+		return fmt.Errorf("setsid: %v", err)
+	}
+	// relative to the working directory of the parent
+	pidFile := opts.PidFile
+	if pidFile != "" {
+		var err error
+		if pidFile, err = filepath.Abs(pidFile); err != nil {
+			return err
+		}
+	}
+	dir := opts.WorkDir
+	if dir == "" {
+		dir = "/"
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	syscall.Umask(opts.Umask)
+
+	if pidFile != "" {
+		release, err := WritePidFile(pidFile)
+		if err != nil {
+			return fmt.Errorf("write pid file %s: %v", pidFile, err)
+		}
+		daemonRelease = release
+	}
+
+	return nil
+}
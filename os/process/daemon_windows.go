@@ -0,0 +1,12 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+// Daemonize is not supported, use a Windows service instead.
+func Daemonize(opts DaemonOptions) error {
+	return ErrNotImplemented
+}
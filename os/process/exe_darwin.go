@@ -0,0 +1,41 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"bytes"
+	"os"
+	"unsafe"
+)
+
+const (
+	_PROC_PIDPATHINFO         = 11
+	_PROC_PIDPATHINFO_MAXSIZE = 4 * _MAXPATHLEN
+)
+
+func (p *DarwinProcess) ExePath() (string, error) {
+	return p.exe.get(p.readExe)
+}
+
+// readExe is proc_pidpath.
+func (p *DarwinProcess) readExe() (string, error) {
+	var buf [_PROC_PIDPATHINFO_MAXSIZE]byte
+	_, err := darwinProcInfo(p.pid, _PROC_PIDPATHINFO, unsafe.Pointer(&buf[0]), uintptr(len(buf)))
+	if err != nil {
+		return "", err
+	}
+
+	path := buf[:]
+	if i := bytes.IndexByte(path, 0); i >= 0 {
+		path = path[:i]
+	}
+	if _, err = os.Stat(string(path)); os.IsNotExist(err) {
+		return string(path), ErrExeDeleted
+	}
+
+	return string(path), nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const deletedSuffix = " (deleted)"
+
+func (p *LinuxProcess) ExePath() (string, error) {
+	return p.exe.get(p.readExe)
+}
+
+func (p *LinuxProcess) readExe() (string, error) {
+	path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", p.pid))
+	if err != nil {
+		// kernel threads have no executable
+		if os.IsNotExist(err) && p.alive() == nil {
+			return "", nil
+		}
+		return "", procError(err)
+	}
+
+	if strings.HasSuffix(path, deletedSuffix) {
+		return strings.TrimSuffix(path, deletedSuffix), ErrExeDeleted
+	}
+
+	return path, nil
+}
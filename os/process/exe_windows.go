@@ -0,0 +1,46 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procQueryFullProcessImageName = modKernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+func (p *WindowsProcess) ExePath() (string, error) {
+	return p.path.get(p.readExe)
+}
+
+func (p *WindowsProcess) readExe() (string, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	ret, _, err := procQueryFullProcessImageName.Call(
+		uintptr(h),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)))
+	if ret == 0 {
+		return "", err
+	}
+
+	path := syscall.UTF16ToString(buf[:size])
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		return path, ErrExeDeleted
+	}
+
+	return path, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"bytes"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_PROC_PIDLISTFDS           = 1
+	_PROC_INFO_CALL_PIDFDINFO  = 3
+	_PROC_PIDFDVNODEPATHINFO   = 2
+	_PROX_FDTYPE_VNODE         = 1
+	_PROC_FILEINFO_SIZE        = 24
+	_VNODE_FDINFOWITHPATH_SIZE = _PROC_FILEINFO_SIZE + _VNODE_INFO_PATH_SIZE
+	_VNODE_FDINFOWITHPATH_PATH = _PROC_FILEINFO_SIZE + _VNODE_INFO_SIZE
+)
+
+// procFdInfo is struct proc_fdinfo
+type procFdInfo struct {
+	FD     int32
+	FDType uint32
+}
+
+func (p *DarwinProcess) listFDs() ([]procFdInfo, error) {
+	// get the buffer size first
+	n, err := darwinProcInfo(p.pid, _PROC_PIDLISTFDS, nil, 0)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+
+	fds := make([]procFdInfo, n/unsafe.Sizeof(procFdInfo{}))
+	n, err = darwinProcInfo(p.pid, _PROC_PIDLISTFDS, unsafe.Pointer(&fds[0]), n)
+	if err != nil {
+		return nil, err
+	}
+
+	return fds[:n/unsafe.Sizeof(procFdInfo{})], nil
+}
+
+func (p *DarwinProcess) NumFDs() (int, error) {
+	fds, err := p.listFDs()
+	return len(fds), err
+}
+
+func (p *DarwinProcess) NumThreads() (int, error) {
+	var info procTaskInfo
+	err := darwinPidInfo(p.pid, _PROC_PIDTASKINFO, unsafe.Pointer(&info), unsafe.Sizeof(info))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(info.Threadnum), nil
+}
+
+// OpenFiles returns the vnode fds, sockets and pipes are not included.
+func (p *DarwinProcess) OpenFiles(limit int) ([]FDInfo, error) {
+	fds, err := p.listFDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files []FDInfo
+		buf   [_VNODE_FDINFOWITHPATH_SIZE]byte
+	)
+	for _, fd := range fds {
+		if fd.FDType != _PROX_FDTYPE_VNODE {
+			continue
+		}
+
+		// proc_pidfdinfo(pid, fd, PROC_PIDFDVNODEPATHINFO, buf, size)
+		_, _, errno := syscall.Syscall6(
+			_SYS_PROC_INFO,
+			_PROC_INFO_CALL_PIDFDINFO,
+			uintptr(p.pid),
+			_PROC_PIDFDVNODEPATHINFO,
+			uintptr(fd.FD),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)))
+		if errno != 0 {
+			// the fd may be closed in the meantime
+			continue
+		}
+
+		path := buf[_VNODE_FDINFOWITHPATH_PATH:]
+		if i := bytes.IndexByte(path, 0); i >= 0 {
+			path = path[:i]
+		}
+		files = append(files, FDInfo{FD: int(fd.FD), Path: string(path)})
+		if limit > 0 && len(files) >= limit {
+			break
+		}
+	}
+
+	return files, nil
+}
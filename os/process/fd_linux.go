@@ -0,0 +1,82 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const fdBatch = 1024
+
+// walkFDs calls @fn with every entry name in /proc/<pid>/fd until it
+// returns false. The directory is read in batches so that processes with
+// lots of fds are not loaded into memory at once.
+func (p *LinuxProcess) walkFDs(fn func(name string) bool) error {
+	d, err := os.Open(fmt.Sprintf("/proc/%d/fd", p.pid))
+	if err != nil {
+		return procError(err)
+	}
+	defer d.Close()
+
+	for {
+		names, err := d.Readdirnames(fdBatch)
+		for _, name := range names {
+			if !fn(name) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return procError(err)
+		}
+	}
+}
+
+func (p *LinuxProcess) NumFDs() (int, error) {
+	n := 0
+	err := p.walkFDs(func(string) bool {
+		n++
+		return true
+	})
+
+	return n, err
+}
+
+func (p *LinuxProcess) NumThreads() (int, error) {
+	status, err := readProcFile(p.pid, "status")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(statusField(status, "Threads"))
+}
+
+func (p *LinuxProcess) OpenFiles(limit int) ([]FDInfo, error) {
+	var fds []FDInfo
+	dir := fmt.Sprintf("/proc/%d/fd/", p.pid)
+	err := p.walkFDs(func(name string) bool {
+		fd, err := strconv.Atoi(name)
+		if err != nil {
+			return true
+		}
+		// the fd may be closed in the meantime
+		path, err := os.Readlink(dir + name)
+		if err != nil {
+			return true
+		}
+
+		fds = append(fds, FDInfo{FD: fd, Path: path})
+		return limit <= 0 || len(fds) < limit
+	})
+
+	return fds, err
+}
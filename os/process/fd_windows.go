@@ -0,0 +1,39 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"unsafe"
+)
+
+var (
+	procGetProcessHandleCount = modKernel32.NewProc("GetProcessHandleCount")
+)
+
+func (p *WindowsProcess) NumFDs() (int, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint32
+	ret, _, err := procGetProcessHandleCount.Call(uintptr(h), uintptr(unsafe.Pointer(&n)))
+	if ret == 0 {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// NumThreads returns the thread count of the process snapshot.
+func (p *WindowsProcess) NumThreads() (int, error) {
+	return p.threads, nil
+}
+
+func (p *WindowsProcess) OpenFiles(limit int) ([]FDInfo, error) {
+	return nil, ErrNotImplemented
+}
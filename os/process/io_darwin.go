@@ -0,0 +1,19 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"time"
+)
+
+func (p *DarwinProcess) IOCounters() (*IOStat, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *DarwinProcess) IORate(interval time.Duration) (*IOStat, error) {
+	return nil, ErrNotImplemented
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IOCounters reads /proc/<pid>/io, which is only readable by the owner of
+// the process and root. Lines missing on old kernels are left 0.
+func (p *LinuxProcess) IOCounters() (*IOStat, error) {
+	data, err := readProcFile(p.pid, "io")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &IOStat{}
+	fields := map[string]*uint64{
+		"rchar":                 &s.ReadChars,
+		"wchar":                 &s.WriteChars,
+		"syscr":                 &s.ReadSyscalls,
+		"syscw":                 &s.WriteSyscalls,
+		"read_bytes":            &s.ReadBytes,
+		"write_bytes":           &s.WriteBytes,
+		"cancelled_write_bytes": &s.CancelledWriteBytes,
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		if v, ok := fields[line[:i]]; ok {
+			if *v, err = strconv.ParseUint(strings.TrimSpace(line[i+1:]), 10, 64); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func (p *LinuxProcess) IORate(interval time.Duration) (*IOStat, error) {
+	return ioRate(p, interval)
+}
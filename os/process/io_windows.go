@@ -0,0 +1,55 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"time"
+	"unsafe"
+)
+
+var (
+	procGetProcessIoCounters = modKernel32.NewProc("GetProcessIoCounters")
+)
+
+// IO_COUNTERS is the Windows API structure that contains the I/O
+// statistics of a process.
+type IO_COUNTERS struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// IOCounters returns the I/O of all devices, Windows does not tell the
+// cached ones from the storage ones.
+func (p *WindowsProcess) IOCounters() (*IOStat, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	var c IO_COUNTERS
+	ret, _, err := procGetProcessIoCounters.Call(uintptr(h), uintptr(unsafe.Pointer(&c)))
+	if ret == 0 {
+		return nil, err
+	}
+
+	return &IOStat{
+		ReadChars:     c.ReadTransferCount,
+		WriteChars:    c.WriteTransferCount,
+		ReadBytes:     c.ReadTransferCount,
+		WriteBytes:    c.WriteTransferCount,
+		ReadSyscalls:  c.ReadOperationCount,
+		WriteSyscalls: c.WriteOperationCount,
+	}, nil
+}
+
+func (p *WindowsProcess) IORate(interval time.Duration) (*IOStat, error) {
+	return ioRate(p, interval)
+}
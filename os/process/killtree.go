@@ -0,0 +1,73 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// KillTreeError lists the processes KillTree failed to kill.
+type KillTreeError struct {
+	Pids []int
+	Errs []error
+}
+
+func (e *KillTreeError) Error() string {
+	s := make([]string, len(e.Pids))
+	for i := range e.Pids {
+		s[i] = fmt.Sprintf("%d: %v", e.Pids[i], e.Errs[i])
+	}
+
+	return "failed to kill processes {" + strings.Join(s, ", ") + "}"
+}
+
+func (e *KillTreeError) add(pid int, err error) {
+	e.Pids = append(e.Pids, pid)
+	e.Errs = append(e.Errs, err)
+}
+
+// KillTree sends @sig to @p and all of its descendants, waits up to
+// @timeout for them to exit, and kills the survivors. It returns a
+// *KillTreeError if some processes can not be killed.
+//
+// If @p is a process group leader the whole group is signaled at once,
+// otherwise the tree is signaled from the bottom up so that parents can not
+// respawn children in the meantime.
+func KillTree(p Process, sig os.Signal, timeout time.Duration) error {
+	descendants, err := p.Children(true)
+	if err != nil {
+		return err
+	}
+	// Children returns parents before children
+	tree := make([]Process, 0, len(descendants)+1)
+	for i := len(descendants) - 1; i >= 0; i-- {
+		tree = append(tree, descendants[i])
+	}
+	tree = append(tree, p)
+
+	// failures are left to the escalation
+	signalTree(p, sig, tree)
+
+	kerr := &KillTreeError{}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, q := range tree {
+		if q.WaitExit(ctx, 10*time.Millisecond) == nil {
+			continue
+		}
+		if err = q.Kill(); err != nil && err != ErrProcessDone {
+			kerr.add(q.Pid(), err)
+		}
+	}
+
+	if len(kerr.Pids) > 0 {
+		return kerr
+	}
+	return nil
+}
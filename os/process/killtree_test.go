@@ -0,0 +1,75 @@
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func startTree(t *testing.T, script string, setpgid bool) (*exec.Cmd, []Process) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	cmd := exec.Command(sh, "-c", script)
+	if setpgid {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		children, err := p.Children(true)
+		if err != nil {
+			t.Fatalf("Children() = error %v", err)
+		}
+		if len(children) == 2 {
+			return cmd, append(children, p)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	t.Fatal("the sleepers are not started")
+	return nil, nil
+}
+
+func testKillTree(t *testing.T, script string, setpgid bool) {
+	cmd, tree := startTree(t, script, setpgid)
+	err := KillTree(tree[len(tree)-1], syscall.SIGTERM, 200*time.Millisecond)
+	cmd.Wait()
+	if err != nil {
+		t.Fatalf("KillTree() = error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, p := range tree {
+		if err = p.WaitExit(ctx, 10*time.Millisecond); err != nil {
+			t.Fatalf("process %d survives: %v", p.Pid(), err)
+		}
+	}
+}
+
+func TestKillTree(t *testing.T) {
+	testKillTree(t, "sleep 100 & sleep 100 & wait", false)
+}
+
+func TestKillTreeGroup(t *testing.T) {
+	testKillTree(t, "sleep 100 & sleep 100 & wait", true)
+}
+
+func TestKillTreeEscalate(t *testing.T) {
+	// ignored signals are inherited by the sleepers
+	testKillTree(t, `trap "" TERM; sleep 100 & sleep 100 & wait`, false)
+}
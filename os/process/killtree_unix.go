@@ -0,0 +1,24 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalTree sends @sig to the process group led by @p, and to each
+// process of @tree in case some descendants have left the group.
+func signalTree(p Process, sig os.Signal, tree []Process) {
+	if pgid, err := syscall.Getpgid(p.Pid()); err == nil && pgid == p.Pid() {
+		signal(-pgid, sig)
+	}
+
+	for _, q := range tree {
+		q.Signal(sig)
+	}
+}
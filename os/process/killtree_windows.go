@@ -0,0 +1,20 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"os"
+)
+
+// signalTree terminates each process of @tree, as Windows has no signals.
+// Job Objects are not used because the job handle can not be got from
+// a process created by others.
+func signalTree(p Process, sig os.Signal, tree []Process) {
+	for _, q := range tree {
+		q.Terminate()
+	}
+}
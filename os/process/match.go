@@ -0,0 +1,86 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Matcher returns whether a process matches, for FindProcesses. The
+// information of a process is read when it is matched, so a matcher of a
+// process which has gone, or whose information can not be read, returns
+// false. Composed by And, the cheap matchers should go first, e.g.
+//
+//	And(ByExecutable("nginx"), ByCmdlineContains("--prefix=/data"))
+type Matcher func(p Process) bool
+
+// ByExecutable matches the processes whose Executable is @name.
+func ByExecutable(name string) Matcher {
+	return func(p Process) bool {
+		return p.Executable() == name
+	}
+}
+
+// ByCmdlineContains matches the processes whose command line, the
+// arguments joined by spaces, contains @s.
+func ByCmdlineContains(s string) Matcher {
+	return func(p Process) bool {
+		args, err := p.Cmdline()
+		return err == nil && strings.Contains(strings.Join(args, " "), s)
+	}
+}
+
+// ByUser matches the processes whose effective user is @user, by its name
+// or its decimal uid.
+func ByUser(user string) Matcher {
+	return func(p Process) bool {
+		if name, err := p.Username(); err == nil && name == user {
+			return true
+		}
+		uids, err := p.UIDs()
+		return err == nil && strconv.Itoa(uids[1]) == user
+	}
+}
+
+// ByPPid matches the children of the process @ppid.
+func ByPPid(ppid int) Matcher {
+	return func(p Process) bool {
+		return p.PPid() == ppid
+	}
+}
+
+// And matches the processes matching all @matchers, in order until one
+// fails. It matches all processes if @matchers is empty.
+func And(matchers ...Matcher) Matcher {
+	return func(p Process) bool {
+		for _, m := range matchers {
+			if !m(p) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches the processes matching any of @matchers, in order until one
+// matches. It matches none if @matchers is empty.
+func Or(matchers ...Matcher) Matcher {
+	return func(p Process) bool {
+		for _, m := range matchers {
+			if m(p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches the processes not matching @m.
+func Not(m Matcher) Matcher {
+	return func(p Process) bool {
+		return !m(p)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"unsafe"
+)
+
+// MemoryInfo returns RSS and VMS, darwin does not account swap and shared
+// memory per process.
+func (p *DarwinProcess) MemoryInfo() (*MemStats, error) {
+	var info procTaskInfo
+	err := darwinPidInfo(p.pid, _PROC_PIDTASKINFO, unsafe.Pointer(&info), unsafe.Sizeof(info))
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemStats{
+		RSS: info.ResidentSize,
+		VMS: info.VirtualSize,
+	}, nil
+}
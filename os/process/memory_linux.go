@@ -0,0 +1,64 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func (p *LinuxProcess) MemoryInfo() (*MemStats, error) {
+	statm, err := readProcFile(p.pid, "statm")
+	if err != nil {
+		return nil, err
+	}
+	status, err := readProcFile(p.pid, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MemStats{}
+	if m.VMS, m.RSS, m.Shared, err = parseStatm(statm); err != nil {
+		return nil, err
+	}
+	// kernel threads and old kernels have no VmSwap
+	if swap := statusField(status, "VmSwap"); swap != "" {
+		m.Swap, err = parseKB(swap)
+	}
+
+	return m, err
+}
+
+// parseStatm parses the sizes in bytes of the content of /proc/<pid>/statm.
+func parseStatm(statm []byte) (vms, rss, shared uint64, err error) {
+	// size resident shared text lib data dt, in pages
+	fields := strings.Fields(string(statm))
+	if len(fields) < 3 {
+		return 0, 0, 0, fmt.Errorf("invalid statm %q", statm)
+	}
+	var pages [3]uint64
+	for i := range pages {
+		if pages[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	return pages[0] * pageSize, pages[1] * pageSize, pages[2] * pageSize, nil
+}
+
+// parseKB parses "1024 kB" into bytes.
+func parseKB(s string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(s, "kB")), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return n << 10, nil
+}
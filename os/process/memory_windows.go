@@ -0,0 +1,50 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"unsafe"
+)
+
+var (
+	procGetProcessMemoryInfo = modKernel32.NewProc("K32GetProcessMemoryInfo")
+)
+
+// PROCESS_MEMORY_COUNTERS is the Windows API structure that contains
+// the memory statistics of a process.
+type PROCESS_MEMORY_COUNTERS struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// MemoryInfo returns the working set as RSS and the commit charge as VMS.
+func (p *WindowsProcess) MemoryInfo() (*MemStats, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	var c PROCESS_MEMORY_COUNTERS
+	c.Cb = uint32(unsafe.Sizeof(c))
+	ret, _, err := procGetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&c)), uintptr(c.Cb))
+	if ret == 0 {
+		return nil, err
+	}
+
+	return &MemStats{
+		RSS: uint64(c.WorkingSetSize),
+		VMS: uint64(c.PagefileUsage),
+	}, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+import (
+	gxlog "github.com/AlexStocks/goext/log"
+)
+
+var (
+	ErrMonitorStarted = fmt.Errorf("resource monitor has been started")
+)
+
+// Snapshot is the resource usage of a process sampled by a Monitor.
+// Fields the platform or the permission does not allow to read are 0.
+type Snapshot struct {
+	Time       time.Time
+	Pid        int
+	CPUPercent float64 // since the previous snapshot, see CPUPercent
+	Memory     MemStats
+	NumFDs     int
+	NumThreads int
+
+	// runtime statistics, only for the current process
+	Goroutines int
+	HeapAlloc  uint64
+	NumGC      uint32 // GCs since the previous snapshot
+
+	// Err is why the monitor stops, set in the final snapshot only, whose
+	// other fields are those of the last successful sampling.
+	Err error
+}
+
+// Monitor samples the resource usage of a process periodically.
+type Monitor struct {
+	p        Process
+	self     bool
+	interval time.Duration
+	sink     func(Snapshot)
+
+	// the previous sampling
+	last     Snapshot
+	lastCPU  time.Duration
+	lastTime time.Time
+	lastGC   uint32
+
+	once    sync.Once
+	started bool
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewResourceMonitor samples @p every @interval, and passes the snapshots
+// to @sink, which can be LogSink.
+func NewResourceMonitor(p Process, interval time.Duration, sink func(Snapshot)) (*Monitor, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("illegal interval %v", interval)
+	}
+	if p == nil || sink == nil {
+		return nil, fmt.Errorf("nil process or sink")
+	}
+
+	return &Monitor{
+		p:        p,
+		self:     p.Pid() == os.Getpid(),
+		interval: interval,
+		sink:     sink,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start starts sampling, the first snapshot comes after an interval.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return ErrMonitorStarted
+	}
+	m.started = true
+
+	// the baseline of the deltas
+	if _, err := m.sample(); err != nil {
+		close(m.done)
+		return err
+	}
+	go m.run()
+
+	return nil
+}
+
+// Stop stops sampling and waits for the sink to return.
+func (m *Monitor) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+
+	m.mu.Lock()
+	started := m.started
+	m.mu.Unlock()
+	if started {
+		<-m.done
+	}
+}
+
+// Done is closed when the monitor stops, by Stop or a sampling failure.
+func (m *Monitor) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *Monitor) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s, err := m.sample()
+		if err != nil {
+			final := m.last
+			final.Time = time.Now()
+			final.Err = err
+			m.sink(final)
+			return
+		}
+		m.sink(s)
+	}
+}
+
+// sample reads a snapshot, it fails only if the process has exited.
+func (m *Monitor) sample() (Snapshot, error) {
+	if state, err := m.p.State(); err != nil {
+		return Snapshot{}, err
+	} else if state == StateZombie || state == StateDead {
+		return Snapshot{}, ErrProcessDone
+	}
+
+	user, system, err := m.p.CPUTimes()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	now := time.Now()
+	s := Snapshot{Time: now, Pid: m.p.Pid()}
+	if !m.lastTime.IsZero() {
+		busy := user + system - m.lastCPU
+		s.CPUPercent = 100 * float64(busy) / float64(now.Sub(m.lastTime)) / float64(runtime.NumCPU())
+	}
+	m.lastCPU, m.lastTime = user+system, now
+
+	if mem, err := m.p.MemoryInfo(); err == nil {
+		s.Memory = *mem
+	}
+	// the fds of others may not be readable
+	s.NumFDs, _ = m.p.NumFDs()
+	s.NumThreads, _ = m.p.NumThreads()
+
+	if m.self {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		s.Goroutines = runtime.NumGoroutine()
+		s.HeapAlloc = ms.HeapAlloc
+		s.NumGC = ms.NumGC - m.lastGC
+		m.lastGC = ms.NumGC
+	}
+	m.last = s
+
+	return s, nil
+}
+
+// LogSink returns a sink logging the snapshots with @logger.
+func LogSink(logger gxlog.Logger) func(Snapshot) {
+	return func(s Snapshot) {
+		if s.Err != nil {
+			logger.Warnf("process %d resource monitor stops: %v", s.Pid, s.Err)
+			return
+		}
+
+		if s.Goroutines > 0 {
+			logger.Infof("process %d: cpu %.1f%%, rss %d, vms %d, fds %d, threads %d, goroutines %d, heap %d, gc %d",
+				s.Pid, s.CPUPercent, s.Memory.RSS, s.Memory.VMS, s.NumFDs, s.NumThreads,
+				s.Goroutines, s.HeapAlloc, s.NumGC)
+			return
+		}
+		logger.Infof("process %d: cpu %.1f%%, rss %d, vms %d, fds %d, threads %d",
+			s.Pid, s.CPUPercent, s.Memory.RSS, s.Memory.VMS, s.NumFDs, s.NumThreads)
+	}
+}
@@ -0,0 +1,76 @@
+package gxprocess
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestResourceMonitor(t *testing.T) {
+	self, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	snapshots := make(chan Snapshot, 100)
+	m, err := NewResourceMonitor(self, 10*time.Millisecond, func(s Snapshot) { snapshots <- s })
+	if err != nil {
+		t.Fatalf("NewResourceMonitor() = error %v", err)
+	}
+	if err = m.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	if err = m.Start(); err != ErrMonitorStarted {
+		t.Fatalf("Start() again = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s := <-snapshots
+		if s.Err != nil || s.Pid != self.Pid() || s.Memory.RSS == 0 || s.NumThreads == 0 || s.Goroutines == 0 {
+			t.Fatalf("snapshot = %+v", s)
+		}
+	}
+	m.Stop()
+	m.Stop()
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("Done() is not closed after Stop")
+	}
+}
+
+func TestResourceMonitorExit(t *testing.T) {
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	snapshots := make(chan Snapshot, 100)
+	m, _ := NewResourceMonitor(p, 10*time.Millisecond, func(s Snapshot) { snapshots <- s })
+	if err = m.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	if s := <-snapshots; s.Err != nil || s.Goroutines != 0 {
+		t.Fatalf("snapshot = %+v", s)
+	}
+
+	p.Kill()
+	cmd.Wait()
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor does not stop after the process exits")
+	}
+	var final Snapshot
+	for len(snapshots) > 0 {
+		final = <-snapshots
+	}
+	if final.Err == nil || final.Pid != p.Pid() {
+		t.Fatalf("final snapshot = %+v", final)
+	}
+}
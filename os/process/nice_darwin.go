@@ -0,0 +1,29 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+func (p *DarwinProcess) Nice() (int, error) {
+	return getNice(p.pid)
+}
+
+func (p *DarwinProcess) SetNice(nice int) error {
+	return setNice(p.pid, nice)
+}
+
+// darwin has no OOM killer.
+
+func (p *DarwinProcess) OOMScore() (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (p *DarwinProcess) OOMScoreAdj() (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (p *DarwinProcess) SetOOMScoreAdj(adj int) error {
+	return ErrNotImplemented
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func (p *LinuxProcess) Nice() (int, error) {
+	return getNice(p.pid)
+}
+
+func (p *LinuxProcess) SetNice(nice int) error {
+	return setNice(p.pid, nice)
+}
+
+func (p *LinuxProcess) readInt(name string) (int, error) {
+	data, err := readProcFile(p.pid, name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (p *LinuxProcess) OOMScore() (int, error) {
+	return p.readInt("oom_score")
+}
+
+func (p *LinuxProcess) OOMScoreAdj() (int, error) {
+	return p.readInt("oom_score_adj")
+}
+
+func (p *LinuxProcess) SetOOMScoreAdj(adj int) error {
+	if err := checkOOMScoreAdj(adj); err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/proc/%d/oom_score_adj", p.pid)
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(adj)), 0644); err != nil {
+		return procError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestNiceSelf(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	nice, err := p.Nice()
+	if err != nil {
+		t.Fatalf("Nice() = error %v", err)
+	}
+	if nice == 19 {
+		t.Skip("the nice value is the maximum")
+	}
+
+	// raising the nice value needs no privilege
+	if err = p.SetNice(nice + 1); err != nil {
+		t.Fatalf("SetNice(%d) = error %v", nice+1, err)
+	}
+	if n, err := p.Nice(); err != nil || n != nice+1 {
+		t.Fatalf("Nice() = %d, %v, want %d", n, err, nice+1)
+	}
+
+	// raising the oom score adj needs no privilege either
+	adj, err := p.OOMScoreAdj()
+	if err == nil && adj < 1000 {
+		if err = p.SetOOMScoreAdj(adj + 1); err != nil {
+			t.Fatalf("SetOOMScoreAdj(%d) = error %v", adj+1, err)
+		}
+		if a, err := p.OOMScoreAdj(); err != nil || a != adj+1 {
+			t.Fatalf("OOMScoreAdj() = %d, %v, want %d", a, err, adj+1)
+		}
+	}
+
+	if err = p.SetNice(20); err == nil {
+		t.Fatal("SetNice(20) should fail")
+	}
+	if err = p.SetOOMScoreAdj(1001); err == nil {
+		t.Fatal("SetOOMScoreAdj(1001) should fail")
+	}
+}
+
+func TestNiceOthers(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("setting others needs root")
+	}
+
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	if err = p.SetNice(-5); err != nil {
+		t.Fatalf("SetNice(-5) = error %v", err)
+	}
+	if n, err := p.Nice(); err != nil || n != -5 {
+		t.Fatalf("Nice() = %d, %v", n, err)
+	}
+
+	err = p.SetOOMScoreAdj(500)
+	if err == ErrNotImplemented {
+		return
+	}
+	if err != nil {
+		t.Fatalf("SetOOMScoreAdj(500) = error %v", err)
+	}
+	if adj, err := p.OOMScoreAdj(); err != nil || adj != 500 {
+		t.Fatalf("OOMScoreAdj() = %d, %v", adj, err)
+	}
+	if score, err := p.OOMScore(); err != nil || score < 0 {
+		t.Fatalf("OOMScore() = %d, %v", score, err)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"runtime"
+	"syscall"
+)
+
+func getNice(pid int) (int, error) {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		return 0, niceError(err)
+	}
+
+	// the raw linux syscall returns 20 - nice to avoid negative values
+	if runtime.GOOS == "linux" {
+		return 20 - prio, nil
+	}
+	return prio, nil
+}
+
+func setNice(pid int, nice int) error {
+	if err := checkNice(nice); err != nil {
+		return err
+	}
+
+	return niceError(syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice))
+}
+
+func niceError(err error) error {
+	switch err {
+	case syscall.ESRCH:
+		return ErrProcessDone
+	case syscall.EPERM, syscall.EACCES:
+		return ErrPermission
+	}
+
+	return err
+}
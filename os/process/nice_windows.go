@@ -0,0 +1,29 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+// Windows has priority classes instead of nice values, and no OOM killer.
+
+func (p *WindowsProcess) Nice() (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (p *WindowsProcess) SetNice(nice int) error {
+	return ErrNotImplemented
+}
+
+func (p *WindowsProcess) OOMScore() (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (p *WindowsProcess) OOMScoreAdj() (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (p *WindowsProcess) SetOOMScoreAdj(adj int) error {
+	return ErrNotImplemented
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStalePidFile  = fmt.Errorf("pid file is stale")
+	ErrPidFileLocked = fmt.Errorf("pid file is locked by another process")
+)
+
+// WritePidFile writes the pid and create time of the current process to
+// @path, and holds a lock on it until the returned release is called,
+// which also removes the file. It returns ErrPidFileLocked if another
+// process holds the lock. The lock is released by the OS if the process
+// crashes, and the file left is overwritten by the next WritePidFile.
+func WritePidFile(path string) (release func() error, err error) {
+	self, err := FindProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+	createTime, err := self.CreateTime()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := lockPidFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := fmt.Sprintf("%d\n%d\n", self.Pid(), createTime.UnixNano())
+	if err = writePidFile(f, content); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		return releasePidFile(f, path)
+	}, nil
+}
+
+func writePidFile(f *os.File, content string) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(content), 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// ReadPidFile returns the process whose pid is in @path. It returns
+// ErrStalePidFile if the process has exited or the pid has been reused by
+// another process, judged by the create time in the file, or the
+// modification time of the file if it is written by others.
+func ReadPidFile(path string) (Process, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty pid file %s", path)
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return nil, fmt.Errorf("invalid pid %q in %s", fields[0], path)
+	}
+
+	p, err := FindProcess(pid)
+	switch {
+	case err == ErrProcessDone || os.IsNotExist(err):
+		return nil, ErrStalePidFile
+	case err != nil:
+		return nil, err
+	case p == nil || p.IsZombie():
+		return nil, ErrStalePidFile
+	}
+
+	createTime, err := p.CreateTime()
+	if err == ErrProcessDone {
+		return nil, ErrStalePidFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 1 {
+		nsec, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid create time %q in %s", fields[1], path)
+		}
+		if createTime.UnixNano() != nsec {
+			return nil, ErrStalePidFile
+		}
+	} else if createTime.After(fi.ModTime()) {
+		return nil, ErrStalePidFile
+	}
+
+	return p, nil
+}
+
+// RemoveStalePidFile removes @path if it is stale. It returns false if
+// the file does not exist, or the process in it is still running, or a
+// process is writing it.
+func RemoveStalePidFile(path string) (removed bool, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	f, err := lockPidFile(path)
+	if err == ErrPidFileLocked {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// an empty file is left by a writer crashed before writing
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+	if fi.Size() > 0 {
+		if _, err = ReadPidFile(path); err != ErrStalePidFile {
+			f.Close()
+			return false, err
+		}
+	}
+	if err = releasePidFile(f, path); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// lockedPidFileRetries bounds the retries when the file is replaced
+// between opening and locking it.
+const lockedPidFileRetries = 10
+
+var errPidFileReplaced = fmt.Errorf("pid file replaced")
+
+// lockPidFile opens or creates @path and locks it exclusively, it retries
+// if the file is removed or replaced by the lock holder meanwhile.
+func lockPidFile(path string) (*os.File, error) {
+	for i := 0; ; i++ {
+		f, err := tryLockPidFile(path)
+		if err != errPidFileReplaced || i == lockedPidFileRetries {
+			return f, err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,140 @@
+package gxprocess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxpidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.pid")
+
+	release, err := WritePidFile(path)
+	if err != nil {
+		t.Fatalf("WritePidFile() = error %v", err)
+	}
+	if _, err = WritePidFile(path); err != ErrPidFileLocked {
+		t.Fatalf("WritePidFile() on a locked file = %v", err)
+	}
+	if removed, err := RemoveStalePidFile(path); removed || err != nil {
+		t.Fatalf("RemoveStalePidFile() on a locked file = %v, %v", removed, err)
+	}
+
+	p, err := ReadPidFile(path)
+	if err != nil || p.Pid() != os.Getpid() {
+		t.Fatalf("ReadPidFile() = %v, %v", p, err)
+	}
+
+	if err = release(); err != nil {
+		t.Fatalf("release() = error %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("pid file is not removed: %v", err)
+	}
+}
+
+func TestPidFileStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxpidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.pid")
+
+	// a crashed process leaves its pid file without the lock
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err = cmd.Run(); err != nil {
+		t.Skip(err)
+	}
+	content := fmt.Sprintf("%d\n", cmd.Process.Pid)
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadPidFile(path); err != ErrStalePidFile {
+		t.Fatalf("ReadPidFile() of an exited process = %v", err)
+	}
+
+	// the pid is reused by the current process
+	content = fmt.Sprintf("%d\n1\n", os.Getpid())
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadPidFile(path); err != ErrStalePidFile {
+		t.Fatalf("ReadPidFile() of a reused pid = %v", err)
+	}
+
+	if removed, err := RemoveStalePidFile(path); !removed || err != nil {
+		t.Fatalf("RemoveStalePidFile() = %v, %v", removed, err)
+	}
+	if removed, err := RemoveStalePidFile(path); removed || err != nil {
+		t.Fatalf("RemoveStalePidFile() on no file = %v, %v", removed, err)
+	}
+
+	// the next start takes over the stale file
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	release, err := WritePidFile(path)
+	if err != nil {
+		t.Fatalf("WritePidFile() on a stale file = error %v", err)
+	}
+	defer release()
+	if _, err = ReadPidFile(path); err != nil {
+		t.Fatalf("ReadPidFile() = error %v", err)
+	}
+}
+
+func TestPidFileConcurrentStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gxpidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.pid")
+
+	const (
+		starters = 8
+		rounds   = 50
+	)
+	for i := 0; i < rounds; i++ {
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			releases []func() error
+		)
+		for j := 0; j < starters; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				release, err := WritePidFile(path)
+				if err == ErrPidFileLocked {
+					return
+				}
+				if err != nil {
+					t.Errorf("WritePidFile() = error %v", err)
+					return
+				}
+				mu.Lock()
+				releases = append(releases, release)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if len(releases) != 1 {
+			t.Fatalf("round %d: %d starters got the pid file", i, len(releases))
+		}
+		if err = releases[0](); err != nil {
+			t.Fatalf("release() = error %v", err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+)
+
+func tryLockPidFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrPidFileLocked
+		}
+		return nil, err
+	}
+
+	// the holder may have removed the file before we locked it
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	pfi, err := os.Stat(path)
+	if err != nil || !os.SameFile(fi, pfi) {
+		f.Close()
+		return nil, errPidFileReplaced
+	}
+
+	return f, nil
+}
+
+// releasePidFile removes the locked pid file @f and unlocks it. It is
+// removed before unlocking, or it may remove the file of the next holder.
+func releasePidFile(f *os.File, path string) error {
+	err := os.Remove(path)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+)
+
+const _ERROR_SHARING_VIOLATION syscall.Errno = 32
+
+// tryLockPidFile opens @path without sharing write or delete access, which
+// works as an exclusive lock released when the handle is closed.
+func tryLockPidFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err == _ERROR_SHARING_VIOLATION {
+		return nil, ErrPidFileLocked
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// releasePidFile closes and removes the locked pid file @f. The file can
+// not be removed before closing, and the removal fails harmlessly if the
+// next holder has opened it meanwhile.
+func releasePidFile(f *os.File, path string) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		if pe, ok := err.(*os.PathError); !ok || pe.Err != _ERROR_SHARING_VIOLATION {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,21 @@
 // packaeg gxprocess is used to get process info of "/proc"
 package gxprocess
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
 // refs: https://github.com/mitchellh/go-ps/blob/master/process.go
 
 // Process is the generic interface that is implemented on every platform
@@ -17,10 +32,296 @@ type Process interface {
 	PPid() int
 
 	// Executable name running this process. This is not a path to the
-	// executable.
+	// executable. The name truncated by the OS is completed with ExePath
+	// or the command line if possible.
 	Executable() string
+
+	// ExecutableShort is the executable name kept by the OS, which is
+	// truncated to 15 bytes on Linux and 16 bytes on macOS.
+	ExecutableShort() string
+
+	// Signal sends @sig to the process. It returns ErrProcessDone if the
+	// process has exited or its pid has been reused by another process.
+	Signal(sig os.Signal) error
+
+	// Kill causes the process to exit immediately(SIGKILL).
+	Kill() error
+
+	// Terminate asks the process to exit(SIGTERM). On Windows it is the
+	// same as Kill.
+	Terminate() error
+
+	// MemoryInfo returns the memory statistics of the process.
+	MemoryInfo() (*MemStats, error)
+
+	// CPUTimes returns the CPU time the process has spent in user and
+	// kernel mode.
+	CPUTimes() (user, system time.Duration, err error)
+
+	// CPUPercent samples CPUTimes twice in @interval and returns the CPU
+	// utilization in the range [0, 100] normalized by the host CPU count.
+	CPUPercent(interval time.Duration) (float64, error)
+
+	// Children returns the direct children of the process, or all of its
+	// descendants if @recursive is true.
+	Children(recursive bool) ([]Process, error)
+
+	// Cmdline returns the command line arguments, it is empty for
+	// kernel threads.
+	Cmdline() ([]string, error)
+
+	// Environ returns the environment variables of the process.
+	Environ() (map[string]string, error)
+
+	// Cwd returns the current working directory of the process.
+	Cwd() (string, error)
+
+	// ExePath returns the absolute path of the executable. It is resolved
+	// on the first call and cached. If the executable has been deleted or
+	// replaced on disk, the original path is returned with ErrExeDeleted.
+	ExePath() (string, error)
+
+	// NumFDs returns the number of opened file descriptors(handles on
+	// Windows).
+	NumFDs() (int, error)
+
+	// NumThreads returns the number of threads.
+	NumThreads() (int, error)
+
+	// OpenFiles returns at most @limit opened file descriptors, @limit <= 0
+	// means no limit.
+	OpenFiles(limit int) ([]FDInfo, error)
+
+	// UIDs returns the real, effective and saved user ids.
+	UIDs() ([]int, error)
+
+	// GIDs returns the real, effective and saved group ids.
+	GIDs() ([]int, error)
+
+	// Username returns the name of the effective user.
+	Username() (string, error)
+
+	// WaitExit waits for the process to exit, the process needs not to be
+	// a child of the caller. If the platform can not be notified of the exit
+	// the process is polled every @pollInterval. It returns nil if the
+	// process exits, ctx.Err() if @ctx is done, or ErrPidReused if the pid
+	// is taken by another process.
+	WaitExit(ctx context.Context, pollInterval time.Duration) error
+
+	// CreateTime returns the time the process started.
+	CreateTime() (time.Time, error)
+
+	// IOCounters returns the I/O statistics of the process.
+	IOCounters() (*IOStat, error)
+
+	// IORate samples IOCounters twice in @interval and returns the
+	// increments per second.
+	IORate(interval time.Duration) (*IOStat, error)
+
+	// Nice returns the nice value in [-20, 19].
+	Nice() (int, error)
+
+	// SetNice sets the nice value. On linux it only changes the main
+	// thread of the process.
+	SetNice(nice int) error
+
+	// OOMScore returns the badness score of the process for the OOM killer.
+	OOMScore() (int, error)
+
+	// OOMScoreAdj returns the adjustment of the OOM badness score.
+	OOMScoreAdj() (int, error)
+
+	// SetOOMScoreAdj sets the adjustment of the OOM badness score in
+	// [-1000, 1000]. Lowering it needs privileges.
+	SetOOMScoreAdj(adj int) error
+
+	// Cgroups returns the cgroups the process belongs to.
+	Cgroups() ([]CgroupInfo, error)
+
+	// CgroupLimits returns the effective resource limits of the cgroups
+	// of the process, the limits of the ancestor cgroups included.
+	CgroupLimits() (*CgroupLimits, error)
+
+	// ContainerID returns the id of the container the process runs in by
+	// its cgroups, or "" if it is not in a known container.
+	ContainerID() (string, error)
+
+	// State returns the current scheduling state of the process.
+	State() (ProcState, error)
+
+	// IsZombie checks whether the process has exited but not been reaped.
+	IsZombie() bool
+
+	// IsRunning checks whether the process exists and is neither a zombie
+	// nor dead. Sleeping and stopped processes are running.
+	IsRunning() bool
+
+	// Rlimits returns the resource limits of the process keyed by the
+	// resource names of prlimit(1), e.g. "nofile" and "as".
+	Rlimits() (map[string]Rlimit, error)
+
+	// RlimitNoFile returns the limit of the open file descriptors.
+	RlimitNoFile() (Rlimit, error)
+}
+
+// RlimitUnlimited is the limit value of "unlimited".
+const RlimitUnlimited = -1
+
+// Rlimit is a resource limit.
+type Rlimit struct {
+	Soft int64  // or RlimitUnlimited
+	Hard int64  // or RlimitUnlimited
+	Unit string // e.g. "bytes", "seconds", "files", "" for plain numbers
+}
+
+// ProcState is the scheduling state of a process.
+type ProcState int
+
+const (
+	StateUnknown ProcState = iota
+	StateRunning
+	StateSleeping
+	StateDiskSleep // uninterruptible sleep
+	StateZombie
+	StateStopped // stopped by a signal or being traced
+	StateDead
+)
+
+var procStateStrings = [...]string{
+	"unknown",
+	"running",
+	"sleeping",
+	"disk-sleep",
+	"zombie",
+	"stopped",
+	"dead",
+}
+
+func (s ProcState) String() string {
+	if s >= 0 && int(s) < len(procStateStrings) {
+		return procStateStrings[s]
+	}
+
+	return fmt.Sprintf("ProcState(%d)", int(s))
 }
 
+func isZombie(p Process) bool {
+	s, err := p.State()
+	return err == nil && s == StateZombie
+}
+
+func isRunning(p Process) bool {
+	s, err := p.State()
+	return err == nil && s != StateZombie && s != StateDead
+}
+
+// CgroupInfo is a line of /proc/<pid>/cgroup.
+type CgroupInfo struct {
+	ID          int      // hierarchy id, 0 for cgroup v2
+	Controllers []string // empty for cgroup v2
+	Path        string   // relative to the mount point of the hierarchy
+}
+
+// CgroupUnlimited means no limit.
+const CgroupUnlimited = -1
+
+// CgroupLimits is the resource limits of cgroups.
+type CgroupLimits struct {
+	MemoryMax int64   // in bytes, or CgroupUnlimited
+	CPUMax    float64 // in CPUs, or CgroupUnlimited
+}
+
+// IOStat is the I/O statistics of a process. Fields not supported by the
+// platform are 0.
+type IOStat struct {
+	ReadChars           uint64 // bytes read by read syscalls, cache included
+	WriteChars          uint64 // bytes written by write syscalls
+	ReadBytes           uint64 // bytes read from the storage layer
+	WriteBytes          uint64 // bytes written to the storage layer
+	ReadSyscalls        uint64
+	WriteSyscalls       uint64
+	CancelledWriteBytes uint64 // bytes not written because of truncation
+}
+
+// FDInfo is an opened file descriptor.
+type FDInfo struct {
+	FD   int
+	Path string
+}
+
+// MemStats is the memory statistics of a process, all in bytes. Fields
+// not supported by the platform are 0.
+type MemStats struct {
+	RSS    uint64 // resident set size
+	VMS    uint64 // virtual memory size
+	Swap   uint64 // swapped out memory
+	Shared uint64 // resident shared memory
+}
+
+// ProcessStat is the resource usage of a process.
+type ProcessStat struct {
+	Pid        int
+	RSS        uint64        // resident set size in bytes
+	VMS        uint64        // virtual memory size in bytes
+	User       time.Duration // CPU time in user mode
+	System     time.Duration // CPU time in kernel mode
+	NumThreads int
+	NumFDs     int // handles on Windows
+	CreateTime time.Time
+}
+
+// Stat returns the resource usage of the process @pid, or ErrProcessDone
+// if it does not exist. The fields the platform or the permission of the
+// caller does not allow are 0, and the stat is returned with the first
+// error of them, e.g. ErrNotImplemented or ErrPermission.
+func Stat(pid int) (*ProcessStat, error) {
+	return stat(pid)
+}
+
+// statOf gets the stat by the methods of @p, for the platforms which have
+// no cheaper way.
+func statOf(p Process) (*ProcessStat, error) {
+	var (
+		s     = &ProcessStat{Pid: p.Pid()}
+		first error
+	)
+	check := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+
+	if m, err := p.MemoryInfo(); err == nil {
+		s.RSS, s.VMS = m.RSS, m.VMS
+	} else {
+		check(err)
+	}
+	var err error
+	s.User, s.System, err = p.CPUTimes()
+	check(err)
+	s.NumThreads, err = p.NumThreads()
+	check(err)
+	s.NumFDs, err = p.NumFDs()
+	check(err)
+	s.CreateTime, err = p.CreateTime()
+	check(err)
+	if first == ErrProcessDone {
+		return nil, first
+	}
+
+	return s, first
+}
+
+var (
+	ErrProcessDone    = fmt.Errorf("process already finished")
+	ErrPermission     = fmt.Errorf("permission denied")
+	ErrNotImplemented = fmt.Errorf("not implemented on " + runtime.GOOS)
+	ErrExeDeleted     = fmt.Errorf("executable has been deleted")
+	ErrPidReused      = fmt.Errorf("pid has been reused by another process")
+	ErrZombie         = fmt.Errorf("process has exited but not been reaped")
+	ErrNotSupported   = fmt.Errorf("signal not supported on " + runtime.GOOS)
+)
+
 // Processes returns all processes.
 //
 // This of course will be a point-in-time snapshot of when this method was
@@ -28,7 +329,102 @@ type Process interface {
 // process table, in which case the process table returned might contain
 // ephemeral entities that happened to be running when this was called.
 func Processes() ([]Process, error) {
-	return processes()
+	return ProcessesWith()
+}
+
+// Field is the process information which can be loaded when listing
+// processes.
+type Field uint
+
+const (
+	FieldPid Field = 1 << iota
+	FieldComm
+	FieldPPid
+	fieldStartTime
+
+	FieldAll = FieldPid | FieldComm | FieldPPid | fieldStartTime
+)
+
+type listOptions struct {
+	fields    Field
+	name      string
+	uid       int
+	noZombies bool
+}
+
+type ListOption func(*listOptions)
+
+// WithFields sets the fields read when listing processes, the others are
+// loaded on the first access. By default all fields are read.
+//
+// Lazily loaded fields come from the time they are accessed rather than
+// the time of listing, and the process may have gone by then.
+func WithFields(fields Field) ListOption {
+	return func(o *listOptions) {
+		o.fields = fields
+	}
+}
+
+// WithNameFilter only lists the processes whose Executable is @name.
+func WithNameFilter(name string) ListOption {
+	return func(o *listOptions) {
+		o.name = name
+	}
+}
+
+// WithUser only lists the processes whose effective user id is @uid.
+// It is not supported on Windows.
+func WithUser(uid int) ListOption {
+	return func(o *listOptions) {
+		o.uid = uid
+	}
+}
+
+// WithoutZombies skips zombie processes. Windows has no zombies, exited
+// processes are not listed.
+func WithoutZombies() ListOption {
+	return func(o *listOptions) {
+		o.noZombies = true
+	}
+}
+
+// ProcessesWith returns the processes filtered by @opts, and only reads the
+// requested fields on platforms supporting it. The processes returned are
+// not safe for concurrent use if some fields are loaded lazily.
+func ProcessesWith(opts ...ListOption) ([]Process, error) {
+	o := &listOptions{fields: FieldAll, uid: -1}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return processes(o)
+}
+
+// filter returns the processes of @ps matching @o, for platforms which
+// have to get all fields anyway.
+func (o *listOptions) filter(ps []Process) ([]Process, error) {
+	if o.name == "" && o.uid < 0 {
+		return ps, nil
+	}
+
+	results := ps[:0]
+	for _, p := range ps {
+		if o.name != "" && p.Executable() != o.name {
+			continue
+		}
+		if o.uid >= 0 {
+			uids, err := p.UIDs()
+			if err != nil {
+				return nil, err
+			}
+			if uids[1] != o.uid {
+				continue
+			}
+		}
+		results = append(results, p)
+	}
+
+	return results, nil
 }
 
 // FindProcess looks up a single process by pid.
@@ -38,3 +434,434 @@ func Processes() ([]Process, error) {
 func FindProcess(pid int) (Process, error) {
 	return findProcess(pid)
 }
+
+// lookup returns the process @pid, or ErrProcessDone if it does not exist.
+func lookup(pid int) (Process, error) {
+	p, err := findProcess(pid)
+	// linux reports the missing /proc/<pid>
+	if os.IsNotExist(err) || (err == nil && p == nil) {
+		return nil, ErrProcessDone
+	}
+
+	return p, err
+}
+
+// Alive checks whether the process @pid is running. It returns false with
+// ErrZombie if the process has exited but not been reaped by its parent,
+// and false with nil if it has gone.
+func Alive(pid int) (bool, error) {
+	p, err := lookup(pid)
+	if err == nil {
+		var state ProcState
+		if state, err = p.State(); err == nil {
+			switch state {
+			case StateZombie:
+				return false, ErrZombie
+			case StateDead:
+				return false, nil
+			}
+			return true, nil
+		}
+	}
+	if err == ErrProcessDone {
+		return false, nil
+	}
+
+	return false, err
+}
+
+type waitOptions struct {
+	startTime time.Time
+}
+
+// WaitOption is an option of WaitExit.
+type WaitOption func(*waitOptions)
+
+// WithStartTime takes the process as exited if its start time is not @t,
+// e.g. the CreateTime of a Stat taken before, as its pid has been reused.
+func WithStartTime(t time.Time) WaitOption {
+	return func(o *waitOptions) {
+		o.startTime = t
+	}
+}
+
+// WaitExit waits for the process @pid to exit, polling every
+// @pollInterval if the platform can not notify the exit. The process needs
+// not to be a child of the caller, and it has exited when it becomes a
+// zombie. It returns nil if the process exits or does not exist, and
+// ctx.Err() if @ctx is done first.
+func WaitExit(ctx context.Context, pid int, pollInterval time.Duration, opts ...WaitOption) error {
+	var o waitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	p, err := lookup(pid)
+	if err == ErrProcessDone {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !o.startTime.IsZero() {
+		createTime, err := p.CreateTime()
+		if err == ErrProcessDone {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !createTime.Equal(o.startTime) {
+			return nil
+		}
+	}
+
+	if err = p.WaitExit(ctx, pollInterval); err == ErrPidReused {
+		return nil
+	}
+	return err
+}
+
+// FindProcesses returns the processes @matcher returns true for, in pid
+// order, see Matcher. The processes exiting while listing or matching are
+// skipped, and an empty result is not an error.
+func FindProcesses(matcher Matcher) ([]Process, error) {
+	ps, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Process
+	for _, p := range ps {
+		if matcher(p) {
+			results = append(results, p)
+		}
+	}
+	sortByPid(results)
+
+	return results, nil
+}
+
+// FindProcessesByName returns the processes whose Executable is @name,
+// in pid order.
+func FindProcessesByName(name string) ([]Process, error) {
+	ps, err := ProcessesWith(WithNameFilter(name))
+	if err != nil {
+		return nil, err
+	}
+	sortByPid(ps)
+
+	return ps, nil
+}
+
+// FindProcessesByCmdline returns the processes whose command line, the
+// arguments joined by spaces, matches @re, in pid order. The command line
+// is only read for processes whose Executable is @name, or all processes
+// if @name is empty. Processes whose command line can not be read are
+// skipped.
+func FindProcessesByCmdline(name string, re *regexp.Regexp) ([]Process, error) {
+	var (
+		err        error
+		candidates []Process
+	)
+	if name != "" {
+		candidates, err = FindProcessesByName(name)
+	} else {
+		candidates, err = FindProcesses(func(Process) bool { return true })
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Process
+	for _, p := range candidates {
+		args, err := p.Cmdline()
+		if err == nil && re.MatchString(strings.Join(args, " ")) {
+			results = append(results, p)
+		}
+	}
+
+	return results, nil
+}
+
+func sortByPid(ps []Process) {
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Pid() < ps[j].Pid()
+	})
+}
+
+func cpuPercent(p Process, interval time.Duration) (float64, error) {
+	if interval <= 0 {
+		return 0, fmt.Errorf("illegal interval %v", interval)
+	}
+
+	user0, sys0, err := p.CPUTimes()
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	time.Sleep(interval)
+	user1, sys1, err := p.CPUTimes()
+	if err != nil {
+		return 0, err
+	}
+
+	busy := (user1 - user0) + (sys1 - sys0)
+	return 100 * float64(busy) / float64(time.Since(start)) / float64(runtime.NumCPU()), nil
+}
+
+func ioRate(p Process, interval time.Duration) (*IOStat, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("illegal interval %v", interval)
+	}
+
+	s0, err := p.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	time.Sleep(interval)
+	s1, err := p.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	secs := time.Since(start).Seconds()
+	rate := func(v0, v1 uint64) uint64 {
+		return uint64(float64(v1-v0) / secs)
+	}
+	return &IOStat{
+		ReadChars:           rate(s0.ReadChars, s1.ReadChars),
+		WriteChars:          rate(s0.WriteChars, s1.WriteChars),
+		ReadBytes:           rate(s0.ReadBytes, s1.ReadBytes),
+		WriteBytes:          rate(s0.WriteBytes, s1.WriteBytes),
+		ReadSyscalls:        rate(s0.ReadSyscalls, s1.ReadSyscalls),
+		WriteSyscalls:       rate(s0.WriteSyscalls, s1.WriteSyscalls),
+		CancelledWriteBytes: rate(s0.CancelledWriteBytes, s1.CancelledWriteBytes),
+	}, nil
+}
+
+func checkNice(nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("nice value %d out of range [-20, 19]", nice)
+	}
+
+	return nil
+}
+
+func checkOOMScoreAdj(adj int) error {
+	if adj < -1000 || adj > 1000 {
+		return fmt.Errorf("oom score adj %d out of range [-1000, 1000]", adj)
+	}
+
+	return nil
+}
+
+// FDUsage returns the number of open file descriptors of @p and its soft
+// limit, which is RlimitUnlimited if not limited.
+func FDUsage(p Process) (used int, limit int64, err error) {
+	rl, err := p.RlimitNoFile()
+	if err != nil {
+		return 0, 0, err
+	}
+	if used, err = p.NumFDs(); err != nil {
+		return 0, 0, err
+	}
+
+	return used, rl.Soft, nil
+}
+
+// SelfCgroupLimits returns the cgroup limits of the current process.
+func SelfCgroupLimits() (*CgroupLimits, error) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+
+	return p.CgroupLimits()
+}
+
+// untruncate returns the executable name of @p whose name kept by the OS
+// @short may be truncated to @maxLen bytes. The base names of ExePath and
+// argv[0] starting with @short are taken as the full name.
+func untruncate(p Process, short string, maxLen int) string {
+	if len(short) < maxLen {
+		return short
+	}
+
+	var names []string
+	// others' executables may be unreadable, their command lines are not
+	if path, err := p.ExePath(); path != "" && (err == nil || err == ErrExeDeleted) {
+		names = append(names, filepath.Base(path))
+	}
+	if args, err := p.Cmdline(); err == nil && len(args) > 0 {
+		names = append(names, filepath.Base(args[0]))
+	}
+	for _, name := range names {
+		if len(name) > len(short) && strings.HasPrefix(name, short) {
+			return name
+		}
+	}
+
+	return short
+}
+
+func children(p Process, recursive bool) ([]Process, error) {
+	if recursive {
+		return Descendants(p.Pid())
+	}
+
+	return Children(p.Pid())
+}
+
+// Tree lists the processes once and returns the children of them keyed by
+// the parent pid, in pid order, for the callers looking up repeatedly. The
+// processes exiting while listing are skipped.
+func Tree() (map[int][]Process, error) {
+	ps, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+	sortByPid(ps)
+
+	return buildTree(ps), nil
+}
+
+func buildTree(ps []Process) map[int][]Process {
+	tree := make(map[int][]Process)
+	for _, c := range ps {
+		// pid 0 on some platforms is its own parent
+		if c.Pid() != c.PPid() {
+			tree[c.PPid()] = append(tree[c.PPid()], c)
+		}
+	}
+
+	return tree
+}
+
+// Children returns the direct children of the process @pid.
+func Children(pid int) ([]Process, error) {
+	tree, err := Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return tree[pid], nil
+}
+
+// Descendants returns all the descendants of the process @pid, breadth
+// first.
+func Descendants(pid int) ([]Process, error) {
+	tree, err := Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return descendants(tree, pid), nil
+}
+
+// descendants walks @tree from @pid. Every pid is visited once, so a cycle
+// of PPids, e.g. made by a pid reused while listing, ends the walk.
+func descendants(tree map[int][]Process, pid int) []Process {
+	var result []Process
+	visited := map[int]bool{pid: true}
+	for i := -1; i < len(result); i++ {
+		parent := pid
+		if i >= 0 {
+			parent = result[i].Pid()
+		}
+		for _, c := range tree[parent] {
+			if !visited[c.Pid()] {
+				visited[c.Pid()] = true
+				result = append(result, c)
+			}
+		}
+	}
+
+	return result
+}
+
+// parseEnviron parses "key=value" pairs.
+func parseEnviron(kvs []string) map[string]string {
+	env := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	return env
+}
+
+// splitNUL splits NUL-separated strings, ignoring the trailing NULs.
+func splitNUL(data []byte) []string {
+	s := strings.TrimRight(string(data), "\x00")
+	if len(s) == 0 {
+		return []string{}
+	}
+
+	return strings.Split(s, "\x00")
+}
+
+// exePath caches the executable path of a process.
+type exePath struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+func (e *exePath) get(resolve func() (string, error)) (string, error) {
+	e.once.Do(func() {
+		e.path, e.err = resolve()
+	})
+
+	return e.path, e.err
+}
+
+var (
+	userNames     = make(map[int]string)
+	userNamesLock sync.RWMutex
+)
+
+// lookupUsername resolves @uid into the user name, the result is cached.
+func lookupUsername(uid int) (string, error) {
+	userNamesLock.RLock()
+	name, ok := userNames[uid]
+	userNamesLock.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return "", err
+	}
+
+	userNamesLock.Lock()
+	userNames[uid] = u.Username
+	userNamesLock.Unlock()
+
+	return u.Username, nil
+}
+
+// waitExit calls @exited every @interval until it returns true or an error.
+func waitExit(ctx context.Context, interval time.Duration, exited func() (bool, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("illegal poll interval %v", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := exited()
+		if done || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -11,6 +11,8 @@ package gxprocess
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"os"
 	"syscall"
 	"unsafe"
 )
@@ -19,6 +21,15 @@ type DarwinProcess struct {
 	pid    int
 	ppid   int
 	binary string
+	stat   int8 // p_stat when listed
+	uids   []int
+	gids   []int
+	// start time in microseconds, used to detect pid reuse
+	startTime int64
+	exe       exePath
+
+	// the untruncated binary and the binary it is resolved from
+	name, nameOf string
 }
 
 func (p *DarwinProcess) Pid() int {
@@ -29,51 +40,118 @@ func (p *DarwinProcess) PPid() int {
 	return p.ppid
 }
 
+// darwinCommLen is MAXCOMLEN, the length p_comm is truncated to.
+const darwinCommLen = 16
+
 func (p *DarwinProcess) Executable() string {
+	if p.nameOf != p.binary {
+		p.name, p.nameOf = untruncate(p, p.binary, darwinCommLen), p.binary
+	}
+	return p.name
+}
+
+func (p *DarwinProcess) ExecutableShort() string {
 	return p.binary
 }
 
+// alive checks whether the process still exists and its pid
+// has not been reused by another process.
+func (p *DarwinProcess) alive() error {
+	buf, err := darwinSyscall(_KERN_PROC_PID, int32(p.pid))
+	if err != nil {
+		return err
+	}
+	if buf.Len() < _KINFO_STRUCT_SIZE {
+		return ErrProcessDone
+	}
+
+	proc := &kinfoProc{}
+	if err = binary.Read(buf, binary.LittleEndian, proc); err != nil {
+		return err
+	}
+	if proc.startTime() != p.startTime {
+		return ErrProcessDone
+	}
+
+	return nil
+}
+
+func (p *DarwinProcess) Signal(sig os.Signal) error {
+	if err := p.alive(); err != nil {
+		return err
+	}
+
+	return signal(p.pid, sig)
+}
+
+func (p *DarwinProcess) Kill() error {
+	return p.Signal(syscall.SIGKILL)
+}
+
+func (p *DarwinProcess) Terminate() error {
+	return p.Signal(syscall.SIGTERM)
+}
+
 func NewDarwinProcess(pid int) (Process, error) {
-	ps, err := processes()
+	buf, err := darwinSyscall(_KERN_PROC_PID, int32(pid))
 	if err != nil {
 		return nil, err
 	}
 
-	for _, p := range ps {
-		if p.Pid() == pid {
-			return p, nil
+	ps, err := parseKinfoProcs(buf.Bytes())
+	if err != nil || len(ps) == 0 {
+		return nil, err
+	}
+
+	return ps[0], nil
+}
+
+func processes(o *listOptions) ([]Process, error) {
+	ps, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	if o.noZombies {
+		live := ps[:0]
+		for _, p := range ps {
+			if p.(*DarwinProcess).stat != _SZOMB {
+				live = append(live, p)
+			}
 		}
+		ps = live
 	}
 
-	return nil, nil
+	return o.filter(ps)
 }
 
-func processes() ([]Process, error) {
-	buf, err := darwinSyscall()
+func listProcesses() ([]Process, error) {
+	buf, err := darwinSyscall(_KERN_PROC_ALL, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	procs := make([]*kinfoProc, 0, 50)
-	k := 0
-	for i := _KINFO_STRUCT_SIZE; i < buf.Len(); i += _KINFO_STRUCT_SIZE {
-		proc := &kinfoProc{}
-		err = binary.Read(bytes.NewBuffer(buf.Bytes()[k:i]), binary.LittleEndian, proc)
+	return parseKinfoProcs(buf.Bytes())
+}
+
+// parseKinfoProcs parses the kinfo_proc array returned by sysctl.
+func parseKinfoProcs(buf []byte) ([]Process, error) {
+	darwinProcs := make([]Process, 0, len(buf)/_KINFO_STRUCT_SIZE)
+	for i := _KINFO_STRUCT_SIZE; i <= len(buf); i += _KINFO_STRUCT_SIZE {
+		p := &kinfoProc{}
+		err := binary.Read(bytes.NewReader(buf[i-_KINFO_STRUCT_SIZE:i]), binary.LittleEndian, p)
 		if err != nil {
 			return nil, err
 		}
 
-		k = i
-		procs = append(procs, proc)
-	}
-
-	darwinProcs := make([]Process, len(procs))
-	for i, p := range procs {
-		darwinProcs[i] = &DarwinProcess{
-			pid:    int(p.Pid),
-			ppid:   int(p.PPid),
-			binary: darwinCstring(p.Comm),
-		}
+		darwinProcs = append(darwinProcs, &DarwinProcess{
+			pid:       int(p.Pid),
+			ppid:      int(p.PPid),
+			binary:    darwinCstring(p.Comm),
+			stat:      p.Stat,
+			startTime: p.startTime(),
+			uids:      []int{int(p.Ruid), int(p.Uid), int(p.Svuid)},
+			gids:      []int{int(p.Rgid), int(p.Gid), int(p.Svgid)},
+		})
 	}
 
 	return darwinProcs, nil
@@ -92,14 +170,22 @@ func darwinCstring(s [16]byte) string {
 	return string(s[:i])
 }
 
-func darwinSyscall() (*bytes.Buffer, error) {
-	mib := [4]int32{_CTRL_KERN, _KERN_PROC, _KERN_PROC_ALL, 0}
+func darwinSyscall(op, arg int32) (*bytes.Buffer, error) {
+	bs, err := darwinSysctl([]int32{_CTRL_KERN, _KERN_PROC, op, arg})
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(bs), nil
+}
+
+func darwinSysctl(mib []int32) ([]byte, error) {
 	size := uintptr(0)
 
 	_, _, errno := syscall.Syscall6(
 		syscall.SYS___SYSCTL,
 		uintptr(unsafe.Pointer(&mib[0])),
-		4,
+		uintptr(len(mib)),
 		0,
 		uintptr(unsafe.Pointer(&size)),
 		0,
@@ -109,11 +195,15 @@ func darwinSyscall() (*bytes.Buffer, error) {
 		return nil, errno
 	}
 
+	if size == 0 {
+		return nil, nil
+	}
+
 	bs := make([]byte, size)
 	_, _, errno = syscall.Syscall6(
 		syscall.SYS___SYSCTL,
 		uintptr(unsafe.Pointer(&mib[0])),
-		4,
+		uintptr(len(mib)),
 		uintptr(unsafe.Pointer(&bs[0])),
 		uintptr(unsafe.Pointer(&size)),
 		0,
@@ -123,26 +213,116 @@ func darwinSyscall() (*bytes.Buffer, error) {
 		return nil, errno
 	}
 
-	return bytes.NewBuffer(bs[0:size]), nil
+	return bs[0:size], nil
+}
+
+// darwinPidInfo calls proc_pidinfo(@pid, @flavor, 0, @buf, @size) and
+// checks that the whole @buf is filled.
+func darwinPidInfo(pid int, flavor int, buf unsafe.Pointer, size uintptr) error {
+	n, err := darwinProcInfo(pid, flavor, buf, size)
+	if err == nil && n != size {
+		err = fmt.Errorf("proc_pidinfo(%d, %d) returns %d bytes, want %d", pid, flavor, n, size)
+	}
+
+	return err
+}
+
+// darwinProcInfo calls proc_pidinfo(@pid, @flavor, 0, @buf, @size) and
+// returns its result.
+func darwinProcInfo(pid int, flavor int, buf unsafe.Pointer, size uintptr) (uintptr, error) {
+	n, _, errno := syscall.Syscall6(
+		_SYS_PROC_INFO,
+		_PROC_INFO_CALL_PIDINFO,
+		uintptr(pid),
+		uintptr(flavor),
+		0,
+		uintptr(buf),
+		size)
+
+	switch errno {
+	case 0:
+		return n, nil
+	case syscall.ESRCH:
+		return 0, ErrProcessDone
+	case syscall.EPERM:
+		return 0, ErrPermission
+	}
+
+	return 0, errno
+}
+
+// procTaskInfo is struct proc_taskinfo
+type procTaskInfo struct {
+	VirtualSize      uint64
+	ResidentSize     uint64
+	TotalUser        uint64 // in mach time units
+	TotalSystem      uint64
+	ThreadsUser      uint64
+	ThreadsSystem    uint64
+	Policy           int32
+	Faults           int32
+	Pageins          int32
+	CowFaults        int32
+	MessagesSent     int32
+	MessagesReceived int32
+	SyscallsMach     int32
+	SyscallsUnix     int32
+	Csw              int32
+	Threadnum        int32
+	Numrunning       int32
+	Priority         int32
 }
 
+const (
+	_SYS_PROC_INFO          = 336
+	_PROC_INFO_CALL_PIDINFO = 2
+	_PROC_PIDTASKINFO       = 4
+)
+
 const (
 	_CTRL_KERN         = 1
 	_KERN_PROC         = 14
 	_KERN_PROC_ALL     = 0
+	_KERN_PROC_PID     = 1
+	_SIDL              = 1 // p_stat values
+	_SRUN              = 2
+	_SSLEEP            = 3
+	_SSTOP             = 4
+	_SZOMB             = 5
 	_KINFO_STRUCT_SIZE = 648
 )
 
 type kinfoProc struct {
-	_    [40]byte
-	Pid  int32
-	_    [199]byte
-	Comm [16]byte
-	_    [301]byte
-	PPid int32
-	_    [84]byte
+	StartSec  int64 // p_starttime.tv_sec
+	StartUsec int32 // p_starttime.tv_usec
+	_         [24]byte
+	Stat      int8 // p_stat
+	_         [3]byte
+	Pid       int32
+	_         [199]byte
+	Comm      [16]byte
+	_         [133]byte
+	Ruid      uint32 // kp_eproc.e_pcred.p_ruid
+	Svuid     uint32
+	Rgid      uint32
+	Svgid     uint32
+	_         [12]byte
+	Uid       uint32 // kp_eproc.e_ucred.cr_uid
+	_         [4]byte
+	Gid       uint32 // kp_eproc.e_ucred.cr_groups[0]
+	_         [128]byte
+	PPid      int32
+	_         [84]byte
+}
+
+func (p *kinfoProc) startTime() int64 {
+	return p.StartSec*1e6 + int64(p.StartUsec)
 }
 
 func findProcess(pid int) (Process, error) {
 	return NewDarwinProcess(pid)
 }
+
+func (p *DarwinProcess) Children(recursive bool) ([]Process, error) {
+	return children(p, recursive)
+}
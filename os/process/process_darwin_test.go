@@ -0,0 +1,5 @@
+package gxprocess
+
+func fakeOlderProcess(p Process) {
+	p.(*DarwinProcess).startTime--
+}
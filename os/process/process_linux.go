@@ -17,6 +17,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // LinuxProcess is an Linux-specific Process information.
@@ -26,8 +27,15 @@ type LinuxProcess struct {
 	state rune
 	pgrp  int
 	sid   int
+	// start time in clock ticks after system boot, used to detect pid reuse
+	startTime uint64
 
 	binary string
+	// the untruncated binary and the binary it is resolved from
+	name, nameOf string
+	exe          exePath
+	// fields having been read, see ProcessesWith
+	loaded Field
 }
 
 func NewLinuxProcess(pid int) (*LinuxProcess, error) {
@@ -48,82 +56,236 @@ func (p *LinuxProcess) Pid() int {
 }
 
 func (p *LinuxProcess) PPid() int {
+	p.load(FieldPPid)
 	return p.ppid
 }
 
+// linuxCommLen is TASK_COMM_LEN - 1, the length comm is truncated to.
+const linuxCommLen = 15
+
 func (p *LinuxProcess) Executable() string {
+	p.load(FieldComm)
+	if p.nameOf != p.binary {
+		p.name, p.nameOf = untruncate(p, p.binary, linuxCommLen), p.binary
+	}
+	return p.name
+}
+
+func (p *LinuxProcess) ExecutableShort() string {
+	p.load(FieldComm)
 	return p.binary
 }
 
+// load reads the stat if @field has not been read.
+func (p *LinuxProcess) load(field Field) {
+	if p.loaded&field != field {
+		p.Refresh()
+	}
+}
+
 // Refresh reloads all the data associated with this process.
 func (p *LinuxProcess) Refresh() error {
-	statPath := fmt.Sprintf("/proc/%d/stat", p.pid)
-	dataBytes, err := ioutil.ReadFile(statPath)
+	data, err := readProcFile(p.pid, "stat")
 	if err != nil {
 		return err
 	}
 
-	// First, parse out the image name
-	data := string(dataBytes)
-	binStart := strings.IndexRune(data, '(') + 1
-	binEnd := strings.IndexRune(data[binStart:], ')')
-	p.binary = data[binStart : binStart+binEnd]
+	binary, fields, err := parseStat(data)
+	if err != nil {
+		return err
+	}
+	p.binary = binary
 
-	// Move past the image name and start parsing the rest
-	data = data[binStart+binEnd+2:]
-	_, err = fmt.Sscanf(data,
+	_, err = fmt.Sscanf(strings.Join(fields[:4], " "),
 		"%c %d %d %d",
 		&p.state,
 		&p.ppid,
 		&p.pgrp,
 		&p.sid)
+	if err != nil {
+		return err
+	}
+
+	// starttime is the 22nd field
+	p.startTime, err = strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	p.loaded = FieldAll
+	return nil
+}
+
+// parseStat splits the content of /proc/<pid>/stat into the image name and
+// the fields following it, so fields[0] is the 3rd field "state".
+//
+// The image name is enclosed in parentheses and may contain spaces and
+// parentheses itself, so it ends at the last ')'.
+func parseStat(data []byte) (string, []string, error) {
+	s := string(data)
+	binStart := strings.IndexByte(s, '(')
+	binEnd := strings.LastIndexByte(s, ')')
+	if binStart < 0 || binEnd < binStart {
+		return "", nil, fmt.Errorf("invalid stat %q", s)
+	}
+
+	fields := strings.Fields(s[binEnd+1:])
+	if len(fields) < 20 {
+		return "", nil, fmt.Errorf("too few stat fields %d", len(fields))
+	}
+
+	return s[binStart+1 : binEnd], fields, nil
+}
+
+// alive checks whether the process still exists and its pid
+// has not been reused by another process.
+func (p *LinuxProcess) alive() error {
+	p.load(fieldStartTime)
+	q := &LinuxProcess{pid: p.pid}
+	if err := q.Refresh(); err != nil {
+		return err
+	}
+	if q.startTime != p.startTime {
+		return ErrProcessDone
+	}
+
+	return nil
+}
+
+func (p *LinuxProcess) Signal(sig os.Signal) error {
+	if err := p.alive(); err != nil {
+		return err
+	}
+
+	return signal(p.pid, sig)
+}
+
+func (p *LinuxProcess) Kill() error {
+	return p.Signal(syscall.SIGKILL)
+}
+
+func (p *LinuxProcess) Terminate() error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// readProcFile reads /proc/<pid>/@name.
+func readProcFile(pid int, name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/%s", pid, name))
+	if err != nil {
+		return nil, procError(err)
+	}
+
+	return data, nil
+}
+
+// procError translates the error accessing /proc/<pid> into ErrProcessDone
+// if the process has gone, or ErrPermission if access is denied.
+func procError(err error) error {
+	if os.IsNotExist(err) {
+		return ErrProcessDone
+	}
+	if os.IsPermission(err) {
+		return ErrPermission
+	}
+	if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.ESRCH {
+		return ErrProcessDone
+	}
 
 	return err
 }
 
-func processes() ([]Process, error) {
+// statusField returns the value of the @key line of /proc/<pid>/status,
+// e.g. "VmSwap" -> "0 kB". It returns "" if the key does not exist.
+func statusField(status []byte, key string) string {
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, key) && len(line) > len(key) && line[len(key)] == ':' {
+			return strings.TrimSpace(line[len(key)+1:])
+		}
+	}
+
+	return ""
+}
+
+// walkPids calls @fn with every pid in /proc.
+func walkPids(fn func(pid int)) error {
 	d, err := os.Open("/proc")
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer d.Close()
 
-	results := make([]Process, 0, 50)
 	for {
-		fis, err := d.Readdir(10)
+		names, err := d.Readdirnames(64)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		for _, fi := range fis {
-			// We only care about directories, since all pids are dirs
-			if !fi.IsDir() {
-				continue
-			}
-
+		for _, name := range names {
 			// We only care if the name starts with a numeric
-			name := fi.Name()
 			if name[0] < '0' || name[0] > '9' {
 				continue
 			}
 
-			// From this point forward, any errors we just ignore, because
-			// it might simply be that the process doesn't exist anymore.
-			pid, err := strconv.ParseInt(name, 10, 0)
+			pid, err := strconv.Atoi(name)
 			if err != nil {
 				continue
 			}
 
-			p, err := NewLinuxProcess(int(pid))
+			fn(pid)
+		}
+	}
+
+	return nil
+}
+
+func processes(o *listOptions) ([]Process, error) {
+	results := make([]Process, 0, 50)
+	err := walkPids(func(pid int) {
+		// From this point forward, any errors we just ignore, because
+		// it might simply be that the process doesn't exist anymore.
+		p := &LinuxProcess{pid: pid, loaded: FieldPid}
+		if o.uid >= 0 {
+			// the owner of /proc/<pid> is the effective user
+			fi, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+			if err != nil || int(fi.Sys().(*syscall.Stat_t).Uid) != o.uid {
+				return
+			}
+		}
+		if o.name != "" {
+			comm, err := readProcFile(pid, "comm")
 			if err != nil {
-				continue
+				return
 			}
-
-			results = append(results, p)
+			short := o.name
+			if len(short) > linuxCommLen {
+				short = short[:linuxCommLen]
+			}
+			if strings.TrimSuffix(string(comm), "\n") != short {
+				return
+			}
+			p.binary = short
+			p.loaded |= FieldComm
+			// the comm may be truncated from another name
+			if len(short) == linuxCommLen && p.Executable() != o.name {
+				return
+			}
+		}
+		if o.fields&^p.loaded != 0 || o.noZombies {
+			if err := p.Refresh(); err != nil {
+				return
+			}
+		}
+		if o.noZombies && p.state == 'Z' {
+			return
 		}
+
+		results = append(results, p)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return results, nil
@@ -132,3 +294,7 @@ func processes() ([]Process, error) {
 func findProcess(pid int) (Process, error) {
 	return NewLinuxProcess(pid)
 }
+
+func (p *LinuxProcess) Children(recursive bool) ([]Process, error) {
+	return children(p, recursive)
+}
@@ -0,0 +1,189 @@
+package gxprocess
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func fakeOlderProcess(p Process) {
+	p.(*LinuxProcess).startTime--
+}
+
+func TestParseStat(t *testing.T) {
+	stat := "42 (a) (b c)) R 1 42 42 0 -1 4194560 100 0 0 0 7 3 0 0 20 0 1 0 12345 1000 100 18446744073709551615\n"
+	binary, fields, err := parseStat([]byte(stat))
+	if err != nil {
+		t.Fatalf("parseStat() = error %v", err)
+	}
+	if binary != "a) (b c)" {
+		t.Fatalf("binary = %q", binary)
+	}
+	if fields[0] != "R" || fields[1] != "1" || fields[19] != "12345" {
+		t.Fatalf("fields = %q", fields)
+	}
+
+	if _, _, err = parseStat([]byte("42 (a R 1")); err == nil {
+		t.Fatal("parseStat() on broken stat should fail")
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat := "42 (my (comm) x) S 1 42 42 0 -1 4194560 100 0 0 0 250 30 0 0 20 0 7 0 12345 1000 100 18446744073709551615\n"
+	s, startTime, err := parseProcStat([]byte(stat))
+	if err != nil {
+		t.Fatalf("parseProcStat() = error %v", err)
+	}
+	if s.User != 2500*time.Millisecond || s.System != 300*time.Millisecond ||
+		s.NumThreads != 7 || startTime != 12345 {
+		t.Fatalf("parseProcStat() = %+v, %d", s, startTime)
+	}
+
+	if _, _, err = parseProcStat([]byte("42 (a) S 1 42 42 0 -1 4194560 100 0 0 0 x 30 0 0 20 0 7 0 12345")); err == nil {
+		t.Fatal("parseProcStat() on bad utime should fail")
+	}
+
+	vms, rss, shared, err := parseStatm([]byte("100 20 5 1 0 30 0\n"))
+	page := uint64(os.Getpagesize())
+	if err != nil || vms != 100*page || rss != 20*page || shared != 5*page {
+		t.Fatalf("parseStatm() = %d, %d, %d, %v", vms, rss, shared, err)
+	}
+}
+
+func TestParseStatusIDs(t *testing.T) {
+	status := "Name:\tnginx\nUmask:\t0022\nState:\tS (sleeping)\nPPid:\t1\n" +
+		"Uid:\t1000\t1001\t1002\t1003\nGid:\t33\t33\t33\t33\nGroups:\t4 24\n"
+	uids, err := parseStatusIDs([]byte(status), "Uid")
+	if err != nil || !reflect.DeepEqual(uids, []int{1000, 1001, 1002}) {
+		t.Fatalf("parseStatusIDs(Uid) = %v, %v", uids, err)
+	}
+	gids, err := parseStatusIDs([]byte(status), "Gid")
+	if err != nil || !reflect.DeepEqual(gids, []int{33, 33, 33}) {
+		t.Fatalf("parseStatusIDs(Gid) = %v, %v", gids, err)
+	}
+
+	for _, bad := range []string{"Name:\tx\n", "Uid:\t1000\t1000\n", "Uid:\t1000\tx\t1000\t1000\n"} {
+		if ids, err := parseStatusIDs([]byte(bad), "Uid"); err == nil {
+			t.Fatalf("parseStatusIDs(%q) = %v", bad, ids)
+		}
+	}
+}
+
+func TestEnvironPermission(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read the environ of all processes")
+	}
+
+	p, err := NewLinuxProcess(1)
+	if err != nil {
+		t.Fatalf("NewLinuxProcess(1) = error %v", err)
+	}
+	if _, err = p.Environ(); err != ErrPermission {
+		t.Fatalf("Environ() of init = %v, want ErrPermission", err)
+	}
+}
+
+func TestExePathKernelThread(t *testing.T) {
+	// kthreadd
+	p, err := NewLinuxProcess(2)
+	if err != nil || p.PPid() != 0 {
+		t.Skip("no kthreadd")
+	}
+	if path, err := p.ExePath(); path != "" || err != nil {
+		t.Fatalf("ExePath() = %q, %v", path, err)
+	}
+}
+
+func TestWaitExitPidfd(t *testing.T) {
+	cmd := exec.Command("sleep", "0.1")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	p, err := NewLinuxProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("NewLinuxProcess() = error %v", err)
+	}
+
+	// the poll interval is never reached with pidfd
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = p.WaitExit(ctx, time.Hour); err != nil {
+		t.Fatalf("WaitExit() = error %v", err)
+	}
+	cmd.Wait()
+}
+
+func TestIOCountersPermission(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read the io of all processes")
+	}
+
+	p, err := NewLinuxProcess(1)
+	if err != nil {
+		t.Fatalf("NewLinuxProcess(1) = error %v", err)
+	}
+	if _, err = p.IOCounters(); err != ErrPermission {
+		t.Fatalf("IOCounters() of init = %v, want ErrPermission", err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits := "Limit                     Soft Limit           Hard Limit           Units     \n" +
+		"Max cpu time              unlimited            unlimited            seconds   \n" +
+		"Max open files            1024                 18446744073709551615 files     \n" +
+		"Max nice priority         0                    0                    \n" +
+		"Max realtime timeout      500                  unlimited            us        \n"
+	rls, err := parseLimits([]byte(limits))
+	if err != nil {
+		t.Fatalf("parseLimits() = error %v", err)
+	}
+	if rl := rls["cpu"]; rl.Soft != RlimitUnlimited || rl.Hard != RlimitUnlimited || rl.Unit != "seconds" {
+		t.Fatalf("cpu = %+v", rl)
+	}
+	if rl := rls["nofile"]; rl.Soft != 1024 || rl.Hard != RlimitUnlimited || rl.Unit != "" {
+		t.Fatalf("nofile = %+v", rl)
+	}
+	if rl := rls["nice"]; rl.Soft != 0 || rl.Unit != "" {
+		t.Fatalf("nice = %+v", rl)
+	}
+	if rl := rls["rttime"]; rl.Soft != 500 || rl.Unit != "microseconds" {
+		t.Fatalf("rttime = %+v", rl)
+	}
+}
+
+func TestRlimitNoFile(t *testing.T) {
+	var want syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := NewLinuxProcess(os.Getpid())
+	rl, err := p.RlimitNoFile()
+	if err != nil {
+		t.Fatalf("RlimitNoFile() = error %v", err)
+	}
+	if uint64(rl.Soft) != want.Cur {
+		t.Fatalf("RlimitNoFile() = %+v, want %+v", rl, want)
+	}
+
+	used, limit, err := FDUsage(p)
+	if err != nil || used <= 0 || limit != rl.Soft {
+		t.Fatalf("FDUsage() = %d, %d, %v", used, limit, err)
+	}
+}
+
+func TestContainerIDSelf(t *testing.T) {
+	p, _ := NewLinuxProcess(os.Getpid())
+	id, err := p.ContainerID()
+	if err != nil {
+		t.Fatalf("ContainerID() = error %v", err)
+	}
+	if id != "" && !InContainer() {
+		t.Fatalf("ContainerID() = %q but not InContainer()", id)
+	}
+	t.Logf("container id %q, in container %v", id, InContainer())
+}
@@ -1,8 +1,17 @@
 package gxprocess
 
 import (
+	"context"
 	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"syscall"
 	"testing"
+	"time"
 )
 
 import (
@@ -49,3 +58,650 @@ func TestProcesses(t *testing.T) {
 		t.Fatal("should have Go")
 	}
 }
+
+func TestTerminate(t *testing.T) {
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can not start sleep: %v", err)
+	}
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	if err = p.Terminate(); err != nil {
+		t.Fatalf("Terminate() = error %v", err)
+	}
+	if err = cmd.Wait(); err == nil {
+		t.Fatal("sleep should be terminated")
+	}
+
+	// the pid has been reaped
+	if err = p.Signal(syscall.Signal(0)); err != ErrProcessDone {
+		t.Fatalf("Signal() after exit = %v, want ErrProcessDone", err)
+	}
+	if err = p.Kill(); err != ErrProcessDone {
+		t.Fatalf("Kill() after exit = %v, want ErrProcessDone", err)
+	}
+}
+
+func TestMemoryInfo(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	m, err := p.MemoryInfo()
+	if err != nil {
+		t.Fatalf("MemoryInfo() = error %v", err)
+	}
+	t.Logf("memory:%+v", m)
+	// a go test binary takes some megabytes
+	if m.RSS < 1<<20 || m.RSS > 1<<32 {
+		t.Fatalf("implausible RSS %d", m.RSS)
+	}
+	// the commit charge on windows does not include shared pages
+	if runtime.GOOS != "windows" && m.VMS < m.RSS {
+		t.Fatalf("VMS %d < RSS %d", m.VMS, m.RSS)
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	cmd := exec.Command(sh, "-c", "while :; do :; done")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	pct, err := p.CPUPercent(200 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("CPUPercent() = error %v", err)
+	}
+	t.Logf("busy loop cpu:%.2f%%", pct)
+	// a busy loop takes one whole CPU
+	if pct < 10/float64(runtime.NumCPU()) || pct > 100.5 {
+		t.Fatalf("implausible cpu percent %.2f", pct)
+	}
+
+	user, system, err := p.CPUTimes()
+	if err != nil || user+system == 0 {
+		t.Fatalf("CPUTimes() = %v, %v, %v", user, system, err)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	if _, err = p.CPUPercent(time.Millisecond); err != ErrProcessDone {
+		t.Fatalf("CPUPercent() after exit = %v, want ErrProcessDone", err)
+	}
+}
+
+func TestChildren(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	cmd := exec.Command(sh, "-c", "sleep 100 & wait")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	self, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	var descendants []Process
+	for i := 0; i < 100; i++ {
+		if descendants, err = self.Children(true); err != nil {
+			t.Fatalf("Children(true) = error %v", err)
+		}
+		if len(descendants) >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var grandchild Process
+	for _, p := range descendants {
+		if p.PPid() == cmd.Process.Pid {
+			grandchild = p
+		}
+	}
+	if len(descendants) != 2 || descendants[0].Pid() != cmd.Process.Pid || grandchild == nil {
+		t.Fatalf("descendants:%s", gxlog.PrettyString(descendants))
+	}
+	defer grandchild.Kill()
+
+	children, err := self.Children(false)
+	if err != nil {
+		t.Fatalf("Children(false) = error %v", err)
+	}
+	if len(children) != 1 || children[0].Pid() != cmd.Process.Pid {
+		t.Fatalf("children:%s", gxlog.PrettyString(children))
+	}
+}
+
+func TestChildrenDescendants(t *testing.T) {
+	var cmds []*exec.Cmd
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command("sleep", "100")
+		if err := cmd.Start(); err != nil {
+			t.Skipf("Start(sleep) = error %v", err)
+		}
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+		cmds = append(cmds, cmd)
+	}
+
+	has := func(ps []Process, pid int) bool {
+		for _, p := range ps {
+			if p.Pid() == pid {
+				return true
+			}
+		}
+		return false
+	}
+	children, err := Children(os.Getpid())
+	if err != nil {
+		t.Fatalf("Children() = error %v", err)
+	}
+	descendants, err := Descendants(os.Getpid())
+	if err != nil {
+		t.Fatalf("Descendants() = error %v", err)
+	}
+	tree, err := Tree()
+	if err != nil {
+		t.Fatalf("Tree() = error %v", err)
+	}
+	for _, cmd := range cmds {
+		pid := cmd.Process.Pid
+		if !has(children, pid) || !has(descendants, pid) || !has(tree[os.Getpid()], pid) {
+			t.Fatalf("pid %d not in children:%s, descendants:%s", pid,
+				gxlog.PrettyString(children), gxlog.PrettyString(descendants))
+		}
+	}
+}
+
+type fakeProcess struct {
+	Process
+	pid, ppid int
+}
+
+func (p fakeProcess) Pid() int  { return p.pid }
+func (p fakeProcess) PPid() int { return p.ppid }
+
+func TestDescendantsCycle(t *testing.T) {
+	// 1 -> 2 -> 3 -> 2, 1 -> 4, 5 -> 5
+	tree := buildTree([]Process{
+		fakeProcess{pid: 1}, fakeProcess{pid: 2, ppid: 3}, fakeProcess{pid: 3, ppid: 2},
+		fakeProcess{pid: 4, ppid: 1}, fakeProcess{pid: 5, ppid: 5},
+	})
+	tree[1] = append(tree[1], fakeProcess{pid: 2, ppid: 1})
+
+	var pids []int
+	for _, p := range descendants(tree, 1) {
+		pids = append(pids, p.Pid())
+	}
+	if !reflect.DeepEqual(pids, []int{4, 2, 3}) {
+		t.Fatalf("descendants(1) = %v", pids)
+	}
+	if ps := descendants(tree, 5); len(ps) != 0 {
+		t.Fatalf("descendants(5) = %v", ps)
+	}
+	if ps := descendants(tree, 3); len(ps) != 1 || ps[0].Pid() != 2 {
+		t.Fatalf("descendants(3) = %v", ps)
+	}
+}
+
+func TestCmdlineEnvironCwd(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks() = error %v", err)
+	}
+
+	cmd := exec.Command(sleep, "100")
+	cmd.Env = []string{"GXPROCESS_TEST=a=b c"}
+	cmd.Dir = dir
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	args, err := p.Cmdline()
+	if err == ErrNotImplemented {
+		t.Skip(err)
+	}
+	if err != nil || len(args) != 2 || args[0] != sleep || args[1] != "100" {
+		t.Fatalf("Cmdline() = %q, %v", args, err)
+	}
+
+	env, err := p.Environ()
+	if err != nil || env["GXPROCESS_TEST"] != "a=b c" {
+		t.Fatalf("Environ() = %v, %v", env, err)
+	}
+
+	cwd, err := p.Cwd()
+	if err != nil || cwd != dir {
+		t.Fatalf("Cwd() = %q, %v, want %q", cwd, err, dir)
+	}
+}
+
+func TestExePath(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	path, err := p.ExePath()
+	if err != nil || path != exe {
+		t.Fatalf("ExePath() = %q, %v, want %q", path, err, exe)
+	}
+}
+
+func TestFDsAndThreads(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	threads, err := p.NumThreads()
+	if err != nil || threads < 1 {
+		t.Fatalf("NumThreads() = %d, %v", threads, err)
+	}
+
+	n0, err := p.NumFDs()
+	if err != nil {
+		t.Fatalf("NumFDs() = error %v", err)
+	}
+	f, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatalf("Open() = error %v", err)
+	}
+	defer f.Close()
+	if n1, err := p.NumFDs(); err != nil || n1 != n0+1 {
+		t.Fatalf("NumFDs() = %d, %v, want %d", n1, err, n0+1)
+	}
+
+	fds, err := p.OpenFiles(0)
+	if err == ErrNotImplemented {
+		return
+	}
+	if err != nil {
+		t.Fatalf("OpenFiles() = error %v", err)
+	}
+	found := false
+	for _, fd := range fds {
+		found = found || fd.FD == int(f.Fd())
+	}
+	if !found {
+		t.Fatalf("fd %d not in %+v", f.Fd(), fds)
+	}
+	if fds, err = p.OpenFiles(2); err != nil || len(fds) != 2 {
+		t.Fatalf("OpenFiles(2) = %+v, %v", fds, err)
+	}
+}
+
+func TestUser(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	name, err := p.Username()
+	if err != nil || name != u.Username {
+		t.Fatalf("Username() = %q, %v, want %q", name, err, u.Username)
+	}
+
+	uids, err := p.UIDs()
+	if err == ErrNotImplemented {
+		return
+	}
+	if err != nil || len(uids) != 3 || uids[1] != os.Geteuid() || uids[0] != os.Getuid() {
+		t.Fatalf("UIDs() = %v, %v", uids, err)
+	}
+	gids, err := p.GIDs()
+	if err != nil || len(gids) != 3 || gids[1] != os.Getegid() || gids[0] != os.Getgid() {
+		t.Fatalf("GIDs() = %v, %v", gids, err)
+	}
+}
+
+func TestWaitExit(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+
+	cmd := exec.Command(sleep, "0.2")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	go cmd.Wait()
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- p.WaitExit(context.Background(), 10*time.Millisecond)
+	}()
+	select {
+	case err = <-ch:
+		if err != nil {
+			t.Fatalf("WaitExit() = error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitExit() does not return after the process exits")
+	}
+
+	// it has gone
+	if err = p.WaitExit(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("WaitExit() after exit = error %v", err)
+	}
+}
+
+func TestWaitExitCancel(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+
+	cmd := exec.Command(sleep, "100")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = p.WaitExit(ctx, 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("WaitExit() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFindProcessesByName(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+
+	var cmds []*exec.Cmd
+	for _, arg := range []string{"100", "101", "102"} {
+		cmd := exec.Command(sleep, arg)
+		if err = cmd.Start(); err != nil {
+			t.Fatalf("Start() = error %v", err)
+		}
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+		cmds = append(cmds, cmd)
+	}
+
+	ps, err := FindProcessesByName(filepath.Base(sleep))
+	if err != nil {
+		t.Fatalf("FindProcessesByName() = error %v", err)
+	}
+	found := 0
+	for i, p := range ps {
+		if i > 0 && ps[i-1].Pid() >= p.Pid() {
+			t.Fatalf("not in pid order: %d, %d", ps[i-1].Pid(), p.Pid())
+		}
+		for _, cmd := range cmds {
+			if cmd.Process.Pid == p.Pid() {
+				found++
+			}
+		}
+	}
+	if found != len(cmds) {
+		t.Fatalf("found %d of %d sleep processes", found, len(cmds))
+	}
+
+	ps, err = FindProcessesByName("no-such-process-name")
+	if err != nil || len(ps) != 0 {
+		t.Fatalf("FindProcessesByName() = %v, %v", ps, err)
+	}
+
+	ps, err = FindProcessesByCmdline(filepath.Base(sleep), regexp.MustCompile(`sleep 10[12]$`))
+	if err == nil && len(ps) == 0 {
+		t.Skip("cmdline is not supported")
+	}
+	if err != nil || len(ps) != 2 || ps[0].Pid() != cmds[1].Process.Pid || ps[1].Pid() != cmds[2].Process.Pid {
+		t.Fatalf("FindProcessesByCmdline() = %v, %v", ps, err)
+	}
+}
+
+func TestSplitNUL(t *testing.T) {
+	for _, c := range []struct {
+		cmdline string
+		args    []string
+	}{
+		{"sleep\x00300\x00--ignored-flag\x00", []string{"sleep", "300", "--ignored-flag"}},
+		{"sh\x00-c\x00echo a b\x00", []string{"sh", "-c", "echo a b"}},
+		// a kernel thread
+		{"", []string{}},
+		// argv rewritten by the process, padded by NULs
+		{"nginx: master process /usr/sbin/nginx\x00\x00\x00\x00", []string{"nginx: master process /usr/sbin/nginx"}},
+		// an empty argument
+		{"a\x00\x00b\x00", []string{"a", "", "b"}},
+	} {
+		if args := splitNUL([]byte(c.cmdline)); !reflect.DeepEqual(args, c.args) {
+			t.Fatalf("splitNUL(%q) = %q, want %q", c.cmdline, args, c.args)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	yes := func(Process) bool { return true }
+	no := Not(yes)
+	for i, c := range []struct {
+		m    Matcher
+		want bool
+	}{
+		{ByExecutable(p.Executable()), true},
+		{ByExecutable("no-such-process-name"), false},
+		{ByPPid(os.Getppid()), true},
+		{ByPPid(os.Getpid()), false},
+		{And(), true},
+		{And(yes, yes), true},
+		{And(yes, no), false},
+		{Or(), false},
+		{Or(no, yes), true},
+		{Or(no, no), false},
+		{Not(no), true},
+	} {
+		if got := c.m(p); got != c.want {
+			t.Fatalf("matcher %d = %v, want %v", i, got, c.want)
+		}
+	}
+
+	if u, err := user.Current(); err == nil {
+		if name, err := p.Username(); err == nil && !ByUser(name)(p) {
+			t.Fatalf("ByUser(%q) = false", name)
+		}
+		if ByUser(u.Username + "-no-such-user")(p) {
+			t.Fatalf("ByUser() of another user = true")
+		}
+	}
+}
+
+// go test -bench=Name -run=^$
+func BenchmarkFindProcessesByName(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FindProcessesByName("init")
+	}
+}
+
+func BenchmarkProcessesFilterName(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FindProcesses(func(p Process) bool {
+			return p.Executable() == "init"
+		})
+	}
+}
+
+func TestProcessesWith(t *testing.T) {
+	ps, err := ProcessesWith(WithFields(FieldPid))
+	if err != nil || len(ps) == 0 {
+		t.Fatalf("ProcessesWith(FieldPid) = %d processes, %v", len(ps), err)
+	}
+	// other fields are loaded lazily
+	for _, p := range ps {
+		if p.Pid() == os.Getpid() && p.PPid() != os.Getppid() {
+			t.Fatalf("PPid() = %d, want %d", p.PPid(), os.Getppid())
+		}
+	}
+
+	self, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	ps, err = ProcessesWith(WithNameFilter(self.Executable()), WithUser(os.Geteuid()))
+	if runtime.GOOS == "windows" {
+		if err != ErrNotImplemented {
+			t.Fatalf("ProcessesWith(WithUser) = %v, want ErrNotImplemented", err)
+		}
+		return
+	}
+	found := false
+	for _, p := range ps {
+		if p.Executable() != self.Executable() {
+			t.Fatalf("process %d named %q", p.Pid(), p.Executable())
+		}
+		found = found || p.Pid() == os.Getpid()
+	}
+	if err != nil || !found {
+		t.Fatalf("ProcessesWith(WithNameFilter, WithUser) = %v, %v", ps, err)
+	}
+
+	ps, err = ProcessesWith(WithUser(os.Geteuid() + 12345))
+	if err != nil || len(ps) != 0 {
+		t.Fatalf("ProcessesWith(WithUser) of no one = %v, %v", ps, err)
+	}
+}
+
+// go test -bench=Processes -benchmem -run=^$
+func BenchmarkProcesses(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Processes()
+	}
+}
+
+func BenchmarkProcessesPidOnly(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ProcessesWith(WithFields(FieldPid))
+	}
+}
+
+func TestCreateTime(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	ct, err := p.CreateTime()
+	if err == ErrNotImplemented {
+		t.Skip(err)
+	}
+	// the start time on linux is in clock ticks, and boot time in seconds
+	now := time.Now()
+	if err != nil || ct.After(now.Add(time.Second)) || ct.Before(now.Add(-time.Hour)) {
+		t.Fatalf("CreateTime() = %v, %v, now %v", ct, err, now)
+	}
+}
+
+func TestStat(t *testing.T) {
+	s, err := Stat(os.Getpid())
+	if err == ErrNotImplemented && s != nil {
+		t.Logf("Stat() = partial stat:%+v", s)
+	} else if err != nil {
+		t.Fatalf("Stat() = error %v", err)
+	}
+	t.Logf("stat:%+v", s)
+	if s.Pid != os.Getpid() || s.RSS == 0 || s.NumThreads < 1 {
+		t.Fatalf("Stat() = %+v", s)
+	}
+	if runtime.GOOS != "windows" && s.VMS < s.RSS {
+		t.Fatalf("VMS %d < RSS %d", s.VMS, s.RSS)
+	}
+
+	// a reaped child is gone
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("Run() = error %v", err)
+	}
+	if _, err = Stat(cmd.Process.Pid); err != ErrProcessDone {
+		t.Fatalf("Stat() of an exited process = %v, want ErrProcessDone", err)
+	}
+}
+
+func TestIOCounters(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	s0, err := p.IOCounters()
+	if err == ErrNotImplemented {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("IOCounters() = error %v", err)
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "io"))
+	if err != nil {
+		t.Fatalf("Create() = error %v", err)
+	}
+	defer f.Close()
+	buf := make([]byte, 4096)
+	for i := 0; i < 16; i++ {
+		f.Write(buf)
+	}
+
+	s1, err := p.IOCounters()
+	if err != nil {
+		t.Fatalf("IOCounters() = error %v", err)
+	}
+	if s1.WriteChars < s0.WriteChars+16*4096 || s1.WriteSyscalls < s0.WriteSyscalls+16 {
+		t.Fatalf("IOCounters() = %+v before writing %+v", s1, s0)
+	}
+
+	if _, err = p.IORate(10 * time.Millisecond); err != nil {
+		t.Fatalf("IORate() = error %v", err)
+	}
+}
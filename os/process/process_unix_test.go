@@ -0,0 +1,394 @@
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalReusedPid(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	// pretend the pid belongs to an older process with the same pid
+	fakeOlderProcess(p)
+	if err = p.Signal(syscall.Signal(0)); err != ErrProcessDone {
+		t.Fatalf("Signal() on reused pid = %v, want ErrProcessDone", err)
+	}
+}
+
+func TestExecutableWithParentheses(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+	data, err := ioutil.ReadFile(sleep)
+	if err != nil {
+		t.Fatalf("ReadFile() = error %v", err)
+	}
+	bin := filepath.Join(t.TempDir(), "x) (y z")
+	if err = ioutil.WriteFile(bin, data, 0755); err != nil {
+		t.Fatalf("WriteFile() = error %v", err)
+	}
+
+	cmd := exec.Command(bin, "100")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	if p.Executable() != "x) (y z" || p.PPid() != os.Getpid() {
+		t.Fatalf("process:%#v", p)
+	}
+}
+
+func TestExePathDeleted(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+	data, err := ioutil.ReadFile(sleep)
+	if err != nil {
+		t.Fatalf("ReadFile() = error %v", err)
+	}
+	bin := filepath.Join(t.TempDir(), "sleep")
+	if err = ioutil.WriteFile(bin, data, 0755); err != nil {
+		t.Fatalf("WriteFile() = error %v", err)
+	}
+
+	cmd := exec.Command(bin, "100")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	os.Remove(bin)
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	path, err := p.ExePath()
+	if err != ErrExeDeleted || path != bin {
+		t.Fatalf("ExePath() = %q, %v", path, err)
+	}
+}
+
+func TestWaitExitPidReused(t *testing.T) {
+	p, err := FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	fakeOlderProcess(p)
+	if err = p.WaitExit(context.Background(), time.Millisecond); err != ErrPidReused {
+		t.Fatalf("WaitExit() = %v, want ErrPidReused", err)
+	}
+}
+
+func TestWaitExitPolling(t *testing.T) {
+	cmd := exec.Command("sleep", "0.1")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	// the zombie counts as exited before it is reaped
+	if err = waitExit(context.Background(), 10*time.Millisecond, p.(interface{ exited() (bool, error) }).exited); err != nil {
+		t.Fatalf("waitExit() = error %v", err)
+	}
+	cmd.Wait()
+}
+
+func TestStateZombie(t *testing.T) {
+	cmd := exec.Command("sleep", "0")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Wait()
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+
+	// not reaped until cmd.Wait
+	deadline := time.Now().Add(5 * time.Second)
+	for !p.IsZombie() {
+		if time.Now().After(deadline) {
+			s, err := p.State()
+			t.Fatalf("State() = %v, %v, want zombie", s, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.IsRunning() {
+		t.Fatal("IsRunning() of a zombie = true")
+	}
+
+	ps, err := ProcessesWith(WithoutZombies())
+	if err != nil {
+		t.Fatalf("ProcessesWith() = error %v", err)
+	}
+	for _, q := range ps {
+		if q.Pid() == p.Pid() {
+			t.Fatal("WithoutZombies() lists the zombie")
+		}
+	}
+
+	// the main thread may be sleeping while the test runs on another one
+	self, _ := FindProcess(os.Getpid())
+	if s, err := self.State(); err != nil || !self.IsRunning() || self.IsZombie() {
+		t.Fatalf("State() of self = %v, %v", s, err)
+	}
+}
+
+func TestAliveWaitExit(t *testing.T) {
+	cmd := exec.Command("sleep", "0.1")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	pid := cmd.Process.Pid
+	if alive, err := Alive(pid); !alive || err != nil {
+		t.Fatalf("Alive() = %v, %v", alive, err)
+	}
+	s, err := Stat(pid)
+	if s == nil {
+		t.Fatalf("Stat() = error %v", err)
+	}
+
+	// the zombie counts as exited before it is reaped
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = WaitExit(ctx, pid, 10*time.Millisecond, WithStartTime(s.CreateTime)); err != nil {
+		t.Fatalf("WaitExit() = error %v", err)
+	}
+	if alive, err := Alive(pid); alive || err != ErrZombie {
+		t.Fatalf("Alive() of a zombie = %v, %v", alive, err)
+	}
+	cmd.Wait()
+	if alive, err := Alive(pid); alive || err != nil {
+		t.Fatalf("Alive() of a reaped process = %v, %v", alive, err)
+	}
+	if err = WaitExit(ctx, pid, 10*time.Millisecond); err != nil {
+		t.Fatalf("WaitExit() of a reaped process = error %v", err)
+	}
+
+	// another start time means the pid is reused
+	if s, err = Stat(os.Getpid()); s == nil {
+		t.Fatalf("Stat() = error %v", err)
+	}
+	if err = WaitExit(ctx, os.Getpid(), 10*time.Millisecond, WithStartTime(s.CreateTime.Add(-time.Second))); err != nil {
+		t.Fatalf("WaitExit() of a reused pid = error %v", err)
+	}
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = WaitExit(timeout, os.Getpid(), 10*time.Millisecond, WithStartTime(s.CreateTime)); err != context.DeadlineExceeded {
+		t.Fatalf("WaitExit() of self = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestExecutableLongName(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+	data, err := ioutil.ReadFile(sleep)
+	if err != nil {
+		t.Fatalf("ReadFile() = error %v", err)
+	}
+	const name = "gx-long-executable-name"
+	bin := filepath.Join(t.TempDir(), name)
+	if err = ioutil.WriteFile(bin, data, 0755); err != nil {
+		t.Fatalf("WriteFile() = error %v", err)
+	}
+
+	cmd := exec.Command(bin, "100")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	p, err := FindProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("FindProcess() = error %v", err)
+	}
+	if p.Executable() != name {
+		t.Fatalf("Executable() = %q, want %q", p.Executable(), name)
+	}
+	if short := p.ExecutableShort(); len(short) >= len(name) || !strings.HasPrefix(name, short) {
+		t.Fatalf("ExecutableShort() = %q", short)
+	}
+
+	ps, err := FindProcessesByName(name)
+	if err != nil || len(ps) != 1 || ps[0].Pid() != p.Pid() {
+		t.Fatalf("FindProcessesByName(%q) = %v, %v", name, ps, err)
+	}
+	// the truncated name is not the name of the process
+	if ps, _ = FindProcessesByName(p.ExecutableShort()); len(ps) != 0 {
+		t.Fatalf("FindProcessesByName(%q) = %v", p.ExecutableShort(), ps)
+	}
+}
+
+// startShell starts sh running @script of the arguments @args, which prints
+// a line when it is ready.
+func startShell(t *testing.T, script string, args ...string) *exec.Cmd {
+	cmd := exec.Command("sh", append([]string{"-c", script}, args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe() = error %v", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	if _, err = bufio.NewReader(stdout).ReadString('\n'); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Fatalf("ReadString() = error %v", err)
+	}
+
+	return cmd
+}
+
+func TestFindProcessesMatch(t *testing.T) {
+	flag := fmt.Sprintf("--ignored-flag=%d", time.Now().UnixNano())
+	// the shell does not exec sleep, as a command follows it
+	const script = "echo ready; sleep 300; :"
+	var cmds []*exec.Cmd
+	for _, args := range [][]string{{"gxprocess", flag}, {"gxprocess", flag}, {"gxprocess", "--other"}} {
+		cmd := startShell(t, script, args...)
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+		cmds = append(cmds, cmd)
+	}
+	pids := func(ps []Process) []int {
+		var pids []int
+		for _, p := range ps {
+			pids = append(pids, p.Pid())
+		}
+		return pids
+	}
+
+	ps, err := FindProcesses(And(ByPPid(os.Getpid()), ByCmdlineContains(flag)))
+	if want := []int{cmds[0].Process.Pid, cmds[1].Process.Pid}; err != nil || !reflect.DeepEqual(pids(ps), want) {
+		t.Fatalf("FindProcesses(ByCmdlineContains) = %v, %v, want %v", pids(ps), err, want)
+	}
+	ps, err = FindProcesses(And(ByPPid(os.Getpid()), ByExecutable("sh"), Not(ByCmdlineContains(flag))))
+	if want := []int{cmds[2].Process.Pid}; err != nil || !reflect.DeepEqual(pids(ps), want) {
+		t.Fatalf("FindProcesses(Not(ByCmdlineContains)) = %v, %v, want %v", pids(ps), err, want)
+	}
+	ps, err = FindProcesses(And(ByCmdlineContains(flag), ByUser(strconv.Itoa(os.Geteuid()))))
+	if err != nil || len(ps) != 2 {
+		t.Fatalf("FindProcesses(ByUser) = %v, %v", pids(ps), err)
+	}
+
+	// a process gone does not match
+	p, err := FindProcess(cmds[0].Process.Pid)
+	if err != nil || p == nil {
+		t.Fatalf("FindProcess() = %v, %v", p, err)
+	}
+	cmds[0].Process.Kill()
+	cmds[0].Wait()
+	if ByCmdlineContains(flag)(p) || ByUser(strconv.Itoa(os.Geteuid()))(p) {
+		t.Fatalf("the matchers match an exited process")
+	}
+}
+
+func TestTerminateGrace(t *testing.T) {
+	const grace = 500 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// exits on SIGTERM
+	cmd := startShell(t, `trap "exit 3" TERM; echo ready; while :; do sleep 0.01; done`)
+	start := time.Now()
+	if err := Terminate(ctx, cmd.Process.Pid, grace); err != nil {
+		t.Fatalf("Terminate() = error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= grace {
+		t.Fatalf("Terminate() of a process trapping SIGTERM takes %v", elapsed)
+	}
+	cmd.Wait()
+	if code := cmd.ProcessState.ExitCode(); code != 3 {
+		t.Fatalf("exit code %d, want 3", code)
+	}
+
+	// ignores SIGTERM and gets SIGKILL
+	cmd = startShell(t, `trap "" TERM; echo ready; while :; do sleep 0.01; done`)
+	start = time.Now()
+	if err := Terminate(ctx, cmd.Process.Pid, grace); err != nil {
+		t.Fatalf("Terminate() = error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < grace {
+		t.Fatalf("Terminate() of a process ignoring SIGTERM takes %v", elapsed)
+	}
+	cmd.Wait()
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Fatalf("wait status %v, want killed by SIGKILL", status)
+	}
+	if err := Terminate(ctx, cmd.Process.Pid, grace); err != nil {
+		t.Fatalf("Terminate() of a reaped process = error %v", err)
+	}
+}
+
+func TestTerminateContext(t *testing.T) {
+	cmd := startShell(t, `trap "" TERM; echo ready; while :; do sleep 0.01; done`)
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := Terminate(ctx, cmd.Process.Pid, time.Minute); err != context.DeadlineExceeded {
+		t.Fatalf("Terminate() = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestSignalErrors(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip(err)
+	}
+	if err := Signal(cmd.Process.Pid, syscall.SIGTERM); err != ErrProcessDone {
+		t.Fatalf("Signal() of a reaped process = %v, want ErrProcessDone", err)
+	}
+	if err := Signal(0, syscall.SIGTERM); err == nil {
+		t.Fatalf("Signal() of pid 0 succeeds")
+	}
+	if err := Signal(os.Getpid(), fakeSignal{}); err != ErrNotSupported {
+		t.Fatalf("Signal() of a fake signal = %v, want ErrNotSupported", err)
+	}
+	if os.Geteuid() != 0 {
+		if err := Signal(1, syscall.Signal(0)); err != ErrPermission {
+			t.Fatalf("Signal() of init = %v, want ErrPermission", err)
+		}
+	}
+}
+
+type fakeSignal struct{}
+
+func (fakeSignal) String() string { return "fake" }
+func (fakeSignal) Signal()        {}
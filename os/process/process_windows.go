@@ -10,6 +10,9 @@ package gxprocess
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -25,8 +28,12 @@ var (
 
 // Some constants from the Windows API
 const (
-	ERROR_NO_MORE_FILES = 0x12
-	MAX_PATH            = 260
+	ERROR_NO_MORE_FILES     = 0x12
+	ERROR_INVALID_PARAMETER = syscall.Errno(0x57)
+	MAX_PATH                = 260
+
+	PROCESS_VM_READ                   = 0x0010
+	PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
 )
 
 // PROCESSENTRY32 is the Windows API structure that contains a process's
@@ -49,6 +56,14 @@ type WindowsProcess struct {
 	pid  int
 	ppid int
 	exe  string
+	path exePath
+	// thread count when the process snapshot is taken
+	threads int
+
+	// handle is opened on the first use and kept to pin the process, so
+	// it is not confused with a new process reusing the pid.
+	handleLock sync.Mutex
+	handle     syscall.Handle
 }
 
 func (p *WindowsProcess) Pid() int {
@@ -63,6 +78,133 @@ func (p *WindowsProcess) Executable() string {
 	return p.exe
 }
 
+// ExecutableShort is Executable, Windows does not truncate it.
+func (p *WindowsProcess) ExecutableShort() string {
+	return p.exe
+}
+
+// Signal only supports os.Kill, Windows has no other signals for processes.
+// Signal supports os.Kill and os.Interrupt only, see signal.
+func (p *WindowsProcess) Signal(sig os.Signal) error {
+	if sig == os.Kill {
+		return p.Kill()
+	}
+	if done, err := p.exited(); done || err != nil {
+		if done {
+			err = ErrProcessDone
+		}
+		return err
+	}
+
+	return signal(p.pid, sig)
+}
+
+func (p *WindowsProcess) Kill() error {
+	if done, err := p.exited(); done || err != nil {
+		if done {
+			err = ErrProcessDone
+		}
+		return err
+	}
+
+	h, err := openProcess(p.pid, syscall.PROCESS_TERMINATE|PROCESS_QUERY_LIMITED_INFORMATION)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	// the pid may be reused between exited and openProcess
+	t0, err := p.CreateTime()
+	if err != nil {
+		return err
+	}
+	t1, err := handleCreateTime(h)
+	if err != nil {
+		return err
+	}
+	if !t0.Equal(t1) {
+		return ErrProcessDone
+	}
+
+	return syscall.TerminateProcess(h, 1)
+}
+
+// exited checks the exit code of the process.
+func (p *WindowsProcess) exited() (bool, error) {
+	h, err := p.getHandle()
+	if err == ErrProcessDone {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var code uint32
+	if err = syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false, err
+	}
+
+	return code != STILL_ACTIVE, nil
+}
+
+func (p *WindowsProcess) Terminate() error {
+	return p.Kill()
+}
+
+// openProcess opens the process handle. It returns ErrProcessDone
+// if the process does not exist, or ErrPermission if access is denied.
+func openProcess(pid int, access uint32) (syscall.Handle, error) {
+	h, err := syscall.OpenProcess(access, false, uint32(pid))
+	switch err {
+	case ERROR_INVALID_PARAMETER:
+		return 0, ErrProcessDone
+	case syscall.ERROR_ACCESS_DENIED:
+		return 0, ErrPermission
+	}
+
+	return h, err
+}
+
+// getHandle returns the process handle, which is opened on the first call
+// and closed by Close.
+func (p *WindowsProcess) getHandle() (syscall.Handle, error) {
+	p.handleLock.Lock()
+	defer p.handleLock.Unlock()
+
+	if p.handle != 0 {
+		return p.handle, nil
+	}
+
+	h, err := openProcess(p.pid, PROCESS_QUERY_LIMITED_INFORMATION|PROCESS_VM_READ|syscall.SYNCHRONIZE)
+	if err == ErrPermission {
+		// protected processes deny PROCESS_VM_READ
+		h, err = openProcess(p.pid, PROCESS_QUERY_LIMITED_INFORMATION|syscall.SYNCHRONIZE)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	p.handle = h
+	runtime.SetFinalizer(p, (*WindowsProcess).Close)
+	return h, nil
+}
+
+// Close closes the process handle if it has been opened. It is also called
+// when the WindowsProcess is garbage collected.
+func (p *WindowsProcess) Close() error {
+	p.handleLock.Lock()
+	defer p.handleLock.Unlock()
+
+	if p.handle == 0 {
+		return nil
+	}
+	err := syscall.CloseHandle(p.handle)
+	p.handle = 0
+	runtime.SetFinalizer(p, nil)
+
+	return err
+}
+
 func newWindowsProcess(e *PROCESSENTRY32) *WindowsProcess {
 	// Find when the string ends for decoding
 	end := 0
@@ -77,11 +219,13 @@ func newWindowsProcess(e *PROCESSENTRY32) *WindowsProcess {
 		pid:  int(e.ProcessID),
 		ppid: int(e.ParentProcessID),
 		exe:  syscall.UTF16ToString(e.ExeFile[:end]),
+
+		threads: int(e.CntThreads),
 	}
 }
 
 func findProcess(pid int) (Process, error) {
-	ps, err := processes()
+	ps, err := listProcesses()
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +239,16 @@ func findProcess(pid int) (Process, error) {
 	return nil, nil
 }
 
-func processes() ([]Process, error) {
+func processes(o *listOptions) ([]Process, error) {
+	ps, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	return o.filter(ps)
+}
+
+func listProcesses() ([]Process, error) {
 	handle, _, _ := procCreateToolhelp32Snapshot.Call(
 		0x00000002,
 		0)
@@ -123,3 +276,7 @@ func processes() ([]Process, error) {
 
 	return results, nil
 }
+
+func (p *WindowsProcess) Children(recursive bool) ([]Process, error) {
+	return children(p, recursive)
+}
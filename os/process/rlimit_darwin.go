@@ -0,0 +1,17 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+// there is no way to get the resource limits of other processes.
+
+func (p *DarwinProcess) Rlimits() (map[string]Rlimit, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *DarwinProcess) RlimitNoFile() (Rlimit, error) {
+	return Rlimit{}, ErrNotImplemented
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rlimitNames maps the names in /proc/<pid>/limits to the names of prlimit(1).
+var rlimitNames = map[string]string{
+	"Max cpu time":          "cpu",
+	"Max file size":         "fsize",
+	"Max data size":         "data",
+	"Max stack size":        "stack",
+	"Max core file size":    "core",
+	"Max resident set":      "rss",
+	"Max processes":         "nproc",
+	"Max open files":        "nofile",
+	"Max locked memory":     "memlock",
+	"Max address space":     "as",
+	"Max file locks":        "locks",
+	"Max pending signals":   "sigpending",
+	"Max msgqueue size":     "msgqueue",
+	"Max nice priority":     "nice",
+	"Max realtime priority": "rtprio",
+	"Max realtime timeout":  "rttime",
+}
+
+func (p *LinuxProcess) Rlimits() (map[string]Rlimit, error) {
+	data, err := readProcFile(p.pid, "limits")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLimits(data)
+}
+
+func (p *LinuxProcess) RlimitNoFile() (Rlimit, error) {
+	limits, err := p.Rlimits()
+	if err != nil {
+		return Rlimit{}, err
+	}
+	rl, ok := limits["nofile"]
+	if !ok {
+		return Rlimit{}, fmt.Errorf("no open files limit of process %d", p.pid)
+	}
+
+	return rl, nil
+}
+
+// parseLimits parses /proc/<pid>/limits, a table whose limit names contain
+// spaces, so the columns are located by the header.
+func parseLimits(data []byte) (map[string]Rlimit, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	col := strings.Index(lines[0], "Soft Limit")
+	if col < 0 {
+		return nil, fmt.Errorf("invalid limits header %q", lines[0])
+	}
+
+	limits := make(map[string]Rlimit, len(lines)-1)
+	for _, line := range lines[1:] {
+		if len(line) <= col {
+			return nil, fmt.Errorf("invalid limits line %q", line)
+		}
+		fields := strings.Fields(line[col:])
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid limits line %q", line)
+		}
+
+		var (
+			rl  Rlimit
+			err error
+		)
+		if rl.Soft, err = parseRlimitValue(fields[0]); err != nil {
+			return nil, err
+		}
+		if rl.Hard, err = parseRlimitValue(fields[1]); err != nil {
+			return nil, err
+		}
+		if len(fields) > 2 {
+			rl.Unit = rlimitUnit(fields[2])
+		}
+
+		name := strings.TrimSpace(line[:col])
+		if n, ok := rlimitNames[name]; ok {
+			name = n
+		}
+		limits[name] = rl
+	}
+
+	return limits, nil
+}
+
+func parseRlimitValue(s string) (int64, error) {
+	if s == "unlimited" {
+		return RlimitUnlimited, nil
+	}
+	// RLIM_INFINITY may be printed as a number by old kernels
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v > 1<<63-1 {
+		return RlimitUnlimited, nil
+	}
+
+	return int64(v), nil
+}
+
+// rlimitUnit normalizes the unit, counts have no unit.
+func rlimitUnit(unit string) string {
+	switch unit {
+	case "bytes", "seconds":
+		return unit
+	case "us":
+		return "microseconds"
+	}
+
+	return ""
+}
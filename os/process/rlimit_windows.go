@@ -0,0 +1,17 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+// Windows has job object limits instead of resource limits.
+
+func (p *WindowsProcess) Rlimits() (map[string]Rlimit, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *WindowsProcess) RlimitNoFile() (Rlimit, error) {
+	return Rlimit{}, ErrNotImplemented
+}
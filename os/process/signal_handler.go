@@ -0,0 +1,239 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	ossignal "os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+import (
+	log "github.com/AlexStocks/log4go"
+)
+
+const (
+	defaultSignalHandlerTimeout = 30 * time.Second
+	signalChanSize              = 8
+)
+
+var (
+	ErrSignalHandlerStarted = fmt.Errorf("signal handler has been started")
+)
+
+type signalHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// SignalHandler dispatches signals to the handlers registered for them
+// from one goroutine, so handlers run one at a time.
+//
+// SIGTERM and SIGINT shut down: their handlers run in the reverse order of
+// registration, like deferred calls, and the dispatcher stops afterwards.
+// SIGHUP reloads: its handlers run in the order of registration and the
+// dispatcher goes on, as for any other signal.
+//
+// A signal received while its handlers are running is coalesced with the
+// others of it, the handlers run only once more for all of them.
+type SignalHandler struct {
+	timeout time.Duration
+
+	mu       sync.Mutex
+	hooks    map[os.Signal][]signalHook
+	reloads  []signalHook
+	pending  map[os.Signal]bool
+	queue    []os.Signal
+	kick     chan struct{}
+	started  bool
+	stopped  bool
+	done     chan struct{}
+	shutdown os.Signal
+}
+
+// NewSignalHandler returns a handler giving each handler @timeout to run,
+// 30s if @timeout <= 0.
+func NewSignalHandler(timeout time.Duration) *SignalHandler {
+	if timeout <= 0 {
+		timeout = defaultSignalHandlerTimeout
+	}
+
+	return &SignalHandler{
+		timeout: timeout,
+		hooks:   make(map[os.Signal][]signalHook),
+		pending: make(map[os.Signal]bool),
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// Register adds the handler @fn named @name for @sig. Handlers of SIGHUP
+// are reload hooks. The context passed to @fn is cancelled when its
+// timeout expires, and the dispatcher goes on without waiting for it.
+func (h *SignalHandler) Register(sig os.Signal, name string, fn func(context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hook := signalHook{name: name, fn: fn}
+	if sig == syscall.SIGHUP {
+		h.reloads = append(h.reloads, hook)
+		return
+	}
+	h.hooks[sig] = append(h.hooks[sig], hook)
+}
+
+// Start listens to the registered signals and dispatches them until a
+// shutdown signal is handled or @ctx is done. Signals registered after
+// Start are not listened to.
+func (h *SignalHandler) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.started {
+		return ErrSignalHandlerStarted
+	}
+	h.started = true
+
+	sigs := make([]os.Signal, 0, len(h.hooks)+1)
+	for sig := range h.hooks {
+		sigs = append(sigs, sig)
+	}
+	if len(h.reloads) > 0 {
+		sigs = append(sigs, syscall.SIGHUP)
+	}
+	ch := make(chan os.Signal, signalChanSize)
+	ossignal.Notify(ch, sigs...)
+
+	go h.receive(ctx, ch)
+	go h.dispatch(ctx)
+
+	return nil
+}
+
+// Done is closed when the dispatcher stops.
+func (h *SignalHandler) Done() <-chan struct{} {
+	return h.done
+}
+
+// Shutdown returns the shutdown signal handled, or nil if the dispatcher
+// has not been stopped by one.
+func (h *SignalHandler) Shutdown() os.Signal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.shutdown
+}
+
+// receive queues the signals received, unless they are queued already.
+func (h *SignalHandler) receive(ctx context.Context, ch chan os.Signal) {
+	defer ossignal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		case sig := <-ch:
+			h.mu.Lock()
+			if !h.pending[sig] && !h.stopped {
+				h.pending[sig] = true
+				h.queue = append(h.queue, sig)
+			}
+			h.mu.Unlock()
+
+			select {
+			case h.kick <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (h *SignalHandler) dispatch(ctx context.Context) {
+	defer close(h.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.kick:
+		}
+
+		for {
+			h.mu.Lock()
+			if len(h.queue) == 0 {
+				h.mu.Unlock()
+				break
+			}
+			sig := h.queue[0]
+			h.queue = h.queue[1:]
+			// a signal received from now on runs the handlers again
+			delete(h.pending, sig)
+			hooks, shutdown := h.hooksOf(sig)
+			if shutdown {
+				h.stopped = true
+				h.shutdown = sig
+			}
+			h.mu.Unlock()
+
+			for _, hook := range hooks {
+				h.run(ctx, sig, hook)
+			}
+			if shutdown {
+				return
+			}
+		}
+	}
+}
+
+// hooksOf returns the handlers of @sig in the order to run them, and
+// whether @sig shuts down.
+func (h *SignalHandler) hooksOf(sig os.Signal) ([]signalHook, bool) {
+	switch sig {
+	case syscall.SIGHUP:
+		return append([]signalHook(nil), h.reloads...), false
+
+	case syscall.SIGTERM, syscall.SIGINT:
+		hooks := h.hooks[sig]
+		reversed := make([]signalHook, 0, len(hooks))
+		for i := len(hooks) - 1; i >= 0; i-- {
+			reversed = append(reversed, hooks[i])
+		}
+		return reversed, true
+	}
+
+	return append([]signalHook(nil), h.hooks[sig]...), false
+}
+
+// run runs @hook within the timeout, and logs its error or panic.
+func (h *SignalHandler) run(ctx context.Context, sig os.Signal, hook signalHook) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		errc <- hook.fn(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			log.Error("signal %v handler %s: %v", sig, hook.name, err)
+		}
+	case <-ctx.Done():
+		log.Error("signal %v handler %s: %v", sig, hook.name, ctx.Err())
+	}
+}
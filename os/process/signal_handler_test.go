@@ -0,0 +1,117 @@
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalHandler(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	h := NewSignalHandler(50 * time.Millisecond)
+	h.Register(syscall.SIGHUP, "reload-a", record("reload-a"))
+	h.Register(syscall.SIGHUP, "reload-b", record("reload-b"))
+	h.Register(syscall.SIGUSR2, "panic", func(context.Context) error { panic("oops") })
+	h.Register(syscall.SIGUSR2, "error", func(context.Context) error { return fmt.Errorf("oops") })
+	h.Register(syscall.SIGUSR2, "hang", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() })
+	h.Register(syscall.SIGUSR2, "usr2", record("usr2"))
+	h.Register(syscall.SIGTERM, "close-db", record("close-db"))
+	h.Register(syscall.SIGTERM, "close-server", record("close-server"))
+	if err := h.Start(context.Background()); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+	if err := h.Start(context.Background()); err != ErrSignalHandlerStarted {
+		t.Fatalf("Start() again = %v", err)
+	}
+
+	wait := func(n int) {
+		for i := 0; i < 200; i++ {
+			mu.Lock()
+			l := len(order)
+			mu.Unlock()
+			if l >= n {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("handlers ran: %v", order)
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	wait(2)
+	// the panic, error and timeout do not stop the dispatcher
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	wait(3)
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatcher does not stop after SIGTERM")
+	}
+	if h.Shutdown() != syscall.SIGTERM {
+		t.Fatalf("Shutdown() = %v", h.Shutdown())
+	}
+
+	want := []string{"reload-a", "reload-b", "usr2", "close-server", "close-db"}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Fatalf("handlers ran: %v, want %v", order, want)
+	}
+}
+
+func TestSignalHandlerCoalesce(t *testing.T) {
+	var (
+		runs    int32
+		mu      sync.Mutex
+		started = make(chan struct{}, 10)
+		gate    = make(chan struct{})
+	)
+	h := NewSignalHandler(5 * time.Second)
+	h.Register(syscall.SIGUSR1, "slow", func(context.Context) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		started <- struct{}{}
+		<-gate
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.Start(ctx)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	<-started
+	for i := 0; i < 5; i++ {
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(gate)
+	<-started
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if runs != 2 {
+		t.Fatalf("handler ran %d times, want 2", runs)
+	}
+
+	cancel()
+	<-h.Done()
+}
@@ -0,0 +1,28 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux darwin
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+)
+
+func signal(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	switch err := syscall.Kill(pid, s); err {
+	case syscall.ESRCH:
+		return ErrProcessDone
+	case syscall.EPERM:
+		return ErrPermission
+	default:
+		return err
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"os"
+	"syscall"
+)
+
+var procGenerateConsoleCtrlEvent = modKernel32.NewProc("GenerateConsoleCtrlEvent")
+
+const CTRL_BREAK_EVENT = 1
+
+// signal supports os.Kill by TerminateProcess, and os.Interrupt by a
+// CTRL_BREAK_EVENT, which is only received by a process group leader
+// sharing the console of the caller, e.g. one started with
+// CREATE_NEW_PROCESS_GROUP. Other signals get ErrNotSupported.
+func signal(pid int, sig os.Signal) error {
+	switch sig {
+	case os.Kill:
+		p, err := lookup(pid)
+		if err != nil {
+			return err
+		}
+		return p.Kill()
+
+	case os.Interrupt:
+		r, _, err := procGenerateConsoleCtrlEvent.Call(CTRL_BREAK_EVENT, uintptr(pid))
+		if r == 0 {
+			if err == syscall.ERROR_ACCESS_DENIED {
+				return ErrPermission
+			}
+			return err
+		}
+		return nil
+	}
+
+	return ErrNotSupported
+}
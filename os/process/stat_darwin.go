@@ -0,0 +1,16 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+func stat(pid int) (*ProcessStat, error) {
+	p, err := lookup(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	return statOf(p)
+}
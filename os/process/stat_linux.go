@@ -0,0 +1,71 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"strconv"
+)
+
+// stat reads /proc/<pid>/stat and /proc/<pid>/statm once each, rather than
+// the several reads of the Process methods.
+func stat(pid int) (*ProcessStat, error) {
+	data, err := readProcFile(pid, "stat")
+	if err != nil {
+		return nil, err
+	}
+	s, startTime, err := parseProcStat(data)
+	if err != nil {
+		return nil, err
+	}
+	s.Pid = pid
+
+	statm, err := readProcFile(pid, "statm")
+	if err != nil {
+		return nil, err
+	}
+	if s.VMS, s.RSS, _, err = parseStatm(statm); err != nil {
+		return nil, err
+	}
+
+	boot, err := getBootTime()
+	if err != nil {
+		return nil, err
+	}
+	s.CreateTime = boot.Add(ticksToDuration(startTime))
+
+	// the fds of the processes of other users are not readable
+	s.NumFDs, err = (&LinuxProcess{pid: pid}).NumFDs()
+	if err == ErrProcessDone {
+		return nil, err
+	}
+
+	return s, err
+}
+
+// parseProcStat parses the CPU times and the thread count of the content
+// of /proc/<pid>/stat, and returns the starttime in clock ticks after boot.
+func parseProcStat(data []byte) (*ProcessStat, uint64, error) {
+	_, fields, err := parseStat(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// utime, stime, num_threads & starttime are the 14th, 15th, 20th &
+	// 22nd fields
+	var n [4]uint64
+	for i, idx := range []int{11, 12, 17, 19} {
+		if n[i], err = strconv.ParseUint(fields[idx], 10, 64); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return &ProcessStat{
+		User:       ticksToDuration(n[0]),
+		System:     ticksToDuration(n[1]),
+		NumThreads: int(n[2]),
+	}, n[3], nil
+}
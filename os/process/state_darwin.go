@@ -0,0 +1,57 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// State can not tell the uninterruptible sleep from the sleep, both are
+// StateSleeping.
+func (p *DarwinProcess) State() (ProcState, error) {
+	buf, err := darwinSyscall(_KERN_PROC_PID, int32(p.pid))
+	if err != nil {
+		return StateUnknown, err
+	}
+	if buf.Len() < _KINFO_STRUCT_SIZE {
+		return StateUnknown, ErrProcessDone
+	}
+
+	proc := &kinfoProc{}
+	if err = binary.Read(bytes.NewReader(buf.Bytes()), binary.LittleEndian, proc); err != nil {
+		return StateUnknown, err
+	}
+	if proc.startTime() != p.startTime {
+		return StateUnknown, ErrProcessDone
+	}
+
+	return darwinState(proc.Stat), nil
+}
+
+func (p *DarwinProcess) IsZombie() bool {
+	return isZombie(p)
+}
+
+func (p *DarwinProcess) IsRunning() bool {
+	return isRunning(p)
+}
+
+func darwinState(stat int8) ProcState {
+	switch stat {
+	case _SIDL, _SRUN: // SIDL is being created
+		return StateRunning
+	case _SSLEEP:
+		return StateSleeping
+	case _SSTOP:
+		return StateStopped
+	case _SZOMB:
+		return StateZombie
+	}
+
+	return StateUnknown
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+func (p *LinuxProcess) State() (ProcState, error) {
+	p.load(fieldStartTime)
+	q := &LinuxProcess{pid: p.pid}
+	if err := q.Refresh(); err != nil {
+		return StateUnknown, err
+	}
+	if q.startTime != p.startTime {
+		return StateUnknown, ErrProcessDone
+	}
+
+	return linuxState(q.state), nil
+}
+
+func (p *LinuxProcess) IsZombie() bool {
+	return isZombie(p)
+}
+
+func (p *LinuxProcess) IsRunning() bool {
+	return isRunning(p)
+}
+
+// linuxState translates the state character of /proc/<pid>/stat.
+func linuxState(c rune) ProcState {
+	switch c {
+	case 'R', 'W': // W is waking on old kernels
+		return StateRunning
+	case 'S', 'I', 'P', 'K': // idle kernel threads, parked, wakekill
+		return StateSleeping
+	case 'D':
+		return StateDiskSleep
+	case 'Z':
+		return StateZombie
+	case 'T', 't':
+		return StateStopped
+	case 'X', 'x':
+		return StateDead
+	}
+
+	return StateUnknown
+}
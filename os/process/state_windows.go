@@ -0,0 +1,39 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"syscall"
+)
+
+// Windows does not expose the scheduling state of processes, State only
+// tells StateRunning from StateDead, the latter is an exited process kept
+// by open handles.
+func (p *WindowsProcess) State() (ProcState, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return StateUnknown, err
+	}
+
+	var code uint32
+	if err = syscall.GetExitCodeProcess(h, &code); err != nil {
+		return StateUnknown, err
+	}
+	if code != STILL_ACTIVE {
+		return StateDead, nil
+	}
+
+	return StateRunning, nil
+}
+
+func (p *WindowsProcess) IsZombie() bool {
+	return false
+}
+
+func (p *WindowsProcess) IsRunning() bool {
+	return isRunning(p)
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TableEntry is a process of a Table.
+type TableEntry struct {
+	Process
+	Parent   *TableEntry   // nil for roots
+	Children []*TableEntry // in pid order
+
+	// filled by Table.Enrich
+	CPUPercent float64
+	RSS        uint64
+	Err        error // why the enrichment fails, e.g. ErrProcessDone
+}
+
+// Table is the process table at a point in time.
+type Table struct {
+	Time    time.Time
+	entries []*TableEntry // in pid order
+	byPid   map[int]*TableEntry
+}
+
+// TakeSnapshot lists all processes once and links them into a tree. The
+// processes exiting while listing are skipped, the others have all the
+// fields of WithFields(FieldAll) read.
+func TakeSnapshot() (*Table, error) {
+	ps, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+	sortByPid(ps)
+
+	t := &Table{
+		Time:    time.Now(),
+		entries: make([]*TableEntry, 0, len(ps)),
+		byPid:   make(map[int]*TableEntry, len(ps)),
+	}
+	for _, p := range ps {
+		e := &TableEntry{Process: p}
+		t.entries = append(t.entries, e)
+		t.byPid[p.Pid()] = e
+	}
+	for _, e := range t.entries {
+		// the idle process of Windows is its own parent
+		if parent, ok := t.byPid[e.PPid()]; ok && parent != e {
+			e.Parent = parent
+			parent.Children = append(parent.Children, e)
+		}
+	}
+
+	return t, nil
+}
+
+// Len returns the number of processes.
+func (t *Table) Len() int {
+	return len(t.entries)
+}
+
+// Entries returns all processes in pid order.
+func (t *Table) Entries() []*TableEntry {
+	return t.entries
+}
+
+// Find returns the process of @pid, or nil if it is not in the table.
+func (t *Table) Find(pid int) *TableEntry {
+	return t.byPid[pid]
+}
+
+// Tree returns the processes whose parents are not in the table, in pid
+// order. The others are reachable by their Children.
+func (t *Table) Tree() []*TableEntry {
+	var roots []*TableEntry
+	for _, e := range t.entries {
+		if e.Parent == nil {
+			roots = append(roots, e)
+		}
+	}
+
+	return roots
+}
+
+// Enrich reads the CPU usage during @interval and the RSS of all processes
+// with at most @concurrency goroutines. The processes failed to read have
+// their Err set.
+func (t *Table) Enrich(interval time.Duration, concurrency int) error {
+	if interval <= 0 {
+		return fmt.Errorf("illegal interval %v", interval)
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	busy := make([]time.Duration, len(t.entries))
+	t.each(concurrency, func(i int, e *TableEntry) {
+		user, system, err := e.CPUTimes()
+		busy[i], e.Err = user+system, err
+	})
+	start := time.Now()
+	time.Sleep(interval)
+
+	t.each(concurrency, func(i int, e *TableEntry) {
+		if e.Err != nil {
+			return
+		}
+		user, system, err := e.CPUTimes()
+		if err != nil {
+			e.Err = err
+			return
+		}
+		elapsed := time.Since(start)
+		e.CPUPercent = 100 * float64(user+system-busy[i]) / float64(elapsed) / float64(runtime.NumCPU())
+
+		mem, err := e.MemoryInfo()
+		if err != nil {
+			e.Err = err
+			return
+		}
+		e.RSS = mem.RSS
+	})
+
+	return nil
+}
+
+// each calls @fn for every entry with @concurrency goroutines.
+func (t *Table) each(concurrency int, fn func(i int, e *TableEntry)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, e := range t.entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, e *TableEntry) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i, e)
+		}(i, e)
+	}
+	wg.Wait()
+}
+
+// SortKey is the key to sort a Table by.
+type SortKey int
+
+const (
+	SortByPid SortKey = iota
+	SortByCPU         // descending, needs Enrich
+	SortByRSS         // descending, needs Enrich
+)
+
+// Sort returns the processes sorted by @key, ties in pid order.
+func (t *Table) Sort(key SortKey) []*TableEntry {
+	sorted := append([]*TableEntry(nil), t.entries...)
+	switch key {
+	case SortByCPU:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].CPUPercent > sorted[j].CPUPercent
+		})
+	case SortByRSS:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].RSS > sorted[j].RSS
+		})
+	}
+
+	return sorted
+}
@@ -0,0 +1,98 @@
+package gxprocess
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	table, err := TakeSnapshot()
+	if err != nil {
+		t.Fatalf("TakeSnapshot() = error %v", err)
+	}
+
+	self := table.Find(os.Getpid())
+	if self == nil {
+		t.Fatal("the current process is not in the table")
+	}
+	if self.Parent == nil || self.Parent.Pid() != os.Getppid() {
+		t.Fatalf("parent of the current process = %v", self.Parent)
+	}
+	if table.Find(-1) != nil {
+		t.Fatal("Find(-1) != nil")
+	}
+
+	var (
+		n    int
+		walk func([]*TableEntry)
+	)
+	walk = func(es []*TableEntry) {
+		for i, e := range es {
+			if i > 0 && es[i-1].Pid() >= e.Pid() {
+				t.Fatalf("entries are not in pid order")
+			}
+			n++
+			walk(e.Children)
+		}
+	}
+	walk(table.Tree())
+	if n != table.Len() || n != len(table.Entries()) {
+		t.Fatalf("%d processes in the tree, %d in the table", n, table.Len())
+	}
+}
+
+func TestTableEnrich(t *testing.T) {
+	table, err := TakeSnapshot()
+	if err != nil {
+		t.Fatalf("TakeSnapshot() = error %v", err)
+	}
+	if err = table.Enrich(0, 4); err == nil {
+		t.Fatal("Enrich(0) should fail")
+	}
+	if err = table.Enrich(50*time.Millisecond, 4); err != nil {
+		t.Fatalf("Enrich() = error %v", err)
+	}
+
+	if self := table.Find(os.Getpid()); self.Err != nil || self.RSS == 0 {
+		t.Fatalf("the current process = %+v", self)
+	}
+	sorted := table.Sort(SortByRSS)
+	if len(sorted) != table.Len() {
+		t.Fatalf("Sort() returns %d processes", len(sorted))
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].RSS < sorted[i].RSS {
+			t.Fatalf("not sorted by RSS at %d", i)
+		}
+	}
+	sorted = table.Sort(SortByCPU)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].CPUPercent < sorted[i].CPUPercent {
+			t.Fatalf("not sorted by CPU at %d", i)
+		}
+	}
+}
+
+// go test -bench=Table -run=^$
+func BenchmarkTableSnapshot(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		table, err := TakeSnapshot()
+		if err != nil {
+			b.Fatal(err)
+		}
+		table.Tree()
+	}
+}
+
+func BenchmarkTableProcessesLookup(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ps, err := Processes()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, p := range ps {
+			FindProcess(p.PPid())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// terminatePollInterval is the poll interval of Terminate on the platforms
+// which can not notify the exit.
+const terminatePollInterval = 10 * time.Millisecond
+
+// Signal sends @sig to the process @pid. It returns ErrProcessDone if the
+// process does not exist, ErrPermission if the caller may not signal it,
+// and ErrNotSupported if the platform has no such signal, e.g. Windows
+// supports os.Kill and os.Interrupt only.
+func Signal(pid int, sig os.Signal) error {
+	// kill(2) takes a non-positive pid as a process group
+	if pid <= 0 {
+		return fmt.Errorf("illegal pid %d", pid)
+	}
+
+	return signal(pid, sig)
+}
+
+// Terminate asks the process @pid to exit by SIGTERM, waits for it up to
+// @grace, and kills it then. On Windows, which can not ask a process to
+// exit, it kills the process at once. It returns nil if the process has
+// exited or does not exist, and ctx.Err() if @ctx is done first.
+func Terminate(ctx context.Context, pid int, grace time.Duration) error {
+	if pid <= 0 {
+		return fmt.Errorf("illegal pid %d", pid)
+	}
+
+	p, err := lookup(pid)
+	if err == ErrProcessDone {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// both calls check that the pid is not reused
+	if err = p.Terminate(); err != nil {
+		if err == ErrProcessDone {
+			return nil
+		}
+		return err
+	}
+	graceCtx, cancel := context.WithTimeout(ctx, grace)
+	err = waitTerminated(graceCtx, p)
+	cancel()
+	if err != context.DeadlineExceeded || ctx.Err() != nil {
+		return err
+	}
+
+	if err = p.Kill(); err != nil {
+		if err == ErrProcessDone {
+			return nil
+		}
+		return err
+	}
+	return waitTerminated(ctx, p)
+}
+
+func waitTerminated(ctx context.Context, p Process) error {
+	err := p.WaitExit(ctx, terminatePollInterval)
+	if err == ErrPidReused || err == ErrProcessDone {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,15 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"time"
+)
+
+func (p *DarwinProcess) CreateTime() (time.Time, error) {
+	return time.Unix(0, p.startTime*int64(time.Microsecond)), nil
+}
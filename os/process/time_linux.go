@@ -0,0 +1,56 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	bootTime     time.Time
+	bootTimeErr  error
+	bootTimeOnce sync.Once
+)
+
+// getBootTime reads the btime line of /proc/stat.
+func getBootTime() (time.Time, error) {
+	bootTimeOnce.Do(func() {
+		data, err := ioutil.ReadFile("/proc/stat")
+		if err != nil {
+			bootTimeErr = err
+			return
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "btime ") {
+				sec, err := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+				bootTime, bootTimeErr = time.Unix(sec, 0), err
+				return
+			}
+		}
+		bootTimeErr = fmt.Errorf("no btime in /proc/stat")
+	})
+
+	return bootTime, bootTimeErr
+}
+
+func (p *LinuxProcess) CreateTime() (time.Time, error) {
+	p.load(fieldStartTime)
+	if p.loaded&fieldStartTime == 0 {
+		return time.Time{}, ErrProcessDone
+	}
+	boot, err := getBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return boot.Add(ticksToDuration(p.startTime)), nil
+}
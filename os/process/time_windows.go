@@ -0,0 +1,30 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"syscall"
+	"time"
+)
+
+func (p *WindowsProcess) CreateTime() (time.Time, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return handleCreateTime(h)
+}
+
+func handleCreateTime(h syscall.Handle) (time.Time, error) {
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+// The ids come from the process snapshot.
+
+func (p *DarwinProcess) UIDs() ([]int, error) {
+	return p.uids, nil
+}
+
+func (p *DarwinProcess) GIDs() ([]int, error) {
+	return p.gids, nil
+}
+
+func (p *DarwinProcess) Username() (string, error) {
+	return lookupUsername(p.uids[1])
+}
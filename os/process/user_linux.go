@@ -0,0 +1,58 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusIDs parses the "Uid" or "Gid" line of /proc/<pid>/status, which is
+// real, effective, saved set and filesystem id.
+func (p *LinuxProcess) statusIDs(key string) ([]int, error) {
+	status, err := readProcFile(p.pid, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatusIDs(status, key)
+}
+
+// parseStatusIDs parses the ids of the line @key of @status.
+func parseStatusIDs(status []byte, key string) ([]int, error) {
+	var err error
+	fields := strings.Fields(statusField(status, key))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid %s line %q", key, fields)
+	}
+	ids := make([]int, 3)
+	for i := range ids {
+		if ids[i], err = strconv.Atoi(fields[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+func (p *LinuxProcess) UIDs() ([]int, error) {
+	return p.statusIDs("Uid")
+}
+
+func (p *LinuxProcess) GIDs() ([]int, error) {
+	return p.statusIDs("Gid")
+}
+
+func (p *LinuxProcess) Username() (string, error) {
+	uids, err := p.UIDs()
+	if err != nil {
+		return "", err
+	}
+
+	return lookupUsername(uids[1])
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"syscall"
+)
+
+func (p *WindowsProcess) UIDs() ([]int, error) {
+	return nil, ErrNotImplemented
+}
+
+func (p *WindowsProcess) GIDs() ([]int, error) {
+	return nil, ErrNotImplemented
+}
+
+// Username returns the "domain\user" of the process token owner.
+func (p *WindowsProcess) Username() (string, error) {
+	h, err := p.getHandle()
+	if err != nil {
+		return "", err
+	}
+
+	var token syscall.Token
+	if err = syscall.OpenProcessToken(h, syscall.TOKEN_QUERY, &token); err != nil {
+		return "", ErrPermission
+	}
+	defer token.Close()
+
+	tu, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	name, domain, _, err := tu.User.Sid.LookupAccount("")
+	if err != nil {
+		return "", err
+	}
+
+	return domain + `\` + name, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build darwin
+
+package gxprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+)
+
+func (p *DarwinProcess) WaitExit(ctx context.Context, pollInterval time.Duration) error {
+	return waitExit(ctx, pollInterval, p.exited)
+}
+
+func (p *DarwinProcess) exited() (bool, error) {
+	buf, err := darwinSyscall(_KERN_PROC_PID, int32(p.pid))
+	if err != nil {
+		return false, err
+	}
+	if buf.Len() < _KINFO_STRUCT_SIZE {
+		return true, nil
+	}
+
+	proc := &kinfoProc{}
+	if err = binary.Read(bytes.NewReader(buf.Bytes()), binary.LittleEndian, proc); err != nil {
+		return false, err
+	}
+	if proc.startTime() != p.startTime {
+		return true, ErrPidReused
+	}
+
+	return proc.Stat == _SZOMB, nil
+}
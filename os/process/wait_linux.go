@@ -0,0 +1,97 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build linux
+
+package gxprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// pidfd_open has the same number on all architectures, since linux 5.3.
+const _SYS_PIDFD_OPEN = 434
+
+func (p *LinuxProcess) WaitExit(ctx context.Context, pollInterval time.Duration) error {
+	r, _, errno := syscall.Syscall(_SYS_PIDFD_OPEN, uintptr(p.pid), 0, 0)
+	switch errno {
+	case 0:
+		if err := p.waitPidfd(ctx, int(r)); err != errNotPollable {
+			return err
+		}
+	case syscall.ESRCH:
+		return nil
+	}
+
+	return waitExit(ctx, pollInterval, p.exited)
+}
+
+// exited checks whether the process has exited, zombies included.
+func (p *LinuxProcess) exited() (bool, error) {
+	q := &LinuxProcess{pid: p.pid}
+	switch err := q.Refresh(); {
+	case err == ErrProcessDone:
+		return true, nil
+	case err != nil:
+		return false, err
+	case q.startTime != p.startTime:
+		return true, ErrPidReused
+	}
+
+	return q.state == 'Z', nil
+}
+
+var errNotPollable = fmt.Errorf("pidfd is not pollable")
+
+// waitPidfd waits for the pidfd @fd to be readable, which means the
+// process has exited. It returns errNotPollable if the runtime poller
+// can not wait on it.
+func (p *LinuxProcess) waitPidfd(ctx context.Context, fd int) error {
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return errNotPollable
+	}
+	f := os.NewFile(uintptr(fd), "pidfd")
+	defer f.Close()
+
+	// the pid may have been reused before pidfd_open
+	if done, err := p.exited(); done || err != nil {
+		return err
+	}
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return errNotPollable
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	// the first call just makes rc wait for readability
+	readable := false
+	err = rc.Read(func(uintptr) bool {
+		r := readable
+		readable = true
+		return r
+	})
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	}
+
+	return errNotPollable
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build windows
+
+package gxprocess
+
+import (
+	"context"
+	"syscall"
+	"time"
+)
+
+const STILL_ACTIVE = 259
+
+// WaitExit waits on the process handle, which is signaled when the process
+// exits. @pollInterval is how often the waiting goroutine checks @ctx.
+func (p *WindowsProcess) WaitExit(ctx context.Context, pollInterval time.Duration) error {
+	h, err := p.getHandle()
+	if err == ErrProcessDone {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+
+	var (
+		result = make(chan error, 1)
+		stop   = make(chan struct{})
+	)
+	defer close(stop)
+	go func() {
+		for {
+			ev, err := syscall.WaitForSingleObject(h, uint32(pollInterval/time.Millisecond))
+			switch {
+			case err != nil:
+				result <- err
+				return
+			case ev == syscall.WAIT_OBJECT_0:
+				result <- nil
+				return
+			}
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	select {
+	case err = <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+package gxprocess
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	procEventChanSize = 32
+)
+
+var (
+	ErrWatcherClosed = fmt.Errorf("process watcher has been closed")
+)
+
+type ProcAction int
+
+const (
+	ProcStart ProcAction = iota
+	ProcExit
+)
+
+var procActionStrings = [...]string{
+	"start",
+	"exit",
+}
+
+func (a ProcAction) String() string {
+	if int(a) < len(procActionStrings) {
+		return procActionStrings[a]
+	}
+
+	return fmt.Sprintf("ProcAction(%d)", int(a))
+}
+
+// ProcEvent is a process start or exit. The process information is
+// captured when the event is detected, for exit events it is the last
+// information seen before the process exits.
+type ProcEvent struct {
+	Action     ProcAction
+	Pid        int
+	PPid       int
+	Executable string
+	CreateTime time.Time
+}
+
+type procEvent struct {
+	ev  *ProcEvent
+	err error
+}
+
+// procKey identifies a process even if its pid is reused.
+type procKey struct {
+	pid        int
+	createTime time.Time
+}
+
+// ProcWatcher reports the start and exit of the processes matched by its
+// matcher by diffing the process table periodically. Processes which start
+// and exit within an interval are not reported.
+type ProcWatcher struct {
+	matcher  func(Process) bool
+	interval time.Duration
+	procs    map[procKey]*ProcEvent
+	events   chan procEvent
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewWatcher watches the processes @matcher returns true for every
+// @interval. Processes running when it is called are not reported as
+// started. A nil @matcher matches all processes.
+func NewWatcher(matcher func(Process) bool, interval time.Duration) (*ProcWatcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("illegal interval %v", interval)
+	}
+	if matcher == nil {
+		matcher = func(Process) bool { return true }
+	}
+
+	w := &ProcWatcher{
+		matcher:  matcher,
+		interval: interval,
+		events:   make(chan procEvent, procEventChanSize),
+		done:     make(chan struct{}),
+	}
+	procs, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.procs = procs
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// scan returns the matched processes now.
+func (w *ProcWatcher) scan() (map[procKey]*ProcEvent, error) {
+	ps, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	procs := make(map[procKey]*ProcEvent, len(ps))
+	for _, p := range ps {
+		if !w.matcher(p) {
+			continue
+		}
+		// the process may have gone, the zero time is ok for the key
+		createTime, _ := p.CreateTime()
+		procs[procKey{pid: p.Pid(), createTime: createTime}] = &ProcEvent{
+			Pid:        p.Pid(),
+			PPid:       p.PPid(),
+			Executable: p.Executable(),
+			CreateTime: createTime,
+		}
+	}
+
+	return procs, nil
+}
+
+func (w *ProcWatcher) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		procs, err := w.scan()
+		if err != nil {
+			if !w.send(procEvent{err: err}) {
+				return
+			}
+			continue
+		}
+
+		for k, ev := range w.procs {
+			if _, ok := procs[k]; !ok {
+				exit := *ev
+				exit.Action = ProcExit
+				if !w.send(procEvent{ev: &exit}) {
+					return
+				}
+			}
+		}
+		for k, ev := range procs {
+			if _, ok := w.procs[k]; !ok {
+				start := *ev
+				start.Action = ProcStart
+				if !w.send(procEvent{ev: &start}) {
+					return
+				}
+			}
+		}
+		w.procs = procs
+	}
+}
+
+// send returns false if the watcher has been closed.
+func (w *ProcWatcher) send(e procEvent) bool {
+	select {
+	case <-w.done:
+		return false
+	case w.events <- e:
+		return true
+	}
+}
+
+// Notify blocks until a process event or a scan error occurs.
+func (w *ProcWatcher) Notify() (*ProcEvent, error) {
+	select {
+	case <-w.done:
+		return nil, ErrWatcherClosed
+
+	case e := <-w.events:
+		return e.ev, e.err
+	}
+}
+
+// Close stops the watcher and drops the events not notified yet.
+func (w *ProcWatcher) Close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+
+		for {
+			select {
+			case <-w.events:
+			default:
+				return
+			}
+		}
+	})
+}
+
+// IsClosed checks whether the watcher has been closed.
+func (w *ProcWatcher) IsClosed() bool {
+	select {
+	case <-w.done:
+		return true
+
+	default:
+		return false
+	}
+}
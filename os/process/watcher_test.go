@@ -0,0 +1,56 @@
+package gxprocess
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcWatcher(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep")
+	}
+	data, err := ioutil.ReadFile(sleep)
+	if err != nil {
+		t.Fatalf("ReadFile() = error %v", err)
+	}
+	// a unique name to match
+	bin := filepath.Join(t.TempDir(), "gxwatchsleep")
+	if err = ioutil.WriteFile(bin, data, 0755); err != nil {
+		t.Fatalf("WriteFile() = error %v", err)
+	}
+
+	w, err := NewWatcher(func(p Process) bool {
+		return p.Executable() == "gxwatchsleep"
+	}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher() = error %v", err)
+	}
+	defer w.Close()
+
+	cmd := exec.Command(bin, "0.2")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Start() = error %v", err)
+	}
+
+	ev, err := w.Notify()
+	if err != nil || ev.Action != ProcStart || ev.Pid != cmd.Process.Pid {
+		t.Fatalf("Notify() = %+v, %v", ev, err)
+	}
+	cmd.Wait()
+	exit, err := w.Notify()
+	if err != nil || exit.Action != ProcExit || exit.Pid != ev.Pid || !exit.CreateTime.Equal(ev.CreateTime) {
+		t.Fatalf("Notify() = %+v, %v", exit, err)
+	}
+
+	w.Close()
+	if !w.IsClosed() {
+		t.Fatal("watcher should be closed")
+	}
+	if _, err = w.Notify(); err != ErrWatcherClosed {
+		t.Fatalf("Notify() after Close = %v", err)
+	}
+}
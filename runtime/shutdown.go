@@ -0,0 +1,310 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxruntime encapsulates some runtime functions
+// shutdown.go provides a graceful shutdown coordinator
+package gxruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+	"github.com/AlexStocks/goext/os/process"
+)
+
+const (
+	defaultHookTimeout      = 10 * time.Second
+	defaultShutdownDeadline = 30 * time.Second
+	// ForceExitCode is the exit code of a shutdown past its deadline.
+	ForceExitCode = 2
+)
+
+var (
+	ErrShutdownTimeout = fmt.Errorf("shutdown deadline exceeded")
+)
+
+// stopErrors are the errors of the hooks, as errors.Join needs go1.20.
+type stopErrors struct {
+	errs []error
+}
+
+func (e *stopErrors) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the errors is @target.
+func (e *stopErrors) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches @target.
+func (e *stopErrors) As(target interface{}) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *stopErrors) Unwrap() []error {
+	return e.errs
+}
+
+type stopHook struct {
+	name  string
+	stop  func(context.Context) error
+	order int
+}
+
+// Coordinator runs the stop hooks of a process when it shuts down, e.g.
+//
+//	c := gxruntime.NewCoordinator()
+//	c.Register("http", server.Shutdown, 0)
+//	c.Register("registry", gxregistry.DeregisterHook(r, service), 1)
+//	c.Register("watcher", gxregistry.CloseWatcherHook(w), 2)
+//	err := c.Run(context.Background())
+//
+// The hooks of an order run concurrently, and the orders run one after
+// another from the lowest. If the hooks do not finish in the deadline of
+// the shutdown, the goroutines are dumped and the process exits.
+type Coordinator struct {
+	hookTimeout time.Duration
+	deadline    time.Duration
+	signals     []os.Signal
+	logger      gxlog.Logger
+	dump        io.Writer
+	exit        func(code int)
+
+	sync.Mutex
+	hooks []stopHook
+	once  sync.Once
+	err   error // of the shutdown
+}
+
+type CoordinatorOption func(*Coordinator)
+
+// WithHookTimeout gives each hook @d to stop, 10s by default.
+func WithHookTimeout(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.hookTimeout = d
+	}
+}
+
+// WithShutdownDeadline forces the process to exit @d after the shutdown
+// started, 30s by default.
+func WithShutdownDeadline(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.deadline = d
+	}
+}
+
+// WithShutdownSignals shuts down on @sigs, SIGINT and SIGTERM by default.
+func WithShutdownSignals(sigs ...os.Signal) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.signals = sigs
+	}
+}
+
+// WithShutdownLogger logs the shutdown by @l, gxlog.Default() by default.
+func WithShutdownLogger(l gxlog.Logger) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.logger = l
+	}
+}
+
+// WithForceExit dumps the goroutines to @dump and calls @exit with
+// ForceExitCode when the deadline is exceeded, os.Stderr and os.Exit by
+// default.
+func WithForceExit(dump io.Writer, exit func(code int)) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.dump = dump
+		c.exit = exit
+	}
+}
+
+// NewCoordinator returns a coordinator of the options.
+func NewCoordinator(opts ...CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		hookTimeout: defaultHookTimeout,
+		deadline:    defaultShutdownDeadline,
+		signals:     []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		dump:        os.Stderr,
+		exit:        os.Exit,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.hookTimeout <= 0 {
+		c.hookTimeout = defaultHookTimeout
+	}
+	if c.deadline <= 0 {
+		c.deadline = defaultShutdownDeadline
+	}
+	if c.logger == nil {
+		c.logger = gxlog.Default()
+	}
+
+	return c
+}
+
+// Register adds the hook @stop named @name of the order @order.
+func (c *Coordinator) Register(name string, stop func(ctx context.Context) error, order int) {
+	c.Lock()
+	c.hooks = append(c.hooks, stopHook{name: name, stop: stop, order: order})
+	c.Unlock()
+}
+
+// Run waits for a shutdown signal or the end of @ctx, then shuts down.
+func (c *Coordinator) Run(ctx context.Context) error {
+	var (
+		sigc = make(chan os.Signal, 1)
+		h    = gxprocess.NewSignalHandler(c.deadline)
+	)
+	for _, sig := range c.signals {
+		sig := sig
+		h.Register(sig, "shutdown", func(context.Context) error {
+			select {
+			case sigc <- sig:
+			default:
+			}
+			return nil
+		})
+	}
+	hctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := h.Start(hctx); err != nil {
+		return err
+	}
+
+	select {
+	case sig := <-sigc:
+		c.logger.Warnf("shut down by signal %v", sig)
+	case <-ctx.Done():
+		c.logger.Warnf("shut down by context: %v", ctx.Err())
+	}
+
+	return c.Shutdown()
+}
+
+// Shutdown runs the hooks once, and returns their errors joined. It calls
+// the force exit, and returns ErrShutdownTimeout, if they do not finish in
+// the deadline.
+func (c *Coordinator) Shutdown() error {
+	c.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.deadline)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.stop(ctx)
+		}()
+
+		select {
+		case c.err = <-done:
+		case <-ctx.Done():
+			c.logger.Errorf("shutdown deadline %v exceeded, force to exit", c.deadline)
+			fmt.Fprintf(c.dump, "shutdown deadline %v exceeded, goroutines:\n", c.deadline)
+			pprof.Lookup("goroutine").WriteTo(c.dump, 2)
+			c.exit(ForceExitCode)
+			c.err = ErrShutdownTimeout
+		}
+	})
+
+	return c.err
+}
+
+// stop runs the hooks by their orders.
+func (c *Coordinator) stop(ctx context.Context) error {
+	c.Lock()
+	hooks := append([]stopHook(nil), c.hooks...)
+	c.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].order < hooks[j].order
+	})
+
+	var errs []error
+	for i := 0; i < len(hooks); {
+		j := i + 1
+		for j < len(hooks) && hooks[j].order == hooks[i].order {
+			j++
+		}
+
+		group := hooks[i:j]
+		groupErrs := make([]error, len(group))
+		var wg sync.WaitGroup
+		for k := range group {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				groupErrs[k] = c.run(ctx, group[k])
+			}(k)
+		}
+		wg.Wait()
+		for _, err := range groupErrs {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		i = j
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &stopErrors{errs: errs}
+}
+
+// run runs @hook within its timeout, and returns its error or panic. It
+// does not wait for a hook past its timeout.
+func (c *Coordinator) run(ctx context.Context, hook stopHook) error {
+	ctx, cancel := context.WithTimeout(ctx, c.hookTimeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		errc <- hook.stop(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		c.logger.Errorf("stop hook %s: %v", hook.name, err)
+		return fmt.Errorf("stop hook %s: %w", hook.name, err)
+	}
+	c.logger.Infof("stop hook %s done", hook.name)
+
+	return nil
+}
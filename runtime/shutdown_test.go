@@ -0,0 +1,165 @@
+// +build linux darwin
+
+package gxruntime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+)
+
+type stopRecorder struct {
+	sync.Mutex
+	order []string
+}
+
+func (r *stopRecorder) hook(name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		r.Lock()
+		r.order = append(r.order, name)
+		r.Unlock()
+		return err
+	}
+}
+
+func (r *stopRecorder) names() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+func TestCoordinatorShutdown(t *testing.T) {
+	var (
+		rec  stopRecorder
+		fail = fmt.Errorf("deregister failed")
+	)
+	c := NewCoordinator(WithShutdownLogger(gxlog.NewNop()))
+	c.Register("watcher", rec.hook("watcher", nil), 2)
+	c.Register("server", rec.hook("server", nil), 0)
+	c.Register("registry", rec.hook("registry", fail), 1)
+	c.Register("panic", func(context.Context) error { panic("oops") }, 1)
+	c.Register("worker", rec.hook("worker", nil), 0)
+
+	err := c.Shutdown()
+	if !errors.Is(err, fail) || !strings.Contains(err.Error(), "stop hook panic: panic: oops") {
+		t.Fatalf("Shutdown() = error:%v", err)
+	}
+	names := rec.names()
+	if len(names) != 4 || names[2] != "registry" || names[3] != "watcher" ||
+		!(names[0] == "server" && names[1] == "worker" || names[0] == "worker" && names[1] == "server") {
+		t.Fatalf("stop order:%v", names)
+	}
+
+	// the hooks run once
+	if err2 := c.Shutdown(); err2 != err || len(rec.names()) != 4 {
+		t.Fatalf("Shutdown() again = error:%v, order:%v", err2, rec.names())
+	}
+}
+
+func TestCoordinatorHookTimeout(t *testing.T) {
+	var rec stopRecorder
+	c := NewCoordinator(WithShutdownLogger(gxlog.NewNop()), WithHookTimeout(20*time.Millisecond))
+	c.Register("hang", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second) // ignores its timeout
+		return nil
+	}, 0)
+	c.Register("next", rec.hook("next", nil), 1)
+
+	start := time.Now()
+	err := c.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stop hook hang") {
+		t.Fatalf("Shutdown() = error:%v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("Shutdown() waits for the hung hook %v", d)
+	}
+	if names := rec.names(); len(names) != 1 {
+		t.Fatalf("the hooks after a timeout ran:%v", names)
+	}
+}
+
+func TestCoordinatorForceExit(t *testing.T) {
+	var (
+		dump bytes.Buffer
+		code = make(chan int, 1)
+	)
+	c := NewCoordinator(
+		WithShutdownLogger(gxlog.NewNop()),
+		WithShutdownDeadline(100*time.Millisecond),
+		WithForceExit(&dump, func(c int) { code <- c }),
+	)
+	// each slow hook is in its timeout, but all of them are past the deadline
+	for i := 0; i < 3; i++ {
+		c.Register(fmt.Sprintf("slow%d", i), func(ctx context.Context) error {
+			select {
+			case <-time.After(60 * time.Millisecond):
+			case <-ctx.Done():
+			}
+			return nil
+		}, i)
+	}
+
+	if err := c.Shutdown(); err != ErrShutdownTimeout {
+		t.Fatalf("Shutdown() = error:%v", err)
+	}
+	select {
+	case c := <-code:
+		if c != ForceExitCode {
+			t.Fatalf("exit code %d", c)
+		}
+	default:
+		t.Fatalf("the process is not forced to exit")
+	}
+	if !strings.Contains(dump.String(), "shutdown deadline 100ms exceeded") ||
+		!strings.Contains(dump.String(), "goroutine ") {
+		t.Fatalf("goroutine dump:%s", dump.String())
+	}
+}
+
+func TestCoordinatorRun(t *testing.T) {
+	var rec stopRecorder
+	c := NewCoordinator(WithShutdownLogger(gxlog.NewNop()), WithShutdownSignals(syscall.SIGUSR1))
+	c.Register("server", rec.hook("server", nil), 0)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Run(context.Background())
+	}()
+	// wait for the signal handler to listen
+	time.Sleep(50 * time.Millisecond)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("Run() = error:%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() does not return after the signal")
+	}
+	if names := rec.names(); len(names) != 1 {
+		t.Fatalf("stop hooks ran:%v", names)
+	}
+
+	// shut down by the context
+	c = NewCoordinator(WithShutdownLogger(gxlog.NewNop()))
+	c.Register("server", rec.hook("server2", nil), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run() = error:%v", err)
+	}
+	if names := rec.names(); len(names) != 2 || names[1] != "server2" {
+		t.Fatalf("stop hooks ran:%v", names)
+	}
+}
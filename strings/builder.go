@@ -0,0 +1,230 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/AlexStocks/goext/sync"
+)
+
+const (
+	maxPooledBuilderSize = 64 << 10
+)
+
+var (
+	ErrPathTraversal = fmt.Errorf("\"..\" in the path")
+)
+
+var (
+	builderPool = gxsync.NewPool(func() interface{} {
+		return new(bytes.Buffer)
+	})
+)
+
+// GetBuilder returns an empty builder from a pool, it should be put back by
+// PutBuilder after its String is taken. It is a bytes.Buffer, as a
+// strings.Builder drops its memory on Reset.
+func GetBuilder() *bytes.Buffer {
+	return builderPool.Get().(*bytes.Buffer)
+}
+
+// PutBuilder resets @b and puts it back to the pool. The builders grown too
+// large are dropped.
+func PutBuilder(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuilderSize {
+		return
+	}
+	b.Reset()
+	builderPool.Put(b)
+}
+
+// itemSize returns the length of @item in JoinAny, or -1 if it is unknown
+// before formatting.
+func itemSize(item interface{}) int {
+	switch v := item.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case int:
+		return intSize(int64(v))
+	case int64:
+		return intSize(v)
+	case int32:
+		return intSize(int64(v))
+	case uint:
+		return uintSize(uint64(v))
+	case uint64:
+		return uintSize(v)
+	case uint32:
+		return uintSize(uint64(v))
+	case bool:
+		if v {
+			return 4
+		}
+		return 5
+	}
+
+	return -1
+}
+
+func uintSize(v uint64) int {
+	n := 1
+	for v >= 10 {
+		v /= 10
+		n++
+	}
+	return n
+}
+
+func intSize(v int64) int {
+	if v < 0 {
+		return 1 + uintSize(uint64(-(v+1))+1)
+	}
+	return uintSize(uint64(v))
+}
+
+func appendItem(b []byte, item interface{}) []byte {
+	switch v := item.(type) {
+	case string:
+		return append(b, v...)
+	case []byte:
+		return append(b, v...)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case fmt.Stringer:
+		return append(b, v.String()...)
+	}
+
+	return append(b, fmt.Sprint(item)...)
+}
+
+// JoinAny joins @items formatted like fmt.Sprint with @sep. The size of the
+// strings, []byte, integers and bools is computed before, so that only the
+// result is allocated if all items are of them.
+func JoinAny(sep string, items ...interface{}) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	size := len(sep) * (len(items) - 1)
+	for _, item := range items {
+		if n := itemSize(item); n >= 0 {
+			size += n
+		} else {
+			size += 16
+		}
+	}
+
+	b := make([]byte, 0, size)
+	for i, item := range items {
+		if i > 0 {
+			b = append(b, sep...)
+		}
+		b = appendItem(b, item)
+	}
+
+	// b is never modified after
+	return String(b)
+}
+
+// Join is strings.Join(@parts, @sep) in one allocation of the result. The
+// @parts of a call with a few arguments do not escape, so a call like
+// Join("/", root, service, node) allocates no slice for them either.
+func Join(sep string, parts ...string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+
+	size := len(sep) * (len(parts) - 1)
+	for _, part := range parts {
+		size += len(part)
+	}
+	b := make([]byte, 0, size)
+	b = append(b, parts[0]...)
+	for _, part := range parts[1:] {
+		b = append(b, sep...)
+		b = append(b, part...)
+	}
+
+	// b is never modified after
+	return String(b)
+}
+
+// JoinPath joins @segs with "/" like path.Join, but a ".." element is an
+// error instead of being resolved, as it must be for the zk paths. The
+// empty and "." elements are dropped, and the result is rooted if the
+// first non-empty segment is.
+func JoinPath(segs ...string) (string, error) {
+	var (
+		size   int
+		rooted bool
+		first  = true
+	)
+	for _, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		if first {
+			rooted = seg[0] == '/'
+			first = false
+		}
+		size += len(seg) + 1
+	}
+
+	b := make([]byte, 0, size)
+	for _, seg := range segs {
+		for len(seg) > 0 {
+			var elem string
+			if i := strings.IndexByte(seg, '/'); i >= 0 {
+				elem, seg = seg[:i], seg[i+1:]
+			} else {
+				elem, seg = seg, ""
+			}
+			switch elem {
+			case "", ".":
+				continue
+			case "..":
+				return "", ErrPathTraversal
+			}
+			if len(b) > 0 || rooted {
+				b = append(b, '/')
+			}
+			b = append(b, elem...)
+		}
+	}
+	if len(b) == 0 {
+		switch {
+		case rooted:
+			return "/", nil
+		case !first:
+			return ".", nil
+		}
+	}
+
+	return String(b), nil
+}
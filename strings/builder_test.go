@@ -0,0 +1,207 @@
+package gxstrings
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/sync"
+)
+
+func TestBuilderPool(t *testing.T) {
+	var errs []*gxsync.PoolError
+	saved := builderPool
+	builderPool = gxsync.NewDebugPool(func() interface{} {
+		return new(bytes.Buffer)
+	}, func(e *gxsync.PoolError) {
+		errs = append(errs, e)
+	})
+	defer func() {
+		builderPool = saved
+	}()
+
+	b := GetBuilder()
+	b.WriteString("hello")
+	s := b.String()
+	PutBuilder(b)
+	if s != "hello" {
+		t.Fatalf("String() = %q", s)
+	}
+	if b = GetBuilder(); b.Len() != 0 {
+		t.Fatalf("a builder of %d bytes from the pool", b.Len())
+	}
+	PutBuilder(b)
+
+	big := GetBuilder()
+	big.Grow(maxPooledBuilderSize + 1)
+	PutBuilder(big) // dropped
+	if len(errs) != 0 {
+		t.Fatalf("pool errors: %v", errs)
+	}
+}
+
+type stringer struct{}
+
+func (stringer) String() string {
+	return "stringer"
+}
+
+func TestJoinAny(t *testing.T) {
+	items := []interface{}{
+		"a", []byte("b"), 0, -1, int64(math.MinInt64), int64(42), int32(-7), uint(3),
+		uint64(math.MaxUint64), uint32(9), true, false, stringer{}, 1.5, nil, time.Second,
+	}
+	want := make([]string, len(items))
+	for i, item := range items {
+		if b, ok := item.([]byte); ok {
+			want[i] = string(b)
+		} else {
+			want[i] = fmt.Sprint(item)
+		}
+		if n := itemSize(item); n >= 0 && n != len(want[i]) {
+			t.Fatalf("itemSize(%#v) = %d, want %d", item, n, len(want[i]))
+		}
+	}
+
+	if s := JoinAny(", ", items...); s != strings.Join(want, ", ") {
+		t.Fatalf("JoinAny() = %q", s)
+	}
+	if s := JoinAny(","); s != "" {
+		t.Fatalf("JoinAny() of nothing = %q", s)
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	for _, segs := range [][]string{
+		{},
+		{""},
+		{"."},
+		{"/"},
+		{"", "/a"},
+		{"/dubbo", "service", "providers"},
+		{"/dubbo/", "/service//", "./providers/"},
+		{"a", "", "b"},
+		{"a/./b", "c"},
+	} {
+		got, err := JoinPath(segs...)
+		if want := path.Join(segs...); err != nil || got != want {
+			t.Fatalf("JoinPath(%q) = %q, %v, want %q", segs, got, err, want)
+		}
+	}
+
+	for _, segs := range [][]string{{"/dubbo", ".."}, {"a/../b"}, {"..", "a"}} {
+		if _, err := JoinPath(segs...); err != ErrPathTraversal {
+			t.Fatalf("JoinPath(%q) = %v", segs, err)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	for _, parts := range [][]string{
+		nil, {"a"}, {"", ""}, {"/dubbo", "provider"}, {"a", "", "c"}, {"a", "b", "c", "d"},
+		{"a", "b", "c", "d", "e"},
+	} {
+		for _, sep := range []string{"", "/", ", "} {
+			if s, want := Join(sep, parts...), strings.Join(parts, sep); s != want {
+				t.Fatalf("Join(%q, %q) = %q, want %q", sep, parts, s, want)
+			}
+		}
+	}
+
+	root, service := "/dubbo", "com.ikurento.user.UserProvider"
+	if n := testing.AllocsPerRun(100, func() {
+		Join("/", root, service, "providers")
+	}); n != 1 {
+		t.Fatalf("Join() allocates %v times", n)
+	}
+}
+
+// go test -bench Join -benchmem -run ^$
+func BenchmarkJoinAny(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		JoinAny(" ", "service", 8080, "pid", int64(i), true)
+	}
+}
+
+func BenchmarkJoinAnyNaive(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		items := []interface{}{"service", 8080, "pid", int64(i), true}
+		strs := make([]string, len(items))
+		for j, item := range items {
+			strs[j] = fmt.Sprint(item)
+		}
+		_ = strings.Join(strs, " ")
+	}
+}
+
+func BenchmarkJoinPath(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		JoinPath("/dubbo", "com.ikurento.user.UserProvider", "providers")
+	}
+}
+
+func BenchmarkJoinPathStd(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = path.Join("/dubbo", "com.ikurento.user.UserProvider", "providers")
+	}
+}
+
+func BenchmarkBuilderPool(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := GetBuilder()
+		for _, seg := range []string{"/dubbo", "/com.ikurento.user.UserProvider", "/providers", "/node"} {
+			buf.WriteString(seg)
+		}
+		_ = buf.String()
+		PutBuilder(buf)
+	}
+}
+
+func BenchmarkBuilderNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var sb strings.Builder
+		for _, seg := range []string{"/dubbo", "/com.ikurento.user.UserProvider", "/providers", "/node"} {
+			sb.WriteString(seg)
+		}
+		_ = sb.String()
+	}
+}
+
+var (
+	joinRoot    = "/dubbo"
+	joinService = "com.ikurento.user.UserProvider"
+)
+
+func BenchmarkJoin(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Join("/", joinRoot, joinService, "providers")
+	}
+}
+
+func BenchmarkJoinStd(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parts := []string{joinRoot, joinService, "providers"}
+		_ = strings.Join(parts, "/")
+	}
+}
+
+func BenchmarkJoinSprintf(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = fmt.Sprintf("%s/%s/%s", joinRoot, joinService, "providers")
+	}
+}
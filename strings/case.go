@@ -0,0 +1,173 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	acronymLock sync.RWMutex
+	// lower case, see RegisterAcronym
+	acronyms = map[string]bool{}
+)
+
+func init() {
+	RegisterAcronym(
+		"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+		"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+		"SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "URI",
+		"URL", "UTF8", "UUID", "VM", "XML", "XMPP", "XSRF", "XSS", "ZK",
+	)
+}
+
+// RegisterAcronym adds @words to the acronyms, which are upper case in
+// ToCamel and ToPascal, and split from the rest of an upper case run.
+func RegisterAcronym(words ...string) {
+	acronymLock.Lock()
+	defer acronymLock.Unlock()
+
+	for _, w := range words {
+		acronyms[strings.ToLower(w)] = true
+	}
+}
+
+func isAcronym(word string) bool {
+	acronymLock.RLock()
+	defer acronymLock.RUnlock()
+
+	return acronyms[word]
+}
+
+func isCaseSeparator(r rune) bool {
+	switch r {
+	case '_', '-', ' ', '.', '/', '\t':
+		return true
+	}
+	return false
+}
+
+// splitAcronyms splits an upper case run into acronyms and an optional
+// tail of 2 runes at least, like "IPID" into "IP", "ID" and "APIV2" into
+// "API", "V2", or returns nil if it can not.
+func splitAcronyms(word string) []string {
+	if word == "" {
+		return []string{}
+	}
+	for i := len(word); i > 0; i-- {
+		if !isAcronym(strings.ToLower(word[:i])) {
+			continue
+		}
+		if rest := splitAcronyms(word[i:]); rest != nil {
+			return append([]string{word[:i]}, rest...)
+		}
+		if utf8.RuneCountInString(word[i:]) >= 2 {
+			return []string{word[:i], word[i:]}
+		}
+	}
+
+	return nil
+}
+
+// words splits @s into its lower case words, by the separators, the case
+// changes and the acronym runs: "HTTPServerID" is "http", "server", "id".
+func words(s string) []string {
+	var (
+		words []string
+		start = -1
+	)
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := s[start:end]
+		start = -1
+		if len(word) > 1 && strings.ToUpper(word) == word && !isAcronym(strings.ToLower(word)) {
+			if parts := splitAcronyms(word); parts != nil {
+				for _, part := range parts {
+					words = append(words, strings.ToLower(part))
+				}
+				return
+			}
+		}
+		words = append(words, strings.ToLower(word))
+	}
+
+	var prev rune
+	for i, r := range s {
+		if isCaseSeparator(r) {
+			flush(i)
+			prev = r
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			switch {
+			case unicode.IsLower(prev) || unicode.IsDigit(prev):
+				flush(i)
+			case unicode.IsUpper(prev):
+				// the last upper letter of an acronym run starts a word
+				if next, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):]); unicode.IsLower(next) {
+					flush(i)
+				}
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+		prev = r
+	}
+	flush(len(s))
+
+	return words
+}
+
+// title returns @word, in lower case, in the case of ToPascal.
+func title(word string) string {
+	if isAcronym(word) {
+		return strings.ToUpper(word)
+	}
+	r, size := utf8.DecodeRuneInString(word)
+
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// ToSnake returns @s in snake case, "HTTPServerID" is "http_server_id".
+func ToSnake(s string) string {
+	return strings.Join(words(s), "_")
+}
+
+// ToKebab returns @s in kebab case, "HTTPServerID" is "http-server-id".
+func ToKebab(s string) string {
+	return strings.Join(words(s), "-")
+}
+
+// ToPascal returns @s in Pascal case, "http_server_id" is "HTTPServerID".
+func ToPascal(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		b.WriteString(title(w))
+	}
+
+	return b.String()
+}
+
+// ToCamel returns @s in camel case, "http_server_id" is "httpServerID".
+// The first word is in lower case, acronym or not.
+func ToCamel(s string) string {
+	var b strings.Builder
+	for i, w := range words(s) {
+		if i == 0 {
+			b.WriteString(w)
+		} else {
+			b.WriteString(title(w))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package gxstrings
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCaseConverters(t *testing.T) {
+	for _, c := range []struct {
+		in, snake, kebab, camel, pascal string
+	}{
+		{"", "", "", "", ""},
+		{"HTTPServerID", "http_server_id", "http-server-id", "httpServerID", "HTTPServerID"},
+		{"httpServerId", "http_server_id", "http-server-id", "httpServerID", "HTTPServerID"},
+		{"http_server_id", "http_server_id", "http-server-id", "httpServerID", "HTTPServerID"},
+		{"IPID", "ip_id", "ip-id", "ipID", "IPID"},
+		{"userIDs", "user_i_ds", "user-i-ds", "userIDs", "UserIDs"}, // a lower "s" after an acronym is ambiguous
+		{"XMLHttpRequest", "xml_http_request", "xml-http-request", "xmlHTTPRequest", "XMLHTTPRequest"},
+		{"APIV2Handler", "api_v2_handler", "api-v2-handler", "apiV2Handler", "APIV2Handler"},
+		{"IDS", "ids", "ids", "ids", "Ids"},
+		{"version2Beta", "version2_beta", "version2-beta", "version2Beta", "Version2Beta"},
+		{"  --foo__bar..baz  ", "foo_bar_baz", "foo-bar-baz", "fooBarBaz", "FooBarBaz"},
+		{"/dubbo/com.ikurento.user", "dubbo_com_ikurento_user", "dubbo-com-ikurento-user", "dubboComIkurentoUser", "DubboComIkurentoUser"},
+		{"A", "a", "a", "a", "A"},
+		{"ÜberCool", "über_cool", "über-cool", "überCool", "ÜberCool"},
+	} {
+		if got := ToSnake(c.in); got != c.snake {
+			t.Fatalf("ToSnake(%q) = %q, want %q", c.in, got, c.snake)
+		}
+		if got := ToKebab(c.in); got != c.kebab {
+			t.Fatalf("ToKebab(%q) = %q, want %q", c.in, got, c.kebab)
+		}
+		if got := ToCamel(c.in); got != c.camel {
+			t.Fatalf("ToCamel(%q) = %q, want %q", c.in, got, c.camel)
+		}
+		if got := ToPascal(c.in); got != c.pascal {
+			t.Fatalf("ToPascal(%q) = %q, want %q", c.in, got, c.pascal)
+		}
+	}
+}
+
+func TestRegisterAcronym(t *testing.T) {
+	if got := ToPascal("grpc_client"); got != "GrpcClient" {
+		t.Fatalf("ToPascal() = %q", got)
+	}
+	RegisterAcronym("GRPC")
+	defer func() {
+		acronymLock.Lock()
+		delete(acronyms, "grpc")
+		acronymLock.Unlock()
+	}()
+	if got := ToPascal("grpc_client"); got != "GRPCClient" {
+		t.Fatalf("ToPascal() = %q", got)
+	}
+	if got := ToSnake("GRPCHTTPClient"); got != "grpc_http_client" {
+		t.Fatalf("ToSnake() = %q", got)
+	}
+}
+
+// TestCaseRoundTrip checks snake -> camel -> snake and snake -> pascal ->
+// snake are stable for the words of 2 letters at least.
+func TestCaseRoundTrip(t *testing.T) {
+	vocabulary := []string{
+		"http", "server", "id", "ip", "url", "user", "name", "api", "json",
+		"v2", "ab", "zk", "node", "uuid", "ui", "path", "tcp",
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		ws := make([]string, 1+r.Intn(5))
+		for j := range ws {
+			ws[j] = vocabulary[r.Intn(len(vocabulary))]
+		}
+		snake := strings.Join(ws, "_")
+		if got := ToSnake(ToCamel(snake)); got != snake {
+			t.Fatalf("snake %q -> camel %q -> snake %q", snake, ToCamel(snake), got)
+		}
+		if got := ToSnake(ToPascal(snake)); got != snake {
+			t.Fatalf("snake %q -> pascal %q -> snake %q", snake, ToPascal(snake), got)
+		}
+		if got := ToSnake(ToKebab(snake)); got != snake {
+			t.Fatalf("snake %q -> kebab %q -> snake %q", snake, ToKebab(snake), got)
+		}
+	}
+}
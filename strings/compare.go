@@ -0,0 +1,211 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"sort"
+	"strings"
+)
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compareDigits compares the digit runs @a and @b by their values, of any
+// length, so a run overflowing int64 is compared as well.
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// NaturalCompare compares @a and @b as strings.Compare, but a digit run of
+// one and a digit run of the other at the same place are compared by their
+// values, e.g. "1.2.9" < "1.2.10" and "node2" < "node10". The runs of the
+// same value with different leading zeros are equal except that the one of
+// less zeros goes first when the strings are equal otherwise, e.g.
+// "a1b" < "a01b", so the order is total. The other bytes are compared as
+// bytes.
+func NaturalCompare(a, b string) int {
+	var (
+		i, j int
+		tie  int // of the first runs of different leading zeros
+	)
+	for i < len(a) && j < len(b) {
+		ca, cb := a[i], b[j]
+		if isDigit(ca) && isDigit(cb) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			if c := compareDigits(a[si:i], b[sj:j]); c != 0 {
+				return c
+			}
+			if tie == 0 && i-si != j-sj {
+				tie = -1
+				if i-si > j-sj {
+					tie = 1
+				}
+			}
+			continue
+		}
+		if ca != cb {
+			if ca < cb {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	default:
+		return tie
+	}
+}
+
+// cut returns the part of @s before the first @sep and the part after it.
+func cut(s string, sep byte) (string, string) {
+	if i := strings.IndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// compareVersionSegment compares the segments of the dotted versions, a
+// missing one is 0.
+func compareVersionSegment(a, b string) int {
+	if a == "" {
+		a = "0"
+	}
+	if b == "" {
+		b = "0"
+	}
+	if isDigits(a) && isDigits(b) {
+		return compareDigits(a, b)
+	}
+	return NaturalCompare(a, b)
+}
+
+// comparePrerelease compares the pre-release identifiers of semver 2.0.0:
+// a numeric one is lower than an alphanumeric one, and a shorter list is
+// lower if it is a prefix of the other.
+func comparePrerelease(a, b string) int {
+	for a != "" && b != "" {
+		var x, y string
+		x, a = cut(a, '.')
+		y, b = cut(b, '.')
+		dx, dy := isDigits(x), isDigits(y)
+		switch {
+		case dx && dy:
+			if c := compareDigits(x, y); c != 0 {
+				return c
+			}
+		case dx:
+			return -1
+		case dy:
+			return 1
+		default:
+			if c := NaturalCompare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// CompareVersion compares the versions @a and @b, e.g. "1.2.9" < "1.2.10"
+// and "1.2.0-rc1" < "1.2.0":
+//
+//	[v]major.minor.patch...[-prerelease][+build]
+//
+// The dotted segments are compared numerically, or by NaturalCompare if one
+// of them is not numeric, and a missing one is 0, so "1.2" == "1.2.0". A
+// version with a pre-release is lower than the one without it, and the
+// dotted pre-release identifiers are compared as those of semver, except
+// that the alphanumeric ones are compared by NaturalCompare, so
+// "1.0-rc2" < "1.0-rc10". The build metadata and a leading "v" are ignored.
+// It does not allocate.
+func CompareVersion(a, b string) int {
+	a, _ = cut(trimVersionPrefix(a), '+')
+	b, _ = cut(trimVersionPrefix(b), '+')
+	a, aPre := cut(a, '-')
+	b, bPre := cut(b, '-')
+
+	for a != "" || b != "" {
+		var x, y string
+		x, a = cut(a, '.')
+		y, b = cut(b, '.')
+		if c := compareVersionSegment(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case aPre == "" && bPre == "":
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return comparePrerelease(aPre, bPre)
+	}
+}
+
+func trimVersionPrefix(s string) string {
+	if len(s) > 1 && (s[0] == 'v' || s[0] == 'V') && isDigit(s[1]) {
+		return s[1:]
+	}
+	return s
+}
+
+// SortNatural sorts @s in the order of NaturalCompare.
+func SortNatural(s []string) {
+	sort.SliceStable(s, func(i, j int) bool {
+		return NaturalCompare(s[i], s[j]) < 0
+	})
+}
+
+// SortVersions sorts the versions @s in the order of CompareVersion. The
+// equal versions, e.g. "1.2" and "1.2.0", keep their order.
+func SortVersions(s []string) {
+	sort.SliceStable(s, func(i, j int) bool {
+		return CompareVersion(s[i], s[j]) < 0
+	})
+}
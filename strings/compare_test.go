@@ -0,0 +1,168 @@
+package gxstrings
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "a", -1},
+		{"a", "a", 0},
+		{"a", "b", -1},
+		{"node2", "node10", -1},
+		{"node10", "node2", 1},
+		{"1.2.9", "1.2.10", -1},
+		{"x9y", "x10y", -1},
+		{"x10y", "x10z", -1},
+		// leading zeros
+		{"007", "7", 1},
+		{"7", "007", -1},
+		{"a1b", "a01b", -1},
+		{"a01b", "a01b", 0},
+		{"a01b2", "a1b3", -1}, // the values go first
+		{"a001", "a2", -1},
+		{"0", "00", -1},
+		{"00", "000", -1},
+		// runs overflowing int64
+		{"id18446744073709551616", "id18446744073709551615", 1},
+		{"id99999999999999999999999999", "id100000000000000000000000000", -1},
+		{"id0018446744073709551616", "id18446744073709551616", 1},
+		// mixed alpha and numeric
+		{"a1", "a", 1},
+		{"a1", "ab", -1},
+		{"1a", "a1", -1},
+		{"abc12def3", "abc12def10", -1},
+		{"abc12def", "abc012de", 1},
+		{"v1.10-rc2", "v1.10-rc10", -1},
+	} {
+		if got := NaturalCompare(c.a, c.b); got != c.want {
+			t.Fatalf("NaturalCompare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := NaturalCompare(c.b, c.a); got != -c.want {
+			t.Fatalf("NaturalCompare(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+// TestNaturalCompareOrder checks that NaturalCompare is a total order of
+// random strings of digits and letters.
+func TestNaturalCompareOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := func() string {
+		b := make([]byte, r.Intn(6))
+		for i := range b {
+			b[i] = "00123ab"[r.Intn(7)]
+		}
+		return string(b)
+	}
+	for i := 0; i < 20000; i++ {
+		a, b, c := gen(), gen(), gen()
+		ab, ba := NaturalCompare(a, b), NaturalCompare(b, a)
+		if ab != -ba || (ab == 0) != (a == b) {
+			t.Fatalf("NaturalCompare(%q, %q) = %d, reversed = %d", a, b, ab, ba)
+		}
+		if ab <= 0 && NaturalCompare(b, c) <= 0 && NaturalCompare(a, c) > 0 {
+			t.Fatalf("NaturalCompare(%q, %q, %q) is not transitive", a, b, c)
+		}
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"1.0", "1.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2", "1.2.0.0", 0},
+		{"", "0", 0},
+		{"1.2.9", "1.2.10", -1},
+		{"1.10", "1.9.9", 1},
+		{"2", "1.99", 1},
+		{"v1.0", "1.0", 0},
+		{"V1.1", "v1.0", 1},
+		// leading zeros
+		{"1.02", "1.2", 0},
+		{"1.010", "1.9", 1},
+		// segments overflowing int64
+		{"1.18446744073709551616", "1.18446744073709551615", 1},
+		{"99999999999999999999999.0", "100000000000000000000000", -1},
+		// pre-releases
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2.0-rc1", "1.2", -1},
+		{"1.2.0-rc1", "1.1.9", 1},
+		{"1.2.0-rc1", "1.2.0-rc1", 0},
+		{"1.2.0-rc2", "1.2.0-rc10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0-01", "1.0.0-1", 0},
+		// build metadata
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"1.0.0-rc1+build", "1.0.0", -1},
+		// mixed alpha and numeric segments
+		{"1.2a", "1.2b", -1},
+		{"1.2a", "1.10a", -1},
+		{"1.2a", "1.2", 1},
+		{"1.x", "1.0", 1},
+	} {
+		if got := CompareVersion(c.a, c.b); got != c.want {
+			t.Fatalf("CompareVersion(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := CompareVersion(c.b, c.a); got != -c.want {
+			t.Fatalf("CompareVersion(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestSortNatural(t *testing.T) {
+	s := []string{"node10", "node2", "node", "node02", "node1", "Node3", "node10a"}
+	SortNatural(s)
+	want := []string{"Node3", "node", "node1", "node2", "node02", "node10", "node10a"}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("SortNatural() = %v, want %v", s, want)
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	s := []string{"1.10.0", "1.2.0", "1.2.0-rc1", "1.2", "v1.9", "1.2.0-beta", "0.9.1"}
+	SortVersions(s)
+	want := []string{"0.9.1", "1.2.0-beta", "1.2.0-rc1", "1.2.0", "1.2", "v1.9", "1.10.0"}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("SortVersions() = %v, want %v", s, want)
+	}
+}
+
+func TestCompareNoAlloc(t *testing.T) {
+	if n := testing.AllocsPerRun(100, func() {
+		NaturalCompare("node-0012-a", "node-12-b")
+		CompareVersion("v1.2.10-rc.2+build", "1.2.10-rc.10")
+	}); n != 0 {
+		t.Fatalf("allocs = %v, want 0", n)
+	}
+}
+
+var compareVersions = []string{"1.2.0", "1.2.0-rc1", "1.10.3", "v2.0.0-beta.11", "1.2"}
+
+func BenchmarkNaturalCompare(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NaturalCompare("node-0012-a", "node-12-b")
+	}
+}
+
+func BenchmarkCompareVersion(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		v := compareVersions[i%len(compareVersions)]
+		CompareVersion(v, "1.2.0-rc10")
+	}
+}
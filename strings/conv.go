@@ -0,0 +1,57 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"strconv"
+)
+
+// AppendInt appends the decimal @v to @dst.
+func AppendInt(dst []byte, v int64) []byte {
+	return strconv.AppendInt(dst, v, 10)
+}
+
+// AppendFloat appends @v to @dst in the shortest form parsed back to it,
+// like strconv.FormatFloat(v, 'g', -1, 64).
+func AppendFloat(dst []byte, v float64) []byte {
+	return strconv.AppendFloat(dst, v, 'g', -1, 64)
+}
+
+// AppendQuote appends @s double-quoted as a Go string literal to @dst.
+func AppendQuote(dst []byte, s string) []byte {
+	return strconv.AppendQuote(dst, s)
+}
+
+// numError copies the number of @err, which shares the memory of @b.
+func numError(err error, b []byte) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		ne.Num = string(b)
+	}
+
+	return err
+}
+
+// ParseIntBytes parses the decimal @b like strconv.ParseInt, without
+// converting it to a string.
+func ParseIntBytes(b []byte) (int64, error) {
+	v, err := strconv.ParseInt(String(b), 10, 64)
+	if err != nil {
+		return v, numError(err, b)
+	}
+
+	return v, nil
+}
+
+// ParseFloatBytes parses @b like strconv.ParseFloat of 64 bits, without
+// converting it to a string.
+func ParseFloatBytes(b []byte) (float64, error) {
+	v, err := strconv.ParseFloat(String(b), 64)
+	if err != nil {
+		return v, numError(err, b)
+	}
+
+	return v, nil
+}
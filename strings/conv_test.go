@@ -0,0 +1,107 @@
+package gxstrings
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestAppendConv(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ints := []int64{0, -1, math.MaxInt64, math.MinInt64}
+	floats := []float64{0, -0.5, math.Inf(1), math.NaN(), math.MaxFloat64, math.SmallestNonzeroFloat64}
+	for i := 0; i < 1000; i++ {
+		ints = append(ints, r.Int63()-r.Int63())
+		floats = append(floats, r.NormFloat64()*math.Pow(10, float64(r.Intn(40)-20)))
+	}
+
+	for _, v := range ints {
+		b := AppendInt([]byte("x"), v)
+		if string(b) != "x"+strconv.FormatInt(v, 10) {
+			t.Fatalf("AppendInt(%d) = %s", v, b)
+		}
+		if got, err := ParseIntBytes(b[1:]); err != nil || got != v {
+			t.Fatalf("ParseIntBytes(%s) = %d, %v", b[1:], got, err)
+		}
+	}
+	for _, v := range floats {
+		b := AppendFloat(nil, v)
+		if string(b) != strconv.FormatFloat(v, 'g', -1, 64) {
+			t.Fatalf("AppendFloat(%v) = %s", v, b)
+		}
+		got, err := ParseFloatBytes(b)
+		if err != nil || (got != v && !(math.IsNaN(v) && math.IsNaN(got))) {
+			t.Fatalf("ParseFloatBytes(%s) = %v, %v", b, got, err)
+		}
+	}
+
+	if b := AppendQuote(nil, "a\"b\n你"); string(b) != strconv.Quote("a\"b\n你") {
+		t.Fatalf("AppendQuote() = %s", b)
+	}
+}
+
+func TestParseBytesError(t *testing.T) {
+	for _, s := range []string{"", "1x", "99999999999999999999", "--1"} {
+		b := []byte(s)
+		_, err := ParseIntBytes(b)
+		_, want := strconv.ParseInt(s, 10, 64)
+		if err == nil || err.Error() != want.Error() {
+			t.Fatalf("ParseIntBytes(%q) = %v, want %v", s, err, want)
+		}
+		// the error does not share the memory of b
+		for i := range b {
+			b[i] = '#'
+		}
+		if err.Error() != want.Error() {
+			t.Fatalf("the error of ParseIntBytes(%q) changed to %v", s, err)
+		}
+	}
+
+	b := []byte("1.5e")
+	_, err := ParseFloatBytes(b)
+	_, want := strconv.ParseFloat("1.5e", 64)
+	b[0] = '#'
+	if err == nil || err.Error() != want.Error() {
+		t.Fatalf("ParseFloatBytes() = %v, want %v", err, want)
+	}
+}
+
+// longer than the 32 bytes converted to a string on the stack
+const (
+	intInput   = "+000000000000000000000000000001234567890"
+	floatInput = "3.14159265358979323846264338327950288419716939937510"
+)
+
+// go test -bench 'Parse.*Bytes' -benchmem -run ^$
+func BenchmarkParseIntBytes(b *testing.B) {
+	buf := []byte(intInput)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseIntBytes(buf)
+	}
+}
+
+func BenchmarkParseIntStringBytes(b *testing.B) {
+	buf := []byte(intInput)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		strconv.ParseInt(string(buf), 10, 64)
+	}
+}
+
+func BenchmarkParseFloatBytes(b *testing.B) {
+	buf := []byte(floatInput)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseFloatBytes(buf)
+	}
+}
+
+func BenchmarkParseFloatStringBytes(b *testing.B) {
+	buf := []byte(floatInput)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		strconv.ParseFloat(string(buf), 64)
+	}
+}
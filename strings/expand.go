@@ -0,0 +1,106 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownKey     = fmt.Errorf("unknown placeholder key")
+	ErrBadPlaceholder = fmt.Errorf("unterminated placeholder")
+)
+
+func isKeyByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// expand is Expand, strict or not.
+func expand(s string, lookup func(key string) (string, bool), strict bool) (string, error) {
+	i := strings.IndexByte(s, '$')
+	if i < 0 {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for ; i >= 0; i = strings.IndexByte(s, '$') {
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		var key string
+		n := 1 // length of the placeholder
+		switch {
+		case len(s) > 1 && s[1] == '$':
+			b.WriteByte('$')
+			s = s[2:]
+			continue
+		case len(s) > 1 && s[1] == '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				if strict {
+					return "", fmt.Errorf("%w: %q", ErrBadPlaceholder, s)
+				}
+				n = len(s)
+				break
+			}
+			key, n = s[2:end], end+1
+		default:
+			for n < len(s) && isKeyByte(s[n]) {
+				n++
+			}
+			key = s[1:n]
+		}
+
+		var (
+			v  string
+			ok bool
+		)
+		if key != "" {
+			v, ok = lookup(key)
+		}
+		switch {
+		case ok:
+			b.WriteString(v)
+		case strict && n > 1:
+			return "", fmt.Errorf("%w: %q", ErrUnknownKey, s[:n])
+		default:
+			// kept as it is
+			b.WriteString(s[:n])
+		}
+		s = s[n:]
+	}
+	b.WriteString(s)
+
+	return b.String(), nil
+}
+
+// Expand replaces the placeholders ${key} and $key of @s by the values of
+// @lookup, and $$ by $. A key of $key is of letters, digits and '_'. The
+// placeholders of unknown keys, and a '$' of no placeholder, are kept as
+// they are.
+//
+// The values are not expanded again, and a placeholder in a key, like
+// "${a${b}}", is not supported: the key is "a${b".
+func Expand(s string, lookup func(key string) (string, bool)) string {
+	s, _ = expand(s, lookup, false)
+	return s
+}
+
+// ExpandStrict is Expand, but returns ErrUnknownKey for an unknown key,
+// and ErrBadPlaceholder for a "${" without "}".
+func ExpandStrict(s string, lookup func(key string) (string, bool)) (string, error) {
+	return expand(s, lookup, true)
+}
+
+// ExpandMap is Expand by the keys of @m.
+func ExpandMap(s string, m map[string]string) string {
+	return Expand(s, func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	})
+}
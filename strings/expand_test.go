@@ -0,0 +1,68 @@
+package gxstrings
+
+import (
+	"errors"
+	"testing"
+)
+
+var expandVars = map[string]string{
+	"env":     "prod",
+	"service": "user",
+	"a":       "${b}",
+	"b":       "B",
+	"a${b":    "nested",
+	"empty":   "",
+}
+
+func TestExpand(t *testing.T) {
+	for s, want := range map[string]string{
+		"":                     "",
+		"/dubbo":               "/dubbo",
+		"/${env}/${service}":   "/prod/user",
+		"/$env/$service/x":     "/prod/user/x",
+		"$env-$service.log":    "prod-user.log",
+		"$$env $$":             "$env $",
+		"${unknown}/$unknown":  "${unknown}/$unknown",
+		"cost $ 5, $-, $":      "cost $ 5, $-, $",
+		"${}":                  "${}",
+		"${env":                "${env",
+		"[${empty}]":           "[]",
+		"${a}":                 "${b}",    // not expanded again
+		"${a${b}}":             "nested}", // the key ends at the first '}'
+		"$env$service":         "produser",
+		"${env}${service}$$$b": "produser$B",
+		"中文${env}中文":           "中文prod中文",
+	} {
+		if got := ExpandMap(s, expandVars); got != want {
+			t.Fatalf("ExpandMap(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestExpandStrict(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		v, ok := expandVars[key]
+		return v, ok
+	}
+	if got, err := ExpandStrict("/${env}/$service/$$ $", lookup); err != nil || got != "/prod/user/$ $" {
+		t.Fatalf("ExpandStrict() = %q, %v", got, err)
+	}
+	for s, want := range map[string]error{
+		"/${unknown}": ErrUnknownKey,
+		"/$unknown":   ErrUnknownKey,
+		"${}":         ErrUnknownKey,
+		"/${env":      ErrBadPlaceholder,
+	} {
+		if _, err := ExpandStrict(s, lookup); !errors.Is(err, want) {
+			t.Fatalf("ExpandStrict(%q) = %v, want %v", s, err, want)
+		}
+	}
+}
+
+// go test -bench Expand -benchmem -run ^$
+func BenchmarkExpand(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ExpandMap("/${env}/dubbo/${service}/providers", expandVars)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// IsASCII returns whether @s has only ASCII characters.
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EqualFoldASCII is strings.EqualFold, faster for the ASCII strings. It
+// falls back to strings.EqualFold if they differ at a non-ASCII byte.
+func EqualFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		// a non-ASCII rune may fold to one of another length
+		if IsASCII(a) && IsASCII(b) {
+			return false
+		}
+		return strings.EqualFold(a, b)
+	}
+
+	for i := 0; i < len(a); i++ {
+		c, d := a[i], b[i]
+		if c == d {
+			continue
+		}
+		if c|d >= utf8.RuneSelf {
+			return strings.EqualFold(a, b)
+		}
+		// only the cases of a letter differ by 0x20
+		if c |= 0x20; c != d|0x20 || c < 'a' || c > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ToLowerNoAlloc is strings.ToLower, but returns @s itself if it has no
+// upper case letter. It falls back to strings.ToLower for a non-ASCII @s.
+func ToLowerNoAlloc(s string) string {
+	upper := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return strings.ToLower(s)
+		}
+		if upper < 0 && 'A' <= c && c <= 'Z' {
+			upper = i
+		}
+	}
+	if upper < 0 {
+		return s
+	}
+
+	b := make([]byte, len(s))
+	copy(b, s[:upper])
+	for i := upper; i < len(s); i++ {
+		b[i] = lowerASCII(s[i])
+	}
+
+	// b is never modified after
+	return String(b)
+}
+
+// HasPrefixFold returns whether @s begins with @prefix under the case
+// folding of strings.EqualFold, rune by rune, ASCII bytes fast.
+func HasPrefixFold(s, prefix string) bool {
+	for prefix != "" {
+		if s == "" {
+			return false
+		}
+		if c, d := s[0], prefix[0]; c < utf8.RuneSelf && d < utf8.RuneSelf {
+			if c != d && lowerASCII(c) != lowerASCII(d) {
+				return false
+			}
+			s, prefix = s[1:], prefix[1:]
+			continue
+		}
+
+		_, n := utf8.DecodeRuneInString(s)
+		_, m := utf8.DecodeRuneInString(prefix)
+		if !strings.EqualFold(s[:n], prefix[:m]) {
+			return false
+		}
+		s, prefix = s[n:], prefix[m:]
+	}
+
+	return true
+}
+
+// HasSuffixFold returns whether @s ends with @suffix under the case
+// folding of strings.EqualFold, rune by rune, ASCII bytes fast.
+func HasSuffixFold(s, suffix string) bool {
+	for suffix != "" {
+		if s == "" {
+			return false
+		}
+		if c, d := s[len(s)-1], suffix[len(suffix)-1]; c < utf8.RuneSelf && d < utf8.RuneSelf {
+			if c != d && lowerASCII(c) != lowerASCII(d) {
+				return false
+			}
+			s, suffix = s[:len(s)-1], suffix[:len(suffix)-1]
+			continue
+		}
+
+		_, n := utf8.DecodeLastRuneInString(s)
+		_, m := utf8.DecodeLastRuneInString(suffix)
+		if !strings.EqualFold(s[len(s)-n:], suffix[len(suffix)-m:]) {
+			return false
+		}
+		s, suffix = s[:len(s)-n], suffix[:len(suffix)-m]
+	}
+
+	return true
+}
@@ -0,0 +1,18 @@
+// +build go1.18
+
+package gxstrings
+
+import (
+	"testing"
+)
+
+// go test -fuzz FuzzFold -run ^$
+func FuzzFold(f *testing.F) {
+	f.Add("UserProvider", "userPROVIDER")
+	f.Add("Straße", "STRASSE")
+	f.Add("Kelvin", "kelvin")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		checkFold(t, a, b)
+		checkFold(t, b, a)
+	})
+}
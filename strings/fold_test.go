@@ -0,0 +1,90 @@
+package gxstrings
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkFold compares the fold helpers with the stdlib.
+func checkFold(t *testing.T, a, b string) {
+	if got, want := EqualFoldASCII(a, b), strings.EqualFold(a, b); got != want {
+		t.Fatalf("EqualFoldASCII(%q, %q) = %v, want %v", a, b, got, want)
+	}
+	if got, want := ToLowerNoAlloc(a), strings.ToLower(a); got != want {
+		t.Fatalf("ToLowerNoAlloc(%q) = %q, want %q", a, got, want)
+	}
+	if IsASCII(a) && IsASCII(b) {
+		la, lb := strings.ToLower(a), strings.ToLower(b)
+		if got, want := HasPrefixFold(a, b), strings.HasPrefix(la, lb); got != want {
+			t.Fatalf("HasPrefixFold(%q, %q) = %v, want %v", a, b, got, want)
+		}
+		if got, want := HasSuffixFold(a, b), strings.HasSuffix(la, lb); got != want {
+			t.Fatalf("HasSuffixFold(%q, %q) = %v, want %v", a, b, got, want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	for _, c := range [][2]string{
+		{"", ""},
+		{"UserProvider", "userprovider"},
+		{"UserProvider", "userprovidex"},
+		{"UserProvider", "user"},
+		{"user", "UserProvider"},
+		{"@[`{", "@[`{"},
+		{"@", "`"}, // not letters
+		{"Straße", "STRASSE"},
+		{"K", "k"}, // the Kelvin sign folds to k
+		{"Kelvin", "KELVIN"},
+		{"Σ", "σ"},
+		{"\xff", "\xfe"},
+	} {
+		checkFold(t, c[0], c[1])
+		checkFold(t, c[1], c[0])
+	}
+
+	// non-ASCII prefixes and suffixes fold rune by rune
+	if !HasPrefixFold("Kelvin", "kel") || !HasSuffixFold("okK", "OKK") || HasPrefixFold("K", "kk") {
+		t.Fatalf("non-ASCII HasPrefixFold/HasSuffixFold failed")
+	}
+	if !HasPrefixFold("Привет", "пРИ") || !HasSuffixFold("Привет", "ВЕТ") || HasSuffixFold("Привет", "вет!") {
+		t.Fatalf("Cyrillic HasPrefixFold/HasSuffixFold failed")
+	}
+}
+
+func TestToLowerNoAlloc(t *testing.T) {
+	s := string([]byte("already.lower"))
+	if got := ToLowerNoAlloc(s); StringPointer(got) != StringPointer(s) {
+		t.Fatalf("ToLowerNoAlloc() copied a lower case string")
+	}
+	if n := testing.AllocsPerRun(100, func() { ToLowerNoAlloc(s) }); n != 0 {
+		t.Fatalf("%v allocs of ToLowerNoAlloc() of a lower case string", n)
+	}
+}
+
+// go test -bench 'Fold|Lower' -benchmem -run ^$
+func BenchmarkEqualFoldASCII(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		EqualFoldASCII("com.ikurento.user.UserProvider", "com.ikurento.user.userprovider")
+	}
+}
+
+func BenchmarkEqualFold(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		strings.EqualFold("com.ikurento.user.UserProvider", "com.ikurento.user.userprovider")
+	}
+}
+
+func BenchmarkToLowerNoAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ToLowerNoAlloc("com.ikurento.user.userprovider")
+	}
+}
+
+func BenchmarkToLower(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		strings.ToLower("com.ikurento.user.UserProvider")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+type internShard struct {
+	sync.Mutex
+	m map[string]string
+	_ [6]uint64 // Pad by cache-line size to prevent false sharing.
+}
+
+// Intern deduplicates strings: it returns the same string for the equal
+// ones, so that the copies can be collected. It is a map sharded by the
+// hash of the strings, like the cells of gxsync.Counter, and safe for
+// concurrent use.
+type Intern struct {
+	seed     maphash.Seed
+	shards   []internShard
+	mask     uint64
+	capacity int // of a shard, no bound if 0
+	hits     int64
+	misses   int64
+}
+
+// InternStats are the statistics of an Intern.
+type InternStats struct {
+	Hits   int64
+	Misses int64
+	Size   int // strings in the pool
+}
+
+// NewIntern returns a pool of at most @capacity strings, no bound if
+// @capacity <= 0. When it is full, an arbitrary string is evicted for a
+// new one, which only makes its later equal strings not deduplicated.
+func NewIntern(capacity int) *Intern {
+	n := 1
+	for n < 4*runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+
+	p := &Intern{
+		seed:   maphash.MakeSeed(),
+		shards: make([]internShard, n),
+		mask:   uint64(n - 1),
+	}
+	if capacity > 0 {
+		p.capacity = (capacity + n - 1) / n
+	}
+	for i := range p.shards {
+		p.shards[i].m = make(map[string]string)
+	}
+
+	return p
+}
+
+// Get returns the string of the pool equal to @s, adding @s if there is
+// none.
+func (p *Intern) Get(s string) string {
+	shard := &p.shards[internHash(p.seed, s)&p.mask]
+	shard.Lock()
+	defer shard.Unlock()
+
+	if v, ok := shard.m[s]; ok {
+		atomic.AddInt64(&p.hits, 1)
+		return v
+	}
+	p.add(shard, s)
+
+	return s
+}
+
+// InternBytes returns the string of the pool equal to @b, it only
+// allocates a string when there is none.
+func (p *Intern) InternBytes(b []byte) string {
+	shard := &p.shards[internHashBytes(p.seed, b)&p.mask]
+	shard.Lock()
+	defer shard.Unlock()
+
+	// the conversion of the key does not allocate
+	if v, ok := shard.m[string(b)]; ok {
+		atomic.AddInt64(&p.hits, 1)
+		return v
+	}
+	s := string(b)
+	p.add(shard, s)
+
+	return s
+}
+
+// add adds @s to @shard, which must be locked.
+func (p *Intern) add(shard *internShard, s string) {
+	atomic.AddInt64(&p.misses, 1)
+	if p.capacity > 0 && len(shard.m) >= p.capacity {
+		for k := range shard.m {
+			delete(shard.m, k)
+			break
+		}
+	}
+	shard.m[s] = s
+}
+
+// Stats returns the statistics of the pool.
+func (p *Intern) Stats() InternStats {
+	stats := InternStats{
+		Hits:   atomic.LoadInt64(&p.hits),
+		Misses: atomic.LoadInt64(&p.misses),
+	}
+	for i := range p.shards {
+		p.shards[i].Lock()
+		stats.Size += len(p.shards[i].m)
+		p.shards[i].Unlock()
+	}
+
+	return stats
+}
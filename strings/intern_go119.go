@@ -0,0 +1,19 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build go1.19
+
+package gxstrings
+
+import (
+	"hash/maphash"
+)
+
+func internHash(seed maphash.Seed, s string) uint64 {
+	return maphash.String(seed, s)
+}
+
+func internHashBytes(seed maphash.Seed, b []byte) uint64 {
+	return maphash.Bytes(seed, b)
+}
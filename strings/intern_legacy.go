@@ -0,0 +1,27 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build !go1.19
+
+package gxstrings
+
+import (
+	"hash/maphash"
+)
+
+// internHash is maphash.String of go1.19.
+func internHash(seed maphash.Seed, s string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	h.WriteString(s)
+	return h.Sum64()
+}
+
+// internHashBytes is maphash.Bytes of go1.19.
+func internHashBytes(seed maphash.Seed, b []byte) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	h.Write(b)
+	return h.Sum64()
+}
@@ -0,0 +1,94 @@
+package gxstrings
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestIntern(t *testing.T) {
+	p := NewIntern(0)
+	a := p.Get(string([]byte("service")))
+	b := p.InternBytes([]byte("service"))
+	if a != b || StringPointer(a) != StringPointer(b) {
+		t.Fatalf("the interned strings differ")
+	}
+	if p.Get("") != "" || p.InternBytes(nil) != "" {
+		t.Fatalf("empty string interned wrong")
+	}
+
+	stats := p.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Size != 2 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+}
+
+func TestInternCapacity(t *testing.T) {
+	p := NewIntern(100)
+	for i := 0; i < 10000; i++ {
+		if s := p.Get(fmt.Sprint(i)); s != fmt.Sprint(i) {
+			t.Fatalf("Get(%d) = %s", i, s)
+		}
+	}
+	// rounded up to the shards
+	if size := p.Stats().Size; size > 100+len(p.shards) {
+		t.Fatalf("%d strings in a pool of 100", size)
+	}
+}
+
+func TestInternConcurrent(t *testing.T) {
+	p := NewIntern(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				p.InternBytes([]byte(fmt.Sprint(j % 100)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if stats.Size != 100 || stats.Misses != 100 || stats.Hits != 8000-100 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+}
+
+// zkChildren returns a zk children list of @n nodes of 100 services.
+func zkChildren(n int) [][]byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "group%%3Dbjtelecom%%26protocol%%3Dpb%%26service%%3Dservice%02d/", i%100)
+	}
+
+	return bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("/")), []byte("/"))
+}
+
+// go test -bench ZkChildren -benchmem -run ^$
+func BenchmarkZkChildrenString(b *testing.B) {
+	children := zkChildren(10000)
+	names := make([]string, len(children))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, c := range children {
+			names[j] = string(c)
+		}
+	}
+}
+
+func BenchmarkZkChildrenIntern(b *testing.B) {
+	children := zkChildren(10000)
+	names := make([]string, len(children))
+	p := NewIntern(0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, c := range children {
+			names[j] = p.InternBytes(c)
+		}
+	}
+}
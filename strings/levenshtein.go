@@ -0,0 +1,134 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"unicode/utf8"
+)
+
+// editDistance returns the Levenshtein distance of @a and @b, or max+1 if
+// it is larger than @max. Only the cells within @max of the diagonal are
+// computed, in two rows of the length of the shorter one.
+func editDistance(a, b []rune, max int) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	inf := max + 1
+	if len(a)-len(b) > max {
+		return inf
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+		if j > max {
+			prev[j] = inf
+		}
+	}
+
+	for i := 1; i <= len(a); i++ {
+		lo, hi := i-max, i+max
+		if lo < 1 {
+			lo = 1
+		}
+		if hi > len(b) {
+			hi = len(b)
+		}
+
+		rowMin := inf
+		cur[0] = inf
+		if i <= max {
+			cur[0] = i
+			rowMin = i
+		}
+		if lo > 1 {
+			cur[lo-1] = inf
+		}
+		for j := lo; j <= hi; j++ {
+			d := prev[j-1]
+			if a[i-1] != b[j-1] {
+				d++
+			}
+			if v := prev[j] + 1; v < d {
+				d = v
+			}
+			if v := cur[j-1] + 1; v < d {
+				d = v
+			}
+			if d > inf {
+				d = inf
+			}
+			cur[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if hi < len(b) {
+			cur[hi+1] = inf
+		}
+		if rowMin > max {
+			return inf
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// Levenshtein returns the edit distance of the runes of @a and @b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	max := len(ra)
+	if len(rb) > max {
+		max = len(rb)
+	}
+
+	return editDistance(ra, rb, max)
+}
+
+// Similarity returns 1 - the edit distance of @a and @b divided by the
+// runes of the longer one, 1 for the equal strings and 0 for the totally
+// different ones.
+func Similarity(a, b string) float64 {
+	n := utf8.RuneCountInString(a)
+	if m := utf8.RuneCountInString(b); m > n {
+		n = m
+	}
+	if n == 0 {
+		return 1
+	}
+
+	return 1 - float64(Levenshtein(a, b))/float64(n)
+}
+
+// ClosestMatch returns the first of @candidates nearest to @target, if it
+// is within @maxDistance edits.
+func ClosestMatch(target string, candidates []string, maxDistance int) (string, bool) {
+	if maxDistance < 0 {
+		return "", false
+	}
+
+	var (
+		rt    = []rune(target)
+		best  = -1
+		bound = maxDistance
+	)
+	for i, c := range candidates {
+		if d := editDistance(rt, []rune(c), bound); d <= bound {
+			best = i
+			// only a nearer one replaces it
+			if bound = d - 1; bound < 0 {
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+
+	return candidates[best], true
+}
@@ -0,0 +1,144 @@
+package gxstrings
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// naiveLevenshtein is the full matrix Levenshtein distance.
+func naiveLevenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if v := d[i-1][j] + 1; v < d[i][j] {
+				d[i][j] = v
+			}
+			if v := d[i][j-1] + 1; v < d[i][j] {
+				d[i][j] = v
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"你好世界", "你好", 2}, // runes, not bytes
+		{"café", "cafe", 1},
+	} {
+		if got := Levenshtein(c.a, c.b); got != c.want {
+			t.Fatalf("Levenshtein(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := Levenshtein(c.b, c.a); got != c.want {
+			t.Fatalf("Levenshtein(%q, %q) = %d, want %d", c.b, c.a, got, c.want)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	word := func() string {
+		b := make([]rune, r.Intn(12))
+		for i := range b {
+			b[i] = []rune("abc好")[r.Intn(4)]
+		}
+		return string(b)
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := word(), word()
+		want := naiveLevenshtein(a, b)
+		if got := Levenshtein(a, b); got != want {
+			t.Fatalf("Levenshtein(%q, %q) = %d, want %d", a, b, got, want)
+		}
+		// the banded one within any bound
+		for max := 0; max <= 12; max++ {
+			got := editDistance([]rune(a), []rune(b), max)
+			if (want <= max && got != want) || (want > max && got != max+1) {
+				t.Fatalf("editDistance(%q, %q, %d) = %d, want %d", a, b, max, got, want)
+			}
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "abc", 1},
+		{"abc", "xyz", 0},
+		{"abcd", "abcx", 0.75},
+		{"", "ab", 0},
+	} {
+		if got := Similarity(c.a, c.b); got != c.want {
+			t.Fatalf("Similarity(%q, %q) = %f, want %f", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestClosestMatch(t *testing.T) {
+	services := []string{"UserProvider", "OrderProvider", "UserConsumer", "UserProviders"}
+	for _, c := range []struct {
+		target string
+		max    int
+		want   string
+		ok     bool
+	}{
+		{"UserProvider", 0, "UserProvider", true},
+		{"UsrProvider", 2, "UserProvider", true},
+		{"UserProvidr", 1, "UserProvider", true},
+		{"OrderProvder", 2, "OrderProvider", true},
+		{"Gateway", 3, "", false},
+		{"UserProvider", -1, "", false},
+	} {
+		got, ok := ClosestMatch(c.target, services, c.max)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("ClosestMatch(%q, %d) = %q, %v", c.target, c.max, got, ok)
+		}
+	}
+	if _, ok := ClosestMatch("a", nil, 10); ok {
+		t.Fatalf("ClosestMatch() of no candidates found one")
+	}
+}
+
+var serviceNames = []string{
+	"com.ikurento.user.UserProvider",
+	"com.ikurento.order.OrderProvider",
+	"com.ikurento.user.UserConsumer",
+	"com.ikurento.payment.PaymentGateway",
+	"com.ikurento.shopping.CartService",
+	"com.ikurento.shopping.CatalogService",
+}
+
+// go test -bench 'Levenshtein|ClosestMatch' -benchmem -run ^$
+func BenchmarkLevenshtein(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Levenshtein(serviceNames[0], serviceNames[2])
+	}
+}
+
+func BenchmarkClosestMatch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ClosestMatch("com.ikurento.user.UserProvidr", serviceNames, 3)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+// Match is a pattern found by Matcher.FindAll.
+type Match struct {
+	Pattern int // index of the pattern in the patterns of NewMatcher
+	Offset  int // byte offset of the pattern in the text
+}
+
+// Matcher searches many patterns in a text at once, in O(len(text) +
+// matches). It is an Aho-Corasick automaton whose transitions are a dense
+// table over the bytes of the patterns, built once by NewMatcher. It is
+// safe for concurrent use.
+type Matcher struct {
+	patterns []string
+	foldCase bool
+	classes  [256]int32 // byte -> column of delta, 0 for the bytes in no pattern
+	width    int32      // columns of delta
+	delta    []int32    // state*width + class -> state
+	out      [][]int    // patterns ending at a state
+	dict     []int32    // the next state of the fail links with output, -1 if none
+}
+
+type MatcherOption func(*Matcher)
+
+// WithCaseInsensitive matches the ASCII letters regardless of their case.
+func WithCaseInsensitive() MatcherOption {
+	return func(m *Matcher) {
+		m.foldCase = true
+	}
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// NewMatcher returns a matcher of @patterns. Empty patterns never match,
+// and duplicated ones are reported by each of their indexes.
+func NewMatcher(patterns []string, opts ...MatcherOption) *Matcher {
+	m := &Matcher{patterns: patterns}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.width = 1
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			if m.foldCase {
+				c = lowerASCII(c)
+			}
+			if m.classes[c] == 0 {
+				m.classes[c] = m.width
+				m.width++
+			}
+		}
+	}
+	if m.foldCase {
+		for c := 'A'; c <= 'Z'; c++ {
+			m.classes[c] = m.classes[c+'a'-'A']
+		}
+	}
+
+	// the trie, state 0 is the root
+	children := []map[int32]int32{{}}
+	m.out = [][]int{nil}
+	for i, p := range patterns {
+		if len(p) == 0 {
+			continue
+		}
+		var s int32
+		for j := 0; j < len(p); j++ {
+			class := m.classes[p[j]]
+			next, ok := children[s][class]
+			if !ok {
+				next = int32(len(children))
+				children = append(children, map[int32]int32{})
+				m.out = append(m.out, nil)
+				children[s][class] = next
+			}
+			s = next
+		}
+		m.out[s] = append(m.out[s], i)
+	}
+
+	// the fail links in BFS order, turned into the transitions of delta
+	states := int32(len(children))
+	m.delta = make([]int32, states*m.width)
+	m.dict = make([]int32, states)
+	fail := make([]int32, states)
+	queue := make([]int32, 0, states)
+	m.dict[0] = -1
+	for class, next := range children[0] {
+		m.delta[class] = next
+		m.dict[next] = -1
+		queue = append(queue, next)
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		f := fail[s]
+		for class := int32(0); class < m.width; class++ {
+			if next, ok := children[s][class]; ok {
+				fail[next] = m.delta[f*m.width+class]
+				if len(m.out[fail[next]]) > 0 {
+					m.dict[next] = fail[next]
+				} else {
+					m.dict[next] = m.dict[fail[next]]
+				}
+				m.delta[s*m.width+class] = next
+				queue = append(queue, next)
+			} else {
+				m.delta[s*m.width+class] = m.delta[f*m.width+class]
+			}
+		}
+	}
+
+	return m
+}
+
+// MatchAny returns whether @s contains any of the patterns.
+func (m *Matcher) MatchAny(s string) bool {
+	var state int32
+	for i := 0; i < len(s); i++ {
+		state = m.delta[state*m.width+m.classes[s[i]]]
+		if len(m.out[state]) > 0 || m.dict[state] >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindAll returns all the patterns in @s, overlapping ones included, in
+// the order of their ends.
+func (m *Matcher) FindAll(s string) []Match {
+	var (
+		state   int32
+		matches []Match
+	)
+	for i := 0; i < len(s); i++ {
+		state = m.delta[state*m.width+m.classes[s[i]]]
+		for o := state; o > 0; o = m.dict[o] {
+			for _, p := range m.out[o] {
+				matches = append(matches, Match{Pattern: p, Offset: i + 1 - len(m.patterns[p])})
+			}
+		}
+	}
+
+	return matches
+}
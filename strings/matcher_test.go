@@ -0,0 +1,145 @@
+package gxstrings
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// naiveFindAll is FindAll by comparing every offset.
+func naiveFindAll(patterns []string, s string) []Match {
+	var matches []Match
+	for i, p := range patterns {
+		if len(p) == 0 {
+			continue
+		}
+		for off := 0; off+len(p) <= len(s); off++ {
+			if s[off:off+len(p)] == p {
+				matches = append(matches, Match{Pattern: i, Offset: off})
+			}
+		}
+	}
+
+	return sortMatches(patterns, matches)
+}
+
+// sortMatches sorts @matches by their ends, then by their patterns.
+func sortMatches(patterns []string, matches []Match) []Match {
+	sort.SliceStable(matches, func(i, j int) bool {
+		ei := matches[i].Offset + len(patterns[matches[i].Pattern])
+		ej := matches[j].Offset + len(patterns[matches[j].Pattern])
+		if ei != ej {
+			return ei < ej
+		}
+		return matches[i].Pattern < matches[j].Pattern
+	})
+	return matches
+}
+
+func TestMatcher(t *testing.T) {
+	patterns := []string{"he", "she", "his", "hers", "", "he"}
+	m := NewMatcher(patterns)
+
+	got := sortMatches(patterns, m.FindAll("ushers"))
+	want := []Match{{0, 2}, {1, 1}, {5, 2}, {3, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll(ushers) = %v, want %v", got, want)
+	}
+	if !m.MatchAny("this") || m.MatchAny("xyz") || m.MatchAny("") {
+		t.Fatalf("MatchAny() failed")
+	}
+
+	if NewMatcher(nil).MatchAny("abc") || NewMatcher([]string{""}).FindAll("abc") != nil {
+		t.Fatalf("empty patterns matched")
+	}
+}
+
+func TestMatcherCaseInsensitive(t *testing.T) {
+	m := NewMatcher([]string{"Error", "WARN"}, WithCaseInsensitive())
+	got := m.FindAll("an ERROR and a warning")
+	want := []Match{{0, 3}, {1, 15}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll() = %v, want %v", got, want)
+	}
+	if NewMatcher([]string{"Error"}).MatchAny("ERROR") {
+		t.Fatalf("case sensitive matcher matched")
+	}
+}
+
+func TestMatcherRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	word := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abcAB"[r.Intn(5)]
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 200; i++ {
+		patterns := make([]string, r.Intn(10))
+		for j := range patterns {
+			patterns[j] = word(r.Intn(5))
+		}
+		text := word(r.Intn(100))
+
+		m := NewMatcher(patterns)
+		got := sortMatches(patterns, m.FindAll(text))
+		want := naiveFindAll(patterns, text)
+		if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Fatalf("FindAll(%q) of %q = %v, want %v", text, patterns, got, want)
+		}
+		if m.MatchAny(text) != (len(want) > 0) {
+			t.Fatalf("MatchAny(%q) of %q failed", text, patterns)
+		}
+
+		lower := make([]string, len(patterns))
+		for j, p := range patterns {
+			lower[j] = strings.ToLower(p)
+		}
+		got = sortMatches(patterns, NewMatcher(patterns, WithCaseInsensitive()).FindAll(text))
+		want = naiveFindAll(lower, strings.ToLower(text))
+		if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Fatalf("case insensitive FindAll(%q) of %q = %v, want %v", text, patterns, got, want)
+		}
+	}
+}
+
+func benchPatterns() ([]string, string) {
+	r := rand.New(rand.NewSource(1))
+	patterns := make([]string, 50)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("keyword%02d", i)
+	}
+	text := make([]byte, 1024)
+	for i := range text {
+		text[i] = byte('a' + r.Intn(26))
+	}
+
+	return patterns, string(text)
+}
+
+// go test -bench Matcher -run ^$
+func BenchmarkMatcherMatchAny(b *testing.B) {
+	patterns, text := benchPatterns()
+	m := NewMatcher(patterns)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.MatchAny(text)
+	}
+}
+
+func BenchmarkNaiveMatchAny(b *testing.B) {
+	patterns, text := benchPatterns()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range patterns {
+			if strings.Contains(text, p) {
+				break
+			}
+		}
+	}
+}
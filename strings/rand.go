@@ -0,0 +1,87 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// the charsets of RandString and RandStringCrypto
+const (
+	Alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	Hex          = "0123456789abcdef"
+	Base62       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// randSource is the source of RandString, seeded by the time, as the global
+// one of math/rand is not seeded before go1.20.
+var randSource = struct {
+	sync.Mutex
+	rand.Source64
+}{Source64: rand.NewSource(time.Now().UnixNano()).(rand.Source64)}
+
+// randString returns @n bytes of @charset picked by the random bytes of
+// @fill. A random byte is masked to the bits of the largest index, and
+// rejected if it is still too large, so that there is no modulo bias.
+func randString(n int, charset string, fill func([]byte)) string {
+	if len(charset) < 2 || len(charset) > 256 {
+		panic("the length of @charset is not in [2, 256]")
+	}
+	if n <= 0 {
+		return ""
+	}
+
+	mask := byte(1<<bits.Len(uint(len(charset)-1)) - 1)
+	b := make([]byte, n)
+	// a random byte is accepted with a probability of more than 1/2
+	buf := make([]byte, 2*n)
+	for i := 0; i < n; {
+		fill(buf)
+		for _, r := range buf {
+			if idx := int(r & mask); idx < len(charset) {
+				b[i] = charset[idx]
+				if i++; i == n {
+					break
+				}
+			}
+		}
+	}
+
+	// b is never modified after
+	return String(b)
+}
+
+// RandString returns @n random bytes of @charset, whose length must be in
+// [2, 256]. It uses a locked source of math/rand, which is fast but
+// predictable, see RandStringCrypto.
+func RandString(n int, charset string) string {
+	return randString(n, charset, func(buf []byte) {
+		randSource.Lock()
+		defer randSource.Unlock()
+		for len(buf) >= 8 {
+			binary.LittleEndian.PutUint64(buf, randSource.Uint64())
+			buf = buf[8:]
+		}
+		for v := randSource.Uint64(); len(buf) > 0; v >>= 8 {
+			buf[0] = byte(v)
+			buf = buf[1:]
+		}
+	})
+}
+
+// RandStringCrypto is RandString by crypto/rand, for the secrets.
+func RandStringCrypto(n int, charset string) string {
+	return randString(n, charset, func(buf []byte) {
+		if _, err := crand.Read(buf); err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package gxstrings
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRandString(t *testing.T) {
+	for _, f := range []func(int, string) string{RandString, RandStringCrypto} {
+		for _, charset := range []string{Alphanumeric, Hex, Base62, "ab"} {
+			s := f(100, charset)
+			if len(s) != 100 {
+				t.Fatalf("len(%q) = %d", s, len(s))
+			}
+			for i := 0; i < len(s); i++ {
+				if strings.IndexByte(charset, s[i]) < 0 {
+					t.Fatalf("%q of %q out of %q", s[i], s, charset)
+				}
+			}
+		}
+		if s := f(0, Hex); s != "" {
+			t.Fatalf("a random string of 0 bytes = %q", s)
+		}
+	}
+	if RandString(32, Base62) == RandString(32, Base62) {
+		t.Fatalf("two equal random strings")
+	}
+}
+
+func TestRandStringCharsetLength(t *testing.T) {
+	all := make([]byte, 257)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	for _, charset := range []string{"", "a", string(all)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("no panic of a charset of %d bytes", len(charset))
+				}
+			}()
+			RandString(1, charset)
+		}()
+	}
+
+	// all the 256 bytes
+	if s := RandStringCrypto(10, string(all[:256])); len(s) != 10 {
+		t.Fatalf("len(%q) = %d", s, len(s))
+	}
+}
+
+// TestRandStringBias counts the bytes of a large sample of charsets whose
+// lengths are not powers of 2, each must be within 5 sigmas of the mean.
+func TestRandStringBias(t *testing.T) {
+	const n = 1 << 20
+	for _, f := range []func(int, string) string{RandString, RandStringCrypto} {
+		for _, charset := range []string{"abc", Alphanumeric, strings.Repeat(Base62, 3)[:129]} {
+			var counts [256]int
+			for _, c := range []byte(f(n, charset)) {
+				counts[c]++
+			}
+
+			// the repeated bytes of the charset are counted together
+			expected := make(map[byte]float64)
+			for i := 0; i < len(charset); i++ {
+				expected[charset[i]] += float64(n) / float64(len(charset))
+			}
+			for c, mean := range expected {
+				p := mean / n
+				sigma := math.Sqrt(n * p * (1 - p))
+				if d := math.Abs(float64(counts[c]) - mean); d > 5*sigma {
+					t.Fatalf("%d of %q in %d bytes of %q, want %.0f", counts[c], c, n, charset, mean)
+				}
+			}
+		}
+	}
+}
+
+// go test -bench RandString -benchmem -run ^$
+func BenchmarkRandString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		RandString(32, Alphanumeric)
+	}
+}
+
+func BenchmarkRandStringCrypto(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		RandStringCrypto(32, Alphanumeric)
+	}
+}
+
+func BenchmarkRandStringBytesMaskImprSrc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		RandStringBytesMaskImprSrc(32)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"crypto/subtle"
+	"strings"
+	"unicode/utf8"
+)
+
+// SecureEqual compares @a and @b in a time independent of their contents,
+// for the secrets like tokens. The time still depends on their lengths,
+// so the length of a secret may leak, which should not matter for a
+// secret of a fixed length.
+func SecureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare(Slice(a), Slice(b)) == 1
+}
+
+// Mask replaces the runes of @s but the first @keepPrefix and the last
+// @keepSuffix ones with @maskRune. @s is all masked if the kept runes
+// would be all of it, so that a short secret never shows.
+func Mask(s string, keepPrefix, keepSuffix int, maskRune rune) string {
+	if keepPrefix < 0 {
+		keepPrefix = 0
+	}
+	if keepSuffix < 0 {
+		keepSuffix = 0
+	}
+	n := utf8.RuneCountInString(s)
+	if keepPrefix+keepSuffix >= n {
+		keepPrefix, keepSuffix = 0, 0
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	i := 0
+	for _, r := range s {
+		if i < keepPrefix || i >= n-keepSuffix {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(maskRune)
+		}
+		i++
+	}
+
+	return b.String()
+}
+
+// MaskEmail masks the local part of the email @s but its first rune, like
+// "j*******@example.com". @s without "@" is masked but its first rune.
+func MaskEmail(s string) string {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return Mask(s, 1, 0, '*')
+	}
+
+	return Mask(s[:at], 1, 0, '*') + s[at:]
+}
+
+// MaskPhone masks the digits of the phone number @s but the last 4, and
+// keeps the others like "+", "-" and spaces, like "+** ***-****-5678". All
+// digits are masked if there are 4 at most.
+func MaskPhone(s string) string {
+	digits := 0
+	for i := 0; i < len(s); i++ {
+		if '0' <= s[i] && s[i] <= '9' {
+			digits++
+		}
+	}
+	keep := 4
+	if digits <= keep {
+		keep = 0
+	}
+
+	b := []byte(s)
+	for i := range b {
+		if '0' <= b[i] && b[i] <= '9' {
+			if digits > keep {
+				b[i] = '*'
+			}
+			digits--
+		}
+	}
+
+	return String(b)
+}
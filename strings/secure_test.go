@@ -0,0 +1,78 @@
+package gxstrings
+
+import (
+	"testing"
+)
+
+func TestSecureEqual(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"token", "token", true},
+		{"token", "tokex", false},
+		{"token", "token1", false},
+		{"", "a", false},
+		{"密钥", "密钥", true},
+	} {
+		if got := SecureEqual(c.a, c.b); got != c.want {
+			t.Fatalf("SecureEqual(%q, %q) = %v", c.a, c.b, got)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	for _, c := range []struct {
+		s              string
+		prefix, suffix int
+		mask           rune
+		want           string
+	}{
+		{"", 1, 1, '*', ""},
+		{"secret", 1, 2, '*', "s***et"},
+		{"secret", 0, 0, '*', "******"},
+		{"secret", -1, 2, '*', "****et"},
+		{"abc", 2, 1, '*', "***"}, // all kept is all masked
+		{"abc", 10, 0, '*', "***"},
+		{"张三丰", 1, 0, '#', "张##"},
+		{"a\xffb", 1, 1, '#', "a#b"},
+		{"abc", 1, 0, '●', "a●●"},
+	} {
+		if got := Mask(c.s, c.prefix, c.suffix, c.mask); got != c.want {
+			t.Fatalf("Mask(%q, %d, %d) = %q, want %q", c.s, c.prefix, c.suffix, got, c.want)
+		}
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	for s, want := range map[string]string{
+		"":                     "",
+		"john.doe@example.com": "j*******@example.com",
+		"j@example.com":        "*@example.com",
+		"@example.com":         "@example.com",
+		"a@b@example.com":      "a**@example.com",
+		"张三@例子.中国":             "张*@例子.中国",
+		"not-an-email":         "n***********",
+	} {
+		if got := MaskEmail(s); got != want {
+			t.Fatalf("MaskEmail(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	for s, want := range map[string]string{
+		"":                  "",
+		"13812345678":       "*******5678",
+		"+86 138-1234-5678": "+** ***-****-5678",
+		"(010) 6552-1234":   "(***) ****-1234",
+		"1234":              "****",
+		"12":                "**",
+		"电话: 12345":         "电话: *2345",
+	} {
+		if got := MaskPhone(s); got != want {
+			t.Fatalf("MaskPhone(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+const (
+	// below which the set operations loop instead of building a map,
+	// chosen by BenchmarkDiff
+	setMapThreshold = 32
+)
+
+// stringSet tells whether a string is in a slice, by a loop if the slice is
+// short, or a map.
+type stringSet struct {
+	s []string
+	m map[string]struct{}
+}
+
+func newStringSet(s []string) stringSet {
+	set := stringSet{s: s}
+	if len(s) > setMapThreshold {
+		set.m = make(map[string]struct{}, len(s))
+		for _, e := range s {
+			set.m[e] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+func (set stringSet) has(e string) bool {
+	if set.m != nil {
+		_, ok := set.m[e]
+		return ok
+	}
+	for _, a := range set.s {
+		if a == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unique returns the strings of @s without the duplicates, in the order of
+// their first occurrences.
+func Unique(s []string) []string {
+	u := make([]string, 0, len(s))
+	if len(s) <= setMapThreshold {
+		for _, e := range s {
+			if !newStringSet(u).has(e) {
+				u = append(u, e)
+			}
+		}
+		return u
+	}
+
+	seen := make(map[string]struct{}, len(s))
+	for _, e := range s {
+		if _, ok := seen[e]; !ok {
+			seen[e] = struct{}{}
+			u = append(u, e)
+		}
+	}
+
+	return u
+}
+
+// Diff returns the strings of @a not in @b, and those of @b not in @a, in
+// their orders.
+func Diff(a, b []string) (onlyA, onlyB []string) {
+	setA, setB := newStringSet(a), newStringSet(b)
+	for _, e := range a {
+		if !setB.has(e) {
+			onlyA = append(onlyA, e)
+		}
+	}
+	for _, e := range b {
+		if !setA.has(e) {
+			onlyB = append(onlyB, e)
+		}
+	}
+
+	return onlyA, onlyB
+}
+
+// Intersect returns the strings of @a also in @b, in the order of @a.
+func Intersect(a, b []string) []string {
+	var (
+		set = newStringSet(b)
+		s   []string
+	)
+	for _, e := range a {
+		if set.has(e) {
+			s = append(s, e)
+		}
+	}
+
+	return s
+}
+
+// Remove returns @s without @elem, @s is not modified.
+func Remove(s []string, elem string) []string {
+	r := make([]string, 0, len(s))
+	for _, e := range s {
+		if e != elem {
+			r = append(r, e)
+		}
+	}
+
+	return r
+}
+
+// ContainsAny returns whether any of @elems is in @s.
+func ContainsAny(s []string, elems ...string) bool {
+	set := newStringSet(s)
+	for _, e := range elems {
+		if set.has(e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAll returns whether all of @elems are in @s.
+func ContainsAll(s []string, elems ...string) bool {
+	set := newStringSet(s)
+	for _, e := range elems {
+		if !set.has(e) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package gxstrings
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	for _, n := range []int{4, 100} {
+		var s, want []string
+		for i := 0; i < n; i++ {
+			want = append(want, fmt.Sprint(i))
+			s = append(s, fmt.Sprint(i), fmt.Sprint(i/2))
+		}
+		if got := Unique(s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Unique(%q) = %q", s, got)
+		}
+	}
+	if got := Unique(nil); len(got) != 0 {
+		t.Fatalf("Unique(nil) = %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, n := range []int{4, 100} {
+		var a, b, wantA, wantB, both []string
+		for i := 0; i < n; i++ {
+			a = append(a, fmt.Sprint(2*i))
+			b = append(b, fmt.Sprint(3*i))
+			if i%3 != 0 {
+				wantA = append(wantA, fmt.Sprint(2*i))
+			} else {
+				both = append(both, fmt.Sprint(2*i))
+			}
+			if i%2 != 0 || 3*i/2 >= n {
+				wantB = append(wantB, fmt.Sprint(3*i))
+			}
+		}
+		onlyA, onlyB := Diff(a, b)
+		if !reflect.DeepEqual(onlyA, wantA) || !reflect.DeepEqual(onlyB, wantB) {
+			t.Fatalf("Diff(%q, %q) = %q, %q", a, b, onlyA, onlyB)
+		}
+		if got := Intersect(a, b); !reflect.DeepEqual(got, both) {
+			t.Fatalf("Intersect(%q, %q) = %q", a, b, got)
+		}
+	}
+
+	onlyA, onlyB := Diff(nil, []string{"a"})
+	if onlyA != nil || !reflect.DeepEqual(onlyB, []string{"a"}) {
+		t.Fatalf("Diff(nil, [a]) = %q, %q", onlyA, onlyB)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := []string{"a", "b", "a", "c"}
+	if got := Remove(s, "a"); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("Remove() = %q", got)
+	}
+	if !reflect.DeepEqual(s, []string{"a", "b", "a", "c"}) {
+		t.Fatalf("Remove() modified its argument: %q", s)
+	}
+}
+
+func TestContainsAnyAll(t *testing.T) {
+	s := []string{"a", "b", "c"}
+	if !ContainsAny(s, "x", "b") || ContainsAny(s, "x") || ContainsAny(s) {
+		t.Fatalf("ContainsAny() failed")
+	}
+	if !ContainsAll(s, "c", "a") || ContainsAll(s, "a", "x") || !ContainsAll(s) {
+		t.Fatalf("ContainsAll() failed")
+	}
+}
+
+func benchmarkDiff(b *testing.B, n int, diff func(a, b []string) ([]string, []string)) {
+	var x, y []string
+	for i := 0; i < n; i++ {
+		x = append(x, fmt.Sprintf("node%d", i))
+		y = append(y, fmt.Sprintf("node%d", i+n/2))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diff(x, y)
+	}
+}
+
+// go test -bench Diff -run ^$
+func BenchmarkDiff(b *testing.B) {
+	for _, n := range []int{4, 8, 16, 32, 64} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			benchmarkDiff(b, n, Diff)
+		})
+	}
+}
@@ -5,40 +5,44 @@
 // http://blog.csdn.net/siddontang/article/details/23541587
 // reflect.StringHeader和reflect.SliceHeader的结构体只相差末尾一个字段(cap)
 // vitess代码，一种很hack的做法，string和slice的转换只需要拷贝底层的指针，而不是内存拷贝。
+//
+// String and Slice share the memory of their argument instead of copying it:
+//   - the []byte passed to String must never be modified while the string
+//     returned is in use, or the "immutable" string changes;
+//   - the []byte returned by Slice must never be modified, the memory of a
+//     string may be read-only (a constant), or shared by other strings.
+//
+// Use CopyString and CopySlice if these can not be guaranteed. A nil or
+// empty argument is safe, and converts to an empty result.
 package gxstrings
 
 import (
-	"reflect"
-	"unsafe"
+	"bytes"
 )
 
-func String(b []byte) (s string) {
-	pbytes := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	pstring := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	pstring.Data = pbytes.Data
-	pstring.Len = pbytes.Len
-	return
+// AppendSafe appends @elems to a copy of @b, so that the memory of @b,
+// which may be that of a string returned by Slice, is never written.
+func AppendSafe(b []byte, elems ...byte) []byte {
+	c := make([]byte, len(b), len(b)+len(elems))
+	copy(c, b)
+	return append(c, elems...)
 }
 
-func Slice(s string) (b []byte) {
-	pbytes := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	pstring := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	pbytes.Data = pstring.Data
-	pbytes.Len = pstring.Len
-	pbytes.Cap = pstring.Len
-	return
+// CopyString returns a string of a copy of @b, the safe version of String.
+func CopyString(b []byte) string {
+	return string(b)
 }
 
-// returns &s[0], which is not allowed in go
-func StringPointer(s string) unsafe.Pointer {
-	p := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	return unsafe.Pointer(p.Data)
+// CopySlice returns a copy of @s as a []byte, the safe version of Slice.
+func CopySlice(s string) []byte {
+	return []byte(s)
 }
 
-// returns &b[0], which is not allowed in go
-func BytePointer(b []byte) unsafe.Pointer {
-	p := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	return unsafe.Pointer(p.Data)
+// TrimBytesSpace returns a string of @b without its leading and trailing
+// white spaces, copying only the bytes left rather than all of @b as
+// strings.TrimSpace(string(b)) does.
+func TrimBytesSpace(b []byte) string {
+	return string(bytes.TrimSpace(b))
 }
 
 // var (
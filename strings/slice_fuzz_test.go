@@ -0,0 +1,57 @@
+// +build go1.18
+
+package gxstrings
+
+import (
+	"bytes"
+	"testing"
+)
+
+// go test -race -fuzz FuzzSliceString -run ^$
+func FuzzSliceString(f *testing.F) {
+	for _, s := range []string{"", "a", "hello world", "\x00\xff"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		b := Slice(s)
+		if b == nil || len(b) != len(s) || string(b) != s {
+			t.Fatalf("Slice(%q) = %q", s, b)
+		}
+		if String(b) != s {
+			t.Fatalf("round trip of %q failed", s)
+		}
+
+		c := AppendSafe(b, 'x')
+		if string(c) != s+"x" || String(Slice(s)) != s {
+			t.Fatalf("AppendSafe() on %q = %q", s, c)
+		}
+		if String(CopySlice(s)) != s || CopyString(Slice(s)) != s {
+			t.Fatalf("copies of %q differ", s)
+		}
+	})
+}
+
+// go test -race -fuzz FuzzStringSlice -run ^$
+func FuzzStringSlice(f *testing.F) {
+	for _, b := range [][]byte{nil, {}, []byte("a"), {0, 0xff}} {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := String(b)
+		if s != string(b) || !bytes.Equal(Slice(s), b) {
+			t.Fatalf("String(%q) = %q", b, s)
+		}
+
+		// the copies do not share the memory
+		c := CopySlice(s)
+		if len(c) > 0 {
+			c[0]++
+			if s != string(b) {
+				t.Fatalf("CopySlice(%q) shares the memory", s)
+			}
+		}
+		if CopyString(b) != s || Join("", s) != s || Join("", s, s) != s+s {
+			t.Fatalf("copies of %q differ", b)
+		}
+	})
+}
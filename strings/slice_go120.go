@@ -0,0 +1,35 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build go1.20
+
+package gxstrings
+
+import (
+	"unsafe"
+)
+
+// String returns a string sharing the memory of @b, see the package doc.
+func String(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// Slice returns a []byte sharing the memory of @s, see the package doc.
+// Slice("") is a non-nil empty slice.
+func Slice(s string) []byte {
+	if len(s) == 0 {
+		return []byte{}
+	}
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// returns &s[0], which is not allowed in go
+func StringPointer(s string) unsafe.Pointer {
+	return unsafe.Pointer(unsafe.StringData(s))
+}
+
+// returns &b[0], which is not allowed in go
+func BytePointer(b []byte) unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(b))
+}
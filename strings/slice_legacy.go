@@ -0,0 +1,47 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// +build !go1.20
+
+package gxstrings
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// String returns a string sharing the memory of @b, see the package doc.
+func String(b []byte) (s string) {
+	pbytes := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	pstring := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	pstring.Data = pbytes.Data
+	pstring.Len = pbytes.Len
+	return
+}
+
+// Slice returns a []byte sharing the memory of @s, see the package doc.
+// Slice("") is a non-nil empty slice.
+func Slice(s string) (b []byte) {
+	if len(s) == 0 {
+		return []byte{}
+	}
+	pbytes := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	pstring := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	pbytes.Data = pstring.Data
+	pbytes.Len = pstring.Len
+	pbytes.Cap = pstring.Len
+	return
+}
+
+// returns &s[0], which is not allowed in go
+func StringPointer(s string) unsafe.Pointer {
+	p := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	return unsafe.Pointer(p.Data)
+}
+
+// returns &b[0], which is not allowed in go
+func BytePointer(b []byte) unsafe.Pointer {
+	p := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	return unsafe.Pointer(p.Data)
+}
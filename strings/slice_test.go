@@ -1,6 +1,7 @@
 package gxstrings
 
 import (
+	"sync"
 	"testing"
 )
 
@@ -35,6 +36,107 @@ func TestSlice2(t *testing.T) {
 	println(String(b)) // output: hello worldhello world
 }
 
+func TestStringNoCopy(t *testing.T) {
+	b := []byte("hello world")
+	s := String(b)
+	if s != "hello world" || StringPointer(s) != BytePointer(b) {
+		t.Fatalf("String(%q) = %q", b, s)
+	}
+
+	s = "hello world"
+	b = Slice(s)
+	if string(b) != s || len(b) != cap(b) || BytePointer(b) != StringPointer(s) {
+		t.Fatalf("Slice(%q) = %q", s, b)
+	}
+}
+
+func TestCopyString(t *testing.T) {
+	b := []byte("hello world")
+	s := CopyString(b)
+	b[0] = 'a'
+	if s != "hello world" {
+		t.Fatalf("CopyString() changed with its argument: %q", s)
+	}
+
+	s = string([]byte("hello world"))
+	b = CopySlice(s)
+	b[0] = 'a'
+	if s != "hello world" {
+		t.Fatalf("CopySlice() modified its argument: %q", s)
+	}
+}
+
+func TestSliceEmpty(t *testing.T) {
+	for _, s := range []string{"", string([]byte{}), "hello"[5:]} {
+		if b := Slice(s); b == nil || len(b) != 0 {
+			t.Fatalf("Slice(%q) = %#v", s, b)
+		}
+		if String(Slice(s)) != "" {
+			t.Fatalf("round trip of %q failed", s)
+		}
+	}
+	if s := String(nil); s != "" {
+		t.Fatalf("String(nil) = %q", s)
+	}
+}
+
+func TestTrimBytesSpace(t *testing.T) {
+	for b, want := range map[string]string{
+		"":               "",
+		" \t\n":          "",
+		"  a b \r\n":     "a b",
+		"\u3000中文\u00a0": "中文",
+	} {
+		if s := TrimBytesSpace([]byte(b)); s != want {
+			t.Fatalf("TrimBytesSpace(%q) = %q, want %q", b, s, want)
+		}
+	}
+	if s := TrimBytesSpace(nil); s != "" {
+		t.Fatalf("TrimBytesSpace(nil) = %q", s)
+	}
+}
+
+func TestIsASCII(t *testing.T) {
+	for s, want := range map[string]bool{"": true, "abc\x7f": true, "ab\x80": false, "中": false} {
+		if IsASCII(s) != want {
+			t.Fatalf("IsASCII(%q) = %v", s, !want)
+		}
+	}
+}
+
+func TestAppendSafe(t *testing.T) {
+	s := string([]byte("hello world"))
+	b := Slice(s)[:5]
+	c := AppendSafe(b, '!')
+	if string(c) != "hello!" || s != "hello world" {
+		t.Fatalf("AppendSafe() = %q, the string %q", c, s)
+	}
+	if c = AppendSafe(nil); c == nil || len(c) != 0 {
+		t.Fatalf("AppendSafe(nil) = %#v", c)
+	}
+}
+
+// go test -race -run TestStringConcurrent
+func TestStringConcurrent(t *testing.T) {
+	b := []byte("hello world")
+	s := String(b)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if String(Slice(s)) != s || string(Slice(s)) != "hello world" {
+					t.Errorf("round trip of %q failed", s)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // func TestCheckByteArray(t *testing.T) {
 // 	var s = "hello"
 // 	var flag bool
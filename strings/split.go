@@ -0,0 +1,104 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SplitIterator yields the fields of strings.SplitN one by one, without
+// allocating: the fields are sub-strings of the string split.
+//
+//	it := gxstrings.SplitIter(path, "/")
+//	for field, ok := it.Next(); ok; field, ok = it.Next() {
+//		...
+//	}
+type SplitIterator struct {
+	s, sep string
+	n      int // fields left, < 0 if no limit
+}
+
+// SplitIter returns an iterator of the fields of strings.Split(@s, @sep).
+func SplitIter(s, sep string) SplitIterator {
+	return SplitNIter(s, sep, -1)
+}
+
+// SplitNIter returns an iterator of the fields of strings.SplitN(@s, @sep,
+// @n).
+func SplitNIter(s, sep string, n int) SplitIterator {
+	// strings.SplitN("", "", n) has no field
+	if sep == "" && s == "" {
+		n = 0
+	}
+
+	return SplitIterator{s: s, sep: sep, n: n}
+}
+
+// Next returns the next field, or false if there is none.
+func (it *SplitIterator) Next() (string, bool) {
+	if it.n == 0 {
+		return "", false
+	}
+
+	var i, skip int
+	switch {
+	case it.n == 1:
+		i = -1
+	case it.sep == "":
+		// the UTF-8 sequences, an invalid byte is one
+		_, i = utf8.DecodeRuneInString(it.s)
+		if i == len(it.s) {
+			i = -1
+		}
+	default:
+		i, skip = strings.Index(it.s, it.sep), len(it.sep)
+	}
+
+	if i < 0 {
+		field := it.s
+		it.s, it.n = "", 0
+		return field, true
+	}
+	field := it.s
+	field, it.s = field[:i], field[i+skip:]
+	if it.n > 0 {
+		it.n--
+	}
+
+	return field, true
+}
+
+// FieldsIterator yields the fields of strings.Fields one by one, without
+// allocating.
+type FieldsIterator struct {
+	s string
+}
+
+// FieldsIter returns an iterator of the fields of strings.Fields(@s).
+func FieldsIter(s string) FieldsIterator {
+	return FieldsIterator{s: s}
+}
+
+// Next returns the next field, or false if there is none.
+func (it *FieldsIterator) Next() (string, bool) {
+	start := strings.IndexFunc(it.s, func(r rune) bool { return !unicode.IsSpace(r) })
+	if start < 0 {
+		it.s = ""
+		return "", false
+	}
+	s := it.s[start:]
+
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		it.s = ""
+		return s, true
+	}
+	it.s = s[end:]
+
+	return s[:end], true
+}
@@ -0,0 +1,18 @@
+// +build go1.18
+
+package gxstrings
+
+import (
+	"testing"
+)
+
+// go test -fuzz FuzzSplitIter -run ^$
+func FuzzSplitIter(f *testing.F) {
+	f.Add("", "")
+	f.Add("/a//b/", "/")
+	f.Add("a b\tc", " ")
+	f.Add("\xff你好\xfe", "")
+	f.Fuzz(func(t *testing.T, s, sep string) {
+		checkSplit(t, s, sep)
+	})
+}
@@ -0,0 +1,90 @@
+package gxstrings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func collectSplit(it SplitIterator) []string {
+	var fields []string
+	for field, ok := it.Next(); ok; field, ok = it.Next() {
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func collectFields(it FieldsIterator) []string {
+	var fields []string
+	for field, ok := it.Next(); ok; field, ok = it.Next() {
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// equalFields is reflect.DeepEqual, but a nil slice equals an empty one.
+func equalFields(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+// checkSplit compares the iterators with strings.SplitN and strings.Fields.
+func checkSplit(t *testing.T, s, sep string) {
+	for _, n := range []int{-1, 0, 1, 2, 3, 10} {
+		if got, want := collectSplit(SplitNIter(s, sep, n)), strings.SplitN(s, sep, n); !equalFields(got, want) {
+			t.Fatalf("SplitNIter(%q, %q, %d) = %q, want %q", s, sep, n, got, want)
+		}
+	}
+	if got, want := collectSplit(SplitIter(s, sep)), strings.Split(s, sep); !equalFields(got, want) {
+		t.Fatalf("SplitIter(%q, %q) = %q, want %q", s, sep, got, want)
+	}
+	if got, want := collectFields(FieldsIter(s)), strings.Fields(s); !equalFields(got, want) {
+		t.Fatalf("FieldsIter(%q) = %q, want %q", s, got, want)
+	}
+}
+
+func TestSplitIter(t *testing.T) {
+	for _, s := range []string{"", "/", "//", "a", "/a/b/", "a//b", "a/b/c", "你/好", "\xff/\xfe", " a  b\t\nc  "} {
+		for _, sep := range []string{"", "/", "//", "a", "好"} {
+			checkSplit(t, s, sep)
+		}
+	}
+
+	// the fields share the memory of the string
+	s := "/dubbo/providers"
+	it := SplitIter(s, "/")
+	it.Next()
+	if field, _ := it.Next(); StringPointer(field) != StringPointer(s[1:]) {
+		t.Fatalf("a field is copied")
+	}
+}
+
+// go test -bench Split -benchmem -run ^$
+func BenchmarkSplitIter(b *testing.B) {
+	s := "group%3Dbjtelecom/protocol%3Dpb/role%3DSRT_Provider/service%3Dshopping/version%3D1.0.1"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it := SplitIter(s, "/")
+		for _, ok := it.Next(); ok; _, ok = it.Next() {
+		}
+	}
+}
+
+func BenchmarkStringsSplit(b *testing.B) {
+	s := "group%3Dbjtelecom/protocol%3Dpb/role%3DSRT_Provider/service%3Dshopping/version%3D1.0.1"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		strings.Split(s, "/")
+	}
+}
+
+func BenchmarkFieldsIter(b *testing.B) {
+	s := "java -server -Xmx2g -jar  provider.jar\t--port 20880"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it := FieldsIter(s)
+		for _, ok := it.Next(); ok; _, ok = it.Next() {
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxstrings implements string related utilities.
+package gxstrings
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// the East Asian Wide and Fullwidth ranges, occupying 2 cells
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x2E80, 0x303E},   // CJK Radicals ~ CJK Symbols and Punctuation
+	{0x3041, 0x33FF},   // Hiragana ~ CJK Compatibility
+	{0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+	{0x4E00, 0x9FFF},   // CJK Unified Ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul Syllables
+	{0xF900, 0xFAFF},   // CJK Compatibility Ideographs
+	{0xFE30, 0xFE4F},   // CJK Compatibility Forms
+	{0xFF00, 0xFF60},   // Fullwidth Forms
+	{0xFFE0, 0xFFE6},   // Fullwidth Signs
+	{0x1F300, 0x1F64F}, // Pictographs and Emoticons
+	{0x1F900, 0x1F9FF}, // Supplemental Symbols and Pictographs
+	{0x20000, 0x2FFFD}, // CJK Extension B ~
+	{0x30000, 0x3FFFD}, // CJK Extension G ~
+}
+
+// RuneWidth returns the cells @r occupies on a terminal: 0 for the
+// combining marks, 2 for the wide East Asian runes, 1 for the others.
+func RuneWidth(r rune) int {
+	if unicode.Is(unicode.M, r) {
+		return 0
+	}
+	for _, rng := range wideRanges {
+		if r < rng[0] {
+			break
+		}
+		if r <= rng[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// Width returns the cells @s occupies on a terminal, see RuneWidth.
+func Width(s string) int {
+	var n int
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+
+	return n
+}
+
+// truncate cuts @s to @max measured by @measure including @ellipsis. The
+// cut is never inside a rune, nor between a rune and its combining marks.
+func truncate(s string, max int, ellipsis string, measure func(r rune, size int) int) string {
+	if max <= 0 {
+		return ""
+	}
+	measureString := func(s string) int {
+		var n int
+		for i := 0; i < len(s); {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			n += measure(r, size)
+			i += size
+		}
+		return n
+	}
+	if measureString(s) <= max {
+		return s
+	}
+	// the ellipsis itself is cut if it is too long
+	if measureString(ellipsis) >= max {
+		return truncate(ellipsis, max, "", measure)
+	}
+
+	budget := max - measureString(ellipsis)
+	var used, cut int
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !unicode.Is(unicode.M, r) {
+			cut = i
+		}
+		if used += measure(r, size); used > budget {
+			break
+		}
+		i += size
+	}
+
+	return s[:cut] + ellipsis
+}
+
+// TruncateRunes returns @s cut to at most @max runes, @ellipsis included
+// if it is cut. A rune is never split from its combining marks, which are
+// runes too.
+func TruncateRunes(s string, max int, ellipsis string) string {
+	return truncate(s, max, ellipsis, func(rune, int) int { return 1 })
+}
+
+// TruncateBytes returns @s cut to at most @maxBytes bytes, @ellipsis
+// included if it is cut. The cut backs off to the previous rune boundary.
+func TruncateBytes(s string, maxBytes int, ellipsis string) string {
+	return truncate(s, maxBytes, ellipsis, func(_ rune, size int) int { return size })
+}
+
+// TruncateWidth returns @s cut to at most @maxWidth cells on a terminal,
+// @ellipsis included if it is cut, see RuneWidth.
+func TruncateWidth(s string, maxWidth int, ellipsis string) string {
+	return truncate(s, maxWidth, ellipsis, func(r rune, _ int) int { return RuneWidth(r) })
+}
@@ -0,0 +1,80 @@
+package gxstrings
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	for _, c := range []struct {
+		s, ellipsis string
+		max         int
+		want        string
+	}{
+		{"", "...", 5, ""},
+		{"hello", "...", 5, "hello"},
+		{"hello world", "...", 8, "hello..."},
+		{"你好世界，再见", "…", 5, "你好世界…"},
+		{"hello", "...", 2, ".."}, // the ellipsis is cut
+		{"hello", "...", 3, "..."},
+		{"hello", "", 3, "hel"},
+		{"hello", "...", 0, ""},
+		{"cafe\u0301s", "", 4, "caf"}, // e and its acute accent are kept together
+		{"cafe\u0301s", "", 5, "cafe\u0301"},
+	} {
+		if got := TruncateRunes(c.s, c.max, c.ellipsis); got != c.want {
+			t.Fatalf("TruncateRunes(%q, %d, %q) = %q, want %q", c.s, c.max, c.ellipsis, got, c.want)
+		}
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	for _, c := range []struct {
+		s, ellipsis string
+		max         int
+		want        string
+	}{
+		{"", "...", 5, ""},
+		{"hello world", "...", 8, "hello..."},
+		{"你好世界", "", 7, "你好"}, // backs off the 3rd rune
+		{"你好世界", "…", 9, "你好…"},
+		{"你好", "…", 2, ""}, // not even the ellipsis fits
+		{"cafe\u0301s", "", 5, "caf"},
+		{"\xff\xfeabc", "", 3, "\xff\xfea"}, // invalid bytes are of a byte
+	} {
+		got := TruncateBytes(c.s, c.max, c.ellipsis)
+		if got != c.want || len(got) > c.max {
+			t.Fatalf("TruncateBytes(%q, %d, %q) = %q, want %q", c.s, c.max, c.ellipsis, got, c.want)
+		}
+	}
+
+	// every cut of a multi-byte string is valid UTF-8
+	s := "a你b好c世d界"
+	for max := 0; max <= len(s); max++ {
+		if got := TruncateBytes(s, max, "~"); !utf8.ValidString(got) || len(got) > max {
+			t.Fatalf("TruncateBytes(%q, %d) = %q", s, max, got)
+		}
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	if w := Width("ab你好e\u0301"); w != 7 {
+		t.Fatalf("Width() = %d", w)
+	}
+	for _, c := range []struct {
+		s, ellipsis string
+		max         int
+		want        string
+	}{
+		{"你好世界", "", 8, "你好世界"},
+		{"你好世界", "", 7, "你好世"},
+		{"你好世界", "..", 7, "你好.."},
+		{"a你好", "", 2, "a"},
+		{"e\u0301e\u0301e\u0301", ".", 2, "e\u0301."},
+	} {
+		got := TruncateWidth(c.s, c.max, c.ellipsis)
+		if got != c.want || Width(got) > c.max {
+			t.Fatalf("TruncateWidth(%q, %d, %q) = %q, want %q", c.s, c.max, c.ellipsis, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a cyclic barrier
+package gxsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrBrokenBarrier = fmt.Errorf("barrier is broken")
+)
+
+// a generation is one round of the barrier. its done channel is closed
+// when all parties have arrived or when the round is broken.
+type barrierGeneration struct {
+	done   chan struct{}
+	broken bool
+}
+
+// Barrier lets a fixed number of goroutines wait for each other at a common
+// point. It is cyclic: once all parties have arrived the barrier is reset
+// and can be used again for the next phase.
+type Barrier struct {
+	parties int
+	action  func()
+
+	mu     sync.Mutex
+	count  int // parties arrived in current generation
+	gen    int // current generation number
+	curGen *barrierGeneration
+}
+
+// NewBarrier returns a barrier for @parties goroutines. If @action is not nil,
+// it is run by the last arriving goroutine before the others are released.
+// @action runs under the barrier lock, so it must not call the barrier.
+func NewBarrier(parties int, action func()) *Barrier {
+	if parties <= 0 {
+		panic("gxsync: NewBarrier parties should be greater than 0")
+	}
+
+	return &Barrier{
+		parties: parties,
+		action:  action,
+		curGen:  &barrierGeneration{done: make(chan struct{})},
+	}
+}
+
+// Await blocks until all parties have called Await on this barrier.
+// It returns the generation number the caller arrived at.
+//
+// If @ctx is done before the barrier trips, the barrier is broken: the caller
+// gets ctx.Err() and all other waiters of this generation get ErrBrokenBarrier.
+// A broken barrier stays broken until Reset is called.
+func (b *Barrier) Await(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	g, gen := b.curGen, b.gen
+	if g.broken {
+		b.mu.Unlock()
+		return gen, ErrBrokenBarrier
+	}
+
+	b.count++
+	if b.count == b.parties {
+		err := b.trip(g)
+		b.mu.Unlock()
+		return gen, err
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-g.done:
+		if g.broken {
+			return gen, ErrBrokenBarrier
+		}
+		return gen, nil
+
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		select {
+		case <-g.done:
+			// tripped or broken just before the cancellation was seen
+			if g.broken {
+				return gen, ErrBrokenBarrier
+			}
+			return gen, nil
+		default:
+		}
+		b.breakBarrier(g)
+		return gen, ctx.Err()
+	}
+}
+
+// trip runs the action and releases the waiters of @g.
+// it should be called with b.mu held.
+func (b *Barrier) trip(g *barrierGeneration) (err error) {
+	if b.action != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				b.breakBarrier(g)
+				err = fmt.Errorf("barrier action panic: %v", r)
+			}
+		}()
+		b.action()
+	}
+
+	close(g.done)
+	b.nextGeneration()
+
+	return nil
+}
+
+// it should be called with b.mu held.
+func (b *Barrier) breakBarrier(g *barrierGeneration) {
+	if g.broken {
+		return
+	}
+	g.broken = true
+	b.count = 0
+	close(g.done)
+}
+
+// it should be called with b.mu held.
+func (b *Barrier) nextGeneration() {
+	b.count = 0
+	b.gen++
+	b.curGen = &barrierGeneration{done: make(chan struct{})}
+}
+
+// Reset breaks the current generation (waiters get ErrBrokenBarrier)
+// and starts a fresh one.
+func (b *Barrier) Reset() {
+	b.mu.Lock()
+	b.breakBarrier(b.curGen)
+	b.nextGeneration()
+	b.mu.Unlock()
+}
+
+// IsBroken returns true if the current generation has been broken.
+func (b *Barrier) IsBroken() bool {
+	b.mu.Lock()
+	broken := b.curGen.broken
+	b.mu.Unlock()
+
+	return broken
+}
+
+// Parties returns the number of parties required to trip the barrier.
+func (b *Barrier) Parties() int {
+	return b.parties
+}
+
+// NumWaiting returns the number of parties currently waiting at the barrier.
+func (b *Barrier) NumWaiting() int {
+	b.mu.Lock()
+	n := b.count
+	b.mu.Unlock()
+
+	return n
+}
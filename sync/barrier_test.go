@@ -0,0 +1,99 @@
+package gxsync
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// go test -race -v -run Barrier
+func TestBarrierGenerations(t *testing.T) {
+	const (
+		parties = 8
+		rounds  = 2000
+	)
+
+	var (
+		actionNum int64
+		phase     int64
+		wg        sync.WaitGroup
+	)
+
+	b := NewBarrier(parties, func() {
+		atomic.AddInt64(&actionNum, 1)
+		atomic.AddInt64(&phase, 1)
+	})
+
+	for i := 0; i < parties; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				if p := atomic.LoadInt64(&phase); p != int64(r) {
+					t.Errorf("round %d: phase = %d", r, p)
+					return
+				}
+				gen, err := b.Await(context.Background())
+				if err != nil {
+					t.Errorf("Await() = error %v", err)
+					return
+				}
+				if gen != r {
+					t.Errorf("Await() generation = %d, want %d", gen, r)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if actionNum != rounds {
+		t.Fatalf("action run %d times, want %d", actionNum, rounds)
+	}
+	if b.NumWaiting() != 0 {
+		t.Fatalf("NumWaiting() = %d", b.NumWaiting())
+	}
+}
+
+func TestBarrierCancel(t *testing.T) {
+	b := NewBarrier(3, nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errCh <- err
+	}()
+
+	for b.NumWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.Await(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Await() = %v, want context.DeadlineExceeded", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != ErrBrokenBarrier {
+			t.Fatalf("waiter got %v, want ErrBrokenBarrier", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter has not been released")
+	}
+
+	if !b.IsBroken() {
+		t.Fatal("barrier should be broken")
+	}
+	if _, err := b.Await(context.Background()); err != ErrBrokenBarrier {
+		t.Fatalf("Await() on broken barrier = %v", err)
+	}
+
+	b.Reset()
+	if b.IsBroken() {
+		t.Fatal("barrier should not be broken after Reset")
+	}
+}
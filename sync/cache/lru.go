@@ -0,0 +1,165 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxcache provides a sharded LRU cache safe for concurrent use
+package gxcache
+
+import (
+	"runtime"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/sync"
+	"github.com/AlexStocks/goext/time"
+)
+
+// LRU is a cache of at most capacity entries, evicting the least recently
+// used one for a new entry. It is a gxsync.LRUCache of string keys, so each
+// shard is a LRU of its own, and the evicted entry is the least recently used
+// one of its shard.
+type LRU struct {
+	cache     *gxsync.LRUCache[string, interface{}]
+	ttl       time.Duration
+	onEvict   func(key string, value interface{})
+	hits      *gxsync.Counter
+	misses    *gxsync.Counter
+	evictions *gxsync.Counter
+}
+
+// Stats are the statistics of a LRU.
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64 // by the capacity or the TTL
+	Size      int
+}
+
+type options struct {
+	ttl     time.Duration
+	onEvict func(key string, value interface{})
+	shards  int
+	clock   gxtime.Clock
+}
+
+type Option func(*options)
+
+// WithTTL expires an entry @ttl after it is set, no expiry by default. The
+// expired entries are removed lazily, when they are got or evicted.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithOnEvict calls @f with an entry evicted by the capacity or expired, out
+// of the lock of the cache. Remove and the overwrite of Set do not call it.
+func WithOnEvict(f func(key string, value interface{})) Option {
+	return func(o *options) {
+		o.onEvict = f
+	}
+}
+
+// WithShards sets the number of shards, rounded up to a power of 2. It is
+// about 4 per CPU by default, and 1 makes a LRU of a single lock.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithClock expires the entries by @c, gxtime.RealClock by default.
+func WithClock(c gxtime.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// New returns a LRU of at most @capacity entries. The shards are fewer for
+// a small @capacity, so that every shard holds one entry at least.
+func New(capacity int, opts ...Option) *LRU {
+	if capacity < 1 {
+		capacity = 1
+	}
+	o := options{shards: 4 * runtime.GOMAXPROCS(0), clock: gxtime.RealClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	n := 1
+	for n < o.shards && n*2 <= capacity {
+		n <<= 1
+	}
+
+	c := &LRU{
+		ttl:       o.ttl,
+		onEvict:   o.onEvict,
+		hits:      gxsync.NewCounter(),
+		misses:    gxsync.NewCounter(),
+		evictions: gxsync.NewCounter(),
+	}
+	c.cache = gxsync.NewLRUCache[string, interface{}](capacity,
+		gxsync.WithLRUShards[string, interface{}](n),
+		gxsync.WithLRUClock[string, interface{}](o.clock),
+		gxsync.WithLRUOnEvict(c.evict),
+	)
+
+	return c
+}
+
+// evict counts the entries evicted by the capacity or expired, and calls
+// the onEvict with them.
+func (c *LRU) evict(key string, value interface{}, reason gxsync.EvictReason) {
+	if reason == gxsync.EvictRemoved {
+		return
+	}
+	c.evictions.Inc()
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
+
+// Get returns the value of @key and marks it as the most recently used.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	value, ok := c.cache.Get(key)
+	if !ok {
+		c.misses.Inc()
+		return nil, false
+	}
+	c.hits.Inc()
+	return value, true
+}
+
+// Peek returns the value of @key without marking it as used or counting it
+// in the stats.
+func (c *LRU) Peek(key string) (interface{}, bool) {
+	return c.cache.Peek(key)
+}
+
+// Set sets the value of @key as the most recently used, evicting the least
+// recently used entry of its shard if it is full.
+func (c *LRU) Set(key string, value interface{}) {
+	c.cache.SetWithTTL(key, value, c.ttl)
+}
+
+// Remove removes @key, and returns whether it was cached.
+func (c *LRU) Remove(key string) bool {
+	return c.cache.Remove(key)
+}
+
+// Len returns the number of the entries, including the expired ones not
+// removed yet.
+func (c *LRU) Len() int {
+	return c.cache.Count()
+}
+
+// Stats returns the statistics of the cache.
+func (c *LRU) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      c.Len(),
+	}
+}
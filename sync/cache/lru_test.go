@@ -0,0 +1,173 @@
+package gxcache
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/time"
+)
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+	c := New(2, WithShards(1), WithOnEvict(func(key string, value interface{}) {
+		evicted = append(evicted, key)
+	}))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	c.Set("c", 3) // evicts b
+	if _, ok := c.Peek("b"); ok {
+		t.Fatalf("Peek(b) after its eviction = true")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [b]", evicted)
+	}
+	c.Peek("a") // not used
+	c.Set("d", 4)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatalf("Peek(a) after its eviction = true")
+	}
+	if !c.Remove("c") || c.Remove("c") || c.Len() != 1 {
+		t.Fatalf("Remove(c) twice, Len() = %d", c.Len())
+	}
+	if len(evicted) != 2 {
+		t.Fatalf("evicted = %v, Remove must not call onEvict", evicted)
+	}
+
+	c.Get("missing")
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Evictions != 2 || s.Size != 1 {
+		t.Fatalf("Stats() = %+v", s)
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	clock := gxtime.NewFakeClock(time.Now())
+	var evicted []string
+	c := New(8, WithTTL(time.Second), WithClock(clock), WithOnEvict(func(key string, value interface{}) {
+		evicted = append(evicted, key)
+	}))
+	c.Set("a", 1)
+	clock.Advance(500 * time.Millisecond)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) before its expiry = false")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatalf("Peek(a) after its expiry = true")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, the expired entries are removed lazily", c.Len())
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) after its expiry = true")
+	}
+	if c.Len() != 1 || len(evicted) != 1 || evicted[0] != "a" {
+		t.Fatalf("Len() = %d, evicted = %v after Get(a)", c.Len(), evicted)
+	}
+
+	// Set renews the expiry
+	c.Set("b", 3)
+	clock.Advance(900 * time.Millisecond)
+	if v, ok := c.Get("b"); !ok || v != 3 {
+		t.Fatalf("Get(b) = %v, %v", v, ok)
+	}
+}
+
+func TestLRUCapacity(t *testing.T) {
+	for _, capacity := range []int{1, 3, 100, 1000} {
+		c := New(capacity, WithShards(16))
+		for i := 0; i < capacity*20; i++ {
+			c.Set(strconv.Itoa(i), i)
+			if n := c.Len(); n > capacity {
+				t.Fatalf("capacity %d: Len() = %d after %d Set()", capacity, n, i+1)
+			}
+		}
+		// every shard is full by now
+		if n := c.Len(); n != capacity {
+			t.Fatalf("capacity %d: Len() = %d", capacity, n)
+		}
+	}
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	const (
+		capacity   = 512
+		goroutines = 32
+		loops      = 2000
+	)
+	var evictions sync.Map
+	c := New(capacity, WithOnEvict(func(key string, value interface{}) {
+		evictions.Store(key, value)
+	}))
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < loops; i++ {
+				key := strconv.Itoa((g*loops + i) % (4 * capacity))
+				if v, ok := c.Get(key); ok && v.(string) != key {
+					panic(fmt.Sprintf("Get(%s) = %v", key, v))
+				}
+				c.Set(key, key)
+				if i%10 == 0 {
+					c.Remove(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s := c.Stats()
+	if s.Size > capacity || s.Hits+s.Misses != goroutines*loops {
+		t.Fatalf("Stats() = %+v", s)
+	}
+}
+
+// BenchmarkLRU compares the sharded LRU with a LRU of a single lock, under
+// 32 goroutines of Get and Set on a key set larger than the capacity.
+func BenchmarkLRU(b *testing.B) {
+	const capacity = 1 << 14
+	keys := make([]string, 4*capacity)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	for _, shards := range []int{1, 0} {
+		opts := []Option{WithShards(shards)}
+		name := "single-lock"
+		if shards == 0 {
+			opts, name = nil, "sharded"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := New(capacity, opts...)
+			for _, key := range keys[:capacity] {
+				c.Set(key, key)
+			}
+			// 32 goroutines at least
+			b.SetParallelism((32 + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if _, ok := c.Get(key); !ok {
+						c.Set(key, key)
+					}
+					i += 7
+				}
+			})
+		})
+	}
+}
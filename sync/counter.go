@@ -0,0 +1,136 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides sharded counter & gauge
+package gxsync
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+type paddedInt64 struct {
+	v int64
+	_ [7]int64 // Pad by cache-line size to prevent false sharing.
+}
+
+// Counter is a contention-free int64 counter. Writers add into one of many
+// cache-line padded cells picked by the stack address of their goroutine,
+// and Load sums all cells.
+//
+// Load during concurrent Add is only approximately consistent: it returns
+// a value between the counter before and after the concurrent Adds, but
+// not necessarily one the counter ever had at a single instant.
+type Counter struct {
+	cells []paddedInt64
+	shift uint32 // of the hash of a stack address to the index of a cell
+	last  int64  // value of the last Snapshot
+}
+
+// CounterSnapshot is a point-in-time reading of a Counter.
+type CounterSnapshot struct {
+	Value int64
+	Delta int64 // change since the previous Snapshot
+}
+
+// NewCounter returns a counter with about 4 cells per CPU.
+func NewCounter() *Counter {
+	n, bits := 1, uint32(0)
+	for n < 4*runtime.GOMAXPROCS(0) {
+		n <<= 1
+		bits++
+	}
+
+	return &Counter{
+		cells: make([]paddedInt64, n),
+		shift: 32 - bits,
+	}
+}
+
+// cell returns the cell of the calling goroutine. The goroutines have
+// stacks of 2KB at least, so the fibonacci hash of the address of a local
+// spreads them over the cells without any shared state, unlike math/rand
+// whose global source is locked before go1.20. A goroutine moves to another
+// cell when its stack grows, which is harmless.
+func (c *Counter) cell() *int64 {
+	var local byte
+	h := uint32(uintptr(unsafe.Pointer(&local))>>11) * 0x9e3779b1
+
+	return &c.cells[h>>c.shift].v
+}
+
+// Add adds @delta to the counter.
+func (c *Counter) Add(delta int64) {
+	atomic.AddInt64(c.cell(), delta)
+}
+
+// Inc adds 1 to the counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Load returns the sum of all cells.
+func (c *Counter) Load() int64 {
+	var sum int64
+	for i := range c.cells {
+		sum += atomic.LoadInt64(&c.cells[i].v)
+	}
+
+	return sum
+}
+
+// Reset sets the counter to zero and returns the value it had.
+func (c *Counter) Reset() int64 {
+	var sum int64
+	for i := range c.cells {
+		sum += atomic.SwapInt64(&c.cells[i].v, 0)
+	}
+	atomic.StoreInt64(&c.last, 0)
+
+	return sum
+}
+
+// Snapshot returns the current value and its change since the last Snapshot.
+func (c *Counter) Snapshot() CounterSnapshot {
+	v := c.Load()
+	last := atomic.SwapInt64(&c.last, v)
+
+	return CounterSnapshot{Value: v, Delta: v - last}
+}
+
+// Gauge is a sharded int64 value supporting both Set and Add.
+// Set is not atomic with respect to concurrent Adds, which may be applied
+// either before or after it.
+type Gauge struct {
+	c *Counter
+}
+
+// NewGauge returns a zero gauge.
+func NewGauge() *Gauge {
+	return &Gauge{c: NewCounter()}
+}
+
+// Set sets the gauge to @v.
+func (g *Gauge) Set(v int64) {
+	for i := 1; i < len(g.c.cells); i++ {
+		atomic.StoreInt64(&g.c.cells[i].v, 0)
+	}
+	atomic.StoreInt64(&g.c.cells[0].v, v)
+}
+
+// Add adds @delta to the gauge, @delta may be negative.
+func (g *Gauge) Add(delta int64) {
+	g.c.Add(delta)
+}
+
+// Load returns the current value.
+func (g *Gauge) Load() int64 {
+	return g.c.Load()
+}
+
+// Snapshot returns the current value, see Counter.Snapshot.
+func (g *Gauge) Snapshot() CounterSnapshot {
+	return g.c.Snapshot()
+}
@@ -0,0 +1,125 @@
+package gxsync
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	const (
+		writers = 64
+		loops   = 10000
+	)
+
+	var wg sync.WaitGroup
+	c := NewCounter()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < loops; j++ {
+				c.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := c.Load(); v != writers*loops {
+		t.Fatalf("Load() = %d, want %d", v, writers*loops)
+	}
+
+	s := c.Snapshot()
+	if s.Value != writers*loops || s.Delta != writers*loops {
+		t.Fatalf("Snapshot() = %+v", s)
+	}
+	c.Add(5)
+	if s = c.Snapshot(); s.Delta != 5 {
+		t.Fatalf("Snapshot() = %+v", s)
+	}
+
+	if v := c.Reset(); v != writers*loops+5 {
+		t.Fatalf("Reset() = %d", v)
+	}
+	if v := c.Load(); v != 0 {
+		t.Fatalf("Load() after Reset = %d", v)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge()
+	for i := 0; i < 100; i++ {
+		g.Add(2)
+	}
+	g.Add(-50)
+	if v := g.Load(); v != 150 {
+		t.Fatalf("Load() = %d", v)
+	}
+
+	g.Set(7)
+	if v := g.Load(); v != 7 {
+		t.Fatalf("Load() after Set(7) = %d", v)
+	}
+	g.Add(-10)
+	if v := g.Snapshot().Value; v != -3 {
+		t.Fatalf("Snapshot().Value = %d", v)
+	}
+}
+
+func TestCounterCells(t *testing.T) {
+	const writers = 32
+
+	// the writers are alive at once, so their stacks differ
+	var wg, added sync.WaitGroup
+	done := make(chan struct{})
+	c := NewCounter()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		added.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc()
+			added.Done()
+			<-done
+		}()
+	}
+	added.Wait()
+	close(done)
+	wg.Wait()
+
+	var used int
+	for i := range c.cells {
+		if c.cells[i].v != 0 {
+			used++
+		}
+	}
+	if used < 2 || c.Load() != writers {
+		t.Fatalf("%d writers add into %d of %d cells, Load() = %d", writers, used, len(c.cells), c.Load())
+	}
+}
+
+// BenchmarkCounterAdd compares the Counter with a single atomic int64 under
+// 32 goroutines at least, run it on many CPUs by
+//
+//	go test -bench=CounterAdd -cpu=8 -run=^$
+func BenchmarkCounterAdd(b *testing.B) {
+	b.Run("Counter", func(b *testing.B) {
+		c := NewCounter()
+		b.SetParallelism(32/runtime.GOMAXPROCS(0) + 1)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c.Inc()
+			}
+		})
+	})
+	b.Run("atomic.AddInt64", func(b *testing.B) {
+		var v int64
+		b.SetParallelism(32/runtime.GOMAXPROCS(0) + 1)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				atomic.AddInt64(&v, 1)
+			}
+		})
+	})
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a read/write lock per key
+package gxsync
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type keyLockEntry struct {
+	sync.RWMutex
+	refs    int   // of the holders and the waiters, under the shard lock
+	writer  int32 // 1 if write locked
+	readers int32
+}
+
+// keyLockEntries pools the removed entries, of the keys locked once mostly
+var keyLockEntries = sync.Pool{New: func() interface{} { return &keyLockEntry{} }}
+
+type keyLockShard struct {
+	sync.Mutex
+	entries map[interface{}]*keyLockEntry
+}
+
+// KeyLock is a read/write lock per key, e.g. to serialize the operations on
+// the same resource. The locks are kept in the maps of its shards by the
+// hash of the keys, as a Map is, and the lock of a key is removed once no
+// goroutine holds or waits on it, so any number of distinct keys can be
+// locked over time. The keys must be comparable.
+type KeyLock struct {
+	shards []*keyLockShard
+}
+
+// NewKeyLock returns a KeyLock of @shards shards, DefaultMapShards if
+// @shards is less than 1.
+func NewKeyLock(shards int) *KeyLock {
+	if shards < 1 {
+		shards = DefaultMapShards
+	}
+
+	l := &KeyLock{shards: make([]*keyLockShard, shards)}
+	for i := range l.shards {
+		l.shards[i] = &keyLockShard{entries: make(map[interface{}]*keyLockEntry)}
+	}
+
+	return l
+}
+
+func (l *KeyLock) shard(key interface{}) *keyLockShard {
+	return l.shards[hashKey(key)%uint32(len(l.shards))]
+}
+
+// acquire returns the entry of @key referenced by the caller.
+func (l *KeyLock) acquire(key interface{}) *keyLockEntry {
+	s := l.shard(key)
+	s.Lock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = keyLockEntries.Get().(*keyLockEntry)
+		s.entries[key] = e
+	}
+	e.refs++
+	s.Unlock()
+
+	return e
+}
+
+// release drops the reference of the caller to the entry of @key after
+// @check succeeds, and removes the entry if it is the last one, which the
+// caller puts back to keyLockEntries after unlocking it. It panics if there
+// is no entry or @check fails.
+func (l *KeyLock) release(key interface{}, method string, check func(*keyLockEntry) bool) (*keyLockEntry, bool) {
+	s := l.shard(key)
+	s.Lock()
+	e, ok := s.entries[key]
+	if !ok || !check(e) {
+		s.Unlock()
+		panic(fmt.Sprintf("gxsync: KeyLock.%s of unlocked key %v", method, key))
+	}
+	e.refs--
+	removed := e.refs == 0
+	if removed {
+		delete(s.entries, key)
+	}
+	s.Unlock()
+
+	return e, removed
+}
+
+// Lock write locks @key, blocking until it is available.
+func (l *KeyLock) Lock(key interface{}) {
+	e := l.acquire(key)
+	e.Lock()
+	atomic.StoreInt32(&e.writer, 1)
+}
+
+// Unlock write unlocks @key. It panics if @key is not write locked.
+func (l *KeyLock) Unlock(key interface{}) {
+	e, removed := l.release(key, "Unlock", func(e *keyLockEntry) bool {
+		return atomic.CompareAndSwapInt32(&e.writer, 1, 0)
+	})
+	e.Unlock()
+	if removed {
+		keyLockEntries.Put(e)
+	}
+}
+
+// RLock read locks @key, blocking until it is not write locked.
+func (l *KeyLock) RLock(key interface{}) {
+	e := l.acquire(key)
+	e.RLock()
+	atomic.AddInt32(&e.readers, 1)
+}
+
+// RUnlock undoes a RLock of @key. It panics if @key is not read locked.
+func (l *KeyLock) RUnlock(key interface{}) {
+	e, removed := l.release(key, "RUnlock", func(e *keyLockEntry) bool {
+		for {
+			n := atomic.LoadInt32(&e.readers)
+			if n == 0 {
+				return false
+			}
+			if atomic.CompareAndSwapInt32(&e.readers, n, n-1) {
+				return true
+			}
+		}
+	})
+	e.RUnlock()
+	if removed {
+		keyLockEntries.Put(e)
+	}
+}
+
+// tryAcquire references the entry of @key if @try succeeds on it.
+func (l *KeyLock) tryAcquire(key interface{}, try func(*keyLockEntry) bool) bool {
+	s := l.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = keyLockEntries.Get().(*keyLockEntry)
+	}
+	if !try(e) {
+		if !ok {
+			keyLockEntries.Put(e)
+		}
+		return false
+	}
+	if !ok {
+		s.entries[key] = e
+	}
+	e.refs++
+
+	return true
+}
+
+// TryLock write locks @key if it is not locked, and reports whether it
+// succeeds without blocking.
+func (l *KeyLock) TryLock(key interface{}) bool {
+	return l.tryAcquire(key, func(e *keyLockEntry) bool {
+		if !e.TryLock() {
+			return false
+		}
+		atomic.StoreInt32(&e.writer, 1)
+		return true
+	})
+}
+
+// TryRLock read locks @key if it is not write locked, and reports whether
+// it succeeds without blocking.
+func (l *KeyLock) TryRLock(key interface{}) bool {
+	return l.tryAcquire(key, func(e *keyLockEntry) bool {
+		if !e.TryRLock() {
+			return false
+		}
+		atomic.AddInt32(&e.readers, 1)
+		return true
+	})
+}
+
+// count returns the number of the entries.
+func (l *KeyLock) count() int {
+	var n int
+	for _, s := range l.shards {
+		s.Lock()
+		n += len(s.entries)
+		s.Unlock()
+	}
+
+	return n
+}
@@ -0,0 +1,189 @@
+package gxsync
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyLock(t *testing.T) {
+	l := NewKeyLock(4)
+	l.Lock("a")
+	if l.TryLock("a") || l.TryRLock("a") {
+		t.Fatalf("TryLock(a) of a write locked key = true")
+	}
+	if !l.TryLock("b") {
+		t.Fatalf("TryLock(b) = false")
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		l.Lock("a")
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatalf("Lock(a) of a write locked key returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.Unlock("a")
+	<-locked
+	l.Unlock("a")
+	l.Unlock("b")
+
+	l.RLock(1)
+	if !l.TryRLock(1) {
+		t.Fatalf("TryRLock(1) of a read locked key = false")
+	}
+	if l.TryLock(1) {
+		t.Fatalf("TryLock(1) of a read locked key = true")
+	}
+	l.RUnlock(1)
+	l.RUnlock(1)
+	if !l.TryLock(1) {
+		t.Fatalf("TryLock(1) after RUnlock = false")
+	}
+	l.Unlock(1)
+
+	if n := l.count(); n != 0 {
+		t.Fatalf("count() = %d, want 0", n)
+	}
+}
+
+func TestKeyLockUnlockPanic(t *testing.T) {
+	expectPanic := func(name, key string, f func()) {
+		defer func() {
+			r := recover()
+			if msg, _ := r.(string); !strings.Contains(msg, key) {
+				t.Fatalf("%s panics %v, want the key %s", name, r, key)
+			}
+		}()
+		f()
+	}
+
+	l := NewKeyLock(0)
+	expectPanic("Unlock of no key", "service-a", func() { l.Unlock("service-a") })
+	expectPanic("RUnlock of no key", "service-b", func() { l.RUnlock("service-b") })
+
+	l.RLock("service-c")
+	expectPanic("Unlock of a read locked key", "service-c", func() { l.Unlock("service-c") })
+	l.RUnlock("service-c")
+
+	l.Lock("service-d")
+	expectPanic("RUnlock of a write locked key", "service-d", func() { l.RUnlock("service-d") })
+	l.Unlock("service-d")
+	expectPanic("Unlock twice", "service-d", func() { l.Unlock("service-d") })
+
+	if n := l.count(); n != 0 {
+		t.Fatalf("count() = %d, want 0", n)
+	}
+}
+
+// TestKeyLockStress locks random keys of a small set, which contend, and
+// of a large set, which churn, and checks the exclusion of the writers and
+// that no entry is left at last.
+func TestKeyLockStress(t *testing.T) {
+	const (
+		goroutines = 16
+		loops      = 2000
+		hotKeys    = 8
+	)
+	var (
+		l       = NewKeyLock(4)
+		writers [hotKeys]int32
+		readers [hotKeys]int32
+		wg      sync.WaitGroup
+	)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < loops; i++ {
+				if r.Intn(4) == 0 {
+					// a distinct key, never locked again
+					key := fmt.Sprintf("churn-%d-%d", g, i)
+					l.Lock(key)
+					l.Unlock(key)
+					continue
+				}
+
+				k := r.Intn(hotKeys)
+				switch r.Intn(4) {
+				case 0, 1:
+					l.Lock(k)
+				case 2:
+					if !l.TryLock(k) {
+						continue
+					}
+				default:
+					l.RLock(k)
+					atomic.AddInt32(&readers[k], 1)
+					if atomic.LoadInt32(&writers[k]) != 0 {
+						t.Errorf("key %d read locked with a writer", k)
+					}
+					atomic.AddInt32(&readers[k], -1)
+					l.RUnlock(k)
+					continue
+				}
+				if atomic.AddInt32(&writers[k], 1) != 1 || atomic.LoadInt32(&readers[k]) != 0 {
+					t.Errorf("key %d write locked with another holder", k)
+				}
+				atomic.AddInt32(&writers[k], -1)
+				l.Unlock(k)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := l.count(); n != 0 {
+		t.Fatalf("count() = %d at quiescence, want 0", n)
+	}
+}
+
+// BenchmarkKeyLock compares KeyLock with a global mutex and a sync.Map of
+// mutexes, which are never removed.
+func BenchmarkKeyLock(b *testing.B) {
+	const keys = 1 << 10
+
+	b.Run("KeyLock", func(b *testing.B) {
+		l := NewKeyLock(DefaultMapShards)
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.Int()
+			for pb.Next() {
+				k := i % keys
+				l.Lock(k)
+				l.Unlock(k)
+				i++
+			}
+		})
+	})
+
+	b.Run("Mutex", func(b *testing.B) {
+		var mu sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mu.Lock()
+				mu.Unlock()
+			}
+		})
+	})
+
+	b.Run("sync.Map", func(b *testing.B) {
+		var m sync.Map
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.Int()
+			for pb.Next() {
+				v, _ := m.LoadOrStore(i%keys, &sync.Mutex{})
+				mu := v.(*sync.Mutex)
+				mu.Lock()
+				mu.Unlock()
+				i++
+			}
+		})
+	})
+}
@@ -0,0 +1,350 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a LRU cache sharded as Map is
+package gxsync
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// EvictReason is why an entry leaves a LRUCache.
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota // for a new entry of a full shard
+	EvictExpired                     // its TTL is over
+	EvictRemoved                     // by Remove
+)
+
+var evictReasonStrings = [...]string{
+	"capacity",
+	"expired",
+	"removed",
+}
+
+func (r EvictReason) String() string {
+	if int(r) < len(evictReasonStrings) {
+		return evictReasonStrings[r]
+	}
+
+	return "unknown"
+}
+
+// LRUCache is a Map of at most a capacity of entries. Every shard is a LRU
+// of its part of the capacity, so the entry evicted for a new one is the
+// least recently used one of its shard. An entry may have a TTL, after
+// which it is removed when it is got, or by the janitor of WithLRUJanitor.
+type LRUCache[K comparable, V any] struct {
+	shards  []*lruShard[K, V]
+	hash    func(K) uint32
+	onEvict func(key K, value V, reason EvictReason)
+	clock   Clock
+
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+type lruEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time // no expiry if zero
+}
+
+type lruEvicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+type lruShard[K comparable, V any] struct {
+	sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	ll       list.List // of *lruEntry, the most recently used at the front
+}
+
+type lruCacheOptions[K comparable, V any] struct {
+	shards   int
+	hash     func(K) uint32
+	onEvict  func(key K, value V, reason EvictReason)
+	interval time.Duration
+	clock    Clock
+}
+
+type LRUCacheOption[K comparable, V any] func(*lruCacheOptions[K, V])
+
+// WithLRUShards sets the shard count of a LRUCache, DefaultMapShards by
+// default. It is the capacity at most, so every shard holds an entry.
+func WithLRUShards[K comparable, V any](n int) LRUCacheOption[K, V] {
+	return func(o *lruCacheOptions[K, V]) {
+		o.shards = n
+	}
+}
+
+// WithLRUHasher hashes the keys by @hash, MapHash by default.
+func WithLRUHasher[K comparable, V any](hash func(K) uint32) LRUCacheOption[K, V] {
+	return func(o *lruCacheOptions[K, V]) {
+		o.hash = hash
+	}
+}
+
+// WithLRUOnEvict calls @fn with every entry leaving the cache, except the
+// ones replaced by Set. It is called out of the locks, so it may access the
+// cache.
+func WithLRUOnEvict[K comparable, V any](fn func(key K, value V, reason EvictReason)) LRUCacheOption[K, V] {
+	return func(o *lruCacheOptions[K, V]) {
+		o.onEvict = fn
+	}
+}
+
+// WithLRUJanitor removes the expired entries every @interval in a goroutine,
+// which is stopped by Close.
+func WithLRUJanitor[K comparable, V any](interval time.Duration) LRUCacheOption[K, V] {
+	return func(o *lruCacheOptions[K, V]) {
+		o.interval = interval
+	}
+}
+
+// WithLRUClock runs the TTLs and the janitor by @c, the clock of package
+// time by default.
+func WithLRUClock[K comparable, V any](c Clock) LRUCacheOption[K, V] {
+	return func(o *lruCacheOptions[K, V]) {
+		o.clock = c
+	}
+}
+
+// NewLRUCache returns an empty LRUCache of at most @capacity entries.
+func NewLRUCache[K comparable, V any](capacity int, opts ...LRUCacheOption[K, V]) *LRUCache[K, V] {
+	if capacity <= 0 {
+		panic("gxsync: NewLRUCache capacity should be greater than 0")
+	}
+	o := lruCacheOptions[K, V]{shards: DefaultMapShards, hash: MapHash[K], clock: realClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shards < 1 {
+		o.shards = 1
+	}
+	if o.shards > capacity {
+		o.shards = capacity
+	}
+
+	c := &LRUCache[K, V]{
+		shards:  make([]*lruShard[K, V], o.shards),
+		hash:    o.hash,
+		onEvict: o.onEvict,
+		clock:   o.clock,
+		done:    make(chan struct{}),
+	}
+	// the capacities of the shards sum up to @capacity
+	for i := range c.shards {
+		c.shards[i] = &lruShard[K, V]{
+			capacity: capacity / o.shards,
+			items:    make(map[K]*list.Element),
+		}
+		if i < capacity%o.shards {
+			c.shards[i].capacity++
+		}
+	}
+
+	if o.interval > 0 {
+		c.wg.Add(1)
+		go c.janitor(o.interval)
+	}
+
+	return c
+}
+
+func (c *LRUCache[K, V]) janitor(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C():
+			c.Purge()
+		}
+	}
+}
+
+func (c *LRUCache[K, V]) shard(key K) *lruShard[K, V] {
+	return c.shards[c.hash(key)%uint32(len(c.shards))]
+}
+
+func (e *lruEntry[K, V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// remove removes @elem of @s, and keeps it for the onEvict.
+// it should be called with s locked.
+func (c *LRUCache[K, V]) remove(s *lruShard[K, V], elem *list.Element, reason EvictReason, evicts *[]lruEvicted[K, V]) {
+	e := s.ll.Remove(elem).(*lruEntry[K, V])
+	delete(s.items, e.key)
+	if c.onEvict != nil {
+		*evicts = append(*evicts, lruEvicted[K, V]{key: e.key, value: e.value, reason: reason})
+	}
+}
+
+func (c *LRUCache[K, V]) notify(evicts []lruEvicted[K, V]) {
+	for _, e := range evicts {
+		c.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+// Set sets @value of @key without a TTL, see SetWithTTL.
+func (c *LRUCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL sets @value of @key as the most recently used, which expires
+// @ttl later, or never if @ttl <= 0. The least recently used entry of the
+// shard is evicted if it is full.
+func (c *LRUCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = c.clock.Now().Add(ttl)
+	}
+
+	var evicts []lruEvicted[K, V]
+	s := c.shard(key)
+	s.Lock()
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*lruEntry[K, V])
+		e.value, e.expireAt = value, expireAt
+		s.ll.MoveToFront(elem)
+	} else {
+		if s.ll.Len() >= s.capacity {
+			c.remove(s, s.ll.Back(), EvictCapacity, &evicts)
+		}
+		s.items[key] = s.ll.PushFront(&lruEntry[K, V]{key: key, value: value, expireAt: expireAt})
+	}
+	s.Unlock()
+	c.notify(evicts)
+}
+
+// Get returns the value of @key and marks it as the most recently used. An
+// expired entry is removed.
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	var (
+		evicts []lruEvicted[K, V]
+		value  V
+		ok     bool
+	)
+	s := c.shard(key)
+	s.Lock()
+	if elem, exist := s.items[key]; exist {
+		if e := elem.Value.(*lruEntry[K, V]); e.expired(c.clock.Now()) {
+			c.remove(s, elem, EvictExpired, &evicts)
+		} else {
+			s.ll.MoveToFront(elem)
+			value, ok = e.value, true
+		}
+	}
+	s.Unlock()
+	c.notify(evicts)
+
+	return value, ok
+}
+
+// Peek returns the value of @key without marking it as used. An expired
+// entry is not returned, but kept until it is got or purged.
+func (c *LRUCache[K, V]) Peek(key K) (V, bool) {
+	s := c.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		if e := elem.Value.(*lruEntry[K, V]); !e.expired(c.clock.Now()) {
+			return e.value, true
+		}
+	}
+
+	var value V
+	return value, false
+}
+
+// Has returns whether @key is in the cache and not expired, without
+// marking it as used.
+func (c *LRUCache[K, V]) Has(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Remove removes @key, and returns whether it was cached.
+func (c *LRUCache[K, V]) Remove(key K) bool {
+	var evicts []lruEvicted[K, V]
+	s := c.shard(key)
+	s.Lock()
+	elem, ok := s.items[key]
+	if ok {
+		c.remove(s, elem, EvictRemoved, &evicts)
+	}
+	s.Unlock()
+	c.notify(evicts)
+
+	return ok
+}
+
+// Purge removes all the expired entries.
+func (c *LRUCache[K, V]) Purge() {
+	for _, s := range c.shards {
+		var evicts []lruEvicted[K, V]
+		now := c.clock.Now()
+		s.Lock()
+		for elem := s.ll.Back(); elem != nil; {
+			prev := elem.Prev()
+			if elem.Value.(*lruEntry[K, V]).expired(now) {
+				c.remove(s, elem, EvictExpired, &evicts)
+			}
+			elem = prev
+		}
+		s.Unlock()
+		c.notify(evicts)
+	}
+}
+
+// Count returns the count of the keys, including the expired ones not
+// removed yet.
+func (c *LRUCache[K, V]) Count() int {
+	var n int
+	for _, s := range c.shards {
+		s.Lock()
+		n += s.ll.Len()
+		s.Unlock()
+	}
+
+	return n
+}
+
+// Keys returns the keys not expired, in no order.
+func (c *LRUCache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.Count())
+	now := c.clock.Now()
+	for _, s := range c.shards {
+		s.Lock()
+		for elem := s.ll.Front(); elem != nil; elem = elem.Next() {
+			if e := elem.Value.(*lruEntry[K, V]); !e.expired(now) {
+				keys = append(keys, e.key)
+			}
+		}
+		s.Unlock()
+	}
+
+	return keys
+}
+
+// Close stops the janitor. The cache is still usable after it.
+func (c *LRUCache[K, V]) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	c.wg.Wait()
+}
@@ -0,0 +1,240 @@
+package gxsync
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lruClock is a Clock of a manual time, and its tickers tick by the ticks.
+type lruClock struct {
+	lock  sync.Mutex
+	now   time.Time
+	ticks chan time.Time
+}
+
+type lruTicker struct {
+	c chan time.Time
+}
+
+func (c *lruClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *lruClock) NewTicker(d time.Duration) Ticker {
+	return lruTicker{c: c.ticks}
+}
+
+func (c *lruClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	c.lock.Unlock()
+}
+
+func (t lruTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t lruTicker) Stop() {}
+
+func (t lruTicker) Reset(d time.Duration) {}
+
+type evictRecord struct {
+	key    string
+	value  int
+	reason EvictReason
+}
+
+func TestLRUCacheOrder(t *testing.T) {
+	var evicts []evictRecord
+	c := NewLRUCache[string, int](3,
+		WithLRUShards[string, int](1),
+		WithLRUOnEvict(func(key string, value int, reason EvictReason) {
+			evicts = append(evicts, evictRecord{key, value, reason})
+		}),
+	)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	// a is used, b is the least recently used one then
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	c.Set("d", 4)
+	// Has does not mark c as used
+	if !c.Has("c") {
+		t.Fatal("Has(c) = false")
+	}
+	c.Set("e", 5)
+	c.Remove("a")
+	c.Remove("a")
+
+	want := []evictRecord{{"b", 2, EvictCapacity}, {"c", 3, EvictCapacity}, {"a", 1, EvictRemoved}}
+	if !reflect.DeepEqual(evicts, want) {
+		t.Fatalf("evicts = %v, want %v", evicts, want)
+	}
+	keys := c.Keys()
+	sort.Strings(keys)
+	if c.Count() != 2 || !reflect.DeepEqual(keys, []string{"d", "e"}) {
+		t.Fatalf("Count() = %d, Keys() = %v", c.Count(), keys)
+	}
+}
+
+func TestLRUCacheCapacity(t *testing.T) {
+	c := NewLRUCache[int, int](100)
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i)
+	}
+	if n := c.Count(); n > 100 {
+		t.Fatalf("Count() = %d, more than the capacity", n)
+	}
+	// the latest one is never evicted
+	if v, ok := c.Get(999); !ok || v != 999 {
+		t.Fatalf("Get(999) = %d, %v", v, ok)
+	}
+	if EvictExpired.String() != "expired" || EvictReason(10).String() != "unknown" {
+		t.Fatalf("EvictReason.String() = %s, %s", EvictExpired, EvictReason(10))
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	var (
+		clock  = &lruClock{now: time.Unix(1000, 0)}
+		evicts []evictRecord
+	)
+	c := NewLRUCache[string, int](10,
+		WithLRUClock[string, int](clock),
+		WithLRUOnEvict(func(key string, value int, reason EvictReason) {
+			evicts = append(evicts, evictRecord{key, value, reason})
+		}),
+	)
+	defer c.Close()
+
+	c.SetWithTTL("a", 1, 10*time.Second)
+	c.SetWithTTL("b", 2, 20*time.Second)
+	c.Set("c", 3)
+
+	clock.Advance(10*time.Second - time.Nanosecond)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) before its TTL = %d, %v", v, ok)
+	}
+	// expired at the TTL, but kept until it is got or purged
+	clock.Advance(time.Nanosecond)
+	if v, ok := c.Peek("a"); ok {
+		t.Fatalf("Peek(a) at its TTL = %d, %v", v, ok)
+	}
+	if c.Has("a") || c.Count() != 3 || len(c.Keys()) != 2 {
+		t.Fatalf("Has(a) = %v, Count() = %d, Keys() = %v", c.Has("a"), c.Count(), c.Keys())
+	}
+	if _, ok := c.Get("a"); ok || c.Count() != 2 {
+		t.Fatalf("Get(a) at its TTL = %v, Count() = %d", ok, c.Count())
+	}
+
+	// Set renews the TTL
+	c.SetWithTTL("b", 20, 20*time.Second)
+	clock.Advance(15 * time.Second)
+	c.Purge()
+	if v, ok := c.Get("b"); !ok || v != 20 {
+		t.Fatalf("Get(b) = %d, %v", v, ok)
+	}
+	clock.Advance(5 * time.Second)
+	c.Purge()
+	if c.Count() != 1 || !c.Has("c") {
+		t.Fatalf("Count() after Purge() = %d", c.Count())
+	}
+
+	want := []evictRecord{{"a", 1, EvictExpired}, {"b", 20, EvictExpired}}
+	if !reflect.DeepEqual(evicts, want) {
+		t.Fatalf("evicts = %v, want %v", evicts, want)
+	}
+}
+
+func TestLRUCacheJanitorClock(t *testing.T) {
+	clock := &lruClock{now: time.Unix(1000, 0), ticks: make(chan time.Time)}
+	c := NewLRUCache[string, int](10,
+		WithLRUClock[string, int](clock),
+		WithLRUJanitor[string, int](time.Minute),
+	)
+	defer c.Close()
+
+	c.SetWithTTL("a", 1, time.Second)
+	c.Set("b", 2)
+	clock.Advance(time.Second)
+	// the second tick is received after the Purge of the first one
+	clock.ticks <- clock.Now()
+	clock.ticks <- clock.Now()
+	if c.Count() != 1 || !c.Has("b") {
+		t.Fatalf("Count() after a tick = %d, Keys() = %v", c.Count(), c.Keys())
+	}
+	if !c.Remove("b") || c.Remove("b") {
+		t.Fatalf("Remove(b) twice")
+	}
+}
+
+// go test -race -v -run LRUCacheJanitor
+func TestLRUCacheJanitor(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		reasons = make(map[EvictReason]int)
+	)
+	c := NewLRUCache[string, int](64,
+		WithLRUJanitor[string, int](time.Millisecond),
+		WithLRUOnEvict(func(key string, value int, reason EvictReason) {
+			lock.Lock()
+			reasons[reason]++
+			lock.Unlock()
+		}),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				key := strconv.Itoa(i*100 + j%100)
+				c.SetWithTTL(key, j, time.Duration(j%3)*time.Millisecond)
+				if v, ok := c.Get(key); ok && v < 0 {
+					t.Errorf("Get(%s) = %d", key, v)
+				}
+				if j%50 == 0 {
+					c.Remove(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the janitor removes the rest of the expiring entries
+	deadline := time.Now().Add(time.Second)
+	for {
+		var expiring int
+		for _, k := range c.Keys() {
+			if v, ok := c.Get(k); ok && v%3 != 0 {
+				expiring++
+			}
+		}
+		if expiring == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d entries not expired", expiring)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	c.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if c.Count() > 64 || reasons[EvictCapacity] == 0 || reasons[EvictExpired] == 0 || reasons[EvictRemoved] == 0 {
+		t.Fatalf("Count() = %d, reasons:%v", c.Count(), reasons)
+	}
+}
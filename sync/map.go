@@ -0,0 +1,483 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a typed concurrent map sharded by the hash of the keys
+package gxsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// DefaultMapShards is the shard count of a Map by default.
+const DefaultMapShards = 32
+
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// Map is a concurrent map of typed keys and values. It is sharded by the
+// hash of the keys, and every shard is a map under its own RWMutex, so
+// that the writers of different shards do not contend.
+type Map[K comparable, V any] struct {
+	shards []*mapShard[K, V]
+	hash   func(K) uint32
+}
+
+type mapShard[K comparable, V any] struct {
+	sync.RWMutex
+	items map[K]V
+}
+
+type mapOptions[K comparable] struct {
+	shards int
+	hash   func(K) uint32
+}
+
+type MapOption[K comparable] func(*mapOptions[K])
+
+// WithMapShards sets the shard count of a Map, DefaultMapShards by default.
+func WithMapShards[K comparable](n int) MapOption[K] {
+	return func(o *mapOptions[K]) {
+		o.shards = n
+	}
+}
+
+// WithMapHasher hashes the keys of a Map by @hash, see MapHash for the
+// default one.
+func WithMapHasher[K comparable](hash func(K) uint32) MapOption[K] {
+	return func(o *mapOptions[K]) {
+		o.hash = hash
+	}
+}
+
+// NewMap returns an empty Map.
+func NewMap[K comparable, V any](opts ...MapOption[K]) *Map[K, V] {
+	m := &Map[K, V]{}
+	m.init(opts...)
+
+	return m
+}
+
+func (m *Map[K, V]) init(opts ...MapOption[K]) {
+	o := mapOptions[K]{shards: DefaultMapShards, hash: MapHash[K]}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shards < 1 {
+		o.shards = 1
+	}
+
+	m.shards = make([]*mapShard[K, V], o.shards)
+	m.hash = o.hash
+	for i := range m.shards {
+		m.shards[i] = &mapShard[K, V]{items: make(map[K]V)}
+	}
+}
+
+// MapHash is the fnv32 hash of @key for a string or an integer key. The
+// keys of the other types are hashed by their fmt "%v" strings, which is
+// slow, so give their maps a hash by WithMapHasher.
+func MapHash[K comparable](key K) uint32 {
+	return hashKey(key)
+}
+
+// hashKey is MapHash of a key of any type, as an interface{} is comparable
+// as a type argument from go1.20 only.
+func hashKey(key interface{}) uint32 {
+	switch k := key.(type) {
+	case string:
+		return fnv32(k)
+	case int:
+		return fnv32Uint64(uint64(k))
+	case int8:
+		return fnv32Uint64(uint64(k))
+	case int16:
+		return fnv32Uint64(uint64(k))
+	case int32:
+		return fnv32Uint64(uint64(k))
+	case int64:
+		return fnv32Uint64(uint64(k))
+	case uint:
+		return fnv32Uint64(uint64(k))
+	case uint8:
+		return fnv32Uint64(uint64(k))
+	case uint16:
+		return fnv32Uint64(uint64(k))
+	case uint32:
+		return fnv32Uint64(uint64(k))
+	case uint64:
+		return fnv32Uint64(k)
+	case uintptr:
+		return fnv32Uint64(uint64(k))
+	default:
+		return fnv32(fmt.Sprintf("%v", key))
+	}
+}
+
+func fnv32(s string) uint32 {
+	hash := uint32(fnvOffset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= fnvPrime32
+	}
+
+	return hash
+}
+
+func fnv32Uint64(v uint64) uint32 {
+	hash := uint32(fnvOffset32)
+	for i := 0; i < 8; i++ {
+		hash ^= uint32(byte(v >> (8 * i)))
+		hash *= fnvPrime32
+	}
+
+	return hash
+}
+
+func (m *Map[K, V]) shard(key K) *mapShard[K, V] {
+	return m.shards[m.hash(key)%uint32(len(m.shards))]
+}
+
+// Set sets @value of @key.
+func (m *Map[K, V]) Set(key K, value V) {
+	s := m.shard(key)
+	s.Lock()
+	s.items[key] = value
+	s.Unlock()
+}
+
+// UpsertCb returns the value to set by Upsert: @exist is whether the key
+// is in the map, @valueInMap is its value then, and @newValue is the value
+// passed to Upsert. It is called under the lock of the shard, so it must
+// not access the map.
+type UpsertCb[V any] func(exist bool, valueInMap V, newValue V) V
+
+// Upsert sets the value of @key returned by @cb, and returns it.
+func (m *Map[K, V]) Upsert(key K, value V, cb UpsertCb[V]) V {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.items[key]
+	v = cb(ok, v, value)
+	s.items[key] = v
+
+	return v
+}
+
+// MapValueError is the error of IncrBy and DecrBy of a key whose value is
+// not an integer.
+type MapValueError struct {
+	Key   interface{}
+	Value interface{}
+}
+
+func (e *MapValueError) Error() string {
+	return fmt.Sprintf("gxsync.Map value %#v of key %v is not an integer", e.Value, e.Key)
+}
+
+// IncrBy adds @delta to the value of @key of a builtin integer type under
+// the lock of the shard, and returns the sum. A missing key is 0 before.
+// The sum is of the type of the value, or an int64 in a map of interface{}
+// values, so it wraps like the integers of its type. It fails by a
+// *MapValueError if the value is not an integer, and the value is kept
+// then.
+func (m *Map[K, V]) IncrBy(key K, delta int64) (int64, error) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	v := s.items[key]
+	sum, n, ok := addInt(v, delta)
+	if !ok {
+		return 0, &MapValueError{Key: key, Value: v}
+	}
+	// an int64 may not be a value of an interface type other than interface{}
+	value, ok := sum.(V)
+	if !ok {
+		return 0, &MapValueError{Key: key, Value: v}
+	}
+	s.items[key] = value
+
+	return n, nil
+}
+
+// DecrBy subtracts @delta from the integer value of @key, see IncrBy.
+func (m *Map[K, V]) DecrBy(key K, delta int64) (int64, error) {
+	return m.IncrBy(key, -delta)
+}
+
+// GetInt64 returns the integer value of @key as an int64, and false if the
+// key is not in the map or its value is not an integer.
+func (m *Map[K, V]) GetInt64(key K) (int64, bool) {
+	v, ok := m.Get(key)
+	if !ok || any(v) == nil {
+		return 0, false
+	}
+	_, n, ok := addInt(v, 0)
+
+	return n, ok
+}
+
+// addInt returns @v + @delta of the integer type of @v, and as an int64.
+// A nil @v is an int64 0.
+func addInt(v interface{}, delta int64) (interface{}, int64, bool) {
+	switch n := v.(type) {
+	case nil:
+		return delta, delta, true
+	case int:
+		n += int(delta)
+		return n, int64(n), true
+	case int8:
+		n += int8(delta)
+		return n, int64(n), true
+	case int16:
+		n += int16(delta)
+		return n, int64(n), true
+	case int32:
+		n += int32(delta)
+		return n, int64(n), true
+	case int64:
+		n += delta
+		return n, n, true
+	case uint:
+		n += uint(delta)
+		return n, int64(n), true
+	case uint8:
+		n += uint8(delta)
+		return n, int64(n), true
+	case uint16:
+		n += uint16(delta)
+		return n, int64(n), true
+	case uint32:
+		n += uint32(delta)
+		return n, int64(n), true
+	case uint64:
+		n += uint64(delta)
+		return n, int64(n), true
+	case uintptr:
+		n += uintptr(delta)
+		return n, int64(n), true
+	default:
+		return nil, 0, false
+	}
+}
+
+// SetIfAbsent sets @value of @key if it is absent, and returns whether it
+// is set.
+func (m *Map[K, V]) SetIfAbsent(key K, value V) bool {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.items[key]; ok {
+		return false
+	}
+	s.items[key] = value
+
+	return true
+}
+
+// Get returns the value of @key, and whether it is in the map.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	s := m.shard(key)
+	s.RLock()
+	v, ok := s.items[key]
+	s.RUnlock()
+
+	return v, ok
+}
+
+// Has returns whether @key is in the map.
+func (m *Map[K, V]) Has(key K) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Remove removes @key.
+func (m *Map[K, V]) Remove(key K) {
+	s := m.shard(key)
+	s.Lock()
+	delete(s.items, key)
+	s.Unlock()
+}
+
+// Pop removes @key, and returns its value and whether it was in the map.
+func (m *Map[K, V]) Pop(key K) (V, bool) {
+	s := m.shard(key)
+	s.Lock()
+	v, ok := s.items[key]
+	delete(s.items, key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// RemoveCb removes @key if @cb returns true, and returns what @cb returns.
+// @cb gets the value of @key and whether it is in the map, under the lock
+// of the shard, so it must not access the map.
+func (m *Map[K, V]) RemoveCb(key K, cb func(key K, v V, exists bool) bool) bool {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.items[key]
+	remove := cb(key, v, ok)
+	if remove && ok {
+		delete(s.items, key)
+	}
+
+	return remove
+}
+
+// RemoveAll removes @keys, and returns the count of them which were in the
+// map.
+func (m *Map[K, V]) RemoveAll(keys ...K) int {
+	var n int
+	for _, key := range keys {
+		s := m.shard(key)
+		s.Lock()
+		if _, ok := s.items[key]; ok {
+			delete(s.items, key)
+			n++
+		}
+		s.Unlock()
+	}
+
+	return n
+}
+
+// Retain removes the keys for which @pred returns false, and returns their
+// count. @pred is called under the lock of the shard, so it must not access
+// the map.
+func (m *Map[K, V]) Retain(pred func(key K, v V) bool) int {
+	var n int
+	for _, s := range m.shards {
+		s.Lock()
+		for k, v := range s.items {
+			if !pred(k, v) {
+				delete(s.items, k)
+				n++
+			}
+		}
+		s.Unlock()
+	}
+
+	return n
+}
+
+// Clear removes all the keys. The shards are cleared one by one, so a key
+// set during Clear may be kept.
+func (m *Map[K, V]) Clear() {
+	for _, s := range m.shards {
+		s.Lock()
+		s.items = make(map[K]V)
+		s.Unlock()
+	}
+}
+
+// GetOrInsert returns the value of @key if it is in the map, or else sets
+// and returns the value of @newFunc. The returned bool is whether the value
+// was already in the map. @newFunc is called at most once per missing key
+// under the lock of the shard, so it must not access the map.
+func (m *Map[K, V]) GetOrInsert(key K, newFunc func() V) (V, bool) {
+	s := m.shard(key)
+	s.RLock()
+	v, ok := s.items[key]
+	s.RUnlock()
+	if ok {
+		return v, true
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if v, ok = s.items[key]; ok {
+		return v, true
+	}
+	v = newFunc()
+	s.items[key] = v
+
+	return v, false
+}
+
+// Count returns the count of the keys. It is not a snapshot of the map
+// during concurrent writes, as the shards are counted one by one.
+func (m *Map[K, V]) Count() int {
+	var n int
+	for _, s := range m.shards {
+		s.RLock()
+		n += len(s.items)
+		s.RUnlock()
+	}
+
+	return n
+}
+
+// Keys returns the keys, in no order. Like IterCb, it copies the shards one
+// by one, so the keys set during the call may be missed, and Count is only
+// the capacity hint of the result.
+func (m *Map[K, V]) Keys() []K {
+	keys := make([]K, 0, m.Count())
+	m.IterCb(func(key K, _ V) {
+		keys = append(keys, key)
+	})
+
+	return keys
+}
+
+// Items returns a copy of the map.
+func (m *Map[K, V]) Items() map[K]V {
+	items := make(map[K]V, m.Count())
+	m.IterCb(func(key K, v V) {
+		items[key] = v
+	})
+
+	return items
+}
+
+// IterCb calls @fn with every key and value, shard by shard under the read
+// lock of the shard, so @fn must not write the map.
+func (m *Map[K, V]) IterCb(fn func(key K, v V)) {
+	for _, s := range m.shards {
+		s.RLock()
+		for k, v := range s.items {
+			fn(k, v)
+		}
+		s.RUnlock()
+	}
+}
+
+// MarshalJSON encodes the map as a json object. Its keys must be strings,
+// integers or encoding.TextMarshalers, as the keys of a map encoded by
+// encoding/json.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(m.Items())
+	if err != nil {
+		return nil, fmt.Errorf("gxsync.Map[%T] MarshalJSON() = error:%w", *new(K), err)
+	}
+
+	return data, nil
+}
+
+// UnmarshalJSON adds the items of a json object to the map, of the same
+// keys as MarshalJSON. A zero Map is initialized with the default options
+// before, so that it can be decoded by json.Unmarshal.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	var items map[K]V
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("gxsync.Map[%T] UnmarshalJSON() = error:%w", *new(K), err)
+	}
+
+	if len(m.shards) == 0 {
+		m.init()
+	}
+	for k, v := range items {
+		m.Set(k, v)
+	}
+
+	return nil
+}
@@ -0,0 +1,459 @@
+package gxsync
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	m := NewMap[string, int]()
+	m.Set("a", 1)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	if !m.SetIfAbsent("b", 2) || m.SetIfAbsent("b", 3) {
+		t.Fatalf("SetIfAbsent(b) twice")
+	}
+	add := func(exist bool, valueInMap int, newValue int) int {
+		return valueInMap + newValue
+	}
+	if v := m.Upsert("b", 10, add); v != 12 {
+		t.Fatalf("Upsert(b) = %d", v)
+	}
+	if v := m.Upsert("c", 10, add); v != 10 {
+		t.Fatalf("Upsert(c) = %d", v)
+	}
+	if m.Count() != 3 || len(m.Keys()) != 3 {
+		t.Fatalf("Count() = %d, Keys() = %v", m.Count(), m.Keys())
+	}
+	if items := m.Items(); items["a"] != 1 || items["b"] != 12 || items["c"] != 10 {
+		t.Fatalf("Items() = %v", items)
+	}
+
+	if v, ok := m.Pop("b"); !ok || v != 12 || m.Has("b") {
+		t.Fatalf("Pop(b) = %d, %v", v, ok)
+	}
+	if _, ok := m.Pop("b"); ok {
+		t.Fatalf("Pop(b) twice = true")
+	}
+	m.Remove("a")
+	var sum int
+	m.IterCb(func(key string, v int) {
+		sum += v
+	})
+	if m.Has("a") || sum != 10 {
+		t.Fatalf("Has(a) after Remove(), sum = %d", sum)
+	}
+}
+
+func TestMapKeyTypes(t *testing.T) {
+	type point struct{ x, y int }
+	points := NewMap[point, string](WithMapShards[point](4))
+	for i := 0; i < 100; i++ {
+		points.Set(point{i, -i}, strconv.Itoa(i))
+	}
+	if v, ok := points.Get(point{42, -42}); !ok || v != "42" || points.Count() != 100 {
+		t.Fatalf("Get() of a struct key = %s, %v, Count() = %d", v, ok, points.Count())
+	}
+
+	// a custom hash puts all keys in one shard
+	ids := NewMap[uint64, int](WithMapHasher(func(uint64) uint32 { return 7 }))
+	for i := uint64(0); i < 100; i++ {
+		ids.Set(i, int(i))
+	}
+	for i, s := range ids.shards {
+		if n := len(s.items); (i == 7 && n != 100) || (i != 7 && n != 0) {
+			t.Fatalf("shard %d has %d keys", i, n)
+		}
+	}
+
+	if MapHash(int64(-1)) != MapHash(uint64(1<<64-1)) || MapHash("a") == MapHash("b") {
+		t.Fatalf("MapHash() of the integers of the same bits differ")
+	}
+}
+
+func TestMapConcurrent(t *testing.T) {
+	m := NewMap[int, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				m.Upsert(i, 1, func(exist bool, v int, n int) int { return v + n })
+				m.Get(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if m.Count() != 1000 {
+		t.Fatalf("Count() = %d", m.Count())
+	}
+	m.IterCb(func(key int, v int) {
+		if v != 16 {
+			t.Fatalf("value of %d = %d", key, v)
+		}
+	})
+}
+
+// the iterations copy the shards under their read locks, so they neither
+// block on the writers growing the map nor leave goroutines behind.
+func TestMapIterConcurrentSets(t *testing.T) {
+	m := NewMap[int, int]()
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Set(i, i)
+		}(i)
+	}
+	for i := 0; i < 50; i++ {
+		for range m.Keys() {
+			break
+		}
+		for k, v := range m.Items() {
+			if k != v {
+				t.Fatalf("item %d:%d", k, v)
+			}
+		}
+		m.IterCb(func(k int, v int) {})
+	}
+	wg.Wait()
+
+	if n := len(m.Keys()); n != 1000 || m.Count() != 1000 {
+		t.Fatalf("len(Keys()) = %d, Count() = %d", n, m.Count())
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines after the iterations, %d before", n, before)
+	}
+}
+
+func TestMapRemoveCb(t *testing.T) {
+	m := NewMap[string, bool]()
+	m.Set("open", false)
+	m.Set("closed", true)
+	closed := func(key string, v bool, exists bool) bool {
+		return exists && v
+	}
+	if m.RemoveCb("open", closed) || !m.Has("open") {
+		t.Fatalf("RemoveCb() removes an open session")
+	}
+	if !m.RemoveCb("closed", closed) || m.Has("closed") {
+		t.Fatalf("RemoveCb() keeps a closed session")
+	}
+	if m.RemoveCb("missing", closed) || m.Count() != 1 {
+		t.Fatalf("RemoveCb() of a missing key, Count() = %d", m.Count())
+	}
+}
+
+func TestMapRemoveAll(t *testing.T) {
+	m := NewMap[int, int](WithMapShards[int](4))
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+	}
+	if n := m.RemoveAll(1, 2, 2, 3, 1000); n != 3 || m.Count() != 97 || m.Has(2) {
+		t.Fatalf("RemoveAll() = %d, Count() = %d", n, m.Count())
+	}
+	if n := m.RemoveAll(); n != 0 {
+		t.Fatalf("RemoveAll() of no keys = %d", n)
+	}
+
+	// keep the even values
+	if n := m.Retain(func(_ int, v int) bool { return v%2 == 0 }); n != 48 || m.Count() != 49 {
+		t.Fatalf("Retain() = %d, Count() = %d", n, m.Count())
+	}
+	m.IterCb(func(key int, v int) {
+		if v%2 != 0 {
+			t.Fatalf("Retain() keeps %d", key)
+		}
+	})
+
+	m.Clear()
+	if m.Count() != 0 || len(m.Items()) != 0 {
+		t.Fatalf("Count() after Clear() = %d", m.Count())
+	}
+	m.Set(1, 1)
+	if v, ok := m.Get(1); !ok || v != 1 || m.Count() != 1 {
+		t.Fatalf("Get(1) after Clear() = %d, %v", v, ok)
+	}
+}
+
+func TestMapClearConcurrent(t *testing.T) {
+	const (
+		writers = 8
+		keys    = 1000
+	)
+	m := NewMap[int, int](WithMapShards[int](8))
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				m.Set((i*keys+j)%(writers*keys), j)
+			}
+		}(i)
+	}
+	for i := 0; i < 200; i++ {
+		switch i % 3 {
+		case 0:
+			m.Clear()
+		case 1:
+			m.RemoveAll(i, i+1, i+2)
+		default:
+			m.Retain(func(key int, _ int) bool { return key%7 != 0 })
+		}
+		if n := m.Count(); n < 0 || n > writers*keys {
+			t.Fatalf("Count() = %d", n)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	// the count of the distinct keys at quiescence
+	if n, items := m.Count(), m.Items(); n != len(items) || n != len(m.Keys()) {
+		t.Fatalf("Count() = %d, %d items", n, len(items))
+	}
+	n := m.Count()
+	if removed := m.Retain(func(int, int) bool { return false }); removed != n || m.Count() != 0 {
+		t.Fatalf("Retain() = %d of %d keys, Count() = %d", removed, n, m.Count())
+	}
+}
+
+func TestMapGetOrInsert(t *testing.T) {
+	m := NewMap[string, *int]()
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		calls  int
+		loaded int
+	)
+	newFunc := func() *int {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		v := 42
+		return &v
+	}
+
+	values := make([]*int, 100)
+	for g := 0; g < 100; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			v, ok := m.GetOrInsert("session", newFunc)
+			values[g] = v
+			if ok {
+				mu.Lock()
+				loaded++
+				mu.Unlock()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if calls != 1 || loaded != 99 || m.Count() != 1 {
+		t.Fatalf("GetOrInsert() calls = %d, loaded = %d, Count() = %d", calls, loaded, m.Count())
+	}
+	for g, v := range values {
+		if v != values[0] || *v != 42 {
+			t.Fatalf("GetOrInsert() of goroutine %d = %p, want %p", g, v, values[0])
+		}
+	}
+}
+
+func TestMapIncrBy(t *testing.T) {
+	m := NewMap[string, int32]()
+	if n, err := m.IncrBy("a", 3); n != 3 || err != nil {
+		t.Fatalf("IncrBy(a, 3) = %d, %v", n, err)
+	}
+	if n, err := m.DecrBy("a", 5); n != -2 || err != nil {
+		t.Fatalf("DecrBy(a, 5) = %d, %v", n, err)
+	}
+	if v, _ := m.Get("a"); v != -2 {
+		t.Fatalf("Get(a) = %d", v)
+	}
+	if n, ok := m.GetInt64("a"); n != -2 || !ok {
+		t.Fatalf("GetInt64(a) = %d, %v", n, ok)
+	}
+	if _, ok := m.GetInt64("b"); ok {
+		t.Fatalf("GetInt64(b) of a missing key = true")
+	}
+
+	values := NewMap[string, interface{}]()
+	values.Set("name", "gx")
+	values.Set("port", uint16(80))
+	if n, err := values.IncrBy("port", 1); n != 81 || err != nil {
+		t.Fatalf("IncrBy(port, 1) = %d, %v", n, err)
+	}
+	if v, _ := values.Get("port"); v != uint16(81) {
+		t.Fatalf("Get(port) = %#v", v)
+	}
+	if n, err := values.IncrBy("hits", 1); n != 1 || err != nil {
+		t.Fatalf("IncrBy(hits, 1) = %d, %v", n, err)
+	}
+	if v, _ := values.Get("hits"); v != int64(1) {
+		t.Fatalf("Get(hits) = %#v", v)
+	}
+	_, err := values.IncrBy("name", 1)
+	if e, ok := err.(*MapValueError); !ok || e.Key != "name" || e.Value != "gx" {
+		t.Fatalf("IncrBy(name, 1) = %v", err)
+	}
+	if v, _ := values.Get("name"); v != "gx" {
+		t.Fatalf("Get(name) after IncrBy() = %#v", v)
+	}
+	if _, ok := values.GetInt64("name"); ok {
+		t.Fatalf("GetInt64(name) of a string = true")
+	}
+
+	if _, err := NewMap[int, string]().IncrBy(1, 1); err == nil {
+		t.Fatalf("IncrBy() of a string map succeeds")
+	}
+}
+
+func TestMapIncrByConcurrent(t *testing.T) {
+	const (
+		goroutines = 64
+		increments = 10000
+		keys       = 8
+	)
+	m := NewMap[int, interface{}]()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				if _, err := m.IncrBy((g+i)%keys, 1); err != nil {
+					t.Errorf("IncrBy() = %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var total int64
+	for k := 0; k < keys; k++ {
+		n, ok := m.GetInt64(k)
+		if !ok || n != goroutines*increments/keys {
+			t.Fatalf("GetInt64(%d) = %d, %v", k, n, ok)
+		}
+		total += n
+	}
+	if total != goroutines*increments {
+		t.Fatalf("total %d", total)
+	}
+}
+
+func TestMapJSON(t *testing.T) {
+	m := NewMap[string, interface{}]()
+	m.Set("list", []interface{}{"a", 1.0})
+	m.Set("dict", map[string]interface{}{"k": []interface{}{true}})
+	m.Set("nil", nil)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal() = error:%s", err)
+	}
+	decoded := NewMap[string, interface{}](WithMapShards[string](4))
+	if err = json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = error:%s", data, err)
+	}
+	if !reflect.DeepEqual(decoded.Items(), m.Items()) || decoded.Count() != 3 {
+		t.Fatalf("json.Unmarshal(%s) = %v", data, decoded.Items())
+	}
+
+	// a zero Map, and integer keys
+	var ints Map[int, []int]
+	if err = json.Unmarshal([]byte(`{"1":[1],"2":[2,2]}`), &ints); err != nil {
+		t.Fatalf("json.Unmarshal() to a zero Map = error:%s", err)
+	}
+	if v, ok := ints.Get(2); !ok || len(v) != 2 || ints.Count() != 2 {
+		t.Fatalf("Get(2) = %v, %v", v, ok)
+	}
+
+	type point struct{ x, y int }
+	points := NewMap[point, int]()
+	points.Set(point{1, 2}, 3)
+	if _, err = json.Marshal(points); err == nil || !strings.Contains(err.Error(), "gxsync.point") {
+		t.Fatalf("json.Marshal() of struct keys = error:%v", err)
+	}
+	if err = json.Unmarshal([]byte(`{"a":1}`), points); err == nil {
+		t.Fatalf("json.Unmarshal() of struct keys = nil error")
+	}
+	if err = json.Unmarshal([]byte(`[1]`), decoded); err == nil {
+		t.Fatalf("json.Unmarshal() of an array = nil error")
+	}
+}
+
+// BenchmarkMap compares Map with sync.Map under 90% reads and 10% writes.
+func BenchmarkMap(b *testing.B) {
+	const keys = 1 << 12
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+	}
+
+	b.Run("Map", func(b *testing.B) {
+		m := NewMap[string, int]()
+		for i, name := range names {
+			m.Set(name, i)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var i int
+			for pb.Next() {
+				name := names[i%keys]
+				if i%10 == 0 {
+					m.Set(name, i)
+				} else {
+					m.Get(name)
+				}
+				i++
+			}
+		})
+	})
+
+	b.Run("sync.Map", func(b *testing.B) {
+		var m sync.Map
+		for i, name := range names {
+			m.Store(name, i)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var i int
+			for pb.Next() {
+				name := names[i%keys]
+				if i%10 == 0 {
+					m.Store(name, i)
+				} else if v, ok := m.Load(name); ok {
+					_ = v.(int)
+				}
+				i++
+			}
+		})
+	})
+}
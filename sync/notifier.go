@@ -0,0 +1,58 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a broadcast notifier
+package gxsync
+
+import (
+	"context"
+	"sync"
+)
+
+// Notifier wakes up every goroutine currently waiting on it. Each Notify
+// closes the channel of the current generation and installs a fresh one,
+// so a waiter who got its channel from Wait before Notify never misses it.
+//
+// The zero value is ready to use.
+type Notifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// NewNotifier returns a new Notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{ch: make(chan struct{})}
+}
+
+// Wait returns the channel which will be closed by the next Notify.
+func (n *Notifier) Wait() <-chan struct{} {
+	n.mu.Lock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	ch := n.ch
+	n.mu.Unlock()
+
+	return ch
+}
+
+// Notify wakes up all current waiters.
+func (n *Notifier) Notify() {
+	n.mu.Lock()
+	if n.ch != nil {
+		close(n.ch)
+	}
+	n.ch = make(chan struct{})
+	n.mu.Unlock()
+}
+
+// WaitContext blocks until the next Notify or until @ctx is done.
+func (n *Notifier) WaitContext(ctx context.Context) error {
+	select {
+	case <-n.Wait():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
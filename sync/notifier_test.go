@@ -0,0 +1,74 @@
+package gxsync
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifier(t *testing.T) {
+	var n Notifier
+
+	ch := n.Wait()
+	select {
+	case <-ch:
+		t.Fatal("channel closed before Notify")
+	default:
+	}
+
+	n.Notify()
+	select {
+	case <-ch:
+	default:
+		t.Fatal("channel not closed after Notify")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := n.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitContext() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// go test -race -v -run NotifierStress
+func TestNotifierStress(t *testing.T) {
+	const (
+		waiters = 64
+		rounds  = 1000
+	)
+
+	var (
+		wg    sync.WaitGroup
+		woken int64
+		n     = NewNotifier()
+	)
+
+	for r := 0; r < rounds; r++ {
+		ready := make(chan struct{}, waiters)
+		for i := 0; i < waiters; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ch := n.Wait()
+				ready <- struct{}{}
+				select {
+				case <-ch:
+					atomic.AddInt64(&woken, 1)
+				case <-time.After(5 * time.Second):
+					t.Error("missed wakeup")
+				}
+			}()
+		}
+		for i := 0; i < waiters; i++ {
+			<-ready
+		}
+		n.Notify()
+	}
+	wg.Wait()
+
+	if woken != waiters*rounds {
+		t.Fatalf("woken = %d, want %d", woken, waiters*rounds)
+	}
+}
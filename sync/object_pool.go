@@ -0,0 +1,192 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a sync.Pool wrapper which can detect misuses
+package gxsync
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+type PoolErrorType int
+
+const (
+	PoolDoublePut  PoolErrorType = iota // object has been put twice
+	PoolForeignPut                      // object was not got from this pool
+	PoolLeak                            // object was got but never put back
+)
+
+func (t PoolErrorType) String() string {
+	switch t {
+	case PoolDoublePut:
+		return "double put"
+	case PoolForeignPut:
+		return "put of foreign object"
+	case PoolLeak:
+		return "object leak"
+	}
+
+	return "unknown pool error"
+}
+
+// PoolError describes a misuse found by a debug Pool.
+type PoolError struct {
+	Type PoolErrorType
+	// object type, the object itself may have been collected
+	ObjType string
+	// stack of the last Get of the object, if any
+	GetStack []byte
+}
+
+func (e *PoolError) Error() string {
+	if len(e.GetStack) == 0 {
+		return fmt.Sprintf("gxsync.Pool: %s of %s", e.Type, e.ObjType)
+	}
+
+	return fmt.Sprintf("gxsync.Pool: %s of %s, got at:\n%s", e.Type, e.ObjType, e.GetStack)
+}
+
+// Pool is a thin wrapper of sync.Pool. A pool created by NewPool adds nothing
+// but a nil check to Get and Put. A pool created by NewDebugPool tracks every
+// pointer object it hands out and reports double Put, Put of objects it did
+// not create, and objects which were Get but became garbage without Put.
+// Non-pointer objects are not tracked.
+type Pool struct {
+	p     sync.Pool
+	debug *poolDebug
+}
+
+type poolObjState struct {
+	pooled bool
+	stack  []byte
+}
+
+type poolDebug struct {
+	sync.Mutex
+	// keyed by object address. the address is not a reference, so the
+	// object can still be collected and its finalizer can run.
+	objs   map[uintptr]*poolObjState
+	report func(*PoolError)
+}
+
+// NewPool returns a Pool whose Get calls @newFunc when the pool is empty.
+func NewPool(newFunc func() interface{}) *Pool {
+	p := &Pool{}
+	p.p.New = newFunc
+	return p
+}
+
+// NewDebugPool returns a Pool which reports misuses to @report.
+// If @report is nil, errors are written to stderr.
+// It is much slower than a release pool and should only be used in tests.
+func NewDebugPool(newFunc func() interface{}, report func(*PoolError)) *Pool {
+	if report == nil {
+		report = func(e *PoolError) {
+			fmt.Fprintln(os.Stderr, e.Error())
+		}
+	}
+
+	p := NewPool(newFunc)
+	p.debug = &poolDebug{
+		objs:   make(map[uintptr]*poolObjState),
+		report: report,
+	}
+
+	return p
+}
+
+// Get selects an arbitrary item from the pool, see sync.Pool.Get.
+func (p *Pool) Get() interface{} {
+	x := p.p.Get()
+	if p.debug != nil && x != nil {
+		p.debug.get(x)
+	}
+
+	return x
+}
+
+// Put adds @x to the pool, see sync.Pool.Put.
+func (p *Pool) Put(x interface{}) {
+	if p.debug != nil && x != nil && !p.debug.put(x) {
+		return
+	}
+
+	p.p.Put(x)
+}
+
+func objAddr(x interface{}) (uintptr, bool) {
+	v := reflect.ValueOf(x)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, false
+	}
+
+	return v.Pointer(), true
+}
+
+func (d *poolDebug) get(x interface{}) {
+	addr, ok := objAddr(x)
+	if !ok {
+		return
+	}
+
+	stack := debug.Stack()
+	d.Lock()
+	st, ok := d.objs[addr]
+	if !ok {
+		st = &poolObjState{}
+		d.objs[addr] = st
+		runtime.SetFinalizer(x, func(obj interface{}) { d.finalize(obj) })
+	}
+	st.pooled = false
+	st.stack = stack
+	d.Unlock()
+}
+
+// returns false if @x should not be put into the pool.
+func (d *poolDebug) put(x interface{}) bool {
+	addr, ok := objAddr(x)
+	if !ok {
+		return true
+	}
+
+	var pe *PoolError
+	d.Lock()
+	st, ok := d.objs[addr]
+	switch {
+	case !ok:
+		pe = &PoolError{Type: PoolForeignPut, ObjType: reflect.TypeOf(x).String()}
+	case st.pooled:
+		pe = &PoolError{Type: PoolDoublePut, ObjType: reflect.TypeOf(x).String(), GetStack: st.stack}
+	default:
+		st.pooled = true
+	}
+	d.Unlock()
+
+	if pe != nil {
+		d.report(pe)
+		return false
+	}
+
+	return true
+}
+
+// called when the object becomes unreachable, either because the caller lost
+// it (a leak) or because sync.Pool dropped it during a GC (not a leak).
+func (d *poolDebug) finalize(x interface{}) {
+	addr, _ := objAddr(x)
+
+	d.Lock()
+	st, ok := d.objs[addr]
+	delete(d.objs, addr)
+	d.Unlock()
+
+	if ok && !st.pooled {
+		d.report(&PoolError{Type: PoolLeak, ObjType: reflect.TypeOf(x).String(), GetStack: st.stack})
+	}
+}
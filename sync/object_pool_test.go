@@ -0,0 +1,136 @@
+package gxsync
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+type poolObj struct {
+	buf [64]byte
+}
+
+func newPoolObj() interface{} {
+	return &poolObj{}
+}
+
+type poolErrRecorder struct {
+	sync.Mutex
+	errs []*PoolError
+}
+
+func (r *poolErrRecorder) report(e *PoolError) {
+	r.Lock()
+	r.errs = append(r.errs, e)
+	r.Unlock()
+}
+
+func (r *poolErrRecorder) count(typ PoolErrorType) int {
+	r.Lock()
+	defer r.Unlock()
+	n := 0
+	for _, e := range r.errs {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDebugPoolMisuse(t *testing.T) {
+	var r poolErrRecorder
+	p := NewDebugPool(newPoolObj, r.report)
+
+	o := p.Get().(*poolObj)
+	p.Put(o)
+	p.Put(o)
+	if n := r.count(PoolDoublePut); n != 1 {
+		t.Fatalf("double put reported %d times", n)
+	}
+
+	p.Put(&poolObj{})
+	if n := r.count(PoolForeignPut); n != 1 {
+		t.Fatalf("foreign put reported %d times", n)
+	}
+
+	// non-pointer objects are passed through
+	p.Put(1)
+	if len(r.errs) != 2 {
+		t.Fatalf("errors:%v", r.errs)
+	}
+}
+
+func TestDebugPoolLeak(t *testing.T) {
+	var r poolErrRecorder
+	p := NewDebugPool(newPoolObj, r.report)
+
+	func() {
+		o := p.Get()
+		_ = o
+	}()
+
+	for i := 0; i < 50 && r.count(PoolLeak) == 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.count(PoolLeak) != 1 {
+		t.Fatalf("leak reported %d times", r.count(PoolLeak))
+	}
+	if len(r.errs[0].GetStack) == 0 {
+		t.Fatal("leak report has no Get stack")
+	}
+	t.Log(r.errs[0])
+
+	// objects dropped by sync.Pool during GC are not leaks
+	o := p.Get()
+	p.Put(o)
+	o = nil
+	for i := 0; i < 5; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.count(PoolLeak) != 1 {
+		t.Fatalf("leak reported %d times", r.count(PoolLeak))
+	}
+}
+
+func TestPoolZeroAlloc(t *testing.T) {
+	p := NewPool(newPoolObj)
+	p.Put(p.Get())
+	allocs := testing.AllocsPerRun(1000, func() {
+		p.Put(p.Get())
+	})
+	if allocs != 0 {
+		t.Fatalf("release Pool Get/Put allocs = %v", allocs)
+	}
+}
+
+// go test -bench=Pool -benchmem -run=^$
+func BenchmarkPool(b *testing.B) {
+	p := NewPool(newPoolObj)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.Put(p.Get())
+		}
+	})
+}
+
+func BenchmarkSyncPool(b *testing.B) {
+	p := sync.Pool{New: newPoolObj}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.Put(p.Get())
+		}
+	})
+}
+
+func BenchmarkDebugPool(b *testing.B) {
+	p := NewDebugPool(newPoolObj, func(*PoolError) {})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Put(p.Get())
+	}
+}
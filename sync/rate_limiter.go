@@ -0,0 +1,205 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a token bucket rate limiter
+package gxsync
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// InfDuration is returned by RateLimiter.Reserve when the request
+	// can never be satisfied, e.g. the rate is zero and the bucket is empty.
+	InfDuration = time.Duration(math.MaxInt64)
+)
+
+var (
+	ErrRateLimiterExceedDeadline = fmt.Errorf("rate limiter wait would exceed context deadline")
+)
+
+// RateLimiter is a token bucket. The bucket holds at most @burst tokens and
+// is refilled at @rate tokens per second. Refilling is computed lazily from
+// the monotonic clock on every call, so no background goroutine is needed.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  int
+	tokens float64
+	last   time.Time // last refill time, carries the monotonic reading
+}
+
+// NewRateLimiter returns a limiter allowing @rate events per second with
+// bursts of at most @burst events. The bucket starts full.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 0 {
+		burst = 0
+	}
+
+	return &RateLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// advance refills the bucket up to @now. it should be called with l.mu held.
+func (l *RateLimiter) advance(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+
+	if l.rate <= 0 {
+		return
+	}
+	l.tokens += elapsed.Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+}
+
+// delay returns how long it takes for the (possibly negative) token balance
+// to become non-negative. it should be called with l.mu held.
+func (l *RateLimiter) delay() time.Duration {
+	if l.tokens >= 0 {
+		return 0
+	}
+	if l.rate <= 0 {
+		return InfDuration
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Allow takes a token if one is available right now.
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance(time.Now())
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+
+	return false
+}
+
+// Reserve takes a token and returns how long the caller should wait before
+// acting on it. The token is always consumed unless InfDuration is returned.
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.reserve()
+}
+
+// it should be called with l.mu held.
+func (l *RateLimiter) reserve() time.Duration {
+	l.advance(time.Now())
+	l.tokens--
+	d := l.delay()
+	if d == InfDuration {
+		l.tokens++
+	}
+
+	return d
+}
+
+// cancel gives back a token taken by reserve.
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.tokens++
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.mu.Unlock()
+}
+
+// Wait blocks until a token is available or @ctx is done. If the context
+// deadline comes before the token would be available, Wait returns
+// ErrRateLimiterExceedDeadline at once instead of sleeping in vain.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	d := l.reserve()
+	l.mu.Unlock()
+	if d == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && (d == InfDuration || time.Until(deadline) < d) {
+		if d != InfDuration {
+			l.cancel()
+		}
+		return ErrRateLimiterExceedDeadline
+	}
+
+	if d == InfDuration {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// SetRate changes the refill rate. Tokens accrued so far are kept.
+func (l *RateLimiter) SetRate(rate float64) {
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.rate = rate
+	l.mu.Unlock()
+}
+
+// SetBurst changes the bucket size, dropping tokens above the new size.
+func (l *RateLimiter) SetBurst(burst int) {
+	if burst < 0 {
+		burst = 0
+	}
+
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	l.mu.Unlock()
+}
+
+// Rate returns the current refill rate.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	r := l.rate
+	l.mu.Unlock()
+
+	return r
+}
+
+// Burst returns the current bucket size.
+func (l *RateLimiter) Burst() int {
+	l.mu.Lock()
+	b := l.burst
+	l.mu.Unlock()
+
+	return b
+}
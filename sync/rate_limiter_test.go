@@ -0,0 +1,107 @@
+package gxsync
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := NewRateLimiter(1, 3)
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("Allow() #%d = false, want true", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("Allow() on empty bucket = true")
+	}
+	if d := l.Reserve(); d <= 0 || d > time.Second {
+		t.Fatalf("Reserve() = %v, want (0, 1s]", d)
+	}
+}
+
+func TestRateLimiterLongRunRate(t *testing.T) {
+	const (
+		rate   = 500.0
+		events = 250
+		burst  = 1
+	)
+
+	var wg sync.WaitGroup
+	l := NewRateLimiter(rate, burst)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < events/5; j++ {
+				if err := l.Wait(context.Background()); err != nil {
+					t.Errorf("Wait() = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	want := time.Duration(float64(events-burst) / rate * float64(time.Second))
+	if elapsed < want*8/10 || elapsed > want*15/10 {
+		t.Fatalf("%d events took %v, want about %v", events, elapsed, want)
+	}
+}
+
+func TestRateLimiterWaitCancel(t *testing.T) {
+	l := NewRateLimiter(0.5, 1)
+	if !l.Allow() {
+		t.Fatal("Allow() = false")
+	}
+
+	// the next token comes after 2s, far behind the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); err != ErrRateLimiterExceedDeadline {
+		t.Fatalf("Wait() = %v, want ErrRateLimiterExceedDeadline", err)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Fatalf("Wait() returned after %v", d)
+	}
+
+	// cancellation without deadline
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("Wait() = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Fatalf("Wait() returned after %v", d)
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	l := NewRateLimiter(0, 1)
+	l.Allow()
+	if d := l.Reserve(); d != InfDuration {
+		t.Fatalf("Reserve() with zero rate = %v", d)
+	}
+
+	l.SetRate(1000)
+	l.SetBurst(10)
+	time.Sleep(20 * time.Millisecond)
+	n := 0
+	for l.Allow() {
+		n++
+	}
+	if n != 10 {
+		t.Fatalf("got %d tokens after SetBurst(10), want 10", n)
+	}
+	if l.Rate() != 1000 || l.Burst() != 10 {
+		t.Fatalf("Rate() = %v, Burst() = %v", l.Rate(), l.Burst())
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a counting semaphore
+package gxsync
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+type semaphoreWaiter struct {
+	n     int
+	ready chan struct{} // closed when the permits are granted
+}
+
+// Semaphore bounds the concurrency by a pool of permits. Its waiters are
+// granted in FIFO order: a waiter which does not fit blocks the ones behind
+// it, so that the callers acquiring many permits are not starved by the
+// ones acquiring few.
+type Semaphore struct {
+	size int
+
+	mu      sync.Mutex
+	cur     int       // permits acquired
+	waiters list.List // of *semaphoreWaiter
+}
+
+// NewSemaphore returns a semaphore of @n permits.
+func NewSemaphore(n int) *Semaphore {
+	if n <= 0 {
+		panic("gxsync: NewSemaphore n should be greater than 0")
+	}
+
+	return &Semaphore{size: n}
+}
+
+// Acquire acquires a permit, blocking until it is available or @ctx is
+// done. See AcquireN.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	return s.AcquireN(ctx, 1)
+}
+
+// AcquireN acquires @n permits, blocking until they are all available or
+// @ctx is done. On failure it returns ctx.Err() and acquires nothing. It
+// panics if @n is greater than the capacity, which would block forever.
+func (s *Semaphore) AcquireN(ctx context.Context, n int) error {
+	if n < 0 || n > s.size {
+		panic("gxsync: Semaphore.AcquireN n should be in [0, capacity]")
+	}
+
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// granted just before the cancellation was seen, so give the
+			// permits back rather than leak them.
+			s.cur -= n
+		default:
+			s.waiters.Remove(elem)
+		}
+		// the removed waiter may have blocked the ones behind it
+		s.notifyWaiters()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire acquires a permit without blocking. It fails if no permit is
+// available or there are waiters before it.
+func (s *Semaphore) TryAcquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cur < s.size && s.waiters.Len() == 0 {
+		s.cur++
+		return true
+	}
+
+	return false
+}
+
+// Release releases a permit.
+func (s *Semaphore) Release() {
+	s.ReleaseN(1)
+}
+
+// ReleaseN releases @n permits. It panics if more permits are released
+// than acquired.
+func (s *Semaphore) ReleaseN(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 0 || n > s.cur {
+		panic("gxsync: Semaphore released more permits than acquired")
+	}
+	s.cur -= n
+	s.notifyWaiters()
+}
+
+// notifyWaiters grants the permits to the waiters in order, until the
+// first one they are not enough for.
+// it should be called with s.mu held.
+func (s *Semaphore) notifyWaiters() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+// Len returns the number of permits acquired.
+func (s *Semaphore) Len() int {
+	s.mu.Lock()
+	n := s.cur
+	s.mu.Unlock()
+
+	return n
+}
+
+// Cap returns the number of permits of the semaphore.
+func (s *Semaphore) Cap() int {
+	return s.size
+}
@@ -0,0 +1,186 @@
+package gxsync
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// go test -race -v -run Semaphore
+func TestSemaphoreStress(t *testing.T) {
+	const (
+		size       = 10
+		goroutines = 5000
+	)
+
+	var (
+		inflight int64
+		max      int64
+		wg       sync.WaitGroup
+	)
+	s := NewSemaphore(size)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := 1 + i%3
+			if err := s.AcquireN(context.Background(), n); err != nil {
+				t.Errorf("AcquireN(%d) = error %v", n, err)
+				return
+			}
+			cur := atomic.AddInt64(&inflight, int64(n))
+			for {
+				m := atomic.LoadInt64(&max)
+				if cur <= m || atomic.CompareAndSwapInt64(&max, m, cur) {
+					break
+				}
+			}
+			atomic.AddInt64(&inflight, -int64(n))
+			s.ReleaseN(n)
+		}(i)
+	}
+	wg.Wait()
+
+	if max > size {
+		t.Fatalf("%d permits in flight, more than %d", max, size)
+	}
+	if s.Len() != 0 || s.Cap() != size {
+		t.Fatalf("Len() = %d, Cap() = %d", s.Len(), s.Cap())
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := NewSemaphore(3)
+	if err := s.AcquireN(context.Background(), 2); err != nil {
+		t.Fatalf("AcquireN(2) = error %v", err)
+	}
+
+	// a waiter for 3 permits blocks the later waiters for 1
+	big := make(chan struct{})
+	go func() {
+		s.AcquireN(context.Background(), 3)
+		close(big)
+	}()
+	waitFor(t, func() bool { return s.waiting() == 1 })
+	if s.TryAcquire() {
+		t.Fatal("TryAcquire() before a waiter = true")
+	}
+	small := make(chan struct{})
+	go func() {
+		s.Acquire(context.Background())
+		close(small)
+	}()
+	waitFor(t, func() bool { return s.waiting() == 2 })
+
+	s.ReleaseN(2)
+	<-big
+	select {
+	case <-small:
+		t.Fatal("a later waiter is granted before the earlier one releases")
+	default:
+	}
+	s.ReleaseN(3)
+	<-small
+	s.Release()
+	if s.Len() != 0 {
+		t.Fatalf("Len() = %d", s.Len())
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := NewSemaphore(2)
+	if !s.TryAcquire() {
+		t.Fatal("TryAcquire() = false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.AcquireN(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("AcquireN(2) = %v, want context.DeadlineExceeded", err)
+	}
+	// the cancelled waiter neither holds permits nor blocks the others
+	if s.Len() != 1 || s.waiting() != 0 {
+		t.Fatalf("Len() = %d, waiting %d", s.Len(), s.waiting())
+	}
+	if !s.TryAcquire() {
+		t.Fatal("TryAcquire() after a cancelled waiter = false")
+	}
+	s.ReleaseN(2)
+
+	// cancellations racing with releases do not leak permits
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		s.AcquireN(context.Background(), 2)
+		ctx, cancel := context.WithCancel(context.Background())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Acquire(ctx) == nil {
+				s.Release()
+			}
+		}()
+		go cancel()
+		s.ReleaseN(2)
+		wg.Wait()
+	}
+	if s.Len() != 0 || s.waiting() != 0 {
+		t.Fatalf("Len() = %d, waiting %d", s.Len(), s.waiting())
+	}
+}
+
+func TestSemaphorePanic(t *testing.T) {
+	expectPanic := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s does not panic", name)
+			}
+		}()
+		f()
+	}
+
+	s := NewSemaphore(2)
+	expectPanic("Release() without Acquire()", s.Release)
+	expectPanic("AcquireN(3)", func() { s.AcquireN(context.Background(), 3) })
+	expectPanic("NewSemaphore(0)", func() { NewSemaphore(0) })
+}
+
+func (s *Semaphore) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.waiters.Len()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// go test -bench Semaphore -run none
+func BenchmarkSemaphore(b *testing.B) {
+	s := NewSemaphore(8)
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Acquire(ctx)
+			s.Release()
+		}
+	})
+}
+
+func BenchmarkSemaphoreChan(b *testing.B) {
+	ch := make(chan struct{}, 8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch <- struct{}{}
+			<-ch
+		}
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// this file provides a goroutine pool consuming a priority task queue
+package gxsync
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTaskPoolClosed = fmt.Errorf("task pool has been closed")
+)
+
+type priorityTask struct {
+	priority int
+	key      float64 // effective priority, see TaskPool
+	seq      uint64
+	task     func()
+}
+
+type taskHeap []*priorityTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].key != h[j].key {
+		return h[i].key > h[j].key
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*priorityTask)) }
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return t
+}
+
+// TaskBandStats is the statistics of one priority.
+type TaskBandStats struct {
+	Submitted int64
+	Completed int64
+	Panicked  int64
+	Pending   int64
+}
+
+// TaskPoolStats is a snapshot of a TaskPool.
+type TaskPoolStats struct {
+	Workers int
+	Pending int
+	Bands   map[int]TaskBandStats // keyed by priority
+}
+
+type TaskPoolOption func(*TaskPool)
+
+// WithTaskPoolBoost sets the starvation protection: a task which has waited
+// @age longer than another one is treated as one priority higher than it.
+// 0, the default, disables aging so ordering is strictly by priority.
+func WithTaskPoolBoost(age time.Duration) TaskPoolOption {
+	return func(p *TaskPool) {
+		p.boost = age
+	}
+}
+
+// TaskPool runs tasks on a fixed number of goroutines. Queued tasks are
+// ordered by priority, the greater the earlier, and FIFO within the same
+// priority. Tasks submitted concurrently are ordered by the time they enter
+// the queue.
+//
+// With aging enabled the effective priority of a task is
+// priority + waited/boostAge. Because every queued task ages at the same
+// speed, this equals a fixed key priority - enqueueTime/boostAge, so the
+// queue stays an ordinary heap.
+type TaskPool struct {
+	workers int
+	boost   time.Duration
+	base    time.Time // monotonic base of enqueue times
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	q      taskHeap
+	seq    uint64
+	bands  map[int]*TaskBandStats
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewTaskPool starts a pool with @workers goroutines.
+func NewTaskPool(workers int, opts ...TaskPoolOption) *TaskPool {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	p := &TaskPool{
+		workers: workers,
+		base:    time.Now(),
+		bands:   make(map[int]*TaskBandStats),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit queues @task with priority 0.
+func (p *TaskPool) Submit(task func()) error {
+	return p.SubmitPriority(0, task)
+}
+
+// SubmitPriority queues @task with priority @pri.
+func (p *TaskPool) SubmitPriority(pri int, task func()) error {
+	t := &priorityTask{priority: pri, task: task, key: float64(pri)}
+	if p.boost > 0 {
+		t.key -= float64(time.Since(p.base)) / float64(p.boost)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrTaskPoolClosed
+	}
+	p.seq++
+	t.seq = p.seq
+	heap.Push(&p.q, t)
+	band := p.band(pri)
+	band.Submitted++
+	band.Pending++
+	p.mu.Unlock()
+	p.cond.Signal()
+
+	return nil
+}
+
+// it should be called with p.mu held.
+func (p *TaskPool) band(pri int) *TaskBandStats {
+	b, ok := p.bands[pri]
+	if !ok {
+		b = &TaskBandStats{}
+		p.bands[pri] = b
+	}
+
+	return b
+}
+
+func (p *TaskPool) work() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		for len(p.q) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.q) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		t := heap.Pop(&p.q).(*priorityTask)
+		p.band(t.priority).Pending--
+		p.mu.Unlock()
+
+		panicked := p.run(t.task)
+
+		p.mu.Lock()
+		band := p.band(t.priority)
+		band.Completed++
+		if panicked {
+			band.Panicked++
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *TaskPool) run(task func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+		}
+	}()
+	task()
+
+	return false
+}
+
+// Stats returns the statistics broken down by priority.
+func (p *TaskPool) Stats() TaskPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := TaskPoolStats{
+		Workers: p.workers,
+		Pending: len(p.q),
+		Bands:   make(map[int]TaskBandStats, len(p.bands)),
+	}
+	for pri, b := range p.bands {
+		s.Bands[pri] = *b
+	}
+
+	return s
+}
+
+// Close stops accepting tasks and waits for the queued ones to finish.
+func (p *TaskPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+
+	p.wg.Wait()
+}
@@ -0,0 +1,121 @@
+package gxsync
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type taskRecord struct {
+	pri int
+	src int
+	seq int
+}
+
+func TestTaskPoolPriorityOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		records []taskRecord
+		wg      sync.WaitGroup
+	)
+
+	p := NewTaskPool(1)
+	gate, started := make(chan struct{}), make(chan struct{})
+	p.Submit(func() { close(started); <-gate })
+	<-started
+
+	// concurrent submitters, each submits tasks of every priority in order
+	const (
+		submitters = 8
+		perPri     = 50
+	)
+	for s := 0; s < submitters; s++ {
+		wg.Add(1)
+		go func(src int) {
+			defer wg.Done()
+			for i := 0; i < perPri; i++ {
+				for pri := 0; pri < 3; pri++ {
+					r := taskRecord{pri: pri, src: src, seq: i}
+					p.SubmitPriority(pri, func() {
+						mu.Lock()
+						records = append(records, r)
+						mu.Unlock()
+					})
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if stats.Bands[2].Pending != submitters*perPri {
+		t.Fatalf("band 2 stats:%+v", stats.Bands[2])
+	}
+
+	close(gate)
+	p.Close()
+
+	if len(records) != 3*submitters*perPri {
+		t.Fatalf("ran %d tasks", len(records))
+	}
+	last := make(map[[2]int]int)
+	for i, r := range records {
+		if i > 0 && records[i-1].pri < r.pri {
+			t.Fatalf("task #%d with priority %d ran after priority %d", i, r.pri, records[i-1].pri)
+		}
+		k := [2]int{r.pri, r.src}
+		if prev, ok := last[k]; ok && prev > r.seq {
+			t.Fatalf("FIFO broken for priority %d submitter %d", r.pri, r.src)
+		}
+		last[k] = r.seq
+	}
+
+	stats = p.Stats()
+	for pri := 0; pri < 3; pri++ {
+		b := stats.Bands[pri]
+		want := int64(submitters * perPri)
+		if pri == 0 {
+			want++ // the gate task
+		}
+		if b.Completed != want || b.Pending != 0 {
+			t.Fatalf("band %d stats:%+v", pri, b)
+		}
+	}
+}
+
+func TestTaskPoolBoost(t *testing.T) {
+	var order []int
+
+	p := NewTaskPool(1, WithTaskPoolBoost(5*time.Millisecond))
+	gate, started := make(chan struct{}), make(chan struct{})
+	p.Submit(func() { close(started); <-gate })
+	<-started
+
+	p.SubmitPriority(0, func() { order = append(order, 0) })
+	time.Sleep(50 * time.Millisecond) // about 10 levels of boost
+	p.SubmitPriority(2, func() { order = append(order, 2) })
+	p.SubmitPriority(100, func() { order = append(order, 100) })
+
+	close(gate)
+	p.Close()
+
+	want := []int{100, 0, 2}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestTaskPoolPanicAndClose(t *testing.T) {
+	p := NewTaskPool(2)
+	p.SubmitPriority(1, func() { panic("oops") })
+	p.Close()
+
+	if b := p.Stats().Bands[1]; b.Panicked != 1 || b.Completed != 1 {
+		t.Fatalf("band stats:%+v", b)
+	}
+	if err := p.Submit(func() {}); err != ErrTaskPoolClosed {
+		t.Fatalf("Submit() after Close = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync"
+	"time"
+)
+
+// MissedTickPolicy is what an AlignedTicker does with the ticks passed
+// while the receiver is not ready or the timer fires late.
+type MissedTickPolicy int
+
+const (
+	// SkipMissedTicks drops them like time.Ticker, the next tick is the next
+	// instant of the schedule.
+	SkipMissedTicks MissedTickPolicy = iota
+	// DeliverMissedTicks queues them, and delivers them one after another
+	// as soon as the receiver is ready.
+	DeliverMissedTicks
+)
+
+// AlignedTicker ticks at the instants offset + k * interval counted from
+// the zero time in UTC, e.g. at :00 of every minute, instead of every
+// interval from its creation like time.Ticker. Every deadline is computed
+// from the schedule, not from the previous tick, so it never drifts.
+// C receives the instants of the schedule, not the time of firing.
+type AlignedTicker struct {
+	C <-chan time.Time
+
+	c      chan time.Time
+	clock  Clock
+	offset time.Duration
+	policy MissedTickPolicy
+
+	mu         sync.Mutex
+	interval   time.Duration
+	next       time.Time // the instant of the next tick
+	timer      ClockTimer
+	gen        uint64        // increased by Stop and Reset, to ignore stale timers
+	stop       chan struct{} // nil if stopped
+	pending    []time.Time   // the ticks to deliver of DeliverMissedTicks
+	delivering bool
+}
+
+type AlignedTickerOption func(*AlignedTicker)
+
+// WithAlignedTickerClock drives the ticker with @c instead of RealClock.
+func WithAlignedTickerClock(c Clock) AlignedTickerOption {
+	return func(t *AlignedTicker) {
+		t.clock = clockOr(c)
+	}
+}
+
+// WithMissedTickPolicy sets the policy of the missed ticks, SkipMissedTicks
+// by default.
+func WithMissedTickPolicy(p MissedTickPolicy) AlignedTickerOption {
+	return func(t *AlignedTicker) {
+		t.policy = p
+	}
+}
+
+// NewAlignedTicker returns a ticker firing every @interval at the instants
+// aligned to @interval and shifted by @offset, e.g. NewAlignedTicker(time.Minute,
+// 30 * time.Second) fires at hh:mm:30. The first tick is the next instant
+// of the schedule.
+func NewAlignedTicker(interval, offset time.Duration, opts ...AlignedTickerOption) *AlignedTicker {
+	if interval <= 0 {
+		panic("non-positive interval for NewAlignedTicker")
+	}
+
+	c := make(chan time.Time, 1)
+	t := &AlignedTicker{C: c, c: c, clock: RealClock{}, offset: offset}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.mu.Lock()
+	t.start(interval)
+	t.mu.Unlock()
+
+	return t
+}
+
+// alignAfter returns the first instant of the schedule after @now.
+func alignAfter(now time.Time, interval, offset time.Duration) time.Time {
+	next := now.Add(-offset).Truncate(interval).Add(offset)
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+
+	return next
+}
+
+// start schedules the next tick, t must be locked.
+func (t *AlignedTicker) start(interval time.Duration) {
+	t.gen++
+	t.interval = interval
+	t.stop = make(chan struct{})
+	t.pending = nil
+	t.delivering = false
+
+	now := t.clock.Now()
+	t.next = alignAfter(now, interval, t.offset)
+	t.arm(now)
+}
+
+// arm sets the timer of t.next, t must be locked.
+func (t *AlignedTicker) arm(now time.Time) {
+	gen := t.gen
+	t.timer = t.clock.AfterFunc(t.next.Sub(now), func() {
+		t.fire(gen)
+	})
+}
+
+func (t *AlignedTicker) fire(gen uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if gen != t.gen {
+		return
+	}
+
+	now := t.clock.Now()
+	// the wall clock is set back
+	if now.Before(t.next) {
+		t.arm(now)
+		return
+	}
+
+	// the ticks from t.next to now are due
+	next := alignAfter(now, t.interval, t.offset)
+	switch t.policy {
+	case DeliverMissedTicks:
+		for tick := t.next; tick.Before(next); tick = tick.Add(t.interval) {
+			t.pending = append(t.pending, tick)
+		}
+		if !t.delivering {
+			t.delivering = true
+			go t.deliver(gen, t.stop)
+		}
+	default:
+		select {
+		case t.c <- next.Add(-t.interval):
+		default:
+		}
+	}
+	t.next = next
+	t.arm(now)
+}
+
+// deliver sends the pending ticks until there is none or @stop is closed.
+func (t *AlignedTicker) deliver(gen uint64, stop chan struct{}) {
+	for {
+		t.mu.Lock()
+		if gen != t.gen {
+			t.mu.Unlock()
+			return
+		}
+		if len(t.pending) == 0 {
+			t.delivering = false
+			t.mu.Unlock()
+			return
+		}
+		tick := t.pending[0]
+		t.mu.Unlock()
+
+		select {
+		case t.c <- tick:
+		case <-stop:
+			return
+		}
+
+		t.mu.Lock()
+		if gen == t.gen {
+			t.pending = t.pending[1:]
+		}
+		t.mu.Unlock()
+	}
+}
+
+// Stop turns off the ticker like time.Ticker.Stop, C is not closed.
+func (t *AlignedTicker) Stop() {
+	t.mu.Lock()
+	t.halt()
+	t.mu.Unlock()
+}
+
+// halt stops the timer and the delivery, t must be locked.
+func (t *AlignedTicker) halt() {
+	if t.stop == nil {
+		return
+	}
+
+	t.gen++
+	t.timer.Stop()
+	close(t.stop)
+	t.stop = nil
+	t.pending = nil
+	t.delivering = false
+}
+
+// Reset stops the ticker and restarts it with @interval and the same
+// offset, like time.Ticker.Reset. The ticks pending are dropped.
+func (t *AlignedTicker) Reset(interval time.Duration) {
+	if interval <= 0 {
+		panic("non-positive interval for AlignedTicker.Reset")
+	}
+
+	t.mu.Lock()
+	t.halt()
+	t.start(interval)
+	t.mu.Unlock()
+}
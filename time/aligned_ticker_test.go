@@ -0,0 +1,101 @@
+package gxtime
+
+import (
+	"testing"
+	"time"
+)
+
+func recvTick(t *testing.T, c <-chan time.Time, want string) {
+	select {
+	case tick := <-c:
+		if got := tick.Format("15:04:05"); got != want {
+			t.Fatalf("tick at %s, want %s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no tick, want %s", want)
+	}
+}
+
+func noTick(t *testing.T, c <-chan time.Time) {
+	select {
+	case tick := <-c:
+		t.Fatalf("unexpected tick at %s", tick.Format("15:04:05"))
+	default:
+	}
+}
+
+func TestAlignedTicker(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 17, 0, time.UTC))
+	ticker := NewAlignedTicker(time.Minute, 0, WithAlignedTickerClock(c))
+	defer ticker.Stop()
+
+	c.Advance(42 * time.Second)
+	noTick(t, ticker.C)
+	c.Advance(time.Second)
+	recvTick(t, ticker.C, "10:01:00")
+
+	// the ticks are not received, the later ones are skipped
+	c.Advance(3 * time.Minute)
+	recvTick(t, ticker.C, "10:02:00")
+	noTick(t, ticker.C)
+	c.Advance(time.Minute)
+	recvTick(t, ticker.C, "10:05:00")
+
+	ticker.Reset(5 * time.Minute)
+	c.Advance(4 * time.Minute)
+	noTick(t, ticker.C)
+	c.Advance(time.Minute)
+	recvTick(t, ticker.C, "10:10:00")
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	noTick(t, ticker.C)
+}
+
+func TestAlignedTickerOffset(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 17, 0, time.UTC))
+	ticker := NewAlignedTicker(time.Minute, 30*time.Second, WithAlignedTickerClock(c))
+	defer ticker.Stop()
+
+	for _, want := range []string{"10:00:30", "10:01:30", "10:02:30"} {
+		c.Advance(time.Minute)
+		recvTick(t, ticker.C, want)
+	}
+}
+
+func TestAlignedTickerDeliverMissed(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 17, 0, time.UTC))
+	ticker := NewAlignedTicker(time.Minute, 0,
+		WithAlignedTickerClock(c), WithMissedTickPolicy(DeliverMissedTicks))
+
+	c.Advance(3 * time.Minute)
+	for _, want := range []string{"10:01:00", "10:02:00", "10:03:00"} {
+		recvTick(t, ticker.C, want)
+	}
+	noTick(t, ticker.C)
+
+	c.Advance(2 * time.Minute)
+	ticker.Stop()
+	c.Advance(time.Hour)
+	// at most the tick sent before Stop
+	select {
+	case tick := <-ticker.C:
+		if got := tick.Format("15:04:05"); got != "10:04:00" {
+			t.Fatalf("tick at %s", got)
+		}
+	default:
+	}
+	noTick(t, ticker.C)
+}
+
+func TestAlignedTickerRealClock(t *testing.T) {
+	ticker := NewAlignedTicker(10*time.Millisecond, 0)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		tick := <-ticker.C
+		if !tick.Truncate(10 * time.Millisecond).Equal(tick) {
+			t.Fatalf("tick at %v is not aligned", tick)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// JitterMode is how Backoff randomizes the delays,
+// ref: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type JitterMode int
+
+const (
+	NoJitter           JitterMode = iota
+	FullJitter                    // random in [0, d]
+	EqualJitter                   // d/2 + random in [0, d/2]
+	DecorrelatedJitter            // random in [Base, 3 * the previous delay]
+)
+
+const defaultBackoffFactor = 2
+
+// Backoff computes the delays between retries, the d of the n-th delay is
+// Base * Factor^(n-1) capped by Max, randomized by Jitter.
+// It is not safe for concurrent use.
+type Backoff struct {
+	Base   time.Duration
+	Max    time.Duration // no cap if 0
+	Factor float64       // 2 if 0
+	Jitter JitterMode
+	// Rand is the random source, the global one of math/rand if nil
+	Rand *rand.Rand
+	// Clock sleeps the delays of DoRetry, RealClock if nil
+	Clock Clock
+
+	attempt int
+	prev    time.Duration
+}
+
+// Next returns the next delay.
+func (b *Backoff) Next() time.Duration {
+	b.attempt++
+
+	var d time.Duration
+	switch b.Jitter {
+	case FullJitter:
+		d = b.random(0, b.delay())
+	case EqualJitter:
+		half := b.delay() / 2
+		d = half + b.random(0, b.delay()-half)
+	case DecorrelatedJitter:
+		prev := b.prev
+		if prev < b.Base {
+			prev = b.Base
+		}
+		upper := time.Duration(math.MaxInt64)
+		if prev < upper/3 {
+			upper = 3 * prev
+		}
+		d = b.random(b.Base, b.cap(upper))
+	default:
+		d = b.delay()
+	}
+	b.prev = d
+
+	return d
+}
+
+// delay returns the delay of the current attempt without jitter.
+func (b *Backoff) delay() time.Duration {
+	factor := b.Factor
+	if factor <= 0 {
+		factor = defaultBackoffFactor
+	}
+
+	d := float64(b.Base)
+	for i := 1; i < b.attempt; i++ {
+		d *= factor
+		if b.Max > 0 && d >= float64(b.Max) {
+			return b.Max
+		}
+		if d >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+	}
+
+	return b.cap(time.Duration(d))
+}
+
+func (b *Backoff) cap(d time.Duration) time.Duration {
+	if b.Max > 0 && d > b.Max {
+		return b.Max
+	}
+
+	return d
+}
+
+// random returns a random duration in [min, max].
+func (b *Backoff) random(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	n := int64(max-min) + 1
+	if b.Rand != nil {
+		return min + time.Duration(b.Rand.Int63n(n))
+	}
+
+	return min + time.Duration(rand.Int63n(n))
+}
+
+// Reset starts over from the first attempt.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.prev = 0
+}
+
+// Attempt returns the number of delays returned since the last Reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// DoRetry calls @fn at most @attempts times until it succeeds, sleeping the
+// delays of @b between the calls. An error @isRetryable returns false for
+// stops retrying, a nil @isRetryable retries all errors. It returns the
+// last error of @fn, or the error of @ctx if it is done while sleeping.
+func (b *Backoff) DoRetry(ctx context.Context, attempts int, fn func() error, isRetryable func(error) bool) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if isRetryable != nil && !isRetryable(err) || i == attempts-1 {
+			break
+		}
+
+		if err := clockOr(b.Clock).Sleep(ctx, b.Next()); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
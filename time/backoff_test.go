@@ -0,0 +1,137 @@
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBackoffNoJitter(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 10 * time.Second}
+	want := []time.Duration{1, 2, 4, 8, 10, 10}
+	for i, w := range want {
+		if d := b.Next(); d != w*time.Second {
+			t.Fatalf("Next() #%d = %v, want %v", i, d, w*time.Second)
+		}
+	}
+	if b.Attempt() != len(want) {
+		t.Fatalf("Attempt() = %d", b.Attempt())
+	}
+
+	b.Reset()
+	if b.Attempt() != 0 || b.Next() != time.Second {
+		t.Fatal("Reset() does not start over")
+	}
+
+	// no cap
+	b = Backoff{Base: time.Second, Factor: 3}
+	for i := 0; i < 100; i++ {
+		b.Next()
+	}
+	if d := b.Next(); d <= 0 {
+		t.Fatalf("Next() overflows to %v", d)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	const (
+		samples = 10000
+		base    = 100 * time.Millisecond
+		max     = 800 * time.Millisecond
+	)
+
+	tests := []struct {
+		mode     JitterMode
+		min, max time.Duration
+		mean     time.Duration
+	}{
+		// the 4th attempt reaches the max
+		{FullJitter, 0, max, max / 2},
+		{EqualJitter, max / 2, max, max * 3 / 4},
+	}
+	for _, test := range tests {
+		var sum time.Duration
+		for i := 0; i < samples; i++ {
+			b := Backoff{Base: base, Max: max, Jitter: test.mode, Rand: rand.New(rand.NewSource(int64(i)))}
+			for j := 0; j < 3; j++ {
+				b.Next()
+			}
+			d := b.Next()
+			if d < test.min || d > test.max {
+				t.Fatalf("jitter %d: delay %v out of [%v, %v]", test.mode, d, test.min, test.max)
+			}
+			sum += d
+		}
+		mean := sum / samples
+		if diff := mean - test.mean; diff < -test.mean/20 || diff > test.mean/20 {
+			t.Fatalf("jitter %d: mean %v, want about %v", test.mode, mean, test.mean)
+		}
+	}
+
+	// decorrelated
+	b := Backoff{Base: base, Max: max, Jitter: DecorrelatedJitter, Rand: rand.New(rand.NewSource(1))}
+	var (
+		prev = base
+		sum  time.Duration
+	)
+	for i := 0; i < samples; i++ {
+		d := b.Next()
+		upper := 3 * prev
+		if upper > max {
+			upper = max
+		}
+		if d < base || d > upper {
+			t.Fatalf("decorrelated delay %v out of [%v, %v]", d, base, upper)
+		}
+		sum += d
+		prev = d
+	}
+	// the delays settle in [base, max]
+	if mean := sum / samples; mean < 2*base || mean > max-base {
+		t.Fatalf("decorrelated mean %v", mean)
+	}
+}
+
+func TestBackoffDoRetry(t *testing.T) {
+	var (
+		calls     int
+		errFlaky  = fmt.Errorf("flaky")
+		errFatal  = fmt.Errorf("fatal")
+		retryable = func(err error) bool { return err == errFlaky }
+	)
+
+	b := Backoff{Base: time.Millisecond}
+	err := b.DoRetry(context.Background(), 5, func() error {
+		calls++
+		if calls < 3 {
+			return errFlaky
+		}
+		return nil
+	}, retryable)
+	if err != nil || calls != 3 || b.Attempt() != 2 {
+		t.Fatalf("DoRetry() = %v after %d calls and %d delays", err, calls, b.Attempt())
+	}
+
+	calls = 0
+	err = b.DoRetry(context.Background(), 5, func() error { calls++; return errFatal }, retryable)
+	if err != errFatal || calls != 1 {
+		t.Fatalf("DoRetry() = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = b.DoRetry(context.Background(), 3, func() error { calls++; return errFlaky }, nil)
+	if err != errFlaky || calls != 3 {
+		t.Fatalf("DoRetry() = %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	b = Backoff{Base: time.Hour}
+	start := time.Now()
+	err = b.DoRetry(ctx, 3, func() error { return errFlaky }, nil)
+	if err != context.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Fatalf("DoRetry() = %v after %v", err, time.Since(start))
+	}
+}
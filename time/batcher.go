@@ -0,0 +1,187 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+)
+
+var (
+	ErrBatcherClosed = fmt.Errorf("batcher has been closed")
+)
+
+// Batcher collects items into batches, and flushes a batch when it has
+// the max size or its first item has waited for the max delay. The batches
+// are flushed one by one in a goroutine, in the order of Add, as are the
+// items of a batch.
+type Batcher struct {
+	maxSize     int
+	maxDelay    time.Duration
+	maxInFlight int
+	flush       func([]interface{})
+	clock       Clock
+
+	mu     sync.Mutex
+	batch  []interface{}
+	timer  ClockTimer
+	gen    uint64 // increased by every cut, to ignore stale timers
+	closed bool
+
+	sendMu sync.Mutex // keeps the order of the batches cut concurrently
+	queue  chan []interface{}
+	done   chan struct{}
+}
+
+type BatcherOption func(*Batcher)
+
+// WithBatcherClock times the max delay with @c instead of RealClock.
+func WithBatcherClock(c Clock) BatcherOption {
+	return func(b *Batcher) {
+		b.clock = clockOr(c)
+	}
+}
+
+// WithMaxInFlight sets the max batches being flushed or waiting for the
+// flush, 1 by default. Add blocks when there are so many.
+func WithMaxInFlight(n int) BatcherOption {
+	return func(b *Batcher) {
+		if n > 0 {
+			b.maxInFlight = n
+		}
+	}
+}
+
+// NewBatcher returns a batcher passing the batches of at most @maxSize
+// items to @flush, at most @maxDelay after their first items are added.
+// @flush owns the batch.
+func NewBatcher(maxSize int, maxDelay time.Duration, flush func([]interface{}), opts ...BatcherOption) *Batcher {
+	if maxSize <= 0 {
+		panic("@maxSize <= 0")
+	}
+	if maxDelay <= 0 {
+		panic("@maxDelay <= 0")
+	}
+
+	b := &Batcher{
+		maxSize:     maxSize,
+		maxDelay:    maxDelay,
+		maxInFlight: 1,
+		flush:       flush,
+		clock:       RealClock{},
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	// the one being flushed is not in the queue
+	b.queue = make(chan []interface{}, b.maxInFlight-1)
+	go b.run()
+
+	return b
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+
+	for batch := range b.queue {
+		b.call(batch)
+	}
+}
+
+func (b *Batcher) call(batch []interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			gxlog.CError("batcher flush of %d items panic: %v", len(batch), r)
+		}
+	}()
+	b.flush(batch)
+}
+
+// Add adds @item to the current batch, it blocks if the batch is full and
+// there are max in-flight batches.
+func (b *Batcher) Add(item interface{}) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+
+	b.batch = append(b.batch, item)
+	if len(b.batch) == 1 && b.maxSize > 1 {
+		gen := b.gen
+		b.timer = b.clock.AfterFunc(b.maxDelay, func() {
+			b.expire(gen)
+		})
+	}
+	if len(b.batch) < b.maxSize {
+		b.mu.Unlock()
+		return nil
+	}
+	b.send(b.cut())
+
+	return nil
+}
+
+// expire flushes the batch of @gen after the max delay.
+func (b *Batcher) expire(gen uint64) {
+	b.mu.Lock()
+	if gen != b.gen || b.closed || len(b.batch) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.send(b.cut())
+}
+
+// cut takes the current batch, and stops its timer, b must be locked.
+func (b *Batcher) cut() []interface{} {
+	batch := b.batch
+	b.batch = nil
+	b.gen++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	return batch
+}
+
+// send queues @batch and unlocks b, the batches are queued in the order
+// they are cut.
+func (b *Batcher) send(batch []interface{}) {
+	b.sendMu.Lock()
+	b.mu.Unlock()
+	b.queue <- batch
+	b.sendMu.Unlock()
+}
+
+// Close stops accepting items, and waits for the batches cut to be flushed.
+// The current batch is flushed too if @flushRemaining, or dropped.
+func (b *Batcher) Close(flushRemaining bool) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		<-b.done
+		return
+	}
+	b.closed = true
+	batch := b.cut()
+
+	b.sendMu.Lock()
+	b.mu.Unlock()
+	if flushRemaining && len(batch) > 0 {
+		b.queue <- batch
+	}
+	close(b.queue)
+	b.sendMu.Unlock()
+
+	<-b.done
+}
@@ -0,0 +1,147 @@
+package gxtime
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func recvBatch(t *testing.T, batches <-chan []interface{}, want string) {
+	select {
+	case batch := <-batches:
+		if got := fmt.Sprint(batch); got != want {
+			t.Fatalf("batch %s, want %s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no batch, want %s", want)
+	}
+}
+
+func noBatch(t *testing.T, batches <-chan []interface{}) {
+	select {
+	case batch := <-batches:
+		t.Fatalf("unexpected batch %v", batch)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	batches := make(chan []interface{}, 10)
+	b := NewBatcher(3, 10*time.Second, func(batch []interface{}) { batches <- batch }, WithBatcherClock(c))
+
+	for i := 1; i <= 7; i++ {
+		b.Add(i)
+	}
+	recvBatch(t, batches, "[1 2 3]")
+	recvBatch(t, batches, "[4 5 6]")
+	noBatch(t, batches)
+
+	c.Advance(10 * time.Second)
+	recvBatch(t, batches, "[7]")
+
+	// a size flush just before the deadline resets the timer
+	b.Add("a")
+	c.Advance(9 * time.Second)
+	b.Add("b")
+	b.Add("c")
+	recvBatch(t, batches, "[a b c]")
+	b.Add("d")
+	c.Advance(time.Second)
+	noBatch(t, batches)
+	c.Advance(8 * time.Second)
+	noBatch(t, batches)
+	c.Advance(time.Second)
+	recvBatch(t, batches, "[d]")
+
+	b.Add("e")
+	b.Close(true)
+	recvBatch(t, batches, "[e]")
+	if err := b.Add("f"); err != ErrBatcherClosed {
+		t.Fatalf("Add() after Close = %v", err)
+	}
+	b.Close(true)
+}
+
+func TestBatcherCloseDrop(t *testing.T) {
+	batches := make(chan []interface{}, 10)
+	b := NewBatcher(2, time.Hour, func(batch []interface{}) { batches <- batch })
+
+	for i := 1; i <= 3; i++ {
+		b.Add(i)
+	}
+	b.Close(false)
+	recvBatch(t, batches, "[1 2]")
+	noBatch(t, batches)
+}
+
+func TestBatcherMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	batches := make(chan []interface{}, 10)
+	b := NewBatcher(1, time.Hour, func(batch []interface{}) {
+		<-release
+		batches <- batch
+	}, WithMaxInFlight(2))
+
+	b.Add(1) // being flushed
+	b.Add(2) // waiting
+	added := make(chan struct{})
+	go func() {
+		b.Add(3)
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatalf("Add() does not block with 2 batches in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	<-added
+	close(release)
+	b.Close(true)
+	for i := 1; i <= 3; i++ {
+		recvBatch(t, batches, fmt.Sprintf("[%d]", i))
+	}
+}
+
+func TestBatcherConcurrentOrder(t *testing.T) {
+	const (
+		adders = 8
+		items  = 1000
+	)
+
+	var flushed []interface{}
+	b := NewBatcher(7, time.Millisecond, func(batch []interface{}) {
+		if len(batch) > 7 {
+			t.Errorf("batch of %d items", len(batch))
+		}
+		flushed = append(flushed, batch...)
+	}, WithMaxInFlight(3))
+
+	var wg sync.WaitGroup
+	for i := 0; i < adders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < items; j++ {
+				b.Add([2]int{i, j})
+			}
+		}(i)
+	}
+	wg.Wait()
+	b.Close(true)
+
+	if len(flushed) != adders*items {
+		t.Fatalf("%d items flushed", len(flushed))
+	}
+	next := make([]int, adders)
+	for _, item := range flushed {
+		ij := item.([2]int)
+		if ij[1] != next[ij[0]] {
+			t.Fatalf("item %v flushed, want %d of adder %d", ij, next[ij[0]], ij[0])
+		}
+		next[ij[0]]++
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/sync"
+)
+
+// Clock is the source of time, so that time dependent code can be tested
+// with a FakeClock instead of real sleeps.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc calls @f after @d, the C of the timer returned is nil.
+	AfterFunc(d time.Duration, f func()) ClockTimer
+	NewTimer(d time.Duration) ClockTimer
+	NewTicker(d time.Duration) ClockTicker
+	// Sleep returns nil after @d, or the error of @ctx if it is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// ClockTimer is the timer of a Clock, see time.Timer.
+type ClockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// ClockTicker is the ticker of a Clock, see time.Ticker. It is the
+// gxsync.Ticker, so that a Clock is a gxsync.Clock as well.
+type ClockTicker = gxsync.Ticker
+
+var _ gxsync.Clock = Clock(nil)
+
+// clockOr returns @c, or RealClock if @c is nil.
+func clockOr(c Clock) Clock {
+	if c == nil {
+		return RealClock{}
+	}
+
+	return c
+}
+
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := c.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+////////////////////////////////////////////////
+// real clock
+////////////////////////////////////////////////
+
+// RealClock is the Clock of package time.
+type RealClock struct{}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (RealClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (RealClock) NewTimer(d time.Duration) ClockTimer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (RealClock) NewTicker(d time.Duration) ClockTicker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (c RealClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, c, d)
+}
+
+////////////////////////////////////////////////
+// wheel clock
+////////////////////////////////////////////////
+
+type wheelClock struct {
+	w *Wheel
+}
+
+type wheelTicker struct {
+	t *WheelTimer
+}
+
+// NewWheelClock returns a Clock whose timers are those of @w, with the
+// precision of the tick of @w.
+func NewWheelClock(w *Wheel) Clock {
+	return wheelClock{w: w}
+}
+
+func (c wheelClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c wheelClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (c wheelClock) After(d time.Duration) <-chan time.Time {
+	return c.w.After(d)
+}
+
+func (c wheelClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return c.w.AfterFunc(d, f)
+}
+
+func (c wheelClock) NewTimer(d time.Duration) ClockTimer {
+	return c.w.NewTimer(d)
+}
+
+func (c wheelClock) NewTicker(d time.Duration) ClockTicker {
+	return wheelTicker{t: c.w.NewTicker(d)}
+}
+
+func (c wheelClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, c, d)
+}
+
+func (t wheelTicker) C() <-chan time.Time {
+	return t.t.C()
+}
+
+func (t wheelTicker) Stop() {
+	t.t.Stop()
+}
+
+func (t wheelTicker) Reset(d time.Duration) {
+	t.t.Reset(d)
+}
+
+////////////////////////////////////////////////
+// fake clock
+////////////////////////////////////////////////
+
+// FakeClock is a Clock whose time only moves by Advance. Its timers fire
+// in Advance, in the order of their deadlines, and of their creation or
+// reset for the same deadline. The functions of AfterFunc run in the
+// goroutine calling Advance.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	seq    uint64
+	timers fakeTimerHeap
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	when   time.Time
+	seq    uint64
+	period time.Duration // > 0 for tickers
+	c      chan time.Time
+	f      func()
+	index  int // in the heap, -1 if not active
+}
+
+// NewFakeClock returns a fake clock starting at @now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return c.newTimer(d, 0, nil, f)
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	return c.newTimer(d, 0, make(chan time.Time, 1), nil)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) ClockTicker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	return (*fakeTicker)(c.newTimer(d, d, make(chan time.Time, 1), nil))
+}
+
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, c, d)
+}
+
+func (c *FakeClock) newTimer(d, period time.Duration, ch chan time.Time, f func()) *fakeTimer {
+	t := &fakeTimer{clock: c, period: period, c: ch, f: f, index: -1}
+
+	c.mu.Lock()
+	c.schedule(t, c.now.Add(d))
+	c.mu.Unlock()
+
+	return t
+}
+
+// schedule activates @t at @when, c must be locked.
+func (c *FakeClock) schedule(t *fakeTimer, when time.Time) {
+	c.seq++
+	t.when, t.seq = when, c.seq
+	heap.Push(&c.timers, t)
+	c.cond.Broadcast()
+}
+
+// Advance moves the time forward by @d, and fires the timers due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].when.After(end) {
+		t := heap.Pop(&c.timers).(*fakeTimer)
+		c.now = t.when
+		if t.period > 0 {
+			c.schedule(t, t.when.Add(t.period))
+		}
+		now := c.now
+		c.mu.Unlock()
+
+		t.fire(now)
+		c.mu.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+	c.mu.Unlock()
+}
+
+// BlockUntil blocks until at least @n timers, tickers and sleepers are
+// waiting on the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+		return
+	}
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&c.timers, t.index)
+
+	return true
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := t.index >= 0
+	if active {
+		heap.Remove(&c.timers, t.index)
+	}
+	if t.period > 0 {
+		t.period = d
+	}
+	c.schedule(t, c.now.Add(d))
+
+	return active
+}
+
+type fakeTicker fakeTimer
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	(*fakeTimer)(t).Stop()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	(*fakeTimer)(t).Reset(d)
+}
+
+type fakeTimerHeap []*fakeTimer
+
+func (h fakeTimerHeap) Len() int {
+	return len(h)
+}
+
+func (h fakeTimerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+
+	return h[i].when.Before(h[j].when)
+}
+
+func (h fakeTimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *fakeTimerHeap) Push(x interface{}) {
+	t := x.(*fakeTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *fakeTimerHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+
+	return t
+}
@@ -0,0 +1,234 @@
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var fakeEpoch = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClockOrder(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+
+	var fired []string
+	add := func(name string, d time.Duration) ClockTimer {
+		return c.AfterFunc(d, func() {
+			fired = append(fired, fmt.Sprintf("%s@%v", name, c.Since(fakeEpoch)))
+		})
+	}
+	add("a", 2*time.Second)
+	add("b", time.Second)
+	add("c", 2*time.Second)
+	stopped := add("d", time.Second)
+	reset := add("e", time.Second)
+	add("f", 3*time.Second)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatalf("Stop() of an active timer should be true once")
+	}
+	// reset to the same deadline, so it fires after c
+	if !reset.Reset(2 * time.Second) {
+		t.Fatalf("Reset() of an active timer = false")
+	}
+
+	c.Advance(2 * time.Second)
+	want := "[b@1s a@2s c@2s e@2s]"
+	if got := fmt.Sprint(fired); got != want {
+		t.Fatalf("fired %s, want %s", got, want)
+	}
+	if c.Since(fakeEpoch) != 2*time.Second {
+		t.Fatalf("Now() = %v", c.Now())
+	}
+
+	c.Advance(time.Hour)
+	if len(fired) != 5 || c.Since(fakeEpoch) != time.Hour+2*time.Second {
+		t.Fatalf("fired %v at %v", fired, c.Now())
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	ticker := c.NewTicker(time.Second)
+
+	c.Advance(time.Second)
+	if now := <-ticker.C(); !now.Equal(fakeEpoch.Add(time.Second)) {
+		t.Fatalf("tick at %v", now)
+	}
+
+	// the ticks not received are dropped
+	c.Advance(5 * time.Second)
+	if now := <-ticker.C(); !now.Equal(fakeEpoch.Add(2 * time.Second)) {
+		t.Fatalf("tick at %v", now)
+	}
+	select {
+	case now := <-ticker.C():
+		t.Fatalf("unexpected tick at %v", now)
+	default:
+	}
+
+	ticker.Reset(10 * time.Second)
+	c.Advance(9 * time.Second)
+	select {
+	case now := <-ticker.C():
+		t.Fatalf("unexpected tick at %v", now)
+	default:
+	}
+	c.Advance(time.Second)
+	<-ticker.C()
+
+	ticker.Stop()
+	c.Advance(time.Minute)
+	select {
+	case now := <-ticker.C():
+		t.Fatalf("tick at %v after Stop", now)
+	default:
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = c.Sleep(context.Background(), time.Minute)
+	}()
+	go func() {
+		defer wg.Done()
+		errs[1] = c.Sleep(ctx, time.Hour)
+	}()
+
+	c.BlockUntil(2)
+	c.Advance(time.Minute)
+	cancel()
+	wg.Wait()
+	if errs[0] != nil || errs[1] != context.Canceled {
+		t.Fatalf("Sleep() = %v", errs)
+	}
+	// the canceled sleeper stops its timer
+	if n := len(c.timers); n != 0 {
+		t.Fatalf("%d timers left", n)
+	}
+
+	if err := c.Sleep(context.Background(), 0); err != nil {
+		t.Fatalf("Sleep(0) = %v", err)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	var c Clock = RealClock{}
+
+	start := c.Now()
+	if err := c.Sleep(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("Sleep() = %v", err)
+	}
+	if d := c.Since(start); d < 10*time.Millisecond {
+		t.Fatalf("Sleep() returns after %v", d)
+	}
+
+	ticker := c.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+}
+
+func TestWheelClock(t *testing.T) {
+	w := NewWheel(time.Millisecond, 16)
+	defer w.Stop()
+	c := NewWheelClock(w)
+
+	start := time.Now()
+	if err := c.Sleep(context.Background(), 20*time.Millisecond); err != nil {
+		t.Fatalf("Sleep() = %v", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("Sleep() returns after %v", d)
+	}
+
+	ticker := c.NewTicker(2 * time.Millisecond)
+	<-ticker.C()
+	<-ticker.C()
+	ticker.Stop()
+}
+
+func TestBackoffDoRetryFakeClock(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	b := Backoff{Base: time.Minute, Clock: c}
+
+	done := make(chan error)
+	go func() {
+		done <- b.DoRetry(context.Background(), 3, func() error { return fmt.Errorf("flaky") }, nil)
+	}()
+
+	for _, d := range []time.Duration{time.Minute, 2 * time.Minute} {
+		c.BlockUntil(1)
+		c.Advance(d)
+	}
+	if err := <-done; err == nil || c.Since(fakeEpoch) != 3*time.Minute {
+		t.Fatalf("DoRetry() = %v at %v", err, c.Now())
+	}
+}
+
+// ttlCache is a cache whose entries expire after a TTL of its clock.
+type ttlCache struct {
+	clock   Clock
+	ttl     time.Duration
+	entries map[string]ttlEntry
+}
+
+type ttlEntry struct {
+	value  string
+	expire time.Time
+}
+
+func (c *ttlCache) Set(key, value string) {
+	c.entries[key] = ttlEntry{value: value, expire: c.clock.Now().Add(c.ttl)}
+}
+
+func (c *ttlCache) Get(key string) (string, bool) {
+	e, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(e.expire) {
+		return "", false
+	}
+
+	return e.value, true
+}
+
+func ExampleFakeClock() {
+	clock := NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := &ttlCache{clock: clock, ttl: time.Minute, entries: map[string]ttlEntry{}}
+
+	cache.Set("session", "alex")
+	clock.Advance(59 * time.Second)
+	fmt.Println(cache.Get("session"))
+
+	// no need to wait for a minute
+	clock.Advance(time.Second)
+	fmt.Println(cache.Get("session"))
+	// Output:
+	// alex true
+	//  false
+}
+
+func ExampleFakeClock_BlockUntil() {
+	clock := NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	expired := make(chan time.Time)
+	go func() {
+		// a goroutine waiting for a TTL
+		<-clock.After(time.Hour)
+		expired <- clock.Now()
+	}()
+
+	// advance only after the goroutine is waiting, or it waits for another hour
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	fmt.Println((<-expired).Format(time.RFC3339))
+	// Output:
+	// 2018-01-01T01:00:00Z
+}
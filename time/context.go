@@ -0,0 +1,83 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	ErrWaitTimeout = fmt.Errorf("wait timeout")
+)
+
+// SleepContext sleeps @d like time.Sleep, but returns the error of @ctx
+// at once if it is done first. Use Clock.Sleep for other clocks.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, RealClock{}, d)
+}
+
+// AfterCtx returns a channel closed after @d. If @ctx is done first the
+// timer is stopped and the channel is never closed, so select it together
+// with ctx.Done(). Unlike time.After, no timer is left running whichever
+// branch of the select wins.
+func AfterCtx(ctx context.Context, d time.Duration) <-chan struct{} {
+	c := make(chan struct{})
+	if d <= 0 {
+		close(c)
+		return c
+	}
+
+	timer := time.AfterFunc(d, func() { close(c) })
+	if ctx.Done() == nil {
+		return c
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-c:
+		}
+	}()
+
+	return c
+}
+
+// WaitChan receives from the channel @ch of any element type, or returns
+// ErrWaitTimeout after @timeout, or the error of @ctx if it is done first.
+// @timeout <= 0 means waiting without a timeout. @ok is false if @ch is
+// closed. It panics if @ch is not a channel to receive from.
+func WaitChan(ctx context.Context, ch interface{}, timeout time.Duration) (v interface{}, ok bool, err error) {
+	chv := reflect.ValueOf(ch)
+	if chv.Kind() != reflect.Chan || chv.Type().ChanDir()&reflect.RecvDir == 0 {
+		panic(fmt.Sprintf("WaitChan of %T", ch))
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: chv},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+	}
+
+	chosen, recv, recvOK := reflect.Select(cases)
+	switch chosen {
+	case 0:
+		if !recvOK {
+			return nil, false, nil
+		}
+		return recv.Interface(), true, nil
+	case 1:
+		return nil, false, ctx.Err()
+	default:
+		return nil, false, ErrWaitTimeout
+	}
+}
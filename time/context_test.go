@@ -0,0 +1,100 @@
+package gxtime
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkGoroutines fails if the goroutines do not go back to @n soon.
+func checkGoroutines(t *testing.T, n int) {
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d goroutines leaked", runtime.NumGoroutine()-n)
+}
+
+func TestSleepContext(t *testing.T) {
+	start := time.Now()
+	if err := SleepContext(context.Background(), 10*time.Millisecond); err != nil || time.Since(start) < 10*time.Millisecond {
+		t.Fatalf("SleepContext() = %v after %v", err, time.Since(start))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := SleepContext(ctx, time.Hour); err != context.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Fatalf("SleepContext() = %v after %v", err, time.Since(start))
+	}
+
+	if err := SleepContext(ctx, 0); err != context.DeadlineExceeded {
+		t.Fatalf("SleepContext(0) = %v", err)
+	}
+}
+
+func TestAfterCtx(t *testing.T) {
+	n := runtime.NumGoroutine()
+
+	select {
+	case <-AfterCtx(context.Background(), 10*time.Millisecond):
+	case <-time.After(time.Second):
+		t.Fatalf("AfterCtx() does not fire")
+	}
+
+	// the timer is stopped and the goroutine exits
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 100; i++ {
+		AfterCtx(ctx, time.Hour)
+	}
+	cancel()
+	checkGoroutines(t, n)
+
+	// the goroutine exits when the timer fires
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	<-AfterCtx(ctx, time.Millisecond)
+	checkGoroutines(t, n)
+
+	select {
+	case <-AfterCtx(ctx, 0):
+	default:
+		t.Fatalf("AfterCtx(0) is not closed")
+	}
+}
+
+func TestWaitChan(t *testing.T) {
+	n := runtime.NumGoroutine()
+
+	ch := make(chan int, 1)
+	ch <- 1
+	if v, ok, err := WaitChan(context.Background(), ch, time.Hour); v != 1 || !ok || err != nil {
+		t.Fatalf("WaitChan() = %v, %v, %v", v, ok, err)
+	}
+
+	if _, _, err := WaitChan(context.Background(), (<-chan int)(ch), 10*time.Millisecond); err != ErrWaitTimeout {
+		t.Fatalf("WaitChan() = %v, want timeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := WaitChan(ctx, ch, 0); err != context.Canceled {
+		t.Fatalf("WaitChan() = %v, want canceled", err)
+	}
+
+	close(ch)
+	if v, ok, err := WaitChan(context.Background(), ch, 0); v != nil || ok || err != nil {
+		t.Fatalf("WaitChan() of a closed channel = %v, %v, %v", v, ok, err)
+	}
+	checkGoroutines(t, n)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("WaitChan() of a send-only channel does not panic")
+		}
+	}()
+	WaitChan(context.Background(), (chan<- int)(ch), 0)
+}
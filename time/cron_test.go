@@ -0,0 +1,189 @@
+package gxtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	const layout = "2006-01-02 15:04 Mon"
+	tests := []struct {
+		spec, from, next string
+	}{
+		{"* * * * *", "2018-01-01 10:00 Mon", "2018-01-01 10:01 Mon"},
+		{"*/15 * * * *", "2018-01-01 10:07 Mon", "2018-01-01 10:15 Mon"},
+		{"5/20 * * * *", "2018-01-01 10:26 Mon", "2018-01-01 10:45 Mon"},
+		{"0 9-17/4 * * *", "2018-01-01 13:00 Mon", "2018-01-01 17:00 Mon"},
+		{"30 2 * * *", "2018-01-01 10:00 Mon", "2018-01-02 02:30 Tue"},
+		{"0 0 1,15 * *", "2018-01-02 00:00 Tue", "2018-01-15 00:00 Mon"},
+		{"0 0 * * mon-fri", "2018-01-05 12:00 Fri", "2018-01-08 00:00 Mon"},
+		{"0 0 * * 7", "2018-01-01 00:00 Mon", "2018-01-07 00:00 Sun"},
+		{"0 0 * feb *", "2018-03-01 00:00 Thu", "2019-02-01 00:00 Fri"},
+		{"0 0 29 2 *", "2018-01-01 00:00 Mon", "2020-02-29 00:00 Sat"},
+		// either day-of-month or day-of-week
+		{"0 0 13 * fri", "2018-01-01 00:00 Mon", "2018-01-05 00:00 Fri"},
+		// a field starting with * restricts nothing, so both like cron
+		{"0 0 */10 * fri", "2018-01-05 00:00 Fri", "2018-05-11 00:00 Fri"},
+		{"@daily", "2018-01-01 10:00 Mon", "2018-01-02 00:00 Tue"},
+		{"@weekly", "2018-01-01 10:00 Mon", "2018-01-07 00:00 Sun"},
+		{"@yearly", "2018-01-01 10:00 Mon", "2019-01-01 00:00 Tue"},
+		{"@every 90m", "2018-01-01 10:00 Mon", "2018-01-01 11:30 Mon"},
+		{"0 0 30 2 *", "2018-01-01 00:00 Mon", ""},
+	}
+	for _, test := range tests {
+		sched, err := ParseCron(test.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) = %v", test.spec, err)
+		}
+		from, _ := time.Parse(layout, test.from)
+		next := sched.Next(from.Add(30 * time.Second))
+		if test.next == "" {
+			next = sched.Next(from)
+		}
+		got := ""
+		if !next.IsZero() {
+			got = next.Format(layout)
+		}
+		if got != test.next {
+			t.Fatalf("%q.Next(%s) = %q, want %q", test.spec, test.from, got, test.next)
+		}
+	}
+}
+
+func TestCronNextZone(t *testing.T) {
+	sched, _ := ParseCron("0 * * * *")
+	ist := time.FixedZone("IST", 5*3600+1800)
+	next := sched.Next(time.Date(2018, 1, 1, 10, 10, 0, 0, ist))
+	if want := time.Date(2018, 1, 1, 11, 0, 0, 0, ist); !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next, want)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+		"@every 0s",
+		"@every 1y",
+		"@often",
+		"1,,2 * * * *",
+		"* * * jan-foo *",
+		"* * * * sun/x",
+		"*/5-10 * * * *",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Fatalf("ParseCron(%q) succeeds", spec)
+		}
+	}
+}
+
+func TestCronNames(t *testing.T) {
+	for _, c := range []struct{ a, b string }{
+		{"0 0 * JAN-MAR MON", "0 0 * 1-3 1"},
+		{"0 0 * Jul sat,SUN", "0 0 * 7 6,0"},
+		{"0 0 * dec/2 *", "0 0 * 12 *"},
+	} {
+		a, errA := ParseCron(c.a)
+		b, errB := ParseCron(c.b)
+		if errA != nil || errB != nil || !reflect.DeepEqual(a, b) {
+			t.Fatalf("ParseCron(%q) = %+v, %v, ParseCron(%q) = %+v, %v", c.a, a, errA, c.b, b, errB)
+		}
+	}
+}
+
+func TestCronNextStrictlyAfter(t *testing.T) {
+	from := time.Date(2018, 1, 31, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 0 * * *", time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// the months without the 31st are skipped
+		{"0 0 31 * *", time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 1ms", from.Add(time.Millisecond)},
+	} {
+		sched, _ := ParseCron(c.spec)
+		if next := sched.Next(from); !next.Equal(c.next) {
+			t.Fatalf("%q.Next(%v) = %v, want %v", c.spec, from, next, c.next)
+		}
+	}
+
+	// month-end rollovers from every day of a leap year
+	sched, _ := ParseCron("0 12 28-31 * *")
+	for day := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC); day.Year() == 2020; day = day.AddDate(0, 0, 1) {
+		next := sched.Next(day)
+		if !next.After(day) || next.Day() < 28 || next.Hour() != 12 || next.Sub(day) > 31*24*time.Hour {
+			t.Fatalf("Next(%v) = %v", day, next)
+		}
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2018, month, day, hour, min, 0, 0, loc)
+	}
+	// 2018-03-11 02:00 EST jumps to 03:00 EDT, 2018-11-04 02:00 EDT falls
+	// back to 01:00 EST.
+	edt := at(11, 4, 1, 30)
+	est := edt.Add(time.Hour)
+	tests := []struct {
+		spec       string
+		from, next time.Time
+	}{
+		// the skipped 02:30 does not run
+		{"30 2 * * *", at(3, 10, 3, 0), at(3, 12, 2, 30)},
+		{"0 * * * *", at(3, 11, 1, 30), at(3, 11, 3, 0)},
+		{"*/30 * * * *", at(3, 11, 1, 45), at(3, 11, 3, 0)},
+		// the repeated 01:30 runs once
+		{"30 1 * * *", at(11, 3, 12, 0), edt},
+		{"30 1 * * *", edt, at(11, 5, 1, 30)},
+		{"*/30 * * * *", edt, at(11, 4, 2, 0)},
+		{"0 2 * * *", edt, at(11, 4, 2, 0)},
+		{"0 2 * * *", est, at(11, 4, 2, 0)},
+		// @every is of the elapsed time
+		{"@every 1h", edt, est},
+	}
+	for _, test := range tests {
+		sched, err := ParseCron(test.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) = %v", test.spec, err)
+		}
+		if next := sched.Next(test.from); !next.Equal(test.next) {
+			t.Fatalf("%q.Next(%v) = %v, want %v", test.spec, test.from, next, test.next)
+		}
+	}
+}
+
+func TestCronNextDSTMidnight(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2018-11-04 00:00 jumps to 01:00
+	sched, _ := ParseCron("0 0 * * *")
+	from := time.Date(2018, 11, 3, 12, 0, 0, 0, loc)
+	if next, want := sched.Next(from), time.Date(2018, 11, 5, 0, 0, 0, 0, loc); !next.Equal(want) {
+		t.Fatalf("Next(%v) = %v, want %v", from, next, want)
+	}
+	sched, _ = ParseCron("30 * 4 11 *")
+	if next, want := sched.Next(from), time.Date(2018, 11, 4, 1, 30, 0, 0, loc); !next.Equal(want) {
+		t.Fatalf("Next(%v) = %v, want %v", from, next, want)
+	}
+}
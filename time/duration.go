@@ -0,0 +1,255 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // U+00B5 micro sign
+	"μs": time.Microsecond, // U+03BC greek mu
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  Day,
+	"w":  Week,
+}
+
+// the units whose length varies, or which read as both minutes and months
+var ambiguousUnits = map[string]bool{
+	"M": true, "mo": true, "y": true, "Y": true, "D": true, "W": true,
+}
+
+// floatDuration returns @v of @unit, saturated at the bounds of time.Duration.
+func floatDuration(v float64, unit time.Duration) time.Duration {
+	f := v * float64(unit)
+	switch {
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	}
+
+	return time.Duration(f)
+}
+
+// ParseDuration parses the syntax of time.ParseDuration with the units "d"
+// of 24 hours and "w" of 7 days, e.g. "1w2d", "1.5d" or "-3d12h". The
+// lengths of a day and a week are fixed, so months and years are rejected
+// as ambiguous, as well as numbers without units except "0".
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+
+	var d uint64
+	for s != "" {
+		// the number
+		i := 0
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		intPart := s[:i]
+		s = s[i:]
+		fracPart := ""
+		if s != "" && s[0] == '.' {
+			s = s[1:]
+			i = 0
+			for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+				i++
+			}
+			fracPart = s[:i]
+			s = s[i:]
+		}
+		if intPart == "" && fracPart == "" {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+
+		// the unit
+		i = 0
+		for i < len(s) && s[i] != '.' && (s[i] < '0' || '9' < s[i]) {
+			i++
+		}
+		u := s[:i]
+		s = s[i:]
+		unit, ok := durationUnits[u]
+		if !ok {
+			if u == "" {
+				return 0, fmt.Errorf("missing unit in duration %q", orig)
+			}
+			if ambiguousUnits[u] {
+				return 0, fmt.Errorf("ambiguous unit %q in duration %q", u, orig)
+			}
+			return 0, fmt.Errorf("unknown unit %q in duration %q", u, orig)
+		}
+
+		v, ok := unitValue(intPart, fracPart, unit)
+		if !ok || d+v < d || d+v > 1<<63 {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		d += v
+	}
+
+	if neg {
+		return -time.Duration(d), nil
+	}
+	if d > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+
+	return time.Duration(d), nil
+}
+
+// unitValue returns the nanoseconds of @intPart.@fracPart @unit, false if
+// it overflows.
+func unitValue(intPart, fracPart string, unit time.Duration) (uint64, bool) {
+	var v uint64
+	if intPart != "" {
+		n, err := strconv.ParseUint(intPart, 10, 64)
+		if err != nil || n > (1<<63)/uint64(unit) {
+			return 0, false
+		}
+		v = n * uint64(unit)
+	}
+
+	// the fraction, exact to the nanosecond like time.ParseDuration
+	var f, scale uint64 = 0, 1
+	for _, c := range fracPart {
+		if scale > math.MaxUint64/10/uint64(unit) {
+			break // beyond the precision
+		}
+		f = f*10 + uint64(c-'0')
+		scale *= 10
+	}
+	v += f * uint64(unit) / scale
+
+	return v, v <= 1<<63
+}
+
+// MustParseDuration is ParseDuration for the configurations, it panics on
+// errors.
+func MustParseDuration(s string) time.Duration {
+	d, err := ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// FormatDuration formats @d rounded to @precision like "2d3h04m", the
+// smallest unit shown is the largest of d, h, m, s, ms, us and ns not
+// greater than @precision. The seconds below a second are shown as the
+// fraction of the seconds, e.g. "1m05.250s" of the precision ms.
+func FormatDuration(d time.Duration, precision time.Duration) string {
+	if precision <= 0 {
+		precision = time.Nanosecond
+	}
+	d = d.Round(precision)
+
+	var sign string
+	// -math.MinInt64 overflows
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = -u
+	}
+
+	days, u := u/uint64(Day), u%uint64(Day)
+	hours, u := u/uint64(time.Hour), u%uint64(time.Hour)
+	minutes, u := u/uint64(time.Minute), u%uint64(time.Minute)
+	seconds, nanos := u/uint64(time.Second), u%uint64(time.Second)
+
+	var b strings.Builder
+	b.WriteString(sign)
+	started := false
+	write := func(v uint64, unit string) {
+		switch {
+		case started:
+			fmt.Fprintf(&b, "%02d%s", v, unit)
+		case v > 0:
+			fmt.Fprintf(&b, "%d%s", v, unit)
+			started = true
+		}
+	}
+
+	if precision >= Day {
+		write(days, "d")
+		return zeroOr(&b, started, "d")
+	}
+	if days > 0 {
+		fmt.Fprintf(&b, "%dd", days)
+		started = true
+	}
+	if started {
+		// hours are not padded after days, "2d3h"
+		fmt.Fprintf(&b, "%dh", hours)
+	} else {
+		write(hours, "h")
+	}
+	if precision >= time.Hour {
+		return zeroOr(&b, started, "h")
+	}
+	write(minutes, "m")
+	if precision >= time.Minute {
+		return zeroOr(&b, started, "m")
+	}
+
+	digits := 0
+	switch {
+	case precision >= time.Second:
+	case precision >= time.Millisecond:
+		digits = 3
+	case precision >= time.Microsecond:
+		digits = 6
+	default:
+		digits = 9
+	}
+	if digits == 0 {
+		write(seconds, "s")
+		return zeroOr(&b, started, "s")
+	}
+
+	frac := fmt.Sprintf("%09d", nanos)[:digits]
+	if started {
+		fmt.Fprintf(&b, "%02d.%ss", seconds, frac)
+	} else {
+		fmt.Fprintf(&b, "%d.%ss", seconds, frac)
+	}
+
+	return b.String()
+}
+
+// zeroOr returns the string of @b, or "0" of @unit if nothing is written.
+func zeroOr(b *strings.Builder, started bool, unit string) string {
+	if !started {
+		return "0" + unit
+	}
+
+	return b.String()
+}
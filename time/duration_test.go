@@ -0,0 +1,134 @@
+package gxtime
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s string
+		d time.Duration
+	}{
+		{"0", 0},
+		{"-0", 0},
+		{"1d", Day},
+		{"1w2d", Week + 2*Day},
+		{"1.5d", 36 * time.Hour},
+		{".5w", 84 * time.Hour},
+		{"-3d12h", -(3*Day + 12*time.Hour)},
+		{"+2d", 2 * Day},
+		{"1h30m", 90 * time.Minute},
+		{"1.000000001s", time.Second + time.Nanosecond},
+		{"300ms", 300 * time.Millisecond},
+		{"2us", 2 * time.Microsecond},
+		{"2µs", 2 * time.Microsecond},
+		{"2μs", 2 * time.Microsecond},
+		{"9223372036854775807ns", math.MaxInt64},
+		{"-9223372036854775808ns", math.MinInt64},
+		{"15250w", 15250 * Week},
+	}
+	for _, test := range tests {
+		d, err := ParseDuration(test.s)
+		if err != nil || d != test.d {
+			t.Fatalf("ParseDuration(%q) = %v, %v, want %v", test.s, d, err, test.d)
+		}
+		// the stdlib syntax is kept
+		if !strings.ContainsAny(test.s, "dw") {
+			if std, err := time.ParseDuration(test.s); err != nil || std != d {
+				t.Fatalf("time.ParseDuration(%q) = %v, %v, ParseDuration = %v", test.s, std, err, d)
+			}
+		}
+	}
+}
+
+func TestParseDurationErrors(t *testing.T) {
+	tests := []struct {
+		s   string
+		err string
+	}{
+		{"", "invalid"},
+		{"-", "invalid"},
+		{".", "invalid"},
+		{"d", "invalid"},
+		{"10", "missing unit"},
+		{"1d2", "missing unit"},
+		{"1.5.d", "missing unit"},
+		{"1 d", "unknown unit"},
+		{"1h-2m", "unknown unit"},
+		{"1dd", "unknown unit"},
+		{"3M", "ambiguous"},
+		{"1mo", "ambiguous"},
+		{"1y2d", "ambiguous"},
+		{"1D", "ambiguous"},
+		{"15251w", "invalid"},
+		{"9223372036854775808ns", "invalid"},
+		{"99999999999999999999s", "invalid"},
+	}
+	for _, test := range tests {
+		d, err := ParseDuration(test.s)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("ParseDuration(%q) = %v, %v, want error %q", test.s, d, err, test.err)
+		}
+	}
+}
+
+func TestMustParseDuration(t *testing.T) {
+	if d := MustParseDuration("1w"); d != Week {
+		t.Fatalf("MustParseDuration(1w) = %v", d)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("MustParseDuration(1y) does not panic")
+		}
+	}()
+	MustParseDuration("1y")
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d         time.Duration
+		precision time.Duration
+		s         string
+	}{
+		{2*Day + 3*time.Hour + 4*time.Minute + 5*time.Second, time.Minute, "2d3h04m"},
+		{2*Day + 3*time.Hour + 4*time.Minute + 35*time.Second, time.Minute, "2d3h05m"},
+		{2*Day + 4*time.Minute, time.Second, "2d0h04m00s"},
+		{3*time.Hour + 4*time.Minute, time.Minute, "3h04m"},
+		{4*time.Minute + 5*time.Second, time.Second, "4m05s"},
+		{65*time.Second + 250*time.Millisecond, time.Millisecond, "1m05.250s"},
+		{5 * time.Millisecond, time.Millisecond, "0.005s"},
+		{1500 * time.Nanosecond, time.Microsecond, "0.000002s"},
+		{1500 * time.Nanosecond, 0, "0.000001500s"},
+		{-(Day + 2*time.Hour), time.Hour, "-1d2h"},
+		{-30 * time.Second, time.Minute, "-1m"},
+		{29 * time.Second, time.Minute, "0m"},
+		{0, time.Second, "0s"},
+		{36 * time.Hour, Day, "2d"},
+		{time.Hour, Day, "0d"},
+		{math.MinInt64, time.Hour, "-106751d23h"},
+	}
+	for _, test := range tests {
+		if s := FormatDuration(test.d, test.precision); s != test.s {
+			t.Fatalf("FormatDuration(%v, %v) = %q, want %q", test.d, test.precision, s, test.s)
+		}
+	}
+}
+
+func TestTimeUnitDuration(t *testing.T) {
+	if d := TimeDayDuratioin(1.5); d != 36*time.Hour {
+		t.Fatalf("TimeDayDuratioin(1.5) = %v", d)
+	}
+	if d := TimeSecondDuration(0.25); d != 250*time.Millisecond {
+		t.Fatalf("TimeSecondDuration(0.25) = %v", d)
+	}
+	if d := TimeDayDuratioin(1e9); d != math.MaxInt64 {
+		t.Fatalf("TimeDayDuratioin(1e9) = %v, want saturated", d)
+	}
+	if d := TimeDayDuratioin(-1e9); d != math.MinInt64 {
+		t.Fatalf("TimeDayDuratioin(-1e9) = %v, want saturated", d)
+	}
+}
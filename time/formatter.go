@@ -0,0 +1,70 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CachedFormatter formats the current time with a layout, re-rendering it
+// at most once per resolution instead of on every call.
+type CachedFormatter struct {
+	layout     string
+	resolution time.Duration
+	clock      Clock
+	cache      atomic.Value // *formatted
+}
+
+// formatted is the time of a slot of the resolution rendered.
+type formatted struct {
+	from, until time.Time
+	s           string
+}
+
+var nowFormatter = NewCachedFormatter(time.RFC3339, time.Second, nil)
+
+// NewCachedFormatter returns a formatter of @layout refreshed every
+// @resolution by @clock, RealClock if nil. The time rendered is the current
+// one truncated to @resolution, so a @layout more precise than @resolution
+// shows zeros.
+func NewCachedFormatter(layout string, resolution time.Duration, clock Clock) *CachedFormatter {
+	if resolution <= 0 {
+		resolution = time.Nanosecond
+	}
+
+	f := &CachedFormatter{
+		layout:     layout,
+		resolution: resolution,
+		clock:      clockOr(clock),
+	}
+	f.cache.Store(&formatted{})
+
+	return f
+}
+
+// Format returns the current time rendered, which is never older than the
+// resolution. It is refreshed without locks by the first call after it is
+// stale, the concurrent callers may render it more than once.
+func (f *CachedFormatter) Format() string {
+	now := f.clock.Now()
+	c := f.cache.Load().(*formatted)
+	// the clock may be set back
+	if !now.Before(c.from) && now.Before(c.until) {
+		return c.s
+	}
+
+	from := now.Truncate(f.resolution)
+	c = &formatted{from: from, until: from.Add(f.resolution), s: from.Format(f.layout)}
+	f.cache.Store(c)
+
+	return c.s
+}
+
+// NowString returns the local time in RFC3339 of the second resolution.
+func NowString() string {
+	return nowFormatter.Format()
+}
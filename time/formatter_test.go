@@ -0,0 +1,97 @@
+package gxtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedFormatter(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 900*int(time.Millisecond), time.UTC))
+	f := NewCachedFormatter("15:04:05.000", 100*time.Millisecond, c)
+
+	tests := []struct {
+		advance time.Duration
+		s       string
+	}{
+		{0, "10:00:00.900"},
+		{99 * time.Millisecond, "10:00:00.900"},
+		// the refresh boundary
+		{time.Millisecond, "10:00:01.000"},
+		{50 * time.Millisecond, "10:00:01.000"},
+		{149 * time.Millisecond, "10:00:01.100"},
+		{time.Hour, "11:00:01.100"},
+	}
+	for _, test := range tests {
+		c.Advance(test.advance)
+		if s := f.Format(); s != test.s {
+			t.Fatalf("Format() at %v = %s, want %s", c.Now().Format("15:04:05.000"), s, test.s)
+		}
+	}
+
+	// the clock set back
+	c = NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+	f = NewCachedFormatter("15:04:05", time.Second, c)
+	f.Format()
+	f.clock = NewFakeClock(time.Date(2018, 1, 1, 9, 0, 0, 0, time.UTC))
+	if s := f.Format(); s != "09:00:00" {
+		t.Fatalf("Format() after the clock is set back = %s", s)
+	}
+}
+
+func TestCachedFormatterRealClock(t *testing.T) {
+	const resolution = 10 * time.Millisecond
+	layout := "2006-01-02 15:04:05.000000"
+	f := NewCachedFormatter(layout, resolution, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deadline := time.Now().Add(50 * time.Millisecond)
+			for time.Now().Before(deadline) {
+				before := time.Now()
+				s := f.Format()
+				after := time.Now()
+				rendered, err := time.ParseInLocation(layout, s, time.Local)
+				if err != nil {
+					t.Errorf("Format() = %s: %v", s, err)
+					return
+				}
+				// never older than the resolution
+				if rendered.After(after) || !rendered.After(before.Add(-resolution)) {
+					t.Errorf("Format() = %s between %s and %s", s, before.Format(layout), after.Format(layout))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNowString(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, NowString())
+	if err != nil || time.Since(now) > 2*time.Second {
+		t.Fatalf("NowString() = %v, %v", now, err)
+	}
+}
+
+func BenchmarkCachedFormatter(b *testing.B) {
+	f := NewCachedFormatter(time.RFC3339, time.Second, nil)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			f.Format()
+		}
+	})
+}
+
+func BenchmarkTimeFormat(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			time.Now().Format(time.RFC3339)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+)
+
+type heartbeat struct {
+	key        interface{}
+	prev, next *heartbeat
+	rounds     int  // full turns of the wheel to wait
+	expiring   bool // expired, waiting for the callback
+}
+
+func (h *heartbeat) unlink() {
+	h.prev.next = h.next
+	h.next.prev = h.prev
+	h.prev, h.next = nil, nil
+}
+
+// HeartbeatTracker calls back when a key has not been touched within its
+// ttl. The keys are kept in a hashed timing wheel like Wheel, so a touch
+// costs O(1) and all keys share one timer of the clock.
+//
+// The precision is the tick of the tracker: a key expires at least ttl,
+// and at most ttl plus a tick, after its last touch. A touch before the
+// callback of an expired key is called cancels the expiry.
+type HeartbeatTracker struct {
+	span     time.Duration
+	onExpire func(key interface{})
+	clock    Clock
+
+	mu      sync.Mutex
+	ring    []*heartbeat // dummy heads of circular lists
+	index   int
+	last    time.Time // time of the last tick
+	keys    map[interface{}]*heartbeat
+	timer   ClockTimer
+	stopped bool
+}
+
+// NewHeartbeatTracker returns a tracker of @buckets slots ticking every
+// @span of @clock, RealClock if nil. @onExpire is called with the keys
+// expired one by one, in a goroutine of the clock.
+func NewHeartbeatTracker(span time.Duration, buckets int, onExpire func(key interface{}), clock Clock) *HeartbeatTracker {
+	if span <= 0 {
+		panic("@span <= 0")
+	}
+	if buckets <= 0 {
+		panic("@buckets <= 0")
+	}
+
+	clock = clockOr(clock)
+	t := &HeartbeatTracker{
+		span:     span,
+		onExpire: onExpire,
+		clock:    clock,
+		ring:     make([]*heartbeat, buckets),
+		last:     clock.Now(),
+		keys:     make(map[interface{}]*heartbeat),
+	}
+	for i := range t.ring {
+		head := &heartbeat{}
+		head.prev, head.next = head, head
+		t.ring[i] = head
+	}
+	t.timer = clock.AfterFunc(span, t.tick)
+
+	return t
+}
+
+// Touch refreshes @key, which expires if it is not touched again within
+// @ttl.
+func (t *HeartbeatTracker) Touch(key interface{}, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.keys[key]
+	if !ok {
+		h = &heartbeat{key: key}
+		t.keys[key] = h
+	} else if h.next != nil {
+		h.unlink()
+	}
+	h.expiring = false
+	t.add(h, ttl)
+}
+
+// Remove stops tracking @key, and returns whether it was tracked.
+func (t *HeartbeatTracker) Remove(key interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.keys[key]
+	if !ok {
+		return false
+	}
+	delete(t.keys, key)
+	if h.next != nil {
+		h.unlink()
+	}
+	h.expiring = false
+
+	return true
+}
+
+// Len returns the number of the keys tracked.
+func (t *HeartbeatTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.keys)
+}
+
+// Stop stops the tracker, no key expires after it returns, except the one
+// being called back.
+func (t *HeartbeatTracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = true
+	t.timer.Stop()
+}
+
+// add links @h into the slot @ttl later, t must be locked.
+func (t *HeartbeatTracker) add(h *heartbeat, ttl time.Duration) {
+	// counted from the last tick, so that h never expires early
+	ticks := int((ttl + t.clock.Since(t.last) + t.span - 1) / t.span)
+	if ticks < 1 {
+		ticks = 1
+	}
+	h.rounds = (ticks - 1) / len(t.ring)
+
+	head := t.ring[(t.index+ticks)%len(t.ring)]
+	h.prev, h.next = head.prev, head
+	head.prev.next = h
+	head.prev = h
+}
+
+// tick turns the slots passed since the last tick, and calls back the keys
+// expired.
+func (t *HeartbeatTracker) tick() {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	var expired []*heartbeat
+	steps := int(now.Sub(t.last) / t.span)
+	for i := 0; i < steps; i++ {
+		t.index = (t.index + 1) % len(t.ring)
+		head := t.ring[t.index]
+		for h := head.next; h != head; {
+			next := h.next
+			if h.rounds > 0 {
+				h.rounds--
+			} else {
+				h.unlink()
+				h.expiring = true
+				expired = append(expired, h)
+			}
+			h = next
+		}
+	}
+	t.last = t.last.Add(time.Duration(steps) * t.span)
+	t.mu.Unlock()
+
+	for _, h := range expired {
+		// a touch after the tick wins
+		t.mu.Lock()
+		stale := h.expiring && !t.stopped && t.keys[h.key] == h
+		if stale {
+			delete(t.keys, h.key)
+			h.expiring = false
+		}
+		t.mu.Unlock()
+
+		if stale {
+			t.call(h.key)
+		}
+	}
+
+	t.mu.Lock()
+	if !t.stopped {
+		t.timer = t.clock.AfterFunc(t.last.Add(t.span).Sub(t.clock.Now()), t.tick)
+	}
+	t.mu.Unlock()
+}
+
+func (t *HeartbeatTracker) call(key interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			gxlog.CError("heartbeat tracker callback of key %v panic: %v", key, r)
+		}
+	}()
+	t.onExpire(key)
+}
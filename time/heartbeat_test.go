@@ -0,0 +1,143 @@
+package gxtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatTracker(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var expired []interface{}
+	tr := NewHeartbeatTracker(100*time.Millisecond, 8, func(key interface{}) {
+		expired = append(expired, key)
+	}, c)
+	defer tr.Stop()
+
+	tr.Touch("a", time.Second)
+	tr.Touch("b", time.Second)
+	tr.Touch("c", time.Second)
+	c.Advance(500 * time.Millisecond)
+	tr.Touch("a", time.Second) // rescheduled
+	if !tr.Remove("c") || tr.Remove("c") {
+		t.Fatalf("Remove() of a key twice")
+	}
+
+	c.Advance(500 * time.Millisecond)
+	if len(expired) != 1 || expired[0] != "b" {
+		t.Fatalf("expired %v after a second", expired)
+	}
+	c.Advance(400 * time.Millisecond)
+	if len(expired) != 1 {
+		t.Fatalf("expired %v before the ttl of the retouch", expired)
+	}
+	c.Advance(200 * time.Millisecond)
+	if len(expired) != 2 || expired[1] != "a" || tr.Len() != 0 {
+		t.Fatalf("expired %v, %d keys left", expired, tr.Len())
+	}
+}
+
+// TestHeartbeatTrackerTouchWins touches a key expiring in the same tick in
+// the callback of another one.
+func TestHeartbeatTrackerTouchWins(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	var (
+		tr      *HeartbeatTracker
+		expired []interface{}
+	)
+	tr = NewHeartbeatTracker(time.Second, 4, func(key interface{}) {
+		expired = append(expired, key)
+		if key == "a" {
+			tr.Touch("b", time.Second)
+		} else {
+			tr.Touch("a", time.Second)
+		}
+	}, c)
+	defer tr.Stop()
+
+	tr.Touch("a", time.Second)
+	tr.Touch("b", time.Second)
+	c.Advance(time.Second)
+	if len(expired) != 1 || expired[0] != "a" || tr.Len() != 1 {
+		t.Fatalf("expired %v, %d keys left", expired, tr.Len())
+	}
+}
+
+func TestHeartbeatTracker100k(t *testing.T) {
+	const (
+		keys = 100000
+		span = 10 * time.Millisecond
+	)
+	c := NewFakeClock(fakeEpoch)
+	touched := make([]time.Time, keys)
+	ttls := make([]time.Duration, keys)
+	expired := 0
+	tr := NewHeartbeatTracker(span, 64, func(key interface{}) {
+		i := key.(int)
+		if late := c.Since(touched[i]) - ttls[i]; late < 0 || late > span {
+			t.Fatalf("key %d of ttl %v expired %v late", i, ttls[i], late)
+		}
+		expired++
+	}, c)
+	defer tr.Stop()
+
+	touch := func(i int) {
+		touched[i] = c.Now()
+		ttls[i] = time.Duration(i%1000+1) * time.Millisecond
+		tr.Touch(i, ttls[i])
+	}
+	for i := 0; i < keys; i++ {
+		touch(i)
+		if i%1000 == 0 {
+			c.Advance(time.Millisecond)
+		}
+	}
+	// retouch the odd keys not expired yet
+	for i := 1; i < keys; i += 2 {
+		if tr.Remove(i) {
+			touch(i)
+		}
+	}
+	for tr.Len() > 0 {
+		c.Advance(span)
+	}
+	if expired != keys {
+		t.Fatalf("%d keys expired", expired)
+	}
+}
+
+func TestHeartbeatTrackerConcurrent(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		expired = make(map[interface{}]int)
+	)
+	tr := NewHeartbeatTracker(time.Millisecond, 16, func(key interface{}) {
+		mu.Lock()
+		expired[key]++
+		mu.Unlock()
+	}, nil)
+	defer tr.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tr.Touch(i, 5*time.Millisecond)
+				time.Sleep(100 * time.Microsecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for tr.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(expired) != 8 {
+		t.Fatalf("expired %v", expired)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+)
+
+// IntervalTicker ticks every interval from its start like time.Ticker, but
+// every deadline is start + k * interval of the monotonic clock, so it never
+// drifts, and a step of the wall clock, e.g. by NTP, does not change its
+// cadence. The ticks missed by a stall, e.g. of a hibernated host or a
+// SIGSTOPped process, are handled by its MissedTickPolicy: SkipMissedTicks
+// delivers one tick after the stall, and the next one at the next instant of
+// the schedule. C receives the instants of the schedule, not the time of
+// firing.
+//
+// It is not gxtime.Ticker, which is the ticker of the timer wheel.
+type IntervalTicker struct {
+	c      chan time.Time
+	clock  Clock
+	policy MissedTickPolicy
+	done   chan struct{} // closed by Stop
+
+	mu         sync.Mutex
+	interval   time.Duration
+	start      time.Time // of the schedule
+	next       time.Time // the instant of the next tick
+	timer      ClockTimer
+	gen        uint64        // increased by Pause, Reset and Stop, to ignore stale timers
+	stop       chan struct{} // nil if paused or stopped
+	stopped    bool
+	pending    []time.Time // the ticks to deliver of DeliverMissedTicks
+	delivering bool
+}
+
+type IntervalTickerOption func(*IntervalTicker)
+
+// WithIntervalTickerClock drives the ticker with @c instead of RealClock.
+func WithIntervalTickerClock(c Clock) IntervalTickerOption {
+	return func(t *IntervalTicker) {
+		t.clock = clockOr(c)
+	}
+}
+
+// WithIntervalTickerPolicy sets the policy of the missed ticks,
+// SkipMissedTicks by default.
+func WithIntervalTickerPolicy(p MissedTickPolicy) IntervalTickerOption {
+	return func(t *IntervalTicker) {
+		t.policy = p
+	}
+}
+
+// NewIntervalTicker returns a ticker firing every @interval from now.
+func NewIntervalTicker(interval time.Duration, opts ...IntervalTickerOption) *IntervalTicker {
+	if interval <= 0 {
+		panic("non-positive interval for NewIntervalTicker")
+	}
+
+	c := make(chan time.Time, 1)
+	t := &IntervalTicker{c: c, clock: RealClock{}, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.mu.Lock()
+	t.interval = interval
+	t.run()
+	t.mu.Unlock()
+
+	return t
+}
+
+// IntervalTickFunc calls @f with every tick of a ticker of @interval in a
+// goroutine of the ticker, one call after another, until Stop. A panic of
+// @f is logged, and the later ticks call it still.
+func IntervalTickFunc(interval time.Duration, f func(), opts ...IntervalTickerOption) *IntervalTicker {
+	t := NewIntervalTicker(interval, opts...)
+	go func() {
+		for {
+			select {
+			case <-t.c:
+				t.call(f)
+			case <-t.done:
+				return
+			}
+		}
+	}()
+
+	return t
+}
+
+func (t *IntervalTicker) call(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			gxlog.CError("interval ticker callback panic: %v", r)
+		}
+	}()
+	f()
+}
+
+// C returns the channel of the ticks.
+func (t *IntervalTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// run starts the schedule from now, t must be locked.
+func (t *IntervalTicker) run() {
+	t.gen++
+	t.stop = make(chan struct{})
+	t.pending = nil
+	t.delivering = false
+
+	t.start = t.clock.Now()
+	t.next = t.start.Add(t.interval)
+	t.arm(t.start)
+}
+
+// arm sets the timer of t.next, t must be locked.
+func (t *IntervalTicker) arm(now time.Time) {
+	gen := t.gen
+	t.timer = t.clock.AfterFunc(t.next.Sub(now), func() {
+		t.fire(gen)
+	})
+}
+
+// after returns the first instant of the schedule after @now.
+func (t *IntervalTicker) after(now time.Time) time.Time {
+	n := now.Sub(t.start)/t.interval + 1
+
+	return t.start.Add(n * t.interval)
+}
+
+func (t *IntervalTicker) fire(gen uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if gen != t.gen {
+		return
+	}
+
+	now := t.clock.Now()
+	if now.Before(t.next) {
+		t.arm(now)
+		return
+	}
+
+	// the ticks from t.next to now are due
+	next := t.after(now)
+	switch t.policy {
+	case DeliverMissedTicks:
+		for tick := t.next; tick.Before(next); tick = tick.Add(t.interval) {
+			t.pending = append(t.pending, tick)
+		}
+		if !t.delivering {
+			t.delivering = true
+			go t.deliver(gen, t.stop)
+		}
+	default:
+		select {
+		case t.c <- next.Add(-t.interval):
+		default:
+		}
+	}
+	t.next = next
+	t.arm(now)
+}
+
+// deliver sends the pending ticks until there is none or @stop is closed.
+func (t *IntervalTicker) deliver(gen uint64, stop chan struct{}) {
+	for {
+		t.mu.Lock()
+		if gen != t.gen {
+			t.mu.Unlock()
+			return
+		}
+		if len(t.pending) == 0 {
+			t.delivering = false
+			t.mu.Unlock()
+			return
+		}
+		tick := t.pending[0]
+		t.mu.Unlock()
+
+		select {
+		case t.c <- tick:
+		case <-stop:
+			return
+		}
+
+		t.mu.Lock()
+		if gen == t.gen {
+			t.pending = t.pending[1:]
+		}
+		t.mu.Unlock()
+	}
+}
+
+// halt stops the timer and the delivery, t must be locked.
+func (t *IntervalTicker) halt() {
+	if t.stop == nil {
+		return
+	}
+
+	t.gen++
+	t.timer.Stop()
+	close(t.stop)
+	t.stop = nil
+	t.pending = nil
+	t.delivering = false
+}
+
+// Pause stops the ticks until Resume. The ticks pending are dropped.
+func (t *IntervalTicker) Pause() {
+	t.mu.Lock()
+	t.halt()
+	t.mu.Unlock()
+}
+
+// Resume restarts a paused ticker, the next tick is an interval later. It
+// does nothing if the ticker is running or stopped.
+func (t *IntervalTicker) Resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil || t.stopped {
+		return
+	}
+	t.run()
+}
+
+// Reset changes the interval to @d. A running ticker restarts from now, and
+// a paused one ticks by @d after Resume. The ticks pending are dropped.
+func (t *IntervalTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for IntervalTicker.Reset")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+	t.interval = d
+	if t.stop != nil {
+		t.halt()
+		t.run()
+	}
+}
+
+// Stop turns off the ticker, which can not be resumed or reset any more.
+// C is not closed.
+func (t *IntervalTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+	t.halt()
+	t.stopped = true
+	close(t.done)
+}
@@ -0,0 +1,172 @@
+package gxtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// stallClock is a FakeClock whose time jumps by stall without firing the
+// timers, as the clock seen by a stalled process.
+type stallClock struct {
+	*FakeClock
+	mu   sync.Mutex
+	skew time.Duration
+}
+
+func (c *stallClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.FakeClock.Now().Add(c.skew)
+}
+
+func (c *stallClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *stallClock) stall(d time.Duration) {
+	c.mu.Lock()
+	c.skew += d
+	c.mu.Unlock()
+}
+
+func TestIntervalTicker(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 17, 0, time.UTC))
+	ticker := NewIntervalTicker(time.Minute, WithIntervalTickerClock(c))
+	defer ticker.Stop()
+
+	c.Advance(59 * time.Second)
+	noTick(t, ticker.C())
+	c.Advance(time.Second)
+	recvTick(t, ticker.C(), "10:01:17")
+
+	// the ticks are not received, the later ones are skipped
+	c.Advance(3 * time.Minute)
+	recvTick(t, ticker.C(), "10:02:17")
+	noTick(t, ticker.C())
+	c.Advance(time.Minute)
+	recvTick(t, ticker.C(), "10:05:17")
+
+	ticker.Reset(5 * time.Minute)
+	c.Advance(4 * time.Minute)
+	noTick(t, ticker.C())
+	c.Advance(time.Minute)
+	recvTick(t, ticker.C(), "10:10:17")
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	noTick(t, ticker.C())
+	ticker.Resume()
+	ticker.Reset(time.Second)
+	c.Advance(time.Hour)
+	noTick(t, ticker.C())
+}
+
+func TestIntervalTickerStall(t *testing.T) {
+	c := &stallClock{FakeClock: NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))}
+	ticker := NewIntervalTicker(time.Second, WithIntervalTickerClock(c))
+	defer ticker.Stop()
+
+	c.Advance(time.Second)
+	recvTick(t, ticker.C(), "10:00:01")
+
+	// the timer of 10:00:02 fires after a stall of 10 intervals
+	c.stall(10 * time.Second)
+	c.Advance(time.Second)
+	recvTick(t, ticker.C(), "10:00:12")
+	noTick(t, ticker.C())
+
+	// the schedule is kept
+	c.Advance(999 * time.Millisecond)
+	noTick(t, ticker.C())
+	c.Advance(time.Millisecond)
+	recvTick(t, ticker.C(), "10:00:13")
+	noTick(t, ticker.C())
+}
+
+func TestIntervalTickerStallDeliverMissed(t *testing.T) {
+	c := &stallClock{FakeClock: NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))}
+	ticker := NewIntervalTicker(time.Second,
+		WithIntervalTickerClock(c), WithIntervalTickerPolicy(DeliverMissedTicks))
+	defer ticker.Stop()
+
+	c.stall(3 * time.Second)
+	c.Advance(time.Second)
+	for _, want := range []string{"10:00:01", "10:00:02", "10:00:03", "10:00:04"} {
+		recvTick(t, ticker.C(), want)
+	}
+	noTick(t, ticker.C())
+}
+
+func TestIntervalTickerPause(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+	ticker := NewIntervalTicker(time.Second, WithIntervalTickerClock(c))
+	defer ticker.Stop()
+
+	c.Advance(time.Second)
+	recvTick(t, ticker.C(), "10:00:01")
+
+	ticker.Pause()
+	ticker.Pause()
+	c.Advance(10 * time.Second)
+	noTick(t, ticker.C())
+
+	// a paused ticker is reset to the interval of the next Resume
+	ticker.Reset(2 * time.Second)
+	c.Advance(10 * time.Second)
+	noTick(t, ticker.C())
+
+	// the schedule starts at Resume
+	c.Advance(500 * time.Millisecond)
+	ticker.Resume()
+	ticker.Resume()
+	c.Advance(time.Second)
+	noTick(t, ticker.C())
+	c.Advance(time.Second)
+	if tick := <-ticker.C(); !tick.Equal(time.Date(2018, 1, 1, 10, 0, 23, 5e8, time.UTC)) {
+		t.Fatalf("tick at %v", tick)
+	}
+}
+
+func TestIntervalTickFunc(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+	calls := make(chan int, 3)
+	var n int
+	ticker := IntervalTickFunc(time.Second, func() {
+		n++
+		calls <- n
+		if n == 1 {
+			panic("the first call")
+		}
+	}, WithIntervalTickerClock(c))
+
+	for i := 1; i <= 3; i++ {
+		c.Advance(time.Second)
+		if got := <-calls; got != i {
+			t.Fatalf("call %d, want %d", got, i)
+		}
+	}
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case got := <-calls:
+		t.Fatalf("call %d after Stop", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestIntervalTickerRealClock(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	start := time.Now()
+	ticker := NewIntervalTicker(interval)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		tick := <-ticker.C()
+		// the instants of the schedule of the monotonic clock
+		if d := tick.Sub(start); d < time.Duration(i)*interval || d > time.Duration(i)*interval+interval/2 {
+			t.Fatalf("tick %d at %v from the start", i, d)
+		}
+	}
+}
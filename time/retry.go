@@ -0,0 +1,160 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// the reasons of RetryError
+var (
+	ErrAttemptsExhausted = fmt.Errorf("retry attempts exhausted")
+	ErrBudgetExhausted   = fmt.Errorf("retry budget exhausted")
+	ErrNotRetryable      = fmt.Errorf("error not retryable")
+)
+
+// RetryError is the error of RetryPolicy.Do, the last error of the retried
+// function and why it stops retrying.
+type RetryError struct {
+	Attempts int
+	Err      error // the last error of the function
+	// Reason is ErrAttemptsExhausted, ErrBudgetExhausted, ErrNotRetryable
+	// or the error of the context of Do
+	Reason error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v after %d attempts: %v", e.Reason, e.Attempts, e.Err)
+}
+
+// Cause returns the last error of the function, for jerrors.Cause.
+func (e *RetryError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the last error of the function, for errors.Is.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRetryable is the default classifier of RetryPolicy, it retries all
+// errors but those of contexts.
+func DefaultRetryable(err error) bool {
+	return err != context.Canceled && err != context.DeadlineExceeded
+}
+
+// RetryPolicy is how to retry a function. The zero value calls it once.
+type RetryPolicy struct {
+	MaxAttempts    int           // 1 if <= 0
+	AttemptTimeout time.Duration // of the context of an attempt, no timeout if 0
+	Budget         time.Duration // of all attempts and delays, no limit if 0
+	// Backoff gives the delays between the attempts, no delay if zero.
+	// Do uses a copy of it.
+	Backoff Backoff
+	// Retryable classifies the errors, DefaultRetryable if nil. The error
+	// of an attempt timed out by AttemptTimeout is ErrTimeout.
+	Retryable func(error) bool
+	Clock     Clock // RealClock if nil
+}
+
+// Do calls @fn until it succeeds, the error is not retryable, the attempts
+// or the budget are exhausted, or @ctx is done. The error returned is a
+// *RetryError unless @fn succeeds.
+func (p *RetryPolicy) Do(ctx context.Context, fn func(context.Context) error) error {
+	clock := clockOr(p.Clock)
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	backoff := p.Backoff
+	backoff.Reset()
+	if backoff.Clock == nil {
+		backoff.Clock = clock
+	}
+
+	start := clock.Now()
+	for attempt := 1; ; attempt++ {
+		timeout := p.AttemptTimeout
+		if p.Budget > 0 {
+			left := p.Budget - clock.Since(start)
+			if timeout <= 0 || left < timeout {
+				timeout = left
+			}
+		}
+
+		actx, cancel, expired := withClockTimeout(ctx, clock, timeout)
+		err := fn(actx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		fail := func(reason error) error {
+			return &RetryError{Attempts: attempt, Err: err, Reason: reason}
+		}
+		if ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+		if expired() && !DefaultRetryable(err) {
+			err = ErrTimeout
+		}
+		switch {
+		case !retryable(err):
+			return fail(ErrNotRetryable)
+		case attempt >= maxAttempts:
+			return fail(ErrAttemptsExhausted)
+		}
+
+		delay := time.Duration(0)
+		if backoff.Base > 0 {
+			delay = backoff.Next()
+		}
+		if p.Budget > 0 && clock.Since(start)+delay >= p.Budget {
+			return fail(ErrBudgetExhausted)
+		}
+		if sleepErr := clock.Sleep(ctx, delay); sleepErr != nil {
+			return fail(sleepErr)
+		}
+	}
+}
+
+// withClockTimeout returns a context canceled after @d of @clock, and
+// whether it is canceled so. There is no timeout if @d <= 0.
+func withClockTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc, func() bool) {
+	if d <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, func() bool { return false }
+	}
+
+	// the deadline is visible to the users of the context
+	if _, ok := clock.(RealClock); ok {
+		actx, cancel := context.WithTimeout(ctx, d)
+		return actx, cancel, func() bool {
+			return actx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		}
+	}
+
+	var fired int32
+	actx, cancel := context.WithCancel(ctx)
+	timer := clock.AfterFunc(d, func() {
+		atomic.StoreInt32(&fired, 1)
+		cancel()
+	})
+
+	return actx, func() {
+			timer.Stop()
+			cancel()
+		}, func() bool {
+			return atomic.LoadInt32(&fired) == 1
+		}
+}
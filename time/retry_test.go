@@ -0,0 +1,150 @@
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errFlaky = fmt.Errorf("flaky")
+
+func TestRetryPolicyAttempts(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+	calls := 0
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	re, ok := err.(*RetryError)
+	if !ok || re.Reason != ErrAttemptsExhausted || re.Attempts != 3 || re.Err != errFlaky || calls != 3 {
+		t.Fatalf("Do() = %v, %d calls", err, calls)
+	}
+
+	calls = 0
+	if err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errFlaky
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Do() = %v", err)
+	}
+
+	// the zero value calls once
+	calls = 0
+	var zero RetryPolicy
+	zero.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	if calls != 1 {
+		t.Fatalf("%d calls of the zero policy", calls)
+	}
+}
+
+func TestRetryPolicyBudget(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	p := RetryPolicy{
+		MaxAttempts: 100,
+		Budget:      10 * time.Second,
+		Backoff:     Backoff{Base: time.Second, Factor: 1},
+		Clock:       c,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- p.Do(context.Background(), func(context.Context) error {
+			return errFlaky
+		})
+	}()
+	for i := 0; i < 9; i++ {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+	}
+
+	err := <-done
+	re, ok := err.(*RetryError)
+	if !ok || re.Reason != ErrBudgetExhausted || re.Attempts != 10 {
+		t.Fatalf("Do() = %v", err)
+	}
+}
+
+func TestRetryPolicyAttemptTimeout(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	p := RetryPolicy{
+		MaxAttempts:    2,
+		AttemptTimeout: time.Second,
+		Budget:         time.Minute,
+		Clock:          c,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- p.Do(context.Background(), func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	for i := 0; i < 2; i++ {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+	}
+
+	// the attempts timed out are retried
+	err := <-done
+	re, ok := err.(*RetryError)
+	if !ok || re.Reason != ErrAttemptsExhausted || re.Attempts != 2 || re.Err != ErrTimeout {
+		t.Fatalf("Do() = %v", err)
+	}
+}
+
+func TestRetryPolicyNotRetryable(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts: 5,
+		Retryable: func(err error) bool {
+			return err != errFlaky
+		},
+	}
+	calls := 0
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errFlaky
+	})
+	if re, ok := err.(*RetryError); !ok || re.Reason != ErrNotRetryable || calls != 1 {
+		t.Fatalf("Do() = %v, %d calls", err, calls)
+	}
+
+	// the context errors are not retried by default
+	p.Retryable = nil
+	calls = 0
+	err = p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return context.Canceled
+	})
+	if re, ok := err.(*RetryError); !ok || re.Reason != ErrNotRetryable || calls != 1 {
+		t.Fatalf("Do() = %v, %d calls", err, calls)
+	}
+}
+
+func TestRetryPolicyContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := RetryPolicy{MaxAttempts: 5, Backoff: Backoff{Base: time.Hour}}
+	err := p.Do(ctx, func(context.Context) error {
+		cancel()
+		return errFlaky
+	})
+	if re, ok := err.(*RetryError); !ok || re.Reason != context.Canceled || re.Attempts != 1 {
+		t.Fatalf("Do() = %v", err)
+	}
+
+	// the deadline of a real clock attempt is visible
+	p = RetryPolicy{AttemptTimeout: time.Hour}
+	p.Do(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatalf("no deadline of the attempt")
+		}
+		return nil
+	})
+}
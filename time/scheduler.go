@@ -0,0 +1,318 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/AlexStocks/goext/log"
+)
+
+var (
+	ErrSchedulerStarted = fmt.Errorf("scheduler has been started")
+	ErrSchedulerStopped = fmt.Errorf("scheduler has been stopped")
+)
+
+// JobID is the id of a job of a Scheduler.
+type JobID uint64
+
+// OverlapPolicy is what a Scheduler does when a job is due while its
+// previous run is not done.
+type OverlapPolicy int
+
+const (
+	SkipIfRunning  OverlapPolicy = iota // the run is skipped
+	QueueIfRunning                      // the run starts after the previous one
+)
+
+// JobInfo is the state of a job of a Scheduler.
+type JobInfo struct {
+	ID       JobID
+	Spec     string
+	LastRun  time.Time // the start of the last run
+	NextRun  time.Time // zero if the scheduler is not started
+	RunCount uint64
+	Skipped  uint64 // by SkipIfRunning
+	Panics   uint64
+	Running  bool
+}
+
+type job struct {
+	JobInfo
+	sched   Schedule
+	fn      func(context.Context)
+	overlap OverlapPolicy
+	queued  int // the runs of QueueIfRunning to start
+	removed bool
+}
+
+type JobOption func(*job)
+
+// WithOverlapPolicy sets the overlap policy of a job, SkipIfRunning by
+// default.
+func WithOverlapPolicy(p OverlapPolicy) JobOption {
+	return func(j *job) {
+		j.overlap = p
+	}
+}
+
+// Scheduler runs jobs by cron specs, every run in its own goroutine.
+type Scheduler struct {
+	clock Clock
+
+	mu      sync.Mutex
+	jobs    map[JobID]*job
+	lastID  JobID
+	ctx     context.Context // of the runs
+	cancel  context.CancelFunc
+	timer   ClockTimer
+	gen     uint64 // increased by every arming, to ignore stale timers
+	started bool
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup // the runs
+}
+
+// NewScheduler returns a scheduler of @clock, RealClock if nil.
+func NewScheduler(clock Clock) *Scheduler {
+	return &Scheduler{
+		clock: clockOr(clock),
+		jobs:  make(map[JobID]*job),
+		done:  make(chan struct{}),
+	}
+}
+
+// Add adds @fn run by @spec, which is a five fields cron expression
+// "minute hour day-of-month month day-of-week" in the local time of the
+// clock, a macro of @yearly, @monthly, @weekly, @daily or @hourly, or
+// "@every <duration>" like "@every 1h30m" counted from the previous due time.
+// See ParseCron.
+func (s *Scheduler) Add(spec string, fn func(context.Context), opts ...JobOption) (JobID, error) {
+	sched, err := ParseCron(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.add(spec, sched, fn, opts)
+}
+
+// AddSchedule adds @fn run by @sched, the Spec of its JobInfo is "".
+func (s *Scheduler) AddSchedule(sched Schedule, fn func(context.Context), opts ...JobOption) (JobID, error) {
+	return s.add("", sched, fn, opts)
+}
+
+func (s *Scheduler) add(spec string, sched Schedule, fn func(context.Context), opts []JobOption) (JobID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return 0, ErrSchedulerStopped
+	}
+	s.lastID++
+	j := &job{JobInfo: JobInfo{ID: s.lastID, Spec: spec}, sched: sched, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+	s.jobs[j.ID] = j
+	if s.started {
+		now := s.clock.Now()
+		j.NextRun = j.sched.Next(now)
+		s.arm(now)
+	}
+
+	return j.ID, nil
+}
+
+// Remove removes the job of @id, its run in progress is not stopped.
+// It returns false if there is no such job.
+func (s *Scheduler) Remove(id JobID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return false
+	}
+	j.removed = true
+	j.queued = 0
+	delete(s.jobs, id)
+	if s.started && !s.stopped {
+		s.arm(s.clock.Now())
+	}
+
+	return true
+}
+
+// Start starts running the jobs until @ctx is done or Stop is called, the
+// runs get a context canceled then.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	if s.started {
+		return ErrSchedulerStarted
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	now := s.clock.Now()
+	for _, j := range s.jobs {
+		j.NextRun = j.sched.Next(now)
+	}
+	s.arm(now)
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			s.Stop(false)
+		case <-s.done:
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops scheduling, the queued runs are dropped. If @graceful, it waits
+// for the runs in progress to return, otherwise it cancels their context
+// and returns at once.
+func (s *Scheduler) Stop(graceful bool) {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		s.gen++
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		for _, j := range s.jobs {
+			j.queued = 0
+		}
+		close(s.done)
+	}
+	s.mu.Unlock()
+
+	if graceful {
+		s.wg.Wait()
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// Jobs returns the jobs in id order.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.JobInfo)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].ID < jobs[k].ID
+	})
+
+	return jobs
+}
+
+// arm sets the timer to the earliest next run, s must be locked.
+func (s *Scheduler) arm(now time.Time) {
+	s.gen++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	var next time.Time
+	for _, j := range s.jobs {
+		if !j.NextRun.IsZero() && (next.IsZero() || j.NextRun.Before(next)) {
+			next = j.NextRun
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	gen := s.gen
+	s.timer = s.clock.AfterFunc(next.Sub(now), func() {
+		s.fire(gen)
+	})
+}
+
+func (s *Scheduler) fire(gen uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.gen {
+		return
+	}
+
+	now := s.clock.Now()
+	for _, j := range s.jobs {
+		if j.NextRun.IsZero() || now.Before(j.NextRun) {
+			continue
+		}
+		j.NextRun = j.sched.Next(now)
+
+		switch {
+		case !j.Running:
+			j.Running = true
+			s.wg.Add(1)
+			go s.run(j)
+		case j.overlap == QueueIfRunning:
+			j.queued++
+		default:
+			j.Skipped++
+		}
+	}
+	s.arm(now)
+}
+
+// run runs @j and its queued runs.
+func (s *Scheduler) run(j *job) {
+	defer s.wg.Done()
+
+	for {
+		s.mu.Lock()
+		j.LastRun = s.clock.Now()
+		j.RunCount++
+		s.mu.Unlock()
+
+		panicked := s.call(j)
+
+		s.mu.Lock()
+		if panicked {
+			j.Panics++
+		}
+		if j.queued == 0 || j.removed || s.stopped {
+			j.Running = false
+			s.mu.Unlock()
+			return
+		}
+		j.queued--
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scheduler) call(j *job) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			gxlog.CError("scheduler job %d %q panic: %v", j.ID, j.Spec, r)
+		}
+	}()
+	j.fn(s.ctx)
+
+	return false
+}
@@ -0,0 +1,167 @@
+package gxtime
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitJob waits until the job @id of @s satisfies @cond.
+func waitJob(t *testing.T, s *Scheduler, id JobID, cond func(JobInfo) bool) JobInfo {
+	for i := 0; i < 100; i++ {
+		for _, j := range s.Jobs() {
+			if j.ID == id && cond(j) {
+				return j
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %d: %+v", id, s.Jobs())
+	return JobInfo{}
+}
+
+func TestScheduler(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 17, 0, time.UTC))
+	s := NewScheduler(c)
+
+	runs := make(chan string, 10)
+	every, err := s.Add("@every 1m", func(context.Context) { runs <- "every" })
+	if err != nil {
+		t.Fatalf("Add(@every) = %v", err)
+	}
+	cron, err := s.Add("*/5 * * * *", func(context.Context) { runs <- "cron" })
+	if err != nil {
+		t.Fatalf("Add(cron) = %v", err)
+	}
+	if _, err := s.Add("* * *", nil); err == nil {
+		t.Fatalf("Add() of an illegal spec succeeds")
+	}
+	if jobs := s.Jobs(); len(jobs) != 2 || !jobs[0].NextRun.IsZero() {
+		t.Fatalf("Jobs() before Start = %+v", jobs)
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	defer s.Stop(true)
+	if err := s.Start(context.Background()); err != ErrSchedulerStarted {
+		t.Fatalf("Start() again = %v", err)
+	}
+	jobs := s.Jobs()
+	if jobs[0].ID != every || jobs[0].NextRun.Format("15:04:05") != "10:01:17" ||
+		jobs[1].ID != cron || jobs[1].NextRun.Format("15:04:05") != "10:05:00" {
+		t.Fatalf("Jobs() = %+v", jobs)
+	}
+
+	// a minute a step, or the runs of every may overlap and be skipped
+	for i := 0; i < 4; i++ {
+		c.Advance(time.Minute)
+		if run := <-runs; run != "every" {
+			t.Fatalf("run %s", run)
+		}
+		waitJob(t, s, every, func(j JobInfo) bool { return !j.Running })
+	}
+	c.Advance(43 * time.Second)
+	if run := <-runs; run != "cron" {
+		t.Fatalf("run %s", run)
+	}
+	waitJob(t, s, cron, func(j JobInfo) bool {
+		return j.RunCount == 1 && !j.Running && j.LastRun.Format("15:04:05") == "10:05:00" &&
+			j.NextRun.Format("15:04:05") == "10:10:00"
+	})
+	waitJob(t, s, every, func(j JobInfo) bool { return j.RunCount == 4 && !j.Running })
+
+	if !s.Remove(every) || s.Remove(every) {
+		t.Fatalf("Remove() should succeed once")
+	}
+	c.Advance(5 * time.Minute)
+	if run := <-runs; run != "cron" {
+		t.Fatalf("run %s", run)
+	}
+	select {
+	case run := <-runs:
+		t.Fatalf("unexpected run %s", run)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+	s := NewScheduler(c)
+
+	release := make(chan struct{})
+	block := func(context.Context) { <-release }
+	skip, _ := s.Add("@every 1m", block)
+	queue, _ := s.Add("@every 1m", block, WithOverlapPolicy(QueueIfRunning))
+	s.Start(context.Background())
+	defer s.Stop(false)
+
+	for i := 0; i < 3; i++ {
+		c.Advance(time.Minute)
+	}
+	jobs := s.Jobs()
+	if jobs[0].Skipped != 2 || !jobs[0].Running || jobs[1].Skipped != 0 || !jobs[1].Running {
+		t.Fatalf("Jobs() = %+v", jobs)
+	}
+
+	// 1 of the skipping job, 3 of the queueing one
+	for i := 0; i < 4; i++ {
+		release <- struct{}{}
+	}
+	waitJob(t, s, skip, func(j JobInfo) bool { return j.RunCount == 1 && !j.Running })
+	waitJob(t, s, queue, func(j JobInfo) bool { return j.RunCount == 3 && !j.Running })
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+	s := NewScheduler(c)
+	id, _ := s.Add("@every 1s", func(context.Context) { panic("oops") })
+	s.Start(context.Background())
+	defer s.Stop(true)
+
+	c.Advance(time.Second)
+	waitJob(t, s, id, func(j JobInfo) bool { return j.Panics == 1 && !j.Running })
+	c.Advance(time.Second)
+	waitJob(t, s, id, func(j JobInfo) bool { return j.Panics == 2 && j.RunCount == 2 })
+}
+
+func TestSchedulerStop(t *testing.T) {
+	c := NewFakeClock(time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	// graceful
+	s := NewScheduler(c)
+	started := make(chan struct{})
+	finished := false
+	s.Add("@every 1s", func(context.Context) {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		finished = true
+	})
+	s.Start(context.Background())
+	c.Advance(time.Second)
+	<-started
+	s.Stop(true)
+	if !finished {
+		t.Fatalf("Stop(true) returns before the run")
+	}
+	if _, err := s.Add("@every 1s", nil); err != ErrSchedulerStopped {
+		t.Fatalf("Add() after Stop = %v", err)
+	}
+	if err := s.Start(context.Background()); err != ErrSchedulerStopped {
+		t.Fatalf("Start() after Stop = %v", err)
+	}
+
+	// the context of Start is done
+	s = NewScheduler(c)
+	canceled := make(chan struct{})
+	s.Add("@every 1s", func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	c.Advance(time.Second)
+	cancel()
+	<-canceled
+	s.Stop(true)
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Lap is a named split of a Stopwatch.
+type Lap struct {
+	Name    string
+	Split   time.Duration // since the previous lap or the start
+	Elapsed time.Duration // since the start
+}
+
+// Stopwatch measures the elapsed time of its runs between Start and Stop,
+// and records laps. It is safe for concurrent use, the zero value is a
+// stopped stopwatch of RealClock.
+type Stopwatch struct {
+	clock Clock
+
+	mu      sync.Mutex
+	running bool
+	start   time.Time     // of the current run
+	elapsed time.Duration // of the runs stopped
+	lastLap time.Duration // the elapsed time of the last lap
+	laps    []Lap
+}
+
+// NewStopwatch returns a stopped stopwatch of @clock, RealClock if nil.
+func NewStopwatch(clock Clock) *Stopwatch {
+	return &Stopwatch{clock: clock}
+}
+
+// StartStopwatch returns a running stopwatch of RealClock.
+func StartStopwatch() *Stopwatch {
+	s := &Stopwatch{}
+	s.Start()
+
+	return s
+}
+
+// Start starts a run, it is a no-op if the stopwatch is running.
+func (s *Stopwatch) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		s.running = true
+		s.start = clockOr(s.clock).Now()
+	}
+}
+
+// Stop stops the run, the elapsed time stays until the next Start.
+func (s *Stopwatch) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		s.elapsed = s.elapsedLocked()
+		s.running = false
+	}
+}
+
+// Reset stops the stopwatch, and clears the elapsed time and the laps.
+func (s *Stopwatch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = false
+	s.elapsed = 0
+	s.lastLap = 0
+	s.laps = nil
+}
+
+// Elapsed returns the total time of the runs.
+func (s *Stopwatch) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.elapsedLocked()
+}
+
+func (s *Stopwatch) elapsedLocked() time.Duration {
+	if !s.running {
+		return s.elapsed
+	}
+
+	return s.elapsed + clockOr(s.clock).Since(s.start)
+}
+
+// Lap records a split named @name, and returns it.
+func (s *Stopwatch) Lap(name string) Lap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := s.elapsedLocked()
+	lap := Lap{Name: name, Split: elapsed - s.lastLap, Elapsed: elapsed}
+	s.lastLap = elapsed
+	s.laps = append(s.laps, lap)
+
+	return lap
+}
+
+// Report returns the laps in the order they are recorded.
+func (s *Stopwatch) Report() []Lap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Lap(nil), s.laps...)
+}
+
+// InfoLogger is the logger of Track, which log4go.Logger and
+// gxlog.Log4goLogger are.
+type InfoLogger interface {
+	Info(arg0 interface{}, args ...interface{})
+}
+
+// Track logs the time @name costs when the func returned is called, e.g.
+//
+//	defer gxtime.Track("decode", logger)()
+func Track(name string, logger InfoLogger) func() {
+	s := StartStopwatch()
+
+	return func() {
+		logger.Info("%s costs %v", name, s.Elapsed())
+	}
+}
@@ -0,0 +1,100 @@
+package gxtime
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStopwatch(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	s := NewStopwatch(c)
+
+	c.Advance(time.Second)
+	if d := s.Elapsed(); d != 0 {
+		t.Fatalf("Elapsed() before Start = %v", d)
+	}
+
+	s.Start()
+	c.Advance(2 * time.Second)
+	s.Lap("read")
+	c.Advance(3 * time.Second)
+	s.Start() // no-op
+	s.Lap("decode")
+	s.Stop()
+	c.Advance(time.Hour)
+	if d := s.Elapsed(); d != 5*time.Second {
+		t.Fatalf("Elapsed() after Stop = %v", d)
+	}
+
+	s.Start()
+	c.Advance(time.Second)
+	lap := s.Lap("write")
+	if lap.Split != time.Second || lap.Elapsed != 6*time.Second {
+		t.Fatalf("Lap() = %+v", lap)
+	}
+
+	want := "[{read 2s 2s} {decode 3s 5s} {write 1s 6s}]"
+	if got := fmt.Sprint(s.Report()); got != want {
+		t.Fatalf("Report() = %s, want %s", got, want)
+	}
+
+	s.Reset()
+	if d := s.Elapsed(); d != 0 || len(s.Report()) != 0 {
+		t.Fatalf("Elapsed() after Reset = %v, laps %v", d, s.Report())
+	}
+}
+
+func TestStopwatchConcurrentLaps(t *testing.T) {
+	s := StartStopwatch()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Lap(fmt.Sprintf("%d-%d", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	laps := s.Report()
+	if len(laps) != 800 {
+		t.Fatalf("%d laps", len(laps))
+	}
+	var sum time.Duration
+	for i, lap := range laps {
+		if i > 0 && lap.Elapsed < laps[i-1].Elapsed {
+			t.Fatalf("lap %d %+v before %+v", i, lap, laps[i-1])
+		}
+		sum += lap.Split
+	}
+	if sum != laps[len(laps)-1].Elapsed {
+		t.Fatalf("the splits sum to %v, elapsed %v", sum, laps[len(laps)-1].Elapsed)
+	}
+}
+
+type infoRecorder []string
+
+func (r *infoRecorder) Info(arg0 interface{}, args ...interface{}) {
+	*r = append(*r, fmt.Sprintf(arg0.(string), args...))
+}
+
+func TestTrack(t *testing.T) {
+	var r infoRecorder
+	func() {
+		defer Track("decode", &r)()
+		time.Sleep(time.Millisecond)
+	}()
+
+	if len(r) != 1 || !strings.HasPrefix(r[0], "decode costs ") {
+		t.Fatalf("logs %q", r)
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(r[0], "decode costs ")); err != nil || d < time.Millisecond {
+		t.Fatalf("log %q", r[0])
+	}
+}
@@ -11,31 +11,31 @@ import (
 )
 
 func TimeDayDuratioin(day float64) time.Duration {
-	return time.Duration(day * 24 * float64(time.Hour))
+	return floatDuration(day, Day)
 }
 
 func TimeHourDuratioin(hour float64) time.Duration {
-	return time.Duration(hour * float64(time.Hour))
+	return floatDuration(hour, time.Hour)
 }
 
 func TimeMinuteDuration(minute float64) time.Duration {
-	return time.Duration(minute * float64(time.Minute))
+	return floatDuration(minute, time.Minute)
 }
 
 func TimeSecondDuration(sec float64) time.Duration {
-	return time.Duration(sec * float64(time.Second))
+	return floatDuration(sec, time.Second)
 }
 
 func TimeMillisecondDuration(m float64) time.Duration {
-	return time.Duration(m * float64(time.Millisecond))
+	return floatDuration(m, time.Millisecond)
 }
 
 func TimeMicrosecondDuration(m float64) time.Duration {
-	return time.Duration(m * float64(time.Microsecond))
+	return floatDuration(m, time.Microsecond)
 }
 
 func TimeNanosecondDuration(n float64) time.Duration {
-	return time.Duration(n * float64(time.Nanosecond))
+	return floatDuration(n, time.Nanosecond)
 }
 
 // desc: convert year-month-day-hour-minute-seccond to int in second
@@ -85,6 +85,51 @@ func Time2UnixNano(t time.Time) int64 {
 	return t.UnixNano()
 }
 
+// UnixMilli returns the milliseconds since the epoch, floored for the time
+// before 1970 and without the overflow of UnixNano beyond 2262.
+func UnixMilli(t time.Time) int64 {
+	return t.Unix()*1e3 + int64(t.Nanosecond())/1e6
+}
+
+// FromUnixMilli returns the local time of the milliseconds @ms since the epoch.
+func FromUnixMilli(ms int64) time.Time {
+	return time.Unix(ms/1e3, ms%1e3*1e6)
+}
+
+func NowMilli() int64 {
+	return UnixMilli(time.Now())
+}
+
+// UnixMicro returns the microseconds since the epoch, see UnixMilli.
+func UnixMicro(t time.Time) int64 {
+	return t.Unix()*1e6 + int64(t.Nanosecond())/1e3
+}
+
+// FromUnixMicro returns the local time of the microseconds @us since the epoch.
+func FromUnixMicro(us int64) time.Time {
+	return time.Unix(us/1e6, us%1e6*1e3)
+}
+
+func NowMicro() int64 {
+	return UnixMicro(time.Now())
+}
+
+// MonotonicElapsed returns the time elapsed since @since by the monotonic
+// clock, which a time.Now() carries and is immune to the wall clock set by
+// NTP or by hand. If @since has no monotonic reading, e.g. it is parsed,
+// unmarshaled or the result of StripMonotonic, Round or Truncate, the wall
+// clock is used and the result may be wrong or even negative.
+func MonotonicElapsed(since time.Time) time.Duration {
+	return time.Since(since)
+}
+
+// StripMonotonic returns @t without its monotonic reading, so that it
+// compares and serializes as the wall clock, e.g. with ==, as a map key
+// or by String.
+func StripMonotonic(t time.Time) time.Time {
+	return t.Round(0)
+}
+
 func GetEndtime(format string) time.Time {
 	timeNow := time.Now()
 	switch format {
@@ -37,3 +37,77 @@ func TestGetEndTime(t *testing.T) {
 	yearEndTime := GetEndtime("year")
 	t.Logf("this year end time %q", yearEndTime)
 }
+
+func TestUnixMilli(t *testing.T) {
+	tests := []struct {
+		t  time.Time
+		ms int64
+	}{
+		{time.Unix(0, 0), 0},
+		{time.Date(2018, 1, 2, 3, 4, 5, 678999999, time.UTC), 1514862245678},
+		// floored before the epoch
+		{time.Unix(-1, 999999999), -1},
+		{time.Unix(-2, 500000000), -1500},
+		// the leap second of 2016 is not counted by unix time
+		{time.Date(2016, 12, 31, 23, 59, 59, 999000000, time.UTC), 1483228799999},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 1483228800000},
+		// beyond the range of UnixNano
+		{time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC), 32503680000000},
+	}
+	for _, test := range tests {
+		if ms := UnixMilli(test.t); ms != test.ms {
+			t.Fatalf("UnixMilli(%v) = %d, want %d", test.t, ms, test.ms)
+		}
+		if tm := FromUnixMilli(test.ms); !tm.Equal(test.t.Truncate(time.Millisecond)) {
+			t.Fatalf("FromUnixMilli(%d) = %v, want %v", test.ms, tm, test.t)
+		}
+	}
+
+	for _, ms := range []int64{0, 1, -1, 999, -999, 1000, -1001, 1514862245678, -1514862245678} {
+		if got := UnixMilli(FromUnixMilli(ms)); got != ms {
+			t.Fatalf("UnixMilli(FromUnixMilli(%d)) = %d", ms, got)
+		}
+		us := ms*1000 + 7
+		if got := UnixMicro(FromUnixMicro(us)); got != us {
+			t.Fatalf("UnixMicro(FromUnixMicro(%d)) = %d", us, got)
+		}
+	}
+
+	if now, ms := time.Now(), NowMilli(); ms < UnixMilli(now) || ms-UnixMilli(now) > 1000 {
+		t.Fatalf("NowMilli() = %d at %v", ms, now)
+	}
+}
+
+func TestUnixMilliDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	// 2018-03-11 02:00 EST jumps to 03:00 EDT, an hour of local time is skipped
+	before := time.Date(2018, 3, 11, 1, 59, 59, 999000000, loc)
+	after := time.Date(2018, 3, 11, 3, 0, 0, 0, loc)
+	if d := UnixMilli(after) - UnixMilli(before); d != 1 {
+		t.Fatalf("%d ms across the DST gap, want 1", d)
+	}
+	// the local time is kept by the instant only
+	if tm := FromUnixMilli(UnixMilli(after)).In(loc); !tm.Equal(after) || tm.Hour() != 3 {
+		t.Fatalf("FromUnixMilli() = %v, want %v", tm, after)
+	}
+}
+
+func TestMonotonicElapsed(t *testing.T) {
+	start := time.Now()
+	time.Sleep(time.Millisecond)
+	if d := MonotonicElapsed(start); d < time.Millisecond {
+		t.Fatalf("MonotonicElapsed() = %v", d)
+	}
+
+	wall := StripMonotonic(start)
+	if wall != start.Round(0) || !wall.Equal(start) {
+		t.Fatalf("StripMonotonic() = %v, want %v", wall, start)
+	}
+	if s := wall.String(); s != start.Round(0).String() || len(s) >= len(start.String()) {
+		t.Fatalf("StripMonotonic().String() = %s", s)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTimeout is returned by RunWithTimeout and friends on expiry. Like
+// net.Error, its Timeout() is true.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "run timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+const (
+	runPending int32 = iota
+	runReturned
+	runAbandoned
+)
+
+// RunWithTimeout runs @fn in a goroutine, and returns its error, or
+// ErrTimeout after @d. @fn keeps running after the timeout, it cannot be
+// stopped but its result is dropped without leaking anything.
+func RunWithTimeout(d time.Duration, fn func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return RunWithContextLate(ctx, func(context.Context) error { return fn() }, nil)
+}
+
+// RunWithContext runs @fn with @ctx in a goroutine, and returns its error,
+// or ErrTimeout if the deadline of @ctx is exceeded first, or the error of
+// @ctx if it is canceled first. @fn keeps running until it returns, so it
+// should watch @ctx.
+func RunWithContext(ctx context.Context, fn func(context.Context) error) error {
+	return RunWithContextLate(ctx, fn, nil)
+}
+
+// RunWithContextLate is RunWithContext calling @late, if not nil, with the
+// error of @fn returning after @ctx is done, e.g. to release the resources
+// @fn acquires. @late runs in the goroutine of @fn. @fn is not run if @ctx
+// is done already. A panic of @fn is returned as an error.
+func RunWithContextLate(ctx context.Context, fn func(context.Context) error, late func(error)) error {
+	if err := ctx.Err(); err != nil {
+		return ctxError(err)
+	}
+
+	var state int32 // runPending
+	result := make(chan error, 1)
+	go func() {
+		err := protect(ctx, fn)
+		if atomic.CompareAndSwapInt32(&state, runPending, runReturned) {
+			result <- err
+			return
+		}
+		// the caller has gone
+		if late != nil {
+			late(err)
+		}
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&state, runPending, runAbandoned) {
+			return ctxError(ctx.Err())
+		}
+		// fn has just returned
+		return <-result
+	}
+}
+
+func protect(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("run panic: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func ctxError(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+
+	return err
+}
@@ -0,0 +1,126 @@
+package gxtime
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	n := runtime.NumGoroutine()
+	errFn := fmt.Errorf("fn error")
+
+	// fn returns first
+	if err := RunWithTimeout(time.Second, func() error { return errFn }); err != errFn {
+		t.Fatalf("RunWithTimeout() = %v, want %v", err, errFn)
+	}
+
+	// the timeout is first, fn returns later
+	release := make(chan struct{})
+	err := RunWithTimeout(10*time.Millisecond, func() error {
+		<-release
+		return errFn
+	})
+	if err != ErrTimeout {
+		t.Fatalf("RunWithTimeout() = %v, want timeout", err)
+	}
+	if e, ok := err.(interface{ Timeout() bool }); !ok || !e.Timeout() {
+		t.Fatalf("ErrTimeout is not a timeout error")
+	}
+	close(release)
+	checkGoroutines(t, n)
+
+	if err := RunWithTimeout(time.Second, func() error { panic("oops") }); err == nil {
+		t.Fatalf("RunWithTimeout() of a panic = nil")
+	}
+	if err := RunWithTimeout(0, func() error { t.Fatalf("fn runs"); return nil }); err != ErrTimeout {
+		t.Fatalf("RunWithTimeout(0) = %v", err)
+	}
+}
+
+func TestRunWithContext(t *testing.T) {
+	n := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := RunWithContext(ctx, func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("RunWithContext() = %v, want canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = RunWithContext(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return ctx.Err()
+	})
+	if err != ErrTimeout {
+		t.Fatalf("RunWithContext() = %v, want timeout", err)
+	}
+	checkGoroutines(t, n)
+}
+
+func TestRunWithContextLate(t *testing.T) {
+	n := runtime.NumGoroutine()
+	errFn := fmt.Errorf("fn error")
+
+	lates := make(chan error, 1)
+	late := func(err error) { lates <- err }
+
+	// fn returns first, late is not called
+	err := RunWithContextLate(context.Background(), func(context.Context) error { return errFn }, late)
+	if err != errFn {
+		t.Fatalf("RunWithContextLate() = %v", err)
+	}
+
+	// the timeout is first, late gets the result
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = RunWithContextLate(ctx, func(context.Context) error {
+		<-release
+		return errFn
+	}, late)
+	if err != ErrTimeout {
+		t.Fatalf("RunWithContextLate() = %v, want timeout", err)
+	}
+	select {
+	case err := <-lates:
+		t.Fatalf("late(%v) before fn returns", err)
+	default:
+	}
+	close(release)
+	if err := <-lates; err != errFn {
+		t.Fatalf("late(%v), want %v", err, errFn)
+	}
+	checkGoroutines(t, n)
+
+	// the result goes to exactly one of the caller and late
+	for i := 0; i < 1000; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := RunWithContextLate(ctx, func(context.Context) error {
+			// races with the return
+			go cancel()
+			time.Sleep(time.Microsecond)
+			return errFn
+		}, late)
+		cancel()
+		if err == errFn {
+			continue
+		}
+		if err != context.Canceled {
+			t.Fatalf("RunWithContextLate() = %v", err)
+		}
+		if err := <-lates; err != errFn {
+			t.Fatalf("late(%v)", err)
+		}
+	}
+	checkGoroutines(t, n)
+}
@@ -7,94 +7,250 @@
 package gxtime
 
 import (
-	//"fmt"
 	"sync"
 	"time"
 )
 
+// Wheel is a hashed timing wheel. All its timers are driven by one ticker
+// and one goroutine, instead of a runtime timer each.
+//
+// The precision is the tick of the wheel: a timer of duration d fires
+// after at least d, and at most d plus a tick later (plus the scheduling
+// delay of the wheel goroutine).
 type Wheel struct {
 	sync.RWMutex
 	span   time.Duration
-	period time.Duration
 	ticker *time.Ticker
 	index  int
-	ring   []chan struct{}
+	ring   []*WheelTimer // dummy heads of circular lists
 	once   sync.Once
-	now    time.Time
+	now    time.Time // time of the last tick
+	done   chan struct{}
 }
 
-func NewWheel(span time.Duration, buckets int) *Wheel {
-	var (
-		w *Wheel
-	)
+// WheelTimer is a timer of a Wheel.
+type WheelTimer struct {
+	w          *Wheel
+	prev, next *WheelTimer
+	rounds     int           // full turns of the wheel to wait
+	period     time.Duration // > 0 for tickers
+	f          func()
+	c          chan time.Time // of NewTimer and NewTicker, f is nil
+}
 
-	if span == 0 {
-		panic("@span == 0")
+// NewWheel returns a wheel of @buckets slots turning a slot every @span.
+// A timer longer than a turn, @span * @buckets, waits for several turns,
+// so @buckets only affects the length of the lists of the slots.
+func NewWheel(span time.Duration, buckets int) *Wheel {
+	if span <= 0 {
+		panic("@span <= 0")
 	}
-	if buckets == 0 {
-		panic("@bucket == 0")
+	if buckets <= 0 {
+		panic("@bucket <= 0")
 	}
 
-	w = &Wheel{
+	w := &Wheel{
 		span:   span,
-		period: span * (time.Duration(buckets)),
 		ticker: time.NewTicker(span),
-		index:  0,
-		ring:   make([](chan struct{}), buckets),
+		ring:   make([]*WheelTimer, buckets),
 		now:    time.Now(),
+		done:   make(chan struct{}),
+	}
+	for i := range w.ring {
+		head := &WheelTimer{}
+		head.prev, head.next = head, head
+		w.ring[i] = head
 	}
 
-	go func() {
-		var notify chan struct{}
-		// var cw CountWatch
-		// cw.Start()
-		for t := range w.ticker.C {
-			w.Lock()
-			w.now = t
+	go w.run()
 
-			// fmt.Println("index:", w.index, ", value:", w.bitmap.Get(w.index))
-			notify = w.ring[w.index]
-			w.ring[w.index] = nil
-			w.index = (w.index + 1) % len(w.ring)
+	return w
+}
 
-			w.Unlock()
+func (w *Wheel) run() {
+	var expired []*WheelTimer
+	for {
+		var now time.Time
+		select {
+		case <-w.done:
+			return
+		case now = <-w.ticker.C:
+		}
 
-			if notify != nil {
-				close(notify)
+		expired = w.turn(now, expired[:0])
+		for i, t := range expired {
+			if t.c != nil {
+				// drop the tick if the last one is not received, like time.Ticker
+				select {
+				case t.c <- now:
+				default:
+				}
+			} else {
+				t.f()
 			}
+			expired[i] = nil
 		}
-		// fmt.Println("timer costs:", cw.Count()/1e9, "s")
-	}()
+	}
+}
 
-	return w
+// turn moves to the next slot at @now, and returns the timers expired.
+func (w *Wheel) turn(now time.Time, expired []*WheelTimer) []*WheelTimer {
+	w.Lock()
+	defer w.Unlock()
+
+	w.now = now
+	w.index = (w.index + 1) % len(w.ring)
+	head := w.ring[w.index]
+	for t := head.next; t != head; {
+		next := t.next
+		if t.rounds > 0 {
+			t.rounds--
+		} else {
+			t.unlink()
+			expired = append(expired, t)
+			if t.period > 0 {
+				w.add(t, t.period)
+			}
+		}
+		t = next
+	}
+
+	return expired
+}
+
+// add links @t into the slot @d later, w must be locked.
+func (w *Wheel) add(t *WheelTimer, d time.Duration) {
+	// counted from the last tick, so that t never fires early
+	ticks := int((d + time.Since(w.now) + w.span - 1) / w.span)
+	if ticks < 1 {
+		ticks = 1
+	}
+	t.rounds = (ticks - 1) / len(w.ring)
+
+	head := w.ring[(w.index+ticks)%len(w.ring)]
+	t.prev, t.next = head.prev, head
+	head.prev.next = t
+	head.prev = t
 }
 
+func (t *WheelTimer) unlink() {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next = nil, nil
+}
+
+// Stop stops the wheel, the timers not fired never fire.
 func (w *Wheel) Stop() {
-	w.once.Do(func() { w.ticker.Stop() })
+	w.once.Do(func() {
+		w.ticker.Stop()
+		close(w.done)
+	})
+}
+
+// After returns a channel receiving the time after @timeout.
+func (w *Wheel) After(timeout time.Duration) <-chan time.Time {
+	return w.NewTimer(timeout).c
+}
+
+// Done returns a channel closed after @timeout, so that it can be received
+// by many goroutines. It is never closed if the wheel stops before.
+func (w *Wheel) Done(timeout time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	w.AfterFunc(timeout, func() {
+		close(done)
+	})
+
+	return done
+}
+
+// NewTimer returns a timer sending the time to its C after @timeout.
+func (w *Wheel) NewTimer(timeout time.Duration) *WheelTimer {
+	t := &WheelTimer{w: w, c: make(chan time.Time, 1)}
+	w.addTimer(t, timeout)
+
+	return t
 }
 
-func (w *Wheel) After(timeout time.Duration) <-chan struct{} {
-	if timeout >= w.period {
-		panic("@timeout over ring's life period")
+// NewTicker returns a timer sending the time to its C every @period, until
+// it is stopped. @period is rounded up to a multiple of the tick.
+func (w *Wheel) NewTicker(period time.Duration) *WheelTimer {
+	if period <= 0 {
+		panic("@period <= 0")
 	}
 
-	var pos = int(timeout / w.span)
-	if 0 < pos {
-		pos--
+	t := &WheelTimer{w: w, period: period, c: make(chan time.Time, 1)}
+	w.addTimer(t, period)
+
+	return t
+}
+
+// AfterFunc calls @f in the wheel goroutine after @timeout. @f should
+// return quickly, as it delays the other timers.
+func (w *Wheel) AfterFunc(timeout time.Duration, f func()) *WheelTimer {
+	t := &WheelTimer{w: w, f: f}
+	w.addTimer(t, timeout)
+
+	return t
+}
+
+// TickFunc calls @f in the wheel goroutine every @period, until the timer
+// returned is stopped. @period is rounded up to a multiple of the tick.
+func (w *Wheel) TickFunc(period time.Duration, f func()) *WheelTimer {
+	if period <= 0 {
+		panic("@period <= 0")
 	}
 
+	t := &WheelTimer{w: w, period: period, f: f}
+	w.addTimer(t, period)
+
+	return t
+}
+
+func (w *Wheel) addTimer(t *WheelTimer, d time.Duration) {
 	w.Lock()
-	pos = (w.index + pos) % len(w.ring)
-	if w.ring[pos] == nil {
-		w.ring[pos] = make(chan struct{})
-	}
-	// fmt.Println("pos:", pos)
-	c := w.ring[pos]
+	w.add(t, d)
 	w.Unlock()
+}
+
+// C returns the channel of the timer, nil for AfterFunc and TickFunc.
+func (t *WheelTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop prevents the timer from firing. It returns false if the timer has
+// fired or been stopped, calling it again is safe.
+func (t *WheelTimer) Stop() bool {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	if t.next == nil {
+		return false
+	}
+	t.unlink()
+
+	return true
+}
+
+// Reset changes the timer to fire after @d, even if it has fired or been
+// stopped, and the period of a ticker to @d. It returns whether the timer
+// had been active.
+func (t *WheelTimer) Reset(d time.Duration) bool {
+	t.w.Lock()
+	defer t.w.Unlock()
+
+	active := t.next != nil
+	if active {
+		t.unlink()
+	}
+	if t.period > 0 && d > 0 {
+		t.period = d
+	}
+	t.w.add(t, d)
 
-	return c
+	return active
 }
 
+// Now returns the time of the last tick.
 func (w *Wheel) Now() time.Time {
 	w.RLock()
 	now := w.now
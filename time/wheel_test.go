@@ -2,6 +2,7 @@ package gxtime
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -71,3 +72,180 @@ func TestWheels(t *testing.T) {
 	go f(1510e6)
 	wg.Wait()
 }
+
+func TestWheelTimers(t *testing.T) {
+	const tick = 10 * time.Millisecond
+	wheel := NewWheel(tick, 8)
+	defer wheel.Stop()
+
+	// longer than a turn of the wheel
+	for _, d := range []time.Duration{tick, 5 * tick, 20 * tick} {
+		start := time.Now()
+		<-wheel.After(d)
+		if elapsed := time.Since(start); elapsed < d || elapsed > d+5*tick {
+			t.Fatalf("After(%v) fires after %v", d, elapsed)
+		}
+	}
+
+	fired := make(chan struct{}, 1)
+	timer := wheel.AfterFunc(3*tick, func() { fired <- struct{}{} })
+	if !timer.Stop() {
+		t.Fatal("Stop() of an active timer = false")
+	}
+	if timer.Stop() {
+		t.Fatal("Stop() of a stopped timer = true")
+	}
+	if timer.Reset(2 * tick) {
+		t.Fatal("Reset() of a stopped timer = true")
+	}
+	<-fired
+	// stopping a fired timer is safe
+	if timer.Stop() {
+		t.Fatal("Stop() of a fired timer = true")
+	}
+	select {
+	case <-fired:
+		t.Fatal("timer fires twice")
+	case <-time.After(5 * tick):
+	}
+}
+
+// pending returns the count of the timers in the slots of @w.
+func (w *Wheel) pending() int {
+	w.RLock()
+	defer w.RUnlock()
+
+	var n int
+	for _, head := range w.ring {
+		for t := head.next; t != head; t = t.next {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestWheelDone(t *testing.T) {
+	const tick = 5 * time.Millisecond
+	wheel := NewWheel(tick, 8)
+	defer wheel.Stop()
+
+	done := wheel.Done(3 * tick)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-done
+		}()
+	}
+	wg.Wait()
+	if n := wheel.pending(); n != 0 {
+		t.Fatalf("%d timers pending after Done", n)
+	}
+}
+
+func TestWheelCancel(t *testing.T) {
+	wheel := NewWheel(time.Millisecond, 16)
+	defer wheel.Stop()
+
+	timers := make([]*WheelTimer, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		timers = append(timers, wheel.NewTimer(time.Duration(i)*time.Millisecond+time.Minute))
+	}
+	if n := wheel.pending(); n != 1000 {
+		t.Fatalf("pending %d timers, want 1000", n)
+	}
+	for _, timer := range timers {
+		timer.Stop()
+	}
+	// the stopped timers are unlinked from their slots at once
+	if n := wheel.pending(); n != 0 {
+		t.Fatalf("%d timers pending after Stop", n)
+	}
+}
+
+func TestWheelStopPending(t *testing.T) {
+	const tick = 5 * time.Millisecond
+	wheel := NewWheel(tick, 8)
+
+	fired := make(chan struct{}, 2)
+	wheel.AfterFunc(2*tick, func() { fired <- struct{}{} })
+	after := wheel.After(2 * tick)
+	done := wheel.Done(2 * tick)
+	wheel.Stop()
+	wheel.Stop()
+
+	select {
+	case <-fired:
+		t.Fatal("AfterFunc() fires after the wheel stops")
+	case <-after:
+		t.Fatal("After() fires after the wheel stops")
+	case <-done:
+		t.Fatal("Done() is closed after the wheel stops")
+	case <-time.After(10 * tick):
+	}
+}
+
+func TestWheelTickFunc(t *testing.T) {
+	const tick = 5 * time.Millisecond
+	wheel := NewWheel(tick, 4)
+	defer wheel.Stop()
+
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	ticker := wheel.TickFunc(2*tick, func() {
+		mu.Lock()
+		n++
+		mu.Unlock()
+	})
+	time.Sleep(30 * tick)
+	ticker.Stop()
+
+	mu.Lock()
+	got := n
+	mu.Unlock()
+	if got < 5 || got > 15 {
+		t.Fatalf("ticked %d times in 15 periods", got)
+	}
+	time.Sleep(5 * tick)
+	mu.Lock()
+	defer mu.Unlock()
+	if n != got {
+		t.Fatalf("ticks after Stop")
+	}
+}
+
+const concurrentWaits = 100000
+
+// go test -bench=Waits -run=^$ -benchmem
+func BenchmarkWheelWaits(b *testing.B) {
+	wheel := NewWheel(time.Millisecond, 256)
+	defer wheel.Stop()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(concurrentWaits)
+		for j := 0; j < concurrentWaits; j++ {
+			wheel.AfterFunc(time.Duration(j%100)*time.Millisecond, wg.Done)
+		}
+		b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+		wg.Wait()
+	}
+}
+
+func BenchmarkStdTimerWaits(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(concurrentWaits)
+		for j := 0; j < concurrentWaits; j++ {
+			time.AfterFunc(time.Duration(j%100)*time.Millisecond, wg.Done)
+		}
+		b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+		wg.Wait()
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 ~ 2018 AlexStocks(https://github.com/AlexStocks).
+// All rights reserved.  Use of this source code is
+// governed by Apache License 2.0.
+
+// Package gxtime encapsulates some golang.time functions
+package gxtime
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindowCounter counts the events of the last window in a ring of
+// buckets, each counting the events of window/buckets. The ring rotates
+// lazily when it is accessed, no goroutine is needed.
+//
+// The accuracy is a bucket: Count includes all events of the last
+// window - window/buckets, and none older than the window.
+type SlidingWindowCounter struct {
+	clock  Clock
+	window time.Duration
+	span   time.Duration // of a bucket
+	start  time.Time     // the creation
+
+	mu        sync.Mutex
+	counts    []int64
+	head      int       // the current bucket
+	headStart time.Time // when the current bucket starts
+	total     int64
+}
+
+// NewSlidingWindowCounter returns a counter of the last @window in @buckets,
+// timed by @clock, RealClock if nil.
+func NewSlidingWindowCounter(window time.Duration, buckets int, clock Clock) *SlidingWindowCounter {
+	if window <= 0 {
+		panic("@window <= 0")
+	}
+	if buckets <= 0 {
+		panic("@buckets <= 0")
+	}
+	span := window / time.Duration(buckets)
+	if span <= 0 {
+		span = 1
+	}
+
+	clock = clockOr(clock)
+	now := clock.Now()
+
+	return &SlidingWindowCounter{
+		clock:     clock,
+		window:    window,
+		span:      span,
+		start:     now,
+		counts:    make([]int64, buckets),
+		headStart: now,
+	}
+}
+
+// rotate moves the head to the bucket of @now, c must be locked.
+func (c *SlidingWindowCounter) rotate(now time.Time) {
+	// the clock set back counts in the current bucket
+	if now.Before(c.headStart) {
+		return
+	}
+
+	steps := int64(now.Sub(c.headStart) / c.span)
+	if steps == 0 {
+		return
+	}
+	if steps >= int64(len(c.counts)) {
+		for i := range c.counts {
+			c.counts[i] = 0
+		}
+		c.total = 0
+	} else {
+		for i := int64(0); i < steps; i++ {
+			c.head = (c.head + 1) % len(c.counts)
+			c.total -= c.counts[c.head]
+			c.counts[c.head] = 0
+		}
+	}
+	c.headStart = c.headStart.Add(time.Duration(steps) * c.span)
+}
+
+// Incr counts @n events now.
+func (c *SlidingWindowCounter) Incr(n int64) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	c.rotate(now)
+	c.counts[c.head] += n
+	c.total += n
+	c.mu.Unlock()
+}
+
+// Count returns the events of the last window, see SlidingWindowCounter for
+// the accuracy.
+func (c *SlidingWindowCounter) Count() int64 {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rotate(now)
+
+	return c.total
+}
+
+// Rate returns the events per second of the last window. It is the Count
+// divided by the time its buckets cover, which is shorter than the window
+// in the first window after the creation.
+func (c *SlidingWindowCounter) Rate() float64 {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	c.rotate(now)
+	total := c.total
+	oldest := c.headStart.Add(-time.Duration(len(c.counts)-1) * c.span)
+	c.mu.Unlock()
+
+	if oldest.Before(c.start) {
+		oldest = c.start
+	}
+	covered := now.Sub(oldest)
+	// a partial bucket only is too short to estimate
+	if covered < c.span {
+		covered = c.span
+	}
+
+	return float64(total) / covered.Seconds()
+}
@@ -0,0 +1,110 @@
+package gxtime
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowCounter(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	w := NewSlidingWindowCounter(10*time.Second, 10, c)
+
+	w.Incr(3)
+	c.Advance(5 * time.Second)
+	w.Incr(2)
+	if n := w.Count(); n != 5 {
+		t.Fatalf("Count() = %d", n)
+	}
+	c.Advance(5 * time.Second)
+	if n := w.Count(); n != 2 {
+		t.Fatalf("Count() after a window = %d", n)
+	}
+	c.Advance(time.Hour)
+	if n := w.Count(); n != 0 {
+		t.Fatalf("Count() after an hour = %d", n)
+	}
+}
+
+// TestSlidingWindowCounterSweep counts an event every 100ms, and checks
+// the count against the exact ones of the window and the window minus a
+// bucket at every step across the bucket boundaries.
+func TestSlidingWindowCounterSweep(t *testing.T) {
+	const (
+		window  = 3 * time.Second
+		buckets = 6
+		step    = 100 * time.Millisecond
+		offset  = 30 * time.Millisecond // not on the boundaries
+	)
+	c := NewFakeClock(fakeEpoch)
+	w := NewSlidingWindowCounter(window, buckets, c)
+	c.Advance(offset)
+
+	var events []time.Time
+	exact := func(d time.Duration) int64 {
+		var n int64
+		for _, e := range events {
+			if c.Since(e) < d {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 100; i++ {
+		w.Incr(1)
+		events = append(events, c.Now())
+		for j := 0; j < 5; j++ {
+			c.Advance(step / 5)
+			n := w.Count()
+			lo, hi := exact(window-window/buckets), exact(window)
+			if n < lo || n > hi {
+				t.Fatalf("Count() at %v = %d, want [%d, %d]", c.Since(fakeEpoch), n, lo, hi)
+			}
+		}
+	}
+
+	// 10 events a second
+	if rate := w.Rate(); math.Abs(rate-10) > 10.0/buckets {
+		t.Fatalf("Rate() = %f", rate)
+	}
+}
+
+func TestSlidingWindowCounterRate(t *testing.T) {
+	c := NewFakeClock(fakeEpoch)
+	w := NewSlidingWindowCounter(time.Minute, 60, c)
+
+	if rate := w.Rate(); rate != 0 {
+		t.Fatalf("Rate() of nothing = %f", rate)
+	}
+	// the first seconds are not diluted by the whole window
+	for i := 0; i < 5; i++ {
+		w.Incr(100)
+		c.Advance(time.Second)
+	}
+	if rate := w.Rate(); rate != 100 {
+		t.Fatalf("Rate() = %f", rate)
+	}
+}
+
+func TestSlidingWindowCounterConcurrent(t *testing.T) {
+	w := NewSlidingWindowCounter(time.Hour, 60, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				w.Incr(1)
+				w.Count()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := w.Count(); n != 8000 {
+		t.Fatalf("Count() = %d", n)
+	}
+}